@@ -0,0 +1,53 @@
+package snip
+
+import (
+	"github.com/ryanfrishkorn/snip/database"
+	"time"
+)
+
+// Activity returns the times snips were created or modified at or after since.
+// Creation times are those of existing snips, and modifications are the changes still in the event journal.
+func Activity(since time.Time) ([]time.Time, error) {
+	var times []time.Time
+
+	queries := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`SELECT timestamp FROM snip`, nil},
+		{`SELECT timestamp FROM snip_event WHERE type IN (?, ?, ?)`, []interface{}{string(EventUpdate), string(EventAttach), string(EventDetach)}},
+	}
+	for _, q := range queries {
+		stmt, err := database.Conn.Prepare(q.query, q.args...)
+		if err != nil {
+			return times, err
+		}
+
+		for {
+			hasRow, err := stmt.Step()
+			if err != nil {
+				stmt.Close()
+				return times, err
+			}
+			if !hasRow {
+				break
+			}
+			var timestamp string
+			err = stmt.Scan(&timestamp)
+			if err != nil {
+				stmt.Close()
+				return times, err
+			}
+			t, err := time.Parse(time.RFC3339Nano, timestamp)
+			if err != nil {
+				stmt.Close()
+				return times, err
+			}
+			if !t.Before(since) {
+				times = append(times, t)
+			}
+		}
+		stmt.Close()
+	}
+	return times, nil
+}
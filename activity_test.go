@@ -0,0 +1,42 @@
+package snip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActivity(t *testing.T) {
+	since := time.Now().Add(-time.Minute)
+	old := New()
+	old.Data = "written long ago"
+	old.Timestamp = since.AddDate(-2, 0, 0)
+	if err := InsertSnip(old); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(old.UUID)
+
+	// changing the old snip is recent activity
+	old.Data = "changed today"
+	if err := old.Update(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	s := New()
+	s.Data = "written today"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	times, err := Activity(since)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(times) != 2 {
+		t.Errorf("expected the insert and the update, got %v", times)
+	}
+	for _, ts := range times {
+		if ts.Before(since) {
+			t.Errorf("expected activity after %s, got %s", since, ts)
+		}
+	}
+}
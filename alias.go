@@ -0,0 +1,99 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"strings"
+	"unicode"
+)
+
+// Alias is a short memorable name for a snip, accepted wherever its uuid is
+type Alias struct {
+	Name string
+	UUID uuid.UUID
+}
+
+// ValidateAlias returns an error unless name is made of letters, digits, and the punctuation - _ . with at least one character that cannot appear in a uuid,
+// so that an alias is never mistaken for part of a uuid
+func ValidateAlias(name string) error {
+	if name == "" {
+		return fmt.Errorf("alias must not be empty")
+	}
+	distinct := false
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune("-_.", c) {
+			return fmt.Errorf("alias %q may contain only letters, digits, and - _ .", name)
+		}
+		if !strings.ContainsRune("0123456789abcdefABCDEF-", c) {
+			distinct = true
+		}
+	}
+	if !distinct {
+		return fmt.Errorf("alias %q could be part of a uuid, add a letter past f or a symbol other than -", name)
+	}
+	return nil
+}
+
+// SetAlias names a snip with alias, replacing the snip it named before
+func SetAlias(alias string, id uuid.UUID) error {
+	if err := ValidateAlias(alias); err != nil {
+		return err
+	}
+	return database.Exec(`INSERT OR REPLACE INTO snip_alias (alias, uuid) VALUES (?, ?)`, alias, id.String())
+}
+
+// RemoveAlias deletes an alias, returning an error wrapping database.ErrNoRows if it does not exist
+func RemoveAlias(alias string) error {
+	err := database.Exec(`DELETE FROM snip_alias WHERE alias = ?`, alias)
+	if err != nil {
+		return err
+	}
+	if database.Conn.Changes() == 0 {
+		return fmt.Errorf("alias %s does not exist: %w", alias, database.ErrNoRows)
+	}
+	return nil
+}
+
+// RemoveAliases deletes every alias of a snip
+func RemoveAliases(id uuid.UUID) error {
+	return database.Exec(`DELETE FROM snip_alias WHERE uuid = ?`, id.String())
+}
+
+// ListAliases returns every alias sorted by name
+func ListAliases() ([]Alias, error) {
+	var aliases []Alias
+	stmt, err := database.Prepare(`SELECT alias, uuid FROM snip_alias ORDER BY alias`)
+	if err != nil {
+		return aliases, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return aliases, err
+		}
+		if !hasRow {
+			return aliases, nil
+		}
+		var a Alias
+		if err = database.Scan(stmt, &a.Name, &a.UUID); err != nil {
+			return aliases, err
+		}
+		aliases = append(aliases, a)
+	}
+}
+
+// resolveAlias returns the full uuid of the snip named by alias, or id unchanged when it is not an alias
+func resolveAlias(id string) (string, error) {
+	if ValidateAlias(id) != nil {
+		return id, nil
+	}
+	var resolved string
+	err := database.QueryRow(`SELECT uuid FROM snip_alias WHERE alias = ?`, []interface{}{id}, &resolved)
+	if errors.Is(err, database.ErrNoRows) {
+		return id, nil
+	}
+	return resolved, err
+}
@@ -0,0 +1,69 @@
+package snip
+
+import (
+	"errors"
+	"github.com/ryanfrishkorn/snip/database"
+	"testing"
+)
+
+func TestAlias(t *testing.T) {
+	s := New()
+	s.Data = "kubectl debug -it pod --image=busybox"
+	if err := InsertSnip(s, WithName("debug a pod")); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	for _, name := range []string{"", "abc-123", "has space", "k8s/debug"} {
+		if err := SetAlias(name, s.UUID); err == nil {
+			t.Errorf("expected alias %q to be refused", name)
+		}
+	}
+	if err := SetAlias("k8s-debug", s.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	// an alias is accepted wherever a uuid is
+	result, err := GetFromUUID("k8s-debug")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if result.UUID != s.UUID || result.Data != s.Data {
+		t.Errorf("expected the aliased snip, got %+v", result)
+	}
+	if _, err = GetSnipMeta("k8s-debug"); err != nil {
+		t.Errorf("expected nil err, got %v", err)
+	}
+	if id, err := ResolveUUID("k8s-debug"); err != nil || id != s.UUID {
+		t.Errorf("expected the aliased uuid resolved, got %v %v", id, err)
+	}
+	if _, err = GetFromUUID("k8s-missing"); !errors.Is(err, database.ErrNoRows) {
+		t.Errorf("expected ErrNoRows for an unknown alias, got %v", err)
+	}
+
+	aliases, err := ListAliases()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(aliases) != 1 || aliases[0].Name != "k8s-debug" || aliases[0].UUID != s.UUID {
+		t.Errorf("expected the alias listed, got %+v", aliases)
+	}
+
+	if err = RemoveAlias("k8s-debug"); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if err = RemoveAlias("k8s-debug"); !errors.Is(err, database.ErrNoRows) {
+		t.Errorf("expected ErrNoRows removing an alias twice, got %v", err)
+	}
+
+	// aliases are removed with their snip
+	if err = SetAlias("k8s-debug", s.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if err = Remove(s.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if aliases, _ = ListAliases(); len(aliases) != 0 {
+		t.Errorf("expected aliases removed with the snip, got %+v", aliases)
+	}
+}
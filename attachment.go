@@ -1,14 +1,18 @@
 package snip
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"github.com/bvinc/go-sqlite-lite/sqlite3"
 	"github.com/google/uuid"
 	"github.com/ryanfrishkorn/snip/database"
-	"strconv"
 	"time"
+	"unicode/utf8"
 )
 
+// ErrNotText is returned when a snip is made of an attachment holding binary data
+var ErrNotText = errors.New("attachment is not text")
+
 // Attachment represents data (binary safe) associated with a specific snip
 type Attachment struct {
 	UUID      uuid.UUID
@@ -21,126 +25,60 @@ type Attachment struct {
 
 // GetAttachmentMetadata returns all fields except Data for analysis without large memory use
 func GetAttachmentMetadata(searchUUID uuid.UUID) (Attachment, error) {
-	a := Attachment{}
-
-	var stmt *sqlite3.Stmt
-	stmt, err := database.Conn.Prepare(`SELECT size, snip_uuid, timestamp, name FROM snip_attachment WHERE uuid = ?`, searchUUID.String())
-	if err != nil {
-		return a, err
-	}
-	defer stmt.Close()
-
-	err = stmt.Exec()
-	if err != nil {
-		return a, err
-	}
-
-	resultCount := 0
-	for {
-		hasRow, err := stmt.Step()
-		if err != nil {
-			return a, err
-		}
-		if !hasRow {
-			break
-		}
-		resultCount++
-		// enforce only one result to avoid ambiguous behavior
-		if resultCount > 1 {
-			return a, fmt.Errorf("database search returned multiple results")
-		}
+	a := Attachment{UUID: searchUUID}
+	err := database.QueryRow(`SELECT size, snip_uuid, timestamp, name FROM snip_attachment WHERE uuid = ?`, []interface{}{searchUUID.String()},
+		&a.Size, &a.SnipUUID, &a.Timestamp, &a.Name)
+	return a, err
+}
 
-		var (
-			size      string
-			snipUUID  string
-			timestamp string
-			name      string
-		)
-		err = stmt.Scan(&size, &snipUUID, &timestamp, &name)
-		if err != nil {
-			return a, err
-		}
-		a.UUID = searchUUID
-		if err != nil {
-			return a, fmt.Errorf("error parsing uuid string into struct")
-		}
-		a.Size, err = strconv.Atoi(size)
-		if err != nil {
-			return a, err
-		}
-		a.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp)
-		if err != nil {
-			return a, err
-		}
-		a.Name = name
-	}
-	if resultCount == 0 {
-		return a, fmt.Errorf("database search returned zero results")
-	}
-	return a, nil
+// FindAttachmentMetadata returns all fields except Data of a single attachment by full or partial uuid
+func FindAttachmentMetadata(searchUUID string) (Attachment, error) {
+	var a Attachment
+	err := database.QueryRow(`SELECT uuid, size, snip_uuid, timestamp, name FROM snip_attachment WHERE uuid LIKE ?`, []interface{}{"%" + searchUUID + "%"},
+		&a.UUID, &a.Size, &a.SnipUUID, &a.Timestamp, &a.Name)
+	return a, err
 }
 
+// GetAttachmentFromUUID returns a single attachment including its data by full or partial uuid
 func GetAttachmentFromUUID(searchUUID string) (Attachment, error) {
 	a := Attachment{}
-
 	searchUUIDFuzzy := "%" + searchUUID + "%"
-	var stmt *sqlite3.Stmt
-	stmt, err := database.Conn.Prepare(`SELECT uuid, data, name, size, snip_uuid, timestamp FROM snip_attachment WHERE uuid LIKE ?`, searchUUIDFuzzy)
+	err := database.QueryRow(`SELECT uuid, data, name, size, snip_uuid, timestamp FROM snip_attachment WHERE uuid LIKE ?`, []interface{}{searchUUIDFuzzy},
+		&a.UUID, &a.Data, &a.Name, &a.Size, &a.SnipUUID, &a.Timestamp)
 	if err != nil {
 		return a, err
 	}
-	defer stmt.Close()
+	a.Data, err = AttachmentData(a.UUID, a.Data)
+	return a, err
+}
 
+// ListAttachmentCounts returns the number of attachments of every snip that has any
+func ListAttachmentCounts() (map[uuid.UUID]int, error) {
+	counts := make(map[uuid.UUID]int)
+
+	stmt, err := database.Prepare(`SELECT snip_uuid, COUNT(*) FROM snip_attachment GROUP BY snip_uuid`)
 	if err != nil {
-		return a, err
+		return counts, err
 	}
+	defer database.Release(stmt)
 
-	resultCount := 0
 	for {
 		hasRow, err := stmt.Step()
 		if err != nil {
-			return a, err
+			return counts, err
 		}
 		if !hasRow {
 			break
 		}
-		resultCount++
-		// enforce only one result to avoid ambiguous behavior
-		if resultCount > 1 {
-			return a, fmt.Errorf("database search returned multiple results")
-		}
-
-		var (
-			id        string
-			data      string
-			name      string
-			size      string
-			snipUUID  string
-			timestamp string
-		)
-		err = stmt.Scan(&id, &data, &name, &size, &snipUUID, &timestamp)
-		if err != nil {
-			return a, err
-		}
-		a.UUID, err = uuid.Parse(id)
-		if err != nil {
-			return a, fmt.Errorf("error parsing uuid string into uuid type")
-		}
-		a.Data = []byte(data)
-		a.Size, err = strconv.Atoi(size)
+		var id uuid.UUID
+		var count int
+		err = database.Scan(stmt, &id, &count)
 		if err != nil {
-			return a, err
+			return counts, err
 		}
-		a.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp)
-		if err != nil {
-			return a, err
-		}
-		a.Name = name
-	}
-	if resultCount == 0 {
-		return a, fmt.Errorf("database search returned zero results")
+		counts[id] = count
 	}
-	return a, nil
+	return counts, nil
 }
 
 // NewAttachment returns a new attachment struct with current defaults
@@ -154,45 +92,147 @@ func NewAttachment() Attachment {
 	}
 }
 
-// RemoveAttachment deletes an attachment from the database
+// RemoveAttachment deletes an attachment from the database.
+// The search index of the snip it belonged to is rebuilt when text was extracted from the attachment, since it is indexed with the snip.
 func RemoveAttachment(id uuid.UUID) error {
-	// see if it exists first
-	stmt, err := database.Conn.Prepare(`SELECT uuid FROM snip_attachment where uuid = ? LIMIT 2`, id.String())
-	if err != nil {
+	snipID, hadText, err := removeAttachment(id)
+	if err != nil || !hadText {
 		return err
 	}
-
-	count := 0
-	for {
-		hasRow, err := stmt.Step()
-		if err != nil {
-			stmt.Close()
-			return err
-		}
-		if !hasRow {
-			break
-		}
-		count += 1
+	// an orphaned attachment has no snip to reindex
+	exists, err := hasSnip("main", snipID)
+	if err != nil || !exists {
+		return err
 	}
-	stmt.Close()
+	return Reindex(snipID)
+}
 
-	// result should always be unique
-	if count == 0 {
-		return fmt.Errorf("could not locate attachment")
+// removeAttachment deletes an attachment from the database, returning the uuid of the snip it belonged to and whether text was extracted from it
+func removeAttachment(id uuid.UUID) (uuid.UUID, bool, error) {
+	// see if it exists first, the result should always be unique
+	var snipID uuid.UUID
+	err := database.QueryRow(`SELECT snip_uuid FROM snip_attachment where uuid = ? LIMIT 2`, []interface{}{id.String()}, &snipID)
+	switch {
+	case errors.Is(err, database.ErrNoRows):
+		return snipID, false, fmt.Errorf("could not locate attachment: %w", err)
+	case errors.Is(err, database.ErrMultipleRows):
+		return snipID, false, fmt.Errorf("attachment id returned ambiguous results: %w", err)
+	case err != nil:
+		return snipID, false, err
+	}
+	if err = checkLocked(snipID); err != nil {
+		return snipID, false, err
 	}
-	if count != 1 {
-		return fmt.Errorf("attachment id returned ambiguous results")
+	_, _, err = GetAttachmentText(id)
+	hadText := err == nil
+	if err != nil && !errors.Is(err, database.ErrNoRows) {
+		return snipID, false, err
 	}
 
 	// remove
-	stmt, err = database.Conn.Prepare(`DELETE FROM snip_attachment WHERE uuid = ?`, id.String())
+	err = database.Exec(`DELETE FROM snip_attachment WHERE uuid = ?`, id.String())
 	if err != nil {
-		return err
+		return snipID, hadText, err
 	}
-	err = stmt.Exec()
-	stmt.Close()
+	err = RemoveAttachmentChecksum(id)
+	if err != nil {
+		return snipID, hadText, err
+	}
+	err = RemoveThumbnails(id)
+	if err != nil {
+		return snipID, hadText, err
+	}
+	err = RemoveAttachmentText(id)
+	if err != nil {
+		return snipID, hadText, err
+	}
+	err = removeBlob(id)
+	if err != nil {
+		return snipID, hadText, err
+	}
+	return snipID, hadText, recordEvent(EventDetach, snipID, id)
+}
+
+// MoveAttachment makes attachment id belong to snip target, leaving its data where it is, and returns the uuid of the snip it belonged to.
+// The search index of both snips is rebuilt when text was extracted from the attachment, since it is indexed with the snip holding it.
+func MoveAttachment(id uuid.UUID, target uuid.UUID) (uuid.UUID, error) {
+	a, err := GetAttachmentMetadata(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("could not locate attachment: %w", err)
+	}
+	source := a.SnipUUID
+	if source == target {
+		return source, fmt.Errorf("attachment %s already belongs to snip %s", id, target)
+	}
+	exists, err := hasSnip("main", target)
+	if err != nil {
+		return source, err
+	}
+	if !exists {
+		return source, fmt.Errorf("could not locate snip %s: %w", target, database.ErrNoRows)
+	}
+	for _, snipID := range []uuid.UUID{source, target} {
+		if err = checkLocked(snipID); err != nil {
+			return source, err
+		}
+	}
+	_, _, err = GetAttachmentText(id)
+	hasText := err == nil
+	if err != nil && !errors.Is(err, database.ErrNoRows) {
+		return source, err
+	}
+
+	return source, database.Conn.WithTx(func() error {
+		err := database.Exec(`UPDATE snip_attachment SET snip_uuid = ? WHERE uuid = ?`, target.String(), id.String())
+		if err != nil {
+			return err
+		}
+		if hasText {
+			for _, snipID := range []uuid.UUID{source, target} {
+				// an orphaned attachment has no snip to reindex
+				exists, err := hasSnip("main", snipID)
+				if err != nil {
+					return err
+				}
+				if !exists {
+					continue
+				}
+				if err = Reindex(snipID); err != nil {
+					return err
+				}
+			}
+		}
+		if err = recordEvent(EventDetach, source, id); err != nil {
+			return err
+		}
+		return recordEvent(EventAttach, target, id)
+	})
+}
+
+// Reindex replaces the search index of snip id, such as when the text of its attachments changes
+func Reindex(id uuid.UUID) error {
+	s, err := GetFromUUID(id.String())
 	if err != nil {
 		return err
 	}
-	return nil
+	if err = RemoveIndex(id); err != nil {
+		return err
+	}
+	return s.Index()
+}
+
+// PromoteAttachment returns a new snip holding the text of attachment a and named after it, without inserting it.
+// The snip links back to the snip the attachment belongs to with its promoted_from metadata, and names the attachment with attachment.
+func PromoteAttachment(a Attachment) (Snip, error) {
+	if !utf8.Valid(a.Data) || bytes.IndexByte(a.Data, 0) >= 0 {
+		return Snip{}, fmt.Errorf("%w: %s", ErrNotText, a.Name)
+	}
+	s := New()
+	s.Name = a.Name
+	s.Data = string(a.Data)
+	s.Meta = map[string]string{
+		"promoted_from": SnipLinkScheme + a.SnipUUID.String(),
+		"attachment":    a.UUID.String(),
+	}
+	return s, nil
 }
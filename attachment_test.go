@@ -0,0 +1,88 @@
+package snip
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMoveAttachment(t *testing.T) {
+	var snips []Snip
+	for _, name := range []string{"wrong parent", "right parent"} {
+		s := New()
+		s.Name = name
+		s.Data = "notes about the release"
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		snips = append(snips, s)
+	}
+	from, to := snips[0], snips[1]
+	a, err := from.AddAttachment("release.png", []byte("pixels"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = SetAttachmentText(a.UUID, AttachmentTextOCR, "changelog"); err != nil {
+		t.Fatal(err)
+	}
+	if err = Reindex(from.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := MoveAttachment(a.UUID, to.UUID)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if source != from.UUID {
+		t.Errorf("expected the snip it belonged to, got %s", source)
+	}
+	moved, err := GetAttachmentFromUUID(a.UUID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.SnipUUID != to.UUID || string(moved.Data) != "pixels" {
+		t.Errorf("expected the attachment with its data to belong to %s, got %+v", to.UUID, moved)
+	}
+	// the text of the attachment is found with the snip now holding it
+	for _, s := range snips {
+		positions, err := s.GetPositions("changelog")
+		if found := err == nil && positions != ""; found != (s.UUID == to.UUID) {
+			t.Errorf("%s: expected the attachment text indexed only with %s, got %q %v", s.Name, to.Name, positions, err)
+		}
+	}
+
+	if _, err = MoveAttachment(a.UUID, to.UUID); err == nil {
+		t.Errorf("expected an error moving an attachment to the snip it belongs to")
+	}
+	if err = Lock(from.UUID); err != nil {
+		t.Fatal(err)
+	}
+	defer Unlock(from.UUID)
+	if _, err = MoveAttachment(a.UUID, from.UUID); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+}
+
+func TestPromoteAttachment(t *testing.T) {
+	a := NewAttachment()
+	a.Name = "server.log"
+	a.Data = []byte("panic: runtime error\n")
+	a.SnipUUID = New().UUID
+	s, err := PromoteAttachment(a)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if s.Name != "server.log" || s.Data != "panic: runtime error\n" {
+		t.Errorf("expected a snip named after the attachment holding its text, got %q %q", s.Name, s.Data)
+	}
+	if s.Meta["promoted_from"] != "snip://"+a.SnipUUID.String() || s.Meta["attachment"] != a.UUID.String() {
+		t.Errorf("expected metadata linking back to the snip, got %v", s.Meta)
+	}
+
+	for _, data := range [][]byte{{0xff, 0xfe, 'a'}, []byte("nul\x00byte")} {
+		a.Data = data
+		if _, err = PromoteAttachment(a); !errors.Is(err, ErrNotText) {
+			t.Errorf("%q: expected ErrNotText, got %v", data, err)
+		}
+	}
+}
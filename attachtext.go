@@ -0,0 +1,140 @@
+package snip
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Sources of the text extracted from attachments
+const (
+	// AttachmentTextOCR is text read from images by optical character recognition
+	AttachmentTextOCR = "ocr"
+	// AttachmentTextTranscript is the transcript of audio
+	AttachmentTextTranscript = "transcript"
+)
+
+// audioExtensions are the file extensions of the attachments transcripts are made of
+var audioExtensions = map[string]bool{
+	".aac":  true,
+	".flac": true,
+	".m4a":  true,
+	".mp3":  true,
+	".oga":  true,
+	".ogg":  true,
+	".opus": true,
+	".wav":  true,
+}
+
+// IsAudioAttachment reports whether transcripts can be made of an attachment with the given name
+func IsAudioAttachment(name string) bool {
+	return audioExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// DefaultOCRCommand reads the text of an image with tesseract
+const DefaultOCRCommand = `tesseract "$SNIP_ATTACHMENT_FILE" stdout`
+
+// ExtractAttachmentText runs command with sh to extract the text of an attachment, returning what it writes to standard output.
+// The attachment is written to a temporary file named as it is, given by $SNIP_ATTACHMENT_FILE along with $SNIP_ATTACHMENT_UUID,
+// $SNIP_ATTACHMENT_NAME, and the uuid of its snip as $SNIP_UUID.
+func ExtractAttachmentText(command string, a Attachment) (string, error) {
+	dir, err := os.MkdirTemp("", "snip-attachment-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, filepath.Base(a.Name))
+	if err = os.WriteFile(file, a.Data, 0600); err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"SNIP_ATTACHMENT_FILE="+file,
+		"SNIP_ATTACHMENT_UUID="+a.UUID.String(),
+		"SNIP_ATTACHMENT_NAME="+a.Name,
+		"SNIP_UUID="+a.SnipUUID.String(),
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		return "", fmt.Errorf("%q: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// SetAttachmentText records the text extracted from attachment id and where it came from, replacing any recorded before
+func SetAttachmentText(id uuid.UUID, source string, text string) error {
+	return database.Exec(`INSERT OR REPLACE INTO snip_attachment_text (uuid, source, text) VALUES (?, ?, ?)`, id.String(), source, text)
+}
+
+// GetAttachmentText returns the text extracted from attachment id and its source, or an error wrapping database.ErrNoRows if none was
+func GetAttachmentText(id uuid.UUID) (string, string, error) {
+	var source, text string
+	err := database.QueryRow(`SELECT source, text FROM snip_attachment_text WHERE uuid = ?`, []interface{}{id.String()}, &source, &text)
+	return source, text, err
+}
+
+// RemoveAttachmentText deletes the text extracted from attachment id
+func RemoveAttachmentText(id uuid.UUID) error {
+	return database.Exec(`DELETE FROM snip_attachment_text WHERE uuid = ?`, id.String())
+}
+
+// attachmentText returns the text extracted from the attachments of snip id in the order they were added, separated by blank lines
+func attachmentText(id uuid.UUID) (string, error) {
+	var texts []string
+	stmt, err := database.Prepare(`SELECT t.text FROM snip_attachment_text t JOIN snip_attachment a ON a.uuid = t.uuid WHERE a.snip_uuid = ? ORDER BY a.rowid`, id.String())
+	if err != nil {
+		return "", err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return "", err
+		}
+		if !hasRow {
+			break
+		}
+		var text string
+		if err = stmt.Scan(&text); err != nil {
+			return "", err
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// ListUnreadAttachments returns the metadata of the attachments whose names match and that no text has been extracted from,
+// in the order they were added
+func ListUnreadAttachments(match func(name string) bool) ([]Attachment, error) {
+	var attachments []Attachment
+	stmt, err := database.Prepare(`SELECT uuid, size, snip_uuid, timestamp, name FROM snip_attachment WHERE uuid NOT IN (SELECT uuid FROM snip_attachment_text) ORDER BY rowid`)
+	if err != nil {
+		return attachments, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return attachments, err
+		}
+		if !hasRow {
+			break
+		}
+		var a Attachment
+		if err = database.Scan(stmt, &a.UUID, &a.Size, &a.SnipUUID, &a.Timestamp, &a.Name); err != nil {
+			return attachments, err
+		}
+		if match(a.Name) {
+			attachments = append(attachments, a)
+		}
+	}
+	return attachments, nil
+}
@@ -0,0 +1,85 @@
+package snip
+
+import (
+	"errors"
+	"github.com/ryanfrishkorn/snip/database"
+	"testing"
+)
+
+func TestAttachmentText(t *testing.T) {
+	s := New()
+	s.Name = "attachment text test"
+	s.Data = "a screenshot of the build"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	a, err := s.AddAttachment("build.png", []byte("pixels"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.AddAttachment("build.log", []byte("log")); err != nil {
+		t.Fatal(err)
+	}
+
+	// unread reports whether attachment a is listed among the unread images
+	unread := func() bool {
+		t.Helper()
+		attachments, err := ListUnreadAttachments(IsImageAttachment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, listed := range attachments {
+			if listed.Name == "build.log" {
+				t.Errorf("expected only images listed, got %s", listed.Name)
+			}
+			if listed.UUID == a.UUID {
+				return true
+			}
+		}
+		return false
+	}
+	if !unread() {
+		t.Errorf("expected the image unread")
+	}
+
+	// the command reads the attachment from a file named as it is
+	text, err := ExtractAttachmentText(`test "$(cat "$SNIP_ATTACHMENT_FILE")" = pixels && echo "  segfault in $SNIP_ATTACHMENT_NAME"`, a)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if text != "segfault in build.png" {
+		t.Errorf("expected the trimmed output of the command, got %q", text)
+	}
+	if _, err = ExtractAttachmentText(`echo unreadable >&2; exit 3`, a); err == nil {
+		t.Errorf("expected an error from a failing command")
+	}
+
+	if err = SetAttachmentText(a.UUID, AttachmentTextOCR, text); err != nil {
+		t.Fatal(err)
+	}
+	if unread() {
+		t.Errorf("expected the image read")
+	}
+	if err = s.Index(); err != nil {
+		t.Fatal(err)
+	}
+	scores, err := Search([]string{"segfault"}, 0)
+	if err != nil || len(scores) != 1 || scores[0].UUID != s.UUID {
+		t.Errorf("expected the snip found by the text of its attachment, got %v %v", scores, err)
+	}
+	matches, err := SearchWithContext([]string{"segfault"}, 0, 3)
+	if err != nil || len(matches) != 1 {
+		t.Errorf("expected a match without context, got %v %v", matches, err)
+	}
+
+	if err = RemoveAttachment(a.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = GetAttachmentText(a.UUID); !errors.Is(err, database.ErrNoRows) {
+		t.Errorf("expected the text removed with the attachment, got %v", err)
+	}
+	if scores, err = Search([]string{"segfault"}, 0); err != nil || len(scores) != 0 {
+		t.Errorf("expected the snip no longer found by the text of the removed attachment, got %v %v", scores, err)
+	}
+}
@@ -0,0 +1,166 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/ryanfrishkorn/snip/database"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupLayout is the time in the file name of each archive, in utc so that names sort in the order the archives were made
+const backupLayout = "20060102T150405Z"
+
+// backupPrefix and backupSuffix surround the time in the file name of each archive, files named otherwise are left alone
+const (
+	backupPrefix = "snip-"
+	backupSuffix = ".sqlite3"
+)
+
+// BackupArchive is a copy of the database made by CreateBackup
+type BackupArchive struct {
+	Path string
+	Time time.Time
+}
+
+// BlobDir returns the directory holding the blobs that the attachments of the archive are kept in, laid out as snip.BlobDir is
+func (a BackupArchive) BlobDir() string {
+	return a.Path + ".blobs"
+}
+
+// Retention is how many archives are kept for the most recent days, weeks, and months, the latest archive of each being kept.
+// The latest archive is always kept, and nothing is pruned when all are zero.
+type Retention struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// Validate returns an error if a count is negative
+func (r Retention) Validate() error {
+	if r.Daily < 0 || r.Weekly < 0 || r.Monthly < 0 {
+		return fmt.Errorf("backups kept must not be negative, got %d daily, %d weekly, and %d monthly", r.Daily, r.Weekly, r.Monthly)
+	}
+	return nil
+}
+
+// CreateBackup writes a consistent copy of the open database into dir, creating it if needed, and returns the archive.
+// The blobs the attachments of the copy are kept in are copied beside it, to the BlobDir of the archive.
+func CreateBackup(dir string, now time.Time) (BackupArchive, error) {
+	a := BackupArchive{Time: now.UTC().Truncate(time.Second)}
+	a.Path = filepath.Join(dir, backupPrefix+a.Time.Format(backupLayout)+backupSuffix)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return a, err
+	}
+	// a copy made in the same second replaces the earlier one
+	if err := os.Remove(a.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return a, err
+	}
+	if err := os.RemoveAll(a.BlobDir()); err != nil {
+		return a, err
+	}
+	if err := database.Exec(`VACUUM INTO ?`, a.Path); err != nil {
+		return a, err
+	}
+	// the blobs are those the copy refers to, whatever changed after it was made
+	conn, err := sqlite3.Open(a.Path, sqlite3.OPEN_READONLY)
+	if err != nil {
+		return a, err
+	}
+	defer conn.Close()
+	return a, copyReferencedBlobs(conn, a.BlobDir())
+}
+
+// ListBackups returns the archives in dir, newest first
+func ListBackups(dir string) ([]BackupArchive, error) {
+	var archives []BackupArchive
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return archives, nil
+	}
+	if err != nil {
+		return archives, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		t, err := time.Parse(backupLayout, strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix))
+		if err != nil {
+			continue
+		}
+		archives = append(archives, BackupArchive{Path: filepath.Join(dir, name), Time: t})
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].Time.After(archives[j].Time)
+	})
+	return archives, nil
+}
+
+// PruneBackups removes the archives in dir that keep does not retain, returning those removed
+func PruneBackups(dir string, keep Retention) ([]BackupArchive, error) {
+	var removed []BackupArchive
+	archives, err := ListBackups(dir)
+	if err != nil {
+		return removed, err
+	}
+	retained := retainBackups(archives, keep)
+	for idx, a := range archives {
+		if retained[idx] {
+			continue
+		}
+		if err = os.Remove(a.Path); err != nil {
+			return removed, err
+		}
+		if err = os.RemoveAll(a.BlobDir()); err != nil {
+			return removed, err
+		}
+		removed = append(removed, a)
+	}
+	return removed, nil
+}
+
+// retainBackups returns the indexes of the archives, sorted newest first, that keep retains: the latest of each of the most recent
+// days, weeks, and months up to their counts, as local time reckons them
+func retainBackups(archives []BackupArchive, keep Retention) map[int]bool {
+	retained := make(map[int]bool)
+	if keep == (Retention{}) {
+		for idx := range archives {
+			retained[idx] = true
+		}
+		return retained
+	}
+	if len(archives) > 0 {
+		retained[0] = true
+	}
+	for _, period := range []struct {
+		count int
+		key   func(t time.Time) string
+	}{
+		{keep.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{keep.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%d", year, week)
+		}},
+		{keep.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+	} {
+		seen := make(map[string]bool)
+		for idx, a := range archives {
+			if len(seen) == period.count {
+				break
+			}
+			key := period.key(a.Time.Local())
+			if !seen[key] {
+				seen[key] = true
+				retained[idx] = true
+			}
+		}
+	}
+	return retained
+}
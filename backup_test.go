@@ -0,0 +1,147 @@
+package snip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackup(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backups")
+	now := time.Now()
+	a, err := CreateBackup(dir, now)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if info, err := os.Stat(a.Path); err != nil || info.Size() == 0 {
+		t.Fatalf("expected the archive written, got %v", err)
+	}
+	// files that are not archives are left alone
+	if err = os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = CreateBackup(dir, now.Add(-48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	archives, err := ListBackups(dir)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(archives) != 2 || archives[0].Path != a.Path {
+		t.Fatalf("expected both archives newest first, got %+v", archives)
+	}
+
+	removed, err := PruneBackups(dir, Retention{Daily: 1})
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(removed) != 1 || removed[0].Path != archives[1].Path {
+		t.Errorf("expected the older archive removed, got %+v", removed)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("expected other files left alone, got %v", err)
+	}
+}
+
+func TestRetainBackups(t *testing.T) {
+	// an archive every twelve hours for ten weeks, newest first
+	start := time.Date(2024, 3, 31, 22, 0, 0, 0, time.Local)
+	var archives []BackupArchive
+	for idx := 0; idx < 140; idx++ {
+		archives = append(archives, BackupArchive{Time: start.Add(time.Duration(-12*idx) * time.Hour)})
+	}
+
+	tests := []struct {
+		keep     Retention
+		expected int
+	}{
+		{Retention{}, 140},
+		{Retention{Daily: 7}, 7},
+		{Retention{Weekly: 4}, 4},
+		// the latest daily archives include the latest of this week
+		{Retention{Daily: 7, Weekly: 4}, 10},
+		{Retention{Monthly: 3}, 3},
+	}
+	for _, tt := range tests {
+		retained := retainBackups(archives, tt.keep)
+		if len(retained) != tt.expected {
+			t.Errorf("%+v: expected %d archives kept, got %d", tt.keep, tt.expected, len(retained))
+		}
+		if !retained[0] {
+			t.Errorf("%+v: expected the latest archive kept", tt.keep)
+		}
+	}
+	if err := (Retention{Daily: -1}).Validate(); err == nil {
+		t.Errorf("expected error for a negative count")
+	}
+}
+
+func TestBackupBlobs(t *testing.T) {
+	BlobDir, StoreBlobs = t.TempDir(), true
+	defer func() {
+		BlobDir, StoreBlobs = "", false
+	}()
+	s := New()
+	s.Data = "a snip with a recording"
+	if err := InsertSnip(s, WithName("blob backup")); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	data := []byte("a recording kept in a blob")
+	if _, err := s.AddAttachment("recording.m4a", data); err != nil {
+		t.Fatal(err)
+	}
+	sum := Checksum(data)
+
+	// archives carry the blobs of their attachments beside them
+	dir := t.TempDir()
+	a, err := CreateBackup(dir, time.Now())
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	path, err := blobFile(a.BlobDir(), sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != string(data) {
+		t.Errorf("expected the blob copied beside the archive, got %q %v", got, err)
+	}
+	if _, err = CreateBackup(dir, time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := PruneBackups(dir, Retention{Daily: 1})
+	if err != nil || len(removed) != 1 {
+		t.Fatalf("expected one archive removed, got %+v %v", removed, err)
+	}
+	if _, err = os.Stat(removed[0].BlobDir()); !os.IsNotExist(err) {
+		t.Errorf("expected the blobs of the pruned archive removed, got %v", err)
+	}
+
+	// incremental backups store blobs as chunks and restore them
+	incremental := t.TempDir()
+	snapshot, _, err := CreateIncrementalBackup(incremental, time.Now())
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(snapshot.Blobs) != 1 || snapshot.Blobs[0].SHA256 != sum {
+		t.Fatalf("expected the blob recorded, got %+v", snapshot.Blobs)
+	}
+	restored := t.TempDir()
+	if err = RestoreIncrementalBlobs(incremental, snapshot, restored); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if path, err = blobFile(restored, sum); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != string(data) {
+		t.Errorf("expected the blob restored, got %q %v", got, err)
+	}
+	if err = os.WriteFile(backupChunkPath(incremental, sum), []byte("damaged"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	v, err := VerifyIncrementalBackups(incremental)
+	if err != nil || len(v.Problems) != 1 || v.Problems[0].Chunk != sum {
+		t.Errorf("expected the damaged blob reported, got %+v %v", v, err)
+	}
+}
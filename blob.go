@@ -0,0 +1,358 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// BlobDir is the directory holding attachment data kept outside the database, each payload in a file named by its SHA-256 digest
+var BlobDir string
+
+// StoreBlobs keeps the data of new attachments in BlobDir rather than in the database
+var StoreBlobs bool
+
+// blobPath returns the location of the blob with digest sum, spread over subdirectories by its first two characters
+func blobPath(sum string) (string, error) {
+	if BlobDir == "" {
+		return "", fmt.Errorf("no blob directory is set to hold attachment data %s", sum)
+	}
+	return blobFile(BlobDir, sum)
+}
+
+// blobFile returns the location of the blob with digest sum within dir, laid out as BlobDir is
+func blobFile(dir string, sum string) (string, error) {
+	if len(sum) < 3 {
+		return "", fmt.Errorf("invalid blob digest %q", sum)
+	}
+	return filepath.Join(dir, sum[:2], sum), nil
+}
+
+// writeBlob stores data as the blob with digest sum unless BlobDir already holds it
+func writeBlob(sum string, data []byte) error {
+	path, err := blobPath(sum)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(path); err == nil {
+		return nil
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// readBlob returns the data of the blob with digest sum
+func readBlob(sum string) ([]byte, error) {
+	path, err := blobPath(sum)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// blobDigest returns the digest of the blob holding the data of attachment id, or an empty string when the database holds it
+func blobDigest(id uuid.UUID) (string, error) {
+	var sum string
+	err := database.QueryRow(`SELECT sha256 FROM snip_attachment_blob WHERE uuid = ?`, []interface{}{id.String()}, &sum)
+	if errors.Is(err, database.ErrNoRows) {
+		return "", nil
+	}
+	return sum, err
+}
+
+// AttachmentData returns data, the payload of attachment id read from the database, or the contents of its blob when it has one
+func AttachmentData(id uuid.UUID, data []byte) ([]byte, error) {
+	sum, err := blobDigest(id)
+	if err != nil || sum == "" {
+		return data, err
+	}
+	data, err = readBlob(sum)
+	if err != nil {
+		return nil, fmt.Errorf("reading data of attachment %s: %w", id, err)
+	}
+	return data, nil
+}
+
+// storeBlob writes data to BlobDir and records that attachment id is kept there, leaving its row in snip_attachment empty
+func storeBlob(id uuid.UUID, data []byte) error {
+	sum := Checksum(data)
+	if err := writeBlob(sum, data); err != nil {
+		return err
+	}
+	err := database.Exec(`INSERT OR REPLACE INTO snip_attachment_blob (uuid, sha256) VALUES (?, ?)`, id.String(), sum)
+	if err != nil {
+		return err
+	}
+	return database.Exec(`UPDATE snip_attachment SET data = zeroblob(0) WHERE uuid = ?`, id.String())
+}
+
+// unusedBlobs are the digests of blobs that lost their last attachment within a transaction, deleted by DeleteUnusedBlobs once it ends
+var unusedBlobs []string
+
+// removeBlob forgets that attachment id is kept in a blob, deleting the file once no other attachment shares it.
+// Within a transaction the file is only queued for deletion, since rolling back restores the record of the blob.
+func removeBlob(id uuid.UUID) error {
+	sum, err := forgetBlob(id)
+	if err != nil || sum == "" {
+		return err
+	}
+	if !database.Conn.AutoCommit() {
+		unusedBlobs = append(unusedBlobs, sum)
+		return nil
+	}
+	return deleteUnusedBlob(sum)
+}
+
+// DeleteUnusedBlobs deletes the blobs queued by attachments removed within a transaction, and must be called once it has ended.
+// A blob whose attachment was restored by rolling back is kept.
+func DeleteUnusedBlobs() error {
+	sums := unusedBlobs
+	unusedBlobs = nil
+	var errs []error
+	for _, sum := range sums {
+		if err := deleteUnusedBlob(sum); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// forgetBlob removes the record of the blob holding the data of attachment id, returning its digest or an empty string when it had none
+func forgetBlob(id uuid.UUID) (string, error) {
+	sum, err := blobDigest(id)
+	if err != nil || sum == "" {
+		return sum, err
+	}
+	return sum, database.Exec(`DELETE FROM snip_attachment_blob WHERE uuid = ?`, id.String())
+}
+
+// deleteUnusedBlob deletes the blob with digest sum unless an attachment is still kept in it
+func deleteUnusedBlob(sum string) error {
+	if sum == "" {
+		return nil
+	}
+	var shared int
+	err := database.QueryRow(`SELECT COUNT(*) FROM snip_attachment_blob WHERE sha256 = ?`, []interface{}{sum}, &shared)
+	if err != nil || shared > 0 {
+		return err
+	}
+	path, err := blobPath(sum)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// blobDigests returns the digests of the blobs the attachments of the database open on conn are kept in
+func blobDigests(conn *sqlite3.Conn) ([]string, error) {
+	var sums []string
+	// copies made before attachments could be kept in blobs have no table recording them
+	stmt, err := conn.Prepare(`SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'snip_attachment_blob'`)
+	if err != nil {
+		return sums, err
+	}
+	hasTable, err := stmt.Step()
+	stmt.Close()
+	if err != nil || !hasTable {
+		return sums, err
+	}
+	stmt, err = conn.Prepare(`SELECT DISTINCT sha256 FROM snip_attachment_blob ORDER BY sha256`)
+	if err != nil {
+		return sums, err
+	}
+	defer stmt.Close()
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return sums, err
+		}
+		if !hasRow {
+			return sums, nil
+		}
+		var sum string
+		if err = stmt.Scan(&sum); err != nil {
+			return sums, err
+		}
+		sums = append(sums, sum)
+	}
+}
+
+// copyBlobs copies the blobs with digests sums from the directory src to dst, both laid out as BlobDir is, skipping those dst already holds.
+// Blobs never change once written, so each is linked rather than copied where the filesystem allows.
+func copyBlobs(sums []string, src string, dst string) error {
+	for _, sum := range sums {
+		from, err := blobFile(src, sum)
+		if err != nil {
+			return err
+		}
+		to, err := blobFile(dst, sum)
+		if err != nil {
+			return err
+		}
+		if _, err = os.Stat(to); err == nil {
+			continue
+		}
+		if err = os.MkdirAll(filepath.Dir(to), 0700); err != nil {
+			return err
+		}
+		if os.Link(from, to) == nil {
+			continue
+		}
+		data, err := os.ReadFile(from)
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("blob %s is missing from %s", sum, src)
+		}
+		if err != nil {
+			return err
+		}
+		if err = writeFileAtomic(to, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyReferencedBlobs copies the blobs that the database open on conn keeps attachments in from BlobDir to dst,
+// so that a copy of the database carries the data of its attachments. Nothing is written when it keeps none in blobs.
+func copyReferencedBlobs(conn *sqlite3.Conn, dst string) error {
+	sums, err := blobDigests(conn)
+	if err != nil || len(sums) == 0 {
+		return err
+	}
+	if BlobDir == "" {
+		return fmt.Errorf("no blob directory is set to copy the data of %d attachments from", len(sums))
+	}
+	return copyBlobs(sums, BlobDir, dst)
+}
+
+// MoveAttachmentsToBlobs moves the data of every attachment held by the database to BlobDir, reporting each to progress,
+// and returns the number moved. The space freed in the database file is reclaimed by vacuum.
+func MoveAttachmentsToBlobs(progress Progress) (int, error) {
+	ids, err := blobAttachmentIDs(false)
+	if err != nil {
+		return 0, err
+	}
+	for idx, id := range ids {
+		var data []byte
+		err = database.QueryRow(`SELECT data FROM snip_attachment WHERE uuid = ?`, []interface{}{id.String()}, &data)
+		if err != nil {
+			return idx, err
+		}
+		err = database.Conn.WithTx(func() error {
+			return storeBlob(id, data)
+		})
+		if err != nil {
+			return idx, fmt.Errorf("%s: %w", id, err)
+		}
+		reportStep(progress, "moving", idx+1, len(ids))
+	}
+	return len(ids), nil
+}
+
+// MoveBlobsToDatabase copies the data of every attachment kept in BlobDir back into the database and deletes the blobs,
+// reporting each attachment to progress, and returns the number moved
+func MoveBlobsToDatabase(progress Progress) (int, error) {
+	ids, err := blobAttachmentIDs(true)
+	if err != nil {
+		return 0, err
+	}
+	for idx, id := range ids {
+		data, err := AttachmentData(id, nil)
+		if err != nil {
+			return idx, err
+		}
+		var sum string
+		err = database.Conn.WithTx(func() error {
+			err := database.Exec(`UPDATE snip_attachment SET data = ? WHERE uuid = ?`, data, id.String())
+			if err != nil {
+				return err
+			}
+			sum, err = forgetBlob(id)
+			return err
+		})
+		if err == nil {
+			// the file is deleted only once the database holds the data
+			err = deleteUnusedBlob(sum)
+		}
+		if err != nil {
+			return idx, fmt.Errorf("%s: %w", id, err)
+		}
+		reportStep(progress, "moving", idx+1, len(ids))
+	}
+	return len(ids), nil
+}
+
+// blobAttachmentIDs returns the uuids of the attachments kept in blobs, or of those held by the database when blobs is false
+func blobAttachmentIDs(blobs bool) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	query := `SELECT uuid FROM snip_attachment WHERE uuid NOT IN (SELECT uuid FROM snip_attachment_blob) ORDER BY rowid`
+	if blobs {
+		query = `SELECT uuid FROM snip_attachment WHERE uuid IN (SELECT uuid FROM snip_attachment_blob) ORDER BY rowid`
+	}
+	stmt, err := database.Prepare(query)
+	if err != nil {
+		return ids, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return ids, err
+		}
+		if !hasRow {
+			break
+		}
+		var id uuid.UUID
+		if err = database.Scan(stmt, &id); err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// inlineBlobs copies the data of the attachments of snip id kept in blobs into the snip_attachment table of the attached database schema
+func inlineBlobs(schema string, id uuid.UUID) error {
+	sums := make(map[uuid.UUID]string)
+	stmt, err := database.Prepare(`SELECT b.uuid, b.sha256 FROM main.snip_attachment_blob b JOIN main.snip_attachment a ON a.uuid = b.uuid WHERE a.snip_uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			break
+		}
+		var attachmentID uuid.UUID
+		var sum string
+		if err = database.Scan(stmt, &attachmentID, &sum); err != nil {
+			return err
+		}
+		sums[attachmentID] = sum
+	}
+	for attachmentID, sum := range sums {
+		data, err := readBlob(sum)
+		if err != nil {
+			return fmt.Errorf("reading data of attachment %s: %w", attachmentID, err)
+		}
+		err = database.Exec(`UPDATE `+schema+`.snip_attachment SET data = ? WHERE uuid = ?`, data, attachmentID.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package snip
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBlobs(t *testing.T) {
+	BlobDir, StoreBlobs = t.TempDir(), true
+	defer func() {
+		BlobDir, StoreBlobs = "", false
+	}()
+
+	s := New()
+	s.Name = "blob test"
+	s.Data = "a recording of the meeting"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	data := []byte("a large recording")
+	a, err := s.AddAttachment("meeting.m4a", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the same data is kept in a single blob
+	copied, err := s.AddAttachment("meeting copy.m4a", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stored returns the data held by the database for attachment a
+	stored := func() []byte {
+		t.Helper()
+		var stored []byte
+		if err := database.QueryRow(`SELECT data FROM snip_attachment WHERE uuid = ?`, []interface{}{a.UUID.String()}, &stored); err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+	if len(stored()) != 0 {
+		t.Errorf("expected the database to hold no data, got %q", stored())
+	}
+	path, err := blobPath(Checksum(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("expected the blob to hold the data, got %q %v", got, err)
+	}
+	got, err := GetAttachmentFromUUID(a.UUID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Data, data) || got.Size != len(data) {
+		t.Errorf("expected the data read from the blob, got %q of size %d", got.Data, got.Size)
+	}
+	v, err := Verify(nil, s.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Mismatches) != 0 {
+		t.Errorf("expected no mismatches, got %+v", v.Mismatches)
+	}
+
+	// the blob is kept while an attachment shares it
+	if err = RemoveAttachment(copied.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); err != nil {
+		t.Errorf("expected the shared blob kept, got %v", err)
+	}
+
+	if _, err = MoveBlobsToDatabase(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stored(), data) {
+		t.Errorf("expected the data restored to the database, got %q", stored())
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the blob deleted once restored, got %v", err)
+	}
+	if _, err = MoveAttachmentsToBlobs(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored()) != 0 {
+		t.Errorf("expected the data moved to a blob, got %q", stored())
+	}
+	// the fixture attachments moved along with it are returned to the database
+	defer MoveBlobsToDatabase(nil)
+
+	// a damaged or missing blob is reported by verify
+	if err = os.WriteFile(path, []byte("damaged"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	v, err = Verify(nil, s.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Mismatches) != 1 || v.Mismatches[0].Actual != Checksum([]byte("damaged")) {
+		t.Errorf("expected the damaged blob reported, got %+v", v.Mismatches)
+	}
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	v, err = Verify(nil, s.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Mismatches) != 1 || v.Mismatches[0].Actual != "" {
+		t.Errorf("expected the missing blob reported, got %+v", v.Mismatches)
+	}
+	if err = os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = RemoveAttachment(a.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the blob deleted with its last attachment, got %v", err)
+	}
+}
+
+func TestBlobsKeptOnRollback(t *testing.T) {
+	BlobDir, StoreBlobs = t.TempDir(), true
+	defer func() {
+		BlobDir, StoreBlobs = "", false
+	}()
+
+	s := New()
+	s.Name = "blob rollback test"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	data := []byte("a recording kept through a failed move")
+	a, err := s.AddAttachment("kept.m4a", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the move fails on the missing second snip after the first was removed, and is rolled back
+	target := filepath.Join(t.TempDir(), "other.sqlite3")
+	if err = MoveToDatabase(target, s.UUID, uuid.New()); err == nil {
+		t.Fatal("expected error moving a missing snip")
+	}
+	got, err := GetAttachmentFromUUID(a.UUID.String())
+	if err != nil || !bytes.Equal(got.Data, data) {
+		t.Errorf("expected the attachment data kept, got %q %v", got.Data, err)
+	}
+
+	// once the move commits the blob is deleted
+	if err = MoveToDatabase(target, s.UUID); err != nil {
+		t.Fatal(err)
+	}
+	path, err := blobPath(Checksum(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the blob deleted after the move, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package snip
+
+import (
+	"strings"
+)
+
+// CodeBlock is a fenced code block of markdown
+type CodeBlock struct {
+	// Lang is the first word of the info string following the opening fence, empty when there is none
+	Lang string
+	// Line is the line number of the opening fence, counting from 1
+	Line int
+	// Code is the content between the fences, ending with a newline unless empty
+	Code string
+}
+
+// CodeBlocks returns the code blocks fenced by ``` or ~~~ in markdown data, in the order they appear.
+// As in CommonMark, a block is closed by a fence of the same character at least as long as the one opening it,
+// or by the end of the data, and the indentation of the opening fence is removed from the lines within.
+func CodeBlocks(data string) []CodeBlock {
+	var blocks []CodeBlock
+	var (
+		open   *CodeBlock
+		fence  string
+		indent int
+		code   strings.Builder
+	)
+	lines := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+	for idx, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if open == nil {
+			var info string
+			if fence, indent, info = openingFence(line); fence != "" {
+				open = &CodeBlock{Lang: strings.ToLower(firstWord(info)), Line: idx + 1}
+				code.Reset()
+			}
+			continue
+		}
+		if closesFence(line, fence) {
+			open.Code = code.String()
+			blocks = append(blocks, *open)
+			open = nil
+			continue
+		}
+		code.WriteString(trimIndent(line, indent))
+		code.WriteByte('\n')
+	}
+	if open != nil {
+		open.Code = code.String()
+		blocks = append(blocks, *open)
+	}
+	return blocks
+}
+
+// openingFence returns the fence opening a code block on line along with its indentation and info string, or an empty fence when there is none
+func openingFence(line string) (string, int, string) {
+	trimmed := strings.TrimLeft(line, " ")
+	indent := len(line) - len(trimmed)
+	if indent > 3 || (!strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~")) {
+		return "", 0, ""
+	}
+	fence := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+	info := strings.TrimSpace(trimmed[len(fence):])
+	// backticks cannot appear in the info string of a backtick fence, which would be inline code instead
+	if fence[0] == '`' && strings.Contains(info, "`") {
+		return "", 0, ""
+	}
+	return fence, indent, info
+}
+
+// closesFence reports whether line is a fence closing a block opened by fence
+func closesFence(line string, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	trimmed = strings.TrimRight(trimmed, " \t")
+	return len(trimmed) >= len(fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// trimIndent removes up to indent leading spaces from line
+func trimIndent(line string, indent int) string {
+	for n := 0; n < indent && strings.HasPrefix(line, " "); n++ {
+		line = line[1:]
+	}
+	return line
+}
+
+// firstWord returns the text of s before any space or tab
+func firstWord(s string) string {
+	if idx := strings.IndexAny(s, " \t"); idx >= 0 {
+		return s[:idx]
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+package snip
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	data := "# Deploy\n" +
+		"Build first.\n" +
+		"```bash\n" +
+		"make build\n" +
+		"\n" +
+		"make test\n" +
+		"```\n" +
+		"Then ship it.\n" +
+		"  ~~~~ Python title=ship.py\n" +
+		"  print('shipped')\n" +
+		"    indented\n" +
+		"  ```\n" +
+		"  ~~~~~\n" +
+		"``` not`code` inline\n" +
+		"```\n" +
+		"unclosed\n"
+	expected := []CodeBlock{
+		{Lang: "bash", Line: 3, Code: "make build\n\nmake test\n"},
+		{Lang: "python", Line: 9, Code: "print('shipped')\n  indented\n```\n"},
+		{Lang: "", Line: 15, Code: "unclosed\n"},
+	}
+	if got := CodeBlocks(data); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if got := CodeBlocks("no code here\n"); len(got) != 0 {
+		t.Errorf("expected no blocks, got %+v", got)
+	}
+	if got := CodeBlocks("```\n```"); !reflect.DeepEqual(got, []CodeBlock{{Line: 1}}) {
+		t.Errorf("expected one empty block, got %+v", got)
+	}
+}
@@ -0,0 +1,183 @@
+package snip
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/kljensen/snowball"
+	"github.com/ryanfrishkorn/snip/database"
+	"strings"
+	"sync"
+)
+
+// FieldBoost weighs search terms found in the name and tags of a snip against those found in its data, whose score is at most 1
+type FieldBoost struct {
+	Name float64
+	Tags float64
+}
+
+// SearchBoost is the FieldBoost of index searches, so that a snip named or tagged with the search terms ranks above passing mentions in others
+var SearchBoost = FieldBoost{Name: 2, Tags: 1}
+
+// Validate returns an error if a weight is negative, which would rank snips lower for being named or tagged with the search terms
+func (b FieldBoost) Validate() error {
+	if b.Name < 0 || b.Tags < 0 {
+		return fmt.Errorf("boost weights must not be negative, got name %g and tags %g", b.Name, b.Tags)
+	}
+	return nil
+}
+
+// FieldMatches are the search terms found in the name and tags of a snip
+type FieldMatches struct {
+	Name []string `json:",omitempty"`
+	Tags []string `json:",omitempty"`
+}
+
+// has reports whether term was found in the name or tags
+func (f FieldMatches) has(term string) bool {
+	for _, list := range [][]string{f.Name, f.Tags} {
+		for _, t := range list {
+			if t == term {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fieldNamesQuery selects the names of unexpired snips, taking sortableNow as its parameter
+const fieldNamesQuery = `SELECT uuid, name FROM snip WHERE ` + unexpired
+
+// fieldTagsQuery selects the tags of every snip
+const fieldTagsQuery = `SELECT uuid, tag FROM snip_tag`
+
+var (
+	fieldStemsMu sync.Mutex
+	// fieldStems holds the stem of each word found in names and tags, which never changes, so that each word is stemmed once
+	// rather than on every search
+	fieldStems = make(map[string]string)
+)
+
+// fieldStem returns the stem of word, stemming it only the first time it is asked for
+func fieldStem(word string) (string, error) {
+	fieldStemsMu.Lock()
+	defer fieldStemsMu.Unlock()
+	if stem, ok := fieldStems[word]; ok {
+		return stem, nil
+	}
+	stem, err := snowball.Stem(word, "english", true)
+	if err != nil {
+		return "", err
+	}
+	fieldStems[word] = stem
+	return stem, nil
+}
+
+// fieldTerm is a search term with the stem it is matched by, unless it is a wildcard matched against words as they are
+type fieldTerm struct {
+	term string
+	stem string
+}
+
+// matches reports whether the term matches any of the words of text
+func (f fieldTerm) matches(words []string) bool {
+	for _, word := range words {
+		if isWildcard(f.term) {
+			if wildcardMatch(f.term, word) {
+				return true
+			}
+			continue
+		}
+		stem, err := fieldStem(word)
+		if err == nil && stem == f.stem {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFields returns the search terms found in the names and tags of unexpired snips, by the uuid of each snip with any
+func matchFields(terms []string) (map[uuid.UUID]FieldMatches, error) {
+	results := make(map[uuid.UUID]FieldMatches)
+	fieldTerms := make([]fieldTerm, 0, len(terms))
+	for _, term := range terms {
+		f := fieldTerm{term: term}
+		if !isWildcard(term) {
+			stem, err := snowball.Stem(term, "english", true)
+			if err != nil {
+				return results, err
+			}
+			f.stem = stem
+		}
+		fieldTerms = append(fieldTerms, f)
+	}
+
+	names := make(map[uuid.UUID]string)
+	err := readFieldRows(fieldNamesQuery, []interface{}{sortableNow()}, func(id uuid.UUID, name string) {
+		names[id] = name
+	})
+	if err != nil {
+		return results, fmt.Errorf("reading names: %w", err)
+	}
+	// tags of expired snips are left out with their names
+	tags := make(map[uuid.UUID][]string)
+	err = readFieldRows(fieldTagsQuery, nil, func(id uuid.UUID, tag string) {
+		if _, ok := names[id]; ok {
+			tags[id] = append(tags[id], tag)
+		}
+	})
+	if err != nil {
+		return results, fmt.Errorf("reading tags: %w", err)
+	}
+
+	// the words of names and tags are split as the data of snips is
+	for id, name := range names {
+		var m FieldMatches
+		nameWords := DownCase(IndexTokenizer.Tokens(name))
+		tagWords := DownCase(IndexTokenizer.Tokens(strings.Join(tags[id], " ")))
+		for _, f := range fieldTerms {
+			if f.matches(nameWords) {
+				m.Name = append(m.Name, f.term)
+			}
+			if f.matches(tagWords) {
+				m.Tags = append(m.Tags, f.term)
+			}
+		}
+		if m.Name != nil || m.Tags != nil {
+			results[id] = m
+		}
+	}
+	return results, nil
+}
+
+// readFieldRows calls row with the uuid and text of each row selected by query
+func readFieldRows(query string, args []interface{}, row func(id uuid.UUID, text string)) error {
+	stmt, err := database.Prepare(query, args...)
+	if err != nil {
+		return err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			return nil
+		}
+		var id uuid.UUID
+		var text string
+		if err = database.Scan(stmt, &id, &text); err != nil {
+			return err
+		}
+		row(id, text)
+	}
+}
+
+// boostScore returns the part of the score of a search result given by the terms found in its name and tags
+func boostScore(terms []string, fields FieldMatches) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	total := float64(len(terms))
+	return SearchBoost.Name*float64(len(fields.Name))/total + SearchBoost.Tags*float64(len(fields.Tags))/total
+}
@@ -0,0 +1,63 @@
+package snip
+
+import (
+	"testing"
+)
+
+func TestSearchBoost(t *testing.T) {
+	add := func(name string, data string, tags ...string) Snip {
+		t.Helper()
+		s := New()
+		s.Data = data
+		if err := InsertSnip(s, WithName(name), WithTags(tags...)); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { Remove(s.UUID) })
+		if err := s.Index(); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	mentioned := add("weekly notes", "quokkadb backup finished, quokkadb backups rotate weekly")
+	named := add("quokkadb backup", "run the dump nightly and copy it offsite")
+	tagged := add("restore steps", "copy the quokkadb dump back and restart", "backup")
+	add("unrelated", "nothing about databases here")
+
+	scores, err := Search([]string{"quokkadb", "backup"}, 0)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(scores) != 3 {
+		t.Fatalf("expected 3 results, got %+v", scores)
+	}
+	for idx, expected := range []Snip{named, tagged, mentioned} {
+		if scores[idx].UUID != expected.UUID {
+			t.Errorf("expected %s at %d, got %+v", expected.Name, idx, scores)
+		}
+	}
+	if len(scores[0].Fields.Name) != 2 || len(scores[1].Fields.Tags) != 1 || scores[1].Fields.Tags[0] != "backup" {
+		t.Errorf("expected the fields matched in the name and tags, got %+v and %+v", scores[0].Fields, scores[1].Fields)
+	}
+	// the words of names and tags are stemmed once and kept for later searches
+	fieldStemsMu.Lock()
+	stem := fieldStems["backup"]
+	fieldStemsMu.Unlock()
+	if stem != "backup" {
+		t.Errorf("expected the stem of a name kept, got %q", stem)
+	}
+
+	// without boosts only the data is scored
+	defer func(b FieldBoost) { SearchBoost = b }(SearchBoost)
+	SearchBoost = FieldBoost{}
+	scores, err = Search([]string{"quokkadb", "backup"}, 0)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(scores) != 3 || scores[0].UUID != mentioned.UUID {
+		t.Errorf("expected the snip mentioning both terms first without boosts, got %+v", scores)
+	}
+
+	if err = (FieldBoost{Name: -1}).Validate(); err == nil {
+		t.Errorf("expected error for negative weight")
+	}
+}
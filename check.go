@@ -0,0 +1,213 @@
+package snip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrCheckerMissing is returned when the program run by a checker is not installed
+var ErrCheckerMissing = errors.New("is not installed")
+
+// Checker is a command finding problems in snips, such as a spell checker or a linter
+type Checker struct {
+	Name string
+	// Command checks the file $SNIP_FILE with sh, writing problems as lines of the form $SNIP_FILE:line:column: message,
+	// where the column may be left out. The language checked is given as $SNIP_LANG.
+	Command string
+	// Langs are the languages of the scripts and fenced code blocks checked, prose and markdown are checked if empty
+	Langs []string
+	// Words is set for a command writing one misspelled word per line, which is found on the lines of the snip
+	Words bool
+}
+
+// DefaultCheckers spell check prose with aspell, lint markdown with markdownlint, and lint shell scripts and blocks with shellcheck
+var DefaultCheckers = []Checker{
+	{Name: "spell", Command: `aspell list --mode=markdown < "$SNIP_FILE"`, Words: true},
+	{Name: "markdownlint", Command: `markdownlint "$SNIP_FILE"`},
+	{Name: "shellcheck", Command: `shellcheck -f gcc -s "$SNIP_LANG" "$SNIP_FILE"`, Langs: []string{"bash", "sh"}},
+}
+
+// Finding is a problem reported by a checker
+type Finding struct {
+	Checker string
+	// Line is the line of the snip counting from 1, or 0 when the problem is not on a line
+	Line int
+	// Column is the column on the line counting from 1, or 0 when it was not reported
+	Column  int
+	Message string
+}
+
+// checkedText is a part of the data of a snip given to a checker, the whole data or a fenced code block
+type checkedText struct {
+	Lang string
+	// Offset is the number of lines of the snip before the text
+	Offset int
+	Text   string
+}
+
+// checks reports whether the checker looks at code in lang
+func (c Checker) checks(lang string) bool {
+	for _, l := range c.Langs {
+		if strings.EqualFold(l, lang) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check runs each checker over the data of s, whose language was detected as lang, returning the problems found in order of line.
+// Checkers without languages look at prose and markdown, while the others look at a snip that is a script in one of their languages,
+// or otherwise at its fenced code blocks in them. A checker that cannot be run is skipped, and the errors of those that could not be
+// are returned along with the problems that were found.
+func Check(s Snip, lang string, checkers []Checker) ([]Finding, error) {
+	script := lang != "" && len(CodeBlocks(s.Data)) == 0
+	var findings []Finding
+	var errs []error
+	for _, c := range checkers {
+		var texts []checkedText
+		switch {
+		case len(c.Langs) == 0:
+			if !script {
+				texts = append(texts, checkedText{Text: s.Data})
+			}
+		case script:
+			if c.checks(lang) {
+				texts = append(texts, checkedText{Lang: lang, Text: s.Data})
+			}
+		default:
+			for _, b := range CodeBlocks(s.Data) {
+				if c.checks(b.Lang) {
+					texts = append(texts, checkedText{Lang: b.Lang, Offset: b.Line, Text: b.Code})
+				}
+			}
+		}
+
+		for _, t := range texts {
+			found, err := c.run(s, t)
+			if errors.Is(err, ErrCheckerMissing) {
+				errs = append(errs, fmt.Errorf("%s %w", c.Name, err))
+				break
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s could not be run: %w", c.Name, err))
+				break
+			}
+			findings = append(findings, found...)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Column < findings[j].Column
+	})
+	return findings, errors.Join(errs...)
+}
+
+// run writes text to a temporary file and checks it, returning the problems reported on the lines of the snip
+func (c Checker) run(s Snip, t checkedText) ([]Finding, error) {
+	dir, err := os.MkdirTemp("", "snip-check-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	ext := ".md"
+	if t.Lang != "" {
+		ext = "." + t.Lang
+	}
+	file := filepath.Join(dir, "snip"+ext)
+	if err = os.WriteFile(file, []byte(t.Text), 0600); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", c.Command)
+	cmd.Env = append(os.Environ(),
+		"SNIP_FILE="+file,
+		"SNIP_LANG="+t.Lang,
+		"SNIP_UUID="+s.UUID.String(),
+		"SNIP_NAME="+s.Name,
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// linters exit with a failure when they find problems, so the status only matters when nothing was reported
+	runErr := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(runErr, &exitErr) && exitErr.ExitCode() == 127 {
+		return nil, fmt.Errorf("%w (%s)", ErrCheckerMissing, strings.TrimSpace(stderr.String()))
+	}
+	if runErr != nil && !errors.As(runErr, &exitErr) {
+		return nil, runErr
+	}
+
+	var findings []Finding
+	if c.Words {
+		findings = findWords(c.Name, t, stdout.String())
+	} else {
+		// some linters such as markdownlint report on standard error
+		findings = parseFindings(c.Name, t, file, stdout.String()+stderr.String())
+	}
+	if runErr != nil && len(findings) == 0 {
+		return nil, fmt.Errorf("%q: %w: %s", c.Command, runErr, strings.TrimSpace(stderr.String()))
+	}
+	return findings, nil
+}
+
+// findingPosition is the line and optional column following the file name in the output of a checker
+var findingPosition = regexp.MustCompile(`^(\d+)(?::(\d+))?:?\s*(.*)$`)
+
+// parseFindings reads the problems written by a checker of file as lines starting with its name, ignoring any other output
+func parseFindings(checker string, t checkedText, file string, output string) []Finding {
+	var findings []Finding
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, file+":") {
+			continue
+		}
+		match := findingPosition.FindStringSubmatch(strings.TrimPrefix(line, file+":"))
+		if match == nil {
+			continue
+		}
+		f := Finding{Checker: checker, Message: match[3]}
+		f.Line, _ = strconv.Atoi(match[1])
+		f.Column, _ = strconv.Atoi(match[2])
+		if f.Line > 0 {
+			f.Line += t.Offset
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// findWords locates each word written by a checker on the lines of the text, reporting every line a word is found on
+func findWords(checker string, t checkedText, output string) []Finding {
+	var findings []Finding
+	lines := strings.Split(t.Text, "\n")
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(output) {
+		// spell checkers list a word for each time it is misspelled
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(word) + `\b`)
+		found := false
+		for idx, line := range lines {
+			if loc := pattern.FindStringIndex(line); loc != nil {
+				findings = append(findings, Finding{Checker: checker, Line: idx + 1 + t.Offset, Column: loc[0] + 1, Message: word})
+				found = true
+			}
+		}
+		if !found {
+			findings = append(findings, Finding{Checker: checker, Message: word})
+		}
+	}
+	return findings
+}
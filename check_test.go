@@ -0,0 +1,46 @@
+package snip
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	s := New()
+	s.Name = "check test"
+	s.Data = "Restart the servise.\n\n```bash\nrm $dir/*\n```\n"
+	checkers := []Checker{
+		{Name: "spell", Command: `grep -o servise "$SNIP_FILE"`, Words: true},
+		{Name: "lint", Command: `echo "$SNIP_FILE:1:4: $SNIP_LANG quote"; exit 1`, Langs: []string{"bash"}},
+		{Name: "missing", Command: `snip-no-such-checker "$SNIP_FILE"`},
+	}
+	findings, err := Check(s, "bash", checkers)
+	if !errors.Is(err, ErrCheckerMissing) {
+		t.Errorf("expected ErrCheckerMissing, got %v", err)
+	}
+	expected := []Finding{
+		{Checker: "spell", Line: 1, Column: 13, Message: "servise"},
+		{Checker: "lint", Line: 4, Column: 4, Message: "bash quote"},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, findings)
+	}
+	for idx, f := range findings {
+		if f != expected[idx] {
+			t.Errorf("expected %v, got %v", expected[idx], f)
+		}
+	}
+
+	// a script is checked whole by the checkers of its language and not as prose
+	s.Data = "#!/bin/bash\nrm $dir/*\n"
+	findings, err = Check(s, "bash", checkers[:2])
+	if err != nil || len(findings) != 1 || findings[0].Line != 1 || findings[0].Checker != "lint" {
+		t.Errorf("expected one finding of lint on line 1, got %v %v", findings, err)
+	}
+
+	// a checker failing without reporting anything is an error
+	_, err = Check(s, "bash", []Checker{{Name: "broken", Command: "exit 2", Langs: []string{"bash"}}})
+	if err == nil || errors.Is(err, ErrCheckerMissing) {
+		t.Errorf("expected an error running the checker, got %v", err)
+	}
+}
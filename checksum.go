@@ -0,0 +1,222 @@
+package snip
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"os"
+)
+
+// Checksum returns the hex encoded sha-256 digest of data
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SetChecksum records the digest of the data stored under id
+func SetChecksum(id uuid.UUID, sum string) error {
+	return database.Exec(`INSERT OR REPLACE INTO snip_checksum (uuid, sha256) VALUES (?, ?)`, id.String(), sum)
+}
+
+// RemoveChecksum deletes the recorded digest of id
+func RemoveChecksum(id uuid.UUID) error {
+	return database.Exec(`DELETE FROM snip_checksum WHERE uuid = ?`, id.String())
+}
+
+// SetAttachmentChecksum records the digest of the data stored under attachment id
+func SetAttachmentChecksum(id uuid.UUID, sum string) error {
+	return database.Exec(`INSERT OR REPLACE INTO snip_attachment_checksum (uuid, sha256) VALUES (?, ?)`, id.String(), sum)
+}
+
+// RemoveAttachmentChecksum deletes the recorded digest of attachment id
+func RemoveAttachmentChecksum(id uuid.UUID) error {
+	return database.Exec(`DELETE FROM snip_attachment_checksum WHERE uuid = ?`, id.String())
+}
+
+// GetUUIDByChecksum returns the uuid of a snip whose data has the digest sum, or uuid.Nil if none exists
+func GetUUIDByChecksum(sum string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := database.QueryRow(`SELECT snip_checksum.uuid FROM snip_checksum JOIN snip ON snip.uuid = snip_checksum.uuid WHERE sha256 = ? LIMIT 1`, []interface{}{sum}, &id)
+	if errors.Is(err, database.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	return id, err
+}
+
+// ChecksumMismatch describes stored data that no longer matches the digest recorded when it was written
+type ChecksumMismatch struct {
+	UUID uuid.UUID
+	// SnipUUID is the snip an attachment belongs to, or the snip itself
+	SnipUUID   uuid.UUID
+	Attachment bool
+	// Expected is empty when no digest was recorded
+	Expected string
+	// Actual is empty when the blob holding the data of an attachment is missing
+	Actual string
+}
+
+// Verification summarizes the data checked by Verify
+type Verification struct {
+	Snips       int
+	Attachments int
+	Mismatches  []ChecksumMismatch
+}
+
+// Verify compares the data of the snips in ids and their attachments with their recorded digests, verifying every snip when ids is empty.
+// Each snip, or each row when verifying everything, is reported to progress.
+func Verify(progress Progress, ids ...uuid.UUID) (Verification, error) {
+	var v Verification
+	snipQuery := `SELECT snip.uuid, snip.uuid, snip.data, snip_checksum.sha256, NULL FROM snip LEFT JOIN snip_checksum ON snip_checksum.uuid = snip.uuid`
+	attachmentQuery := `SELECT a.uuid, a.snip_uuid, a.data, c.sha256, b.sha256 FROM snip_attachment a LEFT JOIN snip_attachment_checksum c ON c.uuid = a.uuid ` +
+		`LEFT JOIN snip_attachment_blob b ON b.uuid = a.uuid`
+	if len(ids) == 0 {
+		var total, done int
+		err := database.QueryRow(`SELECT (SELECT COUNT(*) FROM snip) + (SELECT COUNT(*) FROM snip_attachment)`, nil, &total)
+		if err != nil {
+			return v, err
+		}
+		step := func() {
+			done++
+			reportStep(progress, "verifying", done, total)
+		}
+		n, err := verifyRows(&v, false, step, snipQuery)
+		if err != nil {
+			return v, err
+		}
+		v.Snips += n
+		n, err = verifyRows(&v, true, step, attachmentQuery)
+		if err != nil {
+			return v, err
+		}
+		v.Attachments += n
+		return v, nil
+	}
+
+	for idx, id := range ids {
+		n, err := verifyRows(&v, false, nil, snipQuery+` WHERE snip.uuid = ?`, id.String())
+		if err != nil {
+			return v, err
+		}
+		if n == 0 {
+			return v, fmt.Errorf("snip %s does not exist", id)
+		}
+		v.Snips += n
+		n, err = verifyRows(&v, true, nil, attachmentQuery+` WHERE a.snip_uuid = ?`, id.String())
+		if err != nil {
+			return v, err
+		}
+		v.Attachments += n
+		reportStep(progress, "verifying", idx+1, len(ids))
+	}
+	return v, nil
+}
+
+// verifyRows hashes the data of each row of uuid, snip uuid, data, digest, and blob digest returned by query, adding mismatches to v and returning
+// the number of rows. The data of rows with a blob digest is read from the blob.
+// Step is called for each row when it is not nil.
+func verifyRows(v *Verification, attachment bool, step func(), query string, args ...interface{}) (int, error) {
+	stmt, err := database.Conn.Prepare(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	count := 0
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return count, err
+		}
+		if !hasRow {
+			break
+		}
+		count++
+		if step != nil {
+			step()
+		}
+
+		var idStr, snipIDStr, expected, blob string
+		var data []byte
+		err = stmt.Scan(&idStr, &snipIDStr, &data, &expected, &blob)
+		if err != nil {
+			return count, err
+		}
+		var actual string
+		if blob != "" {
+			data, err = readBlob(blob)
+		}
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// a missing blob leaves actual empty
+		case err != nil:
+			return count, err
+		default:
+			actual = Checksum(data)
+		}
+		if actual == expected {
+			continue
+		}
+		m := ChecksumMismatch{Attachment: attachment, Expected: expected, Actual: actual}
+		m.UUID, err = uuid.Parse(idStr)
+		if err != nil {
+			return count, err
+		}
+		m.SnipUUID, err = uuid.Parse(snipIDStr)
+		if err != nil {
+			return count, err
+		}
+		v.Mismatches = append(v.Mismatches, m)
+	}
+	return count, nil
+}
+
+// backfillChecksums records digests of snips and attachments stored before checksums were kept
+func backfillChecksums() error {
+	err := backfillTable(`SELECT uuid, data FROM snip WHERE uuid NOT IN (SELECT uuid FROM snip_checksum)`, SetChecksum)
+	if err != nil {
+		return err
+	}
+	return backfillTable(`SELECT uuid, data FROM snip_attachment WHERE uuid NOT IN (SELECT uuid FROM snip_attachment_checksum)`, SetAttachmentChecksum)
+}
+
+// backfillTable records the digest of each row of uuid and data returned by query using set
+func backfillTable(query string, set func(uuid.UUID, string) error) error {
+	stmt, err := database.Conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	sums := make(map[uuid.UUID]string)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr string
+		var data []byte
+		err = stmt.Scan(&idStr, &data)
+		if err != nil {
+			return err
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return err
+		}
+		sums[id] = Checksum(data)
+	}
+
+	for id, sum := range sums {
+		err = set(id, sum)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package snip
+
+import (
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	s := New()
+	s.Data = "checksum test data"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	id, err := GetUUIDByChecksum(Checksum([]byte(s.Data)))
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if id != s.UUID {
+		t.Errorf("expected %s, got %s", s.UUID, id)
+	}
+
+	// the digest follows changes to the data
+	previous := Checksum([]byte(s.Data))
+	s.Data = "checksum test data, edited"
+	if err = s.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ = GetUUIDByChecksum(previous); id != uuid.Nil {
+		t.Errorf("expected no snip with the previous digest, got %s", id)
+	}
+	if id, _ = GetUUIDByChecksum(Checksum([]byte(s.Data))); id != s.UUID {
+		t.Errorf("expected %s for the edited digest, got %s", s.UUID, id)
+	}
+
+	if err = Remove(s.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ = GetUUIDByChecksum(Checksum([]byte(s.Data))); id != uuid.Nil {
+		t.Errorf("expected no snip after removal, got %s", id)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	s := New()
+	s.Data = "verify test data"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	a, err := s.AddAttachment("verify.txt", []byte("verify attachment"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := Verify(nil, s.UUID)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if v.Snips != 1 || v.Attachments != 1 || len(v.Mismatches) != 0 {
+		t.Errorf("expected 1 snip and 1 attachment without mismatches, got %+v", v)
+	}
+
+	// alter the stored data without going through Update
+	err = database.Conn.Exec(`UPDATE snip_attachment SET data = ? WHERE uuid = ?`, []byte("altered"), a.UUID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = Verify(nil, s.UUID)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(v.Mismatches) != 1 {
+		t.Fatalf("expected 1 mismatch, got %+v", v.Mismatches)
+	}
+	m := v.Mismatches[0]
+	if !m.Attachment || m.UUID != a.UUID || m.SnipUUID != s.UUID || m.Actual != Checksum([]byte("altered")) {
+		t.Errorf("unexpected mismatch %+v", m)
+	}
+
+	if _, err = Verify(nil, uuid.New()); err == nil {
+		t.Error("expected error verifying a snip that does not exist")
+	}
+}
+
+func TestChecksumBackfill(t *testing.T) {
+	s := New()
+	s.Data = "checksum backfill data"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	// digests are not backfilled once their table exists, and verify reports the missing one
+	if err := RemoveChecksum(s.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateNewDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	v, err := Verify(nil, s.UUID)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(v.Mismatches) != 1 || v.Mismatches[0].Expected != "" || v.Mismatches[0].Actual != Checksum([]byte(s.Data)) {
+		t.Errorf("expected the missing digest reported, got %+v", v.Mismatches)
+	}
+
+	// a database from before checksums were kept has them backfilled when the table is created
+	if err = database.Conn.Exec(`DROP TABLE snip_checksum`); err != nil {
+		t.Fatal(err)
+	}
+	if err = CreateNewDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := GetUUIDByChecksum(Checksum([]byte(s.Data))); id != s.UUID {
+		t.Errorf("expected the digest backfilled for %s, got %s", s.UUID, id)
+	}
+}
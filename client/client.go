@@ -0,0 +1,183 @@
+// Package client implements snip.Store against the json api of snip serve or snip daemon
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is a snip.Store backed by a remote server
+type Client struct {
+	// HTTP is used for all requests and may be replaced, for example to dial a unix socket
+	HTTP    *http.Client
+	baseURL string
+	token   string
+}
+
+// ErrNotFound is wrapped by the errors of requests for snips, attachments, or shares the server does not have
+var ErrNotFound = errors.New("not found")
+
+// check that Client stays interchangeable with the local database
+var _ snip.Store = (*Client)(nil)
+
+// New returns a Client for the server at baseURL, sending token as a bearer credential when it is not empty
+func New(baseURL string, token string) *Client {
+	return &Client{
+		HTTP:    &http.Client{Timeout: 30 * time.Second},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+	}
+}
+
+// List returns the metadata of snips without their data
+func (c *Client) List(limit int) ([]snip.Snip, error) {
+	var snips []snip.Snip
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	err := c.do(http.MethodGet, "/snips", query, nil, "", &snips)
+	return snips, err
+}
+
+// ListPage returns a page of snips selected and ordered by opts, and the cursor of the next page, which is empty on the last page.
+// The owner of opts is not sent, since the server lists the snips of the owner of the token unless it belongs to an admin.
+func (c *Client) ListPage(opts snip.ListOptions) ([]snip.Snip, string, error) {
+	query := url.Values{}
+	for name, value := range map[string]string{"sort": opts.Sort, "tag": opts.Tag, "cursor": opts.Cursor} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.Format(time.RFC3339Nano))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.Format(time.RFC3339Nano))
+	}
+	if opts.Data {
+		query.Set("fields", "uuid,name,timestamp,tags,data")
+	}
+
+	var snips []snip.Snip
+	header, err := c.doHeader(http.MethodGet, "/snips", query, nil, "", &snips)
+	if err != nil {
+		return snips, "", err
+	}
+	return snips, nextCursor(header.Get("Link")), nil
+}
+
+// nextCursor returns the cursor of the page linked as next in a Link header, or an empty string when there is none
+func nextCursor(link string) string {
+	target, params, ok := strings.Cut(link, ";")
+	if !ok || !strings.Contains(params, `rel="next"`) {
+		return ""
+	}
+	u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("cursor")
+}
+
+// Get returns a single snip by full or partial uuid
+func (c *Client) Get(id string) (snip.Snip, error) {
+	var s snip.Snip
+	err := c.do(http.MethodGet, "/snips/"+url.PathEscape(id), nil, nil, "", &s)
+	return s, err
+}
+
+// Search returns scored index search results with context
+func (c *Client) Search(terms []string, limit int, adjacent int) ([]snip.SearchMatch, error) {
+	var matches []snip.SearchMatch
+	query := url.Values{
+		"q":       terms,
+		"limit":   {strconv.Itoa(limit)},
+		"context": {strconv.Itoa(adjacent)},
+	}
+	err := c.do(http.MethodGet, "/search", query, nil, "", &matches)
+	return matches, err
+}
+
+// Insert stores and indexes a new snip along with its metadata
+func (c *Client) Insert(s snip.Snip) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodPost, "/snips", nil, bytes.NewReader(body), "application/json", nil)
+}
+
+// Remove deletes a snip and everything associated with it
+func (c *Client) Remove(id uuid.UUID) error {
+	return c.do(http.MethodDelete, "/snips/"+id.String(), nil, nil, "", nil)
+}
+
+// Attach adds data to a snip and returns the stored attachment
+func (c *Client) Attach(id uuid.UUID, name string, data []byte) (snip.Attachment, error) {
+	var a snip.Attachment
+	query := url.Values{"name": {name}}
+	err := c.do(http.MethodPost, "/snips/"+id.String()+"/attachments", query, bytes.NewReader(data), "application/octet-stream", &a)
+	return a, err
+}
+
+// GetAttachment returns a single attachment including its data by full or partial uuid
+func (c *Client) GetAttachment(id string) (snip.Attachment, error) {
+	var a snip.Attachment
+	err := c.do(http.MethodGet, "/attachments/"+url.PathEscape(id), nil, nil, "", &a)
+	return a, err
+}
+
+// do sends a request and decodes the json response into v unless v is nil
+func (c *Client) do(method string, path string, query url.Values, body io.Reader, contentType string, v any) error {
+	_, err := c.doHeader(method, path, query, body, contentType, v)
+	return err
+}
+
+// doHeader sends a request as do does, returning the header of the response
+func (c *Client) doHeader(method string, path string, query url.Values, body io.Reader, contentType string, v any) (http.Header, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = query.Encode()
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&failure); err != nil || failure.Error == "" {
+			return resp.Header, fmt.Errorf("server returned status %s", resp.Status)
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return resp.Header, fmt.Errorf("%w: %s", ErrNotFound, failure.Error)
+		}
+		return resp.Header, errors.New(failure.Error)
+	}
+	if v == nil {
+		return resp.Header, nil
+	}
+	return resp.Header, json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"github.com/ryanfrishkorn/snip/server"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "snip-client-test")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating temporary directory: %v", err)
+		os.Exit(1)
+	}
+
+	database.Conn, err = sqlite3.Open(filepath.Join(dir, "test.sqlite3"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening sqlite test database: %v", err)
+		os.Exit(1)
+	}
+	err = snip.CreateNewDatabase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating test database schema: %v", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	database.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestClient(t *testing.T) {
+	ts := httptest.NewServer(server.RequireToken(server.New(), "secret"))
+	defer ts.Close()
+	c := New(ts.URL, "secret")
+
+	s := snip.New()
+	s.Name = "Client Test"
+	s.Data = "remote snips behave like local ones"
+	s.Meta = map[string]string{"source": "client"}
+	if err := c.Insert(s); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	got, err := c.Get(s.UUID.String()[:8])
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if got.Data != s.Data || got.Meta["source"] != "client" {
+		t.Errorf("expected inserted snip, got %+v", got)
+	}
+
+	matches, err := c.Search([]string{"remote"}, 0, 6)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(matches) != 1 || matches[0].UUID != s.UUID {
+		t.Errorf("expected one match for the inserted snip, got %+v", matches)
+	}
+
+	data := []byte{0, 1, 2, 3}
+	a, err := c.Attach(s.UUID, "bytes.bin", data)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	attached, err := c.GetAttachment(a.UUID.String())
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !bytes.Equal(attached.Data, data) || attached.Name != "bytes.bin" {
+		t.Errorf("expected attachment bytes.bin with data %v, got %+v", data, attached)
+	}
+
+	if err = c.Remove(s.UUID); err != nil {
+		t.Fatalf("%v", err)
+	}
+	snips, err := c.List(0)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(snips) != 0 {
+		t.Errorf("expected no snips after removal, got %d", len(snips))
+	}
+	if _, err = c.Get(s.UUID.String()); err == nil {
+		t.Errorf("expected error getting removed snip")
+	}
+}
+
+func TestClientListPage(t *testing.T) {
+	ts := httptest.NewServer(server.RequireToken(server.New(), "secret"))
+	defer ts.Close()
+	c := New(ts.URL, "secret")
+
+	for _, name := range []string{"charlie", "alpha", "bravo"} {
+		s := snip.New()
+		s.Name = name
+		s.Data = name + " data"
+		if err := c.Insert(s); err != nil {
+			t.Fatalf("%v", err)
+		}
+		defer c.Remove(s.UUID)
+	}
+
+	var names []string
+	opts := snip.ListOptions{Sort: "name", Limit: 2, Data: true}
+	for pages := 0; pages == 0 || opts.Cursor != ""; pages++ {
+		if pages > 2 {
+			t.Fatalf("expected two pages, got more")
+		}
+		snips, next, err := c.ListPage(opts)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		for _, s := range snips {
+			if s.Data != s.Name+" data" {
+				t.Errorf("expected the data of %s, got %q", s.Name, s.Data)
+			}
+			names = append(names, s.Name)
+		}
+		opts.Cursor = next
+	}
+	if strings.Join(names, ",") != "alpha,bravo,charlie" {
+		t.Errorf("expected the snips in order of name across pages, got %v", names)
+	}
+}
+
+func TestClientSubscribe(t *testing.T) {
+	interval := snip.EventPollInterval
+	snip.EventPollInterval = 10 * time.Millisecond
+	defer func() { snip.EventPollInterval = interval }()
+
+	ts := httptest.NewServer(server.RequireToken(server.New(), "secret"))
+	defer ts.Close()
+	c := New(ts.URL, "secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := c.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	s := snip.New()
+	s.Data = "watched from afar"
+	if err = c.Insert(s); err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer c.Remove(s.UUID)
+
+	select {
+	case e := <-events:
+		if e.Type != snip.EventCreate || e.UUID != s.UUID {
+			t.Errorf("expected a create event for %s, got %+v", s.UUID, e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an event for the inserted snip")
+	}
+
+	// the channel is closed once the subscription is cancelled
+	cancel()
+	for range events {
+	}
+
+	if _, err = New(ts.URL, "wrong").Subscribe(context.Background()); err == nil {
+		t.Errorf("expected error subscribing without the token")
+	}
+}
+
+func TestClientUnauthorized(t *testing.T) {
+	ts := httptest.NewServer(server.RequireToken(server.New(), "secret"))
+	defer ts.Close()
+
+	_, err := New(ts.URL, "wrong").List(0)
+	if err == nil || err.Error() != "unauthorized" {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+}
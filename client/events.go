@@ -0,0 +1,107 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReconnectDelay is how long Subscribe waits before reconnecting after the event stream is cut off
+var ReconnectDelay = 3 * time.Second
+
+// Subscribe returns a channel receiving the events that occur after subscribing, closed once ctx is done.
+// The stream is reconnected when it is cut off, resuming after the last event received.
+func (c *Client) Subscribe(ctx context.Context) (<-chan snip.Event, error) {
+	resp, err := c.openEvents(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan snip.Event, 64)
+	go func() {
+		defer close(events)
+		var lastID int64
+		for {
+			lastID = readEvents(ctx, resp, events, lastID)
+			resp.Body.Close()
+			for {
+				select {
+				case <-time.After(ReconnectDelay):
+				case <-ctx.Done():
+					return
+				}
+				if resp, err = c.openEvents(ctx, lastID); err == nil {
+					break
+				}
+				log.Debug().Err(err).Msg("error reconnecting to event stream")
+			}
+		}
+	}()
+	return events, nil
+}
+
+// openEvents requests the event stream, resuming after lastID when it is not zero
+func (c *Client) openEvents(ctx context.Context, lastID int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(lastID, 10))
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	// the stream is open for as long as the subscription, so the timeout of requests does not apply
+	stream := *c.HTTP
+	stream.Timeout = 0
+	resp, err := stream.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("server returned status %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// readEvents sends the events of a server-sent event stream to events until it ends, returning the id of the last one sent
+func readEvents(ctx context.Context, resp *http.Response, events chan<- snip.Event, lastID int64) int64 {
+	scanner := bufio.NewScanner(resp.Body)
+	var data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "data: "); ok {
+			data = value
+			continue
+		}
+		// a blank line ends each event, while comments and other fields are ignored
+		if line != "" || data == "" {
+			continue
+		}
+		var e snip.Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			log.Debug().Err(err).Msg("error decoding event")
+			data = ""
+			continue
+		}
+		data = ""
+		select {
+		case events <- e:
+			lastID = e.ID
+		case <-ctx.Done():
+			return lastID
+		}
+	}
+	return lastID
+}
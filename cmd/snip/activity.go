@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// heatmapWeeks is the number of weeks shown by the activity heatmap, covering the last year
+const heatmapWeeks = 53
+
+// heatmapLevels are the cells of the heatmap from no activity to the busiest days
+var heatmapLevels = []string{"·", "░", "▒", "▓", "█"}
+
+// dayKey identifies the local day of t
+func dayKey(t time.Time) string {
+	return t.Local().Format("2006-01-02")
+}
+
+// heatmapStart returns the sunday beginning the first week shown by a heatmap ending on the day of end
+func heatmapStart(end time.Time) time.Time {
+	end = end.Local()
+	day := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.Local)
+	return day.AddDate(0, 0, -int(day.Weekday())-(heatmapWeeks-1)*7)
+}
+
+// writeHeatmap draws the number of changes on each day as a grid of weeks ending on the day of end, followed by a summary
+func writeHeatmap(w io.Writer, times []time.Time, end time.Time) {
+	start := heatmapStart(end)
+	counts := make(map[string]int)
+	total, busiest := 0, 0
+	for _, t := range times {
+		if t.Before(start) || t.After(end) {
+			continue
+		}
+		key := dayKey(t)
+		counts[key]++
+		total++
+		if counts[key] > busiest {
+			busiest = counts[key]
+		}
+	}
+
+	// month names are placed above the first week starting in each month, where they fit
+	labels := []rune(strings.Repeat(" ", heatmapWeeks+3))
+	for week := 0; week < heatmapWeeks; week++ {
+		day := start.AddDate(0, 0, week*7)
+		if day.Day() > 7 || (week > 0 && labels[week-1] != ' ') {
+			continue
+		}
+		copy(labels[week:], []rune(day.Format("Jan")))
+	}
+	fmt.Fprintf(w, "    %s\n", strings.TrimRight(string(labels), " "))
+
+	dayNames := []string{"", "Mon", "", "Wed", "", "Fri", ""}
+	for weekday := 0; weekday < 7; weekday++ {
+		var b strings.Builder
+		for week := 0; week < heatmapWeeks; week++ {
+			day := start.AddDate(0, 0, week*7+weekday)
+			if day.After(end) {
+				break
+			}
+			level := 0
+			if count := counts[dayKey(day)]; count > 0 {
+				level = (count*(len(heatmapLevels)-1) + busiest - 1) / busiest
+			}
+			b.WriteString(heatmapLevels[level])
+		}
+		fmt.Fprintf(w, "%-3s %s\n", dayNames[weekday], b.String())
+	}
+
+	fmt.Fprintf(w, "    less %s more\n", strings.Join(heatmapLevels, " "))
+	fmt.Fprintf(w, "%d snips added or changed on %d days since %s\n", total, len(counts), start.Format("2006-01-02"))
+}
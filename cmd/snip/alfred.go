@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"strings"
+)
+
+// alfredItem is a single result in the Alfred Script Filter json format
+type alfredItem struct {
+	UID      string      `json:"uid,omitempty"`
+	Title    string      `json:"title"`
+	Subtitle string      `json:"subtitle,omitempty"`
+	Arg      string      `json:"arg,omitempty"`
+	Valid    *bool       `json:"valid,omitempty"`
+	Text     *alfredText `json:"text,omitempty"`
+}
+
+// alfredText is shown by Alfred when copying or displaying an item in large type
+type alfredText struct {
+	Copy      string `json:"copy,omitempty"`
+	LargeType string `json:"largetype,omitempty"`
+}
+
+// writeAlfred writes search matches as Alfred Script Filter json, using get to obtain the data of each snip
+func writeAlfred(w io.Writer, matches []snip.SearchMatch, get func(id string) (snip.Snip, error)) error {
+	items := []alfredItem{}
+	for _, match := range matches {
+		s, err := get(match.UUID.String())
+		if err != nil {
+			return err
+		}
+		item := alfredItem{
+			UID:      match.UUID.String(),
+			Title:    match.Name,
+			Subtitle: fmt.Sprintf("score %.2f", match.Score),
+			Arg:      match.UUID.String(),
+			Text:     &alfredText{Copy: s.Data, LargeType: s.Data},
+		}
+		if len(match.Context) > 0 {
+			ctx := match.Context[0]
+			words := append(append(append([]string{}, ctx.Before...), ctx.Term), ctx.After...)
+			item.Subtitle += " | " + strings.Join(words, " ")
+		}
+		items = append(items, item)
+	}
+
+	// alfred shows nothing at all for an empty list, so say so explicitly
+	if len(items) == 0 {
+		valid := false
+		items = append(items, alfredItem{Title: "No matching snips", Valid: &valid})
+	}
+	return json.NewEncoder(w).Encode(map[string][]alfredItem{"items": items})
+}
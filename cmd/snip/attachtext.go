@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/config"
+	"strings"
+)
+
+// textReader reads the text of one kind of attachment with a command, such as the text of images or the transcript of audio
+type textReader struct {
+	// Source is recorded with the text read
+	Source string
+	// Command reads the attachment named by $SNIP_ATTACHMENT_FILE, writing its text to standard output
+	Command string
+	// Reads reports whether attachments with a name are of the kind read
+	Reads func(name string) bool
+	// Verb describes reading in messages, such as read or transcribed
+	Verb string
+}
+
+// ocrReader returns the reader of the text of images, which uses tesseract unless the configuration names another command
+func ocrReader(c config.OCR) textReader {
+	r := textReader{Source: snip.AttachmentTextOCR, Command: c.Command, Reads: snip.IsImageAttachment, Verb: "read"}
+	if r.Command == "" {
+		r.Command = snip.DefaultOCRCommand
+	}
+	return r
+}
+
+// transcriptionReader returns the reader of transcripts of audio, whose command is empty unless configured
+func transcriptionReader(c config.Transcription) textReader {
+	return textReader{Source: snip.AttachmentTextTranscript, Command: c.Command, Reads: snip.IsAudioAttachment, Verb: "transcribed"}
+}
+
+// read reads the text of attachment a and records it for the search index, returning the number of words read.
+// A transcript also becomes the data of a snip that has none, so that a voice memo becomes a note.
+// The snip of the attachment must be indexed again for the text to be found.
+func (r textReader) read(a snip.Attachment) (int, error) {
+	text, err := snip.ExtractAttachmentText(r.Command, a)
+	if err != nil {
+		return 0, err
+	}
+	if err = snip.SetAttachmentText(a.UUID, r.Source, text); err != nil {
+		return 0, err
+	}
+	if r.Source == snip.AttachmentTextTranscript && text != "" {
+		s, err := snip.GetFromUUID(a.SnipUUID.String())
+		if err != nil {
+			return 0, err
+		}
+		if strings.TrimSpace(s.Data) == "" {
+			s.Data = text + "\n"
+			if err = s.Update(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(snip.SplitWords(text)), nil
+}
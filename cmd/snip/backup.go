@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/config"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+	"os"
+	"time"
+)
+
+// backupCheckInterval is how often the daemon checks whether a backup is due, unless backups are more frequent
+const backupCheckInterval = time.Minute
+
+// backupSchedule is the backup configuration with its defaults applied
+type backupSchedule struct {
+	Dir      string
+	Interval time.Duration
+	Keep     snip.Retention
+}
+
+// loadBackupSchedule reads the backup configuration of the database at dbFilePath, the interval being zero when the daemon makes no backups
+func loadBackupSchedule(c config.Backup, dbFilePath string) (backupSchedule, error) {
+	b := backupSchedule{
+		Dir:  c.Dir,
+		Keep: snip.Retention{Daily: c.Keep.Daily, Weekly: c.Keep.Weekly, Monthly: c.Keep.Monthly},
+	}
+	if b.Dir == "" {
+		b.Dir = dbFilePath + ".backups"
+	}
+	if c.Interval != "" {
+		interval, err := parseDuration(c.Interval)
+		if err != nil {
+			return b, fmt.Errorf("backup interval %w", err)
+		}
+		if interval <= 0 {
+			return b, fmt.Errorf("backup interval %q must be positive", c.Interval)
+		}
+		b.Interval = interval
+	}
+	return b, b.Keep.Validate()
+}
+
+// backup copies the database into the backup directory and prunes the backups the schedule does not keep, returning the new archive
+// and those removed
+func (b backupSchedule) backup(now time.Time) (snip.BackupArchive, []snip.BackupArchive, error) {
+	database.Mu.Lock()
+	archive, err := snip.CreateBackup(b.Dir, now)
+	database.Mu.Unlock()
+	if err != nil {
+		return archive, nil, err
+	}
+	removed, err := snip.PruneBackups(b.Dir, b.Keep)
+	return archive, removed, err
+}
+
+// due reports whether the latest backup is at least an interval old, or there is none
+func (b backupSchedule) due(now time.Time) (bool, error) {
+	archives, err := snip.ListBackups(b.Dir)
+	if err != nil {
+		return false, err
+	}
+	return len(archives) == 0 || now.Sub(archives[0].Time) >= b.Interval, nil
+}
+
+// backupEvery makes a backup whenever one is due, checking immediately and then periodically until stop is closed.
+// Backups made before the daemon started count, so restarting it does not make extra backups.
+func backupEvery(b backupSchedule, stop <-chan struct{}) {
+	check := backupCheckInterval
+	if b.Interval < check {
+		check = b.Interval
+	}
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		due, err := b.due(now)
+		if err != nil {
+			log.Debug().Err(err).Str("dir", b.Dir).Msg("error listing backups")
+		}
+		if due {
+			archive, removed, err := b.backup(now)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem backing up the database to %s\n", b.Dir)
+				log.Debug().Err(err).Str("dir", b.Dir).Msg("error making backup")
+			} else {
+				fmt.Fprintf(os.Stderr, "backed up to %s\n", archive.Path)
+			}
+			for _, a := range removed {
+				fmt.Fprintf(os.Stderr, "removed backup %s\n", a.Path)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// writeBackupProblems reports each snapshot that cannot be restored as recorded
+func writeBackupProblems(w io.Writer, problems []snip.BackupProblem) {
+	for _, p := range problems {
+		if p.Chunk == "" {
+			fmt.Fprintf(w, "snapshot %s: %s\n", p.Snapshot, p.Problem)
+			continue
+		}
+		fmt.Fprintf(w, "snapshot %s: chunk %s %s\n", p.Snapshot, p.Chunk, p.Problem)
+	}
+}
+
+// restoreSnapshot writes the database of the snapshot named name in the incremental backup directory dir to path, which must not exist,
+// and the blobs of its attachments to path followed by .blobs. Nothing is left at either when the snapshot cannot be restored.
+func restoreSnapshot(dir string, name string, path string) error {
+	s, err := snip.FindIncrementalBackup(dir, name)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(path + ".blobs"); err == nil && len(s.Blobs) > 0 {
+		return fmt.Errorf("%s.blobs already exists", path)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	err = snip.RestoreIncrementalBackup(dir, s, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && len(s.Blobs) > 0 {
+		err = snip.RestoreIncrementalBlobs(dir, s, path+".blobs")
+	}
+	if err != nil {
+		os.Remove(path)
+		os.RemoveAll(path + ".blobs")
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+)
+
+// splitBatch separates data into blocks at lines consisting only of delimiter, omitting blank blocks
+func splitBatch(data string, delimiter string) []string {
+	var blocks []string
+	var current []string
+	flush := func() {
+		block := strings.Trim(strings.Join(current, "\n"), "\r\n")
+		if strings.TrimSpace(block) != "" {
+			blocks = append(blocks, block+"\n")
+		}
+		current = nil
+	}
+	for _, line := range strings.Split(data, "\n") {
+		if strings.TrimRight(line, "\r") == delimiter {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return blocks
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// benchBatch is the number of snips or attachments written in each transaction, as add -batch does
+	benchBatch = 1000
+	// benchWords is the number of words in each generated snip
+	benchWords = 60
+	// benchSearches is the number of index searches timed
+	benchSearches = 100
+	// benchAttachmentSize is the size in bytes of each generated attachment, one for every tenth snip
+	benchAttachmentSize = 4096
+)
+
+// benchResult is the time taken by one operation of the benchmark
+type benchResult struct {
+	Operation string
+	// Count is the number of snips, searches, or attachments handled
+	Count    int
+	Duration time.Duration
+}
+
+// Rate returns the number handled per second
+func (r benchResult) Rate() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Count) / r.Duration.Seconds()
+}
+
+// benchVocabulary generates count distinct words from syllables, so that generated text stems and indexes like prose
+func benchVocabulary(rng *rand.Rand, count int) []string {
+	syllables := []string{"ka", "lo", "mi", "ner", "sta", "vu", "qua", "ter", "bri", "don", "fel", "ish", "ro", "zen", "pal", "cor"}
+	seen := make(map[string]bool)
+	var words []string
+	for len(words) < count {
+		var b strings.Builder
+		for n := 2 + rng.Intn(3); n > 0; n-- {
+			b.WriteString(syllables[rng.Intn(len(syllables))])
+		}
+		if !seen[b.String()] {
+			seen[b.String()] = true
+			words = append(words, b.String())
+		}
+	}
+	return words
+}
+
+// runBench adds count generated snips to the open database, which should be empty, then times listing, searching, and attaching to them.
+// The number of snips added so far is written to progress after each transaction.
+func runBench(progress io.Writer, rng *rand.Rand, count int) ([]benchResult, error) {
+	var results []benchResult
+	store := snip.LocalStore{}
+	vocabulary := benchVocabulary(rng, 5000)
+	// word frequencies follow a zipf distribution as in natural language
+	zipf := rand.NewZipf(rng, 1.1, 1, uint64(len(vocabulary)-1))
+	text := func(words int) string {
+		chosen := make([]string, words)
+		for idx := range chosen {
+			chosen[idx] = vocabulary[zipf.Uint64()]
+		}
+		return strings.Join(chosen, " ")
+	}
+
+	ids := make([]uuid.UUID, 0, count)
+	started := time.Now()
+	for len(ids) < count {
+		err := database.Conn.WithTx(func() error {
+			for n := 0; n < benchBatch && len(ids) < count; n++ {
+				s := snip.New()
+				s.Data = text(benchWords)
+				s.Name = snip.GenerateName([]byte(s.Data), snip.DefaultNameStrategy)
+				if err := store.Insert(s); err != nil {
+					return err
+				}
+				ids = append(ids, s.UUID)
+			}
+			return nil
+		})
+		if err != nil {
+			return results, fmt.Errorf("adding snips: %w", err)
+		}
+		fmt.Fprintf(progress, "added %d of %d snips\n", len(ids), count)
+	}
+	results = append(results, benchResult{Operation: "add", Count: count, Duration: time.Since(started)})
+
+	started = time.Now()
+	listed, err := store.List(0)
+	if err != nil {
+		return results, fmt.Errorf("listing snips: %w", err)
+	}
+	results = append(results, benchResult{Operation: "ls", Count: len(listed), Duration: time.Since(started)})
+
+	started = time.Now()
+	for n := 0; n < benchSearches; n++ {
+		terms := strings.Fields(text(2))
+		if _, err = store.Search(terms, 10, 6); err != nil {
+			return results, fmt.Errorf("searching for %v: %w", terms, err)
+		}
+	}
+	results = append(results, benchResult{Operation: "search", Count: benchSearches, Duration: time.Since(started)})
+
+	attachments := count / 10
+	if attachments == 0 {
+		attachments = 1
+	}
+	data := make([]byte, benchAttachmentSize)
+	started = time.Now()
+	for done := 0; done < attachments; {
+		err = database.Conn.WithTx(func() error {
+			for n := 0; n < benchBatch && done < attachments; n++ {
+				rng.Read(data)
+				if _, err := store.Attach(ids[rng.Intn(len(ids))], "bench-"+strconv.Itoa(done)+".bin", data); err != nil {
+					return err
+				}
+				done++
+			}
+			return nil
+		})
+		if err != nil {
+			return results, fmt.Errorf("adding attachments: %w", err)
+		}
+	}
+	results = append(results, benchResult{Operation: "attach", Count: attachments, Duration: time.Since(started)})
+
+	return results, nil
+}
+
+// writeBenchReport prints the count, time, and rate of each benchmark operation
+func writeBenchReport(w io.Writer, results []benchResult) {
+	var rows [][]string
+	for _, r := range results {
+		rows = append(rows, []string{r.Operation, strconv.Itoa(r.Count), r.Duration.Round(time.Millisecond).String(), strconv.FormatFloat(r.Rate(), 'f', 1, 64)})
+	}
+	writeTable(w, w, []string{"operation", "count", "time", "per second"}, []tableSection{{Rows: rows}}, map[int]bool{1: true, 2: true, 3: true})
+}
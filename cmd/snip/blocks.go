@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// blockInterpreters are the commands running code blocks of each language, given the file holding the code
+var blockInterpreters = map[string]string{
+	"bash":       "bash",
+	"fish":       "fish",
+	"javascript": "node",
+	"js":         "node",
+	"lua":        "lua",
+	"perl":       "perl",
+	"py":         "python3",
+	"python":     "python3",
+	"rb":         "ruby",
+	"ruby":       "ruby",
+	"sh":         "sh",
+	"shell":      "sh",
+	"zsh":        "zsh",
+}
+
+// snipBlocks returns the code blocks of snip data, taking blocks without a language to be in lang, the language detected for the snip.
+// Data detected to be code without any fenced blocks is itself a single block.
+func snipBlocks(data string, lang string) []snip.CodeBlock {
+	blocks := snip.CodeBlocks(data)
+	if len(blocks) == 0 && lang != "" {
+		return []snip.CodeBlock{{Lang: lang, Line: 1, Code: strings.TrimSuffix(data, "\n") + "\n"}}
+	}
+	for idx := range blocks {
+		if blocks[idx].Lang == "" {
+			blocks[idx].Lang = lang
+		}
+	}
+	return blocks
+}
+
+// selectBlocks returns the blocks in lang, or every block when lang is empty, narrowed to the one numbered index from 1 among them when index is set
+func selectBlocks(blocks []snip.CodeBlock, lang string, index int) ([]snip.CodeBlock, error) {
+	var selected []snip.CodeBlock
+	for _, b := range blocks {
+		if lang == "" || b.Lang == strings.ToLower(lang) {
+			selected = append(selected, b)
+		}
+	}
+	if index == 0 {
+		return selected, nil
+	}
+	if index < 1 || index > len(selected) {
+		return nil, fmt.Errorf("there is no block %d of the %d blocks", index, len(selected))
+	}
+	return selected[index-1 : index], nil
+}
+
+// writeBlockList lists blocks numbered from 1 with their language, line, and first line of code
+func writeBlockList(w io.Writer, blocks []snip.CodeBlock) {
+	for idx, b := range blocks {
+		lang := b.Lang
+		if lang == "" {
+			lang = "-"
+		}
+		first := strings.TrimSpace(strings.SplitN(strings.TrimLeft(b.Code, "\n"), "\n", 2)[0])
+		fmt.Fprintf(w, "%3d %-10s line %-4d %s\n", idx+1, lang, b.Line, first)
+	}
+}
+
+// runBlock writes the code of b to a temporary file and runs it with the interpreter of its language, returning the exit code
+func runBlock(b snip.CodeBlock) (int, error) {
+	interpreter, ok := blockInterpreters[b.Lang]
+	if !ok {
+		return 0, fmt.Errorf("no interpreter runs blocks of %q", b.Lang)
+	}
+	dir, err := os.MkdirTemp("", "snip-block-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "block")
+	if err = os.WriteFile(file, []byte(b.Code), 0600); err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(interpreter, file)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// importBatch is the number of snips written in each transaction of a bulk import
+const importBatch = 1000
+
+// importRecord is a snip prepared for a bulk import along with its analyzed index terms
+type importRecord struct {
+	Snip  snip.Snip
+	Terms snip.IndexTerms
+	// Skip is the reason the record is not imported, such as binary data, or empty to import it
+	Skip string
+	// KeepUUID is set when the uuid was read from the source, such as from front matter, so that a snip with it is a duplicate
+	KeepUUID bool
+}
+
+// importResult counts the outcome of a bulk import
+type importResult struct {
+	Imported int
+	Skipped  int
+	// Duplicates are records whose data is identical to an existing snip or an earlier record
+	Duplicates int
+}
+
+// bulkImport prepares count records with jobs concurrent workers and inserts them in their original order, committing every importBatch records.
+// Records committed before an error are kept and counted in the result. Each record handled is reported to progress unless it is nil.
+func bulkImport(count int, jobs int, prepare func(idx int) (importRecord, error), progress snip.Progress) (importResult, error) {
+	var result importResult
+	if jobs < 1 {
+		jobs = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type prepared struct {
+		idx    int
+		record importRecord
+		err    error
+	}
+	indexes := make(chan int)
+	results := make(chan prepared, jobs)
+	var wg sync.WaitGroup
+	for n := 0; n < jobs; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				record, err := prepare(idx)
+				select {
+				case results <- prepared{idx, record, err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(indexes)
+		for idx := 0; idx < count; idx++ {
+			select {
+			case indexes <- idx:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// records finish out of order and are held until those before them are ready
+	pending := make(map[int]importRecord)
+	var batch []importRecord
+	next := 0
+	for p := range results {
+		if p.err != nil {
+			return result, p.err
+		}
+		pending[p.idx] = p.record
+		for {
+			record, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			batch = append(batch, record)
+			if progress != nil {
+				progress.Step("importing", next, count)
+			}
+			if len(batch) == importBatch || next == count {
+				if err := writeImportBatch(batch, &result); err != nil {
+					return result, err
+				}
+				batch = nil
+			}
+		}
+	}
+	return result, nil
+}
+
+// writeImportBatch inserts and indexes the records of batch in a single transaction, adding to the counts of result once it is committed
+func writeImportBatch(batch []importRecord, result *importResult) error {
+	var counts importResult
+	err := database.Conn.WithTx(func() error {
+		counts = importResult{}
+		for _, record := range batch {
+			if record.Skip != "" {
+				counts.Skipped++
+				continue
+			}
+			s := record.Snip
+			if record.KeepUUID {
+				_, err := snip.GetSnipMeta(s.UUID.String())
+				if err == nil {
+					counts.Duplicates++
+					continue
+				}
+				if !errors.Is(err, database.ErrNoRows) {
+					return err
+				}
+			}
+			// earlier records of the import are found as well since they are inserted first
+			existing, err := snip.GetUUIDByChecksum(snip.Checksum([]byte(s.Data)))
+			if err != nil {
+				return err
+			}
+			if existing != uuid.Nil {
+				counts.Duplicates++
+				continue
+			}
+			if err = snip.InsertSnip(s); err != nil {
+				return err
+			}
+			for key, value := range s.Meta {
+				if err = s.SetMeta(key, value); err != nil {
+					return err
+				}
+			}
+			if err = s.WriteIndex(record.Terms); err != nil {
+				return err
+			}
+			if err = s.IndexTrigrams(); err != nil {
+				return err
+			}
+			counts.Imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	result.Imported += counts.Imported
+	result.Skipped += counts.Skipped
+	result.Duplicates += counts.Duplicates
+	return nil
+}
+
+// prepareImport names s from its data when it has no name and analyzes its data for the index, skipping binary or empty data
+func prepareImport(s snip.Snip, strategy snip.NameStrategy) (importRecord, error) {
+	if isBinary(s.Data) {
+		return importRecord{Skip: "binary data"}, nil
+	}
+	if strings.TrimSpace(s.Data) == "" {
+		return importRecord{Skip: "no data"}, nil
+	}
+	for _, tag := range s.Tags {
+		if _, err := snip.NormalizeTag(tag); err != nil {
+			return importRecord{}, err
+		}
+	}
+	if s.Name == "" {
+		s.Name = snip.GenerateName([]byte(s.Data), strategy)
+	}
+	terms, err := snip.AnalyzeTerms(s.Data)
+	if err != nil {
+		return importRecord{}, err
+	}
+	return importRecord{Snip: s, Terms: terms}, nil
+}
+
+// importFiles lists the regular files beneath dir, skipping hidden files and directories
+func importFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// markdownExtensions are the extensions of files whose front matter is read on import
+var markdownExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+}
+
+// linkTarget is a markdown file that wiki links name, and the uuid it is imported with
+type linkTarget struct {
+	File string
+	UUID uuid.UUID
+}
+
+// importLinkTargets returns the uuid each markdown file among files will be imported with, by the name of the file without its extension,
+// so that wiki links between the files become links between their snips. Files without a uuid in their front matter are given a new one.
+// Of files sharing a name, only the first is linked to.
+func importLinkTargets(files []string) (map[string]linkTarget, error) {
+	targets := make(map[string]linkTarget)
+	for _, file := range files {
+		if !markdownExtensions[strings.ToLower(filepath.Ext(file))] {
+			continue
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if _, ok := targets[name]; ok {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return targets, err
+		}
+		id := uuid.New()
+		if f, _, found, err := snip.ParseFrontMatter(string(data)); err == nil && found && f.UUID != "" {
+			if id, err = uuid.Parse(f.UUID); err != nil {
+				return targets, fmt.Errorf("%s: front matter uuid: %w", file, err)
+			}
+		}
+		targets[name] = linkTarget{File: file, UUID: id}
+	}
+	return targets, nil
+}
+
+// prepareFile reads a file beneath dir as a snip named after the file, with the time it was last modified.
+// The front matter of a markdown file, such as written by snip export markdown, sets the uuid, name, timestamp, tags, and metadata instead,
+// and wiki links to the files of targets become links to their snips.
+func prepareFile(dir string, file string, strategy snip.NameStrategy, targets map[string]linkTarget) (importRecord, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return importRecord{}, err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return importRecord{}, err
+	}
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return importRecord{}, err
+	}
+	s := snip.New()
+	s.Data = string(data)
+	s.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	s.Timestamp = info.ModTime()
+	s.Meta = map[string]string{"file": filepath.ToSlash(rel)}
+	if !markdownExtensions[strings.ToLower(filepath.Ext(file))] {
+		return prepareImport(s, strategy)
+	}
+	if t, ok := targets[s.Name]; ok && t.File == file {
+		s.UUID = t.UUID
+	}
+	f, body, found, err := snip.ParseFrontMatter(s.Data)
+	if err != nil {
+		return importRecord{}, fmt.Errorf("%s: %w", rel, err)
+	}
+	s.Data = snip.WikiLinksToSnipLinks(body, func(name string) (uuid.UUID, bool) {
+		t, ok := targets[name]
+		return t.UUID, ok
+	})
+	if !found {
+		return prepareImport(s, strategy)
+	}
+	// snips exported with their uuid come back with only the metadata they had
+	if f.UUID != "" {
+		s.Meta = nil
+	}
+	if err = f.Apply(&s); err != nil {
+		return importRecord{}, fmt.Errorf("%s: %w", rel, err)
+	}
+	record, err := prepareImport(s, strategy)
+	record.KeepUUID = f.UUID != ""
+	return record, err
+}
+
+// importRow is a snip read from a line of json or a row of csv, where all but the data are optional
+type importRow struct {
+	Name      string            `json:"name"`
+	Data      string            `json:"data"`
+	Timestamp string            `json:"timestamp"`
+	Tags      []string          `json:"tags"`
+	Meta      map[string]string `json:"meta"`
+}
+
+// snip returns the snip described by the row, with the current time when the row has none
+func (r importRow) snip() (snip.Snip, error) {
+	s := snip.New()
+	s.Name = r.Name
+	s.Data = r.Data
+	s.Tags = r.Tags
+	s.Meta = r.Meta
+	if r.Timestamp != "" {
+		t, err := parseTimestamp(r.Timestamp)
+		if err != nil {
+			return s, err
+		}
+		s.Timestamp = t
+	}
+	return s, nil
+}
+
+// readImportCSV reads the rows of a csv file with a header naming its columns, of which data is required and name, timestamp, and tags are optional.
+// Tags within a cell are separated by commas.
+func readImportCSV(r io.Reader) ([]importRow, error) {
+	var rows []importRow
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return rows, fmt.Errorf("reading csv header: %w", err)
+	}
+	columns := make(map[string]int)
+	for idx, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "name", "data", "timestamp", "tags":
+			columns[name] = idx
+		default:
+			return rows, fmt.Errorf("unknown csv column %q (name|data|timestamp|tags)", name)
+		}
+	}
+	if _, ok := columns["data"]; !ok {
+		return rows, fmt.Errorf("the csv header has no data column")
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, err
+		}
+		cell := func(name string) string {
+			if idx, ok := columns[name]; ok {
+				return record[idx]
+			}
+			return ""
+		}
+		row := importRow{Name: cell("name"), Data: cell("data"), Timestamp: cell("timestamp")}
+		for _, tag := range strings.Split(cell("tags"), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				row.Tags = append(row.Tags, tag)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readImportJSONL reads the lines of a file of json objects, one per line, leaving out blank lines.
+// Lines are numbered from one.
+func readImportJSONL(r io.Reader) ([]string, []int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	var lines []string
+	var numbers []int
+	for idx, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+		numbers = append(numbers, idx+1)
+	}
+	return lines, numbers, nil
+}
+
+// prepareJSONL decodes a line of a json lines import
+func prepareJSONL(line string, strategy snip.NameStrategy) (importRecord, error) {
+	var r importRow
+	if err := json.Unmarshal([]byte(line), &r); err != nil {
+		return importRecord{}, err
+	}
+	s, err := r.snip()
+	if err != nil {
+		return importRecord{}, err
+	}
+	return prepareImport(s, strategy)
+}
+
+// importSummary describes the outcome of a bulk import
+func importSummary(result importResult, elapsed time.Duration) string {
+	return fmt.Sprintf("imported %d snips in %s, skipped %d duplicates and %d without text", result.Imported, elapsed.Round(time.Millisecond), result.Duplicates, result.Skipped)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/config"
+	"io"
+	"strings"
+)
+
+// checkers returns the checkers of the configuration, or the defaults when none are configured, narrowed to those named when any are
+func checkers(configured []config.Checker, names []string) ([]snip.Checker, error) {
+	all := snip.DefaultCheckers
+	if configured != nil {
+		all = nil
+		for _, c := range configured {
+			all = append(all, snip.Checker{Name: c.Name, Command: c.Command, Langs: c.Langs, Words: c.Words})
+		}
+	}
+	if len(names) == 0 {
+		return all, nil
+	}
+	var selected []snip.Checker
+	for _, name := range names {
+		found := false
+		for _, c := range all {
+			if c.Name == name {
+				selected = append(selected, c)
+				found = true
+			}
+		}
+		if !found {
+			var known []string
+			for _, c := range all {
+				known = append(known, c.Name)
+			}
+			return nil, fmt.Errorf("checker %s is not configured (%s)", name, strings.Join(known, "|"))
+		}
+	}
+	return selected, nil
+}
+
+// writeFindings writes each problem found in snip data with its position and checker, followed by the line it is on
+func writeFindings(w io.Writer, data string, findings []snip.Finding) {
+	lines := strings.Split(data, "\n")
+	for _, f := range findings {
+		position := "-"
+		if f.Line > 0 {
+			position = fmt.Sprintf("%d:%d", f.Line, f.Column)
+			if f.Column == 0 {
+				position = fmt.Sprintf("%d", f.Line)
+			}
+		}
+		fmt.Fprintf(w, "%-7s %s: %s\n", position, f.Checker, f.Message)
+		if f.Line > 0 && f.Line <= len(lines) {
+			fmt.Fprintf(w, "        %s\n", strings.TrimRight(lines[f.Line-1], " \t\r"))
+		}
+	}
+}
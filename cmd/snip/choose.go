@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// noInput is set by --no-input before the command, failing when a partial uuid matches several snips rather than asking which is meant
+var noInput bool
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// getSnip retrieves the snip with a full or partial uuid as snip.GetFromUUID does.
+// When a partial uuid matches several snips and both standard input and error are terminals, the matches are listed and one may be chosen by number.
+func getSnip(id string) (snip.Snip, error) {
+	s, err := snip.GetFromUUID(id)
+	if !errors.Is(err, database.ErrMultipleRows) || noInput || !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return s, err
+	}
+	matches, matchErr := snip.MatchUUID(id)
+	if matchErr != nil {
+		return s, err
+	}
+	chosen, err := chooseSnip(os.Stdin, os.Stderr, id, matches)
+	if err != nil {
+		return s, err
+	}
+	return snip.GetFromUUID(chosen.UUID.String())
+}
+
+// chooseSnip lists matches numbered from 1 on w and reads the number of one from r, returning an error wrapping database.ErrMultipleRows when none is chosen
+func chooseSnip(r io.Reader, w io.Writer, id string, matches []snip.Snip) (snip.Snip, error) {
+	fmt.Fprintf(w, "The id %s matches %d snips:\n", id, len(matches))
+	for idx, s := range matches {
+		fmt.Fprintf(w, "%4d) %s  %s\n", idx+1, snip.ShortenUUID(s.UUID)[0], s.Name)
+	}
+	fmt.Fprintf(w, "Choose a snip [1-%d]: ", len(matches))
+	response, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && response == "" {
+		fmt.Fprintln(w)
+		return snip.Snip{}, fmt.Errorf("no snip was chosen for %s: %w", id, database.ErrMultipleRows)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(response))
+	if err != nil || n < 1 || n > len(matches) {
+		return snip.Snip{}, fmt.Errorf("%q is not one of the snips matching %s: %w", strings.TrimSpace(response), id, database.ErrMultipleRows)
+	}
+	return matches[n-1], nil
+}
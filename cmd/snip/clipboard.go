@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// clipboardCommands are tried in order to read the clipboard contents on the current platform
+func clipboardCommands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbpaste"}}
+	case "windows":
+		return [][]string{{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard -Raw"}}
+	}
+	var commands [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-paste", "--no-newline"})
+	}
+	commands = append(commands,
+		[]string{"xclip", "-selection", "clipboard", "-out"},
+		[]string{"xsel", "--clipboard", "--output"},
+	)
+	return commands
+}
+
+// readClipboard returns the current clipboard contents using the first available command
+func readClipboard() (string, error) {
+	var lastErr error
+	for _, command := range clipboardCommands() {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			lastErr = err
+			continue
+		}
+		output, err := exec.Command(command[0], command[1:]...).Output()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return string(output), nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no clipboard command available")
+	}
+	return "", lastErr
+}
+
+// watchClipboard polls the clipboard and adds a snip for each new unique entry
+func watchClipboard(interval time.Duration, exclude *regexp.Regexp, naming snip.NameStrategy) error {
+	// verify the clipboard can be read before polling
+	last, err := readClipboard()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "watching clipboard\n")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		data, err := readClipboard()
+		if err != nil {
+			log.Debug().Err(err).Msg("error reading clipboard")
+			continue
+		}
+		if data == last {
+			continue
+		}
+		last = data
+
+		if strings.TrimSpace(data) == "" {
+			continue
+		}
+		if exclude != nil && exclude.MatchString(data) {
+			log.Debug().Msg("clipboard entry matched exclusion pattern")
+			continue
+		}
+		existing, err := snip.GetUUIDByChecksum(snip.Checksum([]byte(data)))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem checking for a duplicate clipboard entry.\n")
+			log.Debug().Err(err).Msg("error searching for duplicate data")
+			continue
+		}
+		if existing != uuid.Nil {
+			log.Debug().Str("uuid", existing.String()).Msg("clipboard entry already present")
+			continue
+		}
+
+		s := snip.New()
+		s.Data = data
+		s.Name = snip.GenerateName([]byte(s.Data), naming)
+		err = snip.InsertSnip(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem inserting the clipboard entry into the database.\n")
+			log.Debug().Err(err).Msg("error inserting clipboard snip")
+			continue
+		}
+		err = s.SetMeta("source", "clipboard")
+		if err != nil {
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error setting source metadata")
+		}
+		err = s.Index()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem indexing the clipboard entry %s\n", s.UUID)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error indexing clipboard snip")
+		}
+		fmt.Printf("added %s %s\n", s.UUID, s.Name)
+	}
+	return nil
+}
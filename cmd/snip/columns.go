@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	"strings"
+)
+
+// lsColumns are the columns ls can show
+var lsColumns = []string{"uuid", "name", "modified", "size", "attachments", "stars", "tags", "lang", "status"}
+
+// validateColumns reports a column that ls cannot show
+func validateColumns(columns []string) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("no columns given (%s)", strings.Join(lsColumns, "|"))
+	}
+	for _, c := range columns {
+		supported := false
+		for _, known := range lsColumns {
+			if c == known {
+				supported = true
+			}
+		}
+		if !supported {
+			return fmt.Errorf("column %s is not supported (%s)", c, strings.Join(lsColumns, "|"))
+		}
+	}
+	return nil
+}
+
+// tableSection is a group of rows shown under a title, which is left out when empty
+type tableSection struct {
+	Title string
+	Rows  [][]string
+}
+
+// writeTable writes the header to hw and the sections to w, padding each column to its widest cell in any section except the last column.
+// Columns whose index is in right are aligned to the right.
+func writeTable(w io.Writer, hw io.Writer, header []string, sections []tableSection, right map[int]bool) {
+	writeStyledTable(w, hw, header, sections, right, nil)
+}
+
+// writeStyledTable writes a table as writeTable does, coloring the cells of the sections in each column whose index is in styles
+func writeStyledTable(w io.Writer, hw io.Writer, header []string, sections []tableSection, right map[int]bool, styles map[int]*color.Color) {
+	widths := make([]int, len(header))
+	rowCount := 0
+	for _, section := range append([]tableSection{{Rows: [][]string{header}}}, sections...) {
+		for _, row := range section.Rows {
+			for idx, cell := range row {
+				if n := len([]rune(cell)); n > widths[idx] {
+					widths[idx] = n
+				}
+			}
+		}
+		rowCount += len(section.Rows)
+	}
+	line := func(row []string, styled bool) string {
+		cells := make([]string, len(row))
+		for idx, cell := range row {
+			// the padding is that of the text without the escape sequences of its color
+			pad := strings.Repeat(" ", widths[idx]-len([]rune(cell)))
+			if c, ok := styles[idx]; ok && styled {
+				cell = c.Sprint(cell)
+			}
+			switch {
+			case right[idx]:
+				cells[idx] = pad + cell
+			case idx == len(row)-1:
+				cells[idx] = cell
+			default:
+				cells[idx] = cell + pad
+			}
+		}
+		return strings.Join(cells, " ")
+	}
+
+	// the header row is counted above
+	if rowCount == 1 {
+		return
+	}
+	fmt.Fprintln(hw, line(header, false))
+	for idx, section := range sections {
+		if section.Title != "" {
+			if idx > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, section.Title)
+		}
+		for _, row := range section.Rows {
+			fmt.Fprintln(w, line(row, true))
+		}
+	}
+}
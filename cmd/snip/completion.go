@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// commands are the names completed for the first argument of snip
+var commands = []string{
+	"add", "alias", "ask", "attach", "backup", "bench", "blocks", "check", "completion", "context", "count", "daemon", "diff", "doctor", "du", "due", "exec", "expire", "export",
+	"get", "hook", "import", "index", "journal", "lock", "ls", "mail", "mv-db", "note", "random", "recent", "rename", "replace", "review",
+	"rm", "search", "serve", "share", "snapshot", "sql", "star", "stats", "status", "stdio", "summarize", "tag", "track", "translate", "urls", "verify", "versions", "watch",
+}
+
+// bashCompletion completes commands, then aliases or files, taking the list of commands
+const bashCompletion = `_snip() {
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	if [ "$COMP_CWORD" -eq 1 ]; then
+		COMPREPLY=($(compgen -W "%s" -- "$cur"))
+		return
+	fi
+	COMPREPLY=($(compgen -W "$(snip alias ls -porcelain 2>/dev/null | cut -f1)" -- "$cur"))
+}
+complete -o default -F _snip snip
+`
+
+// zshCompletion completes commands, then aliases and files, taking the list of commands
+const zshCompletion = `#compdef snip
+_snip() {
+	if (( CURRENT == 2 )); then
+		compadd -- %s
+		return
+	fi
+	compadd -- ${(f)"$(snip alias ls -porcelain 2>/dev/null | cut -f1)"}
+	_files
+}
+compdef _snip snip
+`
+
+// writeCompletion writes the completion script of shell (bash|zsh), which completes aliases by running snip alias ls each time
+func writeCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		_, err := fmt.Fprintf(w, bashCompletion, strings.Join(commands, " "))
+		return err
+	case "zsh":
+		_, err := fmt.Fprintf(w, zshCompletion, strings.Join(commands, " "))
+		return err
+	}
+	return fmt.Errorf("shell %s is not supported (bash|zsh)", shell)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// answers reads the answers to confirmations from standard input, shared so that answers piped for several confirmations are not lost to the buffer of the first
+var answers = bufio.NewReader(os.Stdin)
+
+// addForceFlag registers -f and -force on fs, which act on locked snips that the command would otherwise refuse or skip
+func addForceFlag(fs *flag.FlagSet, usage string) *bool {
+	force := fs.Bool("force", false, usage)
+	fs.BoolVar(force, "f", false, usage)
+	return force
+}
+
+// addYesFlag registers -y and -yes on fs, which confirm every action of the command without asking
+func addYesFlag(fs *flag.FlagSet) *bool {
+	yes := fs.Bool("yes", false, "confirm without asking")
+	fs.BoolVar(yes, "y", false, "confirm without asking")
+	return yes
+}
+
+// confirmAction asks on standard error to confirm an action, which is confirmed without asking when yes is set.
+// An answer may be piped when standard input is not a terminal, but with nothing left to read the action is declined rather than assumed.
+func confirmAction(message string, yes bool) bool {
+	if yes {
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "%s [Y/n]: ", message)
+	response, err := answers.ReadString('\n')
+	if err != nil && (err != io.EOF || response == "") {
+		fmt.Fprintln(os.Stderr)
+		if !isTerminal(os.Stdin) {
+			fmt.Fprintf(os.Stderr, "There is no answer on standard input, use -yes to confirm without asking.\n")
+		}
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "", "yes", "y":
+		return true
+	}
+	return false
+}
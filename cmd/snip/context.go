@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/config"
+	"io"
+	"strings"
+)
+
+// contextTags returns the normalized tags of the context followed by tags, leaving out those of the context when ignoreContext is set
+func contextTags(c config.Context, tags []string, ignoreContext bool) ([]string, error) {
+	all := tags
+	if !ignoreContext {
+		all = append(append([]string{}, c.Tags...), tags...)
+	}
+	var results []string
+	seen := make(map[string]bool)
+	for _, tag := range all {
+		tag, err := snip.NormalizeTag(tag)
+		if err != nil {
+			if !ignoreContext && c.Path != "" {
+				return results, fmt.Errorf("%w (tags of %s are included unless -no-context is given)", err, c.Path)
+			}
+			return results, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			results = append(results, tag)
+		}
+	}
+	return results, nil
+}
+
+// writeContext describes the context of the working directory and the database in use
+func writeContext(w io.Writer, c config.Context, dbFilePath string) {
+	if c.Path == "" {
+		fmt.Fprintf(w, "no %s file in this directory or above it\n", config.ContextFile)
+	} else {
+		fmt.Fprintf(w, "context: %s\n", c.Path)
+		fmt.Fprintf(w, "tags: %s\n", strings.Join(c.Tags, ", "))
+	}
+	fmt.Fprintf(w, "database: %s\n", dbFilePath)
+}
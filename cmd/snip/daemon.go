@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/client"
+	"github.com/ryanfrishkorn/snip/database"
+	"github.com/ryanfrishkorn/snip/server"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// daemonSocketPath returns the socket location for the daemon serving the database at dbFilePath
+func daemonSocketPath(dbFilePath string) string {
+	if socket := os.Getenv("SNIP_SOCKET"); socket != "" {
+		return socket
+	}
+	return dbFilePath + ".sock"
+}
+
+// detectDaemon returns a client if a daemon is accepting connections on socket, otherwise nil
+func detectDaemon(socket string) *client.Client {
+	if option := os.Getenv("SNIP_NO_DAEMON"); option != "" && option != "0" {
+		return nil
+	}
+	if _, err := os.Stat(socket); err != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("unix", socket, 50*time.Millisecond)
+	if err != nil {
+		log.Debug().Err(err).Str("socket", socket).Msg("daemon socket present but not accepting connections")
+		return nil
+	}
+	conn.Close()
+	log.Debug().Str("socket", socket).Msg("using daemon")
+
+	c := client.New("http://snip", "")
+	c.HTTP.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	return c
+}
+
+// reminderInterval is how often the daemon checks for snips that have come due
+const reminderInterval = time.Minute
+
+// runDaemon serves requests on a unix socket until interrupted, purging expired snips every purge interval when it is positive,
+// showing desktop notifications of snips that come due when notify is set, and backing up the database when backups has an interval
+func runDaemon(socket string, purge time.Duration, notify bool, backups backupSchedule) error {
+	// a socket file that refuses connections was left by a daemon that did not exit cleanly
+	if _, err := os.Stat(socket); err == nil {
+		conn, err := net.DialTimeout("unix", socket, 50*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return fmt.Errorf("a daemon is already listening on %s", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	// only the owner may talk to the daemon
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return err
+	}
+
+	if purge > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go purgeExpiredEvery(purge, stop)
+	}
+	if notify {
+		stop := make(chan struct{})
+		defer close(stop)
+		go remindDueEvery(reminderInterval, stop)
+	}
+	if backups.Interval > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go backupEvery(backups, stop)
+	}
+
+	fmt.Fprintf(os.Stderr, "daemon listening on %s\n", socket)
+	return serveUntilInterrupted(listener, server.New())
+}
+
+// purgeExpiredEvery removes expired snips immediately and then at each interval until stop is closed
+func purgeExpiredEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		database.Mu.Lock()
+		purged, err := snip.PurgeExpired()
+		database.Mu.Unlock()
+		if err != nil {
+			log.Debug().Err(err).Msg("error purging expired snips")
+		}
+		for _, id := range purged {
+			fmt.Fprintf(os.Stderr, "purged expired snip %s\n", id)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// remindDueEvery shows a desktop notification for each snip that has come due, checking immediately and then at each interval until stop is closed
+func remindDueEvery(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		database.Mu.Lock()
+		remindDue()
+		database.Mu.Unlock()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// remindDue notifies of each pending reminder, leaving those that could not be shown to be tried again
+func remindDue() {
+	reminders, err := snip.PendingReminders()
+	if err != nil {
+		log.Debug().Err(err).Msg("error listing pending reminders")
+		return
+	}
+	for _, d := range reminders {
+		s, err := snip.GetSnipMeta(d.UUID.String())
+		if err != nil {
+			log.Debug().Err(err).Str("uuid", d.UUID.String()).Msg("error retrieving due snip")
+			continue
+		}
+		err = desktopNotify("snip due", s.Name)
+		if err != nil {
+			log.Debug().Err(err).Str("uuid", d.UUID.String()).Msg("error showing notification")
+			continue
+		}
+		err = snip.MarkReminded(d.UUID)
+		if err != nil {
+			log.Debug().Err(err).Str("uuid", d.UUID.String()).Msg("error marking reminder")
+		}
+	}
+}
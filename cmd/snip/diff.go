@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffLine is a line of a diff, kept, removed, or added as shown by its kind of ' ', '-', or '+'.
+// A and B are the indexes of the line in the old and new text, or of the next line when it is not in that text.
+type diffLine struct {
+	Kind byte
+	Text string
+	A, B int
+}
+
+// splitLines splits text into lines without their line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines finds the fewest removed and added lines turning a into b with the algorithm of Myers,
+// which takes time and memory growing with the number of changes rather than the product of the lengths of the texts
+func diffLines(a, b []string) []diffLine {
+	// lines shared at the start and end are kept without comparing them to everything else
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var lines []diffLine
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, diffLine{Kind: ' ', Text: a[i], A: i, B: i})
+	}
+	for _, l := range shortestEdit(midA, midB) {
+		l.A += prefix
+		l.B += prefix
+		lines = append(lines, l)
+	}
+	for k := 0; k < suffix; k++ {
+		lines = append(lines, diffLine{Kind: ' ', Text: a[len(a)-suffix+k], A: len(a) - suffix + k, B: len(b) - suffix + k})
+	}
+	return lines
+}
+
+// shortestEdit returns the lines of a shortest edit script turning a into b, removals before additions where they meet.
+// For each number of changes d, the furthest point reached along each diagonal k = x - y is kept, and the points of every d are kept to walk back from the end.
+func shortestEdit(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	furthest := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		// the points reached with d-1 changes, indexed by k + d
+		trace = append(trace, append([]int(nil), furthest[offset-d:offset+d+1]...))
+		done := false
+		for k := -d; k <= d && !done; k += 2 {
+			var x int
+			if k == -d || (k != d && furthest[offset+k-1] < furthest[offset+k+1]) {
+				x = furthest[offset+k+1]
+			} else {
+				x = furthest[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			furthest[offset+k] = x
+			done = x >= n && y >= m
+		}
+		if done {
+			break
+		}
+	}
+
+	// walk back from the end, collecting lines in reverse
+	var lines []diffLine
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d] < prev[k+1+d]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			lines = append(lines, diffLine{Kind: ' ', Text: a[x], A: x, B: y})
+		}
+		if prevK == k+1 {
+			y--
+			lines = append(lines, diffLine{Kind: '+', Text: b[y], A: x, B: y})
+		} else {
+			x--
+			lines = append(lines, diffLine{Kind: '-', Text: a[x], A: x, B: y})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		lines = append(lines, diffLine{Kind: ' ', Text: a[x], A: x, B: y})
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+// writeUnifiedDiff writes the changes from a to b as a unified diff with colored lines, writing nothing when they are the same
+func writeUnifiedDiff(w io.Writer, a, b string, labelA, labelB string) {
+	lines := diffLines(splitLines(a), splitLines(b))
+	removed := colors.Removed
+	added := colors.Added
+	hunk := colors.Hunk
+
+	headerWritten := false
+	for start := 0; start < len(lines); {
+		// find the next change
+		first := start
+		for first < len(lines) && lines[first].Kind == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		// extend the hunk over changes separated by no more than twice the context
+		last := first
+		for k := first; k < len(lines) && k-last <= 2*diffContext; k++ {
+			if lines[k].Kind != ' ' {
+				last = k
+			}
+		}
+		lo := first - diffContext
+		if lo < start {
+			lo = start
+		}
+		hi := last + diffContext + 1
+		if hi > len(lines) {
+			hi = len(lines)
+		}
+
+		if !headerWritten {
+			removed.Fprintf(w, "--- %s\n", labelA)
+			added.Fprintf(w, "+++ %s\n", labelB)
+			headerWritten = true
+		}
+		countA, countB := 0, 0
+		for _, l := range lines[lo:hi] {
+			if l.Kind != '+' {
+				countA++
+			}
+			if l.Kind != '-' {
+				countB++
+			}
+		}
+		// an empty range starts at the line before it
+		startA, startB := lines[lo].A+1, lines[lo].B+1
+		if countA == 0 {
+			startA--
+		}
+		if countB == 0 {
+			startB--
+		}
+		hunk.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", startA, countA, startB, countB)
+		for _, l := range lines[lo:hi] {
+			switch l.Kind {
+			case '-':
+				removed.Fprintf(w, "-%s\n", l.Text)
+			case '+':
+				added.Fprintf(w, "+%s\n", l.Text)
+			default:
+				fmt.Fprintf(w, " %s\n", l.Text)
+			}
+		}
+		start = hi
+	}
+}
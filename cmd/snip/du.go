@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+)
+
+// quotaWarningPercent is the share of the storage quota past which adding attachments warns that it is nearly used
+const quotaWarningPercent = 90
+
+// writeUsage lists the snips storing the most data with their attachments, followed by the total stored and the quota
+func writeUsage(w io.Writer, usages []snip.Usage, used int64, long bool) {
+	fmt.Fprintf(w, "%7s %11s  %s\n", "size", "attachments", "snip")
+	for _, u := range usages {
+		id := snip.ShortenUUID(u.UUID)[0]
+		if long {
+			id = u.UUID.String()
+		}
+		fmt.Fprintf(w, "%7s %5d %5s  %s %s\n", snip.FormatSize(u.Size()), u.Attachments, snip.FormatSize(u.AttachmentsSize), id, u.Name)
+	}
+	if snip.StorageQuota > 0 {
+		fmt.Fprintf(w, "%7s total of the %s quota (%d%%)\n", snip.FormatSize(used), snip.FormatSize(snip.StorageQuota), used*100/snip.StorageQuota)
+		return
+	}
+	fmt.Fprintf(w, "%7s total\n", snip.FormatSize(used))
+}
+
+// warnStorage writes a warning to w when more than quotaWarningPercent of the storage quota is used
+func warnStorage(w io.Writer) error {
+	if snip.StorageQuota == 0 {
+		return nil
+	}
+	used, err := snip.StorageUsed()
+	if err != nil {
+		return err
+	}
+	if used*100 > snip.StorageQuota*quotaWarningPercent {
+		fmt.Fprintf(w, "warning: %s of the %s storage quota is used, see snip du for the largest snips\n", snip.FormatSize(used), snip.FormatSize(snip.StorageQuota))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// editorCommand returns the editor named by $VISUAL or $EDITOR, or vi when neither is set
+func editorCommand() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(name); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// editText opens data in the editor as a temporary file named name and returns the text saved.
+// The editor is run with sh so that it may be given with arguments, such as code --wait.
+func editText(data string, name string) (string, error) {
+	dir, err := os.MkdirTemp("", "snip-edit-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, name)
+	if err = os.WriteFile(file, []byte(data), 0600); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("sh", "-c", editorCommand()+` "$1"`, "sh", file)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return "", err
+	}
+	edited, err := os.ReadFile(file)
+	return string(edited), err
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// capture is the output and outcome of a command run by snip exec
+type capture struct {
+	Output   []byte
+	ExitCode int
+	Duration time.Duration
+}
+
+// runCapture runs a command, copying its standard output to echo as it is collected.
+// Standard error is collected as well when stderr is set, otherwise it passes through to the terminal.
+// A command that exits unsuccessfully is not an error, its exit code is recorded instead.
+func runCapture(args []string, stderr bool, echo io.Writer) (capture, error) {
+	var result capture
+	var output bytes.Buffer
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(&output, echo)
+	cmd.Stderr = os.Stderr
+	if stderr {
+		cmd.Stderr = cmd.Stdout
+	}
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	result.Output = output.Bytes()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+		return result, nil
+	}
+	return result, err
+}
+
+// commandLine joins arguments into a line that can be pasted into a shell
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/ryanfrishkorn/snip/client"
+	"github.com/ryanfrishkorn/snip/database"
+	"os"
+)
+
+// Exit codes let scripts tell failures apart without reading the message written to stderr.
+// Failures that fit none of the others, such as a locked snip or a failed verification, exit with exitFailure.
+const (
+	exitFailure   = 1 // any other failure
+	exitNotFound  = 2 // no snip or attachment matches the id
+	exitAmbiguous = 3 // a partial id matches more than one snip or attachment
+	exitInvalid   = 4 // the arguments, options, or configuration are not valid
+	exitDatabase  = 5 // the database could not be opened, read, or written
+)
+
+// exitCode returns the exit code for err
+func exitCode(err error) int {
+	var sqliteErr *sqlite3.Error
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, database.ErrNoRows), errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, database.ErrMultipleRows):
+		return exitAmbiguous
+	case errors.As(err, &sqliteErr):
+		return exitDatabase
+	}
+	return exitFailure
+}
+
+// exitOnHelp exits successfully when flags failed to parse because help was asked for with -h, since the usage has been written
+func exitOnHelp(err error) {
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// writeExplanation prints the index entries, stage timings, and query plans of an explained search
+func writeExplanation(w io.Writer, e snip.SearchExplanation) {
+	var terms [][]string
+	for _, t := range e.Terms {
+		terms = append(terms, []string{t.Term, t.Stem, strconv.Itoa(t.Snips), strconv.Itoa(t.Occurrences)})
+	}
+	fmt.Fprintln(w, "index terms")
+	writeTable(w, w, []string{"term", "stem", "snips", "occurrences"}, []tableSection{{Rows: terms}}, map[int]bool{2: true, 3: true})
+
+	var stages [][]string
+	for _, s := range e.Stages {
+		stages = append(stages, []string{s.Name, formatDuration(s.Duration), strconv.Itoa(s.Candidates)})
+	}
+	stages = append(stages, []string{"total", formatDuration(e.Total), strconv.Itoa(len(e.Matches))})
+	fmt.Fprintln(w, "\nstages")
+	writeTable(w, w, []string{"stage", "time", "candidates"}, []tableSection{{Rows: stages}}, map[int]bool{1: true, 2: true})
+
+	fmt.Fprintln(w, "\nquery plans")
+	for _, p := range e.Plans {
+		fmt.Fprintf(w, "%s: %s\n", p.Stage, p.Query)
+		for _, step := range p.Steps {
+			fmt.Fprintf(w, "  %s\n", step)
+		}
+	}
+}
+
+// formatDuration rounds d to microseconds for display
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
+
+// formatTermCounts describes how many times each search term occurs in a result, marking those found in its name or tags, followed by the total
+func formatTermCounts(counts []snip.TermCount) string {
+	var parts []string
+	var total int
+	for _, c := range counts {
+		part := fmt.Sprintf("%s %d", c.Term, c.Count)
+		var fields []string
+		if c.Name {
+			fields = append(fields, "name")
+		}
+		if c.Tags {
+			fields = append(fields, "tags")
+		}
+		if len(fields) > 0 {
+			part += " (" + strings.Join(fields, ", ") + ")"
+		}
+		parts = append(parts, part)
+		total += c.Count
+	}
+	return fmt.Sprintf("counts: %s, total %d", strings.Join(parts, ", "), total)
+}
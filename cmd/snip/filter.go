@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// snipFilter selects snips by search terms, tags, rating, and attachments
+type snipFilter struct {
+	Tags           listFlag
+	Starred        bool
+	HasAttachments bool
+	NoAttachments  bool
+	// Terms are index search terms the snips must match, all snips match when there are none
+	Terms []string
+}
+
+// addFilterFlags registers the flags of a snipFilter on fs, where verb describes what is done with the snips
+func addFilterFlags(fs *flag.FlagSet, verb string) *snipFilter {
+	f := &snipFilter{}
+	fs.BoolVar(&f.HasAttachments, "has-attachments", false, verb+" only snips with attachments")
+	fs.BoolVar(&f.NoAttachments, "no-attachments", false, verb+" only snips without attachments")
+	fs.BoolVar(&f.Starred, "starred", false, verb+" only snips rated with star")
+	fs.Var(&f.Tags, "tag", verb+" only snips with tag, may be repeated or comma separated")
+	return f
+}
+
+// IDs returns the uuids of the snips matching the filter
+func (f *snipFilter) IDs() ([]uuid.UUID, error) {
+	if f.HasAttachments && f.NoAttachments {
+		return nil, fmt.Errorf("the -has-attachments and -no-attachments options cannot be used together")
+	}
+
+	var ids []uuid.UUID
+	if len(f.Terms) > 0 {
+		scores, err := snip.Search(f.Terms, 0)
+		if err != nil {
+			return nil, fmt.Errorf("searching for %v: %w", f.Terms, err)
+		}
+		for _, score := range scores {
+			ids = append(ids, score.UUID)
+		}
+	} else {
+		var err error
+		ids, err = snip.GetUnexpiredSnipIDs()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// each filter keeps only the ids it matches
+	keep := func(match func(id uuid.UUID) bool) {
+		var kept []uuid.UUID
+		for _, id := range ids {
+			if match(id) {
+				kept = append(kept, id)
+			}
+		}
+		ids = kept
+	}
+	for _, tag := range f.Tags {
+		tag, err := snip.NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		tagged, err := snip.GetTagUUIDs(tag)
+		if err != nil {
+			return nil, err
+		}
+		hasTag := make(map[uuid.UUID]bool)
+		for _, id := range tagged {
+			hasTag[id] = true
+		}
+		keep(func(id uuid.UUID) bool { return hasTag[id] })
+	}
+	if f.Starred {
+		ratings, err := snip.ListStars()
+		if err != nil {
+			return nil, err
+		}
+		keep(func(id uuid.UUID) bool { return ratings[id] > 0 })
+	}
+	if f.HasAttachments || f.NoAttachments {
+		counts, err := snip.ListAttachmentCounts()
+		if err != nil {
+			return nil, err
+		}
+		keep(func(id uuid.UUID) bool { return (counts[id] > 0) == f.HasAttachments })
+	}
+	return ids, nil
+}
+
+// withinIDs returns the uuids of the snips a search is narrowed to, given by value as uuids separated by spaces or commas,
+// as - to read them from the start of each line of stdin as printed by ls or search, or otherwise as the terms of an earlier search
+func withinIDs(value string, stdin io.Reader) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	list := strings.FieldsFunc(value, func(c rune) bool {
+		return c == ',' || unicode.IsSpace(c)
+	})
+	if value == "-" {
+		var err error
+		if list, err = readIDs(stdin); err != nil {
+			return ids, fmt.Errorf("reading uuids from standard input: %w", err)
+		}
+	}
+	for _, id := range list {
+		if idPattern.MatchString(id) {
+			continue
+		}
+		// terms of an earlier search
+		scores, err := snip.Search(strings.Fields(value), 0)
+		if err != nil {
+			return ids, fmt.Errorf("searching for %s: %w", value, err)
+		}
+		for _, score := range scores {
+			ids = append(ids, score.UUID)
+		}
+		return ids, nil
+	}
+	for _, id := range list {
+		s, err := snip.GetSnipMeta(id)
+		if err != nil {
+			return ids, fmt.Errorf("snip %s could not be found: %w", id, err)
+		}
+		ids = append(ids, s.UUID)
+	}
+	return ids, nil
+}
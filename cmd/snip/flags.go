@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the formats accepted for user supplied times, without a zone they are local time
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// durationUnits are the duration suffixes accepted by parseDuration in addition to those of time.ParseDuration
+var durationUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// parseInterspersed parses flags that may appear before or after positional arguments, leaving the positional arguments in fs.Args()
+func parseInterspersed(fs *flag.FlagSet, args []string) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	return fs.Parse(append([]string{"--"}, positional...))
+}
+
+// listFlag collects the values of a flag that may be given more than once, each of which may be comma separated
+type listFlag []string
+
+// String returns the values joined with commas
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set adds the comma separated values
+func (l *listFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// sizeUnits are the suffixes accepted by parseSize, in powers of 1024
+var sizeUnits = map[string]int64{
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+}
+
+// parseSize reads a number of bytes, optionally followed by K, M, or G
+func parseSize(value string) (int64, error) {
+	number, unit := value, int64(1)
+	for suffix, multiplier := range sizeUnits {
+		if strings.HasSuffix(strings.ToUpper(value), suffix) {
+			number, unit = value[:len(value)-1], multiplier
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a size such as 512K or 32M", value)
+	}
+	return n * unit, nil
+}
+
+// parseTimestamp reads a time in one of the timestampLayouts
+func parseTimestamp(value string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		t, err := time.ParseInLocation(layout, value, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("timestamp %q is not in a recognized format such as 2006-01-02 15:04 or RFC 3339", value)
+}
+
+// parseDuration reads a duration such as 12h, 7d, or 2w
+func parseDuration(value string) (time.Duration, error) {
+	for suffix, unit := range durationUnits {
+		count, err := strconv.Atoi(strings.TrimSuffix(value, suffix))
+		if err == nil && strings.HasSuffix(value, suffix) {
+			return time.Duration(count) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration such as 7d or 12h", value)
+	}
+	return d, nil
+}
+
+// parseWhen reads a time as a duration from now, such as 12h, 7d, or 2w, or as a time in one of the timestampLayouts
+func parseWhen(value string, now time.Time) (time.Time, error) {
+	if d, err := parseDuration(value); err == nil {
+		return now.Add(d), nil
+	}
+	t, err := parseTimestamp(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a duration such as 7d or 12h, or a time such as 2006-01-02 15:04", value)
+	}
+	return t, nil
+}
+
+// parseExpiry reads an expiry with parseWhen, which must be in the future
+func parseExpiry(value string, now time.Time) (time.Time, error) {
+	t, err := parseWhen(value, now)
+	if err != nil {
+		return t, fmt.Errorf("expiry %w", err)
+	}
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("expiry %q must be in the future", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// idPattern matches a short or full uuid at the start of a line of ls or search output
+var idPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})?$`)
+
+// readIDs returns the uuid that begins each line of r, skipping lines such as headers and search context
+func readIDs(r io.Reader) ([]string, error) {
+	var ids []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && idPattern.MatchString(fields[0]) {
+			ids = append(ids, fields[0])
+		}
+	}
+	return ids, scanner.Err()
+}
+
+// writeSnipHeader writes the identifying lines of a snip followed by a separator line
+func writeSnipHeader(w io.Writer, s snip.Snip) {
+	fmt.Fprintf(w, "uuid: %s\n", s.UUID.String())
+	fmt.Fprintf(w, "name: %s\n", s.Name)
+	fmt.Fprintf(w, "timestamp: %s\n", s.Timestamp.Format(time.RFC3339Nano))
+	if len(s.Tags) > 0 {
+		fmt.Fprintf(w, "tags: %s\n", strings.Join(s.Tags, " "))
+	}
+	fmt.Fprintf(w, "----\n")
+}
+
+// writeSnip writes a snip with its header, attachments, and metadata as displayed by get
+func writeSnip(w io.Writer, s snip.Snip) {
+	writeSnipHeader(w, s)
+	if isBinary(s.Data) {
+		// binary data would garble the terminal, it is available with -raw or -base64
+		fmt.Fprintf(w, "(binary data, %d bytes)\n", len(s.Data))
+	} else {
+		fmt.Fprintf(w, "%s", s.Data)
+		// add an extra newline if the data does not end with one
+		// no one likes their prompt hijacked. This will not affect raw output.
+		if !strings.HasSuffix(s.Data, "\n") {
+			fmt.Fprintln(w)
+		}
+	}
+	fmt.Fprintf(w, "----\n")
+	for idx, a := range s.Attachments {
+		// print attachments if present
+		if idx == 0 {
+			fmt.Fprintf(w, "attachments:\n")
+			fmt.Fprintf(w, "%s %42s %s\n", "uuid", "bytes", "name")
+		}
+		fmt.Fprintf(w, "%s %10d %s\n", a.UUID.String(), a.Size, a.Name)
+	}
+	// print metadata if present
+	var keys []string
+	for key := range s.Meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for idx, key := range keys {
+		if idx == 0 {
+			fmt.Fprintf(w, "metadata:\n")
+		}
+		fmt.Fprintf(w, "%s: %s\n", key, s.Meta[key])
+	}
+}
+
+// writeNotes writes each note with its id and date, indenting the lines of multi-line notes after the first
+func writeNotes(w io.Writer, notes []snip.Note) {
+	for _, n := range notes {
+		text := strings.ReplaceAll(n.Text, "\n", "\n    ")
+		fmt.Fprintf(w, "%d %s %s\n", n.ID, n.Timestamp.Format("2006-01-02 15:04"), text)
+	}
+}
+
+// isBinary reports whether data contains NUL bytes or is not valid UTF-8
+func isBinary(data string) bool {
+	return strings.IndexByte(data, 0) >= 0 || !utf8.ValidString(data)
+}
+
+// lineRange selects lines of snip data, numbered from one
+type lineRange struct {
+	// First is the first line written, zero for the start of the data
+	First int
+	// Last is the last line written, zero for the end of the data
+	Last int
+	// Tail selects the final lines instead when positive
+	Tail int
+}
+
+// parseLineRange reads a range of lines such as 20:40, 20:, :40, or a single line number
+func parseLineRange(value string) (lineRange, error) {
+	var lines lineRange
+	first, last, found := strings.Cut(value, ":")
+	if !found {
+		last = first
+	}
+	var err error
+	if first != "" {
+		if lines.First, err = strconv.Atoi(first); err != nil || lines.First < 1 {
+			return lines, fmt.Errorf("line range %q must use line numbers starting from 1", value)
+		}
+	}
+	if last != "" {
+		if lines.Last, err = strconv.Atoi(last); err != nil || lines.Last < 1 {
+			return lines, fmt.Errorf("line range %q must use line numbers starting from 1", value)
+		}
+	}
+	if lines.Last != 0 && lines.Last < lines.First {
+		return lines, fmt.Errorf("line range %q ends before it starts", value)
+	}
+	return lines, nil
+}
+
+// writeLines copies the selected lines of r to w, reading no further than needed
+func writeLines(w io.Writer, r io.ReadSeeker, lines lineRange) error {
+	if lines.Tail > 0 {
+		offset, err := tailOffset(r, lines.Tail)
+		if err != nil {
+			return err
+		}
+		if _, err = r.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		_, err = io.Copy(w, r)
+		return err
+	}
+
+	reader := bufio.NewReader(r)
+	number := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			number++
+			if number >= lines.First {
+				if _, werr := io.WriteString(w, line); werr != nil {
+					return werr
+				}
+			}
+			if lines.Last != 0 && number >= lines.Last {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// tailOffset returns the position at which the last count lines of r begin, reading backwards from the end
+func tailOffset(r io.ReadSeeker, count int) (int64, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 64*1024)
+	found := 0
+	end := size
+	for end > 0 {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		chunk := buf[:end-start]
+		if _, err = r.Seek(start, io.SeekStart); err != nil {
+			return 0, err
+		}
+		if _, err = io.ReadFull(r, chunk); err != nil {
+			return 0, err
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			// a final newline ends the last line rather than beginning another
+			if chunk[i] != '\n' || start+int64(i) == size-1 {
+				continue
+			}
+			found++
+			if found == count {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// gitHookMarker identifies post-commit hooks written by snip hook install, which are the only ones it replaces or removes without -force
+const gitHookMarker = "# installed by snip hook install"
+
+// git runs git within dir, returning its output without the trailing newline
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+// gitHookPath returns the path of the post-commit hook of the repository containing dir, honoring core.hooksPath and worktrees
+func gitHookPath(dir string) (string, error) {
+	hooks, err := git(dir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(hooks) {
+		hooks = filepath.Join(dir, hooks)
+	}
+	return filepath.Join(hooks, "post-commit"), nil
+}
+
+// installGitHook writes a post-commit hook running exe with args to the repository containing dir, returning the path of the hook.
+// A hook not written by snip is replaced only when force is set.
+func installGitHook(dir string, exe string, args []string, force bool) (string, error) {
+	path, err := gitHookPath(dir)
+	if err != nil {
+		return path, err
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return path, err
+	}
+	if err == nil && !bytes.Contains(existing, []byte(gitHookMarker)) && !force {
+		return path, fmt.Errorf("%s already exists and was not installed by snip, use -force to replace it", path)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return path, err
+	}
+	// a failure to journal the commit is reported without failing git
+	script := fmt.Sprintf("#!/bin/sh\n%s, journaling each commit as a snip\n%s || true\n", gitHookMarker, commandLine(append([]string{exe}, args...)))
+	return path, os.WriteFile(path, []byte(script), 0755)
+}
+
+// uninstallGitHook removes the post-commit hook of the repository containing dir if snip installed it, returning the path of the hook
+func uninstallGitHook(dir string) (string, error) {
+	path, err := gitHookPath(dir)
+	if err != nil {
+		return path, err
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return path, err
+	}
+	if !bytes.Contains(existing, []byte(gitHookMarker)) {
+		return path, fmt.Errorf("%s was not installed by snip and is left in place", path)
+	}
+	return path, os.Remove(path)
+}
+
+// commitSnip returns a snip of the latest commit of the repository containing dir, named by its subject and holding its message.
+// The diff of the commit follows the message when pattern matches it, and the snip is tagged commit and with the name of the repository.
+func commitSnip(dir string, pattern *regexp.Regexp) (snip.Snip, error) {
+	s := snip.New()
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return s, err
+	}
+	info, err := git(dir, "log", "-1", "--format=%H%x00%an%x00%cI%x00%s%x00%B")
+	if err != nil {
+		return s, err
+	}
+	fields := strings.SplitN(info, "\x00", 5)
+	if len(fields) != 5 {
+		return s, fmt.Errorf("unexpected commit description %q", info)
+	}
+	hash, author, committed, subject, message := fields[0], fields[1], fields[2], fields[3], fields[4]
+	if s.Timestamp, err = time.Parse(time.RFC3339, committed); err != nil {
+		return s, err
+	}
+	s.Name = subject
+	s.Data = strings.TrimSpace(message) + "\n"
+	if pattern != nil {
+		diff, err := git(dir, "show", "--format=", "--patch", "HEAD")
+		if err != nil {
+			return s, err
+		}
+		if pattern.MatchString(diff) {
+			s.Data += "\n" + diff + "\n"
+		}
+	}
+	s.Tags = []string{"commit"}
+	// repositories named with characters tags do not allow are tagged commit alone
+	if repo, err := snip.NormalizeTag(filepath.Base(top)); err == nil {
+		s.Tags = append(s.Tags, repo)
+	}
+	s.Meta = map[string]string{
+		"commit":     hash,
+		"author":     author,
+		"repository": top,
+	}
+	return s, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip"
+	"sort"
+	"strings"
+)
+
+// groupings are the ways ls can group snips
+var groupings = []string{"day", "month", "tag", "status"}
+
+// untaggedGroup is the title of the group of snips without tags
+const untaggedGroup = "(untagged)"
+
+// snipGroup is a set of snips listed under a common title
+type snipGroup struct {
+	Title string
+	Snips []snip.Snip
+}
+
+// groupSnips groups snips by the local day or month they were created, by each of their tags, or by their status.
+// Groups by time are in chronological order, groups by tag in alphabetical order followed by untagged snips,
+// and groups by status in the order tasks move through them, like the columns of a board, followed by snips without one.
+// Snips keep their order within a group.
+func groupSnips(snips []snip.Snip, groupBy string, tags map[uuid.UUID][]string, statuses map[uuid.UUID]snip.Status) ([]snipGroup, error) {
+	var keys func(s snip.Snip) []string
+	switch groupBy {
+	case "day":
+		keys = func(s snip.Snip) []string { return []string{s.Timestamp.Local().Format("2006-01-02")} }
+	case "month":
+		keys = func(s snip.Snip) []string { return []string{s.Timestamp.Local().Format("2006-01")} }
+	case "tag":
+		keys = func(s snip.Snip) []string {
+			if len(tags[s.UUID]) == 0 {
+				return []string{untaggedGroup}
+			}
+			return tags[s.UUID]
+		}
+	case "status":
+		keys = func(s snip.Snip) []string { return []string{statuses[s.UUID].String()} }
+	default:
+		return nil, fmt.Errorf("grouping %s is not supported (%s)", groupBy, strings.Join(groupings, "|"))
+	}
+
+	grouped := make(map[string][]snip.Snip)
+	var titles []string
+	for _, s := range snips {
+		for _, key := range keys(s) {
+			if _, ok := grouped[key]; !ok {
+				titles = append(titles, key)
+			}
+			grouped[key] = append(grouped[key], s)
+		}
+	}
+	sort.Slice(titles, func(i, j int) bool {
+		if groupBy == "status" {
+			return statusRank(titles[i]) < statusRank(titles[j])
+		}
+		// untagged snips are listed last
+		if titles[i] == untaggedGroup || titles[j] == untaggedGroup {
+			return titles[j] == untaggedGroup && titles[i] != untaggedGroup
+		}
+		return titles[i] < titles[j]
+	})
+
+	var groups []snipGroup
+	for _, title := range titles {
+		groups = append(groups, snipGroup{Title: title, Snips: grouped[title]})
+	}
+	return groups, nil
+}
+
+// statusRank returns the position of the group of a status on a board, with snips without a status last
+func statusRank(title string) int {
+	for idx, s := range snip.Statuses {
+		if title == s.String() {
+			return idx
+		}
+	}
+	return len(snip.Statuses)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyEntry is a single command read from a shell history file
+type historyEntry struct {
+	Command string
+	// Time is zero when the history file does not record timestamps
+	Time time.Time
+}
+
+// zshExtended matches the prefix of a zsh extended history line, ": <start>:<elapsed>;"
+var zshExtended = regexp.MustCompile(`^: (\d+):\d+;`)
+
+// historyFile returns the default history file location of shell
+func historyFile(shell string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch shell {
+	case "bash":
+		return filepath.Join(home, ".bash_history"), nil
+	case "zsh":
+		return filepath.Join(home, ".zsh_history"), nil
+	case "fish":
+		return filepath.Join(home, ".local", "share", "fish", "fish_history"), nil
+	}
+	return "", fmt.Errorf("unsupported shell %q (bash|fish|zsh)", shell)
+}
+
+// parseHistory reads the commands of a history file in the format of shell
+func parseHistory(r io.Reader, shell string) ([]historyEntry, error) {
+	switch shell {
+	case "bash":
+		return parseBashHistory(r)
+	case "zsh":
+		return parseZshHistory(r)
+	case "fish":
+		return parseFishHistory(r)
+	}
+	return nil, fmt.Errorf("unsupported shell %q (bash|fish|zsh)", shell)
+}
+
+// historyScanner returns a line scanner that tolerates very long commands
+func historyScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return scanner
+}
+
+// parseBashHistory reads one command per line, with timestamps from HISTTIMEFORMAT comments when present
+func parseBashHistory(r io.Reader) ([]historyEntry, error) {
+	var entries []historyEntry
+	var when time.Time
+	scanner := historyScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			if seconds, err := strconv.ParseInt(line[1:], 10, 64); err == nil {
+				when = time.Unix(seconds, 0)
+				continue
+			}
+		}
+		entries = append(entries, historyEntry{Command: line, Time: when})
+		when = time.Time{}
+	}
+	return entries, scanner.Err()
+}
+
+// parseZshHistory reads plain or extended zsh history, joining commands continued with a trailing backslash
+func parseZshHistory(r io.Reader) ([]historyEntry, error) {
+	var entries []historyEntry
+	var current *historyEntry
+	scanner := historyScanner(r)
+	for scanner.Scan() {
+		line := unmetafy(scanner.Text())
+		if current != nil {
+			current.Command += "\n" + line
+		} else {
+			entries = append(entries, historyEntry{Command: line})
+			current = &entries[len(entries)-1]
+			if match := zshExtended.FindStringSubmatch(line); match != nil {
+				seconds, _ := strconv.ParseInt(match[1], 10, 64)
+				current.Time = time.Unix(seconds, 0)
+				current.Command = line[len(match[0]):]
+			}
+		}
+		if strings.HasSuffix(line, `\`) {
+			current.Command = strings.TrimSuffix(current.Command, `\`)
+			continue
+		}
+		current = nil
+	}
+	return entries, scanner.Err()
+}
+
+// unmetafy decodes the bytes zsh escapes in its history file, each stored as 0x83 followed by the byte xor 32
+func unmetafy(line string) string {
+	if !strings.Contains(line, "\x83") {
+		return line
+	}
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == 0x83 && i+1 < len(line) {
+			i++
+			b.WriteByte(line[i] ^ 32)
+			continue
+		}
+		b.WriteByte(line[i])
+	}
+	return b.String()
+}
+
+// parseFishHistory reads the yaml-like fish history format of "- cmd:" and "when:" lines
+func parseFishHistory(r io.Reader) ([]historyEntry, error) {
+	var entries []historyEntry
+	scanner := historyScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if command, ok := strings.CutPrefix(line, "- cmd: "); ok {
+			// fish escapes newlines and backslashes within commands
+			command = strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(command)
+			entries = append(entries, historyEntry{Command: command})
+			continue
+		}
+		if when, ok := strings.CutPrefix(strings.TrimSpace(line), "when: "); ok && len(entries) > 0 {
+			seconds, err := strconv.ParseInt(when, 10, 64)
+			if err == nil {
+				entries[len(entries)-1].Time = time.Unix(seconds, 0)
+			}
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// dedupeHistory removes blank and repeated commands, keeping the most recent use of each
+func dedupeHistory(entries []historyEntry) []historyEntry {
+	latest := make(map[string]int)
+	var unique []historyEntry
+	for _, entry := range entries {
+		entry.Command = strings.TrimSpace(entry.Command)
+		if entry.Command == "" {
+			continue
+		}
+		if idx, ok := latest[entry.Command]; ok {
+			if entry.Time.After(unique[idx].Time) {
+				unique[idx].Time = entry.Time
+			}
+			continue
+		}
+		latest[entry.Command] = len(unique)
+		unique = append(unique, entry)
+	}
+	return unique
+}
+
+// historyDays groups entries by the local date they were run, in chronological order.
+// Entries without a timestamp are grouped under the date given as undated.
+func historyDays(entries []historyEntry, undated time.Time) (days []string, byDay map[string][]historyEntry) {
+	byDay = make(map[string][]historyEntry)
+	for _, entry := range entries {
+		when := entry.Time
+		if when.IsZero() {
+			when = undated
+		}
+		day := when.Local().Format("2006-01-02")
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], entry)
+	}
+	sort.Strings(days)
+	return days, byDay
+}
+
+// mergeHistoryLines returns the commands of a daily history snip followed by the lines of imported that it does not have yet
+func mergeHistoryLines(data string, imported string) string {
+	have := make(map[string]bool)
+	for _, line := range splitLines(data) {
+		have[line] = true
+	}
+	merged := data
+	if merged != "" && !strings.HasSuffix(merged, "\n") {
+		merged += "\n"
+	}
+	for _, line := range splitLines(imported) {
+		if !have[line] {
+			have[line] = true
+			merged += line + "\n"
+		}
+	}
+	if merged == data+"\n" {
+		return data
+	}
+	return merged
+}
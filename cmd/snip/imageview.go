@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// inline image protocols of terminals, and opening the image in the desktop's viewer instead
+const (
+	protocolKitty = "kitty"
+	protocolITerm = "iterm"
+	protocolSixel = "sixel"
+	protocolOpen  = "open"
+)
+
+// kittyChunkSize is the most base64 data the kitty graphics protocol accepts in one escape sequence
+const kittyChunkSize = 4096
+
+// detectImageProtocol returns the inline image protocol of the terminal described by the environment, or open when none is known
+func detectImageProtocol(getenv func(string) string) string {
+	term, program := getenv("TERM"), getenv("TERM_PROGRAM")
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" || program == "ghostty" || term == "xterm-ghostty":
+		return protocolKitty
+	case program == "iTerm.app" || getenv("LC_TERMINAL") == "iTerm2" || program == "WezTerm":
+		return protocolITerm
+	case strings.Contains(term, "sixel") || term == "foot" || strings.HasPrefix(term, "foot-") || term == "mlterm" || program == "mlterm":
+		return protocolSixel
+	}
+	return protocolOpen
+}
+
+// writeInlineImage writes an image attachment to w with an inline image protocol, reduced to at most size pixels across and down
+func writeInlineImage(w io.Writer, protocol string, a snip.Attachment, size int) error {
+	contentType, data, err := snip.MakeThumbnail(a.Data, size)
+	if err != nil {
+		return err
+	}
+	switch protocol {
+	case protocolITerm:
+		// iterm displays png and jpeg data as it is
+		fmt.Fprintf(w, "\x1b]1337;File=name=%s;size=%d;inline=1:%s\a\n",
+			base64.StdEncoding.EncodeToString([]byte(a.Name)), len(data), base64.StdEncoding.EncodeToString(data))
+		return nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decoding %s thumbnail: %w", contentType, err)
+	}
+	switch protocol {
+	case protocolKitty:
+		return writeKittyImage(w, img)
+	case protocolSixel:
+		writeSixelImage(w, img)
+		return nil
+	}
+	return fmt.Errorf("image protocol %s is not supported (%s|%s|%s|%s)", protocol, protocolKitty, protocolITerm, protocolSixel, protocolOpen)
+}
+
+// writeKittyImage sends img as png data in chunks with the kitty graphics protocol, displaying it at the cursor
+func writeKittyImage(w io.Writer, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	for first := true; first || encoded != ""; first = false {
+		chunk := encoded
+		if len(chunk) > kittyChunkSize {
+			chunk = chunk[:kittyChunkSize]
+		}
+		encoded = encoded[len(chunk):]
+		more := 0
+		if encoded != "" {
+			more = 1
+		}
+		// only the first chunk carries the format and action, and m=1 announces further chunks
+		if first {
+			fmt.Fprintf(w, "\x1b_Ga=T,f=100,q=2,m=%d;%s\x1b\\", more, chunk)
+		} else {
+			fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// writeSixelImage writes img as sixel graphics using a palette of 216 colors, leaving transparent pixels unpainted
+func writeSixelImage(w io.Writer, img image.Image) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	// palette index of each pixel, or -1 where transparent
+	pixels := make([]int, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, alpha := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			if alpha < 0x8000 {
+				pixels[y*width+x] = -1
+				continue
+			}
+			// colors are stored premultiplied, so partly transparent pixels are restored before choosing the nearest
+			level := func(c uint32) int {
+				return int((c*0xffff/alpha*5 + 0x7fff) / 0xffff)
+			}
+			pixels[y*width+x] = level(r)*36 + level(g)*6 + level(b)
+		}
+	}
+
+	bw := &bytes.Buffer{}
+	// P2=1 leaves pixels that are not painted with the background of the terminal
+	fmt.Fprintf(bw, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+	for idx := 0; idx < 216; idx++ {
+		fmt.Fprintf(bw, "#%d;2;%d;%d;%d", idx, idx/36*20, idx/6%6*20, idx%6*20)
+	}
+	for top := 0; top < height; top += 6 {
+		used := make(map[int]bool)
+		for y := top; y < top+6 && y < height; y++ {
+			for _, c := range pixels[y*width : (y+1)*width] {
+				if c >= 0 {
+					used[c] = true
+				}
+			}
+		}
+		for c := 0; c < 216; c++ {
+			if !used[c] {
+				continue
+			}
+			fmt.Fprintf(bw, "#%d", c)
+			var run int
+			var last byte
+			for x := 0; x < width; x++ {
+				var bits byte
+				for row := 0; row < 6 && top+row < height; row++ {
+					if pixels[(top+row)*width+x] == c {
+						bits |= 1 << row
+					}
+				}
+				char := '?' + bits
+				if run > 0 && char != last {
+					writeSixelRun(bw, last, run)
+					run = 0
+				}
+				last = char
+				run++
+			}
+			writeSixelRun(bw, last, run)
+			// return to the start of the band for the next color
+			bw.WriteByte('$')
+		}
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\\n")
+	w.Write(bw.Bytes())
+}
+
+// writeSixelRun writes a sixel character repeated run times, using the repeat introducer for longer runs
+func writeSixelRun(w *bytes.Buffer, char byte, run int) {
+	if run > 3 {
+		fmt.Fprintf(w, "!%d%c", run, char)
+		return
+	}
+	for i := 0; i < run; i++ {
+		w.WriteByte(char)
+	}
+}
+
+// openExternally writes an attachment to a temporary file named after it and opens it with the desktop's viewer, returning the path.
+// The file is left in place, since the viewer may read it after this returns.
+func openExternally(a snip.Attachment) (string, error) {
+	dir, err := os.MkdirTemp("", "snip-view-")
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, filepath.Base(a.Name))
+	if err = os.WriteFile(file, a.Data, 0600); err != nil {
+		return file, err
+	}
+	var command *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		command = exec.Command("open", file)
+	case "windows":
+		command = exec.Command("cmd", "/c", "start", "", file)
+	default:
+		command = exec.Command("xdg-open", file)
+	}
+	return file, command.Start()
+}
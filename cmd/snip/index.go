@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/ryanfrishkorn/snip"
+	"os"
+)
+
+// exportIndex writes the search index to file, removing the file when it could not be written completely
+func exportIndex(file string, progress snip.Progress) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = snip.ExportIndex(f, progress); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(file)
+	}
+	return err
+}
+
+// importIndex replaces the search index with the one written to file by exportIndex
+func importIndex(file string, progress snip.Progress) (snip.IndexImport, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return snip.IndexImport{}, err
+	}
+	defer f.Close()
+	return snip.ImportIndex(f, progress)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"strings"
+	"time"
+)
+
+// parseJournalDay reads the day of a journal entry as 2006-01-02, today, or yesterday
+func parseJournalDay(value string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch value {
+	case "", "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	day, err := time.ParseInLocation(snip.JournalDateLayout, value, now.Location())
+	if err != nil {
+		return day, fmt.Errorf("%q is not a day such as 2006-01-02, today, or yesterday", value)
+	}
+	return day, nil
+}
+
+// parseMonth reads a month such as 2006-01, returning its first day and the first day of the month after
+func parseMonth(value string, loc *time.Location) (time.Time, time.Time, error) {
+	first, err := time.ParseInLocation("2006-01", value, loc)
+	if err != nil {
+		return first, first, fmt.Errorf("%q is not a month such as 2006-01", value)
+	}
+	return first, first.AddDate(0, 1, 0), nil
+}
+
+// writeJournal lists journal entries with their day and first line
+func writeJournal(w io.Writer, entries []snip.Snip, long bool) {
+	for _, s := range entries {
+		id := snip.ShortenUUID(s.UUID)[0]
+		if long {
+			id = s.UUID.String()
+		}
+		first := strings.TrimSpace(strings.SplitN(strings.TrimSpace(s.Data), "\n", 2)[0])
+		fmt.Fprintf(w, "%s %s  %s\n", id, s.Name, first)
+	}
+}
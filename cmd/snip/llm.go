@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/llm"
+	"io"
+	"strings"
+)
+
+// writeSending lists the snips about to be sent to a language model, so that nothing is sent without being seen
+func writeSending(w io.Writer, p llm.Provider, snips []snip.Snip) {
+	var size int64
+	for _, s := range snips {
+		size += int64(len(s.Data))
+	}
+	fmt.Fprintf(w, "sending %d snips (%s) to %s:\n", len(snips), snip.FormatSize(size), p)
+	for _, s := range snips {
+		fmt.Fprintf(w, "  %s %s\n", snip.ShortenUUID(s.UUID)[0], s.Name)
+	}
+}
+
+// answerData is the data of a snip saving the answer to a question, linking the snips it was given so that they list it as a backlink
+func answerData(answer string, snips []snip.Snip) string {
+	var data strings.Builder
+	data.WriteString(strings.TrimSpace(answer))
+	data.WriteString("\n\nAnswered from:\n")
+	for _, s := range snips {
+		fmt.Fprintf(&data, "- [%s](%s%s)\n", s.Name, snip.SnipLinkScheme, s.UUID)
+	}
+	return data.String()
+}
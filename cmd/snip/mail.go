@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/ryanfrishkorn/snip/config"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+)
+
+// sendMail delivers a composed message through the configured smtp server
+func sendMail(conf config.SMTP, from string, to []string, msg []byte) error {
+	if conf.Host == "" {
+		return fmt.Errorf("smtp host is not configured")
+	}
+	port := conf.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(conf.Host, strconv.Itoa(port))
+
+	// the envelope carries bare addresses without display names
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("sender %q: %w", from, err)
+	}
+	var recipients []string
+	for _, rcpt := range to {
+		address, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return fmt.Errorf("recipient %q: %w", rcpt, err)
+		}
+		recipients = append(recipients, address.Address)
+	}
+
+	var auth smtp.Auth
+	if conf.Username != "" {
+		password := conf.Password
+		if env := os.Getenv("SNIP_SMTP_PASSWORD"); env != "" {
+			password = env
+		}
+		auth = smtp.PlainAuth("", conf.Username, password, conf.Host)
+	}
+
+	// SendMail upgrades with starttls when offered, but port 465 expects tls from the start
+	if port != 465 {
+		return smtp.SendMail(addr, auth, sender.Address, recipients, msg)
+	}
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: conf.Host})
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, conf.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if auth != nil {
+		if err = c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err = c.Mail(sender.Address); err != nil {
+		return err
+	}
+	for _, rcpt := range recipients {
+		if err = c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -19,7 +19,10 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package main
 
 import (
-	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/bvinc/go-sqlite-lite/sqlite3"
@@ -28,11 +31,18 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/client"
+	"github.com/ryanfrishkorn/snip/config"
 	"github.com/ryanfrishkorn/snip/database"
+	"github.com/ryanfrishkorn/snip/export"
+	"github.com/ryanfrishkorn/snip/llm"
+	"github.com/ryanfrishkorn/snip/server"
 	"io"
 	"math/rand"
 	"os"
 	"path"
+	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -49,129 +59,803 @@ func main() {
 		zerolog.SetGlobalLevel(zerolog.DebugLevel)
 	}
 
-	// check env for explicit database path
+	// a .snip file in the working directory or one above it pins the context of a project
+	workDir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "The working directory could not be determined.\n")
+		log.Debug().Err(err).Msg("error reading working directory")
+		os.Exit(exitFailure)
+	}
+	projectContext, err := config.FindContext(workDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "The %s file could not be read: %v\n", config.ContextFile, err)
+		log.Debug().Err(err).Str("dir", workDir).Msg("error loading context")
+		os.Exit(exitInvalid)
+	}
+
+	// check env for explicit database path, then the context
 	dbFilePath := os.Getenv("SNIP_DB")
+	if dbFilePath == "" && projectContext.Database != "" {
+		// a .snip file checked out with a project must not switch databases unnoticed
+		dbFilePath = projectContext.Database
+		fmt.Fprintf(os.Stderr, "using the database %s of %s\n", dbFilePath, projectContext.Path)
+	}
 	if dbFilePath == "" {
 		homePath := os.Getenv("HOME")
 		dbFilename := ".snip.sqlite3"
 		if homePath == "" {
 			fmt.Fprintf(os.Stderr, "please $HOME env to your home directory for database save location")
 			log.Debug().Msg("could not retrieve $HOME environment variable")
-			os.Exit(1)
+			os.Exit(exitFailure)
 		}
 		dbFilePath = homePath + "/" + dbFilename
 	}
 
+	// user configuration is optional
+	conf, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "The configuration file %s could not be read.\n", config.Path())
+		log.Debug().Err(err).Str("path", config.Path()).Msg("error loading configuration")
+		os.Exit(exitInvalid)
+	}
+	nameStrategy := snip.DefaultNameStrategy
+	if conf.Naming.Strategy != "" {
+		nameStrategy.Kind = conf.Naming.Strategy
+	}
+	if conf.Naming.Words > 0 {
+		nameStrategy.Words = conf.Naming.Words
+	}
+	nameStrategy.Template = conf.Naming.Template
+	if err = nameStrategy.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "The naming setting in %s is not valid: %v\n", config.Path(), err)
+		os.Exit(exitInvalid)
+	}
+	snip.IndexTokenizer, err = snip.NewTokenizer(conf.Search.Tokenizer, conf.Search.Ngram)
+	if conf.Search.Boost.Name != nil {
+		snip.SearchBoost.Name = *conf.Search.Boost.Name
+	}
+	if conf.Search.Boost.Tags != nil {
+		snip.SearchBoost.Tags = *conf.Search.Boost.Tags
+	}
+	if err == nil {
+		err = snip.SearchBoost.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "The search setting in %s is not valid: %v\n", config.Path(), err)
+		os.Exit(exitInvalid)
+	}
+	// blobs stored earlier are read from the directory even when new attachments are kept in the database
+	snip.BlobDir = conf.Attachments.BlobDir
+	if snip.BlobDir == "" {
+		snip.BlobDir = dbFilePath + ".blobs"
+	}
+	snip.StoreBlobs = conf.Attachments.Blobs
+	for _, limit := range []struct {
+		value string
+		dst   *int64
+	}{{conf.Attachments.MaxSize, &snip.MaxAttachmentSize}, {conf.Attachments.Quota, &snip.StorageQuota}} {
+		if limit.value == "" {
+			continue
+		}
+		if *limit.dst, err = parseSize(limit.value); err != nil {
+			fmt.Fprintf(os.Stderr, "The attachments setting in %s is not valid: %v\n", config.Path(), err)
+			os.Exit(exitInvalid)
+		}
+	}
+	colors, err = loadTheme(conf.Theme)
+	if err == nil {
+		err = setColorMode(conf.Color)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "The color settings in %s are not valid: %v\n", config.Path(), err)
+		os.Exit(exitInvalid)
+	}
+	// the color mode of the configuration is the default of the -color flag of commands that color their output
+	colorMode := conf.Color
+
 	helpMessage :=
 		`usage:
 snip add                        add a new snip from standard input
        -f <file>                data from file instead of stdin default
-       -n <name>                use specified name
+       -n, -name <name>         use specified name
+       -tag <tag,...>           apply tags, may be repeated
+       -no-context              do not apply the tags of the .snip file of the working directory
+       -timestamp <time>        creation time, such as "2006-01-02 15:04" or RFC 3339 (default: now)
+       -url <url>               fetch article text from a web page
+       -html                    attach raw html of page (with -url)
+       -batch                   add one snip per block of input separated by delimiter lines
+       -delimiter <line>        line separating batch blocks (default: %%)
+       -first-line              use the first line of each batch block as its name
+       -skip-duplicates         do not add data identical to an existing snip (default: add with a warning)
+       -expires <when>          hide the snip from ls and search after a duration such as 7d, or at a time
+
+snip alias                      short names for snips, accepted wherever a uuid is
+       set <alias> <uuid>       name a snip, replacing the snip the alias named before
+       ls                       list aliases with the snips they name
+         -porcelain             list alias, uuid, and name separated by tabs, stable between releases
+       rm <alias ...>           remove aliases, leaving their snips
+
+snip ask <question ...>         answer a question from the snips found by its words, sent to the language model of the configuration
+       -n <n>                   most snips sent (default: 5)
+       -within <uuids|-|terms>  send these snips instead, given as for search -within
+       -save                    add the answer as a snip linking the snips it was answered from
+       -y, -yes                 send without asking to confirm
 
 snip attach                     attach a file to specified snip
        add <uuid> <file ...>    add attachment files to snip
+       blobs                    move the data of attachments kept in the database to files in the blob directory
+         -restore               move the data of attachments kept in blobs back into the database
        get <uuid>               display attachment metadata and info
        list                     list all attachments in database
          -sort <size|name>      sort by attachment field (default: name)
+         -porcelain             list uuid, snip uuid, size, and name separated by tabs, stable between releases
+       mv <uuid> <snip>         move attachment to another snip without copying its data
+       ocr [uuid ...]           read the text of image attachments into the search index, each not yet read when none are given
+         -show                  print the text read from each attachment
+       promote <uuid>           add a new snip holding the text of an attachment, linking back to the snip it belongs to
+         -n <name>              name of the new snip (default: the attachment name)
+         -rm                    remove the attachment once promoted
        rm <uuid ...>            remove attachment
+         -y, -yes               remove without asking
        stdout <uuid>            write data to stdout
+       transcribe [uuid ...]    transcribe audio attachments with the configured command, each not yet transcribed when none are given
+         -show                  print the transcript of each attachment
+       view <uuid>              show an image in the terminal, or open the attachment in the desktop's viewer
+         -protocol <name>       auto, kitty, iterm, sixel, or open (default: auto, from the terminal)
+         -size <pixels>         largest width or height of the image shown (default: 800)
        write <file>             write data to file
 
-snip get <uuid>                 retrieve snip with specified uuid
-       -raw                     output only raw data from snip
+snip backup                     copy the database to the backup directory, removing old backups the configuration does not keep
+       -dir <path>              backup directory (default: backup.dir of the configuration, or the database path with .backups suffix)
+       -incremental <dir>       add a snapshot to dir, writing only the chunks of the database that changed since earlier snapshots
+       verify <dir>             check that every snapshot in the incremental backup directory can be restored
+       restore <dir> <snapshot> <file>
+                                write the database of a snapshot, such as 20240331T220000Z or latest, to a new file
 
-snip ls                         list all snips
+snip bench                      time add, ls, search, and attach on a generated database, which is then removed
+       -snips <n>               number of snips to generate (default: 100000)
+
+snip blocks <uuid>              print the fenced code blocks of a markdown snip, such as to feed one to a pipeline
+       -lang <lang>             only blocks in the language, such as bash
+       -index <n>               only the nth of the blocks, counting from 1
+       -l                       list the blocks with their language, line, and first line of code
+       -run                     run the selected block with the interpreter of its language, exiting as it does
+
+snip check <uuid>               spell check and lint a snip, printing each problem found with the line it is on
+       -checker <name,...>      only the named checkers (default: spell, markdownlint, shellcheck)
+
+snip completion <bash|zsh>      print a shell completion script for commands and aliases, such as source <(snip completion bash)
+
+snip context                    show the .snip file of the working directory or above it, and the database in use
+
+snip count [term ...]           print the number of snips, or of snips matching the search terms
+       -attachments             count the attachments of the matching snips instead
+       -has-attachments         count only snips with attachments
+       -no-attachments          count only snips without attachments
+       -starred                 count only rated snips
+       -tag <tag,...>           count only snips with all of the tags
+
+snip daemon                     serve the json api from a unix socket, used by ls and search
+       -socket <path>           socket location (default: database path with .sock suffix)
+       -purge <interval>        remove expired snips at each interval, such as 1h (default: keep them)
+       -notify                  show a desktop notification when a snip comes due
+                                back up the database at the interval of backup.interval in the configuration
+
+snip diff <uuid> <uuid>         show the changes between the data of two snips as a unified diff
+       -version <n>             compare a single snip with version n of its data, see versions
+       -color <when>            color output auto (when writing to a terminal), always, or never
+
+snip doctor                     check the database file, data checksums, and orphaned attachments
+
+snip du                         list the snips storing the most data with their attachments, and the total against the quota
+       -top <n>                 list the n largest snips (default: 20)
        -l                       list with full uuid
 
+snip due                        track snips as action items with a due time
+       set <uuid> <when>        set the due time, a duration from now such as 2d or a time such as "2006-01-02 15:04"
+       ls                       list due snips, most urgent first
+         -l                     list with full uuid
+       rm <uuid ...>            clear the due time, such as when done
+
+snip exec -- <command ...>      run command and add its output as a new snip, recording the command in metadata
+       -n <name>                specify name (default: the command line)
+       -stderr                  also capture standard error
+       -no-context              do not apply the tags of the .snip file of the working directory
+
+snip expire                     manage snips added with add -expires
+       ls                       list snips with an expiry, soonest first
+         -l                     list with full uuid
+       purge                    remove expired snips, except locked ones
+
+snip export                     write snips in formats for reading outside of snip
+       feed                     atom feed of recent snips written to stdout
+         -n <count>             number of recent snips (default: 20)
+         -title <title>         title of the feed
+         -url <url>             address the feed will be published at
+       markdown <dir>           a markdown file per snip, with front matter that snip import dir reads back
+       pdf <uuid>               pdf document with rendered markdown and image attachments
+         -o <file>              output file (default: stdout)
+       site <dir>               static html site with search, tag pages, and attachments
+         -title <title>         title of the index page
+       sql                      INSERT statements for the rows of the snip tables, which sqlite tools can load
+         -schema                include the statements creating the tables and their indexes
+         -index                 include the search index, which snip index otherwise rebuilds
+         -o <file>              output file (default: stdout)
+
+snip get <uuid ...>             retrieve snips with specified uuids, - reads uuids from standard input
+       -base64                  output data encoded as base64, for binary snips
+       -delimiter <line>        line written between snips (default: empty line)
+       -head <n>                output only the first n lines
+       -header                  output only the uuid, name, timestamp, and tags, followed by the exact data with -raw
+       -ids-from-stdin          also read uuids from the start of each line of standard input
+       -json                    output snips as a json array
+       -lines <first:last>      output only a range of lines, either end may be omitted
+       -notes                   also display notes
+       -qr                      display data as a qr code
+       -raw                     output only the exact stored data, without a trailing newline added
+       -tail <n>                output only the last n lines
+       -tmux [target-pane]      type data into a tmux pane (default: last pane), pane follows uuid
+
+snip hook                       journal the commits of the git repository in the working directory with a post-commit hook
+       install                  install the hook, replacing one installed before
+         -diff <regex>          add the diff of commits it matches after the message
+         -tag <tag,...>         apply tags besides commit and the name of the repository
+         -f, -force             replace a post-commit hook not installed by snip
+       uninstall                remove the hook installed by snip
+       post-commit              add a snip of the latest commit, as the hook does
+
+snip import history             add commands from a shell history file as snips tagged history
+       -shell <bash|fish|zsh>   shell that wrote the history (default: $SHELL)
+       -f <file>                history file (default: the shell's history file)
+       -daily                   create one snip per day instead of one per command
+snip import dir <dir>           add each text file beneath dir as a snip named after the file, or as its markdown front matter describes
+snip import csv <file>          add each row of a csv file with a header of name, data, timestamp, and tags columns
+snip import jsonl <file>        add each line of json with name, data, timestamp, tags, and meta fields
+       -jobs <n>                files or records prepared at once (default: number of cpus)
+
+snip index                      rebuild the search index from the data of every snip
+       export <file>            write the search index to a file, to load into a copy of the database
+       import <file>            replace the search index with one written by export, indexing snips changed since
+       trigrams <on|off>        keep an index of substrings for search -substring, several times the size of the term index
+
+snip journal [day]              open the journal entry of today or day (2006-01-02 or yesterday) in $EDITOR, creating it tagged journal
+       ls                       list journal entries in order of day with their first line
+         -month <2006-01>       list only the entries of the month
+         -l                     list with full uuid
+
+snip lock [uuid ...]            make snips read-only, or list locked snips when no uuid is given
+       -d                       unlock the given snips
+
+snip ls                         list all snips
+       -columns <col,...>       columns to show: uuid, name, modified, size, attachments, stars, tags, lang, status (default: uuid,name)
+       -group-by <field>        group under headers with counts by creation day, month, tag, or status
+       -has-attachments         list only snips with attachments
+       -l                       list with full uuid and attachment count
+       -no-attachments          list only snips without attachments
+       -sort <stars>            sort by rating, highest first
+       -starred                 list only rated snips
+       -status <status>         list only snips with the status todo, doing, done, or none
+       -porcelain               list uuid, timestamp, and name separated by tabs, stable between releases
+       -color <when>            color output auto (when writing to a terminal), always, or never
+
+snip mail <uuid>                email snip with attachments using the smtp settings in the config file
+       -to <addr,...>           recipient addresses
+       -from <addr>             sender address (default: smtp.from from config)
+       -raw                     send only the raw text without rendered html
+
+snip mv-db <uuid ...>           move snips with their attachments, tags, notes, and versions to another database
+       -to <path>               snip database receiving the snips, created if missing
+
+snip note                       dated remarks about a snip, kept apart from its data
+       add <uuid> [text ...]    add a note from the arguments or standard input
+       ls <uuid>                list the notes of a snip, oldest first
+       rm <note-id ...>         remove notes by the id shown in ls
+
 snip search <term ...>          return snips whose data contains given term
        -type <data|index>       specify search source (data uses a singular term only)
        -f <field>               search snip field
+       -format <format>         output format of text, json, porcelain, or alfred to emit script filter json
+       -json                    output results as json with the offset, line, and column of each match
+       -porcelain               output uuid, score, and name separated by tabs, stable between releases
+       -explain                 print index terms, stage timings, and query plans of an index search to stderr
+       -color <when>            color output auto (when writing to a terminal), always, or never
+       -counts                  print the number of times each term occurs in each result, and whether it is in the name or tags
+       -substring               find snips containing the term anywhere, such as err_conn, using the trigram index when enabled
+       -limit <n>               print only the n highest scoring results
+       -timeout <duration>      give up on an index search taking longer than duration, such as 2s
+       -within <uuids|-|terms>  search only the given snips, those whose uuids are piped in with -, or the results of an earlier search
+       -status <status>         search only snips with the status todo, doing, done, or none
+
+snip random                     print a randomly selected snip, such as for review or a message of the day
+       -raw                     output only the exact stored data
+       -tag <tag,...>           choose only from snips with all of the tags
+
+snip recent [n]                 list the n most recently viewed or modified snips (default: 10)
+       -l                       list with full uuid
+
+snip review                     review the snips that are due like flashcards, grading each from again to easy
+       add <uuid ...>           schedule snips for review, starting now
+       ls                       list snips under review with their next review
+         -l                     list with full uuid
+       rm <uuid ...>            stop reviewing snips
 
 snip rename <uuid> <new_name>   rename snip
+       -f, -force               rename a locked snip after confirmation
+       -y, -yes                 confirm without asking
+
+snip replace <s/old/new/flags>  replace text matching a regular expression in the data of all snips, like sed
+       -dry-run                 show the changes as diffs without making them
+       -filter <args>           change only snips matching search terms and the options of count, such as '-tag infra'
+       -f, -force               replace in locked snips too, rather than skipping them
+       -y, -yes                 replace without asking
 
 snip rm <uuid ...>              remove snip <uuid> ...
+       -f, -force               remove locked snips after confirmation
+       -y, -yes                 remove without asking
+
+snip serve                      serve the json api over http
+       -addr <host:port>        listen address (default: 127.0.0.1:8080)
+       -grpc-addr <host:port>   also serve the grpc api defined in snippb/snip.proto
+       -token <token>           require bearer token (default: $SNIP_TOKEN)
+       -rate-limit <n>          requests a minute for each token, or each address without one (default: 600, 0 for none)
+       -max-upload <size>       largest attachment or other request body such as 512K (default: 32M, 0 for none)
+       -base-path <path>        path prefix such as /snip when served under it by a reverse proxy
+       -trust-proxy             use the client address, scheme, and host from X-Forwarded-* headers
+       -cors-origin <origin>    allow requests from browser apps on origin, * for any (may be repeated)
+       -public-tags <tag,...>   serve snips with these tags to anyone as html and json beneath /public/
+
+snip share <uuid>               print a link to view the snip through snip serve
+       -qr                      also display the link as a qr code
+       -ttl <duration>          duration the link remains valid (default: 24h)
+       -url <url>               address of snip serve (default: share_url from config)
+       ls                       list active shares
+       revoke <token ...>       revoke shares
+
+snip snapshot                   keep named copies of the database to roll back to, such as before a large import or replace
+       create <name>            copy the database as snapshot name
+       ls                       list snapshots, oldest first
+       rollback <name>          replace the database with the snapshot, discarding changes made since
+         -y, -yes               roll back without asking
+       rm <name ...>            remove snapshots
+
+snip summarize <uuid>           summarize a snip with the language model of the configuration
+       -save                    add the summary as a note of the snip
+       -y, -yes                 send without asking to confirm
+
+snip sql <statement>            run a single sql statement against the database, read-only unless -write is given
+       -format <format>         output format: table, csv, or json (default: table)
+       -write                   allow statements that change the database
+
+snip star <uuid> [1-5]          rate a snip, or print its rating when none is given
+       -d                       remove the rating
+
+snip stats                      show statistics about snips
+       activity                 heatmap of snips added or changed each day over the last year
+
+snip status                     keep snips as tasks that are todo, doing, or done
+       set <status> <uuid ...>  set the status of snips to todo, doing, done, or none to clear it
+       ls [status]              print a board of the snips with each status, or list those with the one given
+         -l                     list with full uuid
+
+snip stdio                      answer json-rpc 2.0 requests (get, search, insert) on stdin, one per line
+
+snip tag [uuid] [tag ...]       add tags to snip, print its tags, or list all tags when no uuid is given
+       -d                       remove the given tags
+
+snip translate <uuid>           translate a snip with the language model of the configuration into a linked snip
+       -to <lang>               language code to translate into, such as de or pt-BR
+       -y, -yes                 send without asking to confirm
+
+snip track                      record the time worked on snips, such as tasks or client projects
+       start <uuid>             start tracking time on a snip, stopping any other being tracked
+       stop [uuid]              stop tracking time on the snip, or whichever is being tracked
+       report                   print the hours tracked on each snip and tag (default: all time)
+         -week                  report only on the week so far, starting monday
+         -l                     list with full uuid
+
+snip urls [uuid]                list urls found in snip data (default: all snips)
+       -check                   request each url and report dead links
+       -l                       list with full uuid
+
+snip verify <uuid ...>          compare snip and attachment data with the checksums recorded when written
+       -all                     verify every snip
+
+snip versions <uuid>            list the earlier versions of snip data, kept each time it is replaced
+
+snip watch <dir>                add and update snips as files in directory change
+       -clipboard               add new unique clipboard entries instead of watching a directory
+       -exclude <regex>         skip clipboard entries matching pattern
+       -interval <duration>     clipboard polling interval (default: 1s)
+
+options given before any command:
+       --no-input               fail when a partial uuid matches several snips instead of asking which is meant
 `
 	Usage := func() {
 		fmt.Fprintf(os.Stderr, "%s", helpMessage)
 	}
 
-	addCmd := flag.NewFlagSet("add", flag.ExitOnError)
+	addCmd := flag.NewFlagSet("add", flag.ContinueOnError)
+	addCmdBatch := addCmd.Bool("batch", false, "add one snip per block of input separated by the delimiter")
+	addCmdDelimiter := addCmd.String("delimiter", "%%", "line separating blocks of batch input")
+	addCmdExpires := addCmd.String("expires", "", "hide and later purge the snip after a duration such as 7d, or at a time")
 	addCmdFile := addCmd.String("f", "", "use data from specified file")
+	addCmdFirstLine := addCmd.Bool("first-line", false, "use the first line of each batch block as its name")
+	addCmdHTML := addCmd.Bool("html", false, "attach raw html when adding from url")
 	addCmdName := addCmd.String("n", "", "specify name")
+	addCmdNoContext := addCmd.Bool("no-context", false, "do not apply the tags of the .snip file of the working directory")
+	addCmdSkipDuplicates := addCmd.Bool("skip-duplicates", false, "do not add data identical to an existing snip")
+	addCmd.StringVar(addCmdName, "name", "", "specify name")
+	var addCmdTags listFlag
+	addCmd.Var(&addCmdTags, "tag", "apply tag, may be repeated or comma separated")
+	addCmdTimestamp := addCmd.String("timestamp", "", "creation time, such as 2006-01-02 15:04 or RFC 3339 (default: now)")
+	addCmdURL := addCmd.String("url", "", "fetch article text from url")
 	addCmdUUID := addCmd.String("u", "", "specify uuid")
 
-	attachCmd := flag.NewFlagSet("attach", flag.ExitOnError)
-	attachCmdGet := flag.NewFlagSet("get", flag.ExitOnError)
-	attachCmdAdd := flag.NewFlagSet("add", flag.ExitOnError)
-	attachCmdList := flag.NewFlagSet("ls", flag.ExitOnError)
+	aliasCmd := flag.NewFlagSet("alias", flag.ContinueOnError)
+	aliasCmdList := flag.NewFlagSet("ls", flag.ContinueOnError)
+	aliasCmdListPorcelain := aliasCmdList.Bool("porcelain", false, "list aliases as tab separated fields that are stable between releases")
+
+	askCmd := flag.NewFlagSet("ask", flag.ContinueOnError)
+	askCmdLimit := askCmd.Int("n", 5, "most snips sent")
+	askCmdSave := askCmd.Bool("save", false, "add the answer as a snip")
+	askCmdWithin := askCmd.String("within", "", "send these snips, given as uuids, - to read them from stdin, or search terms")
+	askCmdYes := addYesFlag(askCmd)
+
+	attachCmd := flag.NewFlagSet("attach", flag.ContinueOnError)
+	attachCmdGet := flag.NewFlagSet("get", flag.ContinueOnError)
+	attachCmdAdd := flag.NewFlagSet("add", flag.ContinueOnError)
+	attachCmdBlobs := flag.NewFlagSet("blobs", flag.ContinueOnError)
+	attachCmdBlobsRestore := attachCmdBlobs.Bool("restore", false, "move the data of attachments kept in blobs back into the database")
+	attachCmdList := flag.NewFlagSet("ls", flag.ContinueOnError)
+	attachCmdMove := flag.NewFlagSet("mv", flag.ContinueOnError)
 	attachCmdListSort := attachCmdList.String("sort", "name", "field to sort attachment list by")
-	attachCmdRemove := flag.NewFlagSet("rm", flag.ExitOnError)
-	attachCmdWrite := flag.NewFlagSet("write", flag.ExitOnError)
+	attachCmdListPorcelain := attachCmdList.Bool("porcelain", false, "list attachments as tab separated fields that are stable between releases")
+	attachCmdOCR := flag.NewFlagSet("ocr", flag.ContinueOnError)
+	attachCmdOCRShow := attachCmdOCR.Bool("show", false, "print the text read rather than reading it again")
+	attachCmdTranscribe := flag.NewFlagSet("transcribe", flag.ContinueOnError)
+	attachCmdTranscribeShow := attachCmdTranscribe.Bool("show", false, "print the transcript rather than transcribing again")
+	attachCmdPromote := flag.NewFlagSet("promote", flag.ContinueOnError)
+	attachCmdPromoteName := attachCmdPromote.String("n", "", "name of the new snip")
+	attachCmdPromoteRemove := attachCmdPromote.Bool("rm", false, "remove the attachment once promoted")
+	attachCmdRemove := flag.NewFlagSet("rm", flag.ContinueOnError)
+	attachCmdRemoveYes := addYesFlag(attachCmdRemove)
+	attachCmdView := flag.NewFlagSet("view", flag.ContinueOnError)
+	attachCmdViewProtocol := attachCmdView.String("protocol", "auto", "image protocol (auto|kitty|iterm|sixel|open)")
+	attachCmdViewSize := attachCmdView.Int("size", 800, "largest width or height in pixels")
+	attachCmdWrite := flag.NewFlagSet("write", flag.ContinueOnError)
 	attachCmdWriteForce := attachCmdWrite.Bool("force", false, "force local file overwrite")
 
-	getCmd := flag.NewFlagSet("get", flag.ExitOnError)
-	getCmdRaw := getCmd.Bool("raw", false, "output only raw data")
+	backupCmd := flag.NewFlagSet("backup", flag.ContinueOnError)
+	backupCmdDir := backupCmd.String("dir", "", "backup directory")
+	backupCmdIncremental := backupCmd.String("incremental", "", "incremental backup directory")
+
+	benchCmd := flag.NewFlagSet("bench", flag.ContinueOnError)
+	benchCmdSnips := benchCmd.Int("snips", 100000, "number of snips to generate")
+
+	blocksCmd := flag.NewFlagSet("blocks", flag.ContinueOnError)
+	blocksCmdLang := blocksCmd.String("lang", "", "only blocks in the language")
+	blocksCmdIndex := blocksCmd.Int("index", 0, "only the nth of the blocks, counting from 1")
+	blocksCmdList := blocksCmd.Bool("l", false, "list blocks instead of printing their code")
+	blocksCmdRun := blocksCmd.Bool("run", false, "run the selected block")
+
+	checkCmd := flag.NewFlagSet("check", flag.ContinueOnError)
+	var checkCmdCheckers listFlag
+	checkCmd.Var(&checkCmdCheckers, "checker", "comma separated names of the checkers to run")
+
+	countCmd := flag.NewFlagSet("count", flag.ContinueOnError)
+	countCmdAttachments := countCmd.Bool("attachments", false, "count the attachments of the matching snips")
+	countCmdFilter := addFilterFlags(countCmd, "count")
+
+	daemonCmd := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	daemonCmdNotify := daemonCmd.Bool("notify", false, "show a desktop notification when a snip comes due")
+	daemonCmdPurge := daemonCmd.Duration("purge", 0, "interval at which expired snips are removed, 0 to keep them")
+	daemonCmdSocket := daemonCmd.String("socket", daemonSocketPath(dbFilePath), "unix socket location")
+
+	diffCmd := flag.NewFlagSet("diff", flag.ContinueOnError)
+	diffCmdVersion := diffCmd.Int("version", 0, "compare the snip with an earlier version of its data")
+	addColorFlag(diffCmd, &colorMode)
+
+	doctorCmd := flag.NewFlagSet("doctor", flag.ContinueOnError)
+
+	duCmd := flag.NewFlagSet("du", flag.ContinueOnError)
+	duCmdTop := duCmd.Int("top", 20, "number of snips listed")
+	duCmdLong := duCmd.Bool("l", false, "list full uuid instead of short")
+
+	dueCmd := flag.NewFlagSet("due", flag.ContinueOnError)
+	dueCmdList := flag.NewFlagSet("ls", flag.ContinueOnError)
+	dueCmdListLong := dueCmdList.Bool("l", false, "list full uuid instead of short")
+
+	execCmd := flag.NewFlagSet("exec", flag.ContinueOnError)
+	execCmdName := execCmd.String("n", "", "specify name (default: the command line)")
+	execCmdNoContext := execCmd.Bool("no-context", false, "do not apply the tags of the .snip file of the working directory")
+	execCmdStderr := execCmd.Bool("stderr", false, "also capture standard error")
+
+	hookCmd := flag.NewFlagSet("hook", flag.ContinueOnError)
+	hookCmdInstall := flag.NewFlagSet("install", flag.ContinueOnError)
+	hookCmdInstallDiff := hookCmdInstall.String("diff", "", "add the diff of commits it matches after the message")
+	hookCmdInstallForce := addForceFlag(hookCmdInstall, "replace a post-commit hook not installed by snip")
+	var hookCmdInstallTags listFlag
+	hookCmdInstall.Var(&hookCmdInstallTags, "tag", "apply tag besides commit and the name of the repository, may be repeated or comma separated")
+	hookCmdCommit := flag.NewFlagSet("post-commit", flag.ContinueOnError)
+	hookCmdCommitDiff := hookCmdCommit.String("diff", "", "add the diff of the commit after the message when it matches")
+	var hookCmdCommitTags listFlag
+	hookCmdCommit.Var(&hookCmdCommitTags, "tag", "apply tag besides commit and the name of the repository, may be repeated or comma separated")
+
+	expireCmd := flag.NewFlagSet("expire", flag.ContinueOnError)
+	expireCmdList := flag.NewFlagSet("ls", flag.ContinueOnError)
+	expireCmdListLong := expireCmdList.Bool("l", false, "list full uuid instead of short")
+	expireCmdPurge := flag.NewFlagSet("purge", flag.ContinueOnError)
+
+	exportCmd := flag.NewFlagSet("export", flag.ContinueOnError)
+	exportCmdFeed := flag.NewFlagSet("feed", flag.ContinueOnError)
+	exportCmdFeedLimit := exportCmdFeed.Int("n", 20, "number of recent snips to include, 0 for all")
+	exportCmdFeedTitle := exportCmdFeed.String("title", "snips", "title of the feed")
+	exportCmdFeedURL := exportCmdFeed.String("url", "", "address the feed will be published at")
+	exportCmdPDF := flag.NewFlagSet("pdf", flag.ContinueOnError)
+	exportCmdPDFOutput := exportCmdPDF.String("o", "", "output file (default: stdout)")
+	exportCmdSite := flag.NewFlagSet("site", flag.ContinueOnError)
+	exportCmdSiteTitle := exportCmdSite.String("title", "snips", "title of the index page")
+	exportCmdSQL := flag.NewFlagSet("sql", flag.ContinueOnError)
+	exportCmdSQLIndex := exportCmdSQL.Bool("index", false, "include the search index")
+	exportCmdSQLOutput := exportCmdSQL.String("o", "", "output file (default: stdout)")
+	exportCmdSQLSchema := exportCmdSQL.Bool("schema", false, "include the statements creating the tables")
+
+	getCmd := flag.NewFlagSet("get", flag.ContinueOnError)
+	getCmdRaw := getCmd.Bool("raw", false, "output only the exact stored data")
+	getCmdBase64 := getCmd.Bool("base64", false, "output the data encoded as base64, suitable for binary snips")
+	getCmdDelimiter := getCmd.String("delimiter", "", "line written between snips when getting more than one")
+	getCmdHeader := getCmd.Bool("header", false, "output the uuid, name, timestamp, and tags, followed by the exact data with -raw")
+	getCmdHead := getCmd.Int("head", 0, "output only the first n lines")
+	getCmdIDsFromStdin := getCmd.Bool("ids-from-stdin", false, "read uuids from standard input, such as the output of ls or search")
+	getCmdJSON := getCmd.Bool("json", false, "output snips as a json array")
+	getCmdLines := getCmd.String("lines", "", "output only a range of lines such as 20:40")
+	getCmdNotes := getCmd.Bool("notes", false, "also display notes added with note add")
+	getCmdQR := getCmd.Bool("qr", false, "display data as a qr code")
 	getCmdRandom := getCmd.Bool("random", false, "view a random snip")
+	getCmdTail := getCmd.Int("tail", 0, "output only the last n lines")
+	getCmdTmux := getCmd.Bool("tmux", false, "send data as keystrokes to a tmux pane given after the uuid")
+
+	importCmd := flag.NewFlagSet("import", flag.ContinueOnError)
+	importCmdBulk := flag.NewFlagSet("import", flag.ContinueOnError)
+	importCmdBulkJobs := importCmdBulk.Int("jobs", runtime.NumCPU(), "number of files or records prepared at once")
+	importCmdHistory := flag.NewFlagSet("history", flag.ContinueOnError)
+	importCmdHistoryDaily := importCmdHistory.Bool("daily", false, "create one snip per day instead of one per command")
+	importCmdHistoryFile := importCmdHistory.String("f", "", "history file (default: the shell's history file)")
+	importCmdHistoryShell := importCmdHistory.String("shell", path.Base(os.Getenv("SHELL")), "shell that wrote the history (bash|fish|zsh)")
+
+	journalCmd := flag.NewFlagSet("journal", flag.ContinueOnError)
+	journalCmdList := flag.NewFlagSet("ls", flag.ContinueOnError)
+	journalCmdListMonth := journalCmdList.String("month", "", "list only the entries of the month, such as 2006-01")
+	journalCmdListLong := journalCmdList.Bool("l", false, "list full uuid instead of short")
+
+	lockCmd := flag.NewFlagSet("lock", flag.ContinueOnError)
+	lockCmdRemove := lockCmd.Bool("d", false, "unlock the given snips")
+
+	listCmd := flag.NewFlagSet("ls", flag.ContinueOnError)
+	var listCmdColumns listFlag
+	listCmd.Var(&listCmdColumns, "columns", "comma separated columns to show (uuid,name,modified,size,attachments,stars,tags,lang,status)")
+	listCmdGroupBy := listCmd.String("group-by", "", "group snips under headers by day, month, tag, or status")
+	listCmdHasAttachments := listCmd.Bool("has-attachments", false, "list only snips with attachments")
+	listCmdLang := listCmd.String("lang", "", "list only snips detected to be code in language")
+	listCmdLong := listCmd.Bool("l", false, "list full uuid and attachment count")
+	listCmdNoAttachments := listCmd.Bool("no-attachments", false, "list only snips without attachments")
+	listCmdSort := listCmd.String("sort", "", "sort by field (stars)")
+	listCmdStarred := listCmd.Bool("starred", false, "list only snips rated with star")
+	listCmdStatus := listCmd.String("status", "", "list only snips with the status (todo|doing|done|none)")
+	addColorFlag(listCmd, &colorMode)
+	listCmdPorcelain := listCmd.Bool("porcelain", false, "list snips as tab separated fields that are stable between releases")
+
+	mailCmd := flag.NewFlagSet("mail", flag.ContinueOnError)
+	mailCmdFrom := mailCmd.String("from", conf.SMTP.From, "sender address")
+	mailCmdRaw := mailCmd.Bool("raw", false, "send only the raw text without rendered html")
+	mailCmdTo := mailCmd.String("to", "", "comma separated recipient addresses")
+
+	moveDBCmd := flag.NewFlagSet("mv-db", flag.ContinueOnError)
+	moveDBCmdTo := moveDBCmd.String("to", "", "path of the snip database receiving the snips")
 
-	listCmd := flag.NewFlagSet("ls", flag.ExitOnError)
-	listCmdLong := listCmd.Bool("l", false, "list full uuid instead of short")
+	noteCmd := flag.NewFlagSet("note", flag.ContinueOnError)
 
-	renameCmd := flag.NewFlagSet("rename", flag.ExitOnError)
+	randomCmd := flag.NewFlagSet("random", flag.ContinueOnError)
+	randomCmdRaw := randomCmd.Bool("raw", false, "output only the exact stored data")
+	var randomCmdTags listFlag
+	randomCmd.Var(&randomCmdTags, "tag", "choose only from snips with tag, may be repeated or comma separated")
 
-	searchCmd := flag.NewFlagSet("search", flag.ExitOnError)
+	recentCmd := flag.NewFlagSet("recent", flag.ContinueOnError)
+	recentCmdLong := recentCmd.Bool("l", false, "list full uuid instead of short")
+
+	reviewCmd := flag.NewFlagSet("review", flag.ContinueOnError)
+	reviewCmdList := flag.NewFlagSet("ls", flag.ContinueOnError)
+	reviewCmdListLong := reviewCmdList.Bool("l", false, "list full uuid instead of short")
+
+	renameCmd := flag.NewFlagSet("rename", flag.ContinueOnError)
+	renameCmdForce := addForceFlag(renameCmd, "rename a locked snip after confirmation")
+	renameCmdYes := addYesFlag(renameCmd)
+
+	replaceCmd := flag.NewFlagSet("replace", flag.ContinueOnError)
+	replaceCmdDryRun := replaceCmd.Bool("dry-run", false, "show the changes as diffs without making them")
+	replaceCmdFilter := replaceCmd.String("filter", "", "search terms and -tag, -starred, -has-attachments, or -no-attachments selecting the snips")
+	replaceCmdForce := addForceFlag(replaceCmd, "replace in locked snips too, rather than skipping them")
+	replaceCmdYes := addYesFlag(replaceCmd)
+
+	searchCmd := flag.NewFlagSet("search", flag.ContinueOnError)
 	searchCmdContextWords := searchCmd.Int("context", 6, "number of context words to display")
+	searchCmdCounts := searchCmd.Bool("counts", false, "print the number of times each term occurs in each result")
+	searchCmdExplain := searchCmd.Bool("explain", false, "print index terms, stage timings, and query plans to stderr")
 	searchCmdField := searchCmd.String("f", "data", "field to search (data|uuid)")
+	searchCmdFormat := searchCmd.String("format", "text", "output format of index search (text|alfred|json|porcelain)")
+	searchCmdJSON := searchCmd.Bool("json", false, "output index search results as json, the same as -format json")
+	searchCmdPorcelain := searchCmd.Bool("porcelain", false, "output index search results as tab separated fields that are stable between releases")
 	searchCmdLimit := searchCmd.Int("limit", 0, "limit search results")
 	searchCmdLongUUID := searchCmd.Bool("l", false, "list full uuid instead of short")
+	searchCmdTimeout := searchCmd.Duration("timeout", 0, "stop an index search that takes longer than the duration, such as 2s")
+	searchCmdSubstring := searchCmd.Bool("substring", false, "find snips whose data contains the term anywhere, ignoring case")
 	searchCmdType := searchCmd.String("type", "index", "search type (data|index)")
+	addColorFlag(searchCmd, &colorMode)
+	searchCmdStatus := searchCmd.String("status", "", "search only snips with the status (todo|doing|done|none)")
+	searchCmdWithin := searchCmd.String("within", "", "search only the given uuids, those read from standard input with -, or the results of an earlier search")
+
+	rmCmd := flag.NewFlagSet("rm", flag.ContinueOnError)
+	rmCmdForce := addForceFlag(rmCmd, "remove locked snips after confirmation")
+	rmCmdYes := addYesFlag(rmCmd)
+
+	serveCmd := flag.NewFlagSet("serve", flag.ContinueOnError)
+	serveCmdAddr := serveCmd.String("addr", "127.0.0.1:8080", "listen address")
+	serveCmdGRPCAddr := serveCmd.String("grpc-addr", "", "also serve grpc on this listen address")
+	serveCmdToken := serveCmd.String("token", os.Getenv("SNIP_TOKEN"), "require bearer token")
+	serveCmdRateLimit := serveCmd.Int("rate-limit", 600, "requests a minute allowed for each token, or each address without one, 0 for no limit")
+	serveCmdMaxUpload := serveCmd.String("max-upload", "32M", "largest request body accepted, such as an attachment, 0 for no limit")
+	serveCmdBasePath := serveCmd.String("base-path", "", "path prefix the server is reached under through a reverse proxy, such as /snip")
+	serveCmdTrustProxy := serveCmd.Bool("trust-proxy", false, "use the client address, scheme, and host from X-Forwarded-* headers")
+	var serveCmdCORSOrigins listFlag
+	serveCmd.Var(&serveCmdCORSOrigins, "cors-origin", "browser origin allowed to make cross origin requests, * for any (may be repeated)")
+	var serveCmdPublicTags listFlag
+	serveCmd.Var(&serveCmdPublicTags, "public-tags", "serve snips with these tags to anyone beneath /public/ (comma separated)")
+
+	shareURL := conf.ShareURL
+	if shareURL == "" {
+		shareURL = "http://127.0.0.1:8080"
+	}
+	shareCmd := flag.NewFlagSet("share", flag.ContinueOnError)
+	shareCmdQR := shareCmd.Bool("qr", false, "display the link as a qr code")
+	shareCmdTTL := shareCmd.Duration("ttl", 24*time.Hour, "duration the link remains valid")
+	shareCmdURL := shareCmd.String("url", shareURL, "address of snip serve as reached by others")
+
+	snapshotCmd := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	snapshotCmdRollback := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	snapshotCmdRollbackYes := addYesFlag(snapshotCmdRollback)
+	snapshotDir := dbFilePath + ".snapshots"
+
+	starCmd := flag.NewFlagSet("star", flag.ContinueOnError)
+	starCmdRemove := starCmd.Bool("d", false, "remove the rating")
+
+	statsCmd := flag.NewFlagSet("stats", flag.ContinueOnError)
+
+	sqlCmd := flag.NewFlagSet("sql", flag.ContinueOnError)
+	sqlCmdFormat := sqlCmd.String("format", "table", "output format (table|csv|json)")
+	sqlCmdWrite := sqlCmd.Bool("write", false, "allow statements that change the database")
+
+	statusCmd := flag.NewFlagSet("status", flag.ContinueOnError)
+	statusCmdList := flag.NewFlagSet("ls", flag.ContinueOnError)
+	statusCmdListLong := statusCmdList.Bool("l", false, "list full uuid instead of short")
+
+	stdioCmd := flag.NewFlagSet("stdio", flag.ContinueOnError)
+
+	summarizeCmd := flag.NewFlagSet("summarize", flag.ContinueOnError)
+	summarizeCmdSave := summarizeCmd.Bool("save", false, "add the summary as a note of the snip")
+	summarizeCmdYes := addYesFlag(summarizeCmd)
+
+	tagCmd := flag.NewFlagSet("tag", flag.ContinueOnError)
+	tagCmdRemove := tagCmd.Bool("d", false, "remove the given tags instead of adding them")
 
-	rmCmd := flag.NewFlagSet("rm", flag.ExitOnError)
+	translateCmd := flag.NewFlagSet("translate", flag.ContinueOnError)
+	translateCmdTo := translateCmd.String("to", "", "language code to translate into")
+	translateCmdYes := addYesFlag(translateCmd)
+
+	trackCmd := flag.NewFlagSet("track", flag.ContinueOnError)
+	trackCmdReport := flag.NewFlagSet("report", flag.ContinueOnError)
+	trackCmdReportWeek := trackCmdReport.Bool("week", false, "report only on the week so far, starting monday")
+	trackCmdReportLong := trackCmdReport.Bool("l", false, "list full uuid instead of short")
+
+	urlsCmd := flag.NewFlagSet("urls", flag.ContinueOnError)
+	urlsCmdCheck := urlsCmd.Bool("check", false, "check urls and report dead links")
+	urlsCmdLongUUID := urlsCmd.Bool("l", false, "list full uuid instead of short")
+
+	verifyCmd := flag.NewFlagSet("verify", flag.ContinueOnError)
+	verifyCmdAll := verifyCmd.Bool("all", false, "verify every snip and attachment")
+
+	versionsCmd := flag.NewFlagSet("versions", flag.ContinueOnError)
+
+	watchCmd := flag.NewFlagSet("watch", flag.ContinueOnError)
+	watchCmdClipboard := watchCmd.Bool("clipboard", false, "watch the clipboard instead of a directory")
+	watchCmdExclude := watchCmd.String("exclude", "", "skip clipboard entries matching regex")
+	watchCmdInterval := watchCmd.Duration("interval", time.Second, "clipboard polling interval")
+
+	// options given before the action apply to every command
+	for len(os.Args) > 1 && (os.Args[1] == "-no-input" || os.Args[1] == "--no-input") {
+		noInput = true
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
 
 	// establish action
 	if len(os.Args) < 2 {
 		Usage()
-		os.Exit(1)
+		os.Exit(exitInvalid)
 	}
 	action := os.Args[1]
 
-	var err error
 	database.Conn, err = sqlite3.Open(dbFilePath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "The database could not be opened at this location: %s\n", dbFilePath)
 		log.Debug().Err(err).Str("path", dbFilePath).Msg("error opening database")
-		os.Exit(1)
+		os.Exit(exitDatabase)
 	}
-	defer database.Conn.Close()
+	defer database.Close()
 
 	// ensure database is present
 	err = snip.CreateNewDatabase()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "There was a problem creating the new database structure.\n")
 		log.Debug().Err(err).Msg("error creating database schema")
-		os.Exit(1)
+		os.Exit(exitDatabase)
 	}
 
 	log.Debug().Str("action", action).Msg("action invoked")
 	log.Debug().Str("args", strings.Join(os.Args, " ")).Msg("action invoked")
 
+	// read only commands are answered by a running daemon when available
+	var daemon *client.Client
+	if action == "ls" || action == "search" {
+		daemon = detectDaemon(daemonSocketPath(dbFilePath))
+	}
+
 	switch action {
 	case "add":
 		if err := addCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
 			fmt.Fprintf(os.Stderr, "The add arguments could not be parsed.\n")
 			log.Debug().Err(err).Msg("error parsing add arguments")
-			os.Exit(1)
+			os.Exit(exitInvalid)
 		}
 
 		// create simple object
 		s := snip.New()
+		var article snip.Article
+		if *addCmdTimestamp != "" {
+			s.Timestamp, err = parseTimestamp(*addCmdTimestamp)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(exitInvalid)
+			}
+		}
+		var expires time.Time
+		if *addCmdExpires != "" {
+			expires, err = parseExpiry(*addCmdExpires, time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(exitInvalid)
+			}
+		}
+		s.Tags, err = contextTags(projectContext, addCmdTags, *addCmdNoContext)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitInvalid)
+		}
 
-		// file input takes precedence, but default to standard input
-		if *addCmdFile != "" {
+		// url and file input take precedence, but default to standard input
+		if *addCmdURL != "" {
+			article, err = snip.FetchArticle(*addCmdURL)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem fetching the url %s\n", *addCmdURL)
+				log.Debug().Err(err).Str("url", *addCmdURL).Msg("error fetching article from url")
+				os.Exit(exitCode(err))
+			}
+			s.Data = article.Text
+		} else if *addCmdFile != "" {
 			data, err := readFromFile(*addCmdFile)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "There was a problem reading from the file %s\n", *addCmdFile)
 				log.Debug().Err(err).Str("file", *addCmdFile).Msg("error reading from file")
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 			s.Data = string(data)
 		} else {
@@ -179,14 +863,89 @@ snip rm <uuid ...>              remove snip <uuid> ...
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "The standard input could not be read.\n")
 				log.Debug().Err(err).Msg("error reading from standard input")
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 			s.Data = string(data)
 		}
+
+		if *addCmdBatch {
+			if *addCmdURL != "" || *addCmdUUID != "" || *addCmdName != "" {
+				fmt.Fprintf(os.Stderr, "The -batch option cannot be combined with -url, -u, or -n.\n")
+				os.Exit(exitInvalid)
+			}
+			var snips []snip.Snip
+			for _, block := range splitBatch(s.Data, *addCmdDelimiter) {
+				b := snip.New()
+				b.Data = block
+				b.Timestamp = s.Timestamp
+				b.Tags = s.Tags
+				if *addCmdFirstLine {
+					name, rest, _ := strings.Cut(block, "\n")
+					b.Name = strings.TrimSpace(name)
+					// a block of a single line is kept as the data as well
+					if strings.TrimSpace(rest) != "" {
+						b.Data = rest
+					}
+				}
+				if b.Name == "" {
+					b.Name = snip.GenerateName([]byte(b.Data), nameStrategy)
+				}
+				snips = append(snips, b)
+			}
+
+			// insert every block or none of them
+			var added []snip.Snip
+			err = database.Conn.WithTx(func() error {
+				for _, b := range snips {
+					// earlier blocks of the batch are found as well since they are inserted first
+					existing, err := snip.GetUUIDByChecksum(snip.Checksum([]byte(b.Data)))
+					if err != nil {
+						return err
+					}
+					if existing != uuid.Nil {
+						if *addCmdSkipDuplicates {
+							fmt.Printf("skipped duplicate of snip %s: %s\n", snip.ShortenUUID(existing)[0], b.Name)
+							continue
+						}
+						fmt.Fprintf(os.Stderr, "warning: identical data already exists in snip %s\n", snip.ShortenUUID(existing)[0])
+					}
+					if err = (snip.LocalStore{}).Insert(b); err != nil {
+						return err
+					}
+					if !expires.IsZero() {
+						if err = snip.SetExpiry(b.UUID, expires); err != nil {
+							return err
+						}
+					}
+					added = append(added, b)
+				}
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem inserting the new snips into the database, none were added.\n")
+				log.Debug().Err(err).Msg("error inserting batch of snips into database")
+				os.Exit(exitCode(err))
+			}
+			for _, b := range added {
+				fmt.Printf("added snip uuid: %s\n", b.UUID)
+				err = snip.RunHook(snip.HookPostAdd, conf.Hooks[snip.HookPostAdd], b)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The post-add hook failed: %v\n", err)
+					log.Debug().Err(err).Str("uuid", b.UUID.String()).Msg("error running post-add hook")
+					os.Exit(exitCode(err))
+				}
+			}
+			break
+		}
+
 		s.Name = *addCmdName
+		// prefer the page title for web articles
+		if s.Name == "" {
+			s.Name = article.Title
+		}
 		// generate name if empty
 		if s.Name == "" {
-			s.Name = s.GenerateName(5)
+			s.Name = snip.GenerateName([]byte(s.Data), nameStrategy)
 		}
 
 		// modify uuid if it was specified as an argument
@@ -195,11 +954,25 @@ snip rm <uuid ...>              remove snip <uuid> ...
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "There was a problem parsing the supplied uuid %s which may be malformed.\n", *addCmdUUID)
 				log.Debug().Err(err).Msg("error parsing uuid from arguments")
-				os.Exit(1)
+				os.Exit(exitInvalid)
 			}
 			s.UUID = id
 		}
 
+		existing, err := snip.GetUUIDByChecksum(snip.Checksum([]byte(s.Data)))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem checking for an identical snip.\n")
+			log.Debug().Err(err).Msg("error searching for duplicate data")
+			os.Exit(exitCode(err))
+		}
+		if existing != uuid.Nil {
+			if *addCmdSkipDuplicates {
+				fmt.Printf("skipped duplicate of snip %s\n", snip.ShortenUUID(existing)[0])
+				break
+			}
+			fmt.Fprintf(os.Stderr, "warning: identical data already exists in snip %s\n", snip.ShortenUUID(existing)[0])
+		}
+
 		log.Debug().
 			Str("UUID", s.UUID.String()).
 			Str("timestamp", s.Timestamp.String()).
@@ -210,32 +983,232 @@ snip rm <uuid ...>              remove snip <uuid> ...
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "There was a problem inserting the new snip into the database.\n")
 			log.Debug().Err(err).Msg("error inserting Snip into database")
-			os.Exit(1)
+			os.Exit(exitCode(err))
 		}
 		fmt.Printf("added snip uuid: %s\n", s.UUID)
+
+		if !expires.IsZero() {
+			err = snip.SetExpiry(s.UUID, expires)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem setting the expiry of the new snip.\n")
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error setting expiry")
+				os.Exit(exitCode(err))
+			}
+		}
+
+		if *addCmdURL != "" {
+			err = s.SetMeta("url", article.URL)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem saving the url metadata of the new snip.\n")
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error setting url metadata")
+				os.Exit(exitCode(err))
+			}
+			if *addCmdHTML {
+				err = s.Attach("page.html", article.HTML)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem attaching the html of the page.\n")
+					log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error attaching page html")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("attached page.html %d bytes\n", len(article.HTML))
+			}
+		}
+
 		// index for searching
 		err = s.Index()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "There was a problem indexing the new snip item.\n")
 			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error indexing new snip %s")
-			os.Exit(1)
+			os.Exit(exitCode(err))
+		}
+
+		err = snip.RunHook(snip.HookPostAdd, conf.Hooks[snip.HookPostAdd], s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The post-add hook failed: %v\n", err)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running post-add hook")
+			os.Exit(exitCode(err))
+		}
+
+	case "alias":
+		if err := aliasCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The alias arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing alias arguments")
+			aliasCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(aliasCmd.Args()) < 1 {
+			fmt.Fprintf(os.Stderr, "Must supply an alias action (set|ls|rm)\n")
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch aliasCmd.Args()[0] {
+		case "set":
+			if len(aliasCmd.Args()) != 3 {
+				fmt.Fprintf(os.Stderr, "Setting an alias requires a name and a uuid.\n")
+				os.Exit(exitInvalid)
+			}
+			name, idStr := aliasCmd.Args()[1], aliasCmd.Args()[2]
+			if err := snip.ValidateAlias(name); err != nil {
+				fmt.Fprintf(os.Stderr, "The %v\n", err)
+				os.Exit(exitInvalid)
+			}
+			s, err := getSnip(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+				os.Exit(exitCode(err))
+			}
+			if err = snip.SetAlias(name, s.UUID); err != nil {
+				fmt.Fprintf(os.Stderr, "The alias %s could not be set.\n", name)
+				log.Debug().Err(err).Str("alias", name).Msg("error setting alias")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("alias %s -> %s %s\n", name, snip.ShortenUUID(s.UUID)[0], s.Name)
+		case "ls":
+			if err := aliasCmdList.Parse(aliasCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The alias ls arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing alias ls arguments")
+				os.Exit(exitInvalid)
+			}
+			aliases, err := snip.ListAliases()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem listing the aliases.\n")
+				log.Debug().Err(err).Msg("error listing aliases")
+				os.Exit(exitCode(err))
+			}
+			var rows [][]string
+			for _, a := range aliases {
+				s, err := snip.GetSnipMeta(a.UUID.String())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The snip with uuid: %s could not be obtained from the database.\n", a.UUID)
+					log.Debug().Err(err).Str("uuid", a.UUID.String()).Msg("error obtaining snip from uuid")
+					os.Exit(exitCode(err))
+				}
+				if *aliasCmdListPorcelain {
+					writePorcelain(os.Stdout, a.Name, a.UUID.String(), s.Name)
+					continue
+				}
+				rows = append(rows, []string{a.Name, snip.ShortenUUID(a.UUID)[0], s.Name})
+			}
+			if len(rows) > 0 {
+				writeTable(os.Stdout, os.Stderr, []string{"alias", "uuid", "name"}, []tableSection{{Rows: rows}}, nil)
+			}
+		case "rm":
+			for _, name := range aliasCmd.Args()[1:] {
+				if err := snip.RemoveAlias(name); err != nil {
+					fmt.Fprintf(os.Stderr, "The alias %s could not be removed: %v\n", name, err)
+					log.Debug().Err(err).Str("alias", name).Msg("error removing alias")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("removed alias %s\n", name)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "The alias action %s is not supported.\n", aliasCmd.Args()[0])
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+	case "ask":
+		if err := parseInterspersed(askCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The ask arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing ask arguments")
+			askCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		question := strings.TrimSpace(strings.Join(askCmd.Args(), " "))
+		if question == "" || *askCmdLimit < 1 {
+			fmt.Fprintf(os.Stderr, "The ask command requires a question and -n of at least 1.\n")
+			askCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		provider, err := llm.New(conf.LLM)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The %v, see llm in %s\n", err, config.Path())
+			os.Exit(exitInvalid)
+		}
+		var ids []uuid.UUID
+		if *askCmdWithin != "" {
+			ids, err = withinIDs(*askCmdWithin, os.Stdin)
+		} else {
+			var scores []snip.SearchScore
+			scores, err = snip.SearchAny(snip.SplitWords(question), *askCmdLimit)
+			for _, score := range scores {
+				ids = append(ids, score.UUID)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem finding the snips to ask about: %v\n", err)
+			log.Debug().Err(err).Msg("error finding snips to ask about")
+			os.Exit(exitCode(err))
+		}
+		if len(ids) > *askCmdLimit {
+			ids = ids[:*askCmdLimit]
+		}
+		if len(ids) == 0 {
+			fmt.Fprintf(os.Stderr, "No snips were found to ask about, choose them with -within.\n")
+			os.Exit(exitNotFound)
+		}
+		var snips []snip.Snip
+		for _, id := range ids {
+			s, err := getSnip(id.String())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem locating the snip %s\n", id)
+				log.Debug().Err(err).Str("uuid", id.String()).Msg("could not get snip")
+				os.Exit(exitCode(err))
+			}
+			snips = append(snips, s)
+		}
+		writeSending(os.Stderr, provider, snips)
+		if !confirmAction(fmt.Sprintf("SEND %d snips to %s", len(snips), provider), *askCmdYes) {
+			fmt.Println("skipped")
+			break
+		}
+		answer, err := llm.Ask(context.Background(), provider, question, snips)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem asking %s: %v\n", provider, err)
+			log.Debug().Err(err).Msg("error asking question")
+			os.Exit(exitFailure)
+		}
+		fmt.Println(answer)
+		if *askCmdSave {
+			s := snip.New()
+			s.Name = question
+			s.Data = answerData(answer, snips)
+			if err = (snip.LocalStore{}).Insert(s); err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem inserting the new snip into the database.\n")
+				log.Debug().Err(err).Msg("error inserting Snip into database")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("added snip uuid: %s\n", s.UUID)
+			err = snip.RunHook(snip.HookPostAdd, conf.Hooks[snip.HookPostAdd], s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The post-add hook failed: %v\n", err)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running post-add hook")
+				os.Exit(exitCode(err))
+			}
 		}
 
 	case "attach":
 		if err := attachCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
 			fmt.Fprintf(os.Stderr, "The attach arguments could not be parsed.\n")
 			log.Debug().Err(err).Msg("error parsing attach arguments")
 			attachCmd.Usage()
-			os.Exit(1)
+			os.Exit(exitInvalid)
 		}
 
 		// LIST attachments with additional info
 		switch attachCmd.Args()[0] {
 		case "add":
 			if err := attachCmdAdd.Parse(attachCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
 				log.Debug().Err(err).Msg("error parsing attach list arguments")
 				attachCmdAdd.Usage()
-				os.Exit(1)
+				os.Exit(exitInvalid)
 			}
 
 			// should always have at least two arguments, uuid and at least one file
@@ -243,51 +1216,119 @@ snip rm <uuid ...>              remove snip <uuid> ...
 				fmt.Fprintf(os.Stderr, "The attach add command requires at least two arguments, the snip uuid and the local file to attach.\n")
 				log.Debug().Int("length", len(attachCmdAdd.Args())).Str("args", strings.Join(attachCmdAdd.Args(), " ")).Msg("arguments")
 				attachCmdAdd.Usage()
-				os.Exit(1)
+				os.Exit(exitInvalid)
 			}
 			// INSERT new attachments
 			id := attachCmdAdd.Args()[0]
 			// validate UUID
-			s, err := snip.GetFromUUID(id)
+			s, err := getSnip(id)
 			if err != nil {
 				log.Debug().Str("uuid", id).Msg("error locating snip uuid")
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 			fmt.Printf("attaching files to snip %s %s\n", s.UUID.String(), s.Name)
+			// the text of images and transcripts of audio are read when configured, and then indexed
+			var readers []textReader
+			if conf.OCR.Enabled {
+				readers = append(readers, ocrReader(conf.OCR))
+			}
+			if conf.Transcription.Command != "" {
+				readers = append(readers, transcriptionReader(conf.Transcription))
+			}
+			read := false
 			// TODO: Do not allow duplicate attachments by calculating checksums at this point.
 
 			for _, filename := range attachCmdAdd.Args()[1:] {
+				// limits are checked before reading, so that a file too large to keep is never loaded
+				info, err := os.Stat(filename)
+				if err == nil {
+					err = snip.CheckAttachmentSize(info.Size())
+				}
+				if errors.Is(err, snip.ErrAttachmentTooLarge) || errors.Is(err, snip.ErrQuotaExceeded) {
+					fmt.Fprintf(os.Stderr, "The file %s was not attached, %v. The limits are set by attachments in %s.\n", filename, err, config.Path())
+					os.Exit(exitInvalid)
+				}
 				// attempt to insert file
 				data, err := os.ReadFile(filename)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "The file %s could not be read.\n", filename)
 					log.Debug().Err(err).Str("file", filename).Msg("error reading attachment file data")
-					os.Exit(1)
+					os.Exit(exitCode(err))
 				}
 				basename := path.Base(filename)
 				// name is filename if not supplied
-				err = s.Attach(basename, data)
+				a, err := s.AddAttachment(basename, data)
 				if err != nil {
+					if errors.Is(err, snip.ErrLocked) {
+						fmt.Fprintf(os.Stderr, "The snip %s is locked, unlock it with lock -d to add attachments.\n", s.UUID)
+						os.Exit(exitFailure)
+					}
 					fmt.Fprintf(os.Stderr, "The attach operation of the file %s had a problem.\n", filename)
 					log.Debug().Err(err).Str("filename", filename).Msg("error attaching file")
 					// at least attach partial
 					continue
 				}
 				fmt.Printf("attached %s %d bytes\n", filename, len(data))
+				for _, r := range readers {
+					if !r.Reads(basename) {
+						continue
+					}
+					words, err := r.read(a)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "The text of %s could not be %s.\n", filename, r.Verb)
+						log.Debug().Err(err).Str("filename", filename).Msg("error reading attachment text")
+						continue
+					}
+					fmt.Printf("%s %d words from %s\n", r.Verb, words, filename)
+					read = true
+				}
+			}
+			if read {
+				if err = snip.Reindex(s.UUID); err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem indexing the text of the attachments of %s\n", s.UUID)
+					log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error indexing attachment text")
+					os.Exit(exitCode(err))
+				}
 			}
+			if err = warnStorage(os.Stderr); err != nil {
+				log.Debug().Err(err).Msg("error measuring storage")
+			}
+
+		case "blobs":
+			if err := attachCmdBlobs.Parse(attachCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The attach blobs arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing attach blobs arguments")
+				attachCmdBlobs.Usage()
+				os.Exit(exitInvalid)
+			}
+			move, where := snip.MoveAttachmentsToBlobs, snip.BlobDir
+			if *attachCmdBlobsRestore {
+				move, where = snip.MoveBlobsToDatabase, "the database"
+			}
+			bar := newProgressBar(os.Stderr)
+			count, err := move(bar)
+			bar.Finish()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem moving attachment data to %s, %d attachments were moved.\n", where, count)
+				log.Debug().Err(err).Str("dir", snip.BlobDir).Msg("error moving attachment data")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("moved %d attachments to %s\n", count, where)
 
 		case "ls":
 			if err := attachCmdList.Parse(attachCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
 				fmt.Fprintf(os.Stderr, "The ls arguments could not be parsed.\n")
 				log.Debug().Err(err).Msg("error parsing attach list arguments")
-				os.Exit(1)
+				os.Exit(exitInvalid)
 			}
 
 			list, err := snip.GetAttachmentsAll()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "There was a problem while gathering the list of attachments.\n")
 				log.Debug().Err(err).Msg("could not list all attachments")
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 			// build list
 			// use this function to not load overhead of Data field since it will not be used
@@ -297,7 +1338,7 @@ snip rm <uuid ...>              remove snip <uuid> ...
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "There was a problem when attempting to read metadata of snip with id %s\n", id.String())
 					log.Debug().Err(err).Str("uuid", id.String()).Msg("error getting attachment metadata")
-					os.Exit(1)
+					os.Exit(exitCode(err))
 				}
 				attachments = append(attachments, a)
 			}
@@ -317,6 +1358,12 @@ snip rm <uuid ...>              remove snip <uuid> ...
 				})
 			}
 
+			if *attachCmdListPorcelain {
+				for _, a := range attachments {
+					writePorcelain(os.Stdout, a.UUID.String(), a.SnipUUID.String(), strconv.Itoa(a.Size), a.Name)
+				}
+				break
+			}
 			// print analysis
 			for idx, a := range attachments {
 				// do not print header if no results
@@ -327,26 +1374,206 @@ snip rm <uuid ...>              remove snip <uuid> ...
 				fmt.Printf("%s %10d %s\n", a.UUID, a.Size, a.Name)
 			}
 
-		// REMOVE attachments by uuid
-		case "rm":
-			if err := attachCmdRemove.Parse(attachCmd.Args()[1:]); err != nil {
-				fmt.Fprintf(os.Stderr, "The arguments to the rm command could not be parsed.\n")
-				log.Debug().Err(err).Msg("error parsing attach remove arguments")
-				attachCmdRemove.Usage()
-				os.Exit(1)
+		// OCR read the text of image attachments, or TRANSCRIBE audio attachments
+		case "mv":
+			if err := attachCmdMove.Parse(attachCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The attach mv arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing attach mv arguments")
+				attachCmdMove.Usage()
+				os.Exit(exitInvalid)
 			}
-			// TODO: Check this behavior, don't we need [1:] or something?
-			for _, idStr := range attachCmdRemove.Args() {
+			if attachCmdMove.NArg() != 2 {
+				fmt.Fprintf(os.Stderr, "The attach mv command requires the attachment uuid and the uuid of the snip to move it to.\n")
+				attachCmdMove.Usage()
+				os.Exit(exitInvalid)
+			}
+			idStr := attachCmdMove.Arg(0)
+			a, err := snip.FindAttachmentMetadata(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem locating the attachment with id %s\n", idStr)
+				log.Debug().Err(err).Str("id", idStr).Msg("could not get attachment")
+				os.Exit(exitCode(err))
+			}
+			target, err := getSnip(attachCmdMove.Arg(1))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem locating the snip %s\n", attachCmdMove.Arg(1))
+				log.Debug().Err(err).Str("id", attachCmdMove.Arg(1)).Msg("could not get snip")
+				os.Exit(exitCode(err))
+			}
+			source, err := snip.MoveAttachment(a.UUID, target.UUID)
+			if err != nil {
+				if errors.Is(err, snip.ErrLocked) {
+					fmt.Fprintf(os.Stderr, "The attachment cannot be moved, %v. Unlock the snip with lock -d first.\n", err)
+					os.Exit(exitFailure)
+				}
+				fmt.Fprintf(os.Stderr, "There was a problem moving the attachment %s %s: %v\n", a.UUID, a.Name, err)
+				log.Debug().Err(err).Str("uuid", a.UUID.String()).Msg("error moving attachment")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("moved %s %s from snip %s to %s %s\n", a.UUID, a.Name, snip.ShortenUUID(source)[0], snip.ShortenUUID(target.UUID)[0], target.Name)
+
+		case "ocr", "transcribe":
+			action := attachCmd.Args()[0]
+			flags, show, reader := attachCmdOCR, attachCmdOCRShow, ocrReader(conf.OCR)
+			if action == "transcribe" {
+				flags, show, reader = attachCmdTranscribe, attachCmdTranscribeShow, transcriptionReader(conf.Transcription)
+			}
+			if err := flags.Parse(attachCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The attach %s arguments could not be parsed.\n", action)
+				log.Debug().Err(err).Str("action", action).Msg("error parsing attach arguments")
+				flags.Usage()
+				os.Exit(exitInvalid)
+			}
+			var attachments []snip.Attachment
+			for _, idStr := range flags.Args() {
+				a, err := snip.GetAttachmentFromUUID(idStr)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem locating the attachment with id %s\n", idStr)
+					log.Debug().Err(err).Str("id", idStr).Msg("could not get attachment")
+					os.Exit(exitCode(err))
+				}
+				attachments = append(attachments, a)
+			}
+
+			if *show {
+				for _, a := range attachments {
+					_, text, err := snip.GetAttachmentText(a.UUID)
+					if errors.Is(err, database.ErrNoRows) {
+						fmt.Fprintf(os.Stderr, "No text has been %s from %s %s\n", reader.Verb, a.UUID, a.Name)
+						os.Exit(exitNotFound)
+					}
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "There was a problem reading the text of %s %s\n", a.UUID, a.Name)
+						log.Debug().Err(err).Str("uuid", a.UUID.String()).Msg("error getting attachment text")
+						os.Exit(exitCode(err))
+					}
+					fmt.Println(text)
+				}
+				break
+			}
+			if reader.Command == "" {
+				fmt.Fprintf(os.Stderr, "Audio is transcribed by the transcription.command of the configuration, which is not set.\n")
+				os.Exit(exitInvalid)
+			}
+
+			if len(attachments) == 0 {
+				unread, err := snip.ListUnreadAttachments(reader.Reads)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem listing the attachments.\n")
+					log.Debug().Err(err).Msg("error listing unread attachments")
+					os.Exit(exitCode(err))
+				}
+				for _, meta := range unread {
+					a, err := snip.GetAttachmentFromUUID(meta.UUID.String())
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "There was a problem reading the attachment %s\n", meta.UUID)
+						log.Debug().Err(err).Str("uuid", meta.UUID.String()).Msg("could not get attachment")
+						os.Exit(exitCode(err))
+					}
+					attachments = append(attachments, a)
+				}
+			}
+			reindex := make(map[uuid.UUID]bool)
+			failed := false
+			for _, a := range attachments {
+				words, err := reader.read(a)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The text of %s %s could not be %s.\n", a.UUID, a.Name, reader.Verb)
+					log.Debug().Err(err).Str("uuid", a.UUID.String()).Msg("error reading attachment text")
+					failed = true
+					continue
+				}
+				fmt.Printf("%s %d words from %s %s\n", reader.Verb, words, a.UUID, a.Name)
+				reindex[a.SnipUUID] = true
+			}
+			for id := range reindex {
+				if err := snip.Reindex(id); err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem indexing the text of the attachments of %s\n", id)
+					log.Debug().Err(err).Str("uuid", id.String()).Msg("error indexing attachment text")
+					os.Exit(exitCode(err))
+				}
+			}
+			if failed {
+				os.Exit(exitFailure)
+			}
+
+		// REMOVE attachments by uuid
+		case "promote":
+			if err := parseInterspersed(attachCmdPromote, attachCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The attach promote arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing attach promote arguments")
+				attachCmdPromote.Usage()
+				os.Exit(exitInvalid)
+			}
+			if attachCmdPromote.NArg() != 1 {
+				fmt.Fprintf(os.Stderr, "The attach promote command requires the attachment uuid.\n")
+				attachCmdPromote.Usage()
+				os.Exit(exitInvalid)
+			}
+			idStr := attachCmdPromote.Arg(0)
+			a, err := snip.GetAttachmentFromUUID(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem locating the attachment with id %s\n", idStr)
+				log.Debug().Err(err).Str("id", idStr).Msg("could not get attachment")
+				os.Exit(exitCode(err))
+			}
+			s, err := snip.PromoteAttachment(a)
+			if errors.Is(err, snip.ErrNotText) {
+				fmt.Fprintf(os.Stderr, "The attachment %s %s is not text, so a snip cannot be made of it.\n", a.UUID, a.Name)
+				os.Exit(exitInvalid)
+			}
+			if *attachCmdPromoteName != "" {
+				s.Name = *attachCmdPromoteName
+			}
+			if err = (snip.LocalStore{}).Insert(s); err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem inserting the new snip into the database.\n")
+				log.Debug().Err(err).Msg("error inserting Snip into database")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("added snip uuid: %s\n", s.UUID)
+			err = snip.RunHook(snip.HookPostAdd, conf.Hooks[snip.HookPostAdd], s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The post-add hook failed: %v\n", err)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running post-add hook")
+				os.Exit(exitCode(err))
+			}
+			if *attachCmdPromoteRemove {
+				if err = snip.RemoveAttachment(a.UUID); err != nil {
+					fmt.Fprintf(os.Stderr, "The attachment %s was promoted but could not be removed: %v\n", a.UUID, err)
+					log.Debug().Err(err).Str("uuid", a.UUID.String()).Msg("error removing promoted attachment")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("removed attachment %s %s\n", a.UUID, a.Name)
+			}
+
+		case "rm":
+			if err := attachCmdRemove.Parse(attachCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The arguments to the rm command could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing attach remove arguments")
+				attachCmdRemove.Usage()
+				os.Exit(exitInvalid)
+			}
+			// TODO: Check this behavior, don't we need [1:] or something?
+			// the first failure decides the exit status once the others have been removed
+			var removeFailed error
+			for _, idStr := range attachCmdRemove.Args() {
 				// id, err := uuid.Parse(idStr)
 				attachment, err := snip.GetAttachmentFromUUID(idStr)
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "The supplied id %s could not be located.\n", idStr)
-					log.Debug().Err(err).Str("uuid", "idStr").Msg("error locating attachment")
+					log.Debug().Err(err).Str("uuid", idStr).Msg("error locating attachment")
+					if removeFailed == nil {
+						removeFailed = err
+					}
 					continue
 				}
 
 				// confirm before deletion
-				if !confirmAction(fmt.Sprintf("REMOVE attachment %s %s", attachment.UUID, attachment.Name)) {
+				if !confirmAction(fmt.Sprintf("REMOVE attachment %s %s", attachment.UUID, attachment.Name), *attachCmdRemoveYes) {
 					fmt.Println("skipped")
 					continue
 				}
@@ -354,29 +1581,36 @@ snip rm <uuid ...>              remove snip <uuid> ...
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "There was a problem while trying to delete attachment %s %s\n", idStr, err)
 					log.Debug().Err(err).Str("uuid", idStr).Msg("error removing attachment")
+					if removeFailed == nil {
+						removeFailed = err
+					}
 				} else {
 					fmt.Println("removed attachment")
 				}
 			}
+			if removeFailed != nil {
+				os.Exit(exitCode(removeFailed))
+			}
 
 		// STANDARD OUTPUT
 		case "stdout":
 			// output raw data to stdout for piping or analysis
 			if err := attachCmdGet.Parse(attachCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
 				log.Debug().Err(err).Msg("error parsing attach list arguments")
 				attachCmdGet.Usage()
-				os.Exit(1)
+				os.Exit(exitInvalid)
 			}
 
 			if len(attachCmdGet.Args()) != 1 {
 				Usage()
-				os.Exit(1)
+				os.Exit(exitInvalid)
 			}
 
 			id, err := uuid.Parse(attachCmdGet.Arg(0))
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "The provided id could not be parsed and may be malformed.\n")
-				os.Exit(1)
+				os.Exit(exitInvalid)
 			}
 			a, err := snip.GetAttachmentFromUUID(id.String())
 			if err != nil {
@@ -387,20 +1621,84 @@ snip rm <uuid ...>              remove snip <uuid> ...
 			// output
 			fmt.Printf("%s", a.Data)
 
+		// VIEW image attachment in the terminal
+		case "view":
+			if err := attachCmdView.Parse(attachCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The attach view arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing attach view arguments")
+				attachCmdView.Usage()
+				os.Exit(exitInvalid)
+			}
+			if attachCmdView.NArg() != 1 {
+				fmt.Fprintf(os.Stderr, "The attach view command requires the attachment uuid.\n")
+				attachCmdView.Usage()
+				os.Exit(exitInvalid)
+			}
+			if *attachCmdViewSize < 1 || *attachCmdViewSize > snip.MaxThumbnailSize {
+				fmt.Fprintf(os.Stderr, "The size must be from 1 to %d pixels.\n", snip.MaxThumbnailSize)
+				os.Exit(exitInvalid)
+			}
+			protocol := *attachCmdViewProtocol
+			switch protocol {
+			case "auto":
+				protocol = protocolOpen
+				if isTerminal(os.Stdout) {
+					protocol = detectImageProtocol(os.Getenv)
+				}
+			case protocolKitty, protocolITerm, protocolSixel, protocolOpen:
+			default:
+				fmt.Fprintf(os.Stderr, "The image protocol %s is not supported (auto|kitty|iterm|sixel|open).\n", protocol)
+				os.Exit(exitInvalid)
+			}
+
+			idStr := attachCmdView.Arg(0)
+			a, err := snip.GetAttachmentFromUUID(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem locating the attachment with id %s\n", idStr)
+				log.Debug().Err(err).Str("id", idStr).Msg("could not get attachment")
+				os.Exit(exitCode(err))
+			}
+			if protocol != protocolOpen {
+				err = writeInlineImage(os.Stdout, protocol, a, *attachCmdViewSize)
+				// attachments that are not images are opened instead unless a protocol was asked for
+				if errors.Is(err, snip.ErrNotImage) && *attachCmdViewProtocol == "auto" {
+					protocol = protocolOpen
+				} else if errors.Is(err, snip.ErrNotImage) {
+					fmt.Fprintf(os.Stderr, "The attachment %s %s is not an image that can be shown.\n", a.UUID, a.Name)
+					log.Debug().Err(err).Str("uuid", a.UUID.String()).Msg("error decoding attachment image")
+					os.Exit(exitInvalid)
+				} else if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem showing the attachment %s %s\n", a.UUID, a.Name)
+					log.Debug().Err(err).Str("uuid", a.UUID.String()).Msg("error writing inline image")
+					os.Exit(exitCode(err))
+				}
+			}
+			if protocol == protocolOpen {
+				file, err := openExternally(a)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem opening the attachment %s, which was written to %s\n", a.Name, file)
+					log.Debug().Err(err).Str("file", file).Msg("error opening attachment")
+					os.Exit(exitFailure)
+				}
+				fmt.Printf("opened %s\n", file)
+			}
+
 		// WRITE attachment to file
 		case "write":
 			if err := attachCmdWrite.Parse(attachCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
 				fmt.Fprintf(os.Stderr, "The attach write arguments could not be parsed.\n")
 				log.Debug().Err(err).Msg("error parsing attach remove arguments")
 				attachCmdWrite.Usage()
-				os.Exit(1)
+				os.Exit(exitInvalid)
 			}
 			log.Debug().Str("args", strings.Join(attachCmdWrite.Args(), " ")).Msg("arguments")
 			if len(attachCmdWrite.Args()) == 0 || len(attachCmdWrite.Args()) > 2 {
 				fmt.Fprintf(os.Stderr, "The attach write command requires either one or two arguments.\n")
 				attachCmdWrite.Usage()
 				log.Debug().Msg("writing attachment action requires one or two arguments")
-				os.Exit(1)
+				os.Exit(exitInvalid)
 			}
 
 			var outfile string
@@ -412,14 +1710,14 @@ snip rm <uuid ...>              remove snip <uuid> ...
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "There was a problem attempting to validate the id %s which may be malformed.\n", idStr)
 					log.Debug().Err(err).Msg("error parsing uuid")
-					os.Exit(1)
+					os.Exit(exitInvalid)
 				}
 			*/
 			a, err := snip.GetAttachmentFromUUID(idStr)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "There was a problem locating the attachment with id %s\n", idStr)
 				log.Debug().Err(err).Str("id", idStr).Msg("could not get attachment")
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 			// assign outfile name or use saved name if omitted
 			if len(attachCmdWrite.Args()) == 2 {
@@ -438,439 +1736,3687 @@ snip rm <uuid ...>              remove snip <uuid> ...
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "There was a problem while writing data for the output file %s\n", outfile)
 				log.Debug().Err(err).Msg("error writing attachment to file")
-				os.Exit(1)
+				os.Exit(exitCode(err))
 			}
 			fmt.Printf("%s written -> %s %d bytes\n", a.Name, outfile, bytesWritten)
 		default:
 			Usage()
-			os.Exit(1)
+			os.Exit(exitInvalid)
 		}
 
-	case "get":
-		if err := getCmd.Parse(os.Args[2:]); err != nil {
-			fmt.Fprintf(os.Stderr, "The get arguments could not be parsed.\n")
-			log.Debug().Err(err).Msg("error parsing get arguments")
-			os.Exit(1)
+	case "backup":
+		if err := backupCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The backup arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing backup arguments")
+			backupCmd.Usage()
+			os.Exit(exitInvalid)
 		}
-		var idStr string
-
-		// random from all snips
-		if *getCmdRandom {
-			// get list
-			// TODO: verify that this does not load everything in memory everywhere immediately
-			allSnips, err := snip.List(0)
+		if backupCmd.NArg() > 0 {
+			switch backupCmd.Arg(0) {
+			case "verify":
+				if backupCmd.NArg() != 2 {
+					fmt.Fprintf(os.Stderr, "Must supply the incremental backup directory to verify.\n")
+					os.Exit(exitInvalid)
+				}
+				dir := backupCmd.Arg(1)
+				v, err := snip.VerifyIncrementalBackups(dir)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem verifying the backups in %s\n", dir)
+					log.Debug().Err(err).Str("dir", dir).Msg("error verifying backups")
+					os.Exit(exitCode(err))
+				}
+				writeBackupProblems(os.Stdout, v.Problems)
+				fmt.Printf("verified %d snapshots and %d chunks, %d problems\n", v.Snapshots, v.Chunks, len(v.Problems))
+				if len(v.Problems) > 0 {
+					os.Exit(exitFailure)
+				}
+			case "restore":
+				if backupCmd.NArg() != 4 {
+					fmt.Fprintf(os.Stderr, "Must supply the incremental backup directory, snapshot, and file to write.\n")
+					os.Exit(exitInvalid)
+				}
+				dir, name, path := backupCmd.Arg(1), backupCmd.Arg(2), backupCmd.Arg(3)
+				if err := restoreSnapshot(dir, name, path); err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem restoring snapshot %s of %s to %s\n", name, dir, path)
+					log.Debug().Err(err).Str("dir", dir).Str("snapshot", name).Msg("error restoring backup")
+					os.Exit(exitCode(err))
+				}
+			default:
+				fmt.Fprintf(os.Stderr, "The backup action %s is not supported.\n", backupCmd.Arg(0))
+				os.Exit(exitInvalid)
+			}
+			break
+		}
+		if *backupCmdIncremental != "" {
+			dir := *backupCmdIncremental
+			snapshot, written, err := snip.CreateIncrementalBackup(dir, time.Now())
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "There was a problem building the list of all snips in the database.\n")
-				log.Debug().Err(err).Msg("error retrieving all snips")
-				os.Exit(1)
+				fmt.Fprintf(os.Stderr, "There was a problem backing up the database to %s\n", dir)
+				log.Debug().Err(err).Str("dir", dir).Msg("error making incremental backup")
+				os.Exit(exitCode(err))
 			}
-
-			// get random within range
-			src := rand.NewSource(time.Now().UnixNano())
-			r := rand.New(src)
-			index := r.Intn(len(allSnips))
-			log.Debug().Int("random index", index).Msg("generated random integer")
-			// assign to outside world
-			idStr = allSnips[index].UUID.String()
+			var size int
+			for _, c := range written {
+				size += c.Size
+			}
+			fmt.Println(snapshot.Path)
+			fmt.Printf("wrote %d of %d chunks, %d bytes\n", len(written), len(snapshot.Chunks), size)
+			break
 		}
-
-		// obtain uuid specified from argument
-		if len(getCmd.Args()) != 1 {
-			Usage()
-			os.Exit(1)
+		backups, err := loadBackupSchedule(conf.Backup, dbFilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The backup configuration is not valid: %v\n", err)
+			os.Exit(exitInvalid)
 		}
-		idStr = getCmd.Args()[0]
-
-		// If this has not been set by anything above, use the command line.
-		if idStr == "" {
-			idStr = getCmd.Args()[0]
+		if *backupCmdDir != "" {
+			backups.Dir = *backupCmdDir
+		}
+		archive, removed, err := backups.backup(time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem backing up the database to %s\n", backups.Dir)
+			log.Debug().Err(err).Str("dir", backups.Dir).Msg("error making backup")
+			os.Exit(exitCode(err))
+		}
+		fmt.Println(archive.Path)
+		for _, a := range removed {
+			fmt.Printf("removed %s\n", a.Path)
 		}
 
-		// There is no reason to parse this since it may be a fuzzy term. Rely on the errors.
-		// TODO handle both cases explicitly and derive functions for full and partial uuid
-		s, err := snip.GetFromUUID(idStr)
+	case "bench":
+		if err := benchCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The bench arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing bench arguments")
+			benchCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if *benchCmdSnips < 1 {
+			fmt.Fprintf(os.Stderr, "The number of snips must be at least 1.\n")
+			os.Exit(exitInvalid)
+		}
+		dir, err := os.MkdirTemp("", "snip-bench-")
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
-			log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "There was a problem creating a directory for the benchmark database.\n")
+			log.Debug().Err(err).Msg("error creating benchmark directory")
+			os.Exit(exitCode(err))
 		}
+		defer os.RemoveAll(dir)
 
-		if *getCmdRaw {
-			fmt.Printf("%s", s.Data)
-		} else {
-			fmt.Printf("uuid: %s\n", s.UUID.String())
-			fmt.Printf("name: %s\n", s.Name)
-			fmt.Printf("timestamp: %s\n", s.Timestamp.Format(time.RFC3339Nano))
-			fmt.Printf("----\n")
-			fmt.Printf("%s", s.Data)
-			// add an extra newline if the data does not end with one
-			// no one likes their prompt hijacked. This will not affect raw output.
-			if !strings.HasSuffix(s.Data, "\n") {
-				fmt.Println()
-			}
-			fmt.Printf("----\n")
-			for idx, a := range s.Attachments {
-				// print attachments if present
-				if idx == 0 {
-					fmt.Printf("attachments:\n")
-					fmt.Printf("%s %42s %s\n", "uuid", "bytes", "name")
-				}
-				fmt.Printf("%s %10d %s\n", a.UUID.String(), a.Size, a.Name)
-			}
+		// the benchmark replaces the connection so that the database in use is never written
+		benchPath := path.Join(dir, "bench.sqlite3")
+		conn := database.Conn
+		database.Conn, err = sqlite3.Open(benchPath)
+		if err == nil {
+			err = snip.CreateNewDatabase()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem creating the benchmark database.\n")
+			log.Debug().Err(err).Str("path", benchPath).Msg("error creating benchmark database")
+			os.RemoveAll(dir)
+			os.Exit(exitCode(err))
+		}
+		fmt.Fprintf(os.Stderr, "generating %d snips in %s\n", *benchCmdSnips, benchPath)
+		results, err := runBench(os.Stderr, rand.New(rand.NewSource(1)), *benchCmdSnips)
+		database.Close()
+		database.Conn = conn
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem running the benchmark.\n")
+			log.Debug().Err(err).Msg("error running benchmark")
+			os.RemoveAll(dir)
+			os.Exit(exitCode(err))
 		}
+		writeBenchReport(os.Stdout, results)
 
-	case "ls":
-		if err := listCmd.Parse(os.Args[2:]); err != nil {
-			fmt.Fprintf(os.Stderr, "The ls arguments could not be parsed.\n")
-			log.Debug().Err(err).Msg("error parsing ls arguments")
-			listCmd.Usage()
-			os.Exit(1)
+	case "blocks":
+		if err := parseInterspersed(blocksCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The blocks arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing blocks arguments")
+			blocksCmd.Usage()
+			os.Exit(exitInvalid)
 		}
-		results, err := snip.GetAllSnipIDs()
+		if blocksCmd.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "The blocks command requires the uuid of a snip.\n")
+			blocksCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		s, err := getSnip(blocksCmd.Arg(0))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the metadata of all snips.\n")
-			log.Debug().Err(err).Msg("error listing items metadata")
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "There was a problem locating the snip %s\n", blocksCmd.Arg(0))
+			log.Debug().Err(err).Str("id", blocksCmd.Arg(0)).Msg("could not get snip")
+			os.Exit(exitCode(err))
 		}
-		for idx, id := range results {
-			s, err := snip.GetFromUUID(id.String())
+		lang, err := snip.GetLanguage(s.UUID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the language of snip %s\n", s.UUID)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error getting language")
+			os.Exit(exitCode(err))
+		}
+		blocks, err := selectBlocks(snipBlocks(s.Data, lang), *blocksCmdLang, *blocksCmdIndex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The block could not be selected, %v.\n", err)
+			os.Exit(exitNotFound)
+		}
+		if len(blocks) == 0 {
+			fmt.Fprintf(os.Stderr, "The snip %s has no code blocks to select.\n", s.UUID)
+			os.Exit(exitNotFound)
+		}
+		switch {
+		case *blocksCmdList:
+			writeBlockList(os.Stdout, blocks)
+		case *blocksCmdRun:
+			if len(blocks) > 1 {
+				fmt.Fprintf(os.Stderr, "The snip has %d blocks, choose the one to run with -index.\n", len(blocks))
+				os.Exit(exitInvalid)
+			}
+			if _, ok := blockInterpreters[blocks[0].Lang]; !ok {
+				fmt.Fprintf(os.Stderr, "No interpreter is known for blocks of %q, print the block and pipe it to one instead.\n", blocks[0].Lang)
+				os.Exit(exitInvalid)
+			}
+			code, err := runBlock(blocks[0])
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "The snip with uuid: %s could not be obtained from the database.\n", id.String())
-				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error obtaining snip from uuid")
-				os.Exit(1)
+				fmt.Fprintf(os.Stderr, "The block could not be run: %v\n", err)
+				log.Debug().Err(err).Str("lang", blocks[0].Lang).Msg("error running block")
+				os.Exit(exitFailure)
 			}
-			if idx == 0 {
-				if *listCmdLong {
-					// long
-					fmt.Fprintf(os.Stderr, "%s %36s\n", "uuid", "name")
-				} else {
-					// short
-					fmt.Fprintf(os.Stderr, "%s %8s\n", "uuid", "name")
+			os.Exit(code)
+		default:
+			for idx, b := range blocks {
+				// blocks are separated by a blank line
+				if idx > 0 {
+					fmt.Println()
 				}
-			}
-			if *listCmdLong {
-				fmt.Printf("%s %s\n", s.UUID, s.Name)
-			} else {
-				fmt.Printf("%s %s\n", snip.ShortenUUID(s.UUID)[0], s.Name)
+				fmt.Print(b.Code)
 			}
 		}
 
-	case "rename":
-		if err := renameCmd.Parse(os.Args[2:]); err != nil {
-			fmt.Fprintf(os.Stderr, "The rename arguments could not be parsed.\n")
-			log.Debug().Err(err).Msg("error parsing rename arguments")
-			renameCmd.Usage()
-			os.Exit(1)
+	case "check":
+		if err := parseInterspersed(checkCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The check arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing check arguments")
+			checkCmd.Usage()
+			os.Exit(exitInvalid)
 		}
-		// require one argument
-		if len(renameCmd.Args()) != 2 {
-			fmt.Fprintf(os.Stderr, "The rename command requires two arguments.\n")
-			log.Debug().Err(err).Msg("error parsing rename arguments")
-			os.Exit(1)
+		if checkCmd.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "The check command requires the uuid of a snip.\n")
+			checkCmd.Usage()
+			os.Exit(exitInvalid)
 		}
-
-		idStr := renameCmd.Args()[0]
-		newName := renameCmd.Args()[1]
-		// no empty strings allowed
-		if newName == "" {
-			fmt.Fprintf(os.Stderr, "The new name cannot be an empty string.\n")
-			log.Debug().Err(err).Msg("no empty string allowed for renaming")
-			os.Exit(1)
+		selected, err := checkers(conf.Checkers, checkCmdCheckers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The %v, see checkers in %s\n", err, config.Path())
+			os.Exit(exitInvalid)
 		}
-		s, err := snip.GetFromUUID(idStr)
+		s, err := getSnip(checkCmd.Arg(0))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "could not retrieve snip with id: %s\n", idStr)
-			log.Debug().Err(err).Str("uuid", idStr).Msg("retrieving snip from uuid")
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "There was a problem locating the snip %s\n", checkCmd.Arg(0))
+			log.Debug().Err(err).Str("id", checkCmd.Arg(0)).Msg("could not get snip")
+			os.Exit(exitCode(err))
 		}
-		oldName := s.Name
-		s.Name = newName
-		err = s.Update()
+		lang, err := snip.GetLanguage(s.UUID)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "There was a problem updating snip with id %s\n", idStr)
-			log.Debug().Err(err).Msg("could not update snip")
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the language of snip %s\n", s.UUID)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error getting language")
+			os.Exit(exitCode(err))
+		}
+		findings, err := snip.Check(s, lang, selected)
+		// checkers that are not installed are noted, while those failing otherwise fail the check
+		failed := false
+		if err != nil {
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				fmt.Fprintf(os.Stderr, "The checker %v\n", e)
+				if !errors.Is(e, snip.ErrCheckerMissing) {
+					failed = true
+				}
+			}
+			log.Debug().Err(err).Msg("error running checkers")
+		}
+		writeFindings(os.Stdout, s.Data, findings)
+		fmt.Printf("%d problems found in %s %s\n", len(findings), s.UUID, s.Name)
+		if failed || len(findings) > 0 {
+			os.Exit(exitFailure)
 		}
-		fmt.Printf("renamed %s %s -> %s\n", s.UUID.String(), oldName, newName)
 
-	case "rm":
-		if err := rmCmd.Parse(os.Args[2:]); err != nil {
-			fmt.Fprintf(os.Stderr, "The rm arguments could not be parsed.\n")
-			log.Debug().Err(err).Msg("error parsing rm arguments")
-			rmCmd.Usage()
-			os.Exit(1)
+	case "completion":
+		if len(os.Args) != 3 {
+			fmt.Fprintf(os.Stderr, "Must supply the shell to complete (bash|zsh).\n")
+			os.Exit(exitInvalid)
 		}
-		for idx, arg := range rmCmd.Args() {
-			// parse to uuid because it seems proper
-			s, err := snip.GetFromUUID(arg)
-			// id, err := uuid.Parse(arg)
+		if err := writeCompletion(os.Stdout, os.Args[2]); err != nil {
+			fmt.Fprintf(os.Stderr, "The %v\n", err)
+			os.Exit(exitInvalid)
+		}
+
+	case "context":
+		if len(os.Args) != 2 {
+			fmt.Fprintf(os.Stderr, "The context command accepts no arguments.\n")
+			os.Exit(exitInvalid)
+		}
+		writeContext(os.Stdout, projectContext, dbFilePath)
+
+	case "count":
+		if err := parseInterspersed(countCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The count arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing count arguments")
+			countCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		countCmdFilter.Terms = countCmd.Args()
+		ids, err := countCmdFilter.IDs()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem finding the snips to count: %v\n", err)
+			log.Debug().Err(err).Msg("error filtering snips")
+			os.Exit(exitCode(err))
+		}
+		if *countCmdAttachments {
+			attachmentCounts, err := snip.ListAttachmentCounts()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Could not locate id %d/%d %s\n", idx+1, len(rmCmd.Args()), arg)
-				log.Debug().Str("uuid", arg).Err(err).Msg("error parsing uuid input")
-				// Do not exit as others may be valid.
-				continue
-			}
-			if !confirmAction(fmt.Sprintf("REMOVE snip %s", s.UUID)) {
-				fmt.Println("skipped")
-				continue
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the attachments of snips.\n")
+				log.Debug().Err(err).Msg("error counting attachments")
+				os.Exit(exitCode(err))
 			}
-			err = snip.Remove(s.UUID)
-			if err != nil {
-				fmt.Printf("Could not remove %d/%d %s\n", idx+1, len(rmCmd.Args()), s.UUID)
-				log.Debug().Str("uuid", s.UUID.String()).Err(err).Msg("error while attempting to delete snip")
-			} else {
-				// must else because we don't break
-				fmt.Printf("removed %d/%d %s\n", idx+1, len(rmCmd.Args()), s.UUID)
+			total := 0
+			for _, id := range ids {
+				total += attachmentCounts[id]
 			}
+			fmt.Println(total)
+			break
 		}
+		fmt.Println(len(ids))
 
-	case "search":
-		if err := searchCmd.Parse(os.Args[2:]); err != nil {
-			fmt.Fprintf(os.Stderr, "The search arguments could not be parsed.\n")
-			log.Debug().Err(err).Str("args", strings.Join(searchCmd.Args(), " ")).Msg("error parsing search arguments")
-			searchCmd.Usage()
-			os.Exit(1)
+	case "daemon":
+		if err := daemonCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The daemon arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing daemon arguments")
+			daemonCmd.Usage()
+			os.Exit(exitInvalid)
 		}
-		if len(searchCmd.Args()) < 1 {
-			fmt.Fprintf(os.Stderr, "Must supply at least one search term.\n")
-			searchCmd.Usage()
-			os.Exit(1)
+		backups, err := loadBackupSchedule(conf.Backup, dbFilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The backup configuration is not valid: %v\n", err)
+			os.Exit(exitInvalid)
+		}
+		err = runDaemon(*daemonCmdSocket, *daemonCmdPurge, *daemonCmdNotify, backups)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem running the daemon on socket %s\n", *daemonCmdSocket)
+			log.Debug().Err(err).Str("socket", *daemonCmdSocket).Msg("error running daemon")
+			os.Exit(exitCode(err))
 		}
 
-		var snipResults []snip.Snip
-
-		switch *searchCmdType {
-		case "index":
-			terms := searchCmd.Args()
+	case "diff":
+		if err := parseInterspersed(diffCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The diff arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing diff arguments")
+			diffCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if err := setColorMode(colorMode); err != nil {
+			fmt.Fprintf(os.Stderr, "The %v\n", err)
+			os.Exit(exitInvalid)
+		}
+		if (*diffCmdVersion == 0 && len(diffCmd.Args()) != 2) || (*diffCmdVersion != 0 && len(diffCmd.Args()) != 1) {
+			fmt.Fprintf(os.Stderr, "Must supply two snip uuids, or one with -version.\n")
+			diffCmd.Usage()
+			os.Exit(exitInvalid)
+		}
 
-			searchResults, err := snip.SearchIndexTerm(terms, true)
+		var snips []snip.Snip
+		for _, idStr := range diffCmd.Args() {
+			s, err := getSnip(idStr)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "There was a problem searching the index for term %s\n", terms)
-				log.Debug().Err(err).Msg("error while searching for term")
-				os.Exit(1)
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+				os.Exit(exitCode(err))
 			}
-
-			var scores []snip.SearchScore
-			for key, result := range searchResults {
-				score, err := snip.ScoreCounts(key, terms, result)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "There was a problem scoring the item with id %s\n", key)
-					log.Debug().Err(err).Str("uuid", key.String()).Msg("scoring the results")
-					os.Exit(1)
-				}
-				// add to sortable slice
-				scores = append(scores, snip.SearchScore{UUID: key, Score: score, SearchCounts: result})
+			snips = append(snips, s)
+		}
+		oldData, newData := snips[0].Data, ""
+		oldLabel := fmt.Sprintf("%s %s", snip.ShortenUUID(snips[0].UUID)[0], snips[0].Name)
+		newLabel := ""
+		if *diffCmdVersion != 0 {
+			v, err := snip.GetVersion(snips[0].UUID, *diffCmdVersion)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Version %d of snip %s could not be retrieved.\n", *diffCmdVersion, snips[0].UUID)
+				log.Debug().Err(err).Int("version", *diffCmdVersion).Msg("error retrieving version")
+				os.Exit(exitCode(err))
+			}
+			// the earlier version is the old side of the diff
+			oldData, newData = v.Data, snips[0].Data
+			oldLabel, newLabel = fmt.Sprintf("%s version %d", snip.ShortenUUID(v.UUID)[0], v.Number), oldLabel
+		} else {
+			newData = snips[1].Data
+			newLabel = fmt.Sprintf("%s %s", snip.ShortenUUID(snips[1].UUID)[0], snips[1].Name)
+		}
+		if isBinary(oldData) || isBinary(newData) {
+			if oldData != newData {
+				fmt.Printf("binary data of %s and %s differs\n", oldLabel, newLabel)
 			}
+			break
+		}
+		writeUnifiedDiff(os.Stdout, oldData, newData, oldLabel, newLabel)
 
-			// sorted output by highest score
-			sort.Slice(scores, func(i int, j int) bool {
-				return scores[i].Score > scores[j].Score
-			})
+	case "doctor":
+		if err := doctorCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The doctor arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing doctor arguments")
+			doctorCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		healthy := true
 
-			// enforce limit after sort
-			if *searchCmdLimit != 0 && len(scores) > *searchCmdLimit {
-				scores = scores[:*searchCmdLimit]
+		problems, err := snip.IntegrityCheck()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem checking the integrity of the database.\n")
+			log.Debug().Err(err).Msg("error running integrity check")
+			os.Exit(exitCode(err))
+		}
+		if len(problems) == 0 {
+			fmt.Printf("database integrity: ok\n")
+		} else {
+			healthy = false
+			fmt.Printf("database integrity: %d problems\n", len(problems))
+			for _, problem := range problems {
+				fmt.Printf("  %s\n", problem)
 			}
-			for _, score := range scores {
-				// get full snip to display name
-				s, err := snip.GetFromUUID(score.UUID.String())
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "There was a problem getting the snip to display its name.\n")
-					log.Debug().Err(err).Msg("building snip to display name")
-					os.Exit(1)
-				}
-				fmt.Printf("%s\n", s.Name)
-				if *searchCmdLongUUID {
-					fmt.Printf("  %s ", s.UUID)
-				} else {
-					fmt.Printf("  %s ", snip.ShortenUUID(s.UUID)[0])
-				}
-				fmt.Printf("(score: %f, ", score.Score)
-				fmt.Printf("words: %d)", s.CountWords())
+		}
 
-				// display terms found in document
-				for idx, stat := range score.SearchCounts {
-					if idx == 0 {
-						fmt.Printf(" [")
+		bar := newProgressBar(os.Stderr)
+		v, err := snip.Verify(bar)
+		bar.Finish()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem verifying data checksums.\n")
+			log.Debug().Err(err).Msg("error verifying checksums")
+			os.Exit(exitCode(err))
+		}
+		if len(v.Mismatches) > 0 {
+			healthy = false
+		}
+		fmt.Printf("checksums: %d snips and %d attachments verified, %d mismatches\n", v.Snips, v.Attachments, len(v.Mismatches))
+		writeMismatches(os.Stdout, v.Mismatches)
+
+		orphans, err := snip.OrphanedAttachments()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem searching for orphaned attachments.\n")
+			log.Debug().Err(err).Msg("error searching for orphaned attachments")
+			os.Exit(exitCode(err))
+		}
+		if len(orphans) == 0 {
+			fmt.Printf("orphaned attachments: none\n")
+		} else {
+			healthy = false
+			fmt.Printf("orphaned attachments: %d\n", len(orphans))
+			for _, id := range orphans {
+				fmt.Printf("  %s\n", id)
+			}
+		}
+
+		if !healthy {
+			os.Exit(exitFailure)
+		}
+
+	case "du":
+		if err := duCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The du arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing du arguments")
+			duCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if *duCmdTop < 1 {
+			fmt.Fprintf(os.Stderr, "The number of snips listed must be at least 1.\n")
+			os.Exit(exitInvalid)
+		}
+		usages, err := snip.ListLargest(*duCmdTop)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem measuring the data of snips.\n")
+			log.Debug().Err(err).Msg("error listing largest snips")
+			os.Exit(exitCode(err))
+		}
+		used, err := snip.StorageUsed()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem measuring the data stored.\n")
+			log.Debug().Err(err).Msg("error measuring storage")
+			os.Exit(exitCode(err))
+		}
+		writeUsage(os.Stdout, usages, used, *duCmdLong)
+
+	case "due":
+		if err := dueCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The due arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing due arguments")
+			dueCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(dueCmd.Args()) == 0 {
+			fmt.Fprintf(os.Stderr, "Must supply a due action (set|ls|rm)\n")
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch dueCmd.Args()[0] {
+		case "set":
+			if len(dueCmd.Args()) != 3 {
+				fmt.Fprintf(os.Stderr, "Setting a due time requires a uuid and a time.\n")
+				os.Exit(exitInvalid)
+			}
+			idStr := dueCmd.Args()[1]
+			s, err := getSnip(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+				os.Exit(exitCode(err))
+			}
+			due, err := parseWhen(dueCmd.Args()[2], time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The due time %v\n", err)
+				os.Exit(exitInvalid)
+			}
+			err = snip.SetDue(s.UUID, due)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem setting the due time of snip %s\n", s.UUID)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error setting due time")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("%s due %s %s\n", s.UUID, due.Local().Format("2006-01-02 15:04"), s.Name)
+		case "ls":
+			if err := dueCmdList.Parse(dueCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The due ls arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing due ls arguments")
+				os.Exit(exitInvalid)
+			}
+			dues, err := snip.ListDue()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem listing the due snips.\n")
+				log.Debug().Err(err).Msg("error listing due snips")
+				os.Exit(exitCode(err))
+			}
+			for _, d := range dues {
+				s, err := snip.GetSnipMeta(d.UUID.String())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The snip with uuid: %s could not be obtained from the database.\n", d.UUID)
+					log.Debug().Err(err).Str("uuid", d.UUID.String()).Msg("error obtaining snip from uuid")
+					os.Exit(exitCode(err))
+				}
+				id := snip.ShortenUUID(s.UUID)[0]
+				if *dueCmdListLong {
+					id = s.UUID.String()
+				}
+				state := "due    "
+				if d.Overdue() {
+					state = "overdue"
+				}
+				fmt.Printf("%s %s %s %s\n", id, state, d.Due.Local().Format("2006-01-02 15:04"), s.Name)
+			}
+		case "rm":
+			for _, idStr := range dueCmd.Args()[1:] {
+				s, err := getSnip(idStr)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+					log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+					os.Exit(exitCode(err))
+				}
+				err = snip.RemoveDue(s.UUID)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem clearing the due time of snip %s\n", s.UUID)
+					log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error removing due time")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("cleared due time of %s %s\n", s.UUID, s.Name)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "The due action %s is not supported.\n", dueCmd.Args()[0])
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+	case "exec":
+		if err := execCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The exec arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing exec arguments")
+			execCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(execCmd.Args()) == 0 {
+			fmt.Fprintf(os.Stderr, "Must supply a command to run, for example: snip exec -- df -h\n")
+			execCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		// the tags are checked before the command runs
+		tags, err := contextTags(projectContext, nil, *execCmdNoContext)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitInvalid)
+		}
+
+		result, err := runCapture(execCmd.Args(), *execCmdStderr, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The command could not be run: %v\n", err)
+			log.Debug().Err(err).Strs("args", execCmd.Args()).Msg("error running command")
+			os.Exit(exitCode(err))
+		}
+		hostname, err := os.Hostname()
+		if err != nil {
+			log.Debug().Err(err).Msg("error reading hostname")
+		}
+
+		s := snip.New()
+		s.Data = string(result.Output)
+		s.Name = *execCmdName
+		s.Tags = tags
+		if s.Name == "" {
+			s.Name = commandLine(execCmd.Args())
+		}
+		s.Meta = map[string]string{
+			"command":   commandLine(execCmd.Args()),
+			"exit_code": strconv.Itoa(result.ExitCode),
+			"hostname":  hostname,
+			"duration":  result.Duration.Round(time.Millisecond).String(),
+		}
+		err = snip.LocalStore{}.Insert(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem inserting the new snip into the database.\n")
+			log.Debug().Err(err).Msg("error inserting Snip into database")
+			os.Exit(exitCode(err))
+		}
+		fmt.Fprintf(os.Stderr, "added snip uuid: %s\n", s.UUID)
+
+		err = snip.RunHook(snip.HookPostAdd, conf.Hooks[snip.HookPostAdd], s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The post-add hook failed: %v\n", err)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running post-add hook")
+			os.Exit(exitCode(err))
+		}
+		// exit as the command did so that snip exec can stand in for it in scripts
+		os.Exit(result.ExitCode)
+
+	case "expire":
+		if err := expireCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The expire arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing expire arguments")
+			expireCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(expireCmd.Args()) == 0 {
+			fmt.Fprintf(os.Stderr, "Must supply an expire action (ls|purge)\n")
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch expireCmd.Args()[0] {
+		case "ls":
+			if err := expireCmdList.Parse(expireCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The expire ls arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing expire ls arguments")
+				os.Exit(exitInvalid)
+			}
+			expiries, err := snip.ListExpiries()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem listing the snips with an expiry.\n")
+				log.Debug().Err(err).Msg("error listing expiries")
+				os.Exit(exitCode(err))
+			}
+			for _, e := range expiries {
+				s, err := snip.GetSnipMeta(e.UUID.String())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The snip with uuid: %s could not be obtained from the database.\n", e.UUID)
+					log.Debug().Err(err).Str("uuid", e.UUID.String()).Msg("error obtaining snip from uuid")
+					os.Exit(exitCode(err))
+				}
+				id := snip.ShortenUUID(s.UUID)[0]
+				if *expireCmdListLong {
+					id = s.UUID.String()
+				}
+				state := "expires"
+				if e.Expired() {
+					state = "expired"
+				}
+				fmt.Printf("%s %s %s %s\n", id, state, e.Expires.Local().Format("2006-01-02 15:04"), s.Name)
+			}
+		case "purge":
+			if err := expireCmdPurge.Parse(expireCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The expire purge arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing expire purge arguments")
+				os.Exit(exitInvalid)
+			}
+			purged, err := snip.PurgeExpired()
+			for _, id := range purged {
+				fmt.Printf("removed %s\n", id)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem removing the expired snips.\n")
+				log.Debug().Err(err).Msg("error purging expired snips")
+				os.Exit(exitCode(err))
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "The expire action %s is not supported.\n", expireCmd.Args()[0])
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+	case "export":
+		if err := exportCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The export arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing export arguments")
+			exportCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(exportCmd.Args()) < 1 {
+			fmt.Fprintf(os.Stderr, "Must supply an export format.\n")
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch exportCmd.Args()[0] {
+		case "feed":
+			if err := exportCmdFeed.Parse(exportCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				log.Debug().Err(err).Msg("error parsing export feed arguments")
+				exportCmdFeed.Usage()
+				os.Exit(exitInvalid)
+			}
+			err = export.Feed(os.Stdout, *exportCmdFeedTitle, *exportCmdFeedURL, *exportCmdFeedLimit)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem writing the feed.\n")
+				log.Debug().Err(err).Msg("error exporting feed")
+				os.Exit(exitCode(err))
+			}
+
+		case "markdown":
+			if len(exportCmd.Args()) != 2 {
+				fmt.Fprintf(os.Stderr, "Must supply a directory to write the markdown files to.\n")
+				os.Exit(exitInvalid)
+			}
+			dir := exportCmd.Args()[1]
+			bar := newProgressBar(os.Stderr)
+			count, err := export.MarkdownFiles(dir, bar)
+			bar.Finish()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem exporting markdown files to %s\n", dir)
+				log.Debug().Err(err).Str("dir", dir).Msg("error exporting markdown files")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("%d snips written to %s\n", count, dir)
+
+		case "pdf":
+			if err := parseInterspersed(exportCmdPDF, exportCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				log.Debug().Err(err).Msg("error parsing export pdf arguments")
+				exportCmdPDF.Usage()
+				os.Exit(exitInvalid)
+			}
+			if len(exportCmdPDF.Args()) != 1 {
+				fmt.Fprintf(os.Stderr, "Must supply the uuid of a snip.\n")
+				exportCmdPDF.Usage()
+				os.Exit(exitInvalid)
+			}
+			idStr := exportCmdPDF.Args()[0]
+			s, err := getSnip(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+				os.Exit(exitCode(err))
+			}
+
+			out := os.Stdout
+			if *exportCmdPDFOutput != "" {
+				out, err = os.Create(*exportCmdPDFOutput)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The file %s could not be created.\n", *exportCmdPDFOutput)
+					log.Debug().Err(err).Str("file", *exportCmdPDFOutput).Msg("error creating pdf file")
+					os.Exit(exitCode(err))
+				}
+			}
+			err = export.PDF(out, s)
+			if err == nil && out != os.Stdout {
+				err = out.Close()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem writing the pdf.\n")
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error exporting pdf")
+				os.Exit(exitCode(err))
+			}
+			if out != os.Stdout {
+				fmt.Printf("%s written -> %s\n", s.Name, *exportCmdPDFOutput)
+			}
+
+		case "site":
+			if err := exportCmdSite.Parse(exportCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				log.Debug().Err(err).Msg("error parsing export site arguments")
+				exportCmdSite.Usage()
+				os.Exit(exitInvalid)
+			}
+			if len(exportCmdSite.Args()) != 1 {
+				fmt.Fprintf(os.Stderr, "Must supply a directory to write the site to.\n")
+				exportCmdSite.Usage()
+				os.Exit(exitInvalid)
+			}
+			dir := exportCmdSite.Args()[0]
+			bar := newProgressBar(os.Stderr)
+			err = export.Site(dir, *exportCmdSiteTitle, bar)
+			bar.Finish()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem exporting the site to %s\n", dir)
+				log.Debug().Err(err).Str("dir", dir).Msg("error exporting site")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("site written to %s\n", path.Join(dir, "index.html"))
+
+		case "sql":
+			if err := exportCmdSQL.Parse(exportCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				log.Debug().Err(err).Msg("error parsing export sql arguments")
+				exportCmdSQL.Usage()
+				os.Exit(exitInvalid)
+			}
+			if len(exportCmdSQL.Args()) != 0 {
+				fmt.Fprintf(os.Stderr, "The export sql format accepts no arguments.\n")
+				exportCmdSQL.Usage()
+				os.Exit(exitInvalid)
+			}
+			out := os.Stdout
+			if *exportCmdSQLOutput != "" {
+				out, err = os.Create(*exportCmdSQLOutput)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The file %s could not be created.\n", *exportCmdSQLOutput)
+					log.Debug().Err(err).Str("file", *exportCmdSQLOutput).Msg("error creating sql file")
+					os.Exit(exitCode(err))
+				}
+			}
+			err = export.SQLDump(out, *exportCmdSQLSchema, *exportCmdSQLIndex)
+			if err == nil && out != os.Stdout {
+				err = out.Close()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem writing the sql dump.\n")
+				log.Debug().Err(err).Msg("error exporting sql")
+				os.Exit(exitCode(err))
+			}
+
+		default:
+			fmt.Fprintf(os.Stderr, "The export format %s is not supported.\n", exportCmd.Args()[0])
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+	case "get":
+		if err := getCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The get arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing get arguments")
+			os.Exit(exitInvalid)
+		}
+		var ids []string
+
+		// random from all snips
+		if *getCmdRandom {
+			// get list
+			// TODO: verify that this does not load everything in memory everywhere immediately
+			allSnips, err := snip.List(0)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem building the list of all snips in the database.\n")
+				log.Debug().Err(err).Msg("error retrieving all snips")
+				os.Exit(exitCode(err))
+			}
+
+			// get random within range
+			src := rand.NewSource(time.Now().UnixNano())
+			r := rand.New(src)
+			index := r.Intn(len(allSnips))
+			log.Debug().Int("random index", index).Msg("generated random integer")
+			// assign to outside world
+			ids = append(ids, allSnips[index].UUID.String())
+		} else if *getCmdIDsFromStdin {
+			ids, err = readIDs(os.Stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The standard input could not be read.\n")
+				log.Debug().Err(err).Msg("error reading ids from standard input")
+				os.Exit(exitCode(err))
+			}
+		}
+
+		// obtain uuids specified from arguments, followed by the target pane when sending to tmux
+		if *getCmdTmux {
+			if len(getCmd.Args()) < 1 || len(getCmd.Args()) > 2 || len(ids) > 0 {
+				fmt.Fprintf(os.Stderr, "Sending to tmux requires a single uuid, optionally followed by the target pane.\n")
+				os.Exit(exitInvalid)
+			}
+			ids = getCmd.Args()[:1]
+		} else {
+			ids = append(ids, getCmd.Args()...)
+		}
+		// an id of - is replaced with the ids read from standard input, such as the output of recent
+		var expanded []string
+		for _, idStr := range ids {
+			if idStr != "-" {
+				expanded = append(expanded, idStr)
+				continue
+			}
+			stdinIDs, err := readIDs(os.Stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The standard input could not be read.\n")
+				log.Debug().Err(err).Msg("error reading ids from standard input")
+				os.Exit(exitCode(err))
+			}
+			expanded = append(expanded, stdinIDs...)
+		}
+		ids = expanded
+		if len(ids) == 0 {
+			Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(ids) > 1 && *getCmdQR {
+			fmt.Fprintf(os.Stderr, "Only a single snip may be displayed as a qr code.\n")
+			os.Exit(exitInvalid)
+		}
+		if *getCmdBase64 && (*getCmdRaw || *getCmdJSON || *getCmdQR || *getCmdTmux || *getCmdLines != "" || *getCmdHead > 0 || *getCmdTail > 0) {
+			fmt.Fprintf(os.Stderr, "The -base64 option may not be used with -raw, -json, -qr, -tmux, -lines, -head, or -tail.\n")
+			os.Exit(exitInvalid)
+		}
+
+		// selected lines are read from the database incrementally rather than loading whole snips
+		if *getCmdLines != "" || *getCmdHead > 0 || *getCmdTail > 0 {
+			var lines lineRange
+			selections := 0
+			if *getCmdLines != "" {
+				selections++
+				lines, err = parseLineRange(*getCmdLines)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					os.Exit(exitInvalid)
+				}
+			}
+			if *getCmdHead > 0 {
+				selections++
+				lines = lineRange{First: 1, Last: *getCmdHead}
+			}
+			if *getCmdTail > 0 {
+				selections++
+				lines = lineRange{Tail: *getCmdTail}
+			}
+			if selections > 1 || *getCmdJSON || *getCmdQR || *getCmdTmux || *getCmdHeader {
+				fmt.Fprintf(os.Stderr, "Only one of -lines, -head, and -tail may be used, and not with -json, -qr, -tmux, or -header.\n")
+				os.Exit(exitInvalid)
+			}
+
+			for idx, idStr := range ids {
+				id, err := snip.ResolveUUID(idStr)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+					log.Debug().Err(err).Str("uuid", idStr).Msg("error resolving snip uuid")
+					os.Exit(exitCode(err))
+				}
+				if idx > 0 {
+					fmt.Println(*getCmdDelimiter)
+				}
+				if err = snip.RecordAccess(id); err != nil {
+					log.Debug().Err(err).Str("uuid", id.String()).Msg("error recording access")
+				}
+				data, err := snip.OpenData(id)
+				if err == nil {
+					err = writeLines(os.Stdout, data, lines)
+					data.Close()
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem reading the data of snip %s\n", idStr)
+					log.Debug().Err(err).Str("uuid", id.String()).Msg("error reading snip lines")
+					os.Exit(exitCode(err))
+				}
+			}
+			break
+		}
+
+		// There is no reason to parse this since it may be a fuzzy term. Rely on the errors.
+		// TODO handle both cases explicitly and derive functions for full and partial uuid
+		var snips []snip.Snip
+		for _, idStr := range ids {
+			s, err := getSnip(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+				os.Exit(exitCode(err))
+			}
+			if err = snip.RecordAccess(s.UUID); err != nil {
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error recording access")
+			}
+			snips = append(snips, s)
+		}
+		s := snips[0]
+
+		if *getCmdTmux {
+			target := tmuxDefaultTarget
+			if len(getCmd.Args()) == 2 {
+				target = getCmd.Args()[1]
+			}
+			err = sendTmux(target, s.Data)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem sending the snip to tmux pane %s\n", target)
+				log.Debug().Err(err).Str("target", target).Msg("error sending keys to tmux")
+				os.Exit(exitCode(err))
+			}
+		} else if *getCmdQR {
+			err = writeQR(os.Stdout, s.Data)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip could not be displayed as a qr code, it may be too large (%d bytes).\n", len(s.Data))
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error rendering qr code")
+				os.Exit(exitCode(err))
+			}
+		} else if *getCmdJSON {
+			// attachment contents are left to attach write
+			for idx := range snips {
+				for a := range snips[idx].Attachments {
+					snips[idx].Attachments[a].Data = nil
+				}
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(snips)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem encoding the snips as json.\n")
+				log.Debug().Err(err).Msg("error encoding snips")
+				os.Exit(exitCode(err))
+			}
+		} else {
+			for idx, s := range snips {
+				// separate snips with the delimiter on its own line
+				if idx > 0 {
+					if !strings.HasSuffix(snips[idx-1].Data, "\n") && *getCmdRaw {
+						fmt.Println()
+					}
+					fmt.Println(*getCmdDelimiter)
+				}
+				if *getCmdHeader {
+					writeSnipHeader(os.Stdout, s)
+				}
+				if *getCmdRaw {
+					// exactly the stored bytes, without a trailing newline added
+					_, err = io.WriteString(os.Stdout, s.Data)
+					if err != nil {
+						log.Debug().Err(err).Msg("error writing snip data")
+						os.Exit(exitCode(err))
+					}
+				} else if *getCmdBase64 {
+					fmt.Println(base64.StdEncoding.EncodeToString([]byte(s.Data)))
+				} else if !*getCmdHeader {
+					writeSnip(os.Stdout, s)
+					if *getCmdNotes {
+						notes, err := snip.GetNotes(s.UUID)
+						if err != nil {
+							fmt.Fprintf(os.Stderr, "There was a problem retrieving the notes of snip %s\n", s.UUID)
+							log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error retrieving notes")
+							os.Exit(exitCode(err))
+						}
+						if len(notes) > 0 {
+							fmt.Printf("notes:\n")
+							writeNotes(os.Stdout, notes)
+						}
+					}
+				}
+			}
+		}
+
+	case "hook":
+		if err := hookCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The hook arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing hook arguments")
+			hookCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(hookCmd.Args()) < 1 {
+			fmt.Fprintf(os.Stderr, "Must supply a hook action (install|uninstall|post-commit)\n")
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch hookCmd.Args()[0] {
+		case "install":
+			if err := parseInterspersed(hookCmdInstall, hookCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The hook install arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing hook install arguments")
+				os.Exit(exitInvalid)
+			}
+			args := []string{"hook", "post-commit"}
+			if *hookCmdInstallDiff != "" {
+				if _, err := regexp.Compile(*hookCmdInstallDiff); err != nil {
+					fmt.Fprintf(os.Stderr, "The diff pattern %s could not be compiled.\n", *hookCmdInstallDiff)
+					log.Debug().Err(err).Msg("error compiling diff pattern")
+					os.Exit(exitInvalid)
+				}
+				args = append(args, "-diff", *hookCmdInstallDiff)
+			}
+			for _, tag := range hookCmdInstallTags {
+				if _, err := snip.NormalizeTag(tag); err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					os.Exit(exitInvalid)
+				}
+				args = append(args, "-tag", tag)
+			}
+			// the hook runs this executable, since git may not find snip in its path
+			exe, err := os.Executable()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The location of snip could not be determined.\n")
+				log.Debug().Err(err).Msg("error locating executable")
+				os.Exit(exitFailure)
+			}
+			path, err := installGitHook(workDir, exe, args, *hookCmdInstallForce)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The post-commit hook could not be installed: %v\n", err)
+				log.Debug().Err(err).Str("path", path).Msg("error installing git hook")
+				os.Exit(exitFailure)
+			}
+			fmt.Printf("installed %s\n", path)
+		case "uninstall":
+			path, err := uninstallGitHook(workDir)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The post-commit hook could not be removed: %v\n", err)
+				log.Debug().Err(err).Str("path", path).Msg("error removing git hook")
+				os.Exit(exitFailure)
+			}
+			fmt.Printf("removed %s\n", path)
+		case "post-commit":
+			if err := parseInterspersed(hookCmdCommit, hookCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The hook post-commit arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing hook post-commit arguments")
+				os.Exit(exitInvalid)
+			}
+			var pattern *regexp.Regexp
+			if *hookCmdCommitDiff != "" {
+				pattern, err = regexp.Compile(*hookCmdCommitDiff)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The diff pattern %s could not be compiled.\n", *hookCmdCommitDiff)
+					log.Debug().Err(err).Msg("error compiling diff pattern")
+					os.Exit(exitInvalid)
+				}
+			}
+			s, err := commitSnip(workDir, pattern)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The latest commit could not be read: %v\n", err)
+				log.Debug().Err(err).Str("dir", workDir).Msg("error reading commit")
+				os.Exit(exitFailure)
+			}
+			tags, err := contextTags(projectContext, append(s.Tags, hookCmdCommitTags...), false)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(exitInvalid)
+			}
+			s.Tags = tags
+			err = snip.LocalStore{}.Insert(s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem inserting the new snip into the database.\n")
+				log.Debug().Err(err).Msg("error inserting Snip into database")
+				os.Exit(exitCode(err))
+			}
+			fmt.Fprintf(os.Stderr, "added snip uuid: %s\n", s.UUID)
+
+			err = snip.RunHook(snip.HookPostAdd, conf.Hooks[snip.HookPostAdd], s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The post-add hook failed: %v\n", err)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running post-add hook")
+				os.Exit(exitCode(err))
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "The hook action %s is not supported.\n", hookCmd.Args()[0])
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+	case "import":
+		if err := importCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The import arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing import arguments")
+			importCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(importCmd.Args()) == 0 {
+			fmt.Fprintf(os.Stderr, "Must supply a source to import from (csv|dir|history|jsonl)\n")
+			importCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch importCmd.Args()[0] {
+		case "history":
+			if err := importCmdHistory.Parse(importCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The import history arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing import history arguments")
+				importCmdHistory.Usage()
+				os.Exit(exitInvalid)
+			}
+			filename := *importCmdHistoryFile
+			if filename == "" {
+				filename, err = historyFile(*importCmdHistoryShell)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The history file could not be located: %v\n", err)
+					log.Debug().Err(err).Str("shell", *importCmdHistoryShell).Msg("error locating history file")
+					os.Exit(exitCode(err))
+				}
+			}
+			f, err := os.Open(filename)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The history file %s could not be read.\n", filename)
+				log.Debug().Err(err).Str("file", filename).Msg("error opening history file")
+				os.Exit(exitCode(err))
+			}
+			entries, err := parseHistory(f, *importCmdHistoryShell)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem parsing the history file %s: %v\n", filename, err)
+				log.Debug().Err(err).Str("file", filename).Msg("error parsing history file")
+				os.Exit(exitCode(err))
+			}
+			entries = dedupeHistory(entries)
+
+			var snips []snip.Snip
+			if *importCmdHistoryDaily {
+				days, byDay := historyDays(entries, time.Now())
+				for _, day := range days {
+					var commands []string
+					for _, entry := range byDay[day] {
+						commands = append(commands, entry.Command)
+					}
+					s := snip.New()
+					s.Name = "history " + day
+					s.Data = strings.Join(commands, "\n") + "\n"
+					if when := byDay[day][0].Time; !when.IsZero() {
+						s.Timestamp = when
+					}
+					snips = append(snips, s)
+				}
+			} else {
+				for _, entry := range entries {
+					s := snip.New()
+					s.Name = snip.GenerateName([]byte(entry.Command), snip.NameStrategy{Kind: snip.NameLine})
+					s.Data = entry.Command + "\n"
+					if !entry.Time.IsZero() {
+						s.Timestamp = entry.Time
+					}
+					snips = append(snips, s)
+				}
+			}
+
+			// commands imported by an earlier run are not added again, and the snips of days imported before gain the commands since
+			existing := make(map[string]bool)
+			existingDays := make(map[string]snip.Snip)
+			ids, err := snip.GetTagUUIDs("history")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem reading previously imported history.\n")
+				log.Debug().Err(err).Msg("error listing history snips")
+				os.Exit(exitCode(err))
+			}
+			for _, id := range ids {
+				s, err := snip.GetFromUUID(id.String())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem reading previously imported history.\n")
+					log.Debug().Err(err).Str("uuid", id.String()).Msg("error retrieving history snip")
+					os.Exit(exitCode(err))
+				}
+				existing[s.Name+"\x00"+s.Data] = true
+				if *importCmdHistoryDaily && strings.HasPrefix(s.Name, "history ") {
+					existingDays[s.Name] = s
+				}
+			}
+
+			added, updated := 0, 0
+			// a single transaction keeps large imports fast and leaves nothing behind on failure
+			err = database.Conn.WithTx(func() error {
+				for _, s := range snips {
+					if existing[s.Name+"\x00"+s.Data] {
+						continue
+					}
+					if day, ok := existingDays[s.Name]; ok {
+						data := mergeHistoryLines(day.Data, s.Data)
+						if data == day.Data {
+							continue
+						}
+						day.Data = data
+						if err := day.Update(); err != nil {
+							return err
+						}
+						if err := snip.Reindex(day.UUID); err != nil {
+							return err
+						}
+						updated++
+						continue
+					}
+					s.Tags = []string{"history"}
+					s.Meta = map[string]string{"shell": *importCmdHistoryShell}
+					if err := (snip.LocalStore{}).Insert(s); err != nil {
+						return err
+					}
+					added++
+				}
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem importing the history, nothing was imported.\n")
+				log.Debug().Err(err).Str("file", filename).Msg("error importing history")
+				os.Exit(exitCode(err))
+			}
+			if *importCmdHistoryDaily {
+				fmt.Printf("imported %d snips from %s, updated %d, skipped %d already imported\n", added, filename, updated, len(snips)-added-updated)
+				break
+			}
+			fmt.Printf("imported %d snips from %s, skipped %d already imported\n", added, filename, len(snips)-added)
+
+		case "csv", "dir", "jsonl":
+			source := importCmd.Args()[0]
+			if err := parseInterspersed(importCmdBulk, importCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The import %s arguments could not be parsed.\n", source)
+				log.Debug().Err(err).Msg("error parsing import arguments")
+				importCmdBulk.Usage()
+				os.Exit(exitInvalid)
+			}
+			if importCmdBulk.NArg() != 1 {
+				fmt.Fprintf(os.Stderr, "Must supply a single %s to import.\n", map[string]string{"csv": "file", "dir": "directory", "jsonl": "file"}[source])
+				importCmdBulk.Usage()
+				os.Exit(exitInvalid)
+			}
+			target := importCmdBulk.Arg(0)
+
+			// reading the source is quick, while preparing each record is done by the workers
+			var count int
+			var prepare func(idx int) (importRecord, error)
+			switch source {
+			case "dir":
+				files, err := importFiles(target)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The files beneath %s could not be listed.\n", target)
+					log.Debug().Err(err).Str("dir", target).Msg("error listing import files")
+					os.Exit(exitCode(err))
+				}
+				targets, err := importLinkTargets(files)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The markdown files beneath %s could not be read.\n", target)
+					log.Debug().Err(err).Str("dir", target).Msg("error reading import link targets")
+					os.Exit(exitCode(err))
+				}
+				count = len(files)
+				prepare = func(idx int) (importRecord, error) {
+					record, err := prepareFile(target, files[idx], nameStrategy, targets)
+					if err != nil {
+						return record, fmt.Errorf("%s: %w", files[idx], err)
+					}
+					return record, nil
+				}
+			case "csv", "jsonl":
+				f, err := os.Open(target)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The file %s could not be read.\n", target)
+					log.Debug().Err(err).Str("file", target).Msg("error opening import file")
+					os.Exit(exitCode(err))
+				}
+				if source == "csv" {
+					var rows []importRow
+					rows, err = readImportCSV(f)
+					count = len(rows)
+					prepare = func(idx int) (importRecord, error) {
+						s, err := rows[idx].snip()
+						if err == nil {
+							var record importRecord
+							if record, err = prepareImport(s, nameStrategy); err == nil {
+								return record, nil
+							}
+						}
+						return importRecord{}, fmt.Errorf("record %d: %w", idx+1, err)
+					}
+				} else {
+					var lines []string
+					var numbers []int
+					lines, numbers, err = readImportJSONL(f)
+					count = len(lines)
+					prepare = func(idx int) (importRecord, error) {
+						record, err := prepareJSONL(lines[idx], nameStrategy)
+						if err != nil {
+							return record, fmt.Errorf("line %d: %w", numbers[idx], err)
+						}
+						return record, nil
+					}
+				}
+				f.Close()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem reading %s: %v\n", target, err)
+					log.Debug().Err(err).Str("file", target).Msg("error reading import file")
+					os.Exit(exitCode(err))
+				}
+			}
+
+			started := time.Now()
+			bar := newProgressBar(os.Stderr)
+			result, err := bulkImport(count, *importCmdBulkJobs, prepare, bar)
+			bar.Finish()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem importing %s: %v\n", target, err)
+				fmt.Fprintf(os.Stderr, "%d snips imported before the problem were kept.\n", result.Imported)
+				log.Debug().Err(err).Str("source", target).Msg("error importing")
+				os.Exit(exitCode(err))
+			}
+			fmt.Println(importSummary(result, time.Since(started)))
+
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown import source %s\n", importCmd.Args()[0])
+			importCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+
+	case "journal":
+		if err := journalCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The journal arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing journal arguments")
+			journalCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if journalCmd.Arg(0) == "ls" {
+			if err := parseInterspersed(journalCmdList, journalCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The arguments to the ls command could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing journal ls arguments")
+				journalCmdList.Usage()
+				os.Exit(exitInvalid)
+			}
+			var from, until time.Time
+			if *journalCmdListMonth != "" {
+				from, until, err = parseMonth(*journalCmdListMonth, time.Local)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The month %v.\n", err)
+					os.Exit(exitInvalid)
+				}
+			}
+			entries, err := snip.ListJournal(from, until)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem listing the journal entries.\n")
+				log.Debug().Err(err).Msg("error listing journal")
+				os.Exit(exitCode(err))
+			}
+			writeJournal(os.Stdout, entries, *journalCmdListLong)
+			break
+		}
+		if journalCmd.NArg() > 1 {
+			fmt.Fprintf(os.Stderr, "The journal command takes at most one day.\n")
+			journalCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		now := time.Now()
+		day, err := parseJournalDay(journalCmd.Arg(0), now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The day %v.\n", err)
+			os.Exit(exitInvalid)
+		}
+		s, err := snip.FindJournalEntry(day)
+		exists := err == nil
+		if err != nil && !errors.Is(err, database.ErrNoRows) {
+			fmt.Fprintf(os.Stderr, "There was a problem finding the journal entry for %s\n", day.Format(snip.JournalDateLayout))
+			log.Debug().Err(err).Msg("error finding journal entry")
+			os.Exit(exitCode(err))
+		}
+		if exists {
+			locked, err := snip.IsLocked(s.UUID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem checking the lock of snip %s\n", s.UUID)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error checking lock")
+				os.Exit(exitCode(err))
+			}
+			if locked {
+				fmt.Fprintf(os.Stderr, "The journal entry %s %s is locked.\n", s.UUID, s.Name)
+				os.Exit(exitFailure)
+			}
+		}
+		text, err := editText(s.Data, day.Format(snip.JournalDateLayout)+".md")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The editor %s failed: %v\n", editorCommand(), err)
+			log.Debug().Err(err).Msg("error running editor")
+			os.Exit(exitFailure)
+		}
+
+		switch {
+		case !exists && strings.TrimSpace(text) == "":
+			fmt.Printf("no journal entry was added for %s\n", day.Format(snip.JournalDateLayout))
+		case !exists:
+			s = snip.NewJournalEntry(day, text, now)
+			if err = (snip.LocalStore{}).Insert(s); err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem inserting the new snip into the database.\n")
+				log.Debug().Err(err).Msg("error inserting Snip into database")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("added snip uuid: %s\n", s.UUID)
+			err = snip.RunHook(snip.HookPostAdd, conf.Hooks[snip.HookPostAdd], s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The post-add hook failed: %v\n", err)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running post-add hook")
+				os.Exit(exitCode(err))
+			}
+		case text == s.Data:
+			fmt.Printf("unchanged %s %s\n", s.UUID, s.Name)
+		default:
+			s.Data = text
+			if err = s.Update(); err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem updating snip with id %s\n", s.UUID)
+				log.Debug().Err(err).Msg("could not update snip")
+				os.Exit(exitCode(err))
+			}
+			if err = snip.Reindex(s.UUID); err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem indexing snip %s\n", s.UUID)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error indexing snip")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("updated %s %s\n", s.UUID, s.Name)
+			err = snip.RunHook(snip.HookPostEdit, conf.Hooks[snip.HookPostEdit], s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The post-edit hook failed: %v\n", err)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running post-edit hook")
+				os.Exit(exitCode(err))
+			}
+		}
+
+	case "lock":
+		if err := parseInterspersed(lockCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The lock arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing lock arguments")
+			lockCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+
+		// list locked snips
+		if len(lockCmd.Args()) == 0 {
+			ids, err := snip.ListLocked()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem listing the locked snips.\n")
+				log.Debug().Err(err).Msg("error listing locked snips")
+				os.Exit(exitCode(err))
+			}
+			for _, id := range ids {
+				s, err := snip.GetSnipMeta(id.String())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The snip with uuid: %s could not be obtained from the database.\n", id)
+					log.Debug().Err(err).Str("uuid", id.String()).Msg("error obtaining snip from uuid")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("%s %s\n", snip.ShortenUUID(s.UUID)[0], s.Name)
+			}
+			break
+		}
+
+		for _, idStr := range lockCmd.Args() {
+			s, err := getSnip(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+				os.Exit(exitCode(err))
+			}
+			if *lockCmdRemove {
+				err = snip.Unlock(s.UUID)
+			} else {
+				err = snip.Lock(s.UUID)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The lock of snip %s could not be changed.\n", s.UUID)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error changing lock")
+				os.Exit(exitCode(err))
+			}
+			if *lockCmdRemove {
+				fmt.Printf("unlocked %s %s\n", s.UUID, s.Name)
+			} else {
+				fmt.Printf("locked %s %s\n", s.UUID, s.Name)
+			}
+		}
+
+	case "ls":
+		if err := listCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The ls arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing ls arguments")
+			listCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if err := setColorMode(colorMode); err != nil {
+			fmt.Fprintf(os.Stderr, "The %v\n", err)
+			os.Exit(exitInvalid)
+		}
+		if *listCmdSort != "" && *listCmdSort != "stars" {
+			fmt.Fprintf(os.Stderr, "The sort field %s is not supported (stars)\n", *listCmdSort)
+			os.Exit(exitInvalid)
+		}
+		if *listCmdHasAttachments && *listCmdNoAttachments {
+			fmt.Fprintf(os.Stderr, "The -has-attachments and -no-attachments options cannot be used together.\n")
+			os.Exit(exitInvalid)
+		}
+		if *listCmdPorcelain && (len(listCmdColumns) > 0 || *listCmdGroupBy != "") {
+			fmt.Fprintf(os.Stderr, "The -porcelain option may not be used with -columns or -group-by.\n")
+			os.Exit(exitInvalid)
+		}
+		var listStatus snip.Status
+		if *listCmdStatus != "" {
+			listStatus, err = snip.ParseStatus(*listCmdStatus)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The %s\n", err)
+				os.Exit(exitInvalid)
+			}
+		}
+		columns := []string(listCmdColumns)
+		if len(columns) == 0 {
+			columns = []string{"uuid"}
+			// ratings are shown when they are used to sort or filter
+			if *listCmdSort == "stars" || *listCmdStarred {
+				columns = append(columns, "stars")
+			}
+			if *listCmdLong {
+				columns = append(columns, "attachments")
+			}
+			columns = append(columns, "name")
+		}
+		if err := validateColumns(columns); err != nil {
+			fmt.Fprintf(os.Stderr, "The %s\n", err)
+			os.Exit(exitInvalid)
+		}
+		showColumn := make(map[string]bool)
+		for _, c := range columns {
+			showColumn[c] = true
+		}
+		var snips []snip.Snip
+		if daemon != nil {
+			snips, err = daemon.List(0)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the metadata of all snips.\n")
+				log.Debug().Err(err).Msg("error listing items metadata from daemon")
+				os.Exit(exitCode(err))
+			}
+		} else {
+			snips, err = snip.ListMetadata(0)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the metadata of all snips.\n")
+				log.Debug().Err(err).Msg("error listing items metadata")
+				os.Exit(exitCode(err))
+			}
+		}
+
+		var ratings map[uuid.UUID]int
+		if *listCmdSort == "stars" || *listCmdStarred || showColumn["stars"] {
+			ratings, err = snip.ListStars()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the ratings of snips.\n")
+				log.Debug().Err(err).Msg("error listing ratings")
+				os.Exit(exitCode(err))
+			}
+		}
+		var attachmentCounts map[uuid.UUID]int
+		if *listCmdHasAttachments || *listCmdNoAttachments || showColumn["attachments"] {
+			attachmentCounts, err = snip.ListAttachmentCounts()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the attachments of snips.\n")
+				log.Debug().Err(err).Msg("error counting attachments")
+				os.Exit(exitCode(err))
+			}
+		}
+		if *listCmdHasAttachments || *listCmdNoAttachments {
+			var filtered []snip.Snip
+			for _, s := range snips {
+				if (attachmentCounts[s.UUID] > 0) == *listCmdHasAttachments {
+					filtered = append(filtered, s)
+				}
+			}
+			snips = filtered
+		}
+		if *listCmdStarred {
+			var starred []snip.Snip
+			for _, s := range snips {
+				if ratings[s.UUID] > 0 {
+					starred = append(starred, s)
+				}
+			}
+			snips = starred
+		}
+		var langs map[uuid.UUID]string
+		if *listCmdLang != "" || showColumn["lang"] {
+			langs, err = snip.ListLanguages()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the languages of snips.\n")
+				log.Debug().Err(err).Msg("error listing languages")
+				os.Exit(exitCode(err))
+			}
+		}
+		if *listCmdLang != "" {
+			var code []snip.Snip
+			for _, s := range snips {
+				if langs[s.UUID] == strings.ToLower(*listCmdLang) {
+					code = append(code, s)
+				}
+			}
+			snips = code
+		}
+		var statuses map[uuid.UUID]snip.Status
+		if *listCmdStatus != "" || showColumn["status"] || *listCmdGroupBy == "status" {
+			statuses, err = snip.ListStatuses()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the statuses of snips.\n")
+				log.Debug().Err(err).Msg("error listing statuses")
+				os.Exit(exitCode(err))
+			}
+		}
+		if *listCmdStatus != "" {
+			var tasks []snip.Snip
+			for _, s := range snips {
+				if statuses[s.UUID] == listStatus {
+					tasks = append(tasks, s)
+				}
+			}
+			snips = tasks
+		}
+		// a grouping by time lists each group chronologically, like a journal
+		if *listCmdGroupBy == "day" || *listCmdGroupBy == "month" {
+			sort.SliceStable(snips, func(i, j int) bool {
+				return snips[i].Timestamp.Before(snips[j].Timestamp)
+			})
+		}
+		if *listCmdSort == "stars" {
+			sort.SliceStable(snips, func(i, j int) bool {
+				return ratings[snips[i].UUID] > ratings[snips[j].UUID]
+			})
+		}
+
+		if *listCmdPorcelain {
+			for _, s := range snips {
+				writePorcelain(os.Stdout, s.UUID.String(), s.Timestamp.UTC().Format(time.RFC3339Nano), s.Name)
+			}
+			break
+		}
+
+		// sizes, tags, and modification times are gathered for all snips at once
+		var sizes map[uuid.UUID]int64
+		if showColumn["size"] {
+			sizes, err = snip.ListSizes()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the sizes of snips.\n")
+				log.Debug().Err(err).Msg("error listing sizes")
+				os.Exit(exitCode(err))
+			}
+		}
+		var tags map[uuid.UUID][]string
+		if showColumn["tags"] || *listCmdGroupBy == "tag" {
+			tags, err = snip.ListSnipTags()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the tags of snips.\n")
+				log.Debug().Err(err).Msg("error listing tags")
+				os.Exit(exitCode(err))
+			}
+		}
+		var modified map[uuid.UUID]time.Time
+		if showColumn["modified"] {
+			modified, err = snip.ListModified()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the modification times of snips.\n")
+				log.Debug().Err(err).Msg("error listing modification times")
+				os.Exit(exitCode(err))
+			}
+		}
+
+		row := func(s snip.Snip) []string {
+			var row []string
+			for _, c := range columns {
+				switch c {
+				case "uuid":
+					id := snip.ShortenUUID(s.UUID)[0]
+					if *listCmdLong {
+						id = s.UUID.String()
+					}
+					row = append(row, id)
+				case "name":
+					row = append(row, s.Name)
+				case "modified":
+					t, ok := modified[s.UUID]
+					if !ok {
+						t = s.Timestamp
+					}
+					row = append(row, t.Local().Format("2006-01-02 15:04"))
+				case "size":
+					row = append(row, strconv.FormatInt(sizes[s.UUID], 10))
+				case "attachments":
+					row = append(row, strconv.Itoa(attachmentCounts[s.UUID]))
+				case "stars":
+					row = append(row, strings.Repeat("*", ratings[s.UUID]))
+				case "tags":
+					row = append(row, strings.Join(tags[s.UUID], ","))
+				case "lang":
+					row = append(row, langs[s.UUID])
+				case "status":
+					row = append(row, string(statuses[s.UUID]))
+				}
+			}
+			return row
+		}
+		groups := []snipGroup{{Snips: snips}}
+		if *listCmdGroupBy != "" {
+			groups, err = groupSnips(snips, *listCmdGroupBy, tags, statuses)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The %s\n", err)
+				os.Exit(exitInvalid)
+			}
+		}
+		var sections []tableSection
+		for _, g := range groups {
+			section := tableSection{}
+			if g.Title != "" {
+				section.Title = fmt.Sprintf("%s (%d)", g.Title, len(g.Snips))
+			}
+			for _, s := range g.Snips {
+				section.Rows = append(section.Rows, row(s))
+			}
+			sections = append(sections, section)
+		}
+		right := make(map[int]bool)
+		styles := make(map[int]*color.Color)
+		for idx, c := range columns {
+			right[idx] = c == "size" || c == "attachments"
+			switch c {
+			case "uuid":
+				styles[idx] = colors.ID
+			case "name":
+				styles[idx] = colors.Name
+			}
+		}
+		writeStyledTable(os.Stdout, os.Stderr, columns, sections, right, styles)
+
+	case "mail":
+		if err := parseInterspersed(mailCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The mail arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing mail arguments")
+			mailCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(mailCmd.Args()) != 1 || *mailCmdTo == "" {
+			fmt.Fprintf(os.Stderr, "Must supply a snip uuid and at least one recipient with -to.\n")
+			mailCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if *mailCmdFrom == "" {
+			fmt.Fprintf(os.Stderr, "Must supply a sender with -from or smtp.from in %s\n", config.Path())
+			os.Exit(exitInvalid)
+		}
+
+		idStr := mailCmd.Args()[0]
+		s, err := getSnip(idStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+			log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+			os.Exit(exitCode(err))
+		}
+		var to []string
+		for _, rcpt := range strings.Split(*mailCmdTo, ",") {
+			if rcpt = strings.TrimSpace(rcpt); rcpt != "" {
+				to = append(to, rcpt)
+			}
+		}
+
+		msg, err := export.Message(s, *mailCmdFrom, to, *mailCmdRaw)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem composing the message.\n")
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error composing mail message")
+			os.Exit(exitCode(err))
+		}
+		err = sendMail(conf.SMTP, *mailCmdFrom, to, msg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem sending the message: %v\n", err)
+			log.Debug().Err(err).Str("host", conf.SMTP.Host).Msg("error sending mail")
+			os.Exit(exitCode(err))
+		}
+		fmt.Printf("sent %s to %s\n", s.Name, strings.Join(to, ", "))
+
+	case "mv-db":
+		if err := parseInterspersed(moveDBCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The mv-db arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing mv-db arguments")
+			moveDBCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(moveDBCmd.Args()) == 0 || *moveDBCmdTo == "" {
+			fmt.Fprintf(os.Stderr, "Must supply at least one snip uuid and a database with -to.\n")
+			moveDBCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		var ids []uuid.UUID
+		for _, idStr := range moveDBCmd.Args() {
+			id, err := snip.ResolveUUID(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be found.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error resolving uuid")
+				os.Exit(exitCode(err))
+			}
+			ids = append(ids, id)
+		}
+		err = snip.MoveToDatabase(*moveDBCmdTo, ids...)
+		if errors.Is(err, snip.ErrLocked) {
+			fmt.Fprintf(os.Stderr, "A locked snip cannot be moved, unlock it first: %v\n", err)
+			os.Exit(exitFailure)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem moving the snips to %s, none were moved.\n", *moveDBCmdTo)
+			log.Debug().Err(err).Str("path", *moveDBCmdTo).Msg("error moving snips")
+			os.Exit(exitCode(err))
+		}
+		for _, id := range ids {
+			fmt.Printf("moved %s to %s\n", id, *moveDBCmdTo)
+		}
+
+	case "note":
+		if err := noteCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The note arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing note arguments")
+			noteCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(noteCmd.Args()) < 2 {
+			fmt.Fprintf(os.Stderr, "Must supply a note action (add|ls|rm) and an id\n")
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch noteCmd.Args()[0] {
+		case "add":
+			idStr := noteCmd.Args()[1]
+			s, err := getSnip(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+				os.Exit(exitCode(err))
+			}
+			text := strings.Join(noteCmd.Args()[2:], " ")
+			if text == "" {
+				data, err := readFromStdin()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The standard input could not be read.\n")
+					log.Debug().Err(err).Msg("error reading from standard input")
+					os.Exit(exitCode(err))
+				}
+				text = string(data)
+			}
+			n, err := snip.AddNote(s.UUID, text)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The note could not be added: %v\n", err)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error adding note")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("added note %d to %s %s\n", n.ID, s.UUID, s.Name)
+		case "ls":
+			idStr := noteCmd.Args()[1]
+			s, err := getSnip(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+				os.Exit(exitCode(err))
+			}
+			notes, err := snip.GetNotes(s.UUID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem retrieving the notes of snip %s\n", s.UUID)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error retrieving notes")
+				os.Exit(exitCode(err))
+			}
+			writeNotes(os.Stdout, notes)
+		case "rm":
+			for _, arg := range noteCmd.Args()[1:] {
+				noteID, err := strconv.ParseInt(arg, 10, 64)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The note id %s is not a number.\n", arg)
+					os.Exit(exitInvalid)
+				}
+				err = snip.RemoveNote(noteID)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The note %d could not be removed: %v\n", noteID, err)
+					log.Debug().Err(err).Int64("note", noteID).Msg("error removing note")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("removed note %d\n", noteID)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "The note action %s is not supported.\n", noteCmd.Args()[0])
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+	case "random":
+		if err := randomCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The random arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing random arguments")
+			randomCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		var tags []string
+		for _, tag := range randomCmdTags {
+			tag, err := snip.NormalizeTag(tag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(exitInvalid)
+			}
+			tags = append(tags, tag)
+		}
+
+		id, err := snip.GetRandomSnipID(tags...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem selecting a random snip.\n")
+			log.Debug().Err(err).Msg("error selecting random snip")
+			os.Exit(exitCode(err))
+		}
+		if id == uuid.Nil {
+			fmt.Fprintf(os.Stderr, "There are no snips to choose from.\n")
+			os.Exit(exitNotFound)
+		}
+		s, err := snip.GetFromUUID(id.String())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The snip with uuid: %s could not be obtained from the database.\n", id)
+			log.Debug().Err(err).Str("uuid", id.String()).Msg("error obtaining snip from uuid")
+			os.Exit(exitCode(err))
+		}
+		if err = snip.RecordAccess(s.UUID); err != nil {
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error recording access")
+		}
+		if *randomCmdRaw {
+			fmt.Print(s.Data)
+		} else {
+			writeSnip(os.Stdout, s)
+		}
+
+	case "recent":
+		if err := parseInterspersed(recentCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The recent arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing recent arguments")
+			recentCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		limit := 10
+		if len(recentCmd.Args()) > 1 {
+			fmt.Fprintf(os.Stderr, "The recent command accepts only the number of snips to list.\n")
+			os.Exit(exitInvalid)
+		}
+		if len(recentCmd.Args()) == 1 {
+			limit, err = strconv.Atoi(recentCmd.Args()[0])
+			if err != nil || limit < 1 {
+				fmt.Fprintf(os.Stderr, "The number of snips %s must be a positive number.\n", recentCmd.Args()[0])
+				os.Exit(exitInvalid)
+			}
+		}
+
+		recent, err := snip.Recent(limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem listing the recent snips.\n")
+			log.Debug().Err(err).Msg("error listing recent snips")
+			os.Exit(exitCode(err))
+		}
+		for _, a := range recent {
+			s, err := snip.GetSnipMeta(a.UUID.String())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with uuid: %s could not be obtained from the database.\n", a.UUID)
+				log.Debug().Err(err).Str("uuid", a.UUID.String()).Msg("error obtaining snip from uuid")
+				os.Exit(exitCode(err))
+			}
+			id := snip.ShortenUUID(s.UUID)[0]
+			if *recentCmdLong {
+				id = s.UUID.String()
+			}
+			fmt.Printf("%s %s %s\n", id, a.Accessed.Local().Format("2006-01-02 15:04"), s.Name)
+		}
+
+	case "replace":
+		if err := parseInterspersed(replaceCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The replace arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing replace arguments")
+			replaceCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(replaceCmd.Args()) != 1 {
+			fmt.Fprintf(os.Stderr, "Must supply a single substitution such as s/old/new/g.\n")
+			replaceCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		sub, err := parseSubstitution(replaceCmd.Args()[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The substitution is not valid: %v\n", err)
+			os.Exit(exitInvalid)
+		}
+
+		filterCmd := flag.NewFlagSet("filter", flag.ContinueOnError)
+		filter := addFilterFlags(filterCmd, "replace in")
+		if err = parseInterspersed(filterCmd, strings.Fields(*replaceCmdFilter)); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The filter %q could not be parsed.\n", *replaceCmdFilter)
+			log.Debug().Err(err).Msg("error parsing filter")
+			os.Exit(exitInvalid)
+		}
+		filter.Terms = filterCmd.Args()
+		ids, err := filter.IDs()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem finding the snips to change: %v\n", err)
+			log.Debug().Err(err).Msg("error filtering snips")
+			os.Exit(exitCode(err))
+		}
+
+		var changed []snip.Snip
+		// locked snips are changed only with -force, and locked again once changed
+		lockedIDs := make(map[uuid.UUID]bool)
+		replacements := 0
+		for _, id := range ids {
+			s, err := snip.GetFromUUID(id.String())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with uuid: %s could not be obtained from the database.\n", id)
+				log.Debug().Err(err).Str("uuid", id.String()).Msg("error obtaining snip from uuid")
+				os.Exit(exitCode(err))
+			}
+			// binary data is left alone, since a pattern matching text could corrupt it
+			if isBinary(s.Data) {
+				continue
+			}
+			data, count := sub.Apply(s.Data)
+			if count == 0 {
+				continue
+			}
+			locked, err := snip.IsLocked(s.UUID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem checking the lock of snip %s\n", s.UUID)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error checking lock")
+				os.Exit(exitCode(err))
+			}
+			if locked && !*replaceCmdForce {
+				fmt.Printf("skipped locked %s %s\n", snip.ShortenUUID(s.UUID)[0], s.Name)
+				continue
+			}
+			if locked {
+				lockedIDs[s.UUID] = true
+			}
+			if *replaceCmdDryRun {
+				label := fmt.Sprintf("%s %s", snip.ShortenUUID(s.UUID)[0], s.Name)
+				writeUnifiedDiff(os.Stdout, s.Data, data, label, label)
+			}
+			s.Data = data
+			changed = append(changed, s)
+			replacements += count
+		}
+		if *replaceCmdDryRun {
+			fmt.Printf("would replace %d matches in %d snips\n", replacements, len(changed))
+			break
+		}
+		if len(changed) > 0 && !confirmAction(fmt.Sprintf("REPLACE %d matches in %d snips", replacements, len(changed)), *replaceCmdYes) {
+			fmt.Println("skipped")
+			break
+		}
+
+		// every snip is changed or none are
+		err = database.Conn.WithTx(func() error {
+			for _, s := range changed {
+				if lockedIDs[s.UUID] {
+					if err := snip.Unlock(s.UUID); err != nil {
+						return err
+					}
+				}
+				if err := s.Update(); err != nil {
+					return err
+				}
+				if err := snip.Reindex(s.UUID); err != nil {
+					return err
+				}
+				if lockedIDs[s.UUID] {
+					if err := snip.Lock(s.UUID); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem updating the snips, none were changed.\n")
+			log.Debug().Err(err).Msg("error replacing in snips")
+			os.Exit(exitCode(err))
+		}
+		for _, s := range changed {
+			fmt.Printf("updated %s %s\n", snip.ShortenUUID(s.UUID)[0], s.Name)
+		}
+		fmt.Printf("replaced %d matches in %d snips\n", replacements, len(changed))
+		for _, s := range changed {
+			err = snip.RunHook(snip.HookPostEdit, conf.Hooks[snip.HookPostEdit], s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The post-edit hook failed: %v\n", err)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running post-edit hook")
+				os.Exit(exitCode(err))
+			}
+		}
+
+	case "review":
+		if err := reviewCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The review arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing review arguments")
+			reviewCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(reviewCmd.Args()) == 0 {
+			reviews, err := snip.DueReviews()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem obtaining the snips due for review.\n")
+				log.Debug().Err(err).Msg("error listing due reviews")
+				os.Exit(exitCode(err))
+			}
+			if len(reviews) == 0 {
+				fmt.Println("no snips are due for review")
+				break
+			}
+			graded, err := runReview(os.Stdin, os.Stdout, reviews)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem scheduling the next review.\n")
+				log.Debug().Err(err).Msg("error reviewing snips")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("reviewed %d of %d snips\n", graded, len(reviews))
+			break
+		}
+
+		switch reviewCmd.Args()[0] {
+		case "add":
+			if len(reviewCmd.Args()) < 2 {
+				fmt.Fprintf(os.Stderr, "Must supply at least one snip uuid to review.\n")
+				os.Exit(exitInvalid)
+			}
+			for _, idStr := range reviewCmd.Args()[1:] {
+				id, err := snip.ResolveUUID(idStr)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The snip with id %s could not be found.\n", idStr)
+					log.Debug().Err(err).Str("uuid", idStr).Msg("error resolving uuid")
+					os.Exit(exitCode(err))
+				}
+				if err = snip.AddReview(id); err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem scheduling snip %s for review.\n", id)
+					log.Debug().Err(err).Str("uuid", id.String()).Msg("error adding review")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("added %s for review\n", id)
+			}
+
+		case "ls":
+			if err := reviewCmdList.Parse(reviewCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The review ls arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing review ls arguments")
+				reviewCmdList.Usage()
+				os.Exit(exitInvalid)
+			}
+			reviews, err := snip.ListReviews()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem listing the snips under review.\n")
+				log.Debug().Err(err).Msg("error listing reviews")
+				os.Exit(exitCode(err))
+			}
+			for _, r := range reviews {
+				s, err := snip.GetSnipMeta(r.UUID.String())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The snip with uuid: %s could not be obtained from the database.\n", r.UUID)
+					log.Debug().Err(err).Str("uuid", r.UUID.String()).Msg("error obtaining snip from uuid")
+					os.Exit(exitCode(err))
+				}
+				id := snip.ShortenUUID(s.UUID)[0]
+				if *reviewCmdListLong {
+					id = s.UUID.String()
+				}
+				fmt.Printf("%s %s %s\n", id, r.Due.Local().Format("2006-01-02 15:04"), s.Name)
+			}
+
+		case "rm":
+			if len(reviewCmd.Args()) < 2 {
+				fmt.Fprintf(os.Stderr, "Must supply at least one snip uuid to stop reviewing.\n")
+				os.Exit(exitInvalid)
+			}
+			for _, idStr := range reviewCmd.Args()[1:] {
+				id, err := snip.ResolveUUID(idStr)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The snip with id %s could not be found.\n", idStr)
+					log.Debug().Err(err).Str("uuid", idStr).Msg("error resolving uuid")
+					os.Exit(exitCode(err))
+				}
+				if err = snip.RemoveReview(id); err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem removing snip %s from review.\n", id)
+					log.Debug().Err(err).Str("uuid", id.String()).Msg("error removing review")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("removed %s from review\n", id)
+			}
+
+		default:
+			fmt.Fprintf(os.Stderr, "The review action %s is not supported.\n", reviewCmd.Args()[0])
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+	case "rename":
+		if err := parseInterspersed(renameCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The rename arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing rename arguments")
+			renameCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		// require one argument
+		if len(renameCmd.Args()) != 2 {
+			fmt.Fprintf(os.Stderr, "The rename command requires two arguments.\n")
+			log.Debug().Err(err).Msg("error parsing rename arguments")
+			os.Exit(exitInvalid)
+		}
+
+		idStr := renameCmd.Args()[0]
+		newName := renameCmd.Args()[1]
+		// no empty strings allowed
+		if newName == "" {
+			fmt.Fprintf(os.Stderr, "The new name cannot be an empty string.\n")
+			log.Debug().Err(err).Msg("no empty string allowed for renaming")
+			os.Exit(exitInvalid)
+		}
+		s, err := getSnip(idStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not retrieve snip with id: %s\n", idStr)
+			log.Debug().Err(err).Str("uuid", idStr).Msg("retrieving snip from uuid")
+			os.Exit(exitCode(err))
+		}
+		locked, err := snip.IsLocked(s.UUID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem checking the lock of snip %s\n", s.UUID)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error checking lock")
+			os.Exit(exitCode(err))
+		}
+		if locked && !*renameCmdForce {
+			fmt.Fprintf(os.Stderr, "The snip %s is locked, use -force to rename it anyway.\n", s.UUID)
+			os.Exit(exitFailure)
+		}
+		if locked && !confirmAction(fmt.Sprintf("RENAME LOCKED snip %s", s.UUID), *renameCmdYes) {
+			fmt.Println("skipped")
+			break
+		}
+		oldName := s.Name
+		s.Name = newName
+		if locked {
+			// the lock is lifted only for the duration of the update
+			err = database.Conn.WithTx(func() error {
+				if err := snip.Unlock(s.UUID); err != nil {
+					return err
+				}
+				if err := s.Update(); err != nil {
+					return err
+				}
+				return snip.Lock(s.UUID)
+			})
+		} else {
+			err = s.Update()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem updating snip with id %s\n", idStr)
+			log.Debug().Err(err).Msg("could not update snip")
+			os.Exit(exitCode(err))
+		}
+		fmt.Printf("renamed %s %s -> %s\n", s.UUID.String(), oldName, newName)
+
+		err = snip.RunHook(snip.HookPostEdit, conf.Hooks[snip.HookPostEdit], s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The post-edit hook failed: %v\n", err)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running post-edit hook")
+			os.Exit(exitCode(err))
+		}
+
+	case "rm":
+		if err := parseInterspersed(rmCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The rm arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing rm arguments")
+			rmCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		// the first failure decides the exit status once the others have been removed
+		var rmFailed error
+		for idx, arg := range rmCmd.Args() {
+			// parse to uuid because it seems proper
+			s, err := getSnip(arg)
+			// id, err := uuid.Parse(arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Could not locate id %d/%d %s\n", idx+1, len(rmCmd.Args()), arg)
+				log.Debug().Str("uuid", arg).Err(err).Msg("error parsing uuid input")
+				// Do not exit as others may be valid.
+				if rmFailed == nil {
+					rmFailed = err
+				}
+				continue
+			}
+			locked, err := snip.IsLocked(s.UUID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem checking the lock of snip %s\n", s.UUID)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error checking lock")
+				if rmFailed == nil {
+					rmFailed = err
+				}
+				continue
+			}
+			if locked && !*rmCmdForce {
+				fmt.Fprintf(os.Stderr, "The snip %s is locked, use -force to remove it anyway.\n", s.UUID)
+				continue
+			}
+			prompt := "REMOVE snip %s"
+			if locked {
+				prompt = "REMOVE LOCKED snip %s"
+			}
+			if !confirmAction(fmt.Sprintf(prompt, s.UUID), *rmCmdYes) {
+				fmt.Println("skipped")
+				continue
+			}
+			// a failing pre-rm hook vetoes removal
+			err = snip.RunHook(snip.HookPreRm, conf.Hooks[snip.HookPreRm], s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The pre-rm hook failed, skipping %s: %v\n", s.UUID, err)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running pre-rm hook")
+				if rmFailed == nil {
+					rmFailed = err
+				}
+				continue
+			}
+			if locked {
+				err = database.Conn.WithTx(func() error {
+					if err := snip.Unlock(s.UUID); err != nil {
+						return err
+					}
+					return snip.Remove(s.UUID)
+				})
+				err = errors.Join(err, snip.DeleteUnusedBlobs())
+			} else {
+				err = snip.Remove(s.UUID)
+			}
+			if err != nil {
+				fmt.Printf("Could not remove %d/%d %s\n", idx+1, len(rmCmd.Args()), s.UUID)
+				log.Debug().Str("uuid", s.UUID.String()).Err(err).Msg("error while attempting to delete snip")
+				if rmFailed == nil {
+					rmFailed = err
+				}
+			} else {
+				// must else because we don't break
+				fmt.Printf("removed %d/%d %s\n", idx+1, len(rmCmd.Args()), s.UUID)
+			}
+		}
+		if rmFailed != nil {
+			os.Exit(exitCode(rmFailed))
+		}
+
+	case "search":
+		if err := searchCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The search arguments could not be parsed.\n")
+			log.Debug().Err(err).Str("args", strings.Join(searchCmd.Args(), " ")).Msg("error parsing search arguments")
+			searchCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if err := setColorMode(colorMode); err != nil {
+			fmt.Fprintf(os.Stderr, "The %v\n", err)
+			os.Exit(exitInvalid)
+		}
+		if len(searchCmd.Args()) < 1 {
+			fmt.Fprintf(os.Stderr, "Must supply at least one search term.\n")
+			searchCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if *searchCmdJSON {
+			*searchCmdFormat = "json"
+		}
+		if *searchCmdPorcelain {
+			*searchCmdFormat = "porcelain"
+		}
+		if *searchCmdFormat != "text" && *searchCmdFormat != "alfred" && *searchCmdFormat != "json" && *searchCmdFormat != "porcelain" {
+			fmt.Fprintf(os.Stderr, "The search format %s is not supported.\n", *searchCmdFormat)
+			searchCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if *searchCmdSubstring {
+			*searchCmdType = "substring"
+		}
+		if *searchCmdExplain && *searchCmdType != "index" {
+			fmt.Fprintf(os.Stderr, "Only index searches can be explained.\n")
+			searchCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if *searchCmdCounts && (*searchCmdType != "index" || *searchCmdFormat != "text") {
+			fmt.Fprintf(os.Stderr, "Term counts are only printed for index searches in text format.\n")
+			searchCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if (*searchCmdWithin != "" || *searchCmdStatus != "") && (*searchCmdType != "index" || *searchCmdExplain) {
+			fmt.Fprintf(os.Stderr, "Only index searches that are not explained can be narrowed with -within or -status.\n")
+			searchCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		var searchStatus snip.Status
+		if *searchCmdStatus != "" {
+			searchStatus, err = snip.ParseStatus(*searchCmdStatus)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The %s\n", err)
+				os.Exit(exitInvalid)
+			}
+		}
+		if *searchCmdTimeout != 0 && (*searchCmdType != "index" || *searchCmdExplain || *searchCmdTimeout < 0) {
+			fmt.Fprintf(os.Stderr, "Only index searches that are not explained can be given a positive -timeout.\n")
+			searchCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+
+		var snipResults []snip.Snip
+
+		switch *searchCmdType {
+		case "substring":
+			term := strings.Join(searchCmd.Args(), " ")
+			snipResults, err = snip.SearchSubstring(term, *searchCmdLimit)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem searching for substring %s\n", term)
+				log.Debug().Err(err).Msg("error while searching for substring")
+				os.Exit(exitCode(err))
+			}
+			if len(snipResults) <= 0 {
+				fmt.Fprintf(os.Stderr, "No results for substring \"%s\"\n", term)
+				os.Exit(0)
+			}
+			for _, s := range snipResults {
+				id := snip.ShortenUUID(s.UUID)[0]
+				if *searchCmdLongUUID {
+					id = s.UUID.String()
+				}
+				fmt.Printf("%s %s\n", id, s.Name)
+			}
+
+		case "index":
+			terms := searchCmd.Args()
+
+			var matches []snip.SearchMatch
+			var explanation snip.SearchExplanation
+			switch {
+			case *searchCmdExplain:
+				// the daemon does not report how it searched, so explained searches always use the database directly
+				explanation, err = snip.ExplainSearch(terms, *searchCmdLimit, *searchCmdContextWords)
+				matches = explanation.Matches
+			case *searchCmdWithin != "" || *searchCmdStatus != "" || *searchCmdTimeout > 0:
+				// the daemon searches every snip without a deadline, so narrowed and timed searches always use the database directly
+				ctx := context.Background()
+				if *searchCmdTimeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, *searchCmdTimeout)
+					defer cancel()
+				}
+				if *searchCmdWithin == "" && *searchCmdStatus == "" {
+					matches, err = snip.SearchContext(ctx, terms, *searchCmdLimit, *searchCmdContextWords)
+					break
+				}
+				var ids []uuid.UUID
+				if *searchCmdWithin != "" {
+					ids, err = withinIDs(*searchCmdWithin, os.Stdin)
+				}
+				if err == nil && *searchCmdStatus != "" {
+					ids, err = narrowToStatus(ids, *searchCmdWithin != "", searchStatus)
+				}
+				if err == nil {
+					matches, err = snip.SearchWithin(ctx, terms, ids, *searchCmdLimit, *searchCmdContextWords)
+				}
+			case daemon != nil:
+				matches, err = daemon.Search(terms, *searchCmdLimit, *searchCmdContextWords)
+			default:
+				matches, err = snip.SearchWithContext(terms, *searchCmdLimit, *searchCmdContextWords)
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				fmt.Fprintf(os.Stderr, "The search for %s did not finish within %s.\n", terms, *searchCmdTimeout)
+				os.Exit(exitFailure)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem searching the index for term %s\n", terms)
+				log.Debug().Err(err).Msg("error while searching for term")
+				os.Exit(exitCode(err))
+			}
+			if *searchCmdExplain {
+				writeExplanation(os.Stderr, explanation)
+				fmt.Fprintln(os.Stderr)
+			}
+
+			if *searchCmdFormat == "porcelain" {
+				for _, match := range matches {
+					writePorcelain(os.Stdout, match.UUID.String(), strconv.FormatFloat(match.Score, 'f', 6, 64), match.Name)
+				}
+				break
+			}
+			if *searchCmdFormat == "json" {
+				if matches == nil {
+					matches = []snip.SearchMatch{}
+				}
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err = encoder.Encode(matches); err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem encoding the search results as json.\n")
+					log.Debug().Err(err).Msg("error encoding search results")
+					os.Exit(exitCode(err))
+				}
+				break
+			}
+			if *searchCmdFormat == "alfred" {
+				get := snip.GetFromUUID
+				if daemon != nil {
+					get = daemon.Get
+				}
+				err = writeAlfred(os.Stdout, matches, get)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem writing the alfred results.\n")
+					log.Debug().Err(err).Msg("error writing alfred script filter output")
+					os.Exit(exitCode(err))
+				}
+				break
+			}
+
+			for _, match := range matches {
+				colors.Name.Printf("%s", match.Name)
+				fmt.Printf("\n")
+				if *searchCmdLongUUID {
+					fmt.Printf("  %s ", colors.ID.Sprint(match.UUID))
+				} else {
+					fmt.Printf("  %s ", colors.ID.Sprint(snip.ShortenUUID(match.UUID)[0]))
+				}
+				fmt.Printf("(score: %f, ", match.Score)
+				fmt.Printf("words: %d)", match.Words)
+
+				// display terms found in document
+				for idx, stat := range match.SearchCounts {
+					if idx == 0 {
+						fmt.Printf(" [")
 					} else {
 						fmt.Printf(", ")
 					}
 					fmt.Printf("%s: %d", stat.Stem, stat.Count)
-					if idx == len(score.SearchCounts)-1 {
+					if idx == len(match.SearchCounts)-1 {
 						fmt.Printf("]")
-						fmt.Printf("\n")
 					}
 				}
+				// and those found in its name and tags
+				if len(match.Fields.Name) > 0 {
+					fmt.Printf(" [name: %s]", strings.Join(match.Fields.Name, ", "))
+				}
+				if len(match.Fields.Tags) > 0 {
+					fmt.Printf(" [tags: %s]", strings.Join(match.Fields.Tags, ", "))
+				}
+				fmt.Printf("\n")
+				if *searchCmdCounts {
+					fmt.Printf("    %s\n", formatTermCounts(match.TermCounts(terms)))
+				}
+
+				// print each context
+				for _, ctx := range match.Context {
+					// these will be printed if not empty
+					var before string
+					var after string
+
+					// print indexes for begin and end of context (to give more context)
+					fmt.Printf("    [%d-%d] ", ctx.BeforeStart, ctx.AfterEnd)
+					before = strings.Join(ctx.Before, " ")
+					after = strings.Join(ctx.After, " ")
+
+					// if we don't check for empty line, it will produce padding
+					fmt.Printf(`"`) // quotes separate from before string output
+					if before != "" {
+						fmt.Printf("%s ", before)
+					}
+					_, err = colors.Match.Printf("%s", ctx.Term)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Color output could not be displayed.\n")
+						log.Debug().Err(err).Msg("color print of context term")
+						os.Exit(exitCode(err))
+					}
+					if after != "" {
+						fmt.Printf(" %s", after)
+					}
+					fmt.Printf(`"`) // quotes separate from after string output
+					fmt.Printf("\n")
+				}
+				fmt.Printf("\n")
+			}
+
+			if len(matches) <= 0 {
+				fmt.Fprintf(os.Stderr, "No results for term \"%s\"\n", terms)
+				os.Exit(0)
+			}
+
+		case "data":
+			term := searchCmd.Args()[0]
+
+			fmt.Fprintf(os.Stderr, "Search type %s on field %s for: \"%s\"\n", *searchCmdType, *searchCmdField, term)
+			log.Debug().Str("field", *searchCmdField)
+
+			switch *searchCmdField {
+			case "data":
+				snipResults, err = snip.SearchDataTerm(term)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem searching %s field for term %s\n", *searchCmdField, term)
+					log.Debug().Err(err).Msg("error while searching for term")
+					os.Exit(exitCode(err))
+				}
+
+			case "uuid":
+				snipResults, err = snip.SearchUUID(term)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem searching %s field for term %s\n", *searchCmdField, term)
+					log.Debug().Err(err).Msg("error while searching for term")
+					os.Exit(exitCode(err))
+				}
+			}
+
+			if len(snipResults) <= 0 {
+				fmt.Fprintf(os.Stderr, "No results for term \"%s\"\n", term)
+				os.Exit(0)
+			}
+			fmt.Fprintf(os.Stderr, "%s %36s\n", "uuid", "name")
+			for _, s := range snipResults {
+				fmt.Printf("%s %s\n", s.UUID.String(), s.Name)
+			}
+		}
+
+	case "serve":
+		if err := serveCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The serve arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing serve arguments")
+			serveCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		maxUpload, err := parseSize(*serveCmdMaxUpload)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The maximum upload size %s is not valid: %v\n", *serveCmdMaxUpload, err)
+			os.Exit(exitInvalid)
+		}
+		limits := server.Limits{RequestsPerMinute: *serveCmdRateLimit, MaxUpload: maxUpload}
+		proxy := server.Proxy{BasePath: *serveCmdBasePath, TrustForwarded: *serveCmdTrustProxy, Origins: serveCmdCORSOrigins}
+		err = runServe(*serveCmdAddr, *serveCmdGRPCAddr, *serveCmdToken, limits, proxy, serveCmdPublicTags, conf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem serving on %s: %v\n", *serveCmdAddr, err)
+			log.Debug().Err(err).Str("addr", *serveCmdAddr).Msg("error serving")
+			os.Exit(exitCode(err))
+		}
+
+	case "share":
+		if err := shareCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The share arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing share arguments")
+			shareCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(shareCmd.Args()) < 1 {
+			fmt.Fprintf(os.Stderr, "Must supply a snip uuid, ls, or revoke.\n")
+			shareCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch shareCmd.Args()[0] {
+		case "ls":
+			shares, err := snip.ListShares()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem listing the shares.\n")
+				log.Debug().Err(err).Msg("error listing shares")
+				os.Exit(exitCode(err))
+			}
+			for idx, sh := range shares {
+				if idx == 0 {
+					fmt.Fprintf(os.Stderr, "%-24s %-8s %-16s %s\n", "token", "uuid", "expires", "name")
+				}
+				name := ""
+				if s, err := snip.GetSnipMeta(sh.UUID.String()); err == nil {
+					name = s.Name
+				}
+				fmt.Printf("%s %s %s %s\n", sh.Token, snip.ShortenUUID(sh.UUID)[0], sh.Expires.Format("2006-01-02 15:04"), name)
+			}
+
+		case "revoke":
+			if len(shareCmd.Args()) < 2 {
+				fmt.Fprintf(os.Stderr, "Must supply at least one share token.\n")
+				shareCmd.Usage()
+				os.Exit(exitInvalid)
+			}
+			for _, token := range shareCmd.Args()[1:] {
+				err = snip.RevokeShare(token)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The share %s could not be revoked.\n", token)
+					log.Debug().Err(err).Str("token", token).Msg("error revoking share")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("revoked %s\n", token)
+			}
+
+		default:
+			idStr := shareCmd.Args()[0]
+			s, err := getSnip(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+				os.Exit(exitCode(err))
+			}
+			sh, err := snip.CreateShare(s.UUID, *shareCmdTTL)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem creating the share.\n")
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error creating share")
+				os.Exit(exitCode(err))
+			}
+			link := strings.TrimSuffix(*shareCmdURL, "/") + "/share/" + sh.Token
+			if *shareCmdQR {
+				err = writeQR(os.Stdout, link)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The link could not be displayed as a qr code.\n")
+					log.Debug().Err(err).Msg("error rendering qr code")
+					os.Exit(exitCode(err))
+				}
+			}
+			fmt.Printf("%s\n", link)
+			fmt.Fprintf(os.Stderr, "shared %s until %s, while snip serve is running\n", s.Name, sh.Expires.Format("2006-01-02 15:04"))
+		}
+
+	case "snapshot":
+		if err := snapshotCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The snapshot arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing snapshot arguments")
+			snapshotCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(snapshotCmd.Args()) < 1 {
+			fmt.Fprintf(os.Stderr, "Must supply a snapshot action (create|ls|rollback|rm)\n")
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch snapshotCmd.Args()[0] {
+		case "create":
+			if len(snapshotCmd.Args()) != 2 {
+				fmt.Fprintf(os.Stderr, "Creating a snapshot requires a name.\n")
+				os.Exit(exitInvalid)
+			}
+			name := snapshotCmd.Args()[1]
+			if err := snip.ValidateSnapshotName(name); err != nil {
+				fmt.Fprintf(os.Stderr, "The %v\n", err)
+				os.Exit(exitInvalid)
+			}
+			snapshot, err := snip.CreateSnapshot(snapshotDir, name, time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snapshot %s could not be created: %v\n", name, err)
+				log.Debug().Err(err).Str("snapshot", name).Msg("error creating snapshot")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("created snapshot %s of %d snips\n", snapshot.Name, snapshot.Snips)
+		case "ls":
+			if len(snapshotCmd.Args()) != 1 {
+				fmt.Fprintf(os.Stderr, "The snapshot ls action accepts no arguments.\n")
+				os.Exit(exitInvalid)
+			}
+			snapshots, err := snip.ListSnapshots(snapshotDir)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem listing the snapshots.\n")
+				log.Debug().Err(err).Str("dir", snapshotDir).Msg("error listing snapshots")
+				os.Exit(exitCode(err))
+			}
+			var rows [][]string
+			for _, snapshot := range snapshots {
+				rows = append(rows, []string{snapshot.Name, snapshot.Created.Format("2006-01-02 15:04"), strconv.Itoa(snapshot.Snips), strconv.FormatInt(snapshot.Size, 10)})
+			}
+			if len(rows) > 0 {
+				writeTable(os.Stdout, os.Stderr, []string{"name", "created", "snips", "bytes"}, []tableSection{{Rows: rows}}, nil)
+			}
+		case "rollback":
+			if err := parseInterspersed(snapshotCmdRollback, snapshotCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The snapshot rollback arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing snapshot rollback arguments")
+				os.Exit(exitInvalid)
+			}
+			if snapshotCmdRollback.NArg() != 1 {
+				fmt.Fprintf(os.Stderr, "Rolling back requires the name of a snapshot.\n")
+				os.Exit(exitInvalid)
+			}
+			name := snapshotCmdRollback.Arg(0)
+			snapshot, err := snip.GetSnapshot(snapshotDir, name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snapshot %s could not be retrieved: %v\n", name, err)
+				log.Debug().Err(err).Str("snapshot", name).Msg("error retrieving snapshot")
+				os.Exit(exitCode(err))
+			}
+			prompt := fmt.Sprintf("ROLLBACK to snapshot %s of %s, discarding every change since", name, snapshot.Created.Format("2006-01-02 15:04"))
+			if !confirmAction(prompt, *snapshotCmdRollbackYes) {
+				fmt.Println("skipped")
+				break
+			}
+			if _, err = snip.RollbackSnapshot(snapshotDir, name); err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem rolling back to snapshot %s\n", name)
+				log.Debug().Err(err).Str("snapshot", name).Msg("error rolling back to snapshot")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("rolled back to snapshot %s of %d snips\n", name, snapshot.Snips)
+		case "rm":
+			for _, name := range snapshotCmd.Args()[1:] {
+				if err := snip.RemoveSnapshot(snapshotDir, name); err != nil {
+					fmt.Fprintf(os.Stderr, "The snapshot %s could not be removed: %v\n", name, err)
+					log.Debug().Err(err).Str("snapshot", name).Msg("error removing snapshot")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("removed snapshot %s\n", name)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "The snapshot action %s is not supported.\n", snapshotCmd.Args()[0])
+			os.Exit(exitInvalid)
+		}
+
+	case "sql":
+		if err := parseInterspersed(sqlCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The sql arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing sql arguments")
+			sqlCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(sqlCmd.Args()) != 1 {
+			fmt.Fprintf(os.Stderr, "Must supply a single sql statement, quoted as one argument.\n")
+			sqlCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		supported := false
+		for _, format := range queryFormats {
+			supported = supported || format == *sqlCmdFormat
+		}
+		if !supported {
+			fmt.Fprintf(os.Stderr, "The format %s is not supported (%s)\n", *sqlCmdFormat, strings.Join(queryFormats, "|"))
+			os.Exit(exitInvalid)
+		}
+
+		result, err := snip.Query(sqlCmd.Args()[0], *sqlCmdWrite)
+		if errors.Is(err, snip.ErrWrite) {
+			fmt.Fprintf(os.Stderr, "The statement would change the database, use -write to allow it.\n")
+			os.Exit(exitInvalid)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The statement could not be run: %v\n", err)
+			log.Debug().Err(err).Msg("error running sql statement")
+			os.Exit(exitCode(err))
+		}
+		if err = writeQueryResult(os.Stdout, result, *sqlCmdFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem writing the results.\n")
+			log.Debug().Err(err).Msg("error writing sql results")
+			os.Exit(exitCode(err))
+		}
+		if *sqlCmdWrite && len(result.Columns) == 0 {
+			fmt.Fprintf(os.Stderr, "%d rows changed\n", result.Changes)
+		}
+
+	case "star":
+		if err := parseInterspersed(starCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The star arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing star arguments")
+			starCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(starCmd.Args()) < 1 || len(starCmd.Args()) > 2 {
+			fmt.Fprintf(os.Stderr, "The star command requires a uuid, optionally followed by a rating from 1 to %d.\n", snip.MaxStars)
+			os.Exit(exitInvalid)
+		}
+
+		idStr := starCmd.Args()[0]
+		s, err := getSnip(idStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+			log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+			os.Exit(exitCode(err))
+		}
+		if *starCmdRemove {
+			err = snip.RemoveStars(s.UUID)
+		} else if len(starCmd.Args()) == 2 {
+			stars, convErr := strconv.Atoi(starCmd.Args()[1])
+			if convErr != nil {
+				fmt.Fprintf(os.Stderr, "The rating %s is not a number from 1 to %d.\n", starCmd.Args()[1], snip.MaxStars)
+				os.Exit(exitInvalid)
+			}
+			err = snip.SetStars(s.UUID, stars)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The rating of snip %s could not be changed: %v\n", s.UUID, err)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error changing rating")
+			os.Exit(exitCode(err))
+		}
+		stars, err := snip.GetStars(s.UUID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The rating of snip %s could not be retrieved.\n", s.UUID)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error retrieving rating")
+			os.Exit(exitCode(err))
+		}
+		fmt.Printf("%s %-5s %s\n", s.UUID, strings.Repeat("*", stars), s.Name)
+
+	case "stats":
+		if err := statsCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The stats arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing stats arguments")
+			statsCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(statsCmd.Args()) == 0 {
+			fmt.Fprintf(os.Stderr, "Must supply a stats action (activity)\n")
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch statsCmd.Args()[0] {
+		case "activity":
+			now := time.Now()
+			times, err := snip.Activity(heatmapStart(now))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem obtaining the activity of snips.\n")
+				log.Debug().Err(err).Msg("error obtaining activity")
+				os.Exit(exitCode(err))
+			}
+			writeHeatmap(os.Stdout, times, now)
+		default:
+			fmt.Fprintf(os.Stderr, "The stats action %s is not supported.\n", statsCmd.Args()[0])
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+	case "status":
+		if err := statusCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The status arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing status arguments")
+			statusCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if statusCmd.NArg() == 0 {
+			fmt.Fprintf(os.Stderr, "Must supply a status action (set|ls)\n")
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+		switch statusCmd.Arg(0) {
+		case "set":
+			if statusCmd.NArg() < 3 {
+				fmt.Fprintf(os.Stderr, "Must supply a status and at least one snip uuid.\n")
+				os.Exit(exitInvalid)
+			}
+			status, err := snip.ParseStatus(statusCmd.Arg(1))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The %s\n", err)
+				os.Exit(exitInvalid)
+			}
+			for _, idStr := range statusCmd.Args()[2:] {
+				s, err := getSnip(idStr)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem locating the snip %s\n", idStr)
+					log.Debug().Err(err).Str("id", idStr).Msg("could not get snip")
+					os.Exit(exitCode(err))
+				}
+				if err = snip.SetStatus(s.UUID, status); err != nil {
+					fmt.Fprintf(os.Stderr, "There was a problem setting the status of %s\n", s.UUID)
+					log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error setting status")
+					os.Exit(exitCode(err))
+				}
+				fmt.Printf("%s %s %s\n", status, s.UUID, s.Name)
+			}
 
-				// show context
-				s, err = snip.GetFromUUID(score.UUID.String())
+		case "ls":
+			if err := parseInterspersed(statusCmdList, statusCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The status ls arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing status ls arguments")
+				statusCmdList.Usage()
+				os.Exit(exitInvalid)
+			}
+			if statusCmdList.NArg() > 1 {
+				fmt.Fprintf(os.Stderr, "The status ls action takes at most one status.\n")
+				os.Exit(exitInvalid)
+			}
+			var only snip.Status
+			if statusCmdList.NArg() == 1 {
+				only, err = snip.ParseStatus(statusCmdList.Arg(0))
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "There was a problem showing search context for item %s\n", score.UUID)
-					log.Debug().Err(err).Msg("building snip to obtain search context")
-					os.Exit(1)
+					fmt.Fprintf(os.Stderr, "The %s\n", err)
+					os.Exit(exitInvalid)
 				}
-				for _, term := range terms {
-					ctxAll, err := s.GatherContext(term, *searchCmdContextWords)
-					if err != nil {
-						fmt.Fprintf(os.Stderr, "There was a problem gathering context for term %s: %v\n", term, err)
-						log.Debug().Str("term", term).Str("uuid", score.UUID.String()).Msg("gathering context")
-						log.Debug().Err(err).Msg("gathering context")
-						os.Exit(1)
-					}
-					if len(ctxAll) == 0 {
-						// in this case, there are no results (which is technically not an error)
-						// TODO: perhaps only matching terms should be iterated over instead of supplied terms
-						continue
+			}
+			statuses, err := snip.ListStatuses()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the statuses of snips.\n")
+				log.Debug().Err(err).Msg("error listing statuses")
+				os.Exit(exitCode(err))
+			}
+			snips, err := snip.ListMetadata(0)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem while attempting to obtain the metadata of all snips.\n")
+				log.Debug().Err(err).Msg("error listing items metadata")
+				os.Exit(exitCode(err))
+			}
+			// the board shows tasks alone, while a status given lists only the snips with it, which may be none
+			var tasks []snip.Snip
+			for _, s := range snips {
+				status := statuses[s.UUID]
+				if (statusCmdList.NArg() == 0 && status != snip.StatusNone) || (statusCmdList.NArg() == 1 && status == only) {
+					tasks = append(tasks, s)
+				}
+			}
+			groups, err := groupSnips(tasks, "status", nil, statuses)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The %s\n", err)
+				os.Exit(exitInvalid)
+			}
+			var sections []tableSection
+			for _, g := range groups {
+				section := tableSection{Title: fmt.Sprintf("%s (%d)", g.Title, len(g.Snips))}
+				for _, s := range g.Snips {
+					id := snip.ShortenUUID(s.UUID)[0]
+					if *statusCmdListLong {
+						id = s.UUID.String()
 					}
+					section.Rows = append(section.Rows, []string{id, s.Name})
+				}
+				sections = append(sections, section)
+			}
+			writeStyledTable(os.Stdout, os.Stderr, []string{"uuid", "name"}, sections, nil, map[int]*color.Color{0: colors.ID, 1: colors.Name})
 
-					// log.Debug().Any("ctx", ctxAll).Msg("term context")
+		default:
+			fmt.Fprintf(os.Stderr, "The status action %s is not supported.\n", statusCmd.Arg(0))
+			Usage()
+			os.Exit(exitInvalid)
+		}
 
-					// print each context
-					for _, ctx := range ctxAll {
-						// these will be printed if not empty
-						var before string
-						var after string
+	case "stdio":
+		if err := stdioCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The stdio arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing stdio arguments")
+			stdioCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		err = runStdio(os.Stdin, os.Stdout, snip.LocalStore{}, conf.Hooks, nameStrategy)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem reading requests from standard input.\n")
+			log.Debug().Err(err).Msg("error serving stdio session")
+			os.Exit(exitCode(err))
+		}
 
-						// print indexes for begin and end of context (to give more context)
-						fmt.Printf("    [%d-%d] ", ctx.BeforeStart, ctx.AfterEnd)
-						before = strings.Join(ctx.Before, " ")
-						after = strings.Join(ctx.After, " ")
-						// log.Debug().Int("ctx.Before", len(ctx.After)).Msg("join before length")
-						// log.Debug().Int("ctx.After", len(ctx.After)).Msg("join after length")
+	case "summarize":
+		if err := parseInterspersed(summarizeCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The summarize arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing summarize arguments")
+			summarizeCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if summarizeCmd.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "The summarize command requires the uuid of a snip.\n")
+			summarizeCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		provider, err := llm.New(conf.LLM)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The %v, see llm in %s\n", err, config.Path())
+			os.Exit(exitInvalid)
+		}
+		s, err := getSnip(summarizeCmd.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem locating the snip %s\n", summarizeCmd.Arg(0))
+			log.Debug().Err(err).Str("id", summarizeCmd.Arg(0)).Msg("could not get snip")
+			os.Exit(exitCode(err))
+		}
+		writeSending(os.Stderr, provider, []snip.Snip{s})
+		if !confirmAction(fmt.Sprintf("SEND snip %s %s to %s", s.UUID, s.Name, provider), *summarizeCmdYes) {
+			fmt.Println("skipped")
+			break
+		}
+		summary, err := llm.Summarize(context.Background(), provider, s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem summarizing with %s: %v\n", provider, err)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error summarizing snip")
+			os.Exit(exitFailure)
+		}
+		fmt.Println(summary)
+		if *summarizeCmdSave {
+			n, err := snip.AddNote(s.UUID, summary)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem adding the summary as a note of %s\n", s.UUID)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error adding note")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("added note %d to %s %s\n", n.ID, s.UUID, s.Name)
+		}
 
-						// if we don't check for empty line, it will produce padding
-						fmt.Printf(`"`) // quotes separate from before string output
-						if before != "" {
-							fmt.Printf("%s ", before)
-						}
-						c := color.New(color.FgRed)
-						_, err = c.Printf("%s", ctx.Term)
-						if err != nil {
-							fmt.Fprintf(os.Stderr, "Color output could not be displayed.\n")
-							log.Debug().Err(err).Msg("color print of context term")
-							os.Exit(1)
-						}
-						if after != "" {
-							fmt.Printf(" %s", after)
-						}
-						fmt.Printf(`"`) // quotes separate from after string output
-						fmt.Printf("\n")
-					}
-				}
-				fmt.Printf("\n")
+	case "tag":
+		if err := tagCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The tag arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing tag arguments")
+			tagCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+
+		// list all tags in use
+		if len(tagCmd.Args()) == 0 {
+			tags, err := snip.ListTags()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem listing the tags.\n")
+				log.Debug().Err(err).Msg("error listing tags")
+				os.Exit(exitCode(err))
+			}
+			var names []string
+			for tag := range tags {
+				names = append(names, tag)
 			}
+			sort.Strings(names)
+			for _, tag := range names {
+				fmt.Printf("%5d %s\n", tags[tag], tag)
+			}
+			break
+		}
 
-			if len(searchResults) <= 0 {
-				fmt.Fprintf(os.Stderr, "No results for term \"%s\"\n", terms)
-				os.Exit(0)
+		idStr := tagCmd.Args()[0]
+		s, err := getSnip(idStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+			log.Debug().Err(err).Str("uuid", idStr).Msg("error retrieving snip with uuid")
+			os.Exit(exitCode(err))
+		}
+		for _, tag := range tagCmd.Args()[1:] {
+			if *tagCmdRemove {
+				err = s.RemoveTag(tag)
+			} else {
+				err = s.AddTag(tag)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The tag %s could not be changed: %v\n", tag, err)
+				log.Debug().Err(err).Str("tag", tag).Msg("error changing tag")
+				os.Exit(exitCode(err))
 			}
+		}
+		fmt.Printf("%s\n", strings.Join(s.Tags, " "))
 
-		case "data":
-			term := searchCmd.Args()[0]
+	case "translate":
+		if err := parseInterspersed(translateCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The translate arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing translate arguments")
+			translateCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if translateCmd.NArg() != 1 || *translateCmdTo == "" {
+			fmt.Fprintf(os.Stderr, "The translate command requires the uuid of a snip and a language with -to.\n")
+			translateCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		lang, err := snip.NormalizeLanguageCode(*translateCmdTo)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The language %v.\n", err)
+			os.Exit(exitInvalid)
+		}
+		provider, err := llm.New(conf.LLM)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The %v, see llm in %s\n", err, config.Path())
+			os.Exit(exitInvalid)
+		}
+		s, err := getSnip(translateCmd.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem locating the snip %s\n", translateCmd.Arg(0))
+			log.Debug().Err(err).Str("id", translateCmd.Arg(0)).Msg("could not get snip")
+			os.Exit(exitCode(err))
+		}
+		// translating again replaces the earlier translation, such as after the snip is edited
+		existing, err := snip.FindTranslation(s.UUID, lang)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem looking for an earlier translation of %s\n", s.UUID)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error finding translation")
+			os.Exit(exitCode(err))
+		}
+		writeSending(os.Stderr, provider, []snip.Snip{s})
+		if !confirmAction(fmt.Sprintf("SEND snip %s %s to %s to translate into %s", s.UUID, s.Name, provider, lang), *translateCmdYes) {
+			fmt.Println("skipped")
+			break
+		}
+		text, err := llm.Translate(context.Background(), provider, s, lang)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem translating with %s: %v\n", provider, err)
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error translating snip")
+			os.Exit(exitFailure)
+		}
+		if existing != uuid.Nil {
+			t, err := snip.GetFromUUID(existing.String())
+			if err == nil {
+				t.Data = text + "\n"
+				err = t.Update()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem replacing the translation %s\n", existing)
+				log.Debug().Err(err).Str("uuid", existing.String()).Msg("error updating translation")
+				os.Exit(exitCode(err))
+			}
+			if err = snip.Reindex(t.UUID); err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem indexing snip %s\n", t.UUID)
+				log.Debug().Err(err).Str("uuid", t.UUID.String()).Msg("error indexing snip")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("updated translation %s %s\n", t.UUID, t.Name)
+			err = snip.RunHook(snip.HookPostEdit, conf.Hooks[snip.HookPostEdit], t)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The post-edit hook failed: %v\n", err)
+				log.Debug().Err(err).Str("uuid", t.UUID.String()).Msg("error running post-edit hook")
+				os.Exit(exitCode(err))
+			}
+			break
+		}
+		t := snip.NewTranslation(s, lang, text+"\n")
+		if err = (snip.LocalStore{}).Insert(t); err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem inserting the new snip into the database.\n")
+			log.Debug().Err(err).Msg("error inserting Snip into database")
+			os.Exit(exitCode(err))
+		}
+		fmt.Printf("added snip uuid: %s\n", t.UUID)
+		err = snip.RunHook(snip.HookPostAdd, conf.Hooks[snip.HookPostAdd], t)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The post-add hook failed: %v\n", err)
+			log.Debug().Err(err).Str("uuid", t.UUID.String()).Msg("error running post-add hook")
+			os.Exit(exitCode(err))
+		}
 
-			fmt.Fprintf(os.Stderr, "Search type %s on field %s for: \"%s\"\n", *searchCmdType, *searchCmdField, term)
-			log.Debug().Str("field", *searchCmdField)
+	case "track":
+		if err := trackCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The track arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing track arguments")
+			trackCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if trackCmd.NArg() == 0 {
+			fmt.Fprintf(os.Stderr, "The track command requires an action of start, stop, or report.\n")
+			os.Exit(exitInvalid)
+		}
+		now := time.Now()
 
-			switch *searchCmdField {
-			case "data":
-				snipResults, err = snip.SearchDataTerm(term)
+		switch trackCmd.Arg(0) {
+		case "start":
+			if trackCmd.NArg() != 2 {
+				fmt.Fprintf(os.Stderr, "Must supply the uuid of one snip to track.\n")
+				os.Exit(exitInvalid)
+			}
+			s, err := getSnip(trackCmd.Arg(1))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem locating the snip %s\n", trackCmd.Arg(1))
+				log.Debug().Err(err).Str("id", trackCmd.Arg(1)).Msg("could not get snip")
+				os.Exit(exitCode(err))
+			}
+			stopped, err := snip.StartTracking(s.UUID, now)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem starting to track time on %s\n", s.UUID)
+				log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error starting tracking")
+				os.Exit(exitCode(err))
+			}
+			for _, i := range stopped {
+				fmt.Printf("stopped %s after %s\n", i.UUID, i.Duration(now).Round(time.Minute))
+			}
+			fmt.Printf("tracking %s %s\n", s.UUID, s.Name)
+
+		case "stop":
+			if trackCmd.NArg() > 2 {
+				fmt.Fprintf(os.Stderr, "The track stop action takes at most one snip uuid.\n")
+				os.Exit(exitInvalid)
+			}
+			id := uuid.Nil
+			if trackCmd.NArg() == 2 {
+				s, err := getSnip(trackCmd.Arg(1))
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "There was a problem searching %s field for term %s\n", *searchCmdField, term)
-					log.Debug().Err(err).Msg("error while searching for term")
-					os.Exit(1)
+					fmt.Fprintf(os.Stderr, "There was a problem locating the snip %s\n", trackCmd.Arg(1))
+					log.Debug().Err(err).Str("id", trackCmd.Arg(1)).Msg("could not get snip")
+					os.Exit(exitCode(err))
 				}
+				id = s.UUID
+			}
+			i, err := snip.StopTracking(id, now)
+			if errors.Is(err, snip.ErrNotTracking) {
+				fmt.Fprintf(os.Stderr, "The %v.\n", err)
+				os.Exit(exitFailure)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem stopping the time tracked.\n")
+				log.Debug().Err(err).Msg("error stopping tracking")
+				os.Exit(exitCode(err))
+			}
+			fmt.Printf("stopped %s after %s\n", i.UUID, i.Duration(now).Round(time.Minute))
 
-			case "uuid":
-				snipResults, err = snip.SearchUUID(term)
+		case "report":
+			if err := parseInterspersed(trackCmdReport, trackCmd.Args()[1:]); err != nil {
+				exitOnHelp(err)
+				fmt.Fprintf(os.Stderr, "The track report arguments could not be parsed.\n")
+				log.Debug().Err(err).Msg("error parsing track report arguments")
+				trackCmdReport.Usage()
+				os.Exit(exitInvalid)
+			}
+			var from time.Time
+			if *trackCmdReportWeek {
+				from = weekStart(now)
+			}
+			tracked, ids, err := snip.TrackedTime(from, now, now)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem totaling the time tracked.\n")
+				log.Debug().Err(err).Msg("error totaling tracked time")
+				os.Exit(exitCode(err))
+			}
+			running, tracking, err := snip.RunningInterval()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem finding the time being tracked.\n")
+				log.Debug().Err(err).Msg("error finding running interval")
+				os.Exit(exitCode(err))
+			}
+			names := make(map[uuid.UUID]string)
+			lookup := ids
+			if tracking {
+				lookup = append([]uuid.UUID{running.UUID}, ids...)
+			}
+			for _, id := range lookup {
+				s, err := snip.GetSnipMeta(id.String())
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "There was a problem searching %s field for term %s\n", *searchCmdField, term)
-					log.Debug().Err(err).Msg("error while searching for term")
-					os.Exit(1)
+					fmt.Fprintf(os.Stderr, "The snip with uuid: %s could not be obtained from the database.\n", id)
+					log.Debug().Err(err).Str("uuid", id.String()).Msg("error obtaining snip from uuid")
+					os.Exit(exitCode(err))
+				}
+				names[id] = s.Name
+			}
+			tags, err := snip.ListSnipTags()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem listing the tags of the snips.\n")
+				log.Debug().Err(err).Msg("error listing snip tags")
+				os.Exit(exitCode(err))
+			}
+			if len(ids) == 0 {
+				fmt.Println("no time was tracked")
+			} else {
+				writeTrackReport(os.Stdout, tracked, ids, names, tags, *trackCmdReportLong)
+			}
+			if tracking {
+				short := snip.ShortenUUID(running.UUID)[0]
+				if *trackCmdReportLong {
+					short = running.UUID.String()
 				}
+				fmt.Printf("\ntracking %s %s for %s\n", short, names[running.UUID], running.Duration(now).Round(time.Minute))
 			}
 
-			if len(snipResults) <= 0 {
-				fmt.Fprintf(os.Stderr, "No results for term \"%s\"\n", term)
-				os.Exit(0)
+		default:
+			fmt.Fprintf(os.Stderr, "The track action %s is not supported.\n", trackCmd.Arg(0))
+			Usage()
+			os.Exit(exitInvalid)
+		}
+
+	case "urls":
+		if err := urlsCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The urls arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing urls arguments")
+			urlsCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(urlsCmd.Args()) > 1 {
+			fmt.Fprintf(os.Stderr, "The urls command accepts at most one snip uuid.\n")
+			os.Exit(exitInvalid)
+		}
+
+		var snips []snip.Snip
+		if len(urlsCmd.Args()) == 1 {
+			s, err := getSnip(urlsCmd.Arg(0))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", urlsCmd.Arg(0))
+				log.Debug().Err(err).Str("uuid", urlsCmd.Arg(0)).Msg("error retrieving snip with uuid")
+				os.Exit(exitCode(err))
 			}
-			fmt.Fprintf(os.Stderr, "%s %36s\n", "uuid", "name")
-			for _, s := range snipResults {
-				fmt.Printf("%s %s\n", s.UUID.String(), s.Name)
+			snips = append(snips, s)
+		} else {
+			snips, err = snip.List(0)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem building the list of all snips in the database.\n")
+				log.Debug().Err(err).Msg("error retrieving all snips")
+				os.Exit(exitCode(err))
 			}
 		}
 
-	case "index":
-		// rebuild index
-		fmt.Fprintf(os.Stderr, "dropping index...")
-		err := snip.DropIndex()
+		// gather urls along with the snip that contains them
+		type snipURL struct {
+			id  uuid.UUID
+			url string
+		}
+		var found []snipURL
+		for _, s := range snips {
+			for _, u := range snip.ExtractURLs(s.Data) {
+				found = append(found, snipURL{id: s.UUID, url: u})
+			}
+		}
+
+		idString := func(id uuid.UUID) string {
+			if *urlsCmdLongUUID {
+				return id.String()
+			}
+			return snip.ShortenUUID(id)[0]
+		}
+
+		if !*urlsCmdCheck {
+			for _, f := range found {
+				fmt.Printf("%s %s\n", idString(f.id), f.url)
+			}
+			break
+		}
+
+		var urls []string
+		for _, f := range found {
+			urls = append(urls, f.url)
+		}
+		fmt.Fprintf(os.Stderr, "checking %d urls...\n", len(urls))
+		results := snip.CheckURLs(urls, 8)
+		dead := 0
+		for idx, result := range results {
+			if !result.Dead() {
+				continue
+			}
+			dead++
+			status := strconv.Itoa(result.StatusCode)
+			if result.Err != nil {
+				status = "error"
+				log.Debug().Err(result.Err).Str("url", result.URL).Msg("error checking url")
+			}
+			fmt.Printf("%s %s %s\n", idString(found[idx].id), status, result.URL)
+		}
+		fmt.Fprintf(os.Stderr, "%d/%d urls dead\n", dead, len(results))
+
+	case "verify":
+		if err := parseInterspersed(verifyCmd, os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The verify arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing verify arguments")
+			verifyCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if *verifyCmdAll == (len(verifyCmd.Args()) > 0) {
+			fmt.Fprintf(os.Stderr, "Specify the uuids of snips to verify, or -all.\n")
+			os.Exit(exitInvalid)
+		}
+
+		var ids []uuid.UUID
+		for _, idStr := range verifyCmd.Args() {
+			id, err := snip.ResolveUUID(idStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "The snip with id %s could not be retrieved.\n", idStr)
+				log.Debug().Err(err).Str("uuid", idStr).Msg("error resolving snip uuid")
+				os.Exit(exitCode(err))
+			}
+			ids = append(ids, id)
+		}
+		bar := newProgressBar(os.Stderr)
+		v, err := snip.Verify(bar, ids...)
+		bar.Finish()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error")
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "There was a problem verifying data checksums.\n")
+			log.Debug().Err(err).Msg("error verifying checksums")
+			os.Exit(exitCode(err))
+		}
+		writeMismatches(os.Stdout, v.Mismatches)
+		fmt.Printf("verified %d snips and %d attachments, %d mismatches\n", v.Snips, v.Attachments, len(v.Mismatches))
+		if len(v.Mismatches) > 0 {
+			os.Exit(exitFailure)
 		}
-		fmt.Fprintf(os.Stderr, "success\n")
 
-		fmt.Fprintf(os.Stderr, "indexing...")
+	case "versions":
+		if err := versionsCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The versions arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing versions arguments")
+			versionsCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if len(versionsCmd.Args()) != 1 {
+			fmt.Fprintf(os.Stderr, "Must supply a snip uuid.\n")
+			versionsCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		id, err := snip.ResolveUUID(versionsCmd.Args()[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "The snip with id %s could not be found.\n", versionsCmd.Args()[0])
+			log.Debug().Err(err).Str("uuid", versionsCmd.Args()[0]).Msg("error resolving uuid")
+			os.Exit(exitCode(err))
+		}
+		versions, err := snip.GetVersions(id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "There was a problem listing the versions of snip %s.\n", id)
+			log.Debug().Err(err).Str("uuid", id.String()).Msg("error listing versions")
+			os.Exit(exitCode(err))
+		}
+		for _, v := range versions {
+			fmt.Printf("%d %s %d bytes %s\n", v.Number, v.Timestamp.Local().Format("2006-01-02 15:04"), len(v.Data), v.Name)
+		}
 
-		ids, err := snip.GetAllSnipIDs()
+	case "watch":
+		if err := watchCmd.Parse(os.Args[2:]); err != nil {
+			exitOnHelp(err)
+			fmt.Fprintf(os.Stderr, "The watch arguments could not be parsed.\n")
+			log.Debug().Err(err).Msg("error parsing watch arguments")
+			watchCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		if *watchCmdClipboard {
+			var exclude *regexp.Regexp
+			if *watchCmdExclude != "" {
+				exclude, err = regexp.Compile(*watchCmdExclude)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "The exclude pattern %s could not be compiled.\n", *watchCmdExclude)
+					log.Debug().Err(err).Msg("error compiling exclude pattern")
+					os.Exit(exitInvalid)
+				}
+			}
+			err = watchClipboard(*watchCmdInterval, exclude, nameStrategy)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem watching the clipboard.\n")
+				log.Debug().Err(err).Msg("error watching clipboard")
+				os.Exit(exitCode(err))
+			}
+			break
+		}
+		if len(watchCmd.Args()) != 1 {
+			fmt.Fprintf(os.Stderr, "The watch command requires one directory argument.\n")
+			watchCmd.Usage()
+			os.Exit(exitInvalid)
+		}
+		err = watchDirectory(watchCmd.Arg(0))
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error")
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "There was a problem watching the directory %s\n", watchCmd.Arg(0))
+			log.Debug().Err(err).Str("dir", watchCmd.Arg(0)).Msg("error watching directory")
+			os.Exit(exitCode(err))
 		}
-		numLength := 0
-		for idx, id := range ids {
-			// assign for next time
-			numLength = len(strconv.Itoa(idx+1)) + 1 + len(strconv.Itoa(len(ids)))
-			progressStr := fmt.Sprintf("%d/%d", idx+1, len(ids))
-			fmt.Fprintf(os.Stderr, progressStr)
-			s, err := snip.GetFromUUID(id.String())
+
+	case "index":
+		action := "rebuild"
+		if len(os.Args) > 2 {
+			action = os.Args[2]
+		}
+		switch action {
+		case "rebuild":
+			bar := newProgressBar(os.Stderr)
+			err := snip.RebuildIndex(bar)
+			bar.Finish()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "error")
-				os.Exit(1)
+				fmt.Fprintf(os.Stderr, "There was a problem rebuilding the index.\n")
+				log.Debug().Err(err).Msg("error rebuilding index")
+				os.Exit(exitCode(err))
+			}
+			fmt.Fprintf(os.Stderr, "index rebuilt\n")
+		case "export", "import":
+			if len(os.Args) != 4 {
+				fmt.Fprintf(os.Stderr, "Must supply the file of the index to %s.\n", action)
+				os.Exit(exitInvalid)
 			}
-			log.Debug().Str("uuid", s.UUID.String()).Msg("indexing snip")
-			err = s.Index()
+			file := os.Args[3]
+			bar := newProgressBar(os.Stderr)
+			var result snip.IndexImport
+			if action == "export" {
+				err = exportIndex(file, bar)
+			} else {
+				result, err = importIndex(file, bar)
+			}
+			bar.Finish()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "error indexing item %s\n", s.UUID)
-				os.Exit(1)
+				fmt.Fprintf(os.Stderr, "There was a problem during the index %s.\n", action)
+				log.Debug().Err(err).Str("file", file).Msgf("error during index %s", action)
+				os.Exit(exitCode(err))
+			}
+			if action == "export" {
+				fmt.Fprintf(os.Stderr, "index written to %s\n", file)
+			} else {
+				fmt.Fprintf(os.Stderr, "index loaded for %d snips, %d indexed again\n", result.Loaded, result.Reindexed)
 			}
-			for i := 0; i < numLength; i++ {
-				fmt.Fprintf(os.Stderr, "\b \b")
+		case "trigrams":
+			if len(os.Args) != 4 || (os.Args[3] != "on" && os.Args[3] != "off") {
+				fmt.Fprintf(os.Stderr, "Must supply on or off for the trigram index.\n")
+				os.Exit(exitInvalid)
+			}
+			if os.Args[3] == "off" {
+				err = snip.DisableTrigrams()
+			} else {
+				bar := newProgressBar(os.Stderr)
+				err = snip.EnableTrigrams(bar)
+				bar.Finish()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem turning the trigram index %s.\n", os.Args[3])
+				log.Debug().Err(err).Msg("error changing trigram index")
+				os.Exit(exitCode(err))
 			}
+			fmt.Fprintf(os.Stderr, "trigram index %s\n", os.Args[3])
+		default:
+			fmt.Fprintf(os.Stderr, "The index action %s is not supported.\n", action)
+			Usage()
+			os.Exit(exitInvalid)
 		}
-		fmt.Fprintf(os.Stderr, "success\n")
 
 	default:
 		Usage()
-		os.Exit(1)
+		os.Exit(exitInvalid)
 	}
 
 	log.Debug().Msg("program execution complete")
 }
 
-// confirmAction prompts the user to confirm an action
-func confirmAction(message string) bool {
-	prompt := "[Y/n]"
-	r := bufio.NewReader(os.Stdin)
-	fmt.Printf("%s %s: ", message, prompt)
-	response, err := r.ReadString('\n')
-	if err != nil {
-		return false
-	}
-	// remove newline for comparison
-	response = strings.TrimSuffix(response, "\n")
-	switch strings.ToLower(response) {
-	case "":
-		fallthrough
-	case "yes":
-		fallthrough
-	case "y":
-		return true
-	}
-
-	return false
-}
-
 // readFromFile reads all data from specified file
 func readFromFile(path string) ([]byte, error) {
 	// TODO check file size for sanity to avoid polluting a database
@@ -2,14 +2,24 @@ package main_test
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
+	"image/png"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -151,3 +161,3249 @@ func TestList(t *testing.T) {
 		}
 	}
 }
+
+func TestStdio(t *testing.T) {
+	requests := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"get","params":{"uuid":"990a917e"}}`,
+		`{"jsonrpc":"2.0","method":"get","params":{"uuid":"990a917e"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"missing"}`,
+	}, "\n")
+	cmd := exec.Command(appPath, "stdio")
+	cmd.Stdin = strings.NewReader(requests)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	// the notification without an id is not answered
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 responses, got %d: %s", len(lines), output)
+	}
+	if !strings.HasPrefix(lines[0], `{"jsonrpc":"2.0","id":1,"result":{`) || !strings.Contains(lines[0], "990a917e-66d3-404b-9502-e8341964730b") {
+		t.Errorf("expected snip result for id 1, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"code":-32601`) {
+		t.Errorf("expected method not found error for id 2, got %s", lines[1])
+	}
+}
+
+func TestSearchAlfred(t *testing.T) {
+	output, err := exec.Command(appPath, "search", "-format", "alfred", "nonexistentterm").Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	var result struct {
+		Items []struct {
+			Title string `json:"title"`
+			Valid *bool  `json:"valid"`
+		} `json:"items"`
+	}
+	if err = json.Unmarshal(output, &result); err != nil {
+		t.Fatalf("expected script filter json, got %s", output)
+	}
+	if len(result.Items) != 1 || result.Items[0].Valid == nil || *result.Items[0].Valid {
+		t.Errorf("expected a single invalid placeholder item, got %s", output)
+	}
+
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "alfred.sqlite3"))
+	data := "the wren sings at dawn"
+	cmd := exec.Command(appPath, "add", "-n", "birdsong")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(data)
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+
+	cmd = exec.Command(appPath, "search", "-format", "alfred", "wren")
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	var matches struct {
+		Items []struct {
+			UID      string `json:"uid"`
+			Title    string `json:"title"`
+			Subtitle string `json:"subtitle"`
+			Arg      string `json:"arg"`
+			Valid    *bool  `json:"valid"`
+			Text     struct {
+				Copy      string `json:"copy"`
+				LargeType string `json:"largetype"`
+			} `json:"text"`
+		} `json:"items"`
+	}
+	if err = json.Unmarshal(output, &matches); err != nil {
+		t.Fatalf("expected script filter json, got %s", output)
+	}
+	if len(matches.Items) != 1 {
+		t.Fatalf("expected a single item, got %s", output)
+	}
+	item := matches.Items[0]
+	if item.Title != "birdsong" || item.Arg != id || item.UID != id || item.Valid != nil {
+		t.Errorf("expected the item of snip %s titled birdsong, got %+v", id, item)
+	}
+	if !strings.HasPrefix(item.Subtitle, "score ") || !strings.Contains(item.Subtitle, " | ") || !strings.Contains(item.Subtitle, "wren") {
+		t.Errorf("expected the score and context of the match as subtitle, got %q", item.Subtitle)
+	}
+	if item.Text.Copy != data || item.Text.LargeType != data {
+		t.Errorf("expected the snip text for copying and large type, got %+v", item.Text)
+	}
+}
+
+func TestSearchExplain(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "explain.sqlite3"))
+	cmd := exec.Command(appPath, "add", "-n", "fox")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("the quick brown fox")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	var stderr strings.Builder
+	cmd = exec.Command(appPath, "search", "-explain", "fox")
+	cmd.Env = env
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.HasPrefix(string(output), "fox\n") {
+		t.Errorf("expected the search results on stdout, got %q", output)
+	}
+	for _, expected := range []string{"index terms\n", "fox  fox      1           1\n", "\nstages\n", "lookup ", "total ", "\nquery plans\n", "lookup: SELECT uuid, count FROM snip_index"} {
+		if !strings.Contains(stderr.String(), expected) {
+			t.Errorf("expected explanation to contain %q, got %q", expected, stderr.String())
+		}
+	}
+
+	cmd = exec.Command(appPath, "search", "-explain", "-type", "data", "fox")
+	cmd.Env = env
+	if err = cmd.Run(); err == nil {
+		t.Errorf("expected data searches to refuse -explain")
+	}
+}
+
+func TestExec(t *testing.T) {
+	cmd := exec.Command(appPath, "exec", "-stderr", "--", "sh", "-c", "echo captured; echo problem >&2; exit 3")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("expected exit code 3 from the command, got %v", err)
+	}
+	if !strings.Contains(string(output), "captured") {
+		t.Errorf("expected command output to be echoed, got %q", output)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(stderr.String(), "added snip uuid: "))
+
+	output, err = exec.Command(appPath, "get", id).Output()
+	if err != nil {
+		t.Fatalf("expected nil err getting %q, got %v", id, err)
+	}
+	for _, expected := range []string{"captured\nproblem\n", "exit_code: 3", "command: sh -c 'echo captured; echo problem >&2; exit 3'"} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("expected %q in snip, got %s", expected, output)
+		}
+	}
+
+	// leave the test database as it was
+	remove := exec.Command(appPath, "rm", id)
+	remove.Stdin = strings.NewReader("y\n")
+	if err = remove.Run(); err != nil {
+		t.Errorf("expected nil err removing snip, got %v", err)
+	}
+}
+
+func TestImportHistory(t *testing.T) {
+	dir := t.TempDir()
+	// import into a separate database so that other tests see the usual contents
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "history.sqlite3"))
+	historyPath := path.Join(dir, "zsh_history")
+	history := ": 1696000000:0;ls -la\n: 1696000100:0;echo one\\\ntwo\n: 1696090000:0;ls -la\n"
+	if err := os.WriteFile(historyPath, []byte(history), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"imported 2 snips", "imported 0 snips"} {
+		cmd := exec.Command(appPath, "import", "history", "-shell", "zsh", "-f", historyPath)
+		cmd.Env = env
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if !strings.HasPrefix(string(output), expected) {
+			t.Errorf("expected %q, got %s", expected, output)
+		}
+	}
+
+	cmd := exec.Command(appPath, "ls")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	for _, name := range []string{" ls -la\n", " echo one\n"} {
+		if strings.Count(string(output), name) != 1 {
+			t.Errorf("expected one snip named %q, got %s", strings.TrimSpace(name), output)
+		}
+	}
+
+	// a later daily import adds the new commands of a day to its snip
+	env = append(os.Environ(), "SNIP_DB="+path.Join(dir, "daily.sqlite3"), "TZ=UTC")
+	for _, run := range []struct{ history, expected string }{
+		{": 1696000000:0;ls -la\n: 1696000100:0;make\n", "imported 1 snips from " + historyPath + ", updated 0, skipped 0"},
+		{": 1696000000:0;ls -la\n: 1696000100:0;make\n: 1696000200:0;make test\n", "imported 0 snips from " + historyPath + ", updated 1, skipped 0"},
+		{": 1696000000:0;ls -la\n: 1696000100:0;make\n: 1696000200:0;make test\n", "imported 0 snips from " + historyPath + ", updated 0, skipped 1"},
+	} {
+		if err = os.WriteFile(historyPath, []byte(run.history), 0600); err != nil {
+			t.Fatal(err)
+		}
+		cmd = exec.Command(appPath, "import", "history", "-daily", "-shell", "zsh", "-f", historyPath)
+		cmd.Env = env
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if !strings.HasPrefix(string(output), run.expected) {
+			t.Errorf("expected %q, got %s", run.expected, output)
+		}
+	}
+	cmd = exec.Command(appPath, "ls", "-porcelain")
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	fields := strings.Split(strings.TrimSpace(string(output)), "\t")
+	if strings.Count(string(output), "\n") != 1 || fields[len(fields)-1] != "history 2023-09-29" {
+		t.Fatalf("expected a single daily snip, got %q", output)
+	}
+	cmd = exec.Command(appPath, "get", "-raw", fields[0])
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(output) != "ls -la\nmake\nmake test\n" {
+		t.Errorf("expected the commands of both imports, got %q", output)
+	}
+}
+
+func TestImportBulk(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "bulk.sqlite3"))
+	files := map[string]string{
+		"notes/alpha.md":     "alpha notes about parsing\n",
+		"notes/sub/beta.txt": "beta notes about sorting\n",
+		"notes/.hidden/x.md": "hidden\n",
+		"notes/.gamma.md":    "hidden too\n",
+		"notes/empty.md":     "  \n",
+		"rows.csv":           "name,data,tags\ncsv one,first row,\"a,b\"\ncsv two,\"alpha notes about parsing\n\",\n",
+		"lines.jsonl":        "{\"name\":\"json one\",\"data\":\"first line\",\"meta\":{\"k\":\"v\"}}\n\n{\"data\":\"second line\",\"tags\":[\"c\"]}\n",
+	}
+	for name, data := range files {
+		if err := os.MkdirAll(path.Dir(path.Join(dir, name)), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// imported snips are added to the trigram index
+	cmd := exec.Command(appPath, "index", "trigrams", "on")
+	cmd.Env = env
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"dir", "-jobs", "4", path.Join(dir, "notes")}, "imported 2 snips in "},
+		{[]string{"csv", path.Join(dir, "rows.csv")}, "skipped 1 duplicates and 0 without text"},
+		{[]string{"jsonl", "-jobs", "2", path.Join(dir, "lines.jsonl")}, "imported 2 snips in "},
+		{[]string{"dir", path.Join(dir, "notes")}, "skipped 2 duplicates and 1 without text"},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(appPath, append([]string{"import"}, tt.args...)...)
+		cmd.Env = env
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err importing %v, got %v", tt.args, err)
+		}
+		if !strings.Contains(string(output), tt.expected) {
+			t.Errorf("expected %q importing %v, got %s", tt.expected, tt.args, output)
+		}
+	}
+
+	cmd = exec.Command(appPath, "search", "-substring", "es about sort")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil || !strings.HasSuffix(string(output), " beta\n") {
+		t.Errorf("expected the imported snip found by substring, got %q %v", output, err)
+	}
+
+	cmd = exec.Command(appPath, "ls")
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	for _, name := range []string{" alpha\n", " beta\n", " csv one\n", " json one\n", " second line\n"} {
+		if strings.Count(string(output), name) != 1 {
+			t.Errorf("expected one snip named %q, got %s", strings.TrimSpace(name), output)
+		}
+	}
+	if strings.Contains(string(output), "hidden") || strings.Contains(string(output), "csv two") {
+		t.Errorf("expected hidden files and duplicates to be skipped, got %s", output)
+	}
+
+	os.WriteFile(path.Join(dir, "bad.jsonl"), []byte("{\"data\":\"fine\"}\n{bad\n"), 0600)
+	cmd = exec.Command(appPath, "import", "jsonl", path.Join(dir, "bad.jsonl"))
+	cmd.Env = env
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err == nil {
+		t.Errorf("expected an error importing invalid json")
+	}
+	if !strings.Contains(stderr.String(), "line 2: ") {
+		t.Errorf("expected the invalid line to be reported, got %s", stderr.String())
+	}
+}
+
+func TestAddBatch(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "batch.sqlite3"))
+	cmd := exec.Command(appPath, "add", "-batch", "-delimiter", "---", "-first-line")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("first\nbody one\n---\n\n---\nsecond\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if count := strings.Count(string(output), "added snip uuid: "); count != 2 {
+		t.Fatalf("expected 2 snips added, got %s", output)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(strings.Split(string(output), "\n")[0], "added snip uuid: "))
+
+	cmd = exec.Command(appPath, "get", id)
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(string(output), "name: first\n") || !strings.Contains(string(output), "----\nbody one\n----") {
+		t.Errorf("expected first line as name and the rest as data, got %s", output)
+	}
+
+	cmd = exec.Command(appPath, "add", "-batch", "-delimiter", "---", "-first-line", "-skip-duplicates")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("first\nbody one\n---\nthird\n")
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(string(output), "skipped duplicate of snip "+id[:8]) || strings.Count(string(output), "added snip uuid: ") != 1 {
+		t.Errorf("expected the duplicate block to be skipped, got %s", output)
+	}
+}
+
+func TestGetMultiple(t *testing.T) {
+	cmd := exec.Command(appPath, "get", "-json", "-ids-from-stdin", "990a917e")
+	cmd.Stdin = strings.NewReader("uuid     name\n65f6930f-e970-4b6e-b10c-fca3dac21c1e some name\n    [0-0] \"context\"\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	var snips []struct {
+		UUID string
+	}
+	if err = json.Unmarshal(output, &snips); err != nil {
+		t.Fatalf("expected json array, got %s", output)
+	}
+	if len(snips) != 2 || !strings.HasPrefix(snips[0].UUID, "65f6930f") || !strings.HasPrefix(snips[1].UUID, "990a917e") {
+		t.Errorf("expected snips from standard input followed by arguments, got %+v", snips)
+	}
+
+	output, err = exec.Command(appPath, "get", "-raw", "-delimiter", "%%", "990a917e", "65f6930f").Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if strings.Count(string(output), "\n%%\n") != 1 {
+		t.Errorf("expected a single delimiter line, got %q", output)
+	}
+}
+
+func TestGetRaw(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "raw.sqlite3"))
+	data := "no trailing newline\r\n\ttabs  "
+	cmd := exec.Command(appPath, "add", "-n", "raw")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(data)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+
+	cmd = exec.Command(appPath, "get", "-raw", id)
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(output) != data {
+		t.Errorf("expected exact data %q, got %q", data, output)
+	}
+
+	cmd = exec.Command(appPath, "get", "-raw", "-header", id)
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.HasPrefix(string(output), "uuid: "+id+"\nname: raw\n") || !strings.HasSuffix(string(output), "----\n"+data) {
+		t.Errorf("expected header followed by exact data, got %q", output)
+	}
+}
+
+func TestGetBase64(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "base64.sqlite3"))
+	data := "\x00\x01binary\xff\xfe"
+	cmd := exec.Command(appPath, "add", "-n", "binary")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(data)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+
+	cmd = exec.Command(appPath, "get", "-base64", id)
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(output)))
+	if err != nil {
+		t.Fatalf("expected nil err decoding output, got %v", err)
+	}
+	if string(decoded) != data {
+		t.Errorf("expected data %q, got %q", data, decoded)
+	}
+
+	// binary data is summarized rather than written to the terminal
+	cmd = exec.Command(appPath, "get", id)
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(string(output), "(binary data, 10 bytes)\n") {
+		t.Errorf("expected binary summary, got %q", output)
+	}
+}
+
+func TestGetTmux(t *testing.T) {
+	dir := t.TempDir()
+	// tmux is replaced by a script recording the arguments of each call on a line
+	script := "#!/bin/sh\necho \"$*\" >> \"$TMUX_LOG\"\nexit \"$TMUX_STATUS\"\n"
+	if err := os.WriteFile(path.Join(dir, "tmux"), []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	logPath := path.Join(dir, "tmux.log")
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "tmux.sqlite3"), "PATH="+dir+":"+os.Getenv("PATH"), "TMUX_LOG="+logPath)
+	cmd := exec.Command(appPath, "add", "-n", "commands")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("cd /tmp\n\necho Enter\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+
+	// each line is typed literally and followed by enter, blank lines included
+	cmd = exec.Command(appPath, "get", "-tmux", id, "remote:1.0")
+	cmd.Env = append(env, "TMUX_STATUS=0")
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	sent, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "send-keys -t remote:1.0 -l cd /tmp\nsend-keys -t remote:1.0 Enter\n" +
+		"send-keys -t remote:1.0 Enter\n" +
+		"send-keys -t remote:1.0 -l echo Enter\nsend-keys -t remote:1.0 Enter\n"
+	if string(sent) != expected {
+		t.Errorf("expected %q, got %q", expected, sent)
+	}
+
+	// a pane that cannot be reached fails the command
+	cmd = exec.Command(appPath, "get", "-tmux", id, "missing")
+	cmd.Env = append(env, "TMUX_STATUS=1")
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("expected exit status 1 when tmux fails, got %v", err)
+	}
+}
+
+func TestGetQR(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "qr.sqlite3"))
+	add := func(data string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, "add", "-n", "qr")
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(data)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		return strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+	}
+
+	cmd := exec.Command(appPath, "get", "-qr", add("https://example.com/a/short/url"))
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	// the code is drawn with half blocks, two rows of modules to a line, within a quiet zone
+	lines := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	width := len([]rune(lines[0]))
+	if width < 21 || len(lines) < 21/2 || !strings.ContainsAny(string(output), "█▀▄") {
+		t.Fatalf("expected a qr code, got %q", output)
+	}
+	for idx, line := range lines {
+		if len([]rune(line)) != width {
+			t.Errorf("expected line %d to be %d wide, got %q", idx+1, width, line)
+		}
+	}
+	if strings.Trim(lines[0], "█") != "" {
+		t.Errorf("expected the quiet zone above the code drawn light on a dark terminal, got %q", lines[0])
+	}
+
+	// data beyond the capacity of a qr code is refused
+	cmd = exec.Command(appPath, "get", "-qr", add(strings.Repeat("x", 4000)))
+	cmd.Env = env
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("expected exit status 1 for data too large, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "verify.sqlite3")
+	env := append(os.Environ(), "SNIP_DB="+dbPath)
+	cmd := exec.Command(appPath, "add", "-n", "verify")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("verify data\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+
+	cmd = exec.Command(appPath, "verify", id)
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(output) != "verified 1 snips and 0 attachments, 0 mismatches\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	// tamper with the data outside of snip
+	cmd = exec.Command("sqlite3", dbPath, "UPDATE snip SET data = 'tampered'")
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	for _, args := range [][]string{{"verify", "-all"}, {"doctor"}} {
+		cmd = exec.Command(appPath, args...)
+		cmd.Env = env
+		output, err = cmd.Output()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			t.Errorf("%v: expected exit code 1, got %v", args, err)
+		}
+		if !strings.Contains(string(output), "mismatch snip "+id+"\n") {
+			t.Errorf("%v: expected mismatch to be reported, got %q", args, output)
+		}
+	}
+}
+
+func TestLock(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "lock.sqlite3"))
+	cmd := exec.Command(appPath, "add", "-n", "canonical")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("reference note\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+
+	cmd = exec.Command(appPath, "lock", id)
+	cmd.Env = env
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	cmd = exec.Command(appPath, "rename", id, "changed")
+	cmd.Env = env
+	if err = cmd.Run(); err == nil {
+		t.Errorf("expected rename of a locked snip to fail")
+	}
+	cmd = exec.Command(appPath, "rm", id)
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("y\n")
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	cmd = exec.Command(appPath, "lock")
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(output) != id[:8]+" canonical\n" {
+		t.Errorf("expected the locked snip to remain, got %q", output)
+	}
+
+	cmd = exec.Command(appPath, "rm", "-force", id)
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("y\n")
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(string(output), "removed 1/1 "+id) {
+		t.Errorf("expected forced removal, got %q", output)
+	}
+}
+
+func TestDue(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "due.sqlite3"))
+	var ids []string
+	for _, name := range []string{"later", "sooner"} {
+		cmd := exec.Command(appPath, "add", "-n", name)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(name + " task\n")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: ")))
+	}
+
+	for idx, when := range []string{"3d", "2020-01-01 09:00"} {
+		cmd := exec.Command(appPath, "due", "set", ids[idx], when)
+		cmd.Env = env
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+	}
+
+	cmd := exec.Command(appPath, "due", "ls")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 || lines[0] != ids[1][:8]+" overdue 2020-01-01 09:00 sooner" || !strings.HasPrefix(lines[1], ids[0][:8]+" due ") {
+		t.Errorf("expected the overdue snip first, got %q", output)
+	}
+
+	cmd = exec.Command(appPath, "due", "rm", ids[1])
+	cmd.Env = env
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	cmd = exec.Command(appPath, "due", "ls")
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if strings.Count(string(output), "\n") != 1 {
+		t.Errorf("expected a single due snip, got %q", output)
+	}
+}
+
+func TestMoveDB(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "here.sqlite3"))
+	otherEnv := append(os.Environ(), "SNIP_DB="+path.Join(dir, "there.sqlite3"))
+	var ids []string
+	for _, name := range []string{"moving", "staying"} {
+		cmd := exec.Command(appPath, "add", "-n", name, "-tag", "work")
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(name + " data\n")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: ")))
+	}
+
+	cmd := exec.Command(appPath, "mv-db", ids[0][:8], "-to", path.Join(dir, "there.sqlite3"))
+	cmd.Env = env
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	tests := []struct {
+		env      []string
+		expected string
+	}{
+		{env, ids[1][:8] + " work staying\n"},
+		{otherEnv, ids[0][:8] + " work moving\n"},
+	}
+	for _, test := range tests {
+		cmd = exec.Command(appPath, "ls", "-columns", "uuid,tags,name")
+		cmd.Env = test.env
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if string(output) != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, output)
+		}
+	}
+}
+
+func TestNote(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "note.sqlite3"))
+	cmd := exec.Command(appPath, "add", "-n", "install")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("apt install build-essential\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+
+	cmd = exec.Command(appPath, "note", "add", id, "broke", "on", "Ubuntu", "24.04")
+	cmd.Env = env
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	cmd = exec.Command(appPath, "get", "-notes", id)
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(string(output), "----\nnotes:\n1 ") || !strings.HasSuffix(string(output), " broke on Ubuntu 24.04\n") {
+		t.Errorf("expected the note after the snip, got %q", output)
+	}
+
+	// notes are shown only when asked for
+	cmd = exec.Command(appPath, "get", id)
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if strings.Contains(string(output), "notes:") {
+		t.Errorf("expected no notes without -notes, got %q", output)
+	}
+}
+
+func TestListColumns(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "columns.sqlite3"))
+	cmd := exec.Command(appPath, "add", "-n", "sized", "-tag", "go,ref")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("twelve bytes")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+
+	tests := []struct {
+		columns  string
+		expected string
+	}{
+		{"name,size,tags", "sized   12 go,ref\n"},
+		{"tags,uuid", "go,ref " + id[:8] + "\n"},
+	}
+	for _, test := range tests {
+		cmd = exec.Command(appPath, "ls", "-columns", test.columns)
+		cmd.Env = env
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if string(output) != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.columns, test.expected, output)
+		}
+	}
+
+	cmd = exec.Command(appPath, "ls", "-columns", "uuid,bogus")
+	cmd.Env = env
+	if err = cmd.Run(); err == nil {
+		t.Errorf("expected error listing an unsupported column")
+	}
+}
+
+func TestListAttachments(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "attachments.sqlite3"))
+	var ids []string
+	for _, name := range []string{"bare", "attached"} {
+		cmd := exec.Command(appPath, "add", "-n", name)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(name + " data\n")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: ")))
+	}
+	file := path.Join(dir, "notes.txt")
+	if err := os.WriteFile(file, []byte("attached notes\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(appPath, "attach", "add", ids[1], file, file)
+	cmd.Env = env
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-l"}, ids[0] + "           0 bare\n" + ids[1] + "           2 attached\n"},
+		{[]string{"-has-attachments"}, ids[1][:8] + " attached\n"},
+		{[]string{"-no-attachments"}, ids[0][:8] + " bare\n"},
+	}
+	for _, test := range tests {
+		cmd = exec.Command(appPath, append([]string{"ls"}, test.args...)...)
+		cmd.Env = env
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if string(output) != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.args, test.expected, output)
+		}
+	}
+}
+
+func TestListGroupBy(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "group.sqlite3"))
+	var ids []string
+	for _, add := range []struct{ name, timestamp, tags string }{
+		{"later", "2024-03-09 10:00", "go"},
+		{"earlier", "2024-02-01 09:00", "go,ref"},
+		{"same day", "2024-03-09 08:00", ""},
+	} {
+		cmd := exec.Command(appPath, "add", "-n", add.name, "-timestamp", add.timestamp, "-tag", add.tags)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(add.name + " data\n")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))[:8])
+	}
+
+	tests := []struct {
+		groupBy  string
+		expected string
+	}{
+		{"day", "2024-02-01 (1)\n" + ids[1] + " earlier\n\n2024-03-09 (2)\n" + ids[2] + " same day\n" + ids[0] + " later\n"},
+		{"month", "2024-02 (1)\n" + ids[1] + " earlier\n\n2024-03 (2)\n" + ids[2] + " same day\n" + ids[0] + " later\n"},
+		{"tag", "go (2)\n" + ids[0] + " later\n" + ids[1] + " earlier\n\nref (1)\n" + ids[1] + " earlier\n\n(untagged) (1)\n" + ids[2] + " same day\n"},
+	}
+	for _, test := range tests {
+		cmd := exec.Command(appPath, "ls", "-group-by", test.groupBy)
+		cmd.Env = env
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if string(output) != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.groupBy, test.expected, output)
+		}
+	}
+}
+
+func TestRandom(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "random.sqlite3"))
+	for _, add := range []struct{ data, tags string }{
+		{"review me", "review"},
+		{"not for review", ""},
+	} {
+		cmd := exec.Command(appPath, "add", "-tag", add.tags)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(add.data)
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+	}
+
+	cmd := exec.Command(appPath, "random", "-raw", "-tag", "review")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(output) != "review me" {
+		t.Errorf("expected the only snip tagged review, got %q", output)
+	}
+
+	cmd = exec.Command(appPath, "random", "-tag", "missing")
+	cmd.Env = env
+	if err = cmd.Run(); err == nil {
+		t.Errorf("expected error without snips to choose from")
+	}
+}
+
+func TestRecent(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "recent.sqlite3"))
+	var ids []string
+	for _, name := range []string{"older", "newer"} {
+		cmd := exec.Command(appPath, "add", "-n", name)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(name + " data\n")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: ")))
+	}
+
+	// viewing the older snip makes it the most recent
+	cmd := exec.Command(appPath, "get", "-raw", ids[0])
+	cmd.Env = env
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	cmd = exec.Command(appPath, "recent", "-l")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], ids[0]) || !strings.HasSuffix(lines[0], " older") || !strings.HasPrefix(lines[1], ids[1]) {
+		t.Errorf("expected older then newer, got %q", output)
+	}
+
+	recent := exec.Command(appPath, "recent", "1")
+	recent.Env = env
+	output, err = recent.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	cmd = exec.Command(appPath, "get", "-raw", "-")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(string(output))
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(output) != "older data\n" {
+		t.Errorf("expected the most recent snip, got %q", output)
+	}
+}
+
+func TestStatsActivity(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "activity.sqlite3"))
+	now := time.Now()
+	for _, timestamp := range []time.Time{now, now, now.AddDate(-2, 0, 0)} {
+		cmd := exec.Command(appPath, "add", "-timestamp", timestamp.Format(time.RFC3339))
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("activity data\n")
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+	}
+
+	cmd := exec.Command(appPath, "stats", "activity")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 10 {
+		t.Fatalf("expected month names, 7 days, legend, and summary, got %q", output)
+	}
+	// today is the last cell drawn, in the row of its weekday
+	if row := lines[1+int(now.Weekday())]; !strings.HasSuffix(row, "█") {
+		t.Errorf("expected the busiest cell today, got %q", row)
+	}
+	if !strings.HasPrefix(lines[9], "2 snips added or changed on 1 days since ") {
+		t.Errorf("expected the old snip to be left out, got %q", lines[9])
+	}
+}
+
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	conf := path.Join(dir, "snip.json")
+	edited := path.Join(dir, "edited")
+	if err := os.WriteFile(conf, []byte(`{"hooks": {"post-edit": ["echo $SNIP_UUID >> `+edited+`"]}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "replace.sqlite3"), "SNIP_CONFIG="+conf)
+	var ids []string
+	for _, add := range []struct{ data, tags string }{
+		{"ssh old-host\nping old-host\n", "infra"},
+		{"old-host is in the notes too\n", ""},
+		{"costs $5 at old-host\n", "infra"},
+	} {
+		cmd := exec.Command(appPath, "add", "-tag", add.tags)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(add.data)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: ")))
+	}
+
+	// a dry run changes nothing
+	cmd := exec.Command(appPath, "replace", "-dry-run", "-filter", "-tag infra", "s/old-host/new-host/g")
+	cmd.Env = append(env, "NO_COLOR=1")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(string(output), "-ssh old-host\n-ping old-host\n+ssh new-host\n+ping new-host\n") || !strings.HasSuffix(string(output), "would replace 3 matches in 2 snips\n") {
+		t.Errorf("expected diffs of the tagged snips, got %q", output)
+	}
+
+	cmd = exec.Command(appPath, "replace", "-yes", "-filter", "-tag infra", `s|OLD-(host)|&/\1|gi`)
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.HasSuffix(string(output), "replaced 3 matches in 2 snips\n") {
+		t.Errorf("expected 3 replacements, got %q", output)
+	}
+	hooked, err := os.ReadFile(edited)
+	if err != nil {
+		t.Fatalf("expected the post-edit hook to run, got %v", err)
+	}
+	if string(hooked) != ids[0]+"\n"+ids[2]+"\n" {
+		t.Errorf("expected the post-edit hook run for each changed snip, got %q", hooked)
+	}
+	for idx, expected := range []string{"ssh old-host/host\nping old-host/host\n", "old-host is in the notes too\n", "costs $5 at old-host/host\n"} {
+		cmd = exec.Command(appPath, "get", "-raw", ids[idx])
+		cmd.Env = env
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if string(output) != expected {
+			t.Errorf("expected %q, got %q", expected, output)
+		}
+	}
+
+	cmd = exec.Command(appPath, "replace", "s/unterminated")
+	cmd.Env = env
+	if err = cmd.Run(); err == nil {
+		t.Errorf("expected error for an invalid substitution")
+	}
+}
+
+func TestReview(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "review.sqlite3"))
+	var ids []string
+	for _, name := range []string{"capital of peru", "boiling point"} {
+		cmd := exec.Command(appPath, "add", "-n", name)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(name + " answer\n")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: ")))
+	}
+	cmd := exec.Command(appPath, append([]string{"review", "add"}, ids...)...)
+	cmd.Env = env
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	// grade the first snip after an unknown key, then quit before the second
+	cmd = exec.Command(appPath, "review")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("\nx\ng\nq\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	for _, expected := range []string{"[1/2] capital of peru", "capital of peru answer", "next review in 1 days", "[2/2] boiling point", "reviewed 1 of 2 snips"} {
+		if !strings.Contains(string(output), expected) {
+			t.Errorf("expected %q in review, got %q", expected, output)
+		}
+	}
+
+	cmd = exec.Command(appPath, "review")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("\ne\n")
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(string(output), "[1/1] boiling point") || !strings.HasSuffix(string(output), "reviewed 1 of 1 snips\n") {
+		t.Errorf("expected only the ungraded snip to be due, got %q", output)
+	}
+
+	cmd = exec.Command(appPath, "review", "rm", ids[0])
+	cmd.Env = env
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	cmd = exec.Command(appPath, "review", "ls")
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(output)), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], ids[1][:8]) {
+		t.Errorf("expected only %s under review, got %q", ids[1][:8], output)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "sql.sqlite3"))
+	for _, name := range []string{"alpha", "beta"} {
+		cmd := exec.Command(appPath, "add", "-n", name)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(name + " data")
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+	}
+
+	query := "SELECT name, length(data) AS size FROM snip ORDER BY name"
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"table", "name  size\nalpha 10\nbeta  9\n"},
+		{"csv", "name,size\nalpha,10\nbeta,9\n"},
+		{"json", "[\n  {\n    \"name\": \"alpha\",\n    \"size\": 10\n  },\n  {\n    \"name\": \"beta\",\n    \"size\": 9\n  }\n]\n"},
+	}
+	for _, test := range tests {
+		cmd := exec.Command(appPath, "sql", "-format", test.format, query)
+		cmd.Env = env
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if string(output) != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.format, test.expected, output)
+		}
+	}
+
+	cmd := exec.Command(appPath, "sql", "DELETE FROM snip")
+	cmd.Env = env
+	if err := cmd.Run(); err == nil {
+		t.Errorf("expected a write to be refused without -write")
+	}
+	cmd = exec.Command(appPath, "sql", "-write", "DELETE FROM snip WHERE name = 'beta'")
+	cmd.Env = env
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	cmd = exec.Command(appPath, "sql", "-format", "csv", "SELECT count(*) AS snips FROM snip")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(output) != "snips\n1\n" {
+		t.Errorf("expected 1 snip left, got %q", output)
+	}
+}
+
+func TestStar(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "star.sqlite3"))
+	var ids []string
+	for _, name := range []string{"plain", "good", "best"} {
+		cmd := exec.Command(appPath, "add", "-n", name)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(name + " reference\n")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: ")))
+	}
+	for idx, stars := range []string{"", "3", "5"} {
+		if stars == "" {
+			continue
+		}
+		cmd := exec.Command(appPath, "star", ids[idx], stars)
+		cmd.Env = env
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-sort", "stars"}, ids[2][:8] + " ***** best\n" + ids[1][:8] + " ***   good\n" + ids[0][:8] + "       plain\n"},
+		{[]string{"-starred"}, ids[1][:8] + " ***   good\n" + ids[2][:8] + " ***** best\n"},
+	}
+	for _, test := range tests {
+		cmd := exec.Command(appPath, append([]string{"ls"}, test.args...)...)
+		cmd.Env = env
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if string(output) != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.args, test.expected, output)
+		}
+	}
+}
+
+func TestExpire(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "expire.sqlite3")
+	env := append(os.Environ(), "SNIP_DB="+dbPath)
+	cmd := exec.Command(appPath, "add", "-n", "temporary", "-expires", "7d")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("temporary paste\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+
+	cmd = exec.Command(appPath, "expire", "ls", "-l")
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.HasPrefix(string(output), id+" expires ") || !strings.HasSuffix(string(output), " temporary\n") {
+		t.Errorf("expected the snip to be listed with its expiry, got %q", output)
+	}
+
+	// move the expiry into the past
+	cmd = exec.Command("sqlite3", dbPath, "UPDATE snip_expire SET expires = '2000-01-01T00:00:00Z'")
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	cmd = exec.Command(appPath, "ls")
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(output) != 0 {
+		t.Errorf("expected expired snip to be hidden from ls, got %q", output)
+	}
+
+	cmd = exec.Command(appPath, "expire", "purge")
+	cmd.Env = env
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(output) != "removed "+id+"\n" {
+		t.Errorf("expected the expired snip to be removed, got %q", output)
+	}
+}
+
+func TestBench(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "bench.sqlite3")
+	cmd := exec.Command(appPath, "bench", "-snips", "20")
+	cmd.Env = append(os.Environ(), "SNIP_DB="+dbPath)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "operation count") {
+		t.Fatalf("expected a header and 4 operations, got %q", output)
+	}
+	for idx, expected := range []string{"add          20", "ls           20", "search      100", "attach        2"} {
+		if !strings.HasPrefix(lines[idx+1], expected) {
+			t.Errorf("expected %q, got %q", expected, lines[idx+1])
+		}
+	}
+
+	// the database in use is left untouched
+	cmd = exec.Command(appPath, "count")
+	cmd.Env = append(os.Environ(), "SNIP_DB="+dbPath)
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(output) != "0\n" {
+		t.Errorf("expected no snips in the database in use, got %q", output)
+	}
+}
+
+func TestCount(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "count.sqlite3"))
+	var ids []string
+	for _, add := range []struct{ data, tags string }{
+		{"deploy the kubernetes cluster", "ops"},
+		{"kubernetes pod logs", "ops,ref"},
+		{"grocery list", ""},
+	} {
+		cmd := exec.Command(appPath, "add", "-tag", add.tags)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(add.data)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: ")))
+	}
+	file := path.Join(dir, "pods.txt")
+	if err := os.WriteFile(file, []byte("pod list\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(appPath, "attach", "add", ids[1], file, file)
+	cmd.Env = env
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{nil, "3\n"},
+		{[]string{"kubernetes"}, "2\n"},
+		{[]string{"-tag", "ref", "kubernetes"}, "1\n"},
+		{[]string{"-tag", "ops,ref"}, "1\n"},
+		{[]string{"-no-attachments"}, "2\n"},
+		{[]string{"-attachments"}, "2\n"},
+		{[]string{"-attachments", "grocery"}, "0\n"},
+	}
+	for _, test := range tests {
+		cmd = exec.Command(appPath, append([]string{"count"}, test.args...)...)
+		cmd.Env = env
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if string(output) != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.args, test.expected, output)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "diff.sqlite3"))
+	var ids []string
+	for _, add := range []struct{ name, data string }{
+		{"old", "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"},
+		{"new", "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"},
+	} {
+		cmd := exec.Command(appPath, "add", "-n", add.name)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(add.data)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: ")))
+	}
+
+	cmd := exec.Command(appPath, "diff", ids[0], ids[1])
+	cmd.Env = append(env, "NO_COLOR=1")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	expected := "--- " + ids[0][:8] + " old\n+++ " + ids[1][:8] + " new\n" +
+		"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+		"@@ -8,3 +8,4 @@\n h\n i\n j\n+k\n"
+	if string(output) != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+
+	cmd = exec.Command(appPath, "diff", ids[0], ids[0])
+	cmd.Env = env
+	if output, err = cmd.Output(); err != nil || len(output) != 0 {
+		t.Errorf("expected no differences, got %q %v", output, err)
+	}
+
+	cmd = exec.Command(appPath, "diff", ids[0], "-version", "1")
+	cmd.Env = env
+	if err = cmd.Run(); err == nil {
+		t.Errorf("expected error for a snip without versions")
+	}
+}
+
+func TestGetLines(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "lines.sqlite3"))
+	cmd := exec.Command(appPath, "add", "-n", "lines")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("one\ntwo\nthree\nfour\nfive\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"-lines", "2:3"}, "two\nthree\n"},
+		{[]string{"-lines", "4:"}, "four\nfive\n"},
+		{[]string{"-lines", ":1"}, "one\n"},
+		{[]string{"-head", "2"}, "one\ntwo\n"},
+		{[]string{"-tail", "2"}, "four\nfive\n"},
+		{[]string{"-tail", "10"}, "one\ntwo\nthree\nfour\nfive\n"},
+	}
+	for _, test := range tests {
+		cmd = exec.Command(appPath, append(append([]string{"get"}, test.args...), id)...)
+		cmd.Env = env
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err for %v, got %v", test.args, err)
+		}
+		if string(output) != test.expected {
+			t.Errorf("expected %q for %v, got %q", test.expected, test.args, output)
+		}
+	}
+}
+
+func TestIndexExportImport(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "index.sqlite3"))
+	for _, data := range []string{"restart the walrus service", "walrus feeding schedule"} {
+		cmd := exec.Command(appPath, "add")
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(data)
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+	}
+
+	file := path.Join(dir, "index.gz")
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{nil, "index rebuilt"},
+		{[]string{"rebuild"}, "index rebuilt"},
+		{[]string{"export", file}, "index written to " + file},
+		{[]string{"import", file}, "index loaded for 2 snips, 0 indexed again"},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(appPath, append([]string{"index"}, tt.args...)...)
+		cmd.Env = env
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("expected nil err for %v, got %v: %s", tt.args, err, stderr.String())
+		}
+		if !strings.Contains(stderr.String(), tt.expected) {
+			t.Errorf("expected %q for %v, got %s", tt.expected, tt.args, stderr.String())
+		}
+	}
+
+	cmd := exec.Command(appPath, "count", "walrus")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(output) != "2\n" {
+		t.Errorf("expected both snips to be found after importing the index, got %q", output)
+	}
+
+	cmd = exec.Command(appPath, "index", "import", path.Join(dir, "missing.gz"))
+	cmd.Env = env
+	if err = cmd.Run(); err == nil {
+		t.Errorf("expected an error importing a missing file")
+	}
+}
+
+func TestSearchSubstring(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "substring.sqlite3"))
+	run := func(stdin string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("expected nil err for %v, got %v: %s", args, err, output)
+		}
+		return string(output)
+	}
+	run("upstream returned ERR_CONN_RESET", "add", "-name", "proxy log")
+	run("nothing to see", "add", "-name", "unrelated")
+
+	if output := run("", "index", "trigrams", "on"); !strings.Contains(output, "trigram index on") {
+		t.Errorf("expected the trigram index to be turned on, got %s", output)
+	}
+	// added after the index was turned on
+	run("err_conn again", "add", "-name", "second log")
+	for _, tt := range []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"search", "-substring", "err_conn"}, []string{"proxy log", "second log"}},
+		{[]string{"search", "-substring", "-limit", "1", "err_conn"}, []string{"proxy log"}},
+		{[]string{"search", "-type", "substring", "conn_reset"}, []string{"proxy log"}},
+	} {
+		output := run("", tt.args...)
+		if lines := strings.Split(strings.TrimSpace(output), "\n"); len(lines) != len(tt.expected) {
+			t.Errorf("expected %d results for %v, got %s", len(tt.expected), tt.args, output)
+		}
+		for _, name := range tt.expected {
+			if !strings.Contains(output, name) {
+				t.Errorf("expected %q for %v, got %s", name, tt.args, output)
+			}
+		}
+	}
+
+	run("", "index", "trigrams", "off")
+	if output := run("", "search", "-substring", "err_conn"); !strings.Contains(output, "second log") {
+		t.Errorf("expected substring search without the trigram index, got %s", output)
+	}
+}
+
+func TestSearchWildcard(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "wildcard.sqlite3"))
+	run := func(stdin string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("expected nil err for %v, got %v: %s", args, err, output)
+		}
+		return string(output)
+	}
+	run("the deployment finished at noon", "add", "-name", "deploy notes")
+	run("settings live in config.yaml", "add", "-name", "settings")
+	run("nothing to see", "add", "-name", "unrelated")
+
+	for _, tt := range []struct {
+		term     string
+		expected string
+	}{
+		{"deploy*", "deploy notes"},
+		{"*.yaml", "settings"},
+		{"config.yaml", "settings"},
+	} {
+		output := run("", "search", tt.term)
+		if !strings.Contains(output, tt.expected) || strings.Contains(output, "unrelated") || strings.Count(output, "score:") != 1 {
+			t.Errorf("expected %q for %s, got %s", tt.expected, tt.term, output)
+		}
+	}
+}
+
+func TestSearchBoost(t *testing.T) {
+	dir := t.TempDir()
+	conf := path.Join(dir, "snip.json")
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "boost.sqlite3"), "SNIP_CONFIG="+conf)
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+	for _, s := range []struct {
+		name string
+		data string
+	}{
+		{"weekly notes", "postgres backup finished, postgres backups rotate weekly"},
+		{"postgres backup", "run the dump nightly and copy it offsite"},
+	} {
+		if output, err := run(s.data, "add", "-name", s.name); err != nil {
+			t.Fatalf("expected nil err, got %v: %s", err, output)
+		}
+	}
+
+	output, err := run("", "search", "postgres", "backup")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v: %s", err, output)
+	}
+	if !strings.HasPrefix(output, "postgres backup\n") || !strings.Contains(output, "[name: postgres, backup]") {
+		t.Errorf("expected the snip named with the terms first, got %s", output)
+	}
+
+	// counts of each term, with those in the name of the second snip
+	output, err = run("", "search", "-counts", "postgres", "backup")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v: %s", err, output)
+	}
+	for _, expected := range []string{"counts: postgres 0 (name), backup 0 (name), total 0", "counts: postgres 2, backup 2, total 4"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q, got %s", expected, output)
+		}
+	}
+
+	if err = os.WriteFile(conf, []byte(`{"search": {"boost": {"name": -1}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if output, err = run("", "search", "postgres"); err == nil || !strings.Contains(output, "search setting") {
+		t.Errorf("expected error for a negative boost, got %v: %s", err, output)
+	}
+}
+
+func TestSearchWithin(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "within.sqlite3"))
+	run := func(stdin string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("expected nil err for %v, got %v: %s", args, err, output)
+		}
+		return string(output)
+	}
+	run("gerbil feeding schedule", "add", "-name", "gerbil feeding")
+	run("gerbil bedding changes", "add", "-name", "gerbil bedding")
+	run("hamster feeding schedule", "add", "-name", "hamster feeding")
+
+	earlier := run("", "search", "gerbil")
+	for _, args := range [][]string{
+		{"search", "-within", "gerbil", "feeding"},
+		{"search", "-within", "-", "feeding"},
+	} {
+		output := run(earlier, args...)
+		if !strings.Contains(output, "gerbil feeding") || strings.Contains(output, "hamster") || strings.Contains(output, "bedding") {
+			t.Errorf("expected only the gerbil feeding snip for %v, got %s", args, output)
+		}
+	}
+
+	// uuids given directly, here those of the hamster
+	ids := strings.Fields(run("", "search", "-l", "hamster"))[2]
+	if output := run("", "search", "-within", ids, "feeding"); !strings.Contains(output, "hamster feeding") || strings.Contains(output, "gerbil") {
+		t.Errorf("expected only the hamster feeding snip, got %s", output)
+	}
+}
+
+func TestSearchTimeout(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "timeout.sqlite3"))
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+	for _, data := range []string{"timed lynx", "timed lynx again", "timed lynx once more"} {
+		cmd := exec.Command(appPath, "add")
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(data)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("expected nil err, got %v: %s", err, output)
+		}
+	}
+
+	output, err := run("search", "-timeout", "10s", "-limit", "2", "lynx")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v: %s", err, output)
+	}
+	if count := strings.Count(output, "score:"); count != 2 {
+		t.Errorf("expected 2 results, got %s", output)
+	}
+	if output, err = run("search", "-timeout", "-1s", "lynx"); err == nil || !strings.Contains(output, "positive -timeout") {
+		t.Errorf("expected error for a negative timeout, got %v: %s", err, output)
+	}
+}
+
+func TestSearchJSON(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "json.sqlite3"))
+	cmd := exec.Command(appPath, "add", "-name", "located")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("first line\nthe located ibex")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("expected nil err, got %v: %s", err, output)
+	}
+
+	cmd = exec.Command(appPath, "search", "-json", "ibex")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	var matches []struct {
+		Name    string
+		Context []struct {
+			Offset int
+			Length int
+			Line   int
+			Column int
+		}
+	}
+	if err = json.Unmarshal(output, &matches); err != nil {
+		t.Fatalf("expected json output, got %v: %s", err, output)
+	}
+	if len(matches) != 1 || matches[0].Name != "located" || len(matches[0].Context) != 1 {
+		t.Fatalf("expected one match with one context, got %+v", matches)
+	}
+	if c := matches[0].Context[0]; c.Offset != 23 || c.Length != 4 || c.Line != 2 || c.Column != 13 {
+		t.Errorf("expected ibex at offset 23, line 2, column 13, got %+v", c)
+	}
+
+	// no results are an empty array
+	cmd = exec.Command(appPath, "search", "-format", "json", "absent")
+	cmd.Env = env
+	if output, err = cmd.Output(); err != nil || strings.TrimSpace(string(output)) != "[]" {
+		t.Errorf("expected an empty array, got %v: %s", err, output)
+	}
+}
+
+func TestColor(t *testing.T) {
+	dir := t.TempDir()
+	conf := path.Join(dir, "snip.json")
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "color.sqlite3"), "SNIP_CONFIG="+conf)
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("colorful heron")
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	if _, err := run("add", "-name", "heron"); err != nil {
+		t.Fatal(err)
+	}
+
+	// output is not a terminal
+	for _, args := range [][]string{{"ls"}, {"search", "heron"}, {"ls", "-color", "never"}} {
+		if output, err := run(args...); err != nil || strings.Contains(output, "\x1b[") {
+			t.Errorf("expected plain output for %v, got %v: %q", args, err, output)
+		}
+	}
+	for _, tt := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"ls", "-color", "always"}, "\x1b[1mheron\x1b[0m"},
+		{[]string{"search", "-color", "always", "heron"}, "\x1b[31mheron\x1b[0m"},
+	} {
+		if output, err := run(tt.args...); err != nil || !strings.Contains(output, tt.expected) {
+			t.Errorf("expected %q for %v, got %v: %q", tt.expected, tt.args, err, output)
+		}
+	}
+
+	if err := os.WriteFile(conf, []byte(`{"color": "always", "theme": {"name": "none", "match": "bold yellow"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := run("search", "heron"); err != nil || !strings.Contains(output, "\x1b[1;33mheron") || strings.Contains(output, "\x1b[1mheron") {
+		t.Errorf("expected the colors of the theme, got %v: %q", err, output)
+	}
+	if err := os.WriteFile(conf, []byte(`{"theme": {"name": "sparkly"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run("ls"); err == nil {
+		t.Errorf("expected error for an unsupported theme color")
+	}
+}
+
+func TestPorcelain(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "porcelain.sqlite3"))
+	run := func(stdin string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected nil err for %v, got %v", args, err)
+		}
+		return string(output)
+	}
+	run("porcelain walrus", "add", "-name", "tabbed\tname")
+	file := path.Join(dir, "tusk.txt")
+	if err := os.WriteFile(file, []byte("ivory"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	id := strings.Fields(run("", "search", "-porcelain", "walrus"))[0]
+	run("", "attach", "add", id, file)
+
+	for _, tt := range []struct {
+		args   []string
+		fields int
+		name   string
+	}{
+		{[]string{"ls", "-porcelain"}, 3, `tabbed\tname`},
+		{[]string{"search", "-porcelain", "walrus"}, 3, `tabbed\tname`},
+		{[]string{"attach", "ls", "-porcelain"}, 4, "tusk.txt"},
+	} {
+		output := run("", tt.args...)
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		if len(lines) != 1 {
+			t.Fatalf("expected one record for %v, got %q", tt.args, output)
+		}
+		fields := strings.Split(lines[0], "\t")
+		if len(fields) != tt.fields || fields[len(fields)-1] != tt.name {
+			t.Errorf("expected %d fields ending with %q for %v, got %q", tt.fields, tt.name, tt.args, fields)
+		}
+	}
+	if fields := strings.Split(strings.TrimSpace(run("", "ls", "-porcelain")), "\t"); fields[0] != id {
+		t.Errorf("expected the full uuid %s, got %q", id, fields)
+	}
+}
+
+func TestSearchTokenizer(t *testing.T) {
+	dir := t.TempDir()
+	conf := path.Join(dir, "snip.json")
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "tokenizer.sqlite3"), "SNIP_CONFIG="+conf)
+	if err := os.WriteFile(conf, []byte(`{"search": {"tokenizer": "ngram"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(appPath, "add", "-name", "japanese")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("東京都に住んでいます")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	cmd = exec.Command(appPath, "search", "京都")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.HasPrefix(string(output), "japanese\n") {
+		t.Errorf("expected the snip to be found by a word within its text, got %s", output)
+	}
+
+	if err = os.WriteFile(conf, []byte(`{"search": {"tokenizer": "stems"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command(appPath, "ls")
+	cmd.Env = env
+	if output, err = cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "The search setting") {
+		t.Errorf("expected an unknown tokenizer to be refused, got %v: %s", err, output)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "exit.sqlite3"))
+	for _, name := range []string{"first", "second"} {
+		cmd := exec.Command(appPath, "add", "-name", name)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(name + " data\n")
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+	}
+
+	tests := []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{"ls"}, 0},
+		{[]string{"ls", "-h"}, 0},
+		{[]string{"get", "00000000"}, 2},
+		// every random uuid holds its version 4
+		{[]string{"get", "4"}, 3},
+		{[]string{"--no-input", "get", "4"}, 3},
+		{[]string{"--no-input", "ls"}, 0},
+		{[]string{"rm", "--yes", "00000000"}, 2},
+		{[]string{"attach", "rm", "00000000"}, 2},
+		{[]string{"ls", "-unknown"}, 4},
+		{[]string{"recent", "none"}, 4},
+		{[]string{"exit-code-test"}, 4},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(appPath, tt.args...)
+		cmd.Env = env
+		err := cmd.Run()
+		code := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		}
+		if code != tt.expected {
+			t.Errorf("%v: expected exit code %d, got %v", tt.args, tt.expected, err)
+		}
+	}
+
+	// the database is opened before any command runs
+	cmd := exec.Command(appPath, "ls")
+	cmd.Env = append(os.Environ(), "SNIP_DB="+path.Join(dir, "missing", "exit.sqlite3"))
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 5 {
+		t.Errorf("expected exit code 5 for a database that cannot be opened, got %v", err)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "confirm.sqlite3"))
+	run := func(stdin string, args ...string) (string, string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		return string(output), stderr.String(), err
+	}
+	var ids []string
+	for _, name := range []string{"first", "second", "third", "locked"} {
+		output, _, err := run(name+" old\n", "add", "-name", name)
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: ")))
+	}
+	if _, _, err := run("", "lock", ids[3]); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	// each piped answer goes to its own confirmation
+	output, _, err := run("y\nn\n", "rm", ids[0], ids[1])
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(output, "removed 1/2 "+ids[0]) || !strings.Contains(output, "skipped") {
+		t.Errorf("expected the first removed and the second skipped, got %q", output)
+	}
+
+	// without an answer nothing is changed
+	output, stderr, err := run("", "replace", "s/old/new/")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(output, "skipped locked") || !strings.HasSuffix(output, "skipped\n") || !strings.Contains(stderr, "use -yes") {
+		t.Errorf("expected the replacement declined, got %q and %q", output, stderr)
+	}
+
+	output, _, err = run("", "replace", "-f", "-y", "s/old/new/")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.HasSuffix(output, "replaced 3 matches in 3 snips\n") {
+		t.Errorf("expected the locked snip changed with -force, got %q", output)
+	}
+	if output, _, _ = run("", "get", "-raw", ids[3]); output != "locked new\n" {
+		t.Errorf("expected the locked snip changed, got %q", output)
+	}
+	if output, _, _ = run("", "lock"); !strings.Contains(output, "locked") {
+		t.Errorf("expected the snip locked again, got %q", output)
+	}
+
+	output, _, err = run("", "rm", "--force", "--yes", ids[3])
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(output, "removed 1/1 "+ids[3]) {
+		t.Errorf("expected the locked snip removed without asking, got %q", output)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	env := append(os.Environ(), "SNIP_DB="+path.Join(t.TempDir(), "alias.sqlite3"))
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("kubectl debug -it pod\n")
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	output, err := run("add", "-name", "debug a pod")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+
+	if output, err = run("alias", "set", "k8s-debug", id[:8]); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if output != "alias k8s-debug -> "+id[:8]+" debug a pod\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+	if output, err = run("get", "-raw", "k8s-debug"); err != nil || output != "kubectl debug -it pod\n" {
+		t.Errorf("expected the snip by its alias, got %q and %v", output, err)
+	}
+	if output, err = run("alias", "ls", "-porcelain"); err != nil || output != "k8s-debug\t"+id+"\tdebug a pod\n" {
+		t.Errorf("expected the alias listed, got %q and %v", output, err)
+	}
+	if output, err = run("completion", "bash"); err != nil || !strings.Contains(output, "snip alias ls -porcelain") {
+		t.Errorf("expected the completion script to complete aliases, got %q and %v", output, err)
+	}
+
+	// an alias that could be part of a uuid is refused
+	_, err = run("alias", "set", "beef", id)
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit code 4, got %v", err)
+	}
+
+	if _, err = run("alias", "rm", "k8s-debug"); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	_, err = run("get", "k8s-debug")
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+		t.Errorf("expected exit code 2 for a removed alias, got %v", err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	root := t.TempDir()
+	project := path.Join(root, "project")
+	nested := path.Join(project, "src")
+	if err := os.MkdirAll(nested, 0700); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(path.Join(project, ".snip"), []byte(`{"tags": ["project"], "database": "notes.sqlite3"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// without $SNIP_DB the database of the context is used
+	env := append(os.Environ(), "SNIP_DB=", "HOME="+root)
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Dir = nested
+		cmd.Stdin = strings.NewReader(strings.Join(args, " "))
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v: expected nil err, got %v", args, err)
+		}
+		return string(output)
+	}
+	tagged := strings.TrimSpace(strings.TrimPrefix(run("add", "-tag", "extra"), "added snip uuid: "))
+	untagged := strings.TrimSpace(strings.TrimPrefix(run("add", "-no-context"), "added snip uuid: "))
+
+	if output := run("tag", tagged); output != "extra project\n" {
+		t.Errorf("expected the tags of the context and the arguments, got %q", output)
+	}
+	if output := run("tag", untagged); output != "\n" {
+		t.Errorf("expected no tags with -no-context, got %q", output)
+	}
+	if _, err = os.Stat(path.Join(project, "notes.sqlite3")); err != nil {
+		t.Errorf("expected the database of the context, got %v", err)
+	}
+	if output := run("context"); !strings.Contains(output, "context: "+path.Join(project, ".snip")+"\ntags: project\n") {
+		t.Errorf("unexpected context %q", output)
+	}
+
+	// the database of the context is never used unnoticed
+	cmd := exec.Command(appPath, "ls")
+	cmd.Env = env
+	cmd.Dir = nested
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	expected := "using the database " + path.Join(project, "notes.sqlite3") + " of " + path.Join(project, ".snip") + "\n"
+	if !strings.HasPrefix(stderr.String(), expected) {
+		t.Errorf("expected %q, got %q", expected, stderr.String())
+	}
+}
+
+func TestGitHook(t *testing.T) {
+	dir := t.TempDir()
+	repo := path.Join(dir, "journal-repo")
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "hook.sqlite3"),
+		"GIT_AUTHOR_NAME=tester", "GIT_AUTHOR_EMAIL=tester@example.com",
+		"GIT_COMMITTER_NAME=tester", "GIT_COMMITTER_EMAIL=tester@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null")
+	run := func(name string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(name, args...)
+		cmd.Env = env
+		cmd.Dir = repo
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s %v: expected nil err, got %v: %s", name, args, err, output)
+		}
+		return string(output)
+	}
+	if err := os.Mkdir(repo, 0700); err != nil {
+		t.Fatal(err)
+	}
+	run("git", "init", "-q")
+	if output := run(appPath, "hook", "install", "-diff", "TODO", "-tag", "journal"); !strings.Contains(output, "installed ") {
+		t.Errorf("unexpected output %q", output)
+	}
+
+	for _, commit := range []struct{ file, data, message string }{
+		{"plain.txt", "plain\n", "Add plain file\n\nNothing to see here."},
+		{"todo.txt", "TODO: finish\n", "Add todo list"},
+	} {
+		if err := os.WriteFile(path.Join(repo, commit.file), []byte(commit.data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		run("git", "add", commit.file)
+		run("git", "commit", "-q", "-m", commit.message)
+	}
+
+	output := run(appPath, "ls", "-porcelain")
+	if strings.Count(output, "\n") != 2 || !strings.Contains(output, "\tAdd plain file\n") || !strings.Contains(output, "\tAdd todo list\n") {
+		t.Fatalf("expected a snip of each commit, got %q", output)
+	}
+	var id string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasSuffix(line, "\tAdd todo list") {
+			id = strings.Split(line, "\t")[0]
+		}
+	}
+	if output = run(appPath, "tag"); output != "    2 commit\n    2 journal\n    2 journal-repo\n" {
+		t.Errorf("expected the commits tagged, got %q", output)
+	}
+	if output = run(appPath, "get", "-raw", id); !strings.HasPrefix(output, "Add todo list\n\ndiff --git") || !strings.Contains(output, "+TODO: finish") {
+		t.Errorf("expected the diff matching the pattern after the message, got %q", output)
+	}
+
+	// hooks snip did not install are left alone
+	run(appPath, "hook", "uninstall")
+	hook := path.Join(repo, ".git", "hooks", "post-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\necho mine\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(appPath, "hook", "install")
+	cmd.Env = env
+	cmd.Dir = repo
+	if err := cmd.Run(); err == nil {
+		t.Errorf("expected an existing hook to be refused without -force")
+	}
+	run(appPath, "hook", "install", "-force")
+}
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+	backups := path.Join(dir, "backups")
+	conf := path.Join(dir, "config.json")
+	err := os.WriteFile(conf, []byte(`{"backup": {"dir": "`+backups+`", "interval": "1d", "keep": {"daily": 1}}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "backup.sqlite3"), "SNIP_CONFIG="+conf)
+
+	cmd := exec.Command(appPath, "add", "-n", "kept")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("backed up data")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("expected nil err, got %v: %s", err, output)
+	}
+	if err = os.MkdirAll(backups, 0700); err != nil {
+		t.Fatal(err)
+	}
+	old := path.Join(backups, "snip-20200101T000000Z.sqlite3")
+	if err = os.WriteFile(old, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd = exec.Command(appPath, "backup")
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 || lines[1] != "removed "+old {
+		t.Fatalf("expected the backup and the old one removed, got %q", output)
+	}
+
+	// the backup is a complete database
+	cmd = exec.Command(appPath, "ls")
+	cmd.Env = append(env, "SNIP_DB="+lines[0])
+	if output, err = cmd.Output(); err != nil || !strings.Contains(string(output), "kept") {
+		t.Errorf("expected the snip in the backup, got %v: %q", err, output)
+	}
+
+	err = os.WriteFile(conf, []byte(`{"backup": {"interval": "often"}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command(appPath, "backup")
+	cmd.Env = env
+	if err = cmd.Run(); err == nil || cmd.ProcessState.ExitCode() != 4 {
+		t.Errorf("expected exit code 4 for an invalid interval, got %v", err)
+	}
+}
+
+func TestIncrementalBackup(t *testing.T) {
+	dir := t.TempDir()
+	backups := path.Join(dir, "incremental")
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "incremental.sqlite3"))
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("incremental data")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v: expected nil err, got %v", args, err)
+		}
+		return string(output)
+	}
+	run("add", "-n", "first")
+	run("backup", "-incremental", backups)
+	// an unchanged database writes no chunks
+	if output := run("backup", "-incremental", backups); !strings.Contains(output, "\nwrote 0 of ") {
+		t.Errorf("expected no chunks written, got %q", output)
+	}
+	if output := run("backup", "verify", backups); !strings.HasPrefix(output, "verified ") || !strings.HasSuffix(output, ", 0 problems\n") {
+		t.Errorf("expected no problems, got %q", output)
+	}
+
+	restored := path.Join(dir, "restored.sqlite3")
+	run("backup", "restore", backups, "latest", restored)
+	cmd := exec.Command(appPath, "ls")
+	cmd.Env = append(env, "SNIP_DB="+restored)
+	if output, err := cmd.Output(); err != nil || !strings.Contains(string(output), "first") {
+		t.Errorf("expected the snip in the restored database, got %v: %q", err, output)
+	}
+	// an existing file is not overwritten
+	cmd = exec.Command(appPath, "backup", "restore", backups, "latest", restored)
+	cmd.Env = env
+	if err := cmd.Run(); err == nil {
+		t.Errorf("expected error restoring over an existing file")
+	}
+
+	chunks, err := filepath.Glob(path.Join(backups, "chunks", "*", "*"))
+	if err != nil || len(chunks) == 0 {
+		t.Fatalf("expected chunks, got %v", err)
+	}
+	if err = os.WriteFile(chunks[0], []byte("damaged"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command(appPath, "backup", "verify", backups)
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err == nil || cmd.ProcessState.ExitCode() != 1 || !strings.Contains(string(output), "changed since it was written") {
+		t.Errorf("expected the damaged chunk reported, got %v: %q", err, output)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "snapshot.sqlite3"))
+	run := func(stdin string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v: expected nil err, got %v", args, err)
+		}
+		return string(output)
+	}
+	run("kept", "add", "-n", "kept")
+	if output := run("", "snapshot", "create", "before-import"); output != "created snapshot before-import of 1 snips\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+	run("discarded", "add", "-n", "discarded")
+
+	// declining keeps every change
+	if output := run("n\n", "snapshot", "rollback", "before-import"); output != "skipped\n" {
+		t.Errorf("expected rollback skipped, got %q", output)
+	}
+	if output := run("", "snapshot", "rollback", "-y", "before-import"); output != "rolled back to snapshot before-import of 1 snips\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+	if output := run("", "ls"); !strings.Contains(output, "kept") || strings.Contains(output, "discarded") {
+		t.Errorf("expected only the snip added before the snapshot, got %q", output)
+	}
+	if output := run("", "snapshot", "ls"); !strings.Contains(output, "before-import") {
+		t.Errorf("expected the snapshot listed, got %q", output)
+	}
+	run("", "snapshot", "rm", "before-import")
+
+	cmd := exec.Command(appPath, "snapshot", "rollback", "-y", "before-import")
+	cmd.Env = env
+	if err := cmd.Run(); err == nil || cmd.ProcessState.ExitCode() != 2 {
+		t.Errorf("expected exit code 2 for a missing snapshot, got %v", err)
+	}
+}
+
+func TestExportSQL(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "dump.sqlite3"))
+	cmd := exec.Command(appPath, "add", "-n", "dumped")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("it's dumped")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("expected nil err, got %v: %s", err, output)
+	}
+
+	dump := path.Join(dir, "snips.sql")
+	cmd = exec.Command(appPath, "export", "sql", "-schema", "-o", dump)
+	cmd.Env = env
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("expected nil err, got %v: %s", err, output)
+	}
+	data, err := os.ReadFile(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "CREATE TABLE IF NOT EXISTS snip(") || !strings.Contains(string(data), "CAST('it''s dumped' AS BLOB)") {
+		t.Errorf("expected the schema and the snip, got %s", data)
+	}
+	if !strings.HasSuffix(string(data), "COMMIT;\n") {
+		t.Errorf("expected the dump to end the transaction")
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	dir := t.TempDir()
+	run := func(db string, stdin string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = append(os.Environ(), "SNIP_DB="+path.Join(dir, db))
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v: expected nil err, got %v", args, err)
+		}
+		return string(output)
+	}
+	added := run("source.sqlite3", "---\nfields: data that looks like front matter\n---\n", "add", "-n", "Wrens: notes", "-tag", "birds,field", "-timestamp", "2024-03-31 22:00")
+	id := strings.TrimSpace(strings.TrimPrefix(added, "added snip uuid: "))
+
+	exported := path.Join(dir, "vault")
+	if output := run("source.sqlite3", "", "export", "markdown", exported); output != "1 snips written to "+exported+"\n" {
+		t.Errorf("unexpected output %q", output)
+	}
+	data, err := os.ReadFile(path.Join(exported, "Wrens- notes.md"))
+	if err != nil {
+		t.Fatalf("expected the snip written to a file named after it, got %v", err)
+	}
+	if !strings.HasPrefix(string(data), "---\nuuid: "+id+"\n") {
+		t.Errorf("expected front matter, got %s", data)
+	}
+
+	// the export imports into another database unchanged, and only once
+	if output := run("copy.sqlite3", "", "import", "dir", exported); !strings.Contains(output, "imported 1 snips") {
+		t.Errorf("unexpected output %q", output)
+	}
+	if output := run("copy.sqlite3", "", "import", "dir", exported); !strings.Contains(output, "skipped 1 duplicates") {
+		t.Errorf("expected the snip skipped as a duplicate, got %q", output)
+	}
+	for _, args := range [][]string{{"get", id}, {"tag", id}, {"ls", "-l"}} {
+		expected, actual := run("source.sqlite3", "", args...), run("copy.sqlite3", "", args...)
+		if actual != expected {
+			t.Errorf("%v: expected %q, got %q", args, expected, actual)
+		}
+	}
+}
+
+func TestExportWikiLinks(t *testing.T) {
+	dir := t.TempDir()
+	run := func(db string, stdin string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = append(os.Environ(), "SNIP_DB="+path.Join(dir, db))
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v: expected nil err, got %v", args, err)
+		}
+		return string(output)
+	}
+	added := run("source.sqlite3", "small brown birds\n", "add", "-n", "wrens")
+	wrens := strings.TrimSpace(strings.TrimPrefix(added, "added snip uuid: "))
+	linking := "see [the wrens](snip://" + wrens + ")\n"
+	added = run("source.sqlite3", linking, "add", "-n", "birds")
+	birds := strings.TrimSpace(strings.TrimPrefix(added, "added snip uuid: "))
+
+	vault := path.Join(dir, "vault")
+	run("source.sqlite3", "", "export", "markdown", vault)
+	data, err := os.ReadFile(path.Join(vault, "birds.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "---\nsee [[wrens|the wrens]]\n") {
+		t.Errorf("expected a wiki link, got %s", data)
+	}
+	run("copy.sqlite3", "", "import", "dir", vault)
+	if output := run("copy.sqlite3", "", "get", "-raw", birds); output != linking {
+		t.Errorf("expected the snip link restored %q, got %q", linking, output)
+	}
+
+	// notes written by other tools link to the snips of their files
+	notes := path.Join(dir, "notes")
+	if err = os.MkdirAll(notes, 0700); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path.Join(notes, "robins.md"), []byte("red breasted\n"), 0600)
+	os.WriteFile(path.Join(notes, "garden.md"), []byte("---\ntags: garden\n---\nvisited by [[robins]]\n"), 0600)
+	run("notes.sqlite3", "", "import", "dir", notes)
+	var garden, robins string
+	for _, line := range strings.Split(strings.TrimSpace(run("notes.sqlite3", "", "ls", "-porcelain")), "\n") {
+		fields := strings.Split(line, "\t")
+		switch fields[len(fields)-1] {
+		case "garden":
+			garden = fields[0]
+		case "robins":
+			robins = fields[0]
+		}
+	}
+	if output := run("notes.sqlite3", "", "get", "-raw", garden); output != "visited by snip://"+robins+"\n" {
+		t.Errorf("expected a link to the robins snip %s, got %q", robins, output)
+	}
+	if output := run("notes.sqlite3", "", "tag", garden); output != "garden\n" {
+		t.Errorf("expected the tags of the front matter, got %q", output)
+	}
+}
+
+func TestAttachView(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "view.sqlite3"))
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("screenshots\n")
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	added, err := run("add", "-n", "screenshots")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(added, "added snip uuid: "))
+	var buf strings.Builder
+	if err = png.Encode(&buf, image.NewGray(image.Rect(0, 0, 300, 200))); err != nil {
+		t.Fatal(err)
+	}
+	imageFile, textFile := path.Join(dir, "error.png"), path.Join(dir, "error.txt")
+	os.WriteFile(imageFile, []byte(buf.String()), 0600)
+	os.WriteFile(textFile, []byte("segmentation fault\n"), 0600)
+	if _, err = run("attach", "add", id, imageFile, textFile); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	listing, err := run("attach", "ls", "-porcelain")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	attachments := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(listing), "\n") {
+		fields := strings.Split(line, "\t")
+		attachments[fields[3]] = fields[0]
+	}
+
+	for _, tt := range []struct {
+		protocol string
+		prefix   string
+		suffix   string
+	}{
+		{"kitty", "\x1b_Ga=T,f=100,", "\x1b\\\n"},
+		{"iterm", "\x1b]1337;File=name=" + base64.StdEncoding.EncodeToString([]byte("error.png")) + ";", "\a\n"},
+		{"sixel", "\x1bP0;1;0q\"1;1;50;33#0;2;0;0;0", "\x1b\\\n"},
+	} {
+		output, err := run("attach", "view", "-protocol", tt.protocol, "-size", "50", attachments["error.png"])
+		if err != nil {
+			t.Fatalf("%s: expected nil err, got %v", tt.protocol, err)
+		}
+		if !strings.HasPrefix(output, tt.prefix) || !strings.HasSuffix(output, tt.suffix) {
+			t.Errorf("%s: expected an inline image, got %q", tt.protocol, output)
+		}
+	}
+
+	// only images are shown inline
+	_, err = run("attach", "view", "-protocol", "sixel", attachments["error.txt"])
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit code 4 for an attachment that is not an image, got %v", err)
+	}
+	_, err = run("attach", "view", "-protocol", "braille", attachments["error.png"])
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit code 4 for an unknown protocol, got %v", err)
+	}
+}
+
+func TestAttachOCR(t *testing.T) {
+	dir := t.TempDir()
+	conf := path.Join(dir, "snip.json")
+	// a stand-in for tesseract that names the file it was given
+	if err := os.WriteFile(conf, []byte(`{"ocr": {"enabled": true, "command": "echo kernel panic in $(basename $SNIP_ATTACHMENT_FILE .png)"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "ocr.sqlite3"), "SNIP_CONFIG="+conf)
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("crash report\n")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v: expected nil err, got %v", args, err)
+		}
+		return string(output)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(run("add", "-n", "crash"), "added snip uuid: "))
+	screenshot, notes := path.Join(dir, "screenshot.png"), path.Join(dir, "notes.txt")
+	os.WriteFile(screenshot, []byte("pixels"), 0600)
+	os.WriteFile(notes, []byte("notes"), 0600)
+
+	output := run("attach", "add", id, screenshot, notes)
+	if !strings.Contains(output, "read 4 words from "+screenshot+"\n") || strings.Contains(output, "from "+notes) {
+		t.Errorf("expected the text of the image read, got %s", output)
+	}
+	if output = run("search", "-porcelain", "panic"); !strings.HasPrefix(output, id+"\t") {
+		t.Errorf("expected the snip found by the text of its screenshot, got %q", output)
+	}
+	var attachment string
+	for _, line := range strings.Split(strings.TrimSpace(run("attach", "ls", "-porcelain")), "\n") {
+		if fields := strings.Split(line, "\t"); fields[3] == "screenshot.png" {
+			attachment = fields[0]
+		}
+	}
+	if output = run("attach", "ocr", "-show", attachment); output != "kernel panic in screenshot\n" {
+		t.Errorf("expected the text read, got %q", output)
+	}
+
+	// images already read are skipped unless named
+	if output = run("attach", "ocr"); output != "" {
+		t.Errorf("expected nothing left to read, got %q", output)
+	}
+	if output = run("attach", "ocr", attachment); output != "read 4 words from "+attachment+" screenshot.png\n" {
+		t.Errorf("expected the image read again, got %q", output)
+	}
+}
+
+func TestAttachTranscribe(t *testing.T) {
+	dir := t.TempDir()
+	conf := path.Join(dir, "snip.json")
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "transcribe.sqlite3"), "SNIP_CONFIG="+conf)
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	memo, notes := path.Join(dir, "memo.m4a"), path.Join(dir, "meeting.ogg")
+	os.WriteFile(memo, []byte("audio"), 0600)
+	os.WriteFile(notes, []byte("audio"), 0600)
+
+	output, _ := run("", "add", "-n", "voice memo")
+	memoID := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+	output, _ = run("agenda\n", "add", "-n", "meeting")
+	meetingID := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+
+	// without a command audio is attached without a transcript
+	if _, err := run("", "attach", "add", meetingID, notes); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	_, err := run("", "attach", "transcribe")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit code 4 without a transcription command, got %v", err)
+	}
+
+	if err = os.WriteFile(conf, []byte(`{"transcription": {"command": "echo remember the $(basename $SNIP_ATTACHMENT_FILE)"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if output, err = run("", "attach", "add", memoID, memo); err != nil || !strings.HasSuffix(output, "transcribed 2 words from "+memo+"\n") {
+		t.Errorf("expected the memo transcribed, got %q %v", output, err)
+	}
+	// the transcript becomes the data of a snip without any
+	if output, _ = run("", "get", "-raw", memoID); output != "remember the memo.m4a\n" {
+		t.Errorf("expected the transcript as data, got %q", output)
+	}
+	if output, _ = run("", "attach", "transcribe"); !strings.HasSuffix(output, " meeting.ogg\n") || !strings.HasPrefix(output, "transcribed 2 words from ") {
+		t.Errorf("expected the earlier audio transcribed, got %q", output)
+	}
+	if output, _ = run("", "get", "-raw", meetingID); output != "agenda\n" {
+		t.Errorf("expected the data of the meeting kept, got %q", output)
+	}
+	if output, _ = run("", "search", "-porcelain", "meeting.ogg"); !strings.HasPrefix(output, meetingID+"\t") {
+		t.Errorf("expected the meeting found by its transcript, got %q", output)
+	}
+}
+
+func TestAttachBlobs(t *testing.T) {
+	dir := t.TempDir()
+	conf := path.Join(dir, "snip.json")
+	if err := os.WriteFile(conf, []byte(`{"attachments": {"blobs": true}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	db := path.Join(dir, "blobs.sqlite3")
+	env := append(os.Environ(), "SNIP_DB="+db, "SNIP_CONFIG="+conf)
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("meeting notes\n")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%v: expected nil err, got %v", args, err)
+		}
+		return string(output)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(run("add", "-n", "meeting"), "added snip uuid: "))
+	recording := path.Join(dir, "meeting.m4a")
+	os.WriteFile(recording, []byte("a long recording"), 0600)
+	run("attach", "add", id, recording)
+
+	// blobs are named by the sha-256 digest of their data
+	sum := sha256.Sum256([]byte("a long recording"))
+	blob := path.Join(db+".blobs", hex.EncodeToString(sum[:1]), hex.EncodeToString(sum[:]))
+	if data, err := os.ReadFile(blob); err != nil || string(data) != "a long recording" {
+		t.Errorf("expected the attachment kept in a blob, got %q %v", data, err)
+	}
+	attachment := strings.Split(run("attach", "ls", "-porcelain"), "\t")[0]
+	if output := run("attach", "stdout", attachment); output != "a long recording" {
+		t.Errorf("expected the data read from the blob, got %q", output)
+	}
+	if output := run("verify", id); !strings.Contains(output, "0 mismatches") {
+		t.Errorf("expected the blob verified, got %s", output)
+	}
+
+	if output := run("attach", "blobs", "-restore"); output != "moved 1 attachments to the database\n" {
+		t.Errorf("expected the data moved to the database, got %q", output)
+	}
+	if _, err := os.Stat(blob); !os.IsNotExist(err) {
+		t.Errorf("expected the blob deleted, got %v", err)
+	}
+	if output := run("attach", "blobs"); output != "moved 1 attachments to "+db+".blobs\n" {
+		t.Errorf("expected the data moved to the blob directory, got %q", output)
+	}
+
+	// a database the snip is moved to holds the data itself
+	there := path.Join(dir, "there.sqlite3")
+	run("mv-db", id, "-to", there)
+	if _, err := os.Stat(blob); !os.IsNotExist(err) {
+		t.Errorf("expected the blob deleted with the moved attachment, got %v", err)
+	}
+	cmd := exec.Command(appPath, "attach", "stdout", attachment)
+	cmd.Env = append(os.Environ(), "SNIP_DB="+there, "SNIP_CONFIG="+path.Join(dir, "none.json"))
+	if output, err := cmd.Output(); err != nil || string(output) != "a long recording" {
+		t.Errorf("expected the data in the target database, got %q %v", output, err)
+	}
+}
+
+func TestDu(t *testing.T) {
+	dir := t.TempDir()
+	conf := path.Join(dir, "snip.json")
+	if err := os.WriteFile(conf, []byte(`{"attachments": {"max_size": "1K", "quota": "2K"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "du.sqlite3"), "SNIP_CONFIG="+conf)
+	run := func(args ...string) (string, string, error) {
+		t.Helper()
+		var stderr strings.Builder
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("downloads\n")
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		return string(output), stderr.String(), err
+	}
+	status := func(err error) int {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 0
+	}
+	output, _, err := run("add", "-n", "downloads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+	big, small := path.Join(dir, "big.iso"), path.Join(dir, "small.bin")
+	os.WriteFile(big, make([]byte, 1500), 0600)
+	os.WriteFile(small, make([]byte, 1000), 0600)
+
+	_, stderr, err := run("attach", "add", id, big)
+	if code := status(err); code != 4 || !strings.Contains(stderr, "big.iso was not attached, attachment is too large: 1.5K is larger than the limit of 1.0K") {
+		t.Errorf("expected the file refused with status 4, got %d %q", code, stderr)
+	}
+	if _, stderr, err = run("attach", "add", id, small); err != nil || stderr != "" {
+		t.Errorf("expected the file attached without warning, got %v %q", err, stderr)
+	}
+	// past 90 percent of the quota each attachment warns
+	if _, stderr, err = run("attach", "add", id, small); err != nil || !strings.Contains(stderr, "warning: 2.0K of the 2.0K storage quota is used") {
+		t.Errorf("expected a warning of the quota nearly used, got %v %q", err, stderr)
+	}
+	_, stderr, err = run("attach", "add", id, small)
+	if code := status(err); code != 4 || !strings.Contains(stderr, "storage quota exceeded") {
+		t.Errorf("expected the quota enforced with status 4, got %d %q", code, stderr)
+	}
+
+	output, _, err = run("du", "--top", "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "   size attachments  snip\n" +
+		"   2.0K     2  2.0K  " + id[:8] + " downloads\n" +
+		"   2.0K total of the 2.0K quota (98%)\n"
+	if output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestAttachMove(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "mv.sqlite3"), "SNIP_CONFIG="+path.Join(dir, "none.json"))
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("release notes\n")
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	var ids []string
+	for _, name := range []string{"wrong", "right"} {
+		output, err := run("add", "-n", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: ")))
+	}
+	media := path.Join(dir, "media.bin")
+	os.WriteFile(media, []byte("large media"), 0600)
+	if _, err := run("attach", "add", ids[0], media); err != nil {
+		t.Fatal(err)
+	}
+	output, err := run("attach", "ls", "-porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachment := strings.Split(output, "\t")[0]
+
+	output, err = run("attach", "mv", attachment[:8], ids[1][:8])
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if expected := fmt.Sprintf("moved %s media.bin from snip %s to %s right\n", attachment, ids[0][:8], ids[1][:8]); output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+	if output, _ = run("attach", "ls", "-porcelain"); !strings.HasPrefix(output, attachment+"\t"+ids[1]+"\t") {
+		t.Errorf("expected the attachment to belong to %s, got %q", ids[1], output)
+	}
+	if _, err = run("attach", "mv", attachment, "00000000"); err == nil {
+		t.Errorf("expected an error moving to a snip that does not exist")
+	}
+}
+
+func TestAttachPromote(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "promote.sqlite3"), "SNIP_CONFIG="+path.Join(dir, "none.json"))
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader("imported mail\n")
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	output, err := run("add", "-n", "inbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+	message, image := path.Join(dir, "message.eml"), path.Join(dir, "logo.png")
+	os.WriteFile(message, []byte("Subject: deploy failed\n\nthe canary rolled back\n"), 0600)
+	os.WriteFile(image, []byte{0x89, 'P', 'N', 'G', 0, 0}, 0600)
+	if _, err = run("attach", "add", parent, message, image); err != nil {
+		t.Fatal(err)
+	}
+	attachments := make(map[string]string)
+	output, _ = run("attach", "ls", "-porcelain")
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Split(line, "\t")
+		attachments[fields[3]] = fields[0]
+	}
+
+	output, err = run("attach", "promote", attachments["message.eml"], "-n", "deploy failure", "-rm")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	lines := strings.Split(output, "\n")
+	id := strings.TrimPrefix(lines[0], "added snip uuid: ")
+	if lines[1] != "removed attachment "+attachments["message.eml"]+" message.eml" {
+		t.Errorf("expected the attachment removed, got %q", output)
+	}
+	if output, _ = run("get", "-raw", id); output != "Subject: deploy failed\n\nthe canary rolled back\n" {
+		t.Errorf("expected the text of the attachment, got %q", output)
+	}
+	if output, _ = run("search", "-porcelain", "canary"); !strings.HasPrefix(output, id+"\t") || !strings.Contains(output, "deploy failure") {
+		t.Errorf("expected the new snip indexed, got %q", output)
+	}
+	if output, _ = run("get", id); !strings.Contains(output, "snip://"+parent) {
+		t.Errorf("expected a link back to %s, got %s", parent, output)
+	}
+
+	_, err = run("attach", "promote", attachments["logo.png"])
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 promoting an image, got %v", err)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "blocks.sqlite3"), "SNIP_CONFIG="+path.Join(dir, "none.json"))
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	notes := "Count the lines.\n```bash\necho one\necho two | wc -l\n```\n\nQuery:\n```sql\nSELECT 1;\n```\n\n```sh\nread name; echo \"hello $name\"; exit 3\n```\n"
+	output, err := run(notes, "add", "-n", "runbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+
+	if output, _ = run("", "blocks", id); output != "echo one\necho two | wc -l\n\nSELECT 1;\n\nread name; echo \"hello $name\"; exit 3\n" {
+		t.Errorf("expected every block, got %q", output)
+	}
+	if output, _ = run("", "blocks", id, "--lang", "SQL"); output != "SELECT 1;\n" {
+		t.Errorf("expected the sql block, got %q", output)
+	}
+	expected := "  1 bash       line 2    echo one\n  2 sql        line 8    SELECT 1;\n  3 sh         line 12   read name; echo \"hello $name\"; exit 3\n"
+	if output, _ = run("", "blocks", "-l", id); output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+	if output, err = run("", "blocks", id, "-index", "1", "-run"); err != nil || output != "one\n1\n" {
+		t.Errorf("expected the output of the bash block, got %q %v", output, err)
+	}
+	// the block reads standard input and exits as it does
+	output, err = run("world\n", "blocks", id, "-lang", "sh", "-run")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 || output != "hello world\n" {
+		t.Errorf("expected the sh block to exit 3, got %q %v", output, err)
+	}
+	if _, err = run("", "blocks", id, "-run"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 running several blocks, got %v", err)
+	}
+	if _, err = run("", "blocks", id, "-lang", "sql", "-run"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 running a block without an interpreter, got %v", err)
+	}
+	if _, err = run("", "blocks", id, "-index", "4"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+		t.Errorf("expected exit status 2 for a block that does not exist, got %v", err)
+	}
+}
+
+func TestListLang(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "lang.sqlite3"), "SNIP_CONFIG="+path.Join(dir, "none.json"))
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	add := func(name string, data string) string {
+		t.Helper()
+		output, err := run(data, "add", "-n", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+	}
+	add("server", "package main\n\nfunc main() {\n\tif err != nil {\n\t\tpanic(err)\n\t}\n}\n")
+	script := add("cleanup", "#!/bin/sh\necho cleaned\nexit 5\n")
+	add("groceries", "milk and bread")
+
+	if output, err := run("", "ls", "-lang", "go", "-columns", "name,lang"); err != nil || output != "server go\n" {
+		t.Errorf("expected the go snip, got %q %v", output, err)
+	}
+	if output, err := run("", "ls", "-lang", "rust"); err != nil || output != "" {
+		t.Errorf("expected no snips, got %q %v", output, err)
+	}
+	// a script without fenced blocks is run whole with the interpreter of its language
+	output, err := run("", "blocks", script, "-run")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 5 || output != "cleaned\n" {
+		t.Errorf("expected the script to exit 5, got %q %v", output, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	conf := `{"checkers": [{"name": "spell", "command": "grep -o servise \"$SNIP_FILE\"", "words": true}]}`
+	if err := os.WriteFile(path.Join(dir, "config.json"), []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "check.sqlite3"), "SNIP_CONFIG="+path.Join(dir, "config.json"))
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	output, err := run("Restart the\nservise first.\n", "add", "-n", "runbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+
+	output, err = run("", "check", id)
+	var exitErr *exec.ExitError
+	expected := "2:1     spell: servise\n        servise first.\n1 problems found in " + id + " runbook\n"
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 || output != expected {
+		t.Errorf("expected %q and exit status 1, got %q %v", expected, output, err)
+	}
+	if _, err = run("", "check", id, "-checker", "lint"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 for a checker not configured, got %v", err)
+	}
+}
+
+func TestAskSummarize(t *testing.T) {
+	var prompts []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		prompts = append(prompts, request.Messages[1].Content)
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]string{"role": "assistant", "content": "It listens on 8080."}})
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	conf := `{"llm": {"provider": "ollama", "url": "` + ts.URL + `", "model": "test"}}`
+	if err := os.WriteFile(path.Join(dir, "config.json"), []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "ask.sqlite3"), "SNIP_CONFIG="+path.Join(dir, "config.json"))
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	output, err := run("The proxy listens on port 8080.", "add", "-n", "proxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+	if _, err = run("Wrens nest in shrubs.", "add", "-n", "wrens"); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing is sent unless confirmed
+	if output, err = run("n\n", "summarize", id); err != nil || output != "skipped\n" || len(prompts) != 0 {
+		t.Errorf("expected the summary declined, got %q %v with %d requests", output, err, len(prompts))
+	}
+	if output, err = run("", "summarize", id, "-yes", "-save"); err != nil || !strings.HasPrefix(output, "It listens on 8080.\nadded note 1 to "+id) {
+		t.Errorf("expected the summary saved as a note, got %q %v", output, err)
+	}
+
+	output, err = run("y\n", "ask", "which", "port", "does", "the", "proxy", "use", "-save")
+	if err != nil || !strings.HasPrefix(output, "It listens on 8080.\nadded snip uuid: ") {
+		t.Fatalf("expected the answer saved as a snip, got %q %v", output, err)
+	}
+	if len(prompts) != 2 || !strings.Contains(prompts[1], "## proxy ("+id+")") || strings.Contains(prompts[1], "wrens") {
+		t.Errorf("expected only the proxy snip sent, got %q", prompts)
+	}
+	answer := strings.TrimSpace(strings.TrimPrefix(strings.SplitN(output, "\n", 2)[1], "added snip uuid: "))
+	if output, err = run("", "get", "-raw", answer); err != nil || !strings.Contains(output, "- [proxy](snip://"+id+")") {
+		t.Errorf("expected the answer to link the proxy snip, got %q %v", output, err)
+	}
+
+	var exitErr *exec.ExitError
+	if _, err = run("", "ask", "-yes", "-within", "zebra", "what", "about", "zebras"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+		t.Errorf("expected exit status 2 without snips to ask about, got %v", err)
+	}
+	env = append(env, "SNIP_CONFIG="+path.Join(dir, "none.json"))
+	if _, err = run("", "summarize", id, "-yes"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 without a provider, got %v", err)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	var systems []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		systems = append(systems, request.Messages[0].Content)
+		reply := "Guten Morgen"
+		if len(systems) > 1 {
+			reply = "Guten Tag"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": map[string]string{"role": "assistant", "content": reply}})
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	conf := `{"llm": {"provider": "ollama", "url": "` + ts.URL + `", "model": "test"}}`
+	if err := os.WriteFile(path.Join(dir, "config.json"), []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "translate.sqlite3"), "SNIP_CONFIG="+path.Join(dir, "config.json"))
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	output, err := run("Good morning", "add", "-n", "greeting", "-tag", "phrases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+
+	output, err = run("", "translate", id, "-to", "DE", "-yes")
+	if err != nil || !strings.HasPrefix(output, "added snip uuid: ") {
+		t.Fatalf("expected the translation added, got %q %v", output, err)
+	}
+	translation := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+	if len(systems) != 1 || !strings.Contains(systems[0], "the code de") {
+		t.Errorf("expected a translation into de requested, got %q", systems)
+	}
+	output, err = run("", "get", "-json", translation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []struct {
+		Name string
+		Data string
+		Meta map[string]string
+		Tags []string
+	}
+	if err = json.Unmarshal([]byte(output), &got); err != nil || len(got) != 1 {
+		t.Fatalf("expected one snip, got %q %v", output, err)
+	}
+	if got[0].Name != "greeting (de)" || got[0].Data != "Guten Morgen\n" || got[0].Meta["language"] != "de" ||
+		got[0].Meta["translated_from"] != "snip://"+id || len(got[0].Tags) != 1 {
+		t.Errorf("expected the linked translation, got %+v", got[0])
+	}
+
+	// translating again replaces the earlier translation
+	if output, err = run("", "translate", id, "-to", "de", "-yes"); err != nil || output != "updated translation "+translation+" greeting (de)\n" {
+		t.Errorf("expected the translation updated, got %q %v", output, err)
+	}
+	if output, _ = run("", "get", "-raw", translation); output != "Guten Tag\n" {
+		t.Errorf("expected the new translation, got %q", output)
+	}
+	if output, _ = run("", "search", "tag"); !strings.Contains(output, "greeting (de)") {
+		t.Errorf("expected the new translation indexed, got %q", output)
+	}
+
+	var exitErr *exec.ExitError
+	if _, err = run("", "translate", id, "-to", "german", "-yes"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 for a language that is not a code, got %v", err)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	// the editor appends a line to the entry, as a person writing in it would
+	editor := path.Join(dir, "editor.sh")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\necho \"$JOURNAL_LINE\" >> \"$1\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "journal.sqlite3"), "SNIP_CONFIG="+path.Join(dir, "none.json"), "VISUAL=", "EDITOR="+editor)
+	run := func(line string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = append(env, "JOURNAL_LINE="+line)
+		output, err := cmd.Output()
+		return string(output), err
+	}
+
+	output, err := run("went hiking", "journal", "2024-05-14")
+	if err != nil || !strings.HasPrefix(output, "added snip uuid: ") {
+		t.Fatalf("expected the entry added, got %q %v", output, err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: "))
+	if output, err = run("fixed the bike", "journal", "2024-05-14"); err != nil || output != "updated "+id+" 2024-05-14\n" {
+		t.Errorf("expected the entry updated, got %q %v", output, err)
+	}
+	if output, _ = run("", "get", "-raw", id); output != "went hiking\nfixed the bike\n" {
+		t.Errorf("expected both lines in the entry, got %q", output)
+	}
+	if output, _ = run("", "tag", id); !strings.Contains(output, "journal") {
+		t.Errorf("expected the entry tagged journal, got %q", output)
+	}
+	if _, err = run("read a book", "journal", "2024-06-02"); err != nil {
+		t.Fatal(err)
+	}
+	// the entry for today is found by its name
+	if _, err = run("today", "journal"); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err = run("", "journal", "ls", "-month", "2024-05", "-l")
+	if err != nil || output != id+" 2024-05-14  went hiking\n" {
+		t.Errorf("expected the entry of may, got %q %v", output, err)
+	}
+	if output, err = run("", "journal", "ls"); err != nil || strings.Count(output, "\n") != 3 {
+		t.Errorf("expected every entry, got %q %v", output, err)
+	}
+	var exitErr *exec.ExitError
+	if _, err = run("", "journal", "ls", "-month", "May"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 for a month that cannot be read, got %v", err)
+	}
+	if _, err = run("", "journal", "tomorrowish"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 for a day that cannot be read, got %v", err)
+	}
+}
+
+func TestTrack(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "track.sqlite3"), "SNIP_CONFIG="+path.Join(dir, "none.json"))
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	var ids []string
+	for _, name := range []string{"design review", "invoice tool"} {
+		output, err := run("notes on "+name, "add", "-n", name, "-tag", "acme")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, strings.TrimSpace(strings.TrimPrefix(output, "added snip uuid: ")))
+	}
+
+	if output, err := run("", "track", "report"); err != nil || output != "no time was tracked\n" {
+		t.Errorf("expected no time tracked, got %q %v", output, err)
+	}
+	if output, err := run("", "track", "start", ids[0]); err != nil || output != "tracking "+ids[0]+" design review\n" {
+		t.Errorf("expected the first snip tracked, got %q %v", output, err)
+	}
+	output, err := run("", "track", "start", ids[1])
+	if err != nil || !strings.HasPrefix(output, "stopped "+ids[0]+" after ") || !strings.HasSuffix(output, "tracking "+ids[1]+" invoice tool\n") {
+		t.Errorf("expected the first snip stopped and the second tracked, got %q %v", output, err)
+	}
+	// the report ends with the snip still being tracked
+	if output, err = run("", "track", "report", "-l"); err != nil || !strings.HasSuffix(output, "\ntracking "+ids[1]+" invoice tool for 0s\n") {
+		t.Errorf("expected the running snip reported, got %q %v", output, err)
+	}
+	var exitErr *exec.ExitError
+	if _, err = run("", "track", "stop", ids[0]); !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("expected exit status 1 stopping a snip not tracked, got %v", err)
+	}
+	// times are stored to the second
+	time.Sleep(1100 * time.Millisecond)
+	if output, err = run("", "track", "stop"); err != nil || !strings.HasPrefix(output, "stopped "+ids[1]+" after ") {
+		t.Errorf("expected the second snip stopped, got %q %v", output, err)
+	}
+	if _, err = run("", "track", "stop"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("expected exit status 1 with nothing tracked, got %v", err)
+	}
+
+	output, err = run("", "track", "report", "-week", "-l")
+	if err != nil || !strings.Contains(output, ids[1]+"   0.00h invoice tool\n") || !strings.Contains(output, "\n  0.00h acme\n") || !strings.HasSuffix(output, "\n  0.00h total\n") {
+		t.Errorf("expected the hours of the snip and its tag, got %q %v", output, err)
+	}
+	if _, err = run("", "track", "bill"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 for an unknown action, got %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	dir := t.TempDir()
+	env := append(os.Environ(), "SNIP_DB="+path.Join(dir, "status.sqlite3"), "SNIP_CONFIG="+path.Join(dir, "none.json"))
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(appPath, args...)
+		cmd.Env = env
+		output, err := cmd.Output()
+		return string(output), err
+	}
+	ids := make(map[string]string)
+	for _, name := range []string{"fix login", "write docs", "release", "login notes"} {
+		cmd := exec.Command(appPath, "add", "-n", name)
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(name + " for the login page")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = strings.TrimSpace(strings.TrimPrefix(string(output), "added snip uuid: "))
+	}
+
+	if output, err := run("status", "set", "doing", ids["fix login"]); err != nil || output != "doing "+ids["fix login"]+" fix login\n" {
+		t.Errorf("expected the status set, got %q %v", output, err)
+	}
+	if _, err := run("status", "set", "todo", ids["write docs"], ids["release"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run("status", "set", "done", ids["release"]); err != nil {
+		t.Fatal(err)
+	}
+
+	short := func(name string) string { return ids[name][:8] }
+	output, err := run("status", "ls")
+	expected := "todo (1)\n" + short("write docs") + " write docs\n\ndoing (1)\n" + short("fix login") + " fix login\n\ndone (1)\n" + short("release") + " release\n"
+	if err != nil || output != expected {
+		t.Errorf("expected a board of the tasks, got %q %v", output, err)
+	}
+	if output, err = run("status", "ls", "none", "-l"); err != nil || output != "none (1)\n"+ids["login notes"]+" login notes\n" {
+		t.Errorf("expected the snip without a status, got %q %v", output, err)
+	}
+	if output, err = run("ls", "-status", "todo", "-columns", "name,status"); err != nil || output != "write docs todo\n" {
+		t.Errorf("expected the snip to do, got %q %v", output, err)
+	}
+	if output, err = run("search", "-status", "doing", "-porcelain", "login"); err != nil || !strings.HasPrefix(output, ids["fix login"]+"\t") || strings.Count(output, "\n") != 1 {
+		t.Errorf("expected only the snip being done found, got %q %v", output, err)
+	}
+
+	// none clears the status
+	if _, err = run("status", "set", "none", ids["release"]); err != nil {
+		t.Fatal(err)
+	}
+	if output, err = run("ls", "-status", "done"); err != nil || output != "" {
+		t.Errorf("expected no snips done, got %q %v", output, err)
+	}
+	var exitErr *exec.ExitError
+	if _, err = run("status", "set", "blocked", ids["release"]); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 for an unknown status, got %v", err)
+	}
+	if _, err = run("ls", "-status", "later"); !errors.As(err, &exitErr) || exitErr.ExitCode() != 4 {
+		t.Errorf("expected exit status 4 for an unknown status, got %v", err)
+	}
+}
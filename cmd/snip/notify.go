@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// desktopNotify shows a desktop notification using osascript on macOS or notify-send elsewhere
+func desktopNotify(title string, body string) error {
+	if runtime.GOOS == "darwin" {
+		script := "display notification " + strconv.Quote(body) + " with title " + strconv.Quote(title)
+		return exec.Command("osascript", "-e", script).Run()
+	}
+	return exec.Command("notify-send", title, body).Run()
+}
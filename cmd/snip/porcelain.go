@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Porcelain output is meant for scripts and does not change between releases. Each record is a line of fields separated by tabs,
+// and fields are only ever added at the end of a record, so scripts that split on tabs keep working.
+// The records of each command are:
+//
+//	ls:        uuid, timestamp, name
+//	search:    uuid, score, name
+//	attach ls: uuid, snip uuid, size, name
+//	alias ls:  alias, uuid, name
+
+// porcelainEscaper escapes the characters that would split a field or record
+var porcelainEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// writePorcelain writes fields as a single record, escaping backslashes, tabs, and line breaks within them as \\, \t, \n, and \r
+func writePorcelain(w io.Writer, fields ...string) {
+	escaped := make([]string, len(fields))
+	for idx, field := range fields {
+		escaped[idx] = porcelainEscaper.Replace(field)
+	}
+	fmt.Fprintln(w, strings.Join(escaped, "\t"))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// progressWidth is the number of characters between the brackets of a progress bar
+	progressWidth = 30
+	// progressInterval is the least time between redraws of a progress bar before its stage is complete
+	progressInterval = 100 * time.Millisecond
+)
+
+// progressBar draws the progress of a library operation on a single line of a terminal, and draws nothing when the output is not a terminal
+type progressBar struct {
+	w       io.Writer
+	enabled bool
+	drawn   time.Time
+	// open is whether a line has been drawn without being ended
+	open bool
+}
+
+// newProgressBar returns a bar drawn to f when it is a terminal
+func newProgressBar(f *os.File) *progressBar {
+	return &progressBar{w: f, enabled: isTerminal(f)}
+}
+
+// Step redraws the bar, ending its line once the stage is complete
+func (p *progressBar) Step(stage string, done int, total int) {
+	if !p.enabled {
+		return
+	}
+	complete := total > 0 && done >= total
+	if !complete && time.Since(p.drawn) < progressInterval {
+		return
+	}
+	p.drawn = time.Now()
+	// return to the start of the line and clear it
+	fmt.Fprintf(p.w, "\r\033[K%s", progressLine(stage, done, total))
+	p.open = !complete
+	if complete {
+		fmt.Fprintln(p.w)
+	}
+}
+
+// Finish ends the line of a bar left incomplete, such as when the operation failed
+func (p *progressBar) Finish() {
+	if p.open {
+		fmt.Fprintln(p.w)
+		p.open = false
+	}
+}
+
+// progressLine describes done of total items of a stage, with a bar when the total is known
+func progressLine(stage string, done int, total int) string {
+	if total <= 0 {
+		return fmt.Sprintf("%s %d", stage, done)
+	}
+	filled := progressWidth * done / total
+	if filled > progressWidth {
+		filled = progressWidth
+	}
+	return fmt.Sprintf("%s [%s%s] %d/%d", stage, strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled), done, total)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"github.com/skip2/go-qrcode"
+	"io"
+)
+
+// writeQR renders content as a qr code drawn with block characters for display in a terminal
+func writeQR(w io.Writer, content string) error {
+	// low recovery allows the most content, and screens are not damaged the way print is
+	q, err := qrcode.New(content, qrcode.Low)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, q.ToSmallString(false))
+	return err
+}
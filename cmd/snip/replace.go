@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// substitution is a sed style s/pattern/replacement/flags expression
+type substitution struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+	// Global replaces every match rather than only the first
+	Global bool
+}
+
+// sedGroup matches the group references of a sed replacement, \0 through \9 and &
+var sedGroup = regexp.MustCompile(`\\\\|\\[0-9]|\\&|&`)
+
+// parseSubstitution reads an expression such as s/old/new/g, where any character may follow s as the delimiter.
+// A delimiter preceded by a backslash is literal, and the flags are g for every match and i to ignore case.
+// The replacement may refer to groups as \1 and to the whole match as &.
+func parseSubstitution(expr string) (*substitution, error) {
+	if len(expr) < 2 || expr[0] != 's' {
+		return nil, fmt.Errorf("expression %q is not of the form s/pattern/replacement/flags", expr)
+	}
+	delim := expr[1:2]
+
+	var parts []string
+	var part strings.Builder
+	rest := expr[2:]
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, `\`+delim):
+			part.WriteString(delim)
+			rest = rest[1+len(delim):]
+		case strings.HasPrefix(rest, delim):
+			parts = append(parts, part.String())
+			part.Reset()
+			rest = rest[len(delim):]
+		case strings.HasPrefix(rest, `\`) && len(rest) > 1:
+			part.WriteString(rest[:2])
+			rest = rest[2:]
+		default:
+			part.WriteString(rest[:1])
+			rest = rest[1:]
+		}
+	}
+	parts = append(parts, part.String())
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expression %q is not of the form s/pattern/replacement/flags", expr)
+	}
+
+	sub := &substitution{}
+	pattern := parts[0]
+	for _, flag := range parts[2] {
+		switch flag {
+		case 'g':
+			sub.Global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return nil, fmt.Errorf("flag %q is not supported (g|i)", flag)
+		}
+	}
+	var err error
+	sub.Pattern, err = regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	// translate the sed references into those of regexp.Expand, leaving any other $ literal
+	sub.Replacement = sedGroup.ReplaceAllStringFunc(strings.ReplaceAll(parts[1], "$", "$$"), func(ref string) string {
+		switch ref {
+		case `\\`:
+			return `\`
+		case `\&`:
+			return "&"
+		case "&":
+			return "${0}"
+		}
+		return "${" + ref[1:] + "}"
+	})
+	return sub, nil
+}
+
+// Apply returns data with the substitution made, along with the number of replacements
+func (sub *substitution) Apply(data string) (string, int) {
+	matches := sub.Pattern.FindAllStringSubmatchIndex(data, -1)
+	if !sub.Global && len(matches) > 1 {
+		matches = matches[:1]
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(data[last:m[0]])
+		b.Write(sub.Pattern.ExpandString(nil, sub.Replacement, data, m))
+		last = m[1]
+	}
+	b.WriteString(data[last:])
+	return b.String(), len(matches)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"strings"
+	"time"
+)
+
+// reviewKeys are the responses accepted after a snip is shown during review
+var reviewKeys = map[string]snip.Grade{
+	"a": snip.GradeAgain,
+	"h": snip.GradeHard,
+	"g": snip.GradeGood,
+	"e": snip.GradeEasy,
+}
+
+// runReview shows the name of each due snip and then its data, scheduling the next review by the grade read from in.
+// It returns the number of snips graded, stopping early at the end of input or when q is entered.
+func runReview(in io.Reader, out io.Writer, reviews []snip.Review) (int, error) {
+	r := bufio.NewReader(in)
+	// readKey returns the first letter of the next line of input, or q at the end of input
+	readKey := func() string {
+		line, err := r.ReadString('\n')
+		if err != nil && line == "" {
+			return "q"
+		}
+		return strings.ToLower(strings.TrimSpace(line))
+	}
+
+	graded := 0
+	for idx, review := range reviews {
+		s, err := snip.GetFromUUID(review.UUID.String())
+		if err != nil {
+			return graded, err
+		}
+		fmt.Fprintf(out, "[%d/%d] %s\n", idx+1, len(reviews), s.Name)
+		fmt.Fprintf(out, "press enter to show the snip, q to quit: ")
+		if readKey() == "q" {
+			return graded, nil
+		}
+		fmt.Fprintf(out, "\n%s\n", strings.TrimRight(s.Data, "\n"))
+
+		for {
+			fmt.Fprintf(out, "again (a), hard (h), good (g), easy (e), quit (q): ")
+			key := readKey()
+			if key == "q" {
+				return graded, nil
+			}
+			grade, ok := reviewKeys[key]
+			if !ok {
+				continue
+			}
+			if err = review.Grade(grade, time.Now()); err != nil {
+				return graded, err
+			}
+			if err = snip.SetReview(review); err != nil {
+				return graded, err
+			}
+			graded++
+			fmt.Fprintf(out, "next review in %d days\n\n", review.Interval)
+			break
+		}
+	}
+	return graded, nil
+}
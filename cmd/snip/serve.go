@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip/config"
+	"github.com/ryanfrishkorn/snip/server"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// runServe serves the api over tcp until interrupted, and over grpc when grpcAddr is set, enforcing limits on each client
+// Snips with one of publicTags are served to anyone beneath /public/.
+func runServe(addr string, grpcAddr string, token string, limits server.Limits, proxy server.Proxy, publicTags []string, conf config.Config) error {
+	// the token given on the command line is an admin, alongside the users of the configuration
+	tokens := make(map[string]server.Identity)
+	for _, t := range conf.Tokens {
+		if t.Token == "" || (t.Owner == "" && !t.Admin) {
+			return fmt.Errorf("each configured token needs a value and an owner or admin scope")
+		}
+		tokens[t.Token] = server.Identity{Owner: t.Owner, Admin: t.Admin}
+	}
+	if token != "" {
+		tokens[token] = server.Identity{Admin: true}
+	}
+	// the grpc api is not scoped to owners, so only the admin token reaches it
+	if grpcAddr != "" && token == "" && len(tokens) > 0 {
+		return fmt.Errorf("serving grpc to the users of the configuration requires an admin -token")
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	if grpcAddr != "" {
+		grpcListener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		grpcServer := server.NewGRPC(token, limits)
+		// streams such as WatchEvents never finish on their own, so they are cut off at shutdown
+		defer grpcServer.Stop()
+		go func() {
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				fmt.Fprintf(os.Stderr, "There was a problem serving grpc on %s\n", grpcAddr)
+				log.Debug().Err(err).Str("addr", grpcAddr).Msg("error serving grpc")
+			}
+		}()
+		fmt.Fprintf(os.Stderr, "serving grpc on %s\n", grpcListener.Addr())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = server.DispatchWebhooks(ctx, conf.Webhooks)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	var api http.Handler = server.Limit(server.New(), limits)
+	if len(tokens) > 0 {
+		api = server.LimitFailedAuth(server.RequireTokens(api, tokens), limits)
+	} else {
+		fmt.Fprintf(os.Stderr, "warning: no token configured, all requests are allowed\n")
+	}
+	// share links carry their own token and are public
+	handler := http.NewServeMux()
+	handler.Handle("/", api)
+	handler.Handle("/share/", server.Limit(server.ShareHandler{}, limits))
+	if len(publicTags) > 0 {
+		handler.Handle("/public/", server.Limit(server.PublicHandler{Tags: publicTags}, limits))
+		fmt.Fprintf(os.Stderr, "serving snips tagged %s publicly beneath /public/\n", strings.Join(publicTags, ", "))
+	}
+
+	fmt.Fprintf(os.Stderr, "serving on http://%s%s\n", listener.Addr(), proxy.BasePath)
+	return serveUntilInterrupted(listener, proxy.Wrap(handler))
+}
+
+// serveUntilInterrupted serves handler on listener, shutting down gracefully on interrupt or termination
+func serveUntilInterrupted(listener net.Listener, handler http.Handler) error {
+	// cancelled on shutdown so that streaming responses end
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	httpServer := &http.Server{
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shutdownCancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	err := httpServer.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"strconv"
+)
+
+// queryFormats are the output formats of the sql command
+var queryFormats = []string{"table", "csv", "json"}
+
+// queryValue converts a value returned by a query for output, showing blobs as text unless they hold binary data
+func queryValue(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok && !isBinary(string(b)) {
+		return string(b)
+	}
+	return v
+}
+
+// queryCell formats a value returned by a query as the text of a table or csv cell
+func queryCell(v interface{}, null string) string {
+	switch v := queryValue(v).(type) {
+	case nil:
+		return null
+	case []byte:
+		return fmt.Sprintf("(binary data, %d bytes)", len(v))
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeQueryResult writes the rows of a query as an aligned table, csv with a header, or a json array of objects keyed by column
+func writeQueryResult(w io.Writer, result snip.QueryResult, format string) error {
+	switch format {
+	case "table":
+		section := tableSection{}
+		for _, row := range result.Rows {
+			var cells []string
+			for _, v := range row {
+				cells = append(cells, snip.FlattenString(queryCell(v, "NULL")))
+			}
+			section.Rows = append(section.Rows, cells)
+		}
+		if len(result.Columns) > 0 {
+			writeTable(w, w, result.Columns, []tableSection{section}, nil)
+		}
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(result.Columns); err != nil {
+			return err
+		}
+		for _, row := range result.Rows {
+			var cells []string
+			for _, v := range row {
+				cells = append(cells, queryCell(v, ""))
+			}
+			if err := cw.Write(cells); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		objects := []map[string]interface{}{}
+		for _, row := range result.Rows {
+			object := make(map[string]interface{})
+			for idx, v := range row {
+				object[result.Columns[idx]] = queryValue(v)
+			}
+			objects = append(objects, object)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(objects)
+	default:
+		return fmt.Errorf("format %s is not supported", format)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip"
+)
+
+// narrowToStatus returns the snips of ids with status, or every snip with status when narrowed is false because no ids were given
+func narrowToStatus(ids []uuid.UUID, narrowed bool, status snip.Status) ([]uuid.UUID, error) {
+	withStatus, err := snip.StatusSnips(status)
+	if err != nil || !narrowed {
+		return withStatus, err
+	}
+	has := make(map[uuid.UUID]bool, len(withStatus))
+	for _, id := range withStatus {
+		has[id] = true
+	}
+	var matched []uuid.UUID
+	for _, id := range ids {
+		if has[id] {
+			matched = append(matched, id)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+)
+
+// json-rpc error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// rpcRequest is a json-rpc 2.0 request, a notification when ID is absent
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a json-rpc 2.0 response carrying either a result or an error
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError describes why a request failed
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// stdioSession answers json-rpc requests for an editor plugin
+type stdioSession struct {
+	store  snip.Store
+	hooks  map[string][]string
+	naming snip.NameStrategy
+}
+
+// runStdio answers newline delimited json-rpc 2.0 requests from in until it is closed, writing one response per line to out
+func runStdio(in io.Reader, out io.Writer, store snip.Store, hooks map[string][]string, naming snip.NameStrategy) error {
+	session := stdioSession{store: store, hooks: hooks, naming: naming}
+	encoder := json.NewEncoder(out)
+	scanner := bufio.NewScanner(in)
+	// snips inserted by editors may be large
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			err = encoder.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, err.Error()}})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		log.Debug().Str("method", req.Method).Str("id", string(req.ID)).Msg("stdio request")
+
+		result, rpcErr := session.handle(req)
+		if req.ID == nil {
+			// notifications are never answered
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+		if rpcErr == nil && result == nil {
+			resp.Result = struct{}{}
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// handle dispatches a request to its method
+func (session stdioSession) handle(req rpcRequest) (any, *rpcError) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &rpcError{rpcInvalidRequest, "invalid request"}
+	}
+
+	switch req.Method {
+	case "get":
+		var params struct {
+			UUID string `json:"uuid"`
+		}
+		if err := decodeParams(req.Params, &params); err != nil || params.UUID == "" {
+			return nil, &rpcError{rpcInvalidParams, "uuid is required"}
+		}
+		s, err := session.store.Get(params.UUID)
+		if err != nil {
+			return nil, &rpcError{rpcServerError, err.Error()}
+		}
+		return s, nil
+
+	case "search":
+		params := struct {
+			Terms   []string `json:"terms"`
+			Limit   int      `json:"limit"`
+			Context int      `json:"context"`
+		}{Context: 6}
+		if err := decodeParams(req.Params, &params); err != nil || len(params.Terms) == 0 {
+			return nil, &rpcError{rpcInvalidParams, "at least one search term is required"}
+		}
+		matches, err := session.store.Search(params.Terms, params.Limit, params.Context)
+		if err != nil {
+			return nil, &rpcError{rpcServerError, err.Error()}
+		}
+		if matches == nil {
+			matches = []snip.SearchMatch{}
+		}
+		return matches, nil
+
+	case "insert":
+		var params struct {
+			Name string            `json:"name"`
+			Data string            `json:"data"`
+			Meta map[string]string `json:"meta"`
+		}
+		if err := decodeParams(req.Params, &params); err != nil || params.Data == "" {
+			return nil, &rpcError{rpcInvalidParams, "data is required"}
+		}
+		s := snip.New()
+		s.Data = params.Data
+		s.Name = params.Name
+		if s.Name == "" {
+			s.Name = snip.GenerateName([]byte(s.Data), session.naming)
+		}
+		s.Meta = params.Meta
+		if err := session.store.Insert(s); err != nil {
+			return nil, &rpcError{rpcServerError, err.Error()}
+		}
+		// hook output goes to stderr and cannot corrupt responses
+		if err := snip.RunHook(snip.HookPostAdd, session.hooks[snip.HookPostAdd], s); err != nil {
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error running post-add hook")
+		}
+		return s, nil
+	}
+	return nil, &rpcError{rpcMethodNotFound, "method not found: " + req.Method}
+}
+
+// decodeParams unmarshals request params into v, accepting absent params
+func decodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	return json.Unmarshal(params, v)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/ryanfrishkorn/snip/config"
+	"strings"
+)
+
+// theme holds the colors of terminal output
+type theme struct {
+	ID      *color.Color
+	Name    *color.Color
+	Match   *color.Color
+	Added   *color.Color
+	Removed *color.Color
+	Hunk    *color.Color
+}
+
+// colors is the theme of terminal output, replaced by the theme of the configuration file
+var colors = defaultTheme()
+
+// defaultTheme dims uuids, makes names bold, and colors search matches and diffs as other tools do
+func defaultTheme() theme {
+	return theme{
+		ID:      color.New(color.Faint),
+		Name:    color.New(color.Bold),
+		Match:   color.New(color.FgRed),
+		Added:   color.New(color.FgGreen),
+		Removed: color.New(color.FgRed),
+		Hunk:    color.New(color.FgCyan),
+	}
+}
+
+// colorAttributes are the attributes a theme color is made of
+var colorAttributes = map[string]color.Attribute{
+	"bold":       color.Bold,
+	"dim":        color.Faint,
+	"italic":     color.Italic,
+	"underline":  color.Underline,
+	"reverse":    color.ReverseVideo,
+	"black":      color.FgBlack,
+	"red":        color.FgRed,
+	"green":      color.FgGreen,
+	"yellow":     color.FgYellow,
+	"blue":       color.FgBlue,
+	"magenta":    color.FgMagenta,
+	"cyan":       color.FgCyan,
+	"white":      color.FgWhite,
+	"hi-black":   color.FgHiBlack,
+	"hi-red":     color.FgHiRed,
+	"hi-green":   color.FgHiGreen,
+	"hi-yellow":  color.FgHiYellow,
+	"hi-blue":    color.FgHiBlue,
+	"hi-magenta": color.FgHiMagenta,
+	"hi-cyan":    color.FgHiCyan,
+	"hi-white":   color.FgHiWhite,
+}
+
+// parseColor returns the color of spec, attributes separated by spaces such as "bold yellow", or plain text for "none"
+func parseColor(spec string) (*color.Color, error) {
+	c := color.New()
+	if strings.TrimSpace(spec) == "none" {
+		c.DisableColor()
+		return c, nil
+	}
+	for _, name := range strings.Fields(spec) {
+		attr, ok := colorAttributes[name]
+		if !ok {
+			return nil, fmt.Errorf("color %q is not supported", name)
+		}
+		c.Add(attr)
+	}
+	return c, nil
+}
+
+// loadTheme returns the default theme with the colors set in conf in place of its own
+func loadTheme(conf config.Theme) (theme, error) {
+	t := defaultTheme()
+	for _, field := range []struct {
+		name string
+		spec string
+		dst  **color.Color
+	}{
+		{"id", conf.ID, &t.ID},
+		{"name", conf.Name, &t.Name},
+		{"match", conf.Match, &t.Match},
+		{"added", conf.Added, &t.Added},
+		{"removed", conf.Removed, &t.Removed},
+		{"hunk", conf.Hunk, &t.Hunk},
+	} {
+		if field.spec == "" {
+			continue
+		}
+		c, err := parseColor(field.spec)
+		if err != nil {
+			return t, fmt.Errorf("theme %s: %w", field.name, err)
+		}
+		*field.dst = c
+	}
+	return t, nil
+}
+
+// addColorFlag registers the -color flag on fs, defaulting to the color setting of the configuration file
+func addColorFlag(fs *flag.FlagSet, mode *string) {
+	fs.StringVar(mode, "color", *mode, "color output always, never, or auto when writing to a terminal")
+}
+
+// setColorMode colors output always, never, or when it is written to a terminal and $NO_COLOR is unset (auto, or empty)
+func setColorMode(mode string) error {
+	switch mode {
+	case "", "auto":
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	default:
+		return fmt.Errorf("color mode %s is not supported (auto|always|never)", mode)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// tmuxDefaultTarget is the previously active pane, since the current pane is running snip
+const tmuxDefaultTarget = "{last}"
+
+// sendTmux types data into a tmux pane, pressing enter after each complete line
+func sendTmux(target string, data string) error {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(data, "\n")
+	for _, line := range lines {
+		text := strings.TrimSuffix(line, "\n")
+		if text != "" {
+			// -l sends the text literally so that words such as Enter are not interpreted as keys
+			output, err := exec.Command("tmux", "send-keys", "-t", target, "-l", text).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("tmux send-keys: %w: %s", err, strings.TrimSpace(string(output)))
+			}
+		}
+		if strings.HasSuffix(line, "\n") {
+			output, err := exec.Command("tmux", "send-keys", "-t", target, "Enter").CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("tmux send-keys: %w: %s", err, strings.TrimSpace(string(output)))
+			}
+		}
+	}
+	return nil
+}
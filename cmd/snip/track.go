@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"sort"
+	"time"
+)
+
+// weekStart returns the start of the monday of the week of now
+func weekStart(now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	// time.Sunday is 0, which ends the week
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// formatHours formats d as decimal hours, as billed
+func formatHours(d time.Duration) string {
+	return fmt.Sprintf("%6.2fh", d.Hours())
+}
+
+// writeTrackReport lists the hours tracked on each snip, most first, then the hours of each of their tags and the total
+func writeTrackReport(w io.Writer, tracked map[uuid.UUID]time.Duration, ids []uuid.UUID, names map[uuid.UUID]string, tags map[uuid.UUID][]string, long bool) {
+	var total time.Duration
+	byTag := make(map[string]time.Duration)
+	for _, id := range ids {
+		short := snip.ShortenUUID(id)[0]
+		if long {
+			short = id.String()
+		}
+		fmt.Fprintf(w, "%s %s %s\n", short, formatHours(tracked[id]), names[id])
+		total += tracked[id]
+		for _, tag := range tags[id] {
+			byTag[tag] += tracked[id]
+		}
+	}
+
+	if len(byTag) > 0 {
+		var tagNames []string
+		for tag := range byTag {
+			tagNames = append(tagNames, tag)
+		}
+		sort.SliceStable(tagNames, func(i, j int) bool {
+			if byTag[tagNames[i]] != byTag[tagNames[j]] {
+				return byTag[tagNames[i]] > byTag[tagNames[j]]
+			}
+			return tagNames[i] < tagNames[j]
+		})
+		fmt.Fprintln(w)
+		for _, tag := range tagNames {
+			fmt.Fprintf(w, "%s %s\n", formatHours(byTag[tag]), tag)
+		}
+	}
+	fmt.Fprintf(w, "\n%s total\n", formatHours(total))
+}
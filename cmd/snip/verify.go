@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+)
+
+// writeMismatches reports each checksum mismatch along with the expected and found digests
+func writeMismatches(w io.Writer, mismatches []snip.ChecksumMismatch) {
+	for _, m := range mismatches {
+		if m.Attachment {
+			fmt.Fprintf(w, "mismatch attachment %s of snip %s\n", m.UUID, m.SnipUUID)
+		} else {
+			fmt.Fprintf(w, "mismatch snip %s\n", m.UUID)
+		}
+		if m.Actual == "" {
+			fmt.Fprintf(w, "  blob file is missing\n")
+			continue
+		}
+		if m.Expected == "" {
+			fmt.Fprintf(w, "  no checksum recorded, found %s\n", m.Actual)
+			continue
+		}
+		fmt.Fprintf(w, "  expected %s\n  found    %s\n", m.Expected, m.Actual)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// watchSettle is how long a file must be quiet before it is synced, since editors write in bursts
+const watchSettle = 250 * time.Millisecond
+
+// watchDirectory syncs files in dir to snips and keeps them updated until interrupted
+func watchDirectory(dir string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// watch all directories and sync the files already present
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ignoreWatchPath(path) && path != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		syncWatchedFile(path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "watching %s\n", dir)
+
+	// pending holds paths waiting for writes to settle
+	pending := make(map[string]time.Time)
+	ticker := time.NewTicker(watchSettle / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			log.Debug().Str("event", event.String()).Msg("watch event")
+			if ignoreWatchPath(event.Name) {
+				continue
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				// removed and renamed files keep their snips
+				continue
+			}
+			info, err := os.Stat(event.Name)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() {
+				if err := watcher.Add(event.Name); err != nil {
+					fmt.Fprintf(os.Stderr, "The new directory %s could not be watched.\n", event.Name)
+					log.Debug().Err(err).Str("path", event.Name).Msg("error adding directory to watcher")
+				}
+				continue
+			}
+			pending[event.Name] = time.Now()
+
+		case <-ticker.C:
+			for path, changed := range pending {
+				if time.Since(changed) < watchSettle {
+					continue
+				}
+				delete(pending, path)
+				syncWatchedFile(path)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "The watcher reported an error: %v\n", err)
+			log.Debug().Err(err).Msg("watcher error")
+		}
+	}
+}
+
+// syncWatchedFile creates or updates the snip of a file and reports the result
+func syncWatchedFile(path string) {
+	s, created, changed, err := snip.SyncFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "The file %s could not be synced.\n", path)
+		log.Debug().Err(err).Str("path", path).Msg("error syncing file")
+		return
+	}
+	switch {
+	case created:
+		fmt.Printf("added %s %s\n", s.UUID, path)
+	case changed:
+		fmt.Printf("updated %s %s\n", s.UUID, path)
+	}
+}
+
+// ignoreWatchPath determines if a path is hidden or an editor temporary file
+func ignoreWatchPath(path string) bool {
+	base := filepath.Base(path)
+	switch {
+	case strings.HasPrefix(base, "."):
+		return true
+	case strings.HasSuffix(base, "~"):
+		return true
+	case strings.HasSuffix(base, ".swp"), strings.HasSuffix(base, ".swx"), strings.HasSuffix(base, ".tmp"):
+		return true
+	case strings.HasPrefix(base, "#") && strings.HasSuffix(base, "#"):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Config contains user settings read from the configuration file
+type Config struct {
+	// Attachments chooses where the data of attachments is kept
+	Attachments Attachments `json:"attachments"`
+	// Backup sets when the daemon copies the database and which copies it keeps
+	Backup Backup `json:"backup"`
+	// Checkers are the spell checkers and linters run by snip check, replacing the defaults when set
+	Checkers []Checker `json:"checkers"`
+	// Color is auto (default) to color output written to a terminal, always, or never
+	Color string `json:"color"`
+	// Hooks maps hook names such as post-add to shell commands
+	Hooks map[string][]string `json:"hooks"`
+	// LLM is the language model that snip summarize, ask, and translate send snips to
+	LLM LLM `json:"llm"`
+	// Naming chooses how names are generated for snips added without one
+	Naming Naming `json:"naming"`
+	// OCR reads the text of image attachments into the search index
+	OCR OCR `json:"ocr"`
+	// Search chooses how snips are split into the terms of the search index
+	Search Search `json:"search"`
+	// SMTP is the mail server used to send snips
+	SMTP SMTP `json:"smtp"`
+	// ShareURL is the address at which others reach snip serve, used to print share links
+	ShareURL string `json:"share_url"`
+	// Transcription makes transcripts of audio attachments for the search index
+	Transcription Transcription `json:"transcription"`
+	// Theme sets the colors of terminal output
+	Theme Theme `json:"theme"`
+	// Tokens are the users allowed to reach snip serve, each seeing only their own snips unless they are an admin
+	Tokens []Token `json:"tokens"`
+	// Webhooks receive signed notifications of changes while serving
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// Attachments describes where the data of attachments is stored
+type Attachments struct {
+	// Blobs keeps the data of new attachments in files named by their digest within BlobDir rather than in the database
+	Blobs bool `json:"blobs"`
+	// BlobDir holds the files, the database path followed by .blobs by default
+	BlobDir string `json:"blob_dir"`
+	// MaxSize is the size of the largest attachment that may be added, such as 100M, without a limit when empty
+	MaxSize string `json:"max_size"`
+	// Quota is the size the data of every snip and attachment may reach together, such as 10G, without a limit when empty
+	Quota string `json:"quota"`
+}
+
+// Backup describes the periodic backups made by the daemon
+type Backup struct {
+	// Dir holds the backups, the database path followed by .backups by default
+	Dir string `json:"dir"`
+	// Interval between backups such as 12h or 1d, no backups are made by the daemon when empty
+	Interval string `json:"interval"`
+	// Keep sets how many backups are kept, all of them when left empty
+	Keep Keep `json:"keep"`
+}
+
+// Keep is how many backups are kept for the most recent days, weeks, and months, the latest backup of each being kept
+type Keep struct {
+	Daily   int `json:"daily"`
+	Weekly  int `json:"weekly"`
+	Monthly int `json:"monthly"`
+}
+
+// Checker is a command finding problems in snips, run by snip check
+type Checker struct {
+	Name string `json:"name"`
+	// Command checks the file $SNIP_FILE with sh, writing problems as lines of the form $SNIP_FILE:line:column: message
+	Command string `json:"command"`
+	// Langs are the languages of the scripts and fenced code blocks checked, prose and markdown are checked if empty
+	Langs []string `json:"langs"`
+	// Words is set for a command writing one misspelled word per line, such as aspell list
+	Words bool `json:"words"`
+}
+
+// LLM describes the language model snips are sent to, which is ollama or a server with an api compatible with that of OpenAI
+type LLM struct {
+	// Provider is ollama or openai, no snips are sent when it is empty
+	Provider string `json:"provider"`
+	// URL is the address of the api, http://localhost:11434 for ollama and https://api.openai.com/v1 for openai by default
+	URL   string `json:"url"`
+	Model string `json:"model"`
+	// APIKey is sent to openai as a bearer token, read from $OPENAI_API_KEY when empty
+	APIKey string `json:"api_key"`
+}
+
+// Naming describes how names are derived from snip data
+type Naming struct {
+	// Strategy is one of words (default), line, heading, or template
+	Strategy string `json:"strategy"`
+	// Words is the number of words in generated names, 5 by default
+	Words int `json:"words"`
+	// Template is a text/template such as "{{.Date}} {{.Heading}}"
+	Template string `json:"template"`
+}
+
+// OCR describes how the text of image attachments is read
+type OCR struct {
+	// Enabled reads the text of each image attached with snip attach add
+	Enabled bool `json:"enabled"`
+	// Command reads the image named by $SNIP_ATTACHMENT_FILE, writing its text to standard output, tesseract by default
+	Command string `json:"command"`
+}
+
+// Search describes how the search index is built
+type Search struct {
+	// Tokenizer is words (default) or ngram, which splits chinese, japanese, and korean text into n-grams
+	Tokenizer string `json:"tokenizer"`
+	// Ngram is the number of characters in each n-gram, 2 by default
+	Ngram int `json:"ngram"`
+	// Boost weighs search terms found in the names and tags of snips against those found in their data
+	Boost Boost `json:"boost"`
+}
+
+// Boost holds the weights of snip fields in search scores, left unset to keep the default
+type Boost struct {
+	// Name is 2 by default
+	Name *float64 `json:"name"`
+	// Tags is 1 by default
+	Tags *float64 `json:"tags"`
+}
+
+// Theme describes the colors of terminal output, each given as attributes separated by spaces such as "bold yellow", or "none" for plain text.
+// Colors left empty keep their default.
+type Theme struct {
+	// ID colors uuids, dim by default
+	ID string `json:"id"`
+	// Name colors snip names, bold by default
+	Name string `json:"name"`
+	// Match colors the matching terms of search results, red by default
+	Match string `json:"match"`
+	// Added and Removed color the lines of diffs, green and red by default
+	Added   string `json:"added"`
+	Removed string `json:"removed"`
+	// Hunk colors the line numbers of diff hunks, cyan by default
+	Hunk string `json:"hunk"`
+}
+
+// SMTP describes how to reach a mail server
+type SMTP struct {
+	Host string `json:"host"`
+	// Port defaults to 587, port 465 uses implicit tls
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	// Password may also be supplied by $SNIP_SMTP_PASSWORD
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+// Transcription describes how transcripts of audio attachments are made
+type Transcription struct {
+	// Command transcribes the audio named by $SNIP_ATTACHMENT_FILE, writing the text to standard output.
+	// Attachments are not transcribed when it is empty.
+	Command string `json:"command"`
+}
+
+// Webhook is an endpoint notified of snip changes
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret signs each payload so the receiver can verify its origin
+	Secret string `json:"secret"`
+	// Events limits notifications to the listed event types, all events are sent if empty
+	Events []string `json:"events"`
+}
+
+// Token identifies a user of snip serve
+type Token struct {
+	Token string `json:"token"`
+	// Owner names the user, who owns the snips added with the token
+	Owner string `json:"owner"`
+	// Admin sees and changes the snips of every owner
+	Admin bool `json:"admin"`
+}
+
+// Path returns the location of the configuration file, honoring $SNIP_CONFIG
+func Path() string {
+	if path := os.Getenv("SNIP_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".snip.json"
+	}
+	return filepath.Join(home, ".snip.json")
+}
+
+// Load reads the configuration file at path, returning defaults if it does not exist
+func Load(path string) (Config, error) {
+	var c Config
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return c, err
+	}
+	return c, nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snip.json")
+
+	// a missing file is not an error
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected nil err for missing file, got %v", err)
+	}
+	if len(c.Hooks) != 0 {
+		t.Errorf("expected no hooks, got %v", c.Hooks)
+	}
+
+	err = os.WriteFile(path, []byte(`{"hooks": {"post-add": ["echo added"]}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = Load(path)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(c.Hooks["post-add"]) != 1 || c.Hooks["post-add"][0] != "echo added" {
+		t.Errorf("expected post-add hook, got %v", c.Hooks)
+	}
+
+	err = os.WriteFile(path, []byte(`{"hooks": `), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Load(path); err == nil {
+		t.Errorf("expected error for malformed file")
+	}
+}
+
+func TestFindContext(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "project", "src", "pkg")
+	if err := os.MkdirAll(nested, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := FindContext(nested)
+	if err != nil {
+		t.Fatalf("expected nil err without a context, got %v", err)
+	}
+	if c.Path != "" || len(c.Tags) != 0 {
+		t.Errorf("expected an empty context, got %+v", c)
+	}
+
+	path := filepath.Join(root, "project", ContextFile)
+	err = os.WriteFile(path, []byte(`{"tags": ["project"], "database": "notes.sqlite3"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err = FindContext(nested)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if c.Path != path || len(c.Tags) != 1 || c.Tags[0] != "project" {
+		t.Errorf("expected the context of the project, got %+v", c)
+	}
+	if c.Database != filepath.Join(root, "project", "notes.sqlite3") {
+		t.Errorf("expected the database relative to the project, got %s", c.Database)
+	}
+
+	// the nearest context is used
+	if err = os.Mkdir(filepath.Join(nested, ContextFile), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(root, "project", "src", ContextFile), []byte(`{"tags": ["src"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = FindContext(nested); err != nil || len(c.Tags) != 1 || c.Tags[0] != "src" {
+		t.Errorf("expected the nearest context, got %+v and %v", c, err)
+	}
+
+	if err = os.WriteFile(path, []byte(`{"tags": `), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = FindContext(filepath.Join(root, "project")); err == nil {
+		t.Errorf("expected error for a malformed context")
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ContextFile is the name of the file pinning the context of a project directory
+const ContextFile = ".snip"
+
+// Context holds defaults for snips added within a project directory and the directories beneath it, read from its ContextFile
+type Context struct {
+	// Path is the ContextFile the context was read from, empty when there is none
+	Path string `json:"-"`
+	// Tags are applied to snips added within the directory
+	Tags []string `json:"tags"`
+	// Database is the snip database used within the directory in place of the default, relative to the directory unless absolute.
+	// $SNIP_DB still takes precedence.
+	Database string `json:"database"`
+}
+
+// FindContext reads the ContextFile of dir or the nearest directory above it, returning an empty Context when there is none
+func FindContext(dir string) (Context, error) {
+	var c Context
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return c, err
+	}
+	for {
+		path := filepath.Join(dir, ContextFile)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			if err = json.Unmarshal(data, &c); err != nil {
+				return c, fmt.Errorf("%s: %w", path, err)
+			}
+			c.Path = path
+			if c.Database != "" && !filepath.IsAbs(c.Database) {
+				c.Database = filepath.Join(dir, c.Database)
+			}
+			return c, nil
+		}
+		// a directory named .snip is not a context
+		if !errors.Is(err, fs.ErrNotExist) && !isDir(path) {
+			return c, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return c, nil
+		}
+		dir = parent
+	}
+}
+
+// isDir reports whether path is a directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
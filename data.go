@@ -0,0 +1,82 @@
+package snip
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+)
+
+// ResolveUUID returns the full uuid of the single snip matching a full or partial uuid, without reading its data
+func ResolveUUID(searchUUID string) (uuid.UUID, error) {
+	searchUUID, err := resolveAlias(searchUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	var maxLength = 36
+	length := len(searchUUID)
+	if length > maxLength || length == 0 {
+		return uuid.Nil, fmt.Errorf("supplied uuid string must be 1 to %d characters", maxLength)
+	}
+
+	query := `SELECT uuid FROM snip WHERE uuid LIKE ? LIMIT 2`
+	arg := "%" + searchUUID + "%"
+	if length == maxLength {
+		query = `SELECT uuid FROM snip WHERE uuid = ? LIMIT 2`
+		arg = searchUUID
+	}
+	stmt, err := database.Conn.Prepare(query, arg)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer stmt.Close()
+
+	var ids []string
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if !hasRow {
+			break
+		}
+		var id string
+		err = stmt.Scan(&id)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		ids = append(ids, id)
+	}
+	switch len(ids) {
+	case 0:
+		return uuid.Nil, fmt.Errorf("database search returned zero results: %w", database.ErrNoRows)
+	case 1:
+		return uuid.Parse(ids[0])
+	}
+	return uuid.Nil, fmt.Errorf("database search returned multiple results: %w", database.ErrMultipleRows)
+}
+
+// OpenData returns a reader of the data of a snip that reads from the database as needed,
+// so that part of a large snip can be read without loading all of it.
+// The reader must be closed before the snip is modified.
+func OpenData(id uuid.UUID) (io.ReadSeekCloser, error) {
+	stmt, err := database.Conn.Prepare(`SELECT rowid FROM snip WHERE uuid = ?`, id.String())
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	hasRow, err := stmt.Step()
+	if err != nil {
+		return nil, err
+	}
+	if !hasRow {
+		return nil, fmt.Errorf("snip %s does not exist", id)
+	}
+	var row int64
+	err = stmt.Scan(&row)
+	if err != nil {
+		return nil, err
+	}
+	return database.Conn.BlobIO("main", "snip", "data", row, false)
+}
@@ -0,0 +1,48 @@
+package snip
+
+import (
+	"errors"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+	"testing"
+)
+
+func TestOpenData(t *testing.T) {
+	s := New()
+	s.Data = "first line\nsecond line\n"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	id, err := ResolveUUID(s.UUID.String()[:13])
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if id != s.UUID {
+		t.Errorf("expected %s, got %s", s.UUID, id)
+	}
+
+	r, err := OpenData(id)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	defer r.Close()
+	if _, err = r.Seek(11, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(data) != "second line\n" {
+		t.Errorf("expected data from offset 11, got %q", data)
+	}
+
+	if _, err = ResolveUUID(""); err == nil {
+		t.Error("expected error for empty uuid")
+	}
+	if _, err = ResolveUUID("zzzzzzzz"); !errors.Is(err, database.ErrNoRows) {
+		t.Errorf("expected ErrNoRows for a uuid matching no snip, got %v", err)
+	}
+}
@@ -1,9 +1,32 @@
 package database
 
 import (
+	"context"
 	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"sync"
 )
 
 var (
 	Conn *sqlite3.Conn
+	// Mu serializes use of Conn when it is shared between goroutines
+	Mu sync.Mutex
 )
+
+// InterruptOn interrupts the statements running on Conn once ctx is done, until the returned function is called.
+// That function waits for an interruption in progress, so that statements run after it returns are not interrupted.
+func InterruptOn(ctx context.Context) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			Conn.Interrupt()
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,168 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoRows is returned by QueryRow when the query returns nothing
+	ErrNoRows = errors.New("database search returned zero results")
+	// ErrMultipleRows is returned by QueryRow when the query returns more than one row
+	ErrMultipleRows = errors.New("database search returned multiple results")
+)
+
+var (
+	cacheMu sync.Mutex
+	// cache holds the prepared statements of each connection by their sql
+	cache = make(map[*sqlite3.Conn]map[string]*sqlite3.Stmt)
+	// inUse records whether each cached statement has been handed out by Prepare and not yet released
+	inUse = make(map[*sqlite3.Stmt]bool)
+)
+
+// Prepare returns a statement for query on Conn bound to args, reusing the statement prepared by an earlier call when it is not in use.
+// The statement must be given back with Release rather than closed.
+func Prepare(query string, args ...interface{}) (*sqlite3.Stmt, error) {
+	stmt, err := take(query)
+	if err != nil {
+		return nil, err
+	}
+	if err = stmt.Bind(args...); err != nil {
+		Release(stmt)
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// take returns the cached statement for query, preparing and caching it on first use.
+// A statement still in use, such as by a caller iterating over its rows, is left alone and an uncached one is prepared instead.
+func take(query string) (*sqlite3.Stmt, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	stmts, ok := cache[Conn]
+	if !ok {
+		stmts = make(map[string]*sqlite3.Stmt)
+		cache[Conn] = stmts
+	}
+	if stmt, ok := stmts[query]; ok && !inUse[stmt] {
+		inUse[stmt] = true
+		return stmt, nil
+	}
+
+	stmt, err := Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return nil, fmt.Errorf("query %q contains no statement", query)
+	}
+	if _, ok := stmts[query]; !ok {
+		stmts[query] = stmt
+		inUse[stmt] = true
+	}
+	return stmt, nil
+}
+
+// Release resets a statement returned by Prepare so that it may be used again, closing it if it was not cached
+func Release(stmt *sqlite3.Stmt) error {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if _, ok := inUse[stmt]; !ok {
+		return stmt.Close()
+	}
+	inUse[stmt] = false
+	// reset repeats the error of the last step, which has already been returned to the caller
+	stmt.Reset()
+	return stmt.ClearBindings()
+}
+
+// Exec runs query bound to args to completion using a cached statement
+func Exec(query string, args ...interface{}) error {
+	stmt, err := take(query)
+	if err != nil {
+		return err
+	}
+	defer Release(stmt)
+
+	return stmt.Exec(args...)
+}
+
+// QueryRow runs query bound to args and scans its only row into dst as Scan does
+func QueryRow(query string, args []interface{}, dst ...interface{}) error {
+	stmt, err := Prepare(query, args...)
+	if err != nil {
+		return err
+	}
+	defer Release(stmt)
+
+	hasRow, err := stmt.Step()
+	if err != nil {
+		return err
+	}
+	if !hasRow {
+		return ErrNoRows
+	}
+	if err = Scan(stmt, dst...); err != nil {
+		return err
+	}
+	hasRow, err = stmt.Step()
+	if err != nil {
+		return err
+	}
+	if hasRow {
+		return ErrMultipleRows
+	}
+	return nil
+}
+
+// Scan stores the columns of the current row of stmt into dst, parsing text columns into *uuid.UUID and *time.Time destinations.
+// Timestamps are stored as RFC 3339 text with nanoseconds. Other destinations are scanned by the statement itself.
+func Scan(stmt *sqlite3.Stmt, dst ...interface{}) error {
+	for idx, d := range dst {
+		var err error
+		switch d := d.(type) {
+		case *uuid.UUID:
+			var s string
+			if err = stmt.Scan(scanAt(idx, &s)...); err == nil {
+				*d, err = uuid.Parse(s)
+			}
+		case *time.Time:
+			var s string
+			if err = stmt.Scan(scanAt(idx, &s)...); err == nil {
+				*d, err = time.Parse(time.RFC3339Nano, s)
+			}
+		default:
+			err = stmt.Scan(scanAt(idx, d)...)
+		}
+		if err != nil {
+			return fmt.Errorf("scanning column %s: %w", stmt.ColumnName(idx), err)
+		}
+	}
+	return nil
+}
+
+// scanAt returns arguments to Stmt.Scan that store only column idx into d
+func scanAt(idx int, d interface{}) []interface{} {
+	args := make([]interface{}, idx+1)
+	args[idx] = d
+	return args
+}
+
+// Close finalizes the cached statements of Conn and closes it
+func Close() error {
+	cacheMu.Lock()
+	for _, stmt := range cache[Conn] {
+		stmt.Close()
+		delete(inUse, stmt)
+	}
+	delete(cache, Conn)
+	cacheMu.Unlock()
+
+	return Conn.Close()
+}
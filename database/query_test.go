@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/google/uuid"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	var err error
+	Conn, err = sqlite3.Open(filepath.Join(t.TempDir(), "query.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	if err = Conn.Exec(`CREATE TABLE item(uuid TEXT, timestamp TEXT, n INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	timestamp := time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)
+	for n := 1; n <= 2; n++ {
+		if err = Exec(`INSERT INTO item VALUES (?, ?, ?)`, id.String(), timestamp.Format(time.RFC3339Nano), n); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+	}
+
+	var scannedID uuid.UUID
+	var scannedTime time.Time
+	var n int
+	err = QueryRow(`SELECT uuid, timestamp, n FROM item WHERE n = ?`, []interface{}{2}, &scannedID, &scannedTime, &n)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if scannedID != id || !scannedTime.Equal(timestamp) || n != 2 {
+		t.Errorf("expected %s %s 2, got %s %s %d", id, timestamp, scannedID, scannedTime, n)
+	}
+	if err = QueryRow(`SELECT n FROM item WHERE n = ?`, []interface{}{3}, &n); !errors.Is(err, ErrNoRows) {
+		t.Errorf("expected ErrNoRows, got %v", err)
+	}
+	if err = QueryRow(`SELECT n FROM item`, nil, &n); !errors.Is(err, ErrMultipleRows) {
+		t.Errorf("expected ErrMultipleRows, got %v", err)
+	}
+	if err = QueryRow(`SELECT n FROM item WHERE n = 1`, nil, &scannedID); err == nil {
+		t.Errorf("expected error scanning an integer as a uuid")
+	}
+
+	// a statement is reused once released, and a second one is prepared while it is in use
+	query := `SELECT n FROM item ORDER BY n`
+	outer, err := Prepare(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = outer.Step(); err != nil {
+		t.Fatal(err)
+	}
+	inner, err := Prepare(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner == outer {
+		t.Errorf("expected a separate statement while the cached one is in use")
+	}
+	if _, err = inner.Step(); err != nil {
+		t.Fatal(err)
+	}
+	if err = inner.Scan(&n); err != nil || n != 1 {
+		t.Errorf("expected the separate statement to start from the first row, got %d %v", n, err)
+	}
+	if err = Release(inner); err != nil {
+		t.Errorf("expected nil err closing the uncached statement, got %v", err)
+	}
+	if err = Release(outer); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Prepare(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != outer {
+		t.Errorf("expected the released statement to be reused")
+	}
+	Release(again)
+}
+
+func TestInterruptOn(t *testing.T) {
+	var err error
+	Conn, err = sqlite3.Open(filepath.Join(t.TempDir(), "interrupt.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stop := InterruptOn(ctx)
+	var n int
+	// counts far longer than the deadline
+	err = QueryRow(`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n`, nil, &n)
+	stop()
+	if err == nil {
+		t.Fatalf("expected the query to be interrupted")
+	}
+
+	// queries after stop returns run to completion
+	if err = QueryRow(`SELECT 1`, nil, &n); err != nil || n != 1 {
+		t.Errorf("expected 1 and nil err, got %d and %v", n, err)
+	}
+}
@@ -0,0 +1,66 @@
+package snip
+
+import (
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+)
+
+// IntegrityCheck returns the problems sqlite reports with the database file, or nothing when it is sound
+func IntegrityCheck() ([]string, error) {
+	stmt, err := database.Conn.Prepare(`PRAGMA integrity_check`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var problems []string
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return problems, err
+		}
+		if !hasRow {
+			break
+		}
+		var result string
+		err = stmt.Scan(&result)
+		if err != nil {
+			return problems, err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	return problems, nil
+}
+
+// OrphanedAttachments returns the uuids of attachments whose snip no longer exists
+func OrphanedAttachments() ([]uuid.UUID, error) {
+	stmt, err := database.Conn.Prepare(`SELECT uuid FROM snip_attachment WHERE snip_uuid NOT IN (SELECT uuid FROM snip)`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var ids []uuid.UUID
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return ids, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr string
+		err = stmt.Scan(&idStr)
+		if err != nil {
+			return ids, err
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
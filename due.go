@@ -0,0 +1,97 @@
+package snip
+
+import (
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"time"
+)
+
+// Due is the time by which a snip, such as an action item, should be dealt with
+type Due struct {
+	UUID uuid.UUID
+	Due  time.Time
+}
+
+// Overdue determines if the due time has passed
+func (d Due) Overdue() bool {
+	return !time.Now().Before(d.Due)
+}
+
+// SetDue sets the time a snip is due, replacing any previous due time and allowing a new reminder
+func SetDue(id uuid.UUID, due time.Time) error {
+	stmt, err := database.Conn.Prepare(`INSERT OR REPLACE INTO snip_due (uuid, due, notified) VALUES (?, ?, 0)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec(id.String(), due.UTC().Format(sortableLayout))
+}
+
+// RemoveDue clears the due time of a snip
+func RemoveDue(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_due WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
+
+// ListDue returns the due time of every snip that has one, most urgent first
+func ListDue() ([]Due, error) {
+	return queryDue(`SELECT uuid, due FROM snip_due ORDER BY due`)
+}
+
+// PendingReminders returns the snips that have come due since their due time was set and have not been reminded of
+func PendingReminders() ([]Due, error) {
+	return queryDue(`SELECT uuid, due FROM snip_due WHERE notified = 0 AND due <= ? ORDER BY due`, sortableNow())
+}
+
+// MarkReminded records that a reminder of the due snip was given
+func MarkReminded(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`UPDATE snip_due SET notified = 1 WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
+
+// queryDue returns the uuid and due time of each row returned by query
+func queryDue(query string, args ...interface{}) ([]Due, error) {
+	stmt, err := database.Conn.Prepare(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var dues []Due
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return dues, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr, due string
+		err = stmt.Scan(&idStr, &due)
+		if err != nil {
+			return dues, err
+		}
+		d := Due{}
+		d.UUID, err = uuid.Parse(idStr)
+		if err != nil {
+			return dues, err
+		}
+		d.Due, err = time.Parse(sortableLayout, due)
+		if err != nil {
+			return dues, err
+		}
+		dues = append(dues, d)
+	}
+	return dues, nil
+}
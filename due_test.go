@@ -0,0 +1,69 @@
+package snip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDue(t *testing.T) {
+	later := New()
+	later.Data = "renew the passport"
+	soon := New()
+	soon.Data = "pay the electric bill"
+	for _, s := range []Snip{later, soon} {
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+	}
+
+	if err := SetDue(later.UUID, time.Now().Add(48*time.Hour)); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if err := SetDue(soon.UUID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	dues, err := ListDue()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(dues) != 2 || dues[0].UUID != soon.UUID || dues[1].UUID != later.UUID {
+		t.Fatalf("expected the overdue snip first, got %v", dues)
+	}
+	if !dues[0].Overdue() || dues[1].Overdue() {
+		t.Errorf("expected only the first snip to be overdue, got %v", dues)
+	}
+
+	reminders, err := PendingReminders()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(reminders) != 1 || reminders[0].UUID != soon.UUID {
+		t.Fatalf("expected a reminder for %s, got %v", soon.UUID, reminders)
+	}
+	if err = MarkReminded(soon.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if reminders, _ = PendingReminders(); len(reminders) != 0 {
+		t.Errorf("expected no reminders after marking, got %v", reminders)
+	}
+
+	// a new due time is reminded of again
+	if err = SetDue(soon.UUID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if reminders, _ = PendingReminders(); len(reminders) != 1 {
+		t.Errorf("expected a reminder after setting a new due time, got %v", reminders)
+	}
+
+	if err = RemoveDue(soon.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if err = Remove(later.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if dues, _ = ListDue(); len(dues) != 0 {
+		t.Errorf("expected no due snips, got %v", dues)
+	}
+}
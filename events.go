@@ -0,0 +1,204 @@
+package snip
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip/database"
+	"time"
+)
+
+// EventType describes the kind of change an Event represents
+type EventType string
+
+const (
+	EventCreate EventType = "create"
+	EventUpdate EventType = "update"
+	EventDelete EventType = "delete"
+	EventAttach EventType = "attach"
+	EventDetach EventType = "detach"
+)
+
+// EventPollInterval is how often subscribers check the event journal for new events
+var EventPollInterval = 500 * time.Millisecond
+
+// eventRetention is the number of most recent events kept in the journal
+const eventRetention = 10000
+
+// Event represents a change to a snip or its attachments
+type Event struct {
+	ID         int64
+	Type       EventType
+	UUID       uuid.UUID
+	Attachment uuid.UUID
+	Timestamp  time.Time
+}
+
+// recordEvent appends an event to the journal so that subscribers in any process observe the change
+func recordEvent(eventType EventType, id uuid.UUID, attachment uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`INSERT INTO snip_event (type, uuid, attachment, timestamp) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.Exec(string(eventType), id.String(), attachment.String(), time.Now().Format(time.RFC3339Nano))
+	if err != nil {
+		return err
+	}
+
+	// trim the journal to keep it from growing without bound
+	stmt2, err := database.Conn.Prepare(`DELETE FROM snip_event WHERE id <= ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt2.Close()
+
+	return stmt2.Exec(database.Conn.LastInsertRowID() - eventRetention)
+}
+
+// LastEventID returns the id of the most recent event in the journal, or zero if it is empty
+func LastEventID() (int64, error) {
+	var id int64
+	stmt, err := database.Conn.Prepare(`SELECT coalesce(max(id), 0) FROM snip_event`)
+	if err != nil {
+		return id, err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Step()
+	if err != nil {
+		return id, err
+	}
+	err = stmt.Scan(&id)
+	return id, err
+}
+
+// EventsSince returns all journal events with an id greater than lastID in order of occurrence
+func EventsSince(lastID int64) ([]Event, error) {
+	var events []Event
+
+	stmt, err := database.Conn.Prepare(`SELECT id, type, uuid, attachment, timestamp FROM snip_event WHERE id > ? ORDER BY id`, lastID)
+	if err != nil {
+		return events, err
+	}
+	defer stmt.Close()
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return events, err
+		}
+		if !hasRow {
+			break
+		}
+
+		var (
+			e             Event
+			eventType     string
+			idStr         string
+			attachmentStr string
+			timestamp     string
+		)
+		err = stmt.Scan(&e.ID, &eventType, &idStr, &attachmentStr, &timestamp)
+		if err != nil {
+			return events, err
+		}
+		e.Type = EventType(eventType)
+		e.UUID, err = uuid.Parse(idStr)
+		if err != nil {
+			return events, err
+		}
+		e.Attachment, err = uuid.Parse(attachmentStr)
+		if err != nil {
+			return events, err
+		}
+		e.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// ListModified returns the time of the latest change still in the journal to each snip, which is missing for snips whose events were trimmed
+func ListModified() (map[uuid.UUID]time.Time, error) {
+	modified := make(map[uuid.UUID]time.Time)
+
+	stmt, err := database.Conn.Prepare(`SELECT uuid, timestamp FROM snip_event WHERE id IN (SELECT max(id) FROM snip_event WHERE type != ? GROUP BY uuid)`, string(EventDelete))
+	if err != nil {
+		return modified, err
+	}
+	defer stmt.Close()
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return modified, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr, timestamp string
+		err = stmt.Scan(&idStr, &timestamp)
+		if err != nil {
+			return modified, err
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return modified, err
+		}
+		modified[id], err = time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return modified, err
+		}
+	}
+	return modified, nil
+}
+
+// Subscribe returns a channel receiving all events that occur after subscribing until ctx is done
+func Subscribe(ctx context.Context) (<-chan Event, error) {
+	database.Mu.Lock()
+	lastID, err := LastEventID()
+	database.Mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return SubscribeSince(ctx, lastID), nil
+}
+
+// SubscribeSince returns a channel receiving all events with an id greater than lastID until ctx is done
+func SubscribeSince(ctx context.Context, lastID int64) <-chan Event {
+	events := make(chan Event, 64)
+
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(EventPollInterval)
+		defer ticker.Stop()
+
+		for {
+			database.Mu.Lock()
+			pending, err := EventsSince(lastID)
+			database.Mu.Unlock()
+			if err != nil {
+				log.Debug().Err(err).Msg("error reading event journal")
+			}
+			for _, e := range pending {
+				select {
+				case events <- e:
+					lastID = e.ID
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
@@ -0,0 +1,81 @@
+package snip
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestEventsSince(t *testing.T) {
+	lastID, err := LastEventID()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	s := New()
+	s.Data = "event journal test"
+	if err = InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Attach("event.txt", []byte("attached")); err != nil {
+		t.Fatal(err)
+	}
+	if err = Remove(s.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := EventsSince(lastID)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	expected := []EventType{EventCreate, EventAttach, EventDetach, EventDelete}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %v", len(expected), len(events), events)
+	}
+	for idx, e := range events {
+		if e.Type != expected[idx] {
+			t.Errorf("expected event %s, got %s", expected[idx], e.Type)
+		}
+		if e.UUID != s.UUID {
+			t.Errorf("expected event uuid %s, got %s", s.UUID, e.UUID)
+		}
+	}
+	if events[1].Attachment == uuid.Nil || events[1].Attachment != events[2].Attachment {
+		t.Errorf("expected attach and detach events to reference the attachment")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	interval := EventPollInterval
+	EventPollInterval = 10 * time.Millisecond
+	defer func() { EventPollInterval = interval }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	s := New()
+	s.Data = "subscription test"
+	if err = InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	select {
+	case e := <-events:
+		if e.Type != EventCreate || e.UUID != s.UUID {
+			t.Errorf("expected create event for %s, got %s for %s", s.UUID, e.Type, e.UUID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	// channel is closed once the context is done
+	cancel()
+	for range events {
+	}
+}
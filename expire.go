@@ -0,0 +1,120 @@
+package snip
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"time"
+)
+
+// sortableLayout stores times in UTC at a fixed width so that they compare correctly as text
+const sortableLayout = "2006-01-02T15:04:05Z"
+
+// unexpired is a condition on a uuid column that excludes expired snips, taking sortableNow as its parameter
+const unexpired = `uuid NOT IN (SELECT uuid FROM snip_expire WHERE expires <= ?)`
+
+// Expiry is the time after which a snip is hidden from listings and searches, and may be purged
+type Expiry struct {
+	UUID    uuid.UUID
+	Expires time.Time
+}
+
+// Expired determines if the snip is past its expiry
+func (e Expiry) Expired() bool {
+	return !time.Now().Before(e.Expires)
+}
+
+// sortableNow returns the current time in sortableLayout
+func sortableNow() string {
+	return time.Now().UTC().Format(sortableLayout)
+}
+
+// SetExpiry sets the time after which a snip expires, replacing any previous expiry
+func SetExpiry(id uuid.UUID, expires time.Time) error {
+	stmt, err := database.Conn.Prepare(`INSERT OR REPLACE INTO snip_expire (uuid, expires) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec(id.String(), expires.UTC().Format(sortableLayout))
+}
+
+// RemoveExpiry keeps a snip indefinitely
+func RemoveExpiry(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_expire WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
+
+// IsExpired reports whether a snip has passed its expiry
+func IsExpired(id uuid.UUID) (bool, error) {
+	var count int
+	err := database.QueryRow(`SELECT count(*) FROM snip_expire WHERE uuid = ? AND expires <= ?`, []interface{}{id.String(), sortableNow()}, &count)
+	return count > 0, err
+}
+
+// ListExpiries returns the expiry of every snip that has one, soonest first
+func ListExpiries() ([]Expiry, error) {
+	stmt, err := database.Conn.Prepare(`SELECT uuid, expires FROM snip_expire ORDER BY expires`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var expiries []Expiry
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return expiries, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr, expires string
+		err = stmt.Scan(&idStr, &expires)
+		if err != nil {
+			return expiries, err
+		}
+		e := Expiry{}
+		e.UUID, err = uuid.Parse(idStr)
+		if err != nil {
+			return expiries, err
+		}
+		e.Expires, err = time.Parse(sortableLayout, expires)
+		if err != nil {
+			return expiries, err
+		}
+		expiries = append(expiries, e)
+	}
+	return expiries, nil
+}
+
+// PurgeExpired removes every expired snip and returns their uuids. Locked snips are kept until they are unlocked.
+func PurgeExpired() ([]uuid.UUID, error) {
+	expiries, err := ListExpiries()
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []uuid.UUID
+	for _, e := range expiries {
+		if !e.Expired() {
+			// sorted soonest first
+			break
+		}
+		err = Remove(e.UUID)
+		if errors.Is(err, ErrLocked) {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged = append(purged, e.UUID)
+	}
+	return purged, nil
+}
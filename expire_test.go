@@ -0,0 +1,94 @@
+package snip
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestExpiry(t *testing.T) {
+	s := New()
+	s.Data = "ephemeral xylophonic paste"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	if err := s.Index(); err != nil {
+		t.Fatal(err)
+	}
+
+	listed := func(list func() ([]uuid.UUID, error)) bool {
+		ids, err := list()
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		for _, id := range ids {
+			if id == s.UUID {
+				return true
+			}
+		}
+		return false
+	}
+	visible := func() bool {
+		return listed(GetUnexpiredSnipIDs)
+	}
+
+	if err := SetExpiry(s.UUID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !visible() {
+		t.Error("expected snip to be listed before it expires")
+	}
+
+	if err := SetExpiry(s.UUID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if visible() {
+		t.Error("expected expired snip to be hidden from GetUnexpiredSnipIDs")
+	}
+	// maintenance such as rebuilding the index still covers expired snips
+	if !listed(GetAllSnipIDs) {
+		t.Error("expected expired snip to be kept in GetAllSnipIDs")
+	}
+	results, err := SearchIndexTerm([]string{"xylophonic"}, true)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if _, ok := results[s.UUID]; ok {
+		t.Error("expected expired snip to be hidden from search")
+	}
+	if _, err = GetFromUUID(s.UUID.String()); err != nil {
+		t.Errorf("expected expired snip to be retrievable until purged, got %v", err)
+	}
+
+	// locked snips survive a purge
+	if err = Lock(s.UUID); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := PurgeExpired()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(purged) != 0 {
+		t.Errorf("expected locked snip to be kept, purged %v", purged)
+	}
+	if err = Unlock(s.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err = PurgeExpired()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(purged) != 1 || purged[0] != s.UUID {
+		t.Errorf("expected %s to be purged, got %v", s.UUID, purged)
+	}
+	expiries, err := ListExpiries()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(expiries) != 0 {
+		t.Errorf("expected no remaining expiries, got %v", expiries)
+	}
+}
@@ -0,0 +1,164 @@
+package snip
+
+import (
+	"github.com/kljensen/snowball"
+	"github.com/ryanfrishkorn/snip/database"
+	"strings"
+	"time"
+)
+
+// SearchTermLookup describes the index entries consulted for one search term
+type SearchTermLookup struct {
+	Term string
+	Stem string
+	// Snips is the number of unexpired snips indexed under the stem
+	Snips int
+	// Occurrences is the total count of the stem in those snips
+	Occurrences int
+}
+
+// SearchStage is the time taken by one stage of an index search and the number of snips remaining after it
+type SearchStage struct {
+	Name       string
+	Duration   time.Duration
+	Candidates int
+}
+
+// QueryPlan is the plan sqlite chose for a query run by a search stage
+type QueryPlan struct {
+	Stage string
+	Query string
+	// Steps are the plan details, indented by their depth in the plan
+	Steps []string
+}
+
+// SearchExplanation describes how an index search found its results
+type SearchExplanation struct {
+	Terms   []SearchTermLookup
+	Stages  []SearchStage
+	Plans   []QueryPlan
+	Matches []SearchMatch
+	Total   time.Duration
+}
+
+// ExplainSearch runs the same stages as SearchWithContext, timing each and recording the index entries consulted and the query plans used
+func ExplainSearch(terms []string, limit int, adjacent int) (SearchExplanation, error) {
+	var e SearchExplanation
+	terms = queryTerms(terms)
+	begin := time.Now()
+	stage := func(name string, started time.Time, candidates int) {
+		e.Stages = append(e.Stages, SearchStage{Name: name, Duration: time.Since(started), Candidates: candidates})
+	}
+
+	started := time.Now()
+	found, err := SearchIndexTerm(terms, false)
+	if err != nil {
+		return e, err
+	}
+	stage("lookup", started, len(found))
+
+	started = time.Now()
+	fields, err := matchFields(terms)
+	if err != nil {
+		return e, err
+	}
+	stage("fields", started, len(fields))
+
+	started = time.Now()
+	pruned := pruneResults(terms, found, fields)
+	stage("prune", started, len(pruned))
+
+	started = time.Now()
+	scores, err := rankResults(terms, pruned, fields, limit)
+	if err != nil {
+		return e, err
+	}
+	stage("score", started, len(scores))
+
+	started = time.Now()
+	e.Matches, err = gatherMatches(terms, scores, adjacent)
+	if err != nil {
+		return e, err
+	}
+	stage("context", started, len(e.Matches))
+	e.Total = time.Since(begin)
+
+	for _, term := range terms {
+		// wildcard terms are not stemmed
+		stem := term
+		if !isWildcard(term) {
+			if stem, err = snowball.Stem(term, "english", true); err != nil {
+				return e, err
+			}
+		}
+		lookup := SearchTermLookup{Term: term, Stem: stem}
+		for _, counts := range found {
+			var matched bool
+			for _, c := range counts {
+				if c.Term == term {
+					matched = true
+					lookup.Occurrences += c.Count
+				}
+			}
+			if matched {
+				lookup.Snips++
+			}
+		}
+		e.Terms = append(e.Terms, lookup)
+	}
+
+	plans := []struct {
+		stage string
+		query string
+	}{
+		{"lookup", indexTermQuery},
+		{"fields", fieldNamesQuery},
+		{"fields", fieldTagsQuery},
+		{"score", cumulativeTermsCountsQuery},
+		{"context", snipsByIDQuery},
+		{"context", termPositionsQuery},
+	}
+	for _, p := range plans {
+		steps, err := queryPlan(p.query)
+		if err != nil {
+			return e, err
+		}
+		e.Plans = append(e.Plans, QueryPlan{Stage: p.stage, Query: p.query, Steps: steps})
+	}
+	return e, nil
+}
+
+// queryPlan returns the steps of the plan sqlite chooses for query, with parameters left unbound
+func queryPlan(query string) ([]string, error) {
+	var steps []string
+
+	stmt, err := database.Conn.Prepare(`EXPLAIN QUERY PLAN ` + query)
+	if err != nil {
+		return steps, err
+	}
+	defer stmt.Close()
+
+	depths := make(map[int]int)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return steps, err
+		}
+		if !hasRow {
+			break
+		}
+		var id, parent, unused int
+		var detail string
+		err = stmt.Scan(&id, &parent, &unused, &detail)
+		if err != nil {
+			return steps, err
+		}
+		depth := 0
+		if parent != 0 {
+			depth = depths[parent] + 1
+		}
+		depths[id] = depth
+		steps = append(steps, strings.Repeat("  ", depth)+detail)
+	}
+	return steps, nil
+}
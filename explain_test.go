@@ -0,0 +1,55 @@
+package snip
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplainSearch(t *testing.T) {
+	for _, data := range []string{"explained searching of zebras", "explained zebra crossing", "explained nothing"} {
+		s := New()
+		s.Data = data
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		if err := s.Index(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e, err := ExplainSearch([]string{"explained", "zebras"}, 0, 2)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(e.Terms) != 2 || e.Terms[0].Snips != 3 || e.Terms[1].Stem != "zebra" || e.Terms[1].Snips != 2 {
+		t.Errorf("expected 3 snips for the first term and 2 for the stem zebra, got %+v", e.Terms)
+	}
+	var stages []string
+	for _, stage := range e.Stages {
+		stages = append(stages, stage.Name)
+	}
+	if strings.Join(stages, ",") != "lookup,fields,prune,score,context" {
+		t.Errorf("expected the lookup, fields, prune, score, and context stages, got %v", stages)
+	}
+	if e.Stages[0].Candidates != 3 || e.Stages[2].Candidates != 2 || len(e.Matches) != 2 {
+		t.Errorf("expected 3 candidates pruned to 2 matches, got %+v with %d matches", e.Stages, len(e.Matches))
+	}
+
+	matches, err := SearchWithContext([]string{"explained", "zebras"}, 0, 2)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(matches) != len(e.Matches) || matches[0].UUID != e.Matches[0].UUID {
+		t.Errorf("expected the same matches as SearchWithContext, got %v and %v", e.Matches, matches)
+	}
+
+	if len(e.Plans) != 6 {
+		t.Fatalf("expected 6 query plans, got %d", len(e.Plans))
+	}
+	for _, plan := range e.Plans {
+		if len(plan.Steps) == 0 || !strings.Contains(plan.Steps[0], "snip") {
+			t.Errorf("expected the %s plan to name a table, got %v", plan.Stage, plan.Steps)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"encoding/xml"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"sort"
+	"time"
+)
+
+// atomFeed is the root element of an Atom feed
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Link    *atomLink   `xml:"link,omitempty"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomAuthor names the author of a feed
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+// atomLink refers to the location of a feed or entry
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+// atomEntry is a single snip in a feed
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Content atomContent `xml:"content"`
+}
+
+// atomContent is the rendered html of a snip
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Feed writes an Atom feed of the most recent snips, limit of zero includes all snips.
+// selfURL is the address the feed is published at and may be empty.
+func Feed(w io.Writer, title string, selfURL string, limit int) error {
+	snips, err := snip.List(0)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(snips, func(i, j int) bool {
+		return snips[i].Timestamp.After(snips[j].Timestamp)
+	})
+	if limit > 0 && len(snips) > limit {
+		snips = snips[:limit]
+	}
+
+	feed := atomFeed{
+		// stable across runs so that readers recognize the same feed
+		ID:      "urn:snip:feed",
+		Title:   title,
+		Updated: time.Now().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "snip"},
+	}
+	if len(snips) > 0 {
+		feed.Updated = snips[0].Timestamp.Format(time.RFC3339)
+	}
+	if selfURL != "" {
+		feed.ID = selfURL
+		feed.Link = &atomLink{Rel: "self", Href: selfURL}
+	}
+
+	for _, s := range snips {
+		body, err := SnipMarkdown(s.UUID, s.Data)
+		if err != nil {
+			return err
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      "urn:uuid:" + s.UUID.String(),
+			Title:   s.Name,
+			Updated: s.Timestamp.Format(time.RFC3339),
+			Content: atomContent{Type: "html", Body: string(body)},
+		})
+	}
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err = encoder.Encode(feed); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
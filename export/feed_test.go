@@ -0,0 +1,33 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestFeed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Feed(&buf, "Test Feed", "https://example.com/feed.xml", 1); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+		t.Fatalf("expected valid xml, got %v: %s", err, buf.String())
+	}
+	if feed.Title != "Test Feed" || feed.Link == nil || feed.Link.Href != "https://example.com/feed.xml" {
+		t.Errorf("expected feed title and self link, got %+v", feed)
+	}
+	if len(feed.Entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(feed.Entries))
+	}
+	entry := feed.Entries[0]
+	if entry.ID != "urn:uuid:"+testSnip.UUID.String() || entry.Title != testSnip.Name {
+		t.Errorf("expected entry for %s, got %+v", testSnip.UUID, entry)
+	}
+	if !strings.Contains(entry.Content.Body, "<h1>Wrens</h1>") {
+		t.Errorf("expected rendered content, got %s", entry.Content.Body)
+	}
+}
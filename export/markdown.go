@@ -0,0 +1,46 @@
+// Package export renders snips into formats meant for reading outside of snip
+package export
+
+import (
+	"bytes"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"html/template"
+	"strings"
+)
+
+// markdown converts github flavored markdown, omitting raw html so that exported pages are safe to publish
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// Markdown renders snip data as html
+func Markdown(data string) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(data), &buf); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+// SnipMarkdown renders the data of snip id as html, as a single code block marked with its language when the snip was detected
+// to be code without any fenced blocks of its own, so that the code keeps its layout and is highlighted where the page supports it
+func SnipMarkdown(id uuid.UUID, data string) (template.HTML, error) {
+	lang, err := snip.GetLanguage(id)
+	if err != nil {
+		return "", err
+	}
+	if lang == "" || len(snip.CodeBlocks(data)) > 0 {
+		return Markdown(data)
+	}
+	return Markdown(fenceCode(data, lang))
+}
+
+// fenceCode encloses data in a fenced code block of lang, with a fence longer than any run of backticks within it
+func fenceCode(data string, lang string) string {
+	fence := "```"
+	for strings.Contains(data, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + strings.TrimSuffix(data, "\n") + "\n" + fence + "\n"
+}
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxFileName is the length in characters beyond which snip names are shortened to name markdown files
+const maxFileName = 100
+
+// MarkdownFiles writes each snip to dir as a markdown file named after it, with front matter holding its uuid, timestamp, tags,
+// and metadata so that snip import dir restores it unchanged. Links to other snips become wiki links to their files, so that the
+// directory may be opened as an Obsidian vault. It returns the number of files written, reporting each to progress, which may be nil.
+func MarkdownFiles(dir string, progress snip.Progress) (int, error) {
+	ids, err := snip.GetUnexpiredSnipIDs()
+	if err != nil {
+		return 0, err
+	}
+	var snips []snip.Snip
+	for _, id := range ids {
+		s, err := snip.GetSnipMeta(id.String())
+		if err != nil {
+			return 0, err
+		}
+		snips = append(snips, s)
+	}
+	// the oldest snip keeps a name shared by several
+	sort.SliceStable(snips, func(i, j int) bool {
+		return snips[i].Timestamp.Before(snips[j].Timestamp)
+	})
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+
+	// every file is named first so that links to snips written later can be rewritten
+	used := make(map[string]bool)
+	files := make(map[uuid.UUID]string)
+	for _, s := range snips {
+		files[s.UUID] = markdownFileName(s, used)
+	}
+	linkedFile := func(id uuid.UUID) (string, bool) {
+		name, ok := files[id]
+		return strings.TrimSuffix(name, ".md"), ok
+	}
+	for idx, s := range snips {
+		if err = s.LoadData(); err != nil {
+			return idx, err
+		}
+		s.Data = snip.SnipLinksToWikiLinks(s.Data, linkedFile)
+		doc, err := snip.MarkdownDocument(s)
+		if err != nil {
+			return idx, fmt.Errorf("%s: %w", s.UUID, err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, files[s.UUID]), []byte(doc), 0644); err != nil {
+			return idx, err
+		}
+		if progress != nil {
+			progress.Step("exporting", idx+1, len(snips))
+		}
+	}
+	return len(snips), nil
+}
+
+// markdownFileName returns a file name for s made from its name, adding its short uuid when the name is in used, which it is added to.
+// Names are compared without case for file systems that ignore it.
+func markdownFileName(s snip.Snip, used map[string]bool) string {
+	base := strings.Map(func(r rune) rune {
+		// characters file systems refuse, and those wiki links cannot hold
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|#^[]`, r) {
+			return '-'
+		}
+		return r
+	}, s.Name)
+	base = strings.Trim(strings.TrimSpace(base), ".")
+	if runes := []rune(base); len(runes) > maxFileName {
+		base = strings.TrimSpace(string(runes[:maxFileName]))
+	}
+	short := snip.ShortenUUID(s.UUID)[0]
+	if base == "" {
+		base = short
+	}
+	name := base + ".md"
+	if used[strings.ToLower(name)] {
+		name = base + " " + short + ".md"
+	}
+	used[strings.ToLower(name)] = true
+	return name
+}
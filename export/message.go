@@ -0,0 +1,127 @@
+package export
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message composes an email containing a snip and its attachments.
+// The body is sent as plain text, along with rendered html unless raw is set.
+func Message(s snip.Snip, from string, to []string, raw bool) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := "snip"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = strings.TrimSuffix(from[at+1:], ">")
+	}
+
+	header := []string{
+		"From: " + from,
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", s.Name),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: <" + hex.EncodeToString(id) + "@" + domain + ">",
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/mixed; boundary=" + writer.Boundary(),
+	}
+	buf.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	// the body alternatives are nested so that attachments follow them
+	var body bytes.Buffer
+	alternative := multipart.NewWriter(&body)
+	err := writeQuotedPart(alternative, "text/plain; charset=utf-8", s.Data)
+	if err != nil {
+		return nil, err
+	}
+	if !raw {
+		html, err := SnipMarkdown(s.UUID, s.Data)
+		if err != nil {
+			return nil, err
+		}
+		err = writeQuotedPart(alternative, "text/html; charset=utf-8", string(html))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = alternative.Close(); err != nil {
+		return nil, err
+	}
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alternative.Boundary()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = part.Write(body.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for _, a := range s.Attachments {
+		contentType := mime.TypeByExtension(filepath.Ext(a.Name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = writeBase64Lines(part, a.Data); err != nil {
+			return nil, fmt.Errorf("encoding attachment %s: %w", a.Name, err)
+		}
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPart adds a quoted-printable text part to writer
+func writeQuotedPart(writer *multipart.Writer, contentType string, content string) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err = io.WriteString(qp, content); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeBase64Lines writes data as base64 wrapped at 76 characters as mail requires
+func writeBase64Lines(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"github.com/ryanfrishkorn/snip"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// readParts returns the content types and decoded bodies of the parts of a multipart body
+func readParts(t *testing.T, contentType string, body io.Reader) ([]string, [][]byte) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("expected nil err parsing %q, got %v", contentType, err)
+	}
+	var types []string
+	var bodies [][]byte
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("expected nil err reading part, got %v", err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("expected nil err reading part body, got %v", err)
+		}
+		types = append(types, part.Header.Get("Content-Type"))
+		bodies = append(bodies, data)
+	}
+	return types, bodies
+}
+
+func TestMessage(t *testing.T) {
+	s := snip.New()
+	s.Name = "Mail Tést"
+	s.Data = "Some **bold** text."
+	s.Attachments = []snip.Attachment{{Name: "notes.txt", Data: []byte("attached")}}
+
+	for _, raw := range []bool{false, true} {
+		data, err := Message(s, "Snip <snip@example.com>", []string{"a@example.com", "b@example.com"}, raw)
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("expected nil err parsing message, got %v", err)
+		}
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil || subject != s.Name {
+			t.Errorf("expected subject %q, got %q (%v)", s.Name, subject, err)
+		}
+		if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+			t.Errorf("expected message id in sender domain, got %s", msg.Header.Get("Message-ID"))
+		}
+
+		types, bodies := readParts(t, msg.Header.Get("Content-Type"), msg.Body)
+		if len(types) != 2 {
+			t.Fatalf("expected body and attachment parts, got %v", types)
+		}
+		attachment, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(bodies[1])))
+		if err != nil || types[1] != "text/plain; charset=utf-8" || string(attachment) != "attached" {
+			t.Errorf("expected attachment content, got %s %q (%v)", types[1], attachment, err)
+		}
+
+		// multipart readers decode quoted-printable parts transparently
+		alternatives, texts := readParts(t, types[0], bytes.NewReader(bodies[0]))
+		if string(texts[0]) != s.Data {
+			t.Errorf("expected plain text %q, got %q", s.Data, texts[0])
+		}
+		if raw && len(alternatives) != 1 {
+			t.Errorf("expected only plain text when raw, got %v", alternatives)
+		}
+		if !raw && (len(alternatives) != 2 || !strings.Contains(string(texts[1]), "<strong>bold</strong>")) {
+			t.Errorf("expected rendered html alternative, got %v", alternatives)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-pdf/fpdf"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pdf page layout in millimeters
+const (
+	pdfMargin     = 20.0
+	pdfLineHeight = 5.5
+	pdfFontSize   = 11.0
+)
+
+// pdfImageTypes maps attachment file extensions to the image formats a pdf can embed
+var pdfImageTypes = map[string]string{
+	".png":  "PNG",
+	".jpg":  "JPG",
+	".jpeg": "JPG",
+	".gif":  "GIF",
+}
+
+// pdfRenderer draws a markdown document onto pdf pages
+type pdfRenderer struct {
+	pdf    *fpdf.Fpdf
+	source []byte
+	// translate converts utf-8 to the encoding of the core pdf fonts
+	translate func(string) string
+	// images are the attachments that can be embedded, by name
+	images map[string]snip.Attachment
+	// embedded records images already placed by the document
+	embedded map[string]bool
+	// style is the current font style of inline text
+	style string
+}
+
+// PDF writes a snip as a pdf document, rendering its markdown and embedding its image attachments
+func PDF(w io.Writer, s snip.Snip) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(s.Name, true)
+	pdf.SetCreator("snip", true)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.AddPage()
+
+	r := &pdfRenderer{
+		pdf:       pdf,
+		source:    []byte(s.Data),
+		translate: pdf.UnicodeTranslatorFromDescriptor(""),
+		images:    make(map[string]snip.Attachment),
+		embedded:  make(map[string]bool),
+	}
+	for _, a := range s.Attachments {
+		if _, ok := pdfImageTypes[strings.ToLower(filepath.Ext(a.Name))]; ok {
+			r.images[a.Name] = a
+		}
+	}
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(0, 9, r.translate(s.Name), "", "L", false)
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.CellFormat(0, 6, s.Timestamp.Format(time.RFC1123), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(4)
+
+	doc := markdown.Parser().Parse(text.NewReader(r.source))
+	r.blocks(doc, 0)
+
+	// images that the text does not refer to are placed at the end
+	var remaining []snip.Attachment
+	for _, a := range s.Attachments {
+		if _, ok := r.images[a.Name]; ok && !r.embedded[a.Name] {
+			remaining = append(remaining, a)
+		}
+	}
+	if len(remaining) > 0 {
+		r.heading("Attachments", 2)
+		for _, a := range remaining {
+			r.image(a.Name)
+		}
+	}
+
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+// blocks renders the block children of node indented by indent millimeters
+func (r *pdfRenderer) blocks(node ast.Node, indent float64) {
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		r.pdf.SetLeftMargin(pdfMargin + indent)
+		r.pdf.SetX(pdfMargin + indent)
+
+		switch n := child.(type) {
+		case *ast.Heading:
+			r.heading(r.plainText(n), n.Level)
+
+		case *ast.Paragraph, *ast.TextBlock:
+			r.setFont("")
+			r.inlines(n)
+			r.pdf.Ln(pdfLineHeight)
+			if _, ok := n.(*ast.Paragraph); ok {
+				r.pdf.Ln(2)
+			}
+
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			var code strings.Builder
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				segment := lines.At(i)
+				code.Write(segment.Value(r.source))
+			}
+			r.pdf.SetFont("Courier", "", 9)
+			r.pdf.SetFillColor(240, 240, 240)
+			r.pdf.MultiCell(0, 4.5, r.translate(strings.TrimRight(code.String(), "\n")), "", "L", true)
+			r.pdf.Ln(3)
+
+		case *ast.List:
+			number := n.Start
+			for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+				marker := "•"
+				if n.IsOrdered() {
+					marker = fmt.Sprintf("%d.", number)
+					number++
+				}
+				r.pdf.SetLeftMargin(pdfMargin + indent)
+				r.pdf.SetX(pdfMargin + indent)
+				r.setFont("")
+				r.pdf.CellFormat(6, pdfLineHeight, r.translate(marker), "", 0, "L", false, 0, "")
+				r.blocks(item, indent+6)
+			}
+			r.pdf.Ln(1)
+
+		case *ast.Blockquote:
+			r.pdf.SetTextColor(90, 90, 90)
+			r.blocks(n, indent+6)
+			r.pdf.SetTextColor(0, 0, 0)
+
+		case *ast.ThematicBreak:
+			y := r.pdf.GetY() + 2
+			width, _ := r.pdf.GetPageSize()
+			r.pdf.Line(pdfMargin+indent, y, width-pdfMargin, y)
+			r.pdf.Ln(5)
+
+		case *east.Table:
+			r.table(n)
+
+		case *ast.HTMLBlock:
+			// raw html has no pdf equivalent
+
+		default:
+			r.blocks(n, indent)
+		}
+	}
+	r.pdf.SetLeftMargin(pdfMargin + indent)
+}
+
+// heading renders a heading sized by its level
+func (r *pdfRenderer) heading(title string, level int) {
+	sizes := map[int]float64{1: 18, 2: 15, 3: 13}
+	size, ok := sizes[level]
+	if !ok {
+		size = pdfFontSize + 1
+	}
+	r.pdf.Ln(2)
+	r.pdf.SetFont("Helvetica", "B", size)
+	r.pdf.MultiCell(0, size*0.5, r.translate(title), "", "L", false)
+	r.pdf.Ln(2)
+}
+
+// table renders each row of a table with its cells separated by vertical bars
+func (r *pdfRenderer) table(table *east.Table) {
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, r.plainText(cell))
+		}
+		style := ""
+		if _, ok := row.(*east.TableHeader); ok {
+			style = "B"
+		}
+		r.pdf.SetFont("Helvetica", style, pdfFontSize)
+		r.pdf.MultiCell(0, pdfLineHeight, r.translate(strings.Join(cells, "  |  ")), "", "L", false)
+	}
+	r.pdf.Ln(3)
+}
+
+// inlines writes the inline children of node as flowing text
+func (r *pdfRenderer) inlines(node ast.Node) {
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			r.pdf.Write(pdfLineHeight, r.translate(string(n.Segment.Value(r.source))))
+			if n.HardLineBreak() {
+				r.pdf.Ln(pdfLineHeight)
+			} else if n.SoftLineBreak() {
+				r.pdf.Write(pdfLineHeight, " ")
+			}
+
+		case *ast.String:
+			r.pdf.Write(pdfLineHeight, r.translate(string(n.Value)))
+
+		case *ast.CodeSpan:
+			r.pdf.SetFont("Courier", "", pdfFontSize-1)
+			r.pdf.Write(pdfLineHeight, r.translate(r.plainText(n)))
+			r.setFont(r.style)
+
+		case *ast.Emphasis:
+			previous := r.style
+			style := "I"
+			if n.Level > 1 {
+				style = "B"
+			}
+			if !strings.Contains(previous, style) {
+				r.setFont(previous + style)
+			}
+			r.inlines(n)
+			r.setFont(previous)
+
+		case *ast.Link:
+			r.pdf.SetTextColor(42, 93, 176)
+			r.pdf.WriteLinkString(pdfLineHeight, r.translate(r.plainText(n)), string(n.Destination))
+			r.pdf.SetTextColor(0, 0, 0)
+
+		case *ast.AutoLink:
+			url := string(n.URL(r.source))
+			r.pdf.SetTextColor(42, 93, 176)
+			r.pdf.WriteLinkString(pdfLineHeight, r.translate(url), url)
+			r.pdf.SetTextColor(0, 0, 0)
+
+		case *ast.Image:
+			name := filepath.Base(string(n.Destination))
+			if _, ok := r.images[name]; ok {
+				r.pdf.Ln(pdfLineHeight)
+				r.image(name)
+				r.setFont(r.style)
+			} else {
+				r.pdf.Write(pdfLineHeight, r.translate("["+r.plainText(n)+"]"))
+			}
+
+		case *ast.RawHTML:
+			// raw html has no pdf equivalent
+
+		default:
+			r.inlines(n)
+		}
+	}
+}
+
+// image embeds an image attachment on its own line, scaled down to fit the page width
+func (r *pdfRenderer) image(name string) {
+	a := r.images[name]
+	options := fpdf.ImageOptions{ImageType: pdfImageTypes[strings.ToLower(filepath.Ext(name))], ReadDpi: true}
+	info := r.pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(a.Data))
+	if info == nil || r.pdf.Err() {
+		// an unreadable image should not prevent the rest of the document from rendering
+		r.pdf.ClearError()
+		return
+	}
+
+	pageWidth, _ := r.pdf.GetPageSize()
+	left, _, right, _ := r.pdf.GetMargins()
+	available := pageWidth - left - right
+	width, _ := info.Extent()
+	if width > available {
+		width = available
+	}
+	r.pdf.ImageOptions(name, left, r.pdf.GetY(), width, 0, true, options, 0, "")
+	r.pdf.Ln(3)
+	r.embedded[name] = true
+}
+
+// setFont selects the body font with the given style
+func (r *pdfRenderer) setFont(style string) {
+	r.style = style
+	r.pdf.SetFont("Helvetica", style, pdfFontSize)
+}
+
+// plainText returns the text of node and its descendants without formatting
+func (r *pdfRenderer) plainText(node ast.Node) string {
+	var b strings.Builder
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(r.source))
+			if t.SoftLineBreak() {
+				b.WriteString(" ")
+			}
+		case *ast.String:
+			b.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
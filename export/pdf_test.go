@@ -0,0 +1,40 @@
+package export
+
+import (
+	"bytes"
+	"github.com/ryanfrishkorn/snip"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestPDF(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var imgData bytes.Buffer
+	if err := png.Encode(&imgData, img); err != nil {
+		t.Fatal(err)
+	}
+
+	s := snip.New()
+	s.Name = "PDF Test"
+	s.Data = "# Heading\n\nSome **bold** and `code`.\n\n```\nblock\n```\n\n- item\n\n![dot](dot.png)\n"
+	s.Attachments = []snip.Attachment{
+		{Name: "dot.png", Data: imgData.Bytes()},
+		{Name: "other.png", Data: imgData.Bytes()},
+		{Name: "notes.txt", Data: []byte("not an image")},
+	}
+
+	var buf bytes.Buffer
+	if err := PDF(&buf, s); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("expected pdf header, got %q", buf.Bytes()[:16])
+	}
+	// the referenced image is placed once, and the unreferenced image is appended
+	if count := bytes.Count(buf.Bytes(), []byte("/Subtype /Image")); count != 2 {
+		t.Errorf("expected 2 embedded images, got %d", count)
+	}
+}
@@ -0,0 +1,259 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// siteStyle is shared by every page of an exported site
+const siteStyle = `body { font-family: sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+pre { background: #f4f4f4; padding: 0.5em; overflow-x: auto; }
+a { color: #2a5db0; }
+.meta, .tags { color: #777; font-size: small; }
+#search { width: 100%; font-size: 1.1em; padding: 0.3em; }
+`
+
+// siteSearch filters the snip list by prefix matching each query word against the exported word index
+const siteSearch = `(function () {
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  var all = results.innerHTML;
+  var words = Object.keys(snipIndex.terms);
+  input.addEventListener("input", function () {
+    var query = input.value.toLowerCase().split(/\W+/).filter(function (w) { return w.length > 0; });
+    if (query.length === 0) { results.innerHTML = all; return; }
+    var scores = null;
+    query.forEach(function (q) {
+      var found = {};
+      words.forEach(function (word) {
+        if (word.indexOf(q) !== 0) { return; }
+        snipIndex.terms[word].forEach(function (posting) {
+          found[posting[0]] = (found[posting[0]] || 0) + posting[1] / (word === q ? 1 : 2);
+        });
+      });
+      if (scores === null) { scores = found; return; }
+      Object.keys(scores).forEach(function (doc) {
+        if (found[doc] === undefined) { delete scores[doc]; } else { scores[doc] += found[doc]; }
+      });
+    });
+    var ranked = Object.keys(scores).sort(function (a, b) { return scores[b] - scores[a]; });
+    results.innerHTML = "";
+    ranked.forEach(function (idx) {
+      var doc = snipIndex.docs[idx];
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = doc.url;
+      a.textContent = doc.name;
+      li.appendChild(a);
+      results.appendChild(li);
+    });
+  });
+})();
+`
+
+// siteTemplates lays out the index, tag, and snip pages
+var siteTemplates = template.Must(template.New("site").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Root}}style.css">
+</head>
+<body>
+<nav><a href="{{.Root}}index.html">all snips</a></nav>
+{{end}}
+
+{{define "footer"}}</body>
+</html>
+{{end}}
+
+{{define "list"}}<ul id="results">
+{{range .Snips}}<li><a href="{{$.Root}}snips/{{.UUID}}.html">{{.Name}}</a> <span class="meta">{{.Timestamp.Format "2006-01-02"}}</span></li>
+{{end}}</ul>
+{{end}}
+
+{{define "index"}}{{template "header" .}}<h1>{{.Title}}</h1>
+<input id="search" type="search" placeholder="search" autofocus>
+{{template "list" .}}
+{{if .Tags}}<p class="tags">tags: {{range .Tags}}<a href="tags/{{.}}.html">{{.}}</a> {{end}}</p>{{end}}
+<script src="search.js"></script>
+<script src="index.js"></script>
+{{template "footer" .}}{{end}}
+
+{{define "tag"}}{{template "header" .}}<h1>{{.Title}}</h1>
+{{template "list" .}}
+{{template "footer" .}}{{end}}
+
+{{define "snip"}}{{template "header" .}}<h1>{{.Snip.Name}}</h1>
+<p class="meta">{{.Snip.Timestamp.Format "2006-01-02 15:04"}}</p>
+{{if .Snip.Tags}}<p class="tags">{{range .Snip.Tags}}<a href="../tags/{{.}}.html">{{.}}</a> {{end}}</p>{{end}}
+{{.Body}}
+{{if .Snip.Attachments}}<h2>Attachments</h2>
+<ul>
+{{range .Snip.Attachments}}<li><a href="../attachments/{{.UUID}}/{{$.AttachmentFile .Name}}">{{.Name}}</a> ({{.Size}} bytes)</li>
+{{end}}</ul>
+{{end}}{{template "footer" .}}{{end}}
+`))
+
+// sitePage holds everything a site template may refer to
+type sitePage struct {
+	Title string
+	// Root is the relative path from the page to the top of the site
+	Root  string
+	Snips []snip.Snip
+	Tags  []string
+	Snip  snip.Snip
+	Body  template.HTML
+}
+
+// AttachmentFile returns the file name an attachment is written to, which may not leave its directory
+func (sitePage) AttachmentFile(name string) string {
+	name = filepath.Base(name)
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return "attachment"
+	}
+	return name
+}
+
+// siteIndex is the word index searched by the exported site
+type siteIndex struct {
+	Docs []siteDoc `json:"docs"`
+	// Terms maps each lower case word to pairs of document position and count
+	Terms map[string][][2]int `json:"terms"`
+}
+
+// siteDoc identifies a search result
+type siteDoc struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Site writes a static html site of all snips to dir, with client side search, tag pages, and attachments.
+// Each snip page written is reported to progress, which may be nil.
+func Site(dir string, title string, progress snip.Progress) error {
+	ids, err := snip.GetUnexpiredSnipIDs()
+	if err != nil {
+		return err
+	}
+	var snips []snip.Snip
+	for _, id := range ids {
+		s, err := snip.GetFromUUID(id.String())
+		if err != nil {
+			return err
+		}
+		snips = append(snips, s)
+	}
+	// newest first
+	sort.SliceStable(snips, func(i, j int) bool {
+		return snips[i].Timestamp.After(snips[j].Timestamp)
+	})
+
+	for _, sub := range []string{"snips", "tags", "attachments"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return err
+		}
+	}
+
+	index := siteIndex{Terms: make(map[string][][2]int)}
+	tagged := make(map[string][]snip.Snip)
+	for idx, s := range snips {
+		body, err := SnipMarkdown(s.UUID, s.Data)
+		if err != nil {
+			return fmt.Errorf("rendering %s: %w", s.UUID, err)
+		}
+		page := sitePage{Title: s.Name, Root: "../", Snip: s, Body: body}
+		err = writeSitePage(filepath.Join(dir, "snips", s.UUID.String()+".html"), "snip", page)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range s.Attachments {
+			err = writeSiteAttachment(dir, a.UUID.String(), page.AttachmentFile(a.Name))
+			if err != nil {
+				return err
+			}
+		}
+		for _, tag := range s.Tags {
+			tagged[tag] = append(tagged[tag], s)
+		}
+
+		if progress != nil {
+			progress.Step("exporting", idx+1, len(snips))
+		}
+
+		index.Docs = append(index.Docs, siteDoc{Name: s.Name, URL: "snips/" + s.UUID.String() + ".html"})
+		counts := make(map[string]int)
+		for _, word := range snip.DownCase(snip.StripPunctuation(snip.SplitWords(s.Name + " " + s.Data))) {
+			if word != "" {
+				counts[word]++
+			}
+		}
+		for word, count := range counts {
+			index.Terms[word] = append(index.Terms[word], [2]int{idx, count})
+		}
+	}
+
+	var tags []string
+	for tag, tagSnips := range tagged {
+		tags = append(tags, tag)
+		page := sitePage{Title: "tag: " + tag, Root: "../", Snips: tagSnips}
+		err = writeSitePage(filepath.Join(dir, "tags", tag+".html"), "tag", page)
+		if err != nil {
+			return err
+		}
+	}
+	sort.Strings(tags)
+
+	err = writeSitePage(filepath.Join(dir, "index.html"), "index", sitePage{Title: title, Snips: snips, Tags: tags})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	// a script rather than json so that the site also works from the local file system
+	err = os.WriteFile(filepath.Join(dir, "search.js"), []byte("var snipIndex = "+string(data)+";\n"), 0644)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(dir, "index.js"), []byte(siteSearch), 0644)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "style.css"), []byte(siteStyle), 0644)
+}
+
+// writeSitePage renders the named template to path
+func writeSitePage(path string, name string, page sitePage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = siteTemplates.ExecuteTemplate(f, name, page)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// writeSiteAttachment copies an attachment into the attachments directory of the site
+func writeSiteAttachment(dir string, id string, name string) error {
+	a, err := snip.GetAttachmentFromUUID(id)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(dir, "attachments", id)
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(target, name), a.Data, 0644)
+}
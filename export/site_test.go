@@ -0,0 +1,120 @@
+package export
+
+import (
+	"fmt"
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSnip snip.Snip
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "snip-export-test")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating temporary directory: %v", err)
+		os.Exit(1)
+	}
+
+	database.Conn, err = sqlite3.Open(filepath.Join(dir, "test.sqlite3"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening sqlite test database: %v", err)
+		os.Exit(1)
+	}
+	err = snip.CreateNewDatabase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating test database schema: %v", err)
+		os.Exit(1)
+	}
+
+	testSnip = snip.New()
+	testSnip.Name = "Export Test"
+	testSnip.Data = "# Wrens\n\nSmall *brown* birds.\n"
+	testSnip.Tags = []string{"birds"}
+	if err = (snip.LocalStore{}).Insert(testSnip); err != nil {
+		fmt.Fprintf(os.Stderr, "error inserting test snip: %v", err)
+		os.Exit(1)
+	}
+	if err = testSnip.Attach("../wren.txt", []byte("chirp")); err != nil {
+		fmt.Fprintf(os.Stderr, "error attaching to test snip: %v", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	database.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestMarkdown(t *testing.T) {
+	html, err := Markdown("**bold** <script>alert(1)</script>")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(string(html), "<strong>bold</strong>") {
+		t.Errorf("expected rendered emphasis, got %s", html)
+	}
+	if strings.Contains(string(html), "<script>") {
+		t.Errorf("expected raw html to be omitted, got %s", html)
+	}
+}
+
+func TestSnipMarkdown(t *testing.T) {
+	s := snip.New()
+	s.Name = "markdown language test"
+	s.Data = "# comment\ndef main():\n    print(\"```\")\n"
+	if err := snip.InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer snip.Remove(s.UUID)
+
+	html, err := SnipMarkdown(s.UUID, s.Data)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !strings.Contains(string(html), `<pre><code class="language-python"># comment`) {
+		t.Errorf("expected the code rendered as a python block, got %s", html)
+	}
+}
+
+func TestSite(t *testing.T) {
+	dir := t.TempDir()
+	if err := Site(dir, "Test Site", nil); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	page, err := os.ReadFile(filepath.Join(dir, "snips", testSnip.UUID.String()+".html"))
+	if err != nil {
+		t.Fatalf("expected snip page, got %v", err)
+	}
+	if !strings.Contains(string(page), "<h1>Wrens</h1>") || !strings.Contains(string(page), "<em>brown</em>") {
+		t.Errorf("expected rendered markdown in snip page, got %s", page)
+	}
+
+	tagPage, err := os.ReadFile(filepath.Join(dir, "tags", "birds.html"))
+	if err != nil {
+		t.Fatalf("expected tag page, got %v", err)
+	}
+	if !strings.Contains(string(tagPage), testSnip.UUID.String()) {
+		t.Errorf("expected tag page to link the snip, got %s", tagPage)
+	}
+
+	// attachment names may not escape their directory
+	matches, err := filepath.Glob(filepath.Join(dir, "attachments", "*", "wren.txt"))
+	if err != nil || len(matches) != 1 {
+		t.Errorf("expected one copied attachment, got %v %v", matches, err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(dir, "search.js"))
+	if err != nil {
+		t.Fatalf("expected search index, got %v", err)
+	}
+	if !strings.Contains(string(index), `"wrens":[[0,1]]`) {
+		t.Errorf("expected indexed word wrens, got %s", index)
+	}
+}
@@ -0,0 +1,226 @@
+package export
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// indexTables hold the search index, which snip index rebuilds from the snips, and are left out of sql dumps unless asked for
+var indexTables = map[string]bool{
+	"snip_index":   true,
+	"snip_term":    true,
+	"snip_trigram": true,
+}
+
+// cacheTables hold copies made from attachments on demand, which are never dumped
+var cacheTables = map[string]bool{
+	"snip_thumbnail": true,
+	// the data of attachments kept in blobs is written into snip_attachment instead
+	"snip_attachment_blob": true,
+}
+
+// SQLDump writes the rows of the snip tables as INSERT statements within a transaction, preceded by the statements creating the tables
+// and their indexes when schema is set. Rows are written in the order they were added so that dumps of the same database diff cleanly.
+// The search index is included only when index is set.
+func SQLDump(w io.Writer, schema bool, index bool) error {
+	tables, err := dumpTables(index)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "-- snip sql dump\nBEGIN TRANSACTION;\n")
+	for _, table := range tables {
+		if schema {
+			if err = dumpSchema(bw, table); err != nil {
+				return err
+			}
+		}
+		if err = dumpRows(bw, table); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(bw, "COMMIT;\n")
+	return bw.Flush()
+}
+
+// dumpTables returns the names of the snip tables in the open database, sorted by name
+func dumpTables(index bool) ([]string, error) {
+	var tables []string
+	stmt, err := database.Prepare(`SELECT name FROM sqlite_master WHERE type = 'table' AND (name = 'snip' OR name LIKE 'snip\_%' ESCAPE '\') ORDER BY name`)
+	if err != nil {
+		return tables, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return tables, err
+		}
+		if !hasRow {
+			break
+		}
+		var name string
+		if err = stmt.Scan(&name); err != nil {
+			return tables, err
+		}
+		if !cacheTables[name] && (index || !indexTables[name]) {
+			tables = append(tables, name)
+		}
+	}
+	return tables, nil
+}
+
+// dumpSchema writes the statements creating table and its indexes, which leave existing ones in place
+func dumpSchema(w io.Writer, table string) error {
+	stmt, err := database.Prepare(`SELECT type, sql FROM sqlite_master WHERE tbl_name = ? AND sql IS NOT NULL ORDER BY type = 'index', name`, table)
+	if err != nil {
+		return err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			return nil
+		}
+		var kind, sql string
+		if err = stmt.Scan(&kind, &sql); err != nil {
+			return err
+		}
+		// sqlite keeps the statement that created each table and index without IF NOT EXISTS
+		switch {
+		case strings.HasPrefix(sql, "CREATE TABLE "):
+			sql = "CREATE TABLE IF NOT EXISTS " + strings.TrimPrefix(sql, "CREATE TABLE ")
+		case strings.HasPrefix(sql, "CREATE INDEX "):
+			sql = "CREATE INDEX IF NOT EXISTS " + strings.TrimPrefix(sql, "CREATE INDEX ")
+		case strings.HasPrefix(sql, "CREATE UNIQUE INDEX "):
+			sql = "CREATE UNIQUE INDEX IF NOT EXISTS " + strings.TrimPrefix(sql, "CREATE UNIQUE INDEX ")
+		}
+		fmt.Fprintf(w, "%s;\n", sql)
+	}
+}
+
+// dumpRows writes an INSERT statement for each row of table
+func dumpRows(w io.Writer, table string) error {
+	stmt, err := database.Conn.Prepare(`SELECT * FROM ` + quoteIdentifier(table) + ` ORDER BY rowid`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	var columns []string
+	for _, name := range stmt.ColumnNames() {
+		columns = append(columns, quoteIdentifier(name))
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", quoteIdentifier(table), strings.Join(columns, ", "))
+	// attachments kept in blobs have no data in the database, so the dump holds the contents of their blobs
+	uuidColumn, dataColumn := -1, -1
+	if table == "snip_attachment" {
+		for idx, name := range stmt.ColumnNames() {
+			switch name {
+			case "uuid":
+				uuidColumn = idx
+			case "data":
+				dataColumn = idx
+			}
+		}
+	}
+	row := make([]interface{}, len(columns))
+	dst := make([]interface{}, len(columns))
+	for idx := range row {
+		dst[idx] = &row[idx]
+	}
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			return nil
+		}
+		if err = stmt.Scan(dst...); err != nil {
+			return err
+		}
+		if uuidColumn >= 0 && dataColumn >= 0 {
+			if err = inlineAttachmentData(row, uuidColumn, dataColumn); err != nil {
+				return err
+			}
+		}
+		values := make([]string, len(row))
+		for idx, v := range row {
+			values[idx] = sqlLiteral(v)
+		}
+		fmt.Fprintf(w, "%s%s);\n", prefix, strings.Join(values, ", "))
+	}
+}
+
+// inlineAttachmentData replaces the data of the snip_attachment row with the contents of its blob when it is kept in one
+func inlineAttachmentData(row []interface{}, uuidColumn int, dataColumn int) error {
+	idStr, _ := row[uuidColumn].(string)
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return fmt.Errorf("attachment %q: %w", idStr, err)
+	}
+	data, _ := row[dataColumn].([]byte)
+	data, err = snip.AttachmentData(id, data)
+	if err != nil {
+		return err
+	}
+	row[dataColumn] = data
+	return nil
+}
+
+// quoteIdentifier quotes the name of a table or column for sql
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqlLiteral writes a value returned by a query as a sql literal of the same storage class.
+// Blobs holding text are written as cast text rather than hex so that dumps remain readable.
+func sqlLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		// sqlite reads numbers too large for a double as infinity, and stores not a number as null
+		switch {
+		case math.IsInf(v, 1):
+			return "9e999"
+		case math.IsInf(v, -1):
+			return "-9e999"
+		case math.IsNaN(v):
+			return "NULL"
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case string:
+		return quoteText(v)
+	case []byte:
+		if utf8.Valid(v) && !strings.ContainsRune(string(v), 0) {
+			return "CAST(" + quoteText(string(v)) + " AS BLOB)"
+		}
+		return "X'" + hex.EncodeToString(v) + "'"
+	}
+	return "NULL"
+}
+
+// quoteText quotes a string as a sql text literal
+func quoteText(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
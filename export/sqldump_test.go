@@ -0,0 +1,93 @@
+package export
+
+import (
+	"bytes"
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/ryanfrishkorn/snip"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSQLDump(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SQLDump(&buf, true, false); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	dump := buf.String()
+	if !strings.Contains(dump, "CREATE TABLE IF NOT EXISTS snip(") || !strings.Contains(dump, "CAST('# Wrens") {
+		t.Errorf("expected the schema and readable data, got %s", dump)
+	}
+	if strings.Contains(dump, `INSERT INTO "snip_index"`) {
+		t.Errorf("expected the search index left out")
+	}
+
+	// the dump recreates the snips in an empty database
+	conn, err := sqlite3.Open(filepath.Join(t.TempDir(), "dump.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err = conn.Exec(dump); err != nil {
+		t.Fatalf("expected the dump to load, got %v", err)
+	}
+	stmt, err := conn.Prepare(`SELECT name, data, typeof(data) FROM snip WHERE uuid = ?`, testSnip.UUID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if hasRow, err := stmt.Step(); err != nil || !hasRow {
+		t.Fatalf("expected the snip in the loaded dump, got %v", err)
+	}
+	var name, data, kind string
+	if err = stmt.Scan(&name, &data, &kind); err != nil {
+		t.Fatal(err)
+	}
+	if name != testSnip.Name || data != testSnip.Data || kind != "blob" {
+		t.Errorf("expected the snip unchanged, got %q %q %s", name, data, kind)
+	}
+}
+
+func TestSQLDumpBlobs(t *testing.T) {
+	snip.BlobDir, snip.StoreBlobs = t.TempDir(), true
+	defer func() {
+		snip.BlobDir, snip.StoreBlobs = "", false
+	}()
+	a, err := testSnip.AddAttachment("song.txt", []byte("a song kept in a blob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snip.RemoveAttachment(a.UUID)
+
+	// the data of attachments kept in blobs is written into the dump
+	var buf bytes.Buffer
+	if err = SQLDump(&buf, true, false); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	dump := buf.String()
+	if !strings.Contains(dump, "CAST('a song kept in a blob' AS BLOB)") || strings.Contains(dump, "snip_attachment_blob") {
+		t.Errorf("expected the blob inlined, got %s", dump)
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "NULL"},
+		{int64(-7), "-7"},
+		{2.0, "2.0"},
+		{0.5, "0.5"},
+		{math.Inf(1), "9e999"},
+		{"it's", "'it''s'"},
+		{[]byte("text"), "CAST('text' AS BLOB)"},
+		{[]byte{0, 0xff}, "X'00ff'"},
+	}
+	for _, tt := range tests {
+		if actual := sqlLiteral(tt.value); actual != tt.expected {
+			t.Errorf("%v: expected %s, got %s", tt.value, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package snip
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"time"
+)
+
+// frontMatterFence opens and closes the yaml front matter at the start of a markdown file
+const frontMatterFence = "---"
+
+// FrontMatter is the yaml block at the start of a markdown file describing the snip it holds.
+// Fields written by other tools are ignored, and each field is optional.
+type FrontMatter struct {
+	UUID      string            `yaml:"uuid,omitempty"`
+	Name      string            `yaml:"name,omitempty"`
+	Timestamp time.Time         `yaml:"timestamp,omitempty"`
+	Tags      frontMatterList   `yaml:"tags,omitempty"`
+	Meta      map[string]string `yaml:"meta,omitempty"`
+}
+
+// frontMatterList is a list that other tools may also write as a single string of items separated by commas or spaces
+type frontMatterList []string
+
+// UnmarshalYAML reads a sequence or a single string of items
+func (l *frontMatterList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	}
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	*l = strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	return nil
+}
+
+// MarkdownDocument returns the data of s preceded by front matter holding its uuid, name, timestamp, tags, and metadata,
+// which ParseFrontMatter reads back unchanged
+func MarkdownDocument(s Snip) (string, error) {
+	f := FrontMatter{
+		UUID:      s.UUID.String(),
+		Name:      s.Name,
+		Timestamp: s.Timestamp,
+		Tags:      s.Tags,
+		Meta:      s.Meta,
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(f); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return frontMatterFence + "\n" + buf.String() + frontMatterFence + "\n" + s.Data, nil
+}
+
+// ParseFrontMatter splits a markdown document into its front matter and the data following it, reporting whether there was front matter.
+// A document without front matter is returned whole as the data.
+func ParseFrontMatter(doc string) (FrontMatter, string, bool, error) {
+	var f FrontMatter
+	first, rest, ok := strings.Cut(doc, "\n")
+	if !ok || strings.TrimRight(first, "\r") != frontMatterFence {
+		return f, doc, false, nil
+	}
+	var block strings.Builder
+	for {
+		line, remaining, more := strings.Cut(rest, "\n")
+		if trimmed := strings.TrimRight(line, "\r"); trimmed == frontMatterFence || trimmed == "..." {
+			if err := yaml.Unmarshal([]byte(block.String()), &f); err != nil {
+				return f, doc, false, fmt.Errorf("front matter: %w", err)
+			}
+			return f, remaining, true, nil
+		}
+		if !more {
+			return f, doc, false, fmt.Errorf("front matter is not closed by %s", frontMatterFence)
+		}
+		block.WriteString(line + "\n")
+		rest = remaining
+	}
+}
+
+// Apply sets the fields of s given by the front matter, leaving the others as they are
+func (f FrontMatter) Apply(s *Snip) error {
+	if f.UUID != "" {
+		id, err := uuid.Parse(f.UUID)
+		if err != nil {
+			return fmt.Errorf("front matter uuid: %w", err)
+		}
+		s.UUID = id
+	}
+	if f.Name != "" {
+		s.Name = f.Name
+	}
+	if !f.Timestamp.IsZero() {
+		s.Timestamp = f.Timestamp
+	}
+	if len(f.Tags) > 0 {
+		s.Tags = f.Tags
+	}
+	if len(f.Meta) > 0 {
+		s.Meta = f.Meta
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package snip
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrontMatter(t *testing.T) {
+	s := New()
+	s.Name = "Wrens: small birds"
+	s.Data = "---\nstarts like front matter\n"
+	s.Timestamp = time.Date(2024, 3, 31, 22, 0, 0, 123456789, time.UTC)
+	s.Tags = []string{"birds", "field-notes"}
+	s.Meta = map[string]string{"source": "https://example.com", "count": "5"}
+
+	doc, err := MarkdownDocument(s)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	f, data, found, err := ParseFrontMatter(doc)
+	if err != nil || !found {
+		t.Fatalf("expected front matter, got %v", err)
+	}
+	if data != s.Data {
+		t.Errorf("expected data %q, got %q", s.Data, data)
+	}
+	parsed := New()
+	if err = f.Apply(&parsed); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if parsed.UUID != s.UUID || parsed.Name != s.Name || !parsed.Timestamp.Equal(s.Timestamp) {
+		t.Errorf("expected %s %q %s, got %s %q %s", s.UUID, s.Name, s.Timestamp, parsed.UUID, parsed.Name, parsed.Timestamp)
+	}
+	if !reflect.DeepEqual(parsed.Tags, s.Tags) || !reflect.DeepEqual(parsed.Meta, s.Meta) {
+		t.Errorf("expected tags %v and meta %v, got %v and %v", s.Tags, s.Meta, parsed.Tags, parsed.Meta)
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	// front matter written by other tools
+	f, data, found, err := ParseFrontMatter("---\r\ntitle: ignored\r\ntags: birds, notes\r\n---\r\nbody\r\n")
+	if err != nil || !found {
+		t.Fatalf("expected front matter, got %v", err)
+	}
+	if !reflect.DeepEqual([]string(f.Tags), []string{"birds", "notes"}) || data != "body\r\n" {
+		t.Errorf("unexpected front matter %+v and data %q", f, data)
+	}
+
+	if _, data, found, err = ParseFrontMatter("# heading\n---\n"); err != nil || found || data != "# heading\n---\n" {
+		t.Errorf("expected the document whole without front matter, got %q %v %v", data, found, err)
+	}
+	if _, _, _, err = ParseFrontMatter("---\nname: unclosed\n"); err == nil || !strings.Contains(err.Error(), "not closed") {
+		t.Errorf("expected error for unclosed front matter, got %v", err)
+	}
+	f, _, _, err = ParseFrontMatter("---\nuuid: not-a-uuid\n---\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New()
+	if err = f.Apply(&s); err == nil {
+		t.Errorf("expected error for an invalid uuid")
+	}
+}
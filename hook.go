@@ -0,0 +1,76 @@
+package snip
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Hook names run by the command line tool
+const (
+	HookPostAdd  = "post-add"
+	HookPreRm    = "pre-rm"
+	HookPostEdit = "post-edit"
+)
+
+// RunHook runs each command of a hook with sh, passing snip metadata as environment variables and json on standard input
+func RunHook(hook string, commands []string, s Snip) error {
+	if len(commands) == 0 {
+		return nil
+	}
+
+	// attachment data is omitted, hooks can request it by uuid if needed
+	payload := s
+	payload.Attachments = nil
+	for _, a := range s.Attachments {
+		a.Data = nil
+		payload.Attachments = append(payload.Attachments, a)
+	}
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	env := append(os.Environ(),
+		"SNIP_HOOK="+hook,
+		"SNIP_UUID="+s.UUID.String(),
+		"SNIP_NAME="+s.Name,
+		"SNIP_TIMESTAMP="+s.Timestamp.Format(time.RFC3339Nano),
+		"SNIP_SIZE="+strconv.Itoa(len(s.Data)),
+	)
+	for key, value := range s.Meta {
+		env = append(env, "SNIP_META_"+envName(key)+"="+value)
+	}
+
+	for _, command := range commands {
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Env = env
+		cmd.Stdin = bytes.NewReader(input)
+		// keep standard output free for the results of snip itself
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q: %w", hook, command, err)
+		}
+	}
+	return nil
+}
+
+// envName converts a metadata key to a form suitable for an environment variable name
+func envName(key string) string {
+	name := []byte(key)
+	for idx, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z':
+			name[idx] = c - ('a' - 'A')
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			name[idx] = '_'
+		}
+	}
+	return string(name)
+}
@@ -0,0 +1,56 @@
+package snip
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunHook(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env")
+	stdinFile := filepath.Join(dir, "stdin")
+
+	s := New()
+	s.Name = "Hook Test"
+	s.Data = "hook data"
+	s.Meta = map[string]string{"source-url": "https://example.com"}
+
+	commands := []string{
+		`printf '%s %s %s' "$SNIP_HOOK" "$SNIP_UUID" "$SNIP_META_SOURCE_URL" > ` + envFile,
+		`cat > ` + stdinFile,
+	}
+	err := RunHook(HookPostAdd, commands, s)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	env, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{HookPostAdd, s.UUID.String(), "https://example.com"}, " ")
+	if string(env) != expected {
+		t.Errorf(`expected environment "%s", got "%s"`, expected, env)
+	}
+
+	stdin, err := os.ReadFile(stdinFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload Snip
+	if err = json.Unmarshal(stdin, &payload); err != nil {
+		t.Fatalf("expected json on standard input, got %v", err)
+	}
+	if payload.UUID != s.UUID || payload.Data != s.Data {
+		t.Errorf("expected payload of snip %s, got %s", s.UUID, payload.UUID)
+	}
+
+	// failing commands are reported so that pre hooks can veto an action
+	err = RunHook(HookPreRm, []string{"exit 3"}, s)
+	if err == nil {
+		t.Errorf("expected error from failing hook command")
+	}
+}
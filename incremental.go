@@ -0,0 +1,352 @@
+package snip
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupChunkSize is the size of the chunks of incremental backups. The database file is split at fixed offsets rather than by content
+// because sqlite changes pages in place, so a chunk is written again only when a page within it has changed.
+const BackupChunkSize = 64 << 10
+
+// backupChunkDir and backupSnapshotDir hold the chunks and the snapshots listing them within an incremental backup directory
+const (
+	backupChunkDir    = "chunks"
+	backupSnapshotDir = "snapshots"
+)
+
+// BackupChunk is a piece of the database file stored once under its digest, however many snapshots contain it
+type BackupChunk struct {
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+// BackupSnapshot lists the chunks of the database file at the time it was made, in order
+type BackupSnapshot struct {
+	// Path is the file the snapshot was read from
+	Path string    `json:"-"`
+	Time time.Time `json:"time"`
+	// Size and SHA256 describe the whole database file
+	Size   int64         `json:"size"`
+	SHA256 string        `json:"sha256"`
+	Chunks []BackupChunk `json:"chunks"`
+	// Blobs are the blobs the attachments of the database were kept in, each stored as a chunk named by the same digest
+	Blobs []BackupChunk `json:"blobs,omitempty"`
+}
+
+// BackupProblem describes a snapshot that cannot be restored as recorded
+type BackupProblem struct {
+	Snapshot string
+	// Chunk is empty when the problem concerns the snapshot as a whole
+	Chunk   string
+	Problem string
+}
+
+// BackupVerification summarizes the snapshots and chunks checked by VerifyIncrementalBackups
+type BackupVerification struct {
+	Snapshots int
+	Chunks    int
+	Problems  []BackupProblem
+}
+
+// CreateIncrementalBackup splits the open database file into chunks within dir, writing only the chunks it does not already hold,
+// and records the snapshot. The blobs attachments are kept in are stored as chunks as well. It returns the snapshot along with the chunks that were written.
+func CreateIncrementalBackup(dir string, now time.Time) (BackupSnapshot, []BackupChunk, error) {
+	s := BackupSnapshot{Time: now.UTC().Truncate(time.Second)}
+	var written []BackupChunk
+	s.Path = filepath.Join(dir, backupSnapshotDir, backupPrefix+s.Time.Format(backupLayout)+".json")
+	if err := os.MkdirAll(filepath.Join(dir, backupSnapshotDir), 0700); err != nil {
+		return s, written, err
+	}
+
+	// a read transaction keeps other connections from committing while the file is read
+	err := database.Conn.WithTx(func() error {
+		var tables int
+		if err := database.QueryRow(`SELECT COUNT(*) FROM sqlite_master`, nil, &tables); err != nil {
+			return err
+		}
+		f, err := os.Open(database.Conn.FileName("main"))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		whole := sha256.New()
+		buf := make([]byte, BackupChunkSize)
+		for {
+			n, err := io.ReadFull(f, buf)
+			if n > 0 {
+				whole.Write(buf[:n])
+				c := BackupChunk{SHA256: Checksum(buf[:n]), Size: n}
+				created, err := writeBackupChunk(dir, c, buf[:n])
+				if err != nil {
+					return err
+				}
+				if created {
+					written = append(written, c)
+				}
+				s.Chunks = append(s.Chunks, c)
+				s.Size += int64(n)
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		s.SHA256 = hex.EncodeToString(whole.Sum(nil))
+
+		sums, err := blobDigests(database.Conn)
+		if err != nil {
+			return err
+		}
+		for _, sum := range sums {
+			data, err := readBlob(sum)
+			if err != nil {
+				return fmt.Errorf("reading blob %s: %w", sum, err)
+			}
+			if Checksum(data) != sum {
+				return fmt.Errorf("blob %s has changed since it was written", sum)
+			}
+			c := BackupChunk{SHA256: sum, Size: len(data)}
+			created, err := writeBackupChunk(dir, c, data)
+			if err != nil {
+				return err
+			}
+			if created {
+				written = append(written, c)
+			}
+			s.Blobs = append(s.Blobs, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return s, written, err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return s, written, err
+	}
+	return s, written, writeFileAtomic(s.Path, data)
+}
+
+// backupChunkPath returns the location of the chunk with digest sum, spread over subdirectories by its first two characters
+func backupChunkPath(dir string, sum string) string {
+	return filepath.Join(dir, backupChunkDir, sum[:2], sum)
+}
+
+// writeBackupChunk stores data as chunk c unless dir already holds it, reporting whether it was written
+func writeBackupChunk(dir string, c BackupChunk, data []byte) (bool, error) {
+	path := backupChunkPath(dir, c.SHA256)
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return false, err
+	}
+	return true, writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes data to a temporary file beside path and renames it into place, so an interrupted backup leaves no partial file
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// ListIncrementalBackups returns the snapshots recorded in dir, newest first
+func ListIncrementalBackups(dir string) ([]BackupSnapshot, error) {
+	var snapshots []BackupSnapshot
+	entries, err := os.ReadDir(filepath.Join(dir, backupSnapshotDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return snapshots, nil
+	}
+	if err != nil {
+		return snapshots, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		s, err := readBackupSnapshot(filepath.Join(dir, backupSnapshotDir, name))
+		if err != nil {
+			return snapshots, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.After(snapshots[j].Time)
+	})
+	return snapshots, nil
+}
+
+// readBackupSnapshot reads the snapshot recorded at path
+func readBackupSnapshot(path string) (BackupSnapshot, error) {
+	var s BackupSnapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err = json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	s.Path = path
+	return s, nil
+}
+
+// VerifyIncrementalBackups checks that every chunk of every snapshot in dir is present and unchanged, and that together they
+// reassemble the database file each snapshot recorded. A chunk shared by several snapshots is counted once, and reported for each of them when damaged.
+func VerifyIncrementalBackups(dir string) (BackupVerification, error) {
+	var v BackupVerification
+	snapshots, err := ListIncrementalBackups(dir)
+	if err != nil {
+		return v, err
+	}
+	checked := make(map[string]string)
+	for _, s := range snapshots {
+		v.Snapshots++
+		whole := sha256.New()
+		var size int64
+		intact := true
+		for _, c := range s.Chunks {
+			problem, seen := checked[c.SHA256]
+			if !seen {
+				v.Chunks++
+				problem = verifyBackupChunk(dir, c, whole)
+				checked[c.SHA256] = problem
+			} else if problem == "" {
+				// a chunk found intact before is read again only to hash the snapshot
+				data, err := os.ReadFile(backupChunkPath(dir, c.SHA256))
+				if err != nil {
+					return v, err
+				}
+				whole.Write(data)
+			}
+			if problem != "" {
+				intact = false
+				v.Problems = append(v.Problems, BackupProblem{Snapshot: s.Path, Chunk: c.SHA256, Problem: problem})
+			}
+			size += int64(c.Size)
+		}
+		for _, c := range s.Blobs {
+			problem, seen := checked[c.SHA256]
+			if !seen {
+				v.Chunks++
+				problem = verifyBackupChunk(dir, c, io.Discard)
+				checked[c.SHA256] = problem
+			}
+			if problem != "" {
+				v.Problems = append(v.Problems, BackupProblem{Snapshot: s.Path, Chunk: c.SHA256, Problem: problem})
+			}
+		}
+		if !intact {
+			continue
+		}
+		if size != s.Size || hex.EncodeToString(whole.Sum(nil)) != s.SHA256 {
+			v.Problems = append(v.Problems, BackupProblem{Snapshot: s.Path, Problem: "the chunks do not reassemble the recorded database"})
+		}
+	}
+	return v, nil
+}
+
+// verifyBackupChunk hashes the stored chunk c into whole, returning what is wrong with it or an empty string
+func verifyBackupChunk(dir string, c BackupChunk, whole io.Writer) string {
+	data, err := os.ReadFile(backupChunkPath(dir, c.SHA256))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "missing"
+	}
+	if err != nil {
+		return err.Error()
+	}
+	if Checksum(data) != c.SHA256 || len(data) != c.Size {
+		return "changed since it was written"
+	}
+	whole.Write(data)
+	return ""
+}
+
+// RestoreIncrementalBackup writes the database file recorded by snapshot s to w, failing if a chunk is missing or has changed
+func RestoreIncrementalBackup(dir string, s BackupSnapshot, w io.Writer) error {
+	whole := sha256.New()
+	for _, c := range s.Chunks {
+		data, err := os.ReadFile(backupChunkPath(dir, c.SHA256))
+		if err != nil {
+			return err
+		}
+		if Checksum(data) != c.SHA256 {
+			return fmt.Errorf("chunk %s has changed since it was written", c.SHA256)
+		}
+		whole.Write(data)
+		if _, err = w.Write(data); err != nil {
+			return err
+		}
+	}
+	if hex.EncodeToString(whole.Sum(nil)) != s.SHA256 {
+		return fmt.Errorf("the chunks of %s do not reassemble the recorded database", s.Path)
+	}
+	return nil
+}
+
+// RestoreIncrementalBlobs writes the blobs recorded by snapshot s to blobDir, laid out as BlobDir is, failing if a chunk is missing or has changed
+func RestoreIncrementalBlobs(dir string, s BackupSnapshot, blobDir string) error {
+	for _, c := range s.Blobs {
+		data, err := os.ReadFile(backupChunkPath(dir, c.SHA256))
+		if err != nil {
+			return err
+		}
+		if Checksum(data) != c.SHA256 {
+			return fmt.Errorf("chunk %s has changed since it was written", c.SHA256)
+		}
+		path, err := blobFile(blobDir, c.SHA256)
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		if err = writeFileAtomic(path, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindIncrementalBackup returns the snapshot in dir whose file name or time, such as 20240331T220000Z, is name,
+// or the latest snapshot when name is latest
+func FindIncrementalBackup(dir string, name string) (BackupSnapshot, error) {
+	snapshots, err := ListIncrementalBackups(dir)
+	if err != nil {
+		return BackupSnapshot{}, err
+	}
+	for _, s := range snapshots {
+		base := filepath.Base(s.Path)
+		if name == "latest" || name == base || backupPrefix+name+".json" == base {
+			return s, nil
+		}
+	}
+	return BackupSnapshot{}, fmt.Errorf("%w: snapshot %s in %s", database.ErrNoRows, name, dir)
+}
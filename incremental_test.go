@@ -0,0 +1,91 @@
+package snip
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIncrementalBackup(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	first, written, err := CreateIncrementalBackup(dir, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(first.Chunks) == 0 || len(written) == 0 {
+		t.Fatalf("expected chunks written, got %d of %d", len(written), len(first.Chunks))
+	}
+
+	// an unchanged database writes no chunks, and a changed one writes only some
+	_, written, err = CreateIncrementalBackup(dir, now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(written) != 0 {
+		t.Errorf("expected no chunks written for an unchanged database, got %d", len(written))
+	}
+	s := New()
+	s.Data = "written between backups"
+	if err = InsertSnip(s, WithName("incremental")); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	second, written, err := CreateIncrementalBackup(dir, now)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(written) == 0 {
+		t.Errorf("expected chunks written for a changed database")
+	}
+
+	latest, err := FindIncrementalBackup(dir, "latest")
+	if err != nil || latest.SHA256 != second.SHA256 {
+		t.Fatalf("expected the latest snapshot, got %v", err)
+	}
+	var restored bytes.Buffer
+	if err = RestoreIncrementalBackup(dir, latest, &restored); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if Checksum(restored.Bytes()) != second.SHA256 {
+		t.Errorf("expected the restored database to match the snapshot")
+	}
+
+	v, err := VerifyIncrementalBackups(dir)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if v.Snapshots != 3 || len(v.Problems) != 0 {
+		t.Errorf("expected 3 intact snapshots, got %+v", v)
+	}
+
+	// damage a chunk found only in the first snapshot
+	var damaged string
+	for _, c := range first.Chunks {
+		found := false
+		for _, other := range second.Chunks {
+			found = found || other.SHA256 == c.SHA256
+		}
+		if !found {
+			damaged = c.SHA256
+			break
+		}
+	}
+	if damaged == "" {
+		t.Fatalf("expected a chunk changed between snapshots")
+	}
+	if err = os.WriteFile(backupChunkPath(dir, damaged), []byte("damaged"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	v, err = VerifyIncrementalBackups(dir)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(v.Problems) != 2 || v.Problems[0].Chunk != damaged {
+		t.Errorf("expected the damaged chunk reported for both snapshots containing it, got %+v", v.Problems)
+	}
+	if _, err = FindIncrementalBackup(dir, "missing"); err == nil {
+		t.Errorf("expected error for a missing snapshot")
+	}
+}
@@ -0,0 +1,198 @@
+package snip
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+)
+
+// indexFileVersion is written at the start of an index file so that files from other versions of the format are refused
+const indexFileVersion = 1
+
+// indexFileHeader is the first line of an index file
+type indexFileHeader struct {
+	Version int `json:"version"`
+}
+
+// indexFileSnip is a line of an index file holding the index entries of a snip and the digest of the data they were made from
+type indexFileSnip struct {
+	UUID    uuid.UUID        `json:"uuid"`
+	SHA256  string           `json:"sha256"`
+	Entries []indexFileEntry `json:"entries"`
+}
+
+// indexFileEntry is a row of the search index
+type indexFileEntry struct {
+	Term      string `json:"term"`
+	Count     int    `json:"count"`
+	Positions string `json:"positions"`
+}
+
+// IndexImport summarizes the snips whose index entries were loaded by ImportIndex
+type IndexImport struct {
+	// Loaded is the number of snips whose entries were taken from the file
+	Loaded int
+	// Reindexed is the number of snips indexed again because they were absent from the file or changed since it was written
+	Reindexed int
+}
+
+// ExportIndex writes the search index to w as gzip compressed json lines, one line for each snip, reporting each snip written to progress
+func ExportIndex(w io.Writer, progress Progress) error {
+	var total int
+	if err := database.QueryRow(`SELECT count(DISTINCT uuid) FROM snip_index`, nil, &total); err != nil {
+		return err
+	}
+	stmt, err := database.Prepare(`SELECT snip_index.uuid, coalesce(sha256, ''), term, count, coalesce(positions, '') FROM snip_index LEFT JOIN snip_checksum ON snip_checksum.uuid = snip_index.uuid ORDER BY snip_index.uuid`)
+	if err != nil {
+		return err
+	}
+	defer database.Release(stmt)
+
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+	if err = enc.Encode(indexFileHeader{Version: indexFileVersion}); err != nil {
+		return err
+	}
+	var current indexFileSnip
+	var done int
+	flush := func() error {
+		if current.UUID == uuid.Nil {
+			return nil
+		}
+		done++
+		reportStep(progress, "exporting index", done, total)
+		return enc.Encode(current)
+	}
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			break
+		}
+		var id uuid.UUID
+		var sum string
+		var entry indexFileEntry
+		if err = database.Scan(stmt, &id, &sum, &entry.Term, &entry.Count, &entry.Positions); err != nil {
+			return err
+		}
+		if id != current.UUID {
+			if err = flush(); err != nil {
+				return err
+			}
+			current = indexFileSnip{UUID: id, SHA256: sum}
+		}
+		current.Entries = append(current.Entries, entry)
+	}
+	if err = flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ImportIndex replaces the search index with the entries read from r as written by ExportIndex.
+// Entries of snips that no longer exist are discarded, and snips changed since the file was written or missing from it are indexed again,
+// so that the index matches the database whichever copy of it the file was exported from.
+func ImportIndex(r io.Reader, progress Progress) (IndexImport, error) {
+	var result IndexImport
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return result, fmt.Errorf("reading index file: %w", err)
+	}
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	var header indexFileHeader
+	if err = dec.Decode(&header); err != nil {
+		return result, fmt.Errorf("reading index file header: %w", err)
+	}
+	if header.Version != indexFileVersion {
+		return result, fmt.Errorf("index file version %d is not supported", header.Version)
+	}
+
+	// the digests of the data as it is now decide which entries still apply
+	sums := make(map[uuid.UUID]string)
+	stmt, err := database.Prepare(`SELECT snip.uuid, coalesce(sha256, '') FROM snip LEFT JOIN snip_checksum ON snip_checksum.uuid = snip.uuid`)
+	if err != nil {
+		return result, err
+	}
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			database.Release(stmt)
+			return result, err
+		}
+		if !hasRow {
+			break
+		}
+		var id uuid.UUID
+		var sum string
+		if err = database.Scan(stmt, &id, &sum); err != nil {
+			database.Release(stmt)
+			return result, err
+		}
+		sums[id] = sum
+	}
+	database.Release(stmt)
+
+	loaded := make(map[uuid.UUID]bool)
+	err = database.Conn.WithTx(func() error {
+		// the file holds no trigrams, so the trigram index is kept as it is, following the data of each snip
+		if err := database.Exec(`DELETE FROM snip_index`); err != nil {
+			return err
+		}
+		if err := database.Exec(`DELETE FROM snip_term`); err != nil {
+			return err
+		}
+		for {
+			var s indexFileSnip
+			err := dec.Decode(&s)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("reading index file: %w", err)
+			}
+			if sum, ok := sums[s.UUID]; !ok || sum == "" || sum != s.SHA256 {
+				continue
+			}
+			for _, e := range s.Entries {
+				err = database.Exec(`INSERT INTO snip_index (term, uuid, count, positions) VALUES (?, ?, ?, ?)`, e.Term, s.UUID.String(), e.Count, e.Positions)
+				if err != nil {
+					return err
+				}
+				if err = addTerm(e.Term); err != nil {
+					return err
+				}
+			}
+			loaded[s.UUID] = true
+			reportStep(progress, "importing index", len(loaded), len(sums))
+		}
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Loaded = len(loaded)
+
+	var stale []uuid.UUID
+	for id := range sums {
+		if !loaded[id] {
+			stale = append(stale, id)
+		}
+	}
+	for idx, id := range stale {
+		s, err := GetFromUUID(id.String())
+		if err != nil {
+			return result, err
+		}
+		if err = s.Index(); err != nil {
+			return result, fmt.Errorf("indexing %s: %w", id, err)
+		}
+		result.Reindexed++
+		reportStep(progress, "indexing", idx+1, len(stale))
+	}
+	return result, nil
+}
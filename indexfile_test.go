@@ -0,0 +1,69 @@
+package snip
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExportImportIndex(t *testing.T) {
+	kept := New()
+	kept.Data = "a walrus kept as it was"
+	changed := New()
+	changed.Data = "a narwhal about to change"
+	for _, s := range []Snip{kept, changed} {
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		if err := s.Index(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ExportIndex(&buf, nil); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	// the index in the file no longer describes the changed snip
+	changed.Data = "a platypus after the change"
+	if err := changed.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	var steps int
+	result, err := ImportIndex(bytes.NewReader(buf.Bytes()), ProgressFunc(func(stage string, done int, total int) {
+		steps++
+	}))
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if result.Loaded == 0 || result.Reindexed == 0 || steps != result.Loaded+result.Reindexed {
+		t.Errorf("expected snips both loaded and indexed again with a step each, got %+v in %d steps", result, steps)
+	}
+
+	tests := []struct {
+		term  string
+		found Snip
+		not   Snip
+	}{
+		{"walrus", kept, changed},
+		{"platypus", changed, kept},
+		{"narwhal", Snip{}, changed},
+	}
+	for _, tt := range tests {
+		results, err := SearchIndexTerm([]string{tt.term}, false)
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if _, ok := results[tt.found.UUID]; tt.found.Data != "" && !ok {
+			t.Errorf("expected %s to find %s", tt.term, tt.found.UUID)
+		}
+		if _, ok := results[tt.not.UUID]; ok {
+			t.Errorf("expected %s not to find %s", tt.term, tt.not.UUID)
+		}
+	}
+
+	if _, err = ImportIndex(bytes.NewReader([]byte("not an index")), nil); err == nil {
+		t.Errorf("expected an error importing a file that is not an index")
+	}
+}
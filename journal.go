@@ -0,0 +1,78 @@
+package snip
+
+import (
+	"github.com/ryanfrishkorn/snip/database"
+	"time"
+)
+
+// JournalTag is the tag of the journal entries made by snip journal
+const JournalTag = "journal"
+
+// JournalDateLayout names each journal entry after the day it is for
+const JournalDateLayout = "2006-01-02"
+
+// FindJournalEntry returns the journal entry for day, or an error wrapping database.ErrNoRows when there is none.
+// The first of several entries with the name of the day is returned.
+func FindJournalEntry(day time.Time) (Snip, error) {
+	var id string
+	err := database.QueryRow(`SELECT s.uuid FROM snip s JOIN snip_tag t ON t.uuid = s.uuid WHERE t.tag = ? AND s.name = ? ORDER BY s.timestamp LIMIT 1`,
+		[]interface{}{JournalTag, day.Format(JournalDateLayout)}, &id)
+	if err != nil {
+		return Snip{}, err
+	}
+	return GetFromUUID(id)
+}
+
+// NewJournalEntry returns a journal entry for day holding data, to be inserted with InsertSnip.
+// An entry for a day before today is timestamped at its start, so that it is listed with the snips added that day.
+func NewJournalEntry(day time.Time, data string, now time.Time) Snip {
+	s := New()
+	s.Name = day.Format(JournalDateLayout)
+	s.Data = data
+	s.Tags = []string{JournalTag}
+	s.Timestamp = now
+	if s.Name != now.Format(JournalDateLayout) {
+		s.Timestamp = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	}
+	return s
+}
+
+// ListJournal returns the journal entries whose names are days from the start of from until before the start of until, in order of day.
+// Every entry is listed when both are zero.
+func ListJournal(from time.Time, until time.Time) ([]Snip, error) {
+	var entries []Snip
+	first, last := "0000-00-00", "9999-99-99"
+	if !from.IsZero() {
+		first = from.Format(JournalDateLayout)
+	}
+	if !until.IsZero() {
+		last = until.Format(JournalDateLayout)
+	}
+	stmt, err := database.Prepare(`SELECT s.uuid, s.timestamp, s.name, s.data FROM snip s JOIN snip_tag t ON t.uuid = s.uuid `+
+		`WHERE t.tag = ? AND s.name >= ? AND s.name < ? ORDER BY s.name, s.timestamp`, JournalTag, first, last)
+	if err != nil {
+		return entries, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return entries, err
+		}
+		if !hasRow {
+			break
+		}
+		var s Snip
+		var data []byte
+		if err = database.Scan(stmt, &s.UUID, &s.Timestamp, &s.Name, &data); err != nil {
+			return entries, err
+		}
+		s.Data = string(data)
+		// snips tagged journal by hand are listed only when named for a day
+		if _, err = time.Parse(JournalDateLayout, s.Name); err != nil {
+			continue
+		}
+		entries = append(entries, s)
+	}
+	return entries, nil
+}
@@ -0,0 +1,48 @@
+package snip
+
+import (
+	"errors"
+	"github.com/ryanfrishkorn/snip/database"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	now := time.Date(2031, 5, 14, 9, 30, 0, 0, time.Local)
+	today := NewJournalEntry(now, "shipped the release", now)
+	if today.Name != "2031-05-14" || !today.Timestamp.Equal(now) || len(today.Tags) != 1 || today.Tags[0] != JournalTag {
+		t.Errorf("expected an entry for today, got %+v", today)
+	}
+	earlier := NewJournalEntry(time.Date(2031, 4, 30, 0, 0, 0, 0, time.Local), "planning", now)
+	if earlier.Name != "2031-04-30" || !earlier.Timestamp.Equal(time.Date(2031, 4, 30, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("expected an entry timestamped at the start of its day, got %+v", earlier)
+	}
+	// a snip named for a day without the journal tag is not an entry
+	other := New()
+	other.Name = "2031-05-01"
+	for _, s := range []Snip{today, earlier, other} {
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+	}
+
+	found, err := FindJournalEntry(now)
+	if err != nil || found.UUID != today.UUID {
+		t.Errorf("expected the entry for today, got %s %v", found.UUID, err)
+	}
+	if _, err = FindJournalEntry(time.Date(2031, 5, 1, 0, 0, 0, 0, time.Local)); !errors.Is(err, database.ErrNoRows) {
+		t.Errorf("expected ErrNoRows for a day without an entry, got %v", err)
+	}
+
+	entries, err := ListJournal(time.Date(2031, 5, 1, 0, 0, 0, 0, time.Local), time.Date(2031, 6, 1, 0, 0, 0, 0, time.Local))
+	if err != nil || len(entries) != 1 || entries[0].UUID != today.UUID || entries[0].Data != "shipped the release" {
+		t.Errorf("expected the entry of may, got %+v %v", entries, err)
+	}
+	if entries, err = ListJournal(time.Time{}, time.Time{}); err != nil || len(entries) < 2 {
+		t.Fatalf("expected every entry, got %+v %v", entries, err)
+	}
+	if entries[0].Name > entries[1].Name {
+		t.Errorf("expected entries in order of day, got %s before %s", entries[0].Name, entries[1].Name)
+	}
+}
@@ -0,0 +1,255 @@
+package snip
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// minLangScore is the score the patterns of a language must reach before snip data is taken to be written in it
+const minLangScore = 4
+
+// langPattern is evidence of a language found on a line of snip data, weighted by how surely it identifies the language
+type langPattern struct {
+	Pattern *regexp.Regexp
+	Weight  int
+}
+
+// langPatterns are the lines characteristic of each programming language detected, in the manner of linguist and enry
+var langPatterns = map[string][]langPattern{
+	"bash": {
+		{regexp.MustCompile(`^\s*(if \[\[? .*\]\]?; then|then|fi|do|done|esac)\s*$`), 2},
+		{regexp.MustCompile(`^\s*(export \w+=|\w+=\$\(|\$ )`), 2},
+		{regexp.MustCompile(`^\s*(echo|cd|sudo|apt(-get)?|brew|curl|grep|chmod|mkdir|tar|ssh|git|docker|kubectl) `), 1},
+		{regexp.MustCompile(`\| *(grep|awk|sed|xargs|sort|uniq|wc|head|tail|cut|tr)\b`), 2},
+		{regexp.MustCompile(`\$\{\w+[^}]*\}|"\$\w+"`), 1},
+	},
+	"c": {
+		{regexp.MustCompile(`^#include [<"]`), 3},
+		{regexp.MustCompile(`^\s*(int|void|char|static|unsigned|struct \w+) \*?\w+\(.*\)\s*\{?\s*$`), 2},
+		{regexp.MustCompile(`\b(printf|malloc|free|sizeof)\(`), 2},
+		{regexp.MustCompile(`^#define \w+`), 2},
+	},
+	"go": {
+		{regexp.MustCompile(`^package \w+\s*$`), 3},
+		{regexp.MustCompile(`^func (\(\w+ \*?\w+(\[.*\])?\) )?\w+(\[.*\])?\(.*\{\s*$`), 3},
+		{regexp.MustCompile(`^import \($|^import "[\w./-]+"$`), 3},
+		{regexp.MustCompile(`\bif err != nil\b`), 3},
+		{regexp.MustCompile(`\w+ := `), 1},
+		{regexp.MustCompile(`\b(fmt|errors|strings|os|http)\.[A-Z]\w*\(`), 2},
+	},
+	"html": {
+		{regexp.MustCompile(`(?i)^\s*<(!doctype html|html|head|body|div|script|style|meta|link|table|ul)\b`), 3},
+		{regexp.MustCompile(`</\w+>\s*$`), 1},
+	},
+	"java": {
+		{regexp.MustCompile(`^\s*(public|private|protected) (static )?(final )?(class|interface|enum|void|[A-Z]\w*(<.*>)?) \w+`), 3},
+		{regexp.MustCompile(`^import java\.|^package [\w.]+;$`), 3},
+		{regexp.MustCompile(`System\.(out|err)\.print`), 3},
+		{regexp.MustCompile(`^\s*@Override\s*$`), 2},
+	},
+	"javascript": {
+		{regexp.MustCompile(`\bconsole\.(log|error)\(`), 3},
+		{regexp.MustCompile(`\brequire\(['"][\w@./-]+['"]\)`), 3},
+		{regexp.MustCompile(`^\s*(export (default |const |function |class )|import .* from ['"])`), 2},
+		{regexp.MustCompile(`^\s*(const|let|var) \w+ = `), 1},
+		{regexp.MustCompile(`\bfunction\s*\w*\(.*\)\s*\{|=> \{`), 2},
+		{regexp.MustCompile(`\b(document|window)\.\w+`), 2},
+	},
+	"python": {
+		{regexp.MustCompile(`^\s*def \w+\(.*\)( -> .+)?:\s*$`), 3},
+		{regexp.MustCompile(`^\s*class \w+(\(.*\))?:\s*$`), 3},
+		{regexp.MustCompile(`^\s*(elif .*|else|try|except.*|finally):\s*$`), 2},
+		{regexp.MustCompile(`^(from [\w.]+ import \w+|import [\w.]+( as \w+)?)\s*$`), 2},
+		{regexp.MustCompile(`\bself\.\w+|__name__ == ['"]__main__['"]`), 2},
+		{regexp.MustCompile(`^\s*print\(`), 1},
+	},
+	"ruby": {
+		{regexp.MustCompile(`^\s*def \w+[?!]?(\(.*\))?\s*$`), 2},
+		{regexp.MustCompile(`^\s*end\s*$`), 1},
+		{regexp.MustCompile(`\.each( do)? \|\w+(, \w+)?\|`), 3},
+		{regexp.MustCompile(`^\s*(require ['"]|puts |attr_(reader|accessor) :)`), 2},
+	},
+	"rust": {
+		{regexp.MustCompile(`^\s*(pub )?fn \w+(<.*>)?\(.*\)( -> .+)?\s*\{?\s*$`), 3},
+		{regexp.MustCompile(`\blet mut \w+`), 3},
+		{regexp.MustCompile(`^use \w+(::[\w{}, *]+)+;\s*$`), 3},
+		{regexp.MustCompile(`\b(println|format|vec|panic)!\(`), 3},
+		{regexp.MustCompile(`^\s*impl(<.*>)? \w+`), 2},
+	},
+	"sql": {
+		{regexp.MustCompile(`(?i)^\s*(SELECT\b.*\bFROM\b|SELECT \w|INSERT INTO|CREATE (TABLE|INDEX|VIEW)|UPDATE \w+ SET|DELETE FROM|ALTER TABLE|DROP TABLE)`), 3},
+		{regexp.MustCompile(`(?i)^\s*(WHERE|GROUP BY|ORDER BY|JOIN|LEFT JOIN|LIMIT)\b`), 2},
+	},
+	"yaml": {
+		{regexp.MustCompile(`^---\s*$`), 1},
+		{regexp.MustCompile(`^\s*[\w.-]+:( [^ ].*)?\s*$`), 1},
+		{regexp.MustCompile(`^\s*- [\w.-]+:( |$)`), 2},
+	},
+}
+
+// shebangInterpreters are the languages of scripts run by each interpreter named on their first line
+var shebangInterpreters = map[string]string{
+	"bash":    "bash",
+	"node":    "javascript",
+	"perl":    "perl",
+	"python":  "python",
+	"python3": "python",
+	"ruby":    "ruby",
+	"sh":      "sh",
+	"zsh":     "zsh",
+}
+
+// DetectLanguage returns the dominant programming language of snip data, or an empty string for prose.
+// A script is known by its #! line and json by parsing it. In markdown with fenced code blocks, the language of the most lines of
+// code wins, and otherwise each line is matched against patterns characteristic of each language, as linguist and enry do.
+func DetectLanguage(data string) string {
+	trimmed := strings.TrimSpace(data)
+	if trimmed == "" {
+		return ""
+	}
+	if strings.HasPrefix(trimmed, "#!") {
+		fields := strings.Fields(strings.SplitN(trimmed, "\n", 2)[0][2:])
+		if len(fields) > 0 {
+			interpreter := path.Base(fields[0])
+			if interpreter == "env" && len(fields) > 1 {
+				interpreter = fields[1]
+			}
+			if lang, ok := shebangInterpreters[interpreter]; ok {
+				return lang
+			}
+		}
+	}
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+
+	lines := make(map[string]int)
+	for _, b := range CodeBlocks(data) {
+		if b.Lang != "" {
+			lines[b.Lang] += strings.Count(b.Code, "\n")
+		}
+	}
+	if len(lines) > 0 {
+		return highest(lines)
+	}
+
+	scores := make(map[string]int)
+	for _, line := range strings.Split(data, "\n") {
+		for lang, patterns := range langPatterns {
+			for _, p := range patterns {
+				if p.Pattern.MatchString(line) {
+					scores[lang] += p.Weight
+				}
+			}
+		}
+	}
+	lang := highest(scores)
+	if scores[lang] < minLangScore {
+		return ""
+	}
+	// keys and values alone are as likely to be notes written as a list, so yaml must be most of the lines
+	if lang == "yaml" && scores[lang]*2 < strings.Count(trimmed, "\n")+1 {
+		return ""
+	}
+	return lang
+}
+
+// highest returns the key with the largest count, the first in order of name when several share it
+func highest(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	best := ""
+	for _, key := range keys {
+		if best == "" || counts[key] > counts[best] {
+			best = key
+		}
+	}
+	return best
+}
+
+// SetLanguage records the programming language of snip id, an empty string meaning that it was found to be prose
+func SetLanguage(id uuid.UUID, lang string) error {
+	return database.Exec(`INSERT OR REPLACE INTO snip_lang (uuid, lang) VALUES (?, ?)`, id.String(), lang)
+}
+
+// GetLanguage returns the programming language recorded for snip id, or an empty string when it has none
+func GetLanguage(id uuid.UUID) (string, error) {
+	var lang string
+	err := database.QueryRow(`SELECT lang FROM snip_lang WHERE uuid = ?`, []interface{}{id.String()}, &lang)
+	if errors.Is(err, database.ErrNoRows) {
+		return "", nil
+	}
+	return lang, err
+}
+
+// RemoveLanguage removes the language recorded for snip id
+func RemoveLanguage(id uuid.UUID) error {
+	return database.Exec(`DELETE FROM snip_lang WHERE uuid = ?`, id.String())
+}
+
+// ListLanguages returns the programming language of every snip that has one
+func ListLanguages() (map[uuid.UUID]string, error) {
+	langs := make(map[uuid.UUID]string)
+	stmt, err := database.Prepare(`SELECT uuid, lang FROM snip_lang WHERE lang != ''`)
+	if err != nil {
+		return langs, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return langs, err
+		}
+		if !hasRow {
+			break
+		}
+		var id uuid.UUID
+		var lang string
+		if err = database.Scan(stmt, &id, &lang); err != nil {
+			return langs, err
+		}
+		langs[id] = lang
+	}
+	return langs, nil
+}
+
+// backfillLanguages detects the language of snips stored before languages were recorded
+func backfillLanguages() error {
+	langs := make(map[uuid.UUID]string)
+	stmt, err := database.Prepare(`SELECT uuid, data FROM snip WHERE uuid NOT IN (SELECT uuid FROM snip_lang)`)
+	if err != nil {
+		return err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			break
+		}
+		var id uuid.UUID
+		var data []byte
+		if err = database.Scan(stmt, &id, &data); err != nil {
+			return err
+		}
+		langs[id] = DetectLanguage(string(data))
+	}
+
+	for id, lang := range langs {
+		if err = SetLanguage(id, lang); err != nil {
+			return err
+		}
+	}
+	return nil
+}
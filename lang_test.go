@@ -0,0 +1,63 @@
+package snip
+
+import (
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	for name, tc := range map[string]struct {
+		data     string
+		expected string
+	}{
+		"empty":    {"  \n", ""},
+		"prose":    {"Remember to renew the passport.\nThe office opens at 9: bring two photos.\n", ""},
+		"shebang":  {"#!/usr/bin/env python3\nprint('hi')\n", "python"},
+		"bash":     {"#!/bin/bash\nls\n", "bash"},
+		"json":     {`{"name": "snip", "tags": ["a"]}`, "json"},
+		"fenced":   {"Setup:\n```sh\nmake\n```\n\n```go\nfunc main() {\n\tprintln()\n}\n```\n", "go"},
+		"go":       {"package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n", "go"},
+		"python":   {"def greet(name):\n    if name:\n        print(name)\n    else:\n        return None\n", "python"},
+		"rust":     {"fn main() {\n    let mut total = 0;\n    println!(\"{}\", total);\n}\n", "rust"},
+		"sql":      {"SELECT name, count(*) FROM snip\nWHERE name LIKE 'a%'\nGROUP BY name;\n", "sql"},
+		"shell":    {"cd /tmp\nexport PATH=$PATH:/opt/bin\ngrep -r todo . | wc -l\n", "bash"},
+		"yaml":     {"name: snip\nversion: 2\nservices:\n  - web: true\n", "yaml"},
+		"key list": {"Shopping\nmilk: 2 litres\nA long list of things to buy before the weekend and some notes about them.\nMore notes without any colons at all.\nbread\n", ""},
+	} {
+		if got := DetectLanguage(tc.data); got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", name, tc.expected, got)
+		}
+	}
+}
+
+func TestLanguageRecorded(t *testing.T) {
+	s := New()
+	s.Name = "language test"
+	s.Data = "package main\n\nfunc main() {\n\tif err != nil {\n\t}\n}\n"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	lang, err := GetLanguage(s.UUID)
+	if err != nil || lang != "go" {
+		t.Errorf("expected go, got %q %v", lang, err)
+	}
+	langs, err := ListLanguages()
+	if err != nil || langs[s.UUID] != "go" {
+		t.Errorf("expected go listed, got %q %v", langs[s.UUID], err)
+	}
+
+	s.Data = "Only prose remains."
+	if err = s.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if lang, err = GetLanguage(s.UUID); err != nil || lang != "" {
+		t.Errorf("expected no language after the update, got %q %v", lang, err)
+	}
+	if langs, err = ListLanguages(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := langs[s.UUID]; ok {
+		t.Errorf("expected prose left out of the languages listed")
+	}
+}
@@ -0,0 +1,60 @@
+package snip
+
+import (
+	"github.com/google/uuid"
+	"regexp"
+	"strings"
+)
+
+// SnipLinkScheme begins links within snip data to other snips, such as [wrens](snip://99bc7e51-54d4-4c47-8f1c-33e8b0c8a1d2)
+const SnipLinkScheme = "snip://"
+
+// snipLinkPattern matches a markdown link to a snip, capturing its text and uuid, or a bare snip link, capturing its uuid
+var snipLinkPattern = regexp.MustCompile(`\[([^\]\n]*)\]\(snip://([0-9a-fA-F-]{36})\)|snip://([0-9a-fA-F-]{36})`)
+
+// wikiLinkPattern matches a wiki link such as [[file]] or [[file|text]] along with an ! before it that makes it an embed,
+// capturing the ! and the file and text. Links to a heading within a file are not matched.
+var wikiLinkPattern = regexp.MustCompile(`(!?)\[\[([^\[\]|#\n]+)(?:\|([^\[\]\n]*))?\]\]`)
+
+// SnipLinksToWikiLinks rewrites links to snips as wiki links to the files named by file, such as [[file|text]] for a markdown link.
+// Links to snips that file does not name are left as they are.
+func SnipLinksToWikiLinks(data string, file func(id uuid.UUID) (string, bool)) string {
+	return snipLinkPattern.ReplaceAllStringFunc(data, func(link string) string {
+		m := snipLinkPattern.FindStringSubmatch(link)
+		idStr, text, markdown := m[2], m[1], true
+		if idStr == "" {
+			idStr, markdown = m[3], false
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return link
+		}
+		name, ok := file(id)
+		if !ok {
+			return link
+		}
+		if markdown {
+			return "[[" + name + "|" + text + "]]"
+		}
+		return "[[" + name + "]]"
+	})
+}
+
+// WikiLinksToSnipLinks rewrites wiki links to the files that id finds as links to their snips, reversing SnipLinksToWikiLinks.
+// A .md extension of the file is ignored, and embeds and links to other files are left as they are.
+func WikiLinksToSnipLinks(data string, id func(file string) (uuid.UUID, bool)) string {
+	return wikiLinkPattern.ReplaceAllStringFunc(data, func(link string) string {
+		m := wikiLinkPattern.FindStringSubmatch(link)
+		if m[1] == "!" {
+			return link
+		}
+		target, ok := id(strings.TrimSuffix(strings.TrimSpace(m[2]), ".md"))
+		if !ok {
+			return link
+		}
+		if strings.Contains(link, "|") {
+			return "[" + m[3] + "](" + SnipLinkScheme + target.String() + ")"
+		}
+		return SnipLinkScheme + target.String()
+	})
+}
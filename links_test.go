@@ -0,0 +1,40 @@
+package snip
+
+import (
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestWikiLinks(t *testing.T) {
+	wrens := uuid.MustParse("99bc7e51-54d4-4c47-8f1c-33e8b0c8a1d2")
+	missing := uuid.MustParse("0a6f1bde-3e5c-4a8e-9f47-2b4f4c1d7e90")
+	files := map[uuid.UUID]string{wrens: "Wrens notes"}
+	ids := map[string]uuid.UUID{"Wrens notes": wrens}
+
+	data := "see [the wrens](snip://" + wrens.String() + ") and snip://" + wrens.String() + ", not snip://" + missing.String() + "\n"
+	expected := "see [[Wrens notes|the wrens]] and [[Wrens notes]], not snip://" + missing.String() + "\n"
+	exported := SnipLinksToWikiLinks(data, func(id uuid.UUID) (string, bool) {
+		name, ok := files[id]
+		return name, ok
+	})
+	if exported != expected {
+		t.Errorf("expected %q, got %q", expected, exported)
+	}
+
+	toSnip := func(file string) (uuid.UUID, bool) {
+		id, ok := ids[file]
+		return id, ok
+	}
+	if imported := WikiLinksToSnipLinks(exported, toSnip); imported != data {
+		t.Errorf("expected the links restored %q, got %q", data, imported)
+	}
+	// embeds, headings, and other files are left alone
+	for _, link := range []string{"![[Wrens notes]]", "[[Wrens notes#Nests]]", "[[Robins]]"} {
+		if actual := WikiLinksToSnipLinks(link, toSnip); actual != link {
+			t.Errorf("expected %q unchanged, got %q", link, actual)
+		}
+	}
+	if actual := WikiLinksToSnipLinks("[[Wrens notes.md]]", toSnip); actual != "snip://"+wrens.String() {
+		t.Errorf("expected the extension ignored, got %q", actual)
+	}
+}
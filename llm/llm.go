@@ -0,0 +1,203 @@
+// Package llm sends snips to a language model, served by ollama or an api compatible with that of OpenAI, to summarize,
+// translate, or answer questions about them
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/config"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotConfigured is returned when no provider is configured, since snips are never sent anywhere unless asked to be
+var ErrNotConfigured = errors.New("no language model provider is configured")
+
+// Default addresses of the apis of each provider
+const (
+	DefaultOllamaURL = "http://localhost:11434"
+	DefaultOpenAIURL = "https://api.openai.com/v1"
+)
+
+// Provider completes prompts with a language model
+type Provider interface {
+	// Complete returns the reply of the model to prompt, following the instructions of system
+	Complete(ctx context.Context, system string, prompt string) (string, error)
+	// String names the model and where it is served, such as in confirmations before snips are sent
+	String() string
+}
+
+// New returns the provider of the configuration, or ErrNotConfigured when there is none
+func New(c config.LLM) (Provider, error) {
+	if c.Provider == "" {
+		return nil, ErrNotConfigured
+	}
+	if c.Model == "" {
+		return nil, fmt.Errorf("no model is configured for %s", c.Provider)
+	}
+	client := &http.Client{Timeout: 5 * time.Minute}
+	switch c.Provider {
+	case "ollama":
+		if c.URL == "" {
+			c.URL = DefaultOllamaURL
+		}
+		return &Ollama{HTTP: client, URL: strings.TrimSuffix(c.URL, "/"), Model: c.Model}, nil
+	case "openai":
+		if c.URL == "" {
+			c.URL = DefaultOpenAIURL
+		}
+		if c.APIKey == "" {
+			c.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+		return &OpenAI{HTTP: client, URL: strings.TrimSuffix(c.URL, "/"), Model: c.Model, APIKey: c.APIKey}, nil
+	}
+	return nil, fmt.Errorf("provider %s is not supported (ollama|openai)", c.Provider)
+}
+
+// message is a turn of a chat with a model
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Ollama is a model served by ollama
+type Ollama struct {
+	HTTP  *http.Client
+	URL   string
+	Model string
+}
+
+// Complete returns the reply of the model to prompt
+func (o *Ollama) Complete(ctx context.Context, system string, prompt string) (string, error) {
+	request := struct {
+		Model    string    `json:"model"`
+		Messages []message `json:"messages"`
+		Stream   bool      `json:"stream"`
+	}{o.Model, []message{{"system", system}, {"user", prompt}}, false}
+	var response struct {
+		Message message `json:"message"`
+	}
+	err := post(ctx, o.HTTP, o.URL+"/api/chat", "", request, &response)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response.Message.Content), nil
+}
+
+// String names the model and server
+func (o *Ollama) String() string {
+	return fmt.Sprintf("ollama %s at %s", o.Model, o.URL)
+}
+
+// OpenAI is a model served by the api of OpenAI or a server compatible with it, such as llama.cpp or vLLM
+type OpenAI struct {
+	HTTP  *http.Client
+	URL   string
+	Model string
+	// APIKey is sent as a bearer token unless empty
+	APIKey string
+}
+
+// Complete returns the reply of the model to prompt
+func (o *OpenAI) Complete(ctx context.Context, system string, prompt string) (string, error) {
+	request := struct {
+		Model    string    `json:"model"`
+		Messages []message `json:"messages"`
+	}{o.Model, []message{{"system", system}, {"user", prompt}}}
+	var response struct {
+		Choices []struct {
+			Message message `json:"message"`
+		} `json:"choices"`
+	}
+	err := post(ctx, o.HTTP, o.URL+"/chat/completions", o.APIKey, request, &response)
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("%s returned no reply", o)
+	}
+	return strings.TrimSpace(response.Choices[0].Message.Content), nil
+}
+
+// String names the model and server
+func (o *OpenAI) String() string {
+	return fmt.Sprintf("openai %s at %s", o.Model, o.URL)
+}
+
+// post sends request as json to url and decodes the json response into v, with the error given by the server when it fails
+func post(ctx context.Context, client *http.Client, url string, token string, request any, v any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// ollama gives the error as a string and OpenAI as an object with a message
+		var failure struct {
+			Error json.RawMessage `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&failure); err != nil || len(failure.Error) == 0 {
+			return fmt.Errorf("%s returned status %s", url, resp.Status)
+		}
+		var detail struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(failure.Error, &detail) == nil && detail.Message != "" {
+			return fmt.Errorf("%s returned status %s: %s", url, resp.Status, detail.Message)
+		}
+		return fmt.Errorf("%s returned status %s: %s", url, resp.Status, strings.Trim(string(failure.Error), `"`))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// summarizeInstructions are the system prompt of Summarize
+const summarizeInstructions = "Summarize the note you are given in a few sentences, keeping any commands, names, and numbers that matter. " +
+	"Reply with the summary alone."
+
+// askInstructions are the system prompt of Ask
+const askInstructions = "Answer the question using only the notes you are given, each headed by its name and uuid. " +
+	"Say so when the notes do not contain the answer, and name the notes the answer comes from."
+
+// translateInstructions are the system prompt of Translate, given the language code translated into
+const translateInstructions = "Translate the note you are given into the language with the code %s. " +
+	"Keep its markdown formatting, and leave code, commands, and links as they are. Reply with the translation alone."
+
+// Summarize returns a summary of snip s written by p
+func Summarize(ctx context.Context, p Provider, s snip.Snip) (string, error) {
+	return p.Complete(ctx, summarizeInstructions, "# "+s.Name+"\n\n"+s.Data)
+}
+
+// Ask returns the answer of p to question, given the snips as the notes to answer it from
+func Ask(ctx context.Context, p Provider, question string, snips []snip.Snip) (string, error) {
+	var prompt strings.Builder
+	for _, s := range snips {
+		fmt.Fprintf(&prompt, "## %s (%s)\n\n%s\n\n", s.Name, s.UUID, strings.TrimSpace(s.Data))
+	}
+	fmt.Fprintf(&prompt, "Question: %s\n", question)
+	return p.Complete(ctx, askInstructions, prompt.String())
+}
+
+// Translate returns the data of snip s translated by p into the language with code lang, such as de
+func Translate(ctx context.Context, p Provider, s snip.Snip, lang string) (string, error) {
+	return p.Complete(ctx, fmt.Sprintf(translateInstructions, lang), s.Data)
+}
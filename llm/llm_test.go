@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// chatRequest is the request sent by both providers
+type chatRequest struct {
+	Model    string    `json:"model"`
+	Messages []message `json:"messages"`
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(config.LLM{}); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("expected ErrNotConfigured, got %v", err)
+	}
+	if _, err := New(config.LLM{Provider: "ollama"}); err == nil {
+		t.Errorf("expected an error without a model")
+	}
+	if _, err := New(config.LLM{Provider: "claude", Model: "x"}); err == nil {
+		t.Errorf("expected an error for an unsupported provider")
+	}
+	p, err := New(config.LLM{Provider: "ollama", Model: "llama3.2"})
+	if err != nil || p.String() != "ollama llama3.2 at "+DefaultOllamaURL {
+		t.Errorf("expected the default ollama address, got %v %v", p, err)
+	}
+}
+
+func TestOllama(t *testing.T) {
+	var received chatRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte(`{"message": {"role": "assistant", "content": " Restart it with systemctl.\n"}, "done": true}`))
+	}))
+	defer ts.Close()
+
+	p, err := New(config.LLM{Provider: "ollama", URL: ts.URL, Model: "llama3.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := snip.New()
+	s.Name = "nginx"
+	s.Data = "systemctl restart nginx"
+	summary, err := Summarize(context.Background(), p, s)
+	if err != nil || summary != "Restart it with systemctl." {
+		t.Errorf("expected the trimmed reply, got %q %v", summary, err)
+	}
+	if received.Model != "llama3.2" || len(received.Messages) != 2 || received.Messages[0].Role != "system" ||
+		received.Messages[1].Content != "# nginx\n\nsystemctl restart nginx" {
+		t.Errorf("expected the system prompt and the snip, got %+v", received)
+	}
+
+	if _, err = Translate(context.Background(), p, s, "de"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(received.Messages[0].Content, "the code de") || received.Messages[1].Content != s.Data {
+		t.Errorf("expected the data to translate into de, got %+v", received)
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	var received chatRequest
+	var auth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+		if received.Model == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": {"message": "model not found", "type": "invalid_request_error"}}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Port 8080."}}]}`))
+	}))
+	defer ts.Close()
+
+	p, err := New(config.LLM{Provider: "openai", URL: ts.URL + "/v1/", Model: "gpt-4o-mini", APIKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := snip.New()
+	s.Name = "proxy"
+	s.Data = "listens on 8080"
+	answer, err := Ask(context.Background(), p, "Which port?", []snip.Snip{s})
+	if err != nil || answer != "Port 8080." {
+		t.Errorf("expected the answer, got %q %v", answer, err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("expected the api key as a bearer token, got %q", auth)
+	}
+	prompt := received.Messages[1].Content
+	if !strings.Contains(prompt, "## proxy ("+s.UUID.String()+")\n\nlistens on 8080") || !strings.HasSuffix(prompt, "Question: Which port?\n") {
+		t.Errorf("expected the snips followed by the question, got %q", prompt)
+	}
+
+	p, _ = New(config.LLM{Provider: "openai", URL: ts.URL, Model: "missing"})
+	if _, err = p.Complete(context.Background(), "", "hello"); err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Errorf("expected the error message of the server, got %v", err)
+	}
+}
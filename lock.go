@@ -0,0 +1,88 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"time"
+)
+
+// ErrLocked is returned when changing or removing a locked snip
+var ErrLocked = errors.New("snip is locked")
+
+// Lock marks a snip read-only, refusing updates, removal, and changes to its attachments, tags, and metadata until it is unlocked
+func Lock(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`INSERT OR IGNORE INTO snip_lock (uuid, timestamp) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec(id.String(), time.Now().Format(time.RFC3339Nano))
+}
+
+// Unlock allows a locked snip to be changed again
+func Unlock(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_lock WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
+
+// IsLocked determines if a snip is read-only
+func IsLocked(id uuid.UUID) (bool, error) {
+	stmt, err := database.Conn.Prepare(`SELECT 1 FROM snip_lock WHERE uuid = ?`, id.String())
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	return stmt.Step()
+}
+
+// ListLocked returns the uuids of all locked snips in the order they were locked
+func ListLocked() ([]uuid.UUID, error) {
+	stmt, err := database.Conn.Prepare(`SELECT uuid FROM snip_lock ORDER BY timestamp`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var ids []uuid.UUID
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return ids, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr string
+		err = stmt.Scan(&idStr)
+		if err != nil {
+			return ids, err
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// checkLocked returns an error wrapping ErrLocked if the snip is read-only
+func checkLocked(id uuid.UUID) error {
+	locked, err := IsLocked(id)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return fmt.Errorf("%w: %s", ErrLocked, id)
+	}
+	return nil
+}
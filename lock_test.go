@@ -0,0 +1,72 @@
+package snip
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLock(t *testing.T) {
+	s := New()
+	s.Data = "lock test data"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	a, err := s.AddAttachment("lock.txt", []byte("lock attachment"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Lock(s.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	locked, err := IsLocked(s.UUID)
+	if err != nil || !locked {
+		t.Fatalf("expected snip to be locked, got %v %v", locked, err)
+	}
+
+	s.Name = "renamed while locked"
+	if err = s.Update(); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked from Update, got %v", err)
+	}
+	if _, err = s.AddAttachment("another.txt", []byte("data")); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked from AddAttachment, got %v", err)
+	}
+	if err = RemoveAttachment(a.UUID); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked from RemoveAttachment, got %v", err)
+	}
+	if err = Remove(s.UUID); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked from Remove, got %v", err)
+	}
+	if err = s.AddTag("locked"); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked from AddTag, got %v", err)
+	}
+	if err = s.RemoveTag("locked"); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked from RemoveTag, got %v", err)
+	}
+	if err = s.SetMeta("status", "changed"); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked from SetMeta, got %v", err)
+	}
+	result, err := GetFromUUID(s.UUID.String())
+	if err != nil {
+		t.Fatalf("expected locked snip to remain, got %v", err)
+	}
+	if result.Name == s.Name || len(result.Attachments) != 1 || len(result.Tags) != 0 || len(result.Meta) != 0 {
+		t.Errorf("expected locked snip to be unchanged, got %+v", result)
+	}
+
+	ids, err := ListLocked()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(ids) != 1 || ids[0] != s.UUID {
+		t.Errorf("expected only %s to be locked, got %v", s.UUID, ids)
+	}
+
+	if err = Unlock(s.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if err = s.Update(); err != nil {
+		t.Errorf("expected nil err after unlocking, got %v", err)
+	}
+}
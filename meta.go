@@ -0,0 +1,60 @@
+package snip
+
+import (
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+)
+
+// GetMeta returns all metadata key value pairs associated with a snip
+func GetMeta(id uuid.UUID) (map[string]string, error) {
+	meta := make(map[string]string)
+
+	stmt, err := database.Prepare(`SELECT key, value FROM snip_meta WHERE uuid = ?`, id.String())
+	if err != nil {
+		return meta, err
+	}
+	defer database.Release(stmt)
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return meta, err
+		}
+		if !hasRow {
+			break
+		}
+		var key, value string
+		err = stmt.Scan(&key, &value)
+		if err != nil {
+			return meta, err
+		}
+		meta[key] = value
+	}
+	return meta, nil
+}
+
+// RemoveMeta deletes all metadata associated with a snip
+func RemoveMeta(id uuid.UUID) error {
+	return database.Exec(`DELETE FROM snip_meta WHERE uuid = ?`, id.String())
+}
+
+// SetMeta inserts or replaces a metadata value for the snip
+func (s *Snip) SetMeta(key string, value string) error {
+	if err := checkLocked(s.UUID); err != nil {
+		return err
+	}
+	err := database.Exec(`DELETE FROM snip_meta WHERE uuid = ? AND key = ?`, s.UUID.String(), key)
+	if err != nil {
+		return err
+	}
+	err = database.Exec(`INSERT INTO snip_meta (uuid, key, value) VALUES (?, ?, ?)`, s.UUID.String(), key, value)
+	if err != nil {
+		return err
+	}
+
+	if s.Meta == nil {
+		s.Meta = make(map[string]string)
+	}
+	s.Meta[key] = value
+	return nil
+}
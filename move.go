@@ -0,0 +1,189 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"path/filepath"
+	"strings"
+)
+
+// movedTables are the tables copied when moving a snip to another database, with the condition selecting the rows of a snip.
+// Shares, aliases, watched paths, and the event journal belong to the database itself and are left behind.
+var movedTables = []struct {
+	Name      string
+	Condition string
+}{
+	{"snip", `uuid = ?`},
+	{"snip_access", `uuid = ?`},
+	{"snip_attachment", `snip_uuid = ?`},
+	{"snip_attachment_checksum", `uuid IN (SELECT uuid FROM main.snip_attachment WHERE snip_uuid = ?)`},
+	{"snip_attachment_text", `uuid IN (SELECT uuid FROM main.snip_attachment WHERE snip_uuid = ?)`},
+	{"snip_checksum", `uuid = ?`},
+	{"snip_due", `uuid = ?`},
+	{"snip_expire", `uuid = ?`},
+	{"snip_index", `uuid = ?`},
+	{"snip_lang", `uuid = ?`},
+	{"snip_lock", `uuid = ?`},
+	{"snip_meta", `uuid = ?`},
+	{"snip_note", `uuid = ?`},
+	{"snip_owner", `uuid = ?`},
+	{"snip_review", `uuid = ?`},
+	{"snip_star", `uuid = ?`},
+	{"snip_status", `uuid = ?`},
+	{"snip_tag", `uuid = ?`},
+	{"snip_track", `uuid = ?`},
+	{"snip_version", `uuid = ?`},
+}
+
+// MoveToDatabase copies snips along with their attachments, tags, notes, versions, and schedules into the snip database at path,
+// creating it if needed, and then removes them from the open database. Either every snip is moved or none are.
+func MoveToDatabase(path string, ids ...uuid.UUID) error {
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	current, err := databaseFile()
+	if err != nil {
+		return err
+	}
+	if current == target {
+		return fmt.Errorf("snips cannot be moved to the database they are in")
+	}
+
+	err = database.Conn.Exec(`ATTACH DATABASE ? AS target`, target)
+	if err != nil {
+		return err
+	}
+	defer database.Conn.Exec(`DETACH DATABASE target`)
+
+	// the schema is copied from the open database, so that a new file becomes a snip database
+	for _, table := range movedTables {
+		err = createTargetTable(table.Name)
+		if err != nil {
+			return err
+		}
+	}
+	err = createTargetTable("snip_term")
+	if err != nil {
+		return err
+	}
+
+	// the trigram index is optional, and is kept only by databases that enabled it
+	trigrams, err := trigramsEnabled("target")
+	if err != nil {
+		return err
+	}
+
+	err = database.Conn.WithTx(func() error {
+		for _, id := range ids {
+			exists, err := hasSnip("main", id)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("snip %s does not exist", id)
+			}
+			exists, err = hasSnip("target", id)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return fmt.Errorf("snip %s already exists in %s", id, target)
+			}
+			for _, table := range movedTables {
+				columns := `*`
+				// notes and intervals are numbered anew by the target database
+				switch table.Name {
+				case "snip_note":
+					columns = `NULL, uuid, timestamp, text`
+				case "snip_track":
+					columns = `NULL, uuid, start, stop`
+				}
+				err = database.Conn.Exec(`INSERT INTO target.`+table.Name+` SELECT `+columns+` FROM main.`+table.Name+` WHERE `+table.Condition, id.String())
+				if err != nil {
+					return fmt.Errorf("copying %s of %s: %w", table.Name, id, err)
+				}
+			}
+			// blobs belong to the open database, so the target holds the data of moved attachments itself
+			if err = inlineBlobs("target", id); err != nil {
+				return err
+			}
+			// the term list is shared by every snip, so only the terms missing from the target are added
+			err = database.Conn.Exec(`INSERT OR IGNORE INTO target.snip_term SELECT * FROM main.snip_term WHERE term IN (SELECT term FROM main.snip_index WHERE uuid = ?)`, id.String())
+			if err != nil {
+				return fmt.Errorf("copying terms of %s: %w", id, err)
+			}
+			if trigrams {
+				s, err := GetFromUUID(id.String())
+				if err != nil {
+					return err
+				}
+				if err = writeTrigrams("target", id, s.Data); err != nil {
+					return fmt.Errorf("indexing trigrams of %s: %w", id, err)
+				}
+			}
+			err = Remove(id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	// the blobs of the moved attachments are deleted only once the target holds their data
+	return errors.Join(err, DeleteUnusedBlobs())
+}
+
+// databaseFile returns the path of the file of the open database
+func databaseFile() (string, error) {
+	stmt, err := database.Conn.Prepare(`SELECT file FROM pragma_database_list WHERE name = 'main'`)
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	hasRow, err := stmt.Step()
+	if err != nil || !hasRow {
+		return "", err
+	}
+	var file string
+	err = stmt.Scan(&file)
+	return file, err
+}
+
+// createTargetTable creates a table of the open database in the attached target database if it is missing there
+func createTargetTable(name string) error {
+	stmt, err := database.Conn.Prepare(`SELECT sql FROM main.sqlite_master WHERE type = 'table' AND name = ?`, name)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	hasRow, err := stmt.Step()
+	if err != nil {
+		return err
+	}
+	if !hasRow {
+		return fmt.Errorf("table %s is missing from the database", name)
+	}
+	var sql string
+	err = stmt.Scan(&sql)
+	if err != nil {
+		return err
+	}
+	// sqlite keeps the statement that created the table without IF NOT EXISTS
+	definition := strings.TrimPrefix(sql, "CREATE TABLE ")
+	return database.Conn.Exec(`CREATE TABLE IF NOT EXISTS target.` + definition)
+}
+
+// hasSnip reports whether the database attached as schema holds a snip with id
+func hasSnip(schema string, id uuid.UUID) (bool, error) {
+	stmt, err := database.Conn.Prepare(`SELECT 1 FROM `+schema+`.snip WHERE uuid = ?`, id.String())
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	return stmt.Step()
+}
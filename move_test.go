@@ -0,0 +1,57 @@
+package snip
+
+import (
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"path"
+	"testing"
+)
+
+func TestMoveToDatabase(t *testing.T) {
+	s := New()
+	s.Data = "moving to another database"
+	if err := InsertSnip(s, WithTags("moving")); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	if err := s.Attach("moved.txt", []byte("attachment data")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddNote(s.UUID, "moved along"); err != nil {
+		t.Fatal(err)
+	}
+
+	target := path.Join(t.TempDir(), "other.sqlite3")
+	if err := MoveToDatabase(target, s.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if _, err := GetFromUUID(s.UUID.String()); err == nil {
+		t.Error("expected the moved snip to be removed")
+	}
+	if err := MoveToDatabase(target, s.UUID); err == nil {
+		t.Error("expected error moving a snip that is no longer present")
+	}
+
+	conn, err := sqlite3.Open(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, table := range []string{"snip", "snip_attachment", "snip_attachment_checksum", "snip_checksum", "snip_note", "snip_tag"} {
+		stmt, err := conn.Prepare(`SELECT count(*) FROM ` + table)
+		if err != nil {
+			t.Fatalf("%s: expected nil err, got %v", table, err)
+		}
+		var count int64
+		if _, err = stmt.Step(); err == nil {
+			err = stmt.Scan(&count)
+		}
+		stmt.Close()
+		if err != nil || count != 1 {
+			t.Errorf("%s: expected 1 row, got %d %v", table, count, err)
+		}
+	}
+
+	if err = MoveToDatabase(DatabasePath, s.UUID); err == nil {
+		t.Error("expected error moving to the open database")
+	}
+}
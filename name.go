@@ -0,0 +1,146 @@
+package snip
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Naming strategies understood by GenerateName
+const (
+	NameWords    = "words"
+	NameLine     = "line"
+	NameHeading  = "heading"
+	NameTemplate = "template"
+)
+
+// nameMaxLength limits names taken from a line of data, in characters
+const nameMaxLength = 80
+
+// NameStrategy describes how a name is derived from snip data
+type NameStrategy struct {
+	// Kind is one of NameWords, NameLine, NameHeading, or NameTemplate
+	Kind string
+	// Words is the number of words used by NameWords and by the template
+	Words int
+	// Template is a text/template with the fields Words, Line, Heading, Date, and Time
+	Template string
+}
+
+// DefaultNameStrategy names snips by their first five words
+var DefaultNameStrategy = NameStrategy{Kind: NameWords, Words: 5}
+
+// nameFields are the values available to a name template
+type nameFields struct {
+	Words   string
+	Line    string
+	Heading string
+	Date    string
+	Time    time.Time
+}
+
+// Validate reports a strategy that GenerateName cannot apply
+func (n NameStrategy) Validate() error {
+	switch n.Kind {
+	case NameWords, NameLine, NameHeading:
+		return nil
+	case NameTemplate:
+		_, err := template.New("name").Parse(n.Template)
+		return err
+	}
+	return fmt.Errorf("unknown naming strategy %q (words|line|heading|template)", n.Kind)
+}
+
+// GenerateName derives a name from data using strategy.
+// Strategies that find nothing to use, such as heading for data without one, fall back to the first words.
+func GenerateName(data []byte, strategy NameStrategy) string {
+	words := strategy.Words
+	if words <= 0 {
+		words = DefaultNameStrategy.Words
+	}
+	text := string(data)
+
+	var name string
+	switch strategy.Kind {
+	case NameLine:
+		name = firstLine(text)
+	case NameHeading:
+		name = firstHeading(text)
+	case NameTemplate:
+		now := time.Now()
+		fields := nameFields{
+			Words:   firstWords(text, words),
+			Line:    firstLine(text),
+			Heading: firstHeading(text),
+			Date:    now.Format("2006-01-02"),
+			Time:    now,
+		}
+		var b strings.Builder
+		tmpl, err := template.New("name").Parse(strategy.Template)
+		if err == nil {
+			err = tmpl.Execute(&b, fields)
+		}
+		if err == nil {
+			name = FlattenString(b.String())
+		}
+	}
+	if strings.TrimSpace(name) == "" {
+		name = firstWords(text, words)
+	}
+	return strings.TrimSpace(name)
+}
+
+// firstWords returns up to count words of text
+func firstWords(text string, count int) string {
+	data := FlattenString(text)
+	// FIXME by allowing additional sensible characters such as `:`
+	pattern := regexp.MustCompile(`\w+`)
+	name := pattern.FindAllString(data, count)
+	return strings.Join(name, " ")
+}
+
+// firstLine returns the first line of text that is not blank, shortened to nameMaxLength
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return truncateName(line)
+		}
+	}
+	return ""
+}
+
+// firstHeading returns the text of the first markdown heading outside of code blocks
+func firstHeading(text string) string {
+	fenced := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced || !strings.HasPrefix(line, "#") {
+			continue
+		}
+		heading := strings.TrimLeft(line, "#")
+		// a heading marker is followed by a space, which distinguishes it from tags such as #todo
+		if len(line)-len(heading) > 6 || (heading != "" && heading[0] != ' ' && heading[0] != '\t') {
+			continue
+		}
+		heading = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(heading), "#"))
+		if heading != "" {
+			return truncateName(heading)
+		}
+	}
+	return ""
+}
+
+// truncateName shortens a name to nameMaxLength characters
+func truncateName(name string) string {
+	runes := []rune(name)
+	if len(runes) <= nameMaxLength {
+		return name
+	}
+	return strings.TrimSpace(string(runes[:nameMaxLength-1])) + "…"
+}
@@ -0,0 +1,99 @@
+package snip
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"strings"
+	"time"
+)
+
+// Note is a dated remark about a snip, kept apart from its data
+type Note struct {
+	ID        int64
+	UUID      uuid.UUID
+	Timestamp time.Time
+	Text      string
+}
+
+// AddNote records a remark about a snip. Notes may be added to locked snips since they leave the data unchanged.
+func AddNote(id uuid.UUID, text string) (Note, error) {
+	n := Note{UUID: id, Timestamp: time.Now(), Text: strings.TrimSpace(text)}
+	if n.Text == "" {
+		return n, fmt.Errorf("refusing to add an empty note")
+	}
+
+	stmt, err := database.Conn.Prepare(`INSERT INTO snip_note (uuid, timestamp, text) VALUES (?, ?, ?)`)
+	if err != nil {
+		return n, err
+	}
+	defer stmt.Close()
+
+	err = stmt.Exec(n.UUID.String(), n.Timestamp.Format(time.RFC3339Nano), n.Text)
+	if err != nil {
+		return n, err
+	}
+	n.ID = database.Conn.LastInsertRowID()
+	return n, nil
+}
+
+// GetNotes returns the notes of a snip, oldest first
+func GetNotes(id uuid.UUID) ([]Note, error) {
+	stmt, err := database.Conn.Prepare(`SELECT id, timestamp, text FROM snip_note WHERE uuid = ? ORDER BY id`, id.String())
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var notes []Note
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return notes, err
+		}
+		if !hasRow {
+			break
+		}
+		n := Note{UUID: id}
+		var timestamp string
+		err = stmt.Scan(&n.ID, &timestamp, &n.Text)
+		if err != nil {
+			return notes, err
+		}
+		n.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return notes, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, nil
+}
+
+// RemoveNote deletes a single note by its id
+func RemoveNote(noteID int64) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_note WHERE id = ?`, noteID)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+	if database.Conn.Changes() == 0 {
+		return fmt.Errorf("note %d does not exist", noteID)
+	}
+	return nil
+}
+
+// RemoveNotes deletes all notes of a snip
+func RemoveNotes(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_note WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
@@ -0,0 +1,59 @@
+package snip
+
+import (
+	"testing"
+)
+
+func TestNotes(t *testing.T) {
+	s := New()
+	s.Data = "apt install build-essential"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	first, err := AddNote(s.UUID, "this broke on Ubuntu 24.04\n")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if _, err = AddNote(s.UUID, "works again after the point release"); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if _, err = AddNote(s.UUID, "  \n"); err == nil {
+		t.Error("expected error adding an empty note")
+	}
+
+	notes, err := GetNotes(s.UUID)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(notes) != 2 || notes[0].Text != "this broke on Ubuntu 24.04" || notes[1].Text != "works again after the point release" {
+		t.Fatalf("expected both notes oldest first, got %+v", notes)
+	}
+
+	// the snip data is left untouched
+	result, err := GetFromUUID(s.UUID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Data != s.Data {
+		t.Errorf("expected data %q, got %q", s.Data, result.Data)
+	}
+
+	if err = RemoveNote(first.ID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if err = RemoveNote(first.ID); err == nil {
+		t.Error("expected error removing a note twice")
+	}
+	if notes, _ = GetNotes(s.UUID); len(notes) != 1 {
+		t.Errorf("expected 1 note after removal, got %+v", notes)
+	}
+
+	if err = Remove(s.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if notes, _ = GetNotes(s.UUID); len(notes) != 0 {
+		t.Errorf("expected notes to be removed with the snip, got %+v", notes)
+	}
+}
@@ -0,0 +1,55 @@
+package snip
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+)
+
+// SetOwner records the user a snip belongs to when several users share a server, an empty owner removes it
+func SetOwner(id uuid.UUID, owner string) error {
+	if owner == "" {
+		return RemoveOwner(id)
+	}
+	return database.Exec(`INSERT OR REPLACE INTO snip_owner (uuid, owner) VALUES (?, ?)`, id.String(), owner)
+}
+
+// GetOwner returns the user a snip belongs to, or an empty string if it has no owner
+func GetOwner(id uuid.UUID) (string, error) {
+	var owner string
+	err := database.QueryRow(`SELECT owner FROM snip_owner WHERE uuid = ?`, []interface{}{id.String()}, &owner)
+	if errors.Is(err, database.ErrNoRows) {
+		return "", nil
+	}
+	return owner, err
+}
+
+// OwnedBy returns which of ids belong to owner
+func OwnedBy(owner string, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	owned := make(map[uuid.UUID]bool)
+	stmt, err := database.Prepare(`SELECT uuid FROM snip_owner WHERE owner = ? AND uuid IN (SELECT value FROM json_each(?))`, owner, uuidList(ids))
+	if err != nil {
+		return owned, err
+	}
+	defer database.Release(stmt)
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return owned, err
+		}
+		if !hasRow {
+			return owned, nil
+		}
+		var id uuid.UUID
+		if err = database.Scan(stmt, &id); err != nil {
+			return owned, err
+		}
+		owned[id] = true
+	}
+}
+
+// RemoveOwner removes the owner of a snip
+func RemoveOwner(id uuid.UUID) error {
+	return database.Exec(`DELETE FROM snip_owner WHERE uuid = ?`, id.String())
+}
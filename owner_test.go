@@ -0,0 +1,54 @@
+package snip
+
+import (
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestOwner(t *testing.T) {
+	var ids []uuid.UUID
+	for _, owner := range []string{"alice", "bob", ""} {
+		s := New()
+		s.Data = "owned by " + owner
+		s.Owner = owner
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		ids = append(ids, s.UUID)
+	}
+
+	s, err := GetSnipMeta(ids[0].String())
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if s.Owner != "alice" {
+		t.Errorf("expected owner alice, got %q", s.Owner)
+	}
+	if owner, err := GetOwner(ids[2]); err != nil || owner != "" {
+		t.Errorf("expected no owner, got %q and %v", owner, err)
+	}
+
+	owned, err := OwnedBy("bob", ids)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(owned) != 1 || !owned[ids[1]] {
+		t.Errorf("expected only the snip of bob, got %v", owned)
+	}
+
+	snips, _, err := ListPage(ListOptions{Owner: "alice"})
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(snips) != 1 || snips[0].UUID != ids[0] || snips[0].Owner != "alice" {
+		t.Errorf("expected only the snip of alice, got %v", snips)
+	}
+
+	if err = SetOwner(ids[0], ""); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if owner, _ := GetOwner(ids[0]); owner != "" {
+		t.Errorf("expected the owner to be removed, got %q", owner)
+	}
+}
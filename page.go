@@ -0,0 +1,209 @@
+package snip
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a page cursor was not returned by ListPage with the same sort
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// listSorts maps each sort of ListPage to the expression snips are ordered by and its direction
+var listSorts = map[string]struct {
+	key  string
+	desc bool
+}{
+	"":       {`rowid`, false},
+	"newest": {`julianday(timestamp)`, true},
+	"oldest": {`julianday(timestamp)`, false},
+	"name":   {`name COLLATE NOCASE`, false},
+}
+
+// ListOptions selects, orders, and pages the snips returned by ListPage
+type ListOptions struct {
+	// Sort is newest, oldest, or name, or empty for the order snips were added
+	Sort string
+	// Tag limits the listing to snips with the tag when not empty
+	Tag string
+	// Owner limits the listing to snips belonging to the user when not empty
+	Owner string
+	// Since and Until limit the listing to snips created at or after Since and before Until when they are not zero
+	Since time.Time
+	Until time.Time
+	// Limit is the number of snips in a page, zero returns all of them
+	Limit int
+	// Cursor continues the listing after the page that returned it
+	Cursor string
+	// Data reads the data of each snip as well
+	Data bool
+}
+
+// pageCursor is the position of the last snip of a page, encoded as the cursor of the next one
+type pageCursor struct {
+	Sort string      `json:"s"`
+	Key  interface{} `json:"k"`
+	UUID uuid.UUID   `json:"u"`
+}
+
+// Validate checks the sort and cursor of the options
+func (o ListOptions) Validate() error {
+	if _, ok := listSorts[o.Sort]; !ok {
+		return fmt.Errorf("unknown sort %q (newest|oldest|name)", o.Sort)
+	}
+	if o.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+	_, err := o.cursor()
+	return err
+}
+
+// cursor decodes the cursor of the options, returning nil when there is none
+func (o ListOptions) cursor() (*pageCursor, error) {
+	if o.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(o.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err = json.Unmarshal(data, &c); err != nil || c.Sort != o.Sort {
+		return nil, ErrInvalidCursor
+	}
+	switch c.Key.(type) {
+	case float64, string:
+		return &c, nil
+	}
+	return nil, ErrInvalidCursor
+}
+
+// ListPage returns a page of unexpired snips with their tags and owners, and the cursor of the next page, which is empty on the last page.
+// Pages are positioned by the last snip returned rather than an offset, so they stay consistent while snips are added and removed.
+func ListPage(opts ListOptions) ([]Snip, string, error) {
+	var results []Snip
+	if err := opts.Validate(); err != nil {
+		return results, "", err
+	}
+	sort := listSorts[opts.Sort]
+
+	columns := `uuid, timestamp, name, (SELECT owner FROM snip_owner WHERE snip_owner.uuid = snip.uuid), ` + sort.key
+	if opts.Data {
+		columns += `, data`
+	}
+	conditions := []string{unexpired}
+	args := []interface{}{sortableNow()}
+	if opts.Tag != "" {
+		tag, err := NormalizeTag(opts.Tag)
+		if err != nil {
+			return results, "", err
+		}
+		conditions = append(conditions, `uuid IN (SELECT uuid FROM snip_tag WHERE tag = ?)`)
+		args = append(args, tag)
+	}
+	if opts.Owner != "" {
+		conditions = append(conditions, `uuid IN (SELECT uuid FROM snip_owner WHERE owner = ?)`)
+		args = append(args, opts.Owner)
+	}
+	if !opts.Since.IsZero() {
+		conditions = append(conditions, `julianday(timestamp) >= julianday(?)`)
+		args = append(args, opts.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if !opts.Until.IsZero() {
+		conditions = append(conditions, `julianday(timestamp) < julianday(?)`)
+		args = append(args, opts.Until.UTC().Format(time.RFC3339Nano))
+	}
+	direction, after := `ASC`, `>`
+	if sort.desc {
+		direction, after = `DESC`, `<`
+	}
+	c, _ := opts.cursor()
+	if c != nil {
+		conditions = append(conditions, `(`+sort.key+`, uuid) `+after+` (?, ?)`)
+		args = append(args, c.Key, c.UUID.String())
+	}
+	// one more than the page is read to learn whether another page follows
+	limit := -1
+	if opts.Limit > 0 {
+		limit = opts.Limit + 1
+	}
+	args = append(args, limit)
+	query := `SELECT ` + columns + ` FROM snip WHERE ` + strings.Join(conditions, ` AND `) + ` ORDER BY ` + sort.key + ` ` + direction + `, uuid ` + direction + ` LIMIT ?`
+
+	stmt, err := database.Prepare(query, args...)
+	if err != nil {
+		return results, "", err
+	}
+	defer database.Release(stmt)
+
+	var last pageCursor
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return results, "", err
+		}
+		if !hasRow {
+			break
+		}
+		if opts.Limit > 0 && len(results) == opts.Limit {
+			next, err := json.Marshal(last)
+			if err != nil {
+				return results, "", err
+			}
+			return results, base64.RawURLEncoding.EncodeToString(next), snipTags(results)
+		}
+		var s Snip
+		var owner interface{}
+		dst := []interface{}{&s.UUID, &s.Timestamp, &s.Name, &owner, &last.Key}
+		var data []byte
+		if opts.Data {
+			dst = append(dst, &data)
+		}
+		if err = database.Scan(stmt, dst...); err != nil {
+			return results, "", err
+		}
+		s.Data = string(data)
+		s.Owner, _ = owner.(string)
+		last.Sort = opts.Sort
+		last.UUID = s.UUID
+		results = append(results, s)
+	}
+	return results, "", snipTags(results)
+}
+
+// snipTags reads the tags of each of snips in a single query
+func snipTags(snips []Snip) error {
+	ids := make([]uuid.UUID, len(snips))
+	positions := make(map[uuid.UUID]int)
+	for idx, s := range snips {
+		ids[idx] = s.UUID
+		positions[s.UUID] = idx
+	}
+	stmt, err := database.Prepare(`SELECT uuid, tag FROM snip_tag WHERE uuid IN (SELECT value FROM json_each(?)) ORDER BY tag`, uuidList(ids))
+	if err != nil {
+		return err
+	}
+	defer database.Release(stmt)
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			return nil
+		}
+		var id uuid.UUID
+		var tag string
+		if err = database.Scan(stmt, &id, &tag); err != nil {
+			return err
+		}
+		idx := positions[id]
+		snips[idx].Tags = append(snips[idx].Tags, tag)
+	}
+}
@@ -0,0 +1,90 @@
+package snip
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestListPage(t *testing.T) {
+	base := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	var ids []uuid.UUID
+	for idx, name := range []string{"cherry", "Apple", "banana"} {
+		s := New()
+		s.Name = name
+		s.Data = "page data " + name
+		s.Timestamp = base.Add(time.Duration(idx) * time.Hour)
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		if err := s.AddTag("paged"); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s.UUID)
+	}
+
+	tests := []struct {
+		opts     ListOptions
+		expected []string
+	}{
+		{ListOptions{Tag: "paged"}, []string{"cherry", "Apple", "banana"}},
+		{ListOptions{Tag: "paged", Sort: "newest"}, []string{"banana", "Apple", "cherry"}},
+		{ListOptions{Tag: "paged", Sort: "name"}, []string{"Apple", "banana", "cherry"}},
+		{ListOptions{Tag: "paged", Since: base.Add(time.Hour)}, []string{"Apple", "banana"}},
+		{ListOptions{Tag: "paged", Until: base.Add(time.Hour)}, []string{"cherry"}},
+	}
+	for _, tt := range tests {
+		snips, next, err := ListPage(tt.opts)
+		if err != nil {
+			t.Fatalf("expected nil err listing %+v, got %v", tt.opts, err)
+		}
+		var names []string
+		for _, s := range snips {
+			names = append(names, s.Name)
+		}
+		if next != "" || len(names) != len(tt.expected) {
+			t.Errorf("expected %v without a next page listing %+v, got %v and %q", tt.expected, tt.opts, names, next)
+			continue
+		}
+		for idx := range names {
+			if names[idx] != tt.expected[idx] {
+				t.Errorf("expected %v listing %+v, got %v", tt.expected, tt.opts, names)
+				break
+			}
+		}
+	}
+
+	// follow the cursor through pages of one, reading data and tags
+	opts := ListOptions{Tag: "paged", Sort: "oldest", Limit: 1, Data: true}
+	var pages int
+	for {
+		snips, next, err := ListPage(opts)
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if len(snips) != 1 || snips[0].UUID != ids[pages] {
+			t.Fatalf("expected page %d to hold %s, got %v", pages, ids[pages], snips)
+		}
+		if snips[0].Data != "page data "+snips[0].Name || len(snips[0].Tags) != 1 || snips[0].Tags[0] != "paged" {
+			t.Errorf("expected data and tags to be read, got %+v", snips[0])
+		}
+		pages++
+		if next == "" {
+			break
+		}
+		opts.Cursor = next
+	}
+	if pages != 3 {
+		t.Errorf("expected 3 pages, got %d", pages)
+	}
+
+	// a cursor belongs to the sort that returned it
+	opts.Sort = "name"
+	if _, _, err := ListPage(opts); err != ErrInvalidCursor {
+		t.Errorf("expected %v, got %v", ErrInvalidCursor, err)
+	}
+	if err := (ListOptions{Sort: "size"}).Validate(); err == nil {
+		t.Errorf("expected an unknown sort to be refused")
+	}
+}
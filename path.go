@@ -0,0 +1,138 @@
+package snip
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GetPathUUID returns the uuid of the snip mapped to a file path, or uuid.Nil if there is no mapping
+func GetPathUUID(path string) (uuid.UUID, error) {
+	stmt, err := database.Conn.Prepare(`SELECT uuid FROM snip_path WHERE path = ?`, path)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer stmt.Close()
+
+	hasRow, err := stmt.Step()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !hasRow {
+		return uuid.Nil, nil
+	}
+	var idStr string
+	err = stmt.Scan(&idStr)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(idStr)
+}
+
+// RemovePaths deletes all file path mappings of a snip
+func RemovePaths(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_path WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
+
+// SetPath maps a file path to the snip, replacing any previous mapping of the path
+func (s *Snip) SetPath(path string) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_path WHERE path = ?`)
+	if err != nil {
+		return err
+	}
+	err = stmt.Exec(path)
+	stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	stmt, err = database.Conn.Prepare(`INSERT INTO snip_path (path, uuid) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec(path, s.UUID.String())
+}
+
+// NameFromPath returns a snip name derived from a file name without its extension
+func NameFromPath(path string) string {
+	base := filepath.Base(path)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	if name == "" {
+		return base
+	}
+	return name
+}
+
+// SyncFile creates or updates the snip mapped to a file, reporting whether the snip was created or changed
+func SyncFile(path string) (Snip, bool, bool, error) {
+	var s Snip
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return s, false, false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, false, false, err
+	}
+
+	id, err := GetPathUUID(path)
+	if err != nil {
+		return s, false, false, err
+	}
+	if id != uuid.Nil {
+		s, err = GetFromUUID(id.String())
+		if err != nil {
+			// the snip may have been removed, so create a new one below
+			id = uuid.Nil
+		}
+	}
+
+	if id == uuid.Nil {
+		s = New()
+		s.Name = NameFromPath(path)
+		s.Data = string(data)
+		err = InsertSnip(s)
+		if err != nil {
+			return s, false, false, err
+		}
+		err = s.SetPath(path)
+		if err != nil {
+			return s, false, false, err
+		}
+		err = s.Index()
+		if err != nil {
+			return s, false, false, err
+		}
+		return s, true, true, nil
+	}
+
+	if s.Data == string(data) {
+		return s, false, false, nil
+	}
+	s.Data = string(data)
+	err = s.Update()
+	if err != nil {
+		return s, false, false, err
+	}
+	err = RemoveIndex(s.UUID)
+	if err != nil {
+		return s, false, false, fmt.Errorf("removing stale index: %w", err)
+	}
+	err = s.Index()
+	if err != nil {
+		return s, false, false, err
+	}
+	return s, false, true, nil
+}
@@ -0,0 +1,77 @@
+package snip
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNameFromPath(t *testing.T) {
+	tests := map[string]string{
+		"/notes/postgres-backup.md": "postgres-backup",
+		"notes/todo":                "todo",
+		"archive.tar.gz":            "archive.tar",
+		"/notes/.hidden":            ".hidden",
+	}
+	for path, expected := range tests {
+		if name := NameFromPath(path); name != expected {
+			t.Errorf("expected name %s for %s, got %s", expected, path, name)
+		}
+	}
+}
+
+func TestSyncFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync-test.md")
+	err := os.WriteFile(path, []byte("first revision of the watched file"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, created, changed, err := SyncFile(path)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	defer func() {
+		if err := Remove(s.UUID); err != nil {
+			t.Errorf("error removing synced snip: %v", err)
+		}
+	}()
+	if !created || !changed {
+		t.Errorf("expected first sync to create snip, got created %t changed %t", created, changed)
+	}
+	if s.Name != "sync-test" {
+		t.Errorf("expected name sync-test, got %s", s.Name)
+	}
+
+	// unchanged data is left alone
+	_, created, changed, err = SyncFile(path)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if created || changed {
+		t.Errorf("expected unchanged sync, got created %t changed %t", created, changed)
+	}
+
+	err = os.WriteFile(path, []byte("second revision"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, created, changed, err := SyncFile(path)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if created || !changed || updated.UUID != s.UUID {
+		t.Errorf("expected update of %s, got %s created %t changed %t", s.UUID, updated.UUID, created, changed)
+	}
+	if updated.Data != "second revision" {
+		t.Errorf("expected updated data, got %s", updated.Data)
+	}
+	// stale terms must not remain in the index
+	count, err := GetIndexTermCount("first", s.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected stale term to be removed from index, got count %d", count)
+	}
+}
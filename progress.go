@@ -0,0 +1,46 @@
+package snip
+
+import "fmt"
+
+// Progress receives the advance of a long running operation such as rebuilding the index, so that it can be shown to a user or reported to a client
+type Progress interface {
+	// Step reports that done of total items of the named stage have been handled. Total is zero when it is not known.
+	Step(stage string, done int, total int)
+}
+
+// ProgressFunc adapts a function to the Progress interface
+type ProgressFunc func(stage string, done int, total int)
+
+// Step calls f
+func (f ProgressFunc) Step(stage string, done int, total int) {
+	f(stage, done, total)
+}
+
+// reportStep passes a step to progress, which may be nil when the caller does not follow progress
+func reportStep(progress Progress, stage string, done int, total int) {
+	if progress != nil {
+		progress.Step(stage, done, total)
+	}
+}
+
+// RebuildIndex drops the search index and indexes every snip again, reporting each snip indexed to progress
+func RebuildIndex(progress Progress) error {
+	if err := DropIndex(); err != nil {
+		return err
+	}
+	ids, err := GetAllSnipIDs()
+	if err != nil {
+		return err
+	}
+	for idx, id := range ids {
+		s, err := GetFromUUID(id.String())
+		if err != nil {
+			return err
+		}
+		if err = s.Index(); err != nil {
+			return fmt.Errorf("indexing %s: %w", id, err)
+		}
+		reportStep(progress, "indexing", idx+1, len(ids))
+	}
+	return nil
+}
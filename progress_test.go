@@ -0,0 +1,54 @@
+package snip
+
+import (
+	"testing"
+)
+
+func TestRebuildIndex(t *testing.T) {
+	s := New()
+	s.Data = "a zeppelin indexed again"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	ids, err := GetAllSnipIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var steps int
+	var last [2]int
+	err = RebuildIndex(ProgressFunc(func(stage string, done int, total int) {
+		if stage != "indexing" {
+			t.Errorf("expected stage indexing, got %s", stage)
+		}
+		steps++
+		last = [2]int{done, total}
+	}))
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if steps != len(ids) || last != [2]int{len(ids), len(ids)} {
+		t.Errorf("expected %d steps ending at %d/%d, got %d ending at %d/%d", len(ids), len(ids), len(ids), steps, last[0], last[1])
+	}
+
+	results, err := SearchIndexTerm([]string{"zeppelin"}, false)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if _, ok := results[s.UUID]; !ok {
+		t.Errorf("expected the snip to be found after rebuilding the index, got %v", results)
+	}
+}
+
+func TestVerifyProgress(t *testing.T) {
+	var last [2]int
+	v, err := Verify(ProgressFunc(func(stage string, done int, total int) {
+		last = [2]int{done, total}
+	}))
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if last[0] != last[1] || last[1] != v.Snips+v.Attachments {
+		t.Errorf("expected progress to end at %d, got %d/%d", v.Snips+v.Attachments, last[0], last[1])
+	}
+}
@@ -0,0 +1,112 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"strconv"
+)
+
+// ErrAttachmentTooLarge is returned when an attachment is larger than MaxAttachmentSize
+var ErrAttachmentTooLarge = errors.New("attachment is too large")
+
+// ErrQuotaExceeded is returned when an attachment would take the data stored beyond StorageQuota
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// MaxAttachmentSize is the size in bytes of the largest attachment that may be added, without a limit when zero
+var MaxAttachmentSize int64
+
+// StorageQuota is the size in bytes that the data of snips and their attachments may reach together, without a limit when zero
+var StorageQuota int64
+
+// Usage is the data stored by a snip and its attachments
+type Usage struct {
+	UUID uuid.UUID
+	Name string
+	// DataSize is the size of the data of the snip itself
+	DataSize        int64
+	Attachments     int
+	AttachmentsSize int64
+}
+
+// Size returns the size of the data of the snip and its attachments
+func (u Usage) Size() int64 {
+	return u.DataSize + u.AttachmentsSize
+}
+
+// StorageUsed returns the size of the data of every snip and attachment, as counted against StorageQuota.
+// Attachments kept in blobs are counted, while the search index and space not yet reclaimed by vacuum are not.
+func StorageUsed() (int64, error) {
+	var used int64
+	err := database.QueryRow(`SELECT coalesce((SELECT sum(length(CAST(data AS BLOB))) FROM snip), 0) + coalesce((SELECT sum(size) FROM snip_attachment), 0)`, nil, &used)
+	return used, err
+}
+
+// CheckAttachmentSize returns an error wrapping ErrAttachmentTooLarge or ErrQuotaExceeded when adding an attachment of size bytes
+// would break MaxAttachmentSize or StorageQuota
+func CheckAttachmentSize(size int64) error {
+	if MaxAttachmentSize > 0 && size > MaxAttachmentSize {
+		return fmt.Errorf("%w: %s is larger than the limit of %s", ErrAttachmentTooLarge, FormatSize(size), FormatSize(MaxAttachmentSize))
+	}
+	if StorageQuota == 0 {
+		return nil
+	}
+	used, err := StorageUsed()
+	if err != nil {
+		return err
+	}
+	if used+size > StorageQuota {
+		return fmt.Errorf("%w: %s more than the %s stored would pass the quota of %s", ErrQuotaExceeded, FormatSize(size), FormatSize(used), FormatSize(StorageQuota))
+	}
+	return nil
+}
+
+// ListLargest returns the n snips storing the most data along with their attachments, largest first
+func ListLargest(n int) ([]Usage, error) {
+	var usages []Usage
+	stmt, err := database.Prepare(`SELECT snip.uuid, snip.name, length(CAST(snip.data AS BLOB)), count(a.uuid), coalesce(sum(a.size), 0) AS attached `+
+		`FROM snip LEFT JOIN snip_attachment a ON a.snip_uuid = snip.uuid GROUP BY snip.uuid `+
+		`ORDER BY length(CAST(snip.data AS BLOB)) + attached DESC, snip.timestamp LIMIT ?`, n)
+	if err != nil {
+		return usages, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return usages, err
+		}
+		if !hasRow {
+			break
+		}
+		var u Usage
+		if err = database.Scan(stmt, &u.UUID, &u.Name, &u.DataSize, &u.Attachments, &u.AttachmentsSize); err != nil {
+			return usages, err
+		}
+		usages = append(usages, u)
+	}
+	return usages, nil
+}
+
+// sizeSuffixes are the units of FormatSize in powers of 1024
+var sizeSuffixes = []string{"K", "M", "G", "T"}
+
+// FormatSize returns a number of bytes in the largest unit of K, M, G, or T that leaves at least one, such as 512K or 4.2G
+func FormatSize(n int64) string {
+	if n < 1024 {
+		return strconv.FormatInt(n, 10) + "B"
+	}
+	size := float64(n)
+	suffix := ""
+	for _, suffix = range sizeSuffixes {
+		size /= 1024
+		if size < 1024 {
+			break
+		}
+	}
+	if size < 10 {
+		return strconv.FormatFloat(size, 'f', 1, 64) + suffix
+	}
+	return strconv.FormatFloat(size, 'f', 0, 64) + suffix
+}
@@ -0,0 +1,73 @@
+package snip
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormatSize(t *testing.T) {
+	for n, expected := range map[int64]string{
+		0:                   "0B",
+		1023:                "1023B",
+		1024:                "1.0K",
+		512 << 10:           "512K",
+		100 << 20:           "100M",
+		4*(1<<30) + 200<<20: "4.2G",
+		3 << 40:             "3.0T",
+	} {
+		if got := FormatSize(n); got != expected {
+			t.Errorf("%d: expected %s, got %s", n, expected, got)
+		}
+	}
+}
+
+func TestCheckAttachmentSize(t *testing.T) {
+	defer func() {
+		MaxAttachmentSize, StorageQuota = 0, 0
+	}()
+	s := New()
+	s.Name = "quota test"
+	s.Data = "an iso image"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	MaxAttachmentSize = 8
+	if _, err := s.AddAttachment("big.iso", []byte("larger than eight")); !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Errorf("expected ErrAttachmentTooLarge, got %v", err)
+	}
+	if _, err := s.AddAttachment("small.txt", []byte("small")); err != nil {
+		t.Errorf("expected nil err, got %v", err)
+	}
+
+	used, err := StorageUsed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	StorageQuota = used + 5
+	if err = CheckAttachmentSize(5); err != nil {
+		t.Errorf("expected an attachment filling the quota allowed, got %v", err)
+	}
+	if _, err = s.AddAttachment("more.txt", []byte("more!!")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded, got %v", err)
+	}
+
+	usages, err := ListLargest(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for idx, u := range usages {
+		if idx > 0 && u.Size() > usages[idx-1].Size() {
+			t.Errorf("expected the largest snips first, got %d after %d", u.Size(), usages[idx-1].Size())
+		}
+		if u.UUID == s.UUID && (u.DataSize != 12 || u.Attachments != 1 || u.AttachmentsSize != 5) {
+			t.Errorf("expected the data and one attachment counted, got %+v", u)
+		}
+		total += u.Size()
+	}
+	if total != used {
+		t.Errorf("expected the usage of every snip to add up to %d, got %d", used, total)
+	}
+}
@@ -0,0 +1,74 @@
+package snip
+
+import (
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"time"
+)
+
+// accessLayout stores access times in UTC at a fixed width with nanoseconds, so that accesses within the same second stay in order
+const accessLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Access is the last time a snip was viewed or modified
+type Access struct {
+	UUID     uuid.UUID
+	Accessed time.Time
+}
+
+// RecordAccess marks a snip as viewed or modified now
+func RecordAccess(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`INSERT OR REPLACE INTO snip_access (uuid, accessed) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec(id.String(), time.Now().UTC().Format(accessLayout))
+}
+
+// RemoveAccess forgets when a snip was last used
+func RemoveAccess(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_access WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
+
+// Recent returns up to limit of the most recently viewed or modified snips, most recent first. Expired snips are left out.
+func Recent(limit int) ([]Access, error) {
+	stmt, err := database.Conn.Prepare(`SELECT uuid, accessed FROM snip_access WHERE `+unexpired+` ORDER BY accessed DESC LIMIT ?`, sortableNow(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var recent []Access
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return recent, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr, accessed string
+		err = stmt.Scan(&idStr, &accessed)
+		if err != nil {
+			return recent, err
+		}
+		a := Access{}
+		a.UUID, err = uuid.Parse(idStr)
+		if err != nil {
+			return recent, err
+		}
+		a.Accessed, err = time.Parse(accessLayout, accessed)
+		if err != nil {
+			return recent, err
+		}
+		recent = append(recent, a)
+	}
+	return recent, nil
+}
@@ -0,0 +1,48 @@
+package snip
+
+import (
+	"testing"
+)
+
+func TestRecent(t *testing.T) {
+	var snips []Snip
+	for _, data := range []string{"first recent snip", "second recent snip"} {
+		s := New()
+		s.Data = data
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		snips = append(snips, s)
+	}
+
+	recent, err := Recent(1)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(recent) != 1 || recent[0].UUID != snips[1].UUID {
+		t.Errorf("expected the last inserted snip %s, got %v", snips[1].UUID, recent)
+	}
+
+	if err = RecordAccess(snips[0].UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	recent, err = Recent(2)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(recent) != 2 || recent[0].UUID != snips[0].UUID || recent[1].UUID != snips[1].UUID {
+		t.Errorf("expected the viewed snip %s first, got %v", snips[0].UUID, recent)
+	}
+
+	if err = Remove(snips[0].UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	recent, err = Recent(1)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(recent) != 1 || recent[0].UUID != snips[1].UUID {
+		t.Errorf("expected a removed snip to be forgotten, got %v", recent)
+	}
+}
@@ -0,0 +1,151 @@
+package snip
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"math"
+	"time"
+)
+
+// Grade rates how well a snip was recalled during review
+type Grade int
+
+// Grades given during review, from forgotten to effortless
+const (
+	GradeAgain Grade = iota
+	GradeHard
+	GradeGood
+	GradeEasy
+)
+
+// gradeQuality maps each grade to the recall quality of the SM-2 algorithm, from 0 to 5
+var gradeQuality = map[Grade]float64{
+	GradeAgain: 1,
+	GradeHard:  3,
+	GradeGood:  4,
+	GradeEasy:  5,
+}
+
+// Ease limits of a review schedule, the factor by which the interval grows after each successful review
+const (
+	initialEase = 2.5
+	minimumEase = 1.3
+)
+
+// Review is the spaced repetition schedule of a snip
+type Review struct {
+	UUID uuid.UUID
+	Due  time.Time
+	// Interval is the number of days until the next review
+	Interval    int
+	Ease        float64
+	Repetitions int
+}
+
+// Grade schedules the next review following the SM-2 algorithm, given how well the snip was recalled at now.
+// A forgotten snip starts over and is reviewed again the next day.
+func (r *Review) Grade(grade Grade, now time.Time) error {
+	quality, ok := gradeQuality[grade]
+	if !ok {
+		return fmt.Errorf("grade %d is not valid", grade)
+	}
+
+	r.Ease = math.Max(minimumEase, r.Ease+0.1-(5-quality)*(0.08+(5-quality)*0.02))
+	switch {
+	case grade == GradeAgain:
+		r.Repetitions = 0
+		r.Interval = 1
+	case r.Repetitions == 0:
+		r.Interval = 1
+	case r.Repetitions == 1:
+		r.Interval = 6
+	default:
+		r.Interval = int(math.Round(float64(r.Interval) * r.Ease))
+	}
+	if grade != GradeAgain {
+		r.Repetitions++
+	}
+	r.Due = now.AddDate(0, 0, r.Interval)
+	return nil
+}
+
+// AddReview schedules a snip for review now, keeping the schedule of a snip that already has one
+func AddReview(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`INSERT OR IGNORE INTO snip_review (uuid, due, interval, ease, repetitions) VALUES (?, ?, 0, ?, 0)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec(id.String(), sortableNow(), initialEase)
+}
+
+// SetReview stores the schedule of a snip
+func SetReview(r Review) error {
+	stmt, err := database.Conn.Prepare(`INSERT OR REPLACE INTO snip_review (uuid, due, interval, ease, repetitions) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec(r.UUID.String(), r.Due.UTC().Format(sortableLayout), r.Interval, r.Ease, r.Repetitions)
+}
+
+// RemoveReview stops reviewing a snip
+func RemoveReview(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_review WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
+
+// ListReviews returns the schedule of every snip under review, soonest first
+func ListReviews() ([]Review, error) {
+	return queryReviews(`SELECT uuid, due, interval, ease, repetitions FROM snip_review ORDER BY due`)
+}
+
+// DueReviews returns the schedules of the snips due for review, longest waiting first. Expired snips are left out.
+func DueReviews() ([]Review, error) {
+	now := sortableNow()
+	return queryReviews(`SELECT uuid, due, interval, ease, repetitions FROM snip_review WHERE due <= ? AND `+unexpired+` ORDER BY due`, now, now)
+}
+
+// queryReviews returns the schedule in each row returned by query
+func queryReviews(query string, args ...interface{}) ([]Review, error) {
+	stmt, err := database.Conn.Prepare(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var reviews []Review
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return reviews, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr, due string
+		r := Review{}
+		err = stmt.Scan(&idStr, &due, &r.Interval, &r.Ease, &r.Repetitions)
+		if err != nil {
+			return reviews, err
+		}
+		r.UUID, err = uuid.Parse(idStr)
+		if err != nil {
+			return reviews, err
+		}
+		r.Due, err = time.Parse(sortableLayout, due)
+		if err != nil {
+			return reviews, err
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, nil
+}
@@ -0,0 +1,87 @@
+package snip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReviewGrade(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 9, 0, 0, 0, time.UTC)
+	r := Review{Ease: initialEase}
+	tests := []struct {
+		grade    Grade
+		interval int
+	}{
+		{GradeGood, 1},
+		{GradeGood, 6},
+		{GradeEasy, 16},
+		{GradeAgain, 1},
+		{GradeHard, 1},
+	}
+	for idx, test := range tests {
+		if err := r.Grade(test.grade, now); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if r.Interval != test.interval {
+			t.Errorf("review %d: expected interval of %d days, got %d", idx, test.interval, r.Interval)
+		}
+		if !r.Due.Equal(now.AddDate(0, 0, test.interval)) {
+			t.Errorf("review %d: expected due %s, got %s", idx, now.AddDate(0, 0, test.interval), r.Due)
+		}
+	}
+	if r.Ease < minimumEase || r.Ease >= initialEase {
+		t.Errorf("expected ease lowered by forgetting, got %f", r.Ease)
+	}
+	if err := r.Grade(Grade(9), now); err == nil {
+		t.Error("expected error for an invalid grade")
+	}
+}
+
+func TestReviews(t *testing.T) {
+	s := New()
+	s.Data = "a flashcard"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	if err := AddReview(s.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	due, err := DueReviews()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(due) != 1 || due[0].UUID != s.UUID || due[0].Ease != initialEase {
+		t.Fatalf("expected the new review due now, got %v", due)
+	}
+
+	r := due[0]
+	if err = r.Grade(GradeGood, time.Now()); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if err = SetReview(r); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	// scheduling again keeps the existing schedule
+	if err = AddReview(s.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if due, _ = DueReviews(); len(due) != 0 {
+		t.Errorf("expected nothing due after grading, got %v", due)
+	}
+	reviews, err := ListReviews()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].Repetitions != 1 || reviews[0].Interval != 1 {
+		t.Errorf("expected the graded schedule, got %v", reviews)
+	}
+
+	if err = Remove(s.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if reviews, _ = ListReviews(); len(reviews) != 0 {
+		t.Errorf("expected removing the snip to stop its review, got %v", reviews)
+	}
+}
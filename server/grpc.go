@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/snippb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+	"strings"
+)
+
+// attachmentChunkSize is the amount of attachment data sent in each streamed chunk
+const attachmentChunkSize = 64 * 1024
+
+// GRPCServer serves snip operations as the gRPC service defined in snippb/snip.proto
+type GRPCServer struct {
+	snippb.UnimplementedSnipServiceServer
+	store snip.Store
+	// maxUpload is the largest attachment accepted in bytes, zero for no limit
+	maxUpload int64
+}
+
+// NewGRPC returns a grpc.Server with the snip service registered, requiring token when it is not empty and enforcing limits
+func NewGRPC(token string, limits Limits) *grpc.Server {
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	if token != "" {
+		unary = append(unary, func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if err := checkToken(ctx, token); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		})
+		stream = append(stream, func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := checkToken(ss.Context(), token); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		})
+	}
+	// calls are counted once their token has been accepted
+	if limits.RequestsPerMinute > 0 {
+		limitUnary, limitStream := limitInterceptors(limits, token != "")
+		unary = append(unary, limitUnary)
+		stream = append(stream, limitStream)
+	}
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	snippb.RegisterSnipServiceServer(s, &GRPCServer{store: snip.LocalStore{}, maxUpload: limits.MaxUpload})
+	return s
+}
+
+// checkToken verifies that the call metadata presents token as a bearer credential
+func checkToken(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var supplied string
+	if values := md.Get("authorization"); len(values) > 0 {
+		supplied = strings.TrimPrefix(values[0], "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(supplied), []byte(token)) != 1 {
+		return status.Error(codes.Unauthenticated, "unauthorized")
+	}
+	return nil
+}
+
+// ListSnips returns the metadata of snips without their data
+func (srv *GRPCServer) ListSnips(ctx context.Context, req *snippb.ListSnipsRequest) (*snippb.ListSnipsResponse, error) {
+	snips, err := srv.store.List(int(req.GetLimit()))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &snippb.ListSnipsResponse{}
+	for _, s := range snips {
+		resp.Snips = append(resp.Snips, snipToProto(s))
+	}
+	return resp, nil
+}
+
+// GetSnip returns a single snip by full or partial uuid
+func (srv *GRPCServer) GetSnip(ctx context.Context, req *snippb.GetSnipRequest) (*snippb.Snip, error) {
+	s, err := srv.store.Get(req.GetUuid())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return snipToProto(s), nil
+}
+
+// Search streams scored index search results with context
+func (srv *GRPCServer) Search(req *snippb.SearchRequest, stream snippb.SnipService_SearchServer) error {
+	if len(req.GetTerms()) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one search term is required")
+	}
+	adjacent := 6
+	if req.Context != nil {
+		adjacent = int(req.GetContext())
+	}
+
+	matches, err := srv.store.Search(req.GetTerms(), int(req.GetLimit()), adjacent)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for _, m := range matches {
+		if err := stream.Send(matchToProto(m)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchEvents streams change events until the client cancels
+func (srv *GRPCServer) WatchEvents(req *snippb.WatchEventsRequest, stream snippb.SnipService_WatchEventsServer) error {
+	var events <-chan snip.Event
+	if req.GetLastEventId() > 0 {
+		events = snip.SubscribeSince(stream.Context(), req.GetLastEventId())
+	} else {
+		var err error
+		events, err = srv.store.Subscribe(stream.Context())
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	for e := range events {
+		if err := stream.Send(eventToProto(e)); err != nil {
+			return err
+		}
+	}
+	return stream.Context().Err()
+}
+
+// GetAttachment streams an attachment, metadata in the first chunk followed by its data
+func (srv *GRPCServer) GetAttachment(req *snippb.GetAttachmentRequest, stream snippb.SnipService_GetAttachmentServer) error {
+	a, err := srv.store.GetAttachment(req.GetUuid())
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	chunk := &snippb.AttachmentChunk{Attachment: attachmentToProto(a)}
+	for offset := 0; offset == 0 || offset < len(a.Data); offset += attachmentChunkSize {
+		end := offset + attachmentChunkSize
+		if end > len(a.Data) {
+			end = len(a.Data)
+		}
+		chunk.Data = a.Data[offset:end]
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		chunk = &snippb.AttachmentChunk{}
+	}
+	return nil
+}
+
+// AddAttachment stores an attachment received as a stream, metadata in the first chunk
+func (srv *GRPCServer) AddAttachment(stream snippb.SnipService_AddAttachmentServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	meta := first.GetAttachment()
+	if meta.GetSnipUuid() == "" || meta.GetName() == "" {
+		return status.Error(codes.InvalidArgument, "the first chunk must name the attachment and its snip uuid")
+	}
+
+	data := first.GetData()
+	for {
+		if srv.maxUpload > 0 && int64(len(data)) > srv.maxUpload {
+			return status.Errorf(codes.ResourceExhausted, "attachment larger than %d bytes", srv.maxUpload)
+		}
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		data = append(data, chunk.GetData()...)
+	}
+
+	s, err := srv.store.Get(meta.GetSnipUuid())
+	if err != nil {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	a, err := srv.store.Attach(s.UUID, meta.GetName(), data)
+	if errors.Is(err, snip.ErrLocked) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if errors.Is(err, snip.ErrAttachmentTooLarge) || errors.Is(err, snip.ErrQuotaExceeded) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	log.Debug().Str("uuid", a.UUID.String()).Int("size", a.Size).Msg("attachment added")
+	return stream.SendAndClose(attachmentToProto(a))
+}
+
+// snipToProto converts a snip to its protocol buffer message
+func snipToProto(s snip.Snip) *snippb.Snip {
+	msg := &snippb.Snip{
+		Uuid:      s.UUID.String(),
+		Name:      s.Name,
+		Data:      s.Data,
+		Timestamp: timestamppb.New(s.Timestamp),
+		Meta:      s.Meta,
+		Tags:      s.Tags,
+	}
+	for _, a := range s.Attachments {
+		msg.Attachments = append(msg.Attachments, attachmentToProto(a))
+	}
+	return msg
+}
+
+// attachmentToProto converts attachment metadata to its protocol buffer message
+func attachmentToProto(a snip.Attachment) *snippb.Attachment {
+	return &snippb.Attachment{
+		Uuid:      a.UUID.String(),
+		SnipUuid:  a.SnipUUID.String(),
+		Name:      a.Name,
+		Size:      int64(a.Size),
+		Timestamp: timestamppb.New(a.Timestamp),
+	}
+}
+
+// matchToProto converts a search match to its protocol buffer message
+func matchToProto(m snip.SearchMatch) *snippb.SearchMatch {
+	msg := &snippb.SearchMatch{
+		Uuid:  m.UUID.String(),
+		Name:  m.Name,
+		Score: m.Score,
+		Words: int32(m.Words),
+	}
+	for _, c := range m.SearchCounts {
+		msg.Counts = append(msg.Counts, &snippb.SearchCount{Term: c.Term, Stem: c.Stem, Count: int32(c.Count)})
+	}
+	for _, c := range m.Context {
+		msg.Context = append(msg.Context, &snippb.TermContext{
+			Before:      c.Before,
+			BeforeStart: int32(c.BeforeStart),
+			Term:        c.Term,
+			After:       c.After,
+			AfterEnd:    int32(c.AfterEnd),
+		})
+	}
+	return msg
+}
+
+// eventToProto converts a change event to its protocol buffer message
+func eventToProto(e snip.Event) *snippb.Event {
+	msg := &snippb.Event{
+		Id:        e.ID,
+		Type:      string(e.Type),
+		Uuid:      e.UUID.String(),
+		Timestamp: timestamppb.New(e.Timestamp),
+	}
+	if e.Attachment != uuid.Nil {
+		msg.Attachment = e.Attachment.String()
+	}
+	return msg
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/ryanfrishkorn/snip/snippb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
+	"testing"
+)
+
+// grpcClient starts a grpc server on an in-memory listener and returns a client connected to it
+func grpcClient(t *testing.T, token string) snippb.SnipServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	s := NewGRPC(token, Limits{})
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return snippb.NewSnipServiceClient(conn)
+}
+
+func TestGRPCSearch(t *testing.T) {
+	client := grpcClient(t, "")
+	stream, err := client.Search(context.Background(), &snippb.SearchRequest{Terms: []string{"fox"}})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var matches []*snippb.SearchMatch
+	for {
+		m, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		matches = append(matches, m)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+	if matches[0].Uuid != testSnip.UUID.String() {
+		t.Errorf("expected uuid %s, got %s", testSnip.UUID, matches[0].Uuid)
+	}
+	if len(matches[0].Context) == 0 {
+		t.Errorf("expected match context")
+	}
+}
+
+func TestGRPCAttachment(t *testing.T) {
+	client := grpcClient(t, "")
+	ctx := context.Background()
+
+	// larger than one chunk so that both directions stream more than one message
+	data := bytes.Repeat([]byte("attachment data "), attachmentChunkSize/8)
+
+	upload, err := client.AddAttachment(ctx)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	err = upload.Send(&snippb.AttachmentChunk{
+		Attachment: &snippb.Attachment{SnipUuid: testSnip.UUID.String(), Name: "grpc.txt"},
+		Data:       data[:10],
+	})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err = upload.Send(&snippb.AttachmentChunk{Data: data[10:]}); err != nil {
+		t.Fatalf("%v", err)
+	}
+	added, err := upload.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if added.Size != int64(len(data)) {
+		t.Errorf("expected size %d, got %d", len(data), added.Size)
+	}
+
+	download, err := client.GetAttachment(ctx, &snippb.GetAttachmentRequest{Uuid: added.Uuid})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var received []byte
+	chunks := 0
+	for {
+		chunk, err := download.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if chunks == 0 && chunk.Attachment.GetName() != "grpc.txt" {
+			t.Errorf("expected name grpc.txt in first chunk, got %q", chunk.Attachment.GetName())
+		}
+		chunks++
+		received = append(received, chunk.Data...)
+	}
+	if chunks < 2 {
+		t.Errorf("expected multiple chunks, got %d", chunks)
+	}
+	if !bytes.Equal(received, data) {
+		t.Errorf("received data does not match the data sent")
+	}
+}
+
+func TestGRPCToken(t *testing.T) {
+	client := grpcClient(t, "secret")
+
+	_, err := client.ListSnips(context.Background(), &snippb.ListSnipsRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected unauthenticated without token, got %v", err)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	resp, err := client.ListSnips(ctx, &snippb.ListSnipsRequest{})
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if len(resp.Snips) != 1 || resp.Snips[0].Data != "" {
+		t.Errorf("expected one snip without data, got %v", resp.Snips)
+	}
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limits bounds what each client of the server may send
+type Limits struct {
+	// RequestsPerMinute is the number of requests each identity may make a minute, or each address when no token was accepted.
+	// Up to a minute of requests may be made at once. Zero is no limit.
+	RequestsPerMinute int
+	// MaxUpload is the largest request body accepted in bytes, such as an attachment, zero for no limit
+	MaxUpload int64
+}
+
+// rateLimiter keeps a bucket of requests for each client, refilled at a steady rate up to a minute of requests
+type rateLimiter struct {
+	mu        sync.Mutex
+	perMinute int
+	buckets   map[string]*rateBucket
+	// swept is when full buckets were last discarded, so that clients seen once are not kept forever
+	swept time.Time
+}
+
+// rateBucket holds the requests a client may still make
+type rateBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{perMinute: perMinute, buckets: make(map[string]*rateBucket)}
+}
+
+// take uses a request from the bucket of key, returning zero when it is allowed or how long until it would be
+func (l *rateLimiter) take(key string, now time.Time) time.Duration {
+	return l.use(key, now, true)
+}
+
+// peek returns zero when the bucket of key has a request left or how long until it would, without using it
+func (l *rateLimiter) peek(key string, now time.Time) time.Duration {
+	return l.use(key, now, false)
+}
+
+// use checks the bucket of key for a request, taking it when it is allowed and consume is set
+func (l *rateLimiter) use(key string, now time.Time, consume bool) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	capacity := float64(l.perMinute)
+	perSecond := capacity / 60
+	refill := func(b *rateBucket) {
+		b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+		b.updated = now
+	}
+	if now.Sub(l.swept) > time.Minute {
+		for k, b := range l.buckets {
+			if refill(b); b.tokens == capacity {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateBucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+	refill(b)
+	if b.tokens >= 1 {
+		if consume {
+			b.tokens--
+		}
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+}
+
+// Limit wraps a handler so that clients are refused with 429 Too Many Requests beyond the rate of limits, and 413 Request Entity Too Large for bodies beyond its maximum upload.
+// Clients are told apart by the identity of the token accepted by RequireToken, which Limit belongs inside, and otherwise by their address,
+// since a token that was not checked could be changed with every request.
+func Limit(next http.Handler, limits Limits) http.Handler {
+	var limiter *rateLimiter
+	if limits.RequestsPerMinute > 0 {
+		limiter = newRateLimiter(limits.RequestsPerMinute)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limiter != nil {
+			key := "addr:" + remoteHost(r.RemoteAddr)
+			if id, ok := r.Context().Value(identityKey{}).(Identity); ok {
+				key = fmt.Sprintf("identity:%t:%s", id.Admin, id.Owner)
+			}
+			if wait := limiter.take(key, time.Now()); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+		}
+		if limits.MaxUpload > 0 {
+			if r.ContentLength > limits.MaxUpload {
+				writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limits.MaxUpload)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LimitFailedAuth wraps a handler requiring tokens so that an address is refused with 429 Too Many Requests once its requests were refused
+// with 401 Unauthorized beyond the rate of limits, so that tokens cannot be guessed quickly. It belongs outside RequireTokens,
+// while Limit inside it counts the requests of each identity.
+func LimitFailedAuth(next http.Handler, limits Limits) http.Handler {
+	if limits.RequestsPerMinute <= 0 {
+		return next
+	}
+	limiter := newRateLimiter(limits.RequestsPerMinute)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := remoteHost(r.RemoteAddr)
+		if wait := limiter.peek(key, time.Now()); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "too many failed attempts")
+			return
+		}
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		if recorder.status() == http.StatusUnauthorized {
+			limiter.take(key, time.Now())
+		}
+	})
+}
+
+// bodyStatus returns the http status for an error reading a request body
+func bodyStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// remoteHost returns the host of a remote address, or the address itself when it has no port
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// limitInterceptors returns grpc interceptors refusing calls beyond the rate of limits with ResourceExhausted.
+// Calls are counted by their token when authenticated reports that it was checked before, and otherwise by their address.
+func limitInterceptors(limits Limits, authenticated bool) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	limiter := newRateLimiter(limits.RequestsPerMinute)
+	check := func(ctx context.Context) error {
+		key := "addr:"
+		if p, ok := peer.FromContext(ctx); ok {
+			key += remoteHost(p.Addr.String())
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get("authorization"); authenticated && len(values) > 0 {
+			key = "token:" + values[0]
+		}
+		if wait := limiter.take(key, time.Now()); wait > 0 {
+			return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %s", wait.Round(time.Second))
+		}
+		return nil
+	}
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := check(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := check(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+	return unary, stream
+}
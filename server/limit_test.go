@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(60)
+	now := time.Now()
+	for n := 0; n < 60; n++ {
+		if wait := limiter.take("a", now); wait != 0 {
+			t.Fatalf("expected request %d to be allowed, got wait %s", n, wait)
+		}
+	}
+	if wait := limiter.take("a", now); wait != time.Second {
+		t.Errorf("expected a wait of 1s once the burst is used, got %s", wait)
+	}
+	if wait := limiter.take("b", now); wait != 0 {
+		t.Errorf("expected another client to be allowed, got wait %s", wait)
+	}
+	if wait := limiter.take("a", now.Add(time.Second)); wait != 0 {
+		t.Errorf("expected a request to be allowed after a second, got wait %s", wait)
+	}
+
+	// full buckets are forgotten
+	limiter.take("c", now.Add(3*time.Minute))
+	if _, ok := limiter.buckets["b"]; ok || len(limiter.buckets) != 1 {
+		t.Errorf("expected only the bucket of c to remain, got %v", limiter.buckets)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	tokens := map[string]Identity{"one": {Admin: true}, "two": {Owner: "two"}, "three": {Owner: "three", Admin: true}}
+	handler := RequireTokens(Limit(New(), Limits{RequestsPerMinute: 2, MaxUpload: 16}), tokens)
+	send := func(method string, target string, token string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for n := 0; n < 2; n++ {
+		if w := send(http.MethodGet, "/snips", "one", ""); w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+	w := send(http.MethodGet, "/snips", "one", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Errorf("expected status %d retrying after 30 seconds, got %d after %q", http.StatusTooManyRequests, w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// tokens that were not checked do not get a bucket of their own
+	unchecked := Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), Limits{RequestsPerMinute: 2})
+	for n := 0; n < 3; n++ {
+		r := httptest.NewRequest(http.MethodGet, "/share/x", nil)
+		r.Header.Set("Authorization", "Bearer random"+strconv.Itoa(n))
+		w = httptest.NewRecorder()
+		unchecked.ServeHTTP(w, r)
+	}
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d changing the token with each request, got %d", http.StatusTooManyRequests, w.Code)
+	}
+
+	target := "/snips/" + testSnip.UUID.String() + "/attachments?name=large.txt"
+	if w = send(http.MethodPost, target, "two", strings.Repeat("x", 17)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+	// bodies without a length are cut off while reading
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(strings.Repeat("x", 17)))
+	r.ContentLength = -1
+	r.Header.Set("Authorization", "Bearer three")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestLimitFailedAuth(t *testing.T) {
+	tokens := map[string]Identity{"one": {Admin: true}}
+	handler := LimitFailedAuth(RequireTokens(Limit(New(), Limits{RequestsPerMinute: 2}), tokens), Limits{RequestsPerMinute: 2})
+	send := func(token string, addr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/snips", nil)
+		r.RemoteAddr = addr
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	// successful requests do not count against the address
+	for n := 0; n < 2; n++ {
+		if w := send("one", "192.0.2.1:1000"); w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+	}
+	for n := 0; n < 2; n++ {
+		if w := send("guess"+strconv.Itoa(n), "192.0.2.1:1000"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	}
+	w := send("guess2", "192.0.2.1:1001")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Errorf("expected status %d retrying after 30 seconds, got %d after %q", http.StatusTooManyRequests, w.Code, w.Header().Get("Retry-After"))
+	}
+	// the right token is refused from the same address as well, but not from another
+	if w = send("one", "192.0.2.1:1002"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w = send("guess3", "192.0.2.2:1000"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d from another address, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
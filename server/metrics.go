@@ -0,0 +1,227 @@
+package server
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricBuckets are the upper bounds in seconds of the latency histograms, the defaults of the Prometheus client libraries
+var metricBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// metricMethods are the request methods counted by name, so that clients cannot add label values without bound
+var metricMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// histogram counts observations in seconds by the bucket of metricBuckets they fall in
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *histogram) observe(seconds float64) {
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(metricBuckets))
+	}
+	for idx, le := range metricBuckets {
+		if seconds <= le {
+			h.buckets[idx]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// write writes the histogram in the Prometheus text format with the labels of the series followed by a comma, such as route="/search",
+func (h *histogram) write(w io.Writer, name string, labels string) {
+	var cumulative uint64
+	for idx, le := range metricBuckets {
+		if h.buckets != nil {
+			cumulative += h.buckets[idx]
+		}
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", name, labels, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.count)
+	// the sum and count have the labels of the series alone
+	if labels = strings.TrimSuffix(labels, ","); labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// requestKey identifies the series a request is counted in
+type requestKey struct {
+	route  string
+	method string
+	code   int
+}
+
+// metrics counts the requests served since the server started
+type metrics struct {
+	mu        sync.Mutex
+	started   time.Time
+	requests  map[requestKey]uint64
+	durations map[string]*histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		started:   time.Now(),
+		requests:  make(map[requestKey]uint64),
+		durations: make(map[string]*histogram),
+	}
+}
+
+// observe counts a request to the mux pattern route, and its duration unless it is a stream
+func (m *metrics) observe(route string, method string, code int, elapsed time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	if !metricMethods[method] {
+		method = "other"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{route, method, code}]++
+	// event streams last as long as the client listens
+	if route == "/events" {
+		return
+	}
+	h, ok := m.durations[route]
+	if !ok {
+		h = &histogram{}
+		m.durations[route] = h
+	}
+	h.observe(elapsed.Seconds())
+}
+
+// write writes the request metrics in the Prometheus text format, ordered so that scrapes are comparable
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP snip_start_time_seconds Start time of the server since the unix epoch in seconds.\n")
+	fmt.Fprintf(w, "# TYPE snip_start_time_seconds gauge\n")
+	fmt.Fprintf(w, "snip_start_time_seconds %d\n", m.started.Unix())
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].code < keys[j].code
+	})
+	fmt.Fprintf(w, "# HELP snip_http_requests_total Requests served by the api, by route, method, and status code.\n")
+	fmt.Fprintf(w, "# TYPE snip_http_requests_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(w, "snip_http_requests_total{route=%q,method=%q,code=\"%d\"} %d\n", k.route, k.method, k.code, m.requests[k])
+	}
+
+	routes := make([]string, 0, len(m.durations))
+	for route := range m.durations {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	fmt.Fprintf(w, "# HELP snip_http_request_duration_seconds Time taken to serve api requests by route, where /search is the latency of searches.\n")
+	fmt.Fprintf(w, "# TYPE snip_http_request_duration_seconds histogram\n")
+	for _, route := range routes {
+		m.durations[route].write(w, "snip_http_request_duration_seconds", fmt.Sprintf("route=%q,", route))
+	}
+}
+
+// handleMetrics exports request, latency, and database metrics in the Prometheus text format
+func (srv *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	database.Mu.Lock()
+	stats, err := snip.GetStats()
+	database.Mu.Unlock()
+	if err != nil {
+		log.Debug().Err(err).Msg("error reading database stats")
+		writeError(w, http.StatusInternalServerError, "database stats could not be read")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	srv.metrics.write(w)
+	gauges := []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{"snip_snips", "Snips in the database.", int64(stats.Snips)},
+		{"snip_attachments", "Attachments in the database.", int64(stats.Attachments)},
+		{"snip_tags", "Distinct tags in the database.", int64(stats.Tags)},
+		{"snip_database_size_bytes", "Size of the database file in bytes.", stats.Bytes},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", g.name, g.help, g.name, g.name, g.value)
+	}
+}
+
+// statusRecorder remembers the status code written through it, and flushes streams as the writer it wraps does
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.code == 0 {
+		s.code = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(data []byte) (int, error) {
+	if s.code == 0 {
+		s.code = http.StatusOK
+	}
+	return s.ResponseWriter.Write(data)
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// status returns the code written, which is 200 when the handler wrote nothing
+func (s *statusRecorder) status() int {
+	if s.code == 0 {
+		return http.StatusOK
+	}
+	return s.code
+}
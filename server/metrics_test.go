@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	srv := New()
+	get := func(target string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+	get("/snips")
+	get("/snips")
+	get("/search?q=fox")
+	get("/unknown")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("PROPFIND", "/snips", nil))
+
+	w = get("/metrics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		`snip_http_requests_total{route="/snips",method="GET",code="200"} 2`,
+		`snip_http_requests_total{route="/snips",method="other",code="405"} 1`,
+		`snip_http_requests_total{route="unmatched",method="GET",code="404"} 1`,
+		`snip_http_request_duration_seconds_bucket{route="/search",le="+Inf"} 1`,
+		`snip_http_request_duration_seconds_count{route="/search"} 1`,
+		"# TYPE snip_http_request_duration_seconds histogram",
+		"snip_snips 1",
+		"# TYPE snip_database_size_bytes gauge",
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected metrics to contain %q, got:\n%s", line, body)
+		}
+	}
+
+	// the metrics describe every owner's snips
+	handler := RequireTokens(srv, map[string]Identity{"user-token": {Owner: "someone"}})
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Authorization", "Bearer user-token")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for a user token, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var h histogram
+	h.observe(0.001)
+	h.observe(0.3)
+	h.observe(60)
+	var b strings.Builder
+	h.write(&b, "test_seconds", "")
+	for _, line := range []string{
+		`test_seconds_bucket{le="0.005"} 1`,
+		`test_seconds_bucket{le="0.25"} 1`,
+		`test_seconds_bucket{le="0.5"} 2`,
+		`test_seconds_bucket{le="10"} 2`,
+		`test_seconds_bucket{le="+Inf"} 3`,
+		`test_seconds_sum 60.301`,
+		`test_seconds_count 3`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("expected histogram to contain %q, got:\n%s", line, b.String())
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ryanfrishkorn/snip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOwners(t *testing.T) {
+	handler := RequireTokens(New(), map[string]Identity{
+		"alice-token": {Owner: "alice"},
+		"bob-token":   {Owner: "bob"},
+		"admin-token": {Admin: true},
+	})
+	send := func(method string, target string, token string, body []byte, v any) int {
+		t.Helper()
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if v != nil && w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+				t.Fatalf("error decoding response: %v", err)
+			}
+		}
+		return w.Code
+	}
+
+	// the owner is that of the token, whatever the client sends
+	s := snip.New()
+	s.Data = "alice keeps a private zeppelin"
+	s.Owner = "bob"
+	body, _ := json.Marshal(s)
+	if code := send(http.MethodPost, "/snips", "alice-token", body, nil); code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, code)
+	}
+	defer snip.Remove(s.UUID)
+
+	tests := []struct {
+		token   string
+		snips   int
+		matches int
+		get     int
+		admin   int
+	}{
+		{"alice-token", 1, 1, http.StatusOK, http.StatusForbidden},
+		{"bob-token", 0, 0, http.StatusNotFound, http.StatusForbidden},
+		// the admin also sees the test snip, which has no owner
+		{"admin-token", 2, 1, http.StatusOK, http.StatusOK},
+	}
+	for _, tt := range tests {
+		var snips []snip.Snip
+		send(http.MethodGet, "/snips", tt.token, nil, &snips)
+		if len(snips) != tt.snips {
+			t.Errorf("expected %s to list %d snips, got %d", tt.token, tt.snips, len(snips))
+		}
+		var matches []snip.SearchMatch
+		send(http.MethodGet, "/search?q=zeppelin", tt.token, nil, &matches)
+		if len(matches) != tt.matches {
+			t.Errorf("expected %s to find %d matches, got %d", tt.token, tt.matches, len(matches))
+		}
+		var got snip.Snip
+		if code := send(http.MethodGet, "/snips/"+s.UUID.String(), tt.token, nil, &got); code != tt.get {
+			t.Errorf("expected status %d getting the snip with %s, got %d", tt.get, tt.token, code)
+		} else if code == http.StatusOK && got.Owner != "alice" {
+			t.Errorf("expected owner alice, got %q", got.Owner)
+		}
+		if code := send(http.MethodGet, "/feed.xml", tt.token, nil, nil); code != tt.admin {
+			t.Errorf("expected status %d reading the feed with %s, got %d", tt.admin, tt.token, code)
+		}
+	}
+
+	if code := send(http.MethodDelete, "/snips/"+s.UUID.String(), "bob-token", nil, nil); code != http.StatusNotFound {
+		t.Errorf("expected status %d removing the snip of another owner, got %d", http.StatusNotFound, code)
+	}
+}
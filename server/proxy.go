@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Proxy describes how the server is reached through a reverse proxy such as nginx or Caddy, and by browser apps on other origins
+type Proxy struct {
+	// BasePath is the path prefix the server is reached under, such as /snip, or empty for the root
+	BasePath string
+	// TrustForwarded takes the client address, scheme, and host from the X-Forwarded-For, X-Forwarded-Proto, and X-Forwarded-Host headers.
+	// Only a proxy that sets them should be able to reach the server, since clients could otherwise claim any address.
+	TrustForwarded bool
+	// Origins are the browser origins such as https://notes.example.com allowed to make cross origin requests, where * allows any
+	Origins []string
+}
+
+// requestContext is what Proxy learned about how a request reached the server
+type requestContext struct {
+	scheme   string
+	basePath string
+}
+
+type requestContextKey struct{}
+
+// Wrap returns next behind the proxy. It should be the outermost handler so that client addresses are known to Limit and preflight requests need no token.
+func (p Proxy) Wrap(next http.Handler) http.Handler {
+	basePath := strings.TrimSuffix(p.BasePath, "/")
+	if basePath != "" && !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := requestContext{scheme: "http", basePath: basePath}
+		if r.TLS != nil {
+			rc.scheme = "https"
+		}
+		// the request is changed on a copy, as http.StripPrefix does
+		r = r.WithContext(context.WithValue(r.Context(), requestContextKey{}, &rc))
+		if p.TrustForwarded {
+			// the proxy nearest the server appends the address it was reached from
+			if client := lastValue(r.Header.Get("X-Forwarded-For")); client != "" {
+				r.RemoteAddr = net.JoinHostPort(client, "0")
+			}
+			if proto := lastValue(r.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+				rc.scheme = proto
+			}
+			if host := lastValue(r.Header.Get("X-Forwarded-Host")); host != "" {
+				r.Host = host
+			}
+		}
+
+		if origin := r.Header.Get("Origin"); origin != "" && p.allowsOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Expose-Headers", "Link, Retry-After")
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Last-Event-ID")
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if basePath != "" {
+			path := strings.TrimPrefix(r.URL.Path, basePath)
+			if len(path) == len(r.URL.Path) || (path != "" && !strings.HasPrefix(path, "/")) {
+				http.NotFound(w, r)
+				return
+			}
+			if path == "" {
+				path = "/"
+			}
+			u := *r.URL
+			u.Path = path
+			u.RawPath = ""
+			r.URL = &u
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowsOrigin reports whether cross origin requests from origin are allowed
+func (p Proxy) allowsOrigin(origin string) bool {
+	for _, allowed := range p.Origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastValue returns the last of the comma separated values of a header
+func lastValue(header string) string {
+	values := strings.Split(header, ",")
+	return strings.TrimSpace(values[len(values)-1])
+}
+
+// externalURL returns the absolute url at which the client reached path, including the scheme, host, and base path seen by the client
+func externalURL(r *http.Request, path string) string {
+	rc, ok := r.Context().Value(requestContextKey{}).(*requestContext)
+	if !ok {
+		rc = &requestContext{scheme: "http"}
+		if r.TLS != nil {
+			rc.scheme = "https"
+		}
+	}
+	return rc.scheme + "://" + r.Host + rc.basePath + path
+}
+
+// externalPath returns path with the base path seen by the client
+func externalPath(r *http.Request, path string) string {
+	if rc, ok := r.Context().Value(requestContextKey{}).(*requestContext); ok {
+		return rc.basePath + path
+	}
+	return path
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"github.com/ryanfrishkorn/snip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProxy(t *testing.T) {
+	extra := snip.New()
+	extra.Data = "proxied"
+	if err := snip.InsertSnip(extra); err != nil {
+		t.Fatal(err)
+	}
+	defer snip.Remove(extra.UUID)
+
+	proxy := Proxy{BasePath: "/snip/", TrustForwarded: true, Origins: []string{"https://app.example.com"}}
+	handler := proxy.Wrap(RequireToken(New(), "secret"))
+	send := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/snip/snips?limit=1", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := send(r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d beneath the base path, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.HasPrefix(w.Header().Get("Link"), "</snip/snips?") {
+		t.Errorf("expected the next page to be linked beneath the base path, got %s", w.Header().Get("Link"))
+	}
+	for _, target := range []string{"/snips", "/snipsnips"} {
+		if w = send(httptest.NewRequest(http.MethodGet, target, nil)); w.Code != http.StatusNotFound {
+			t.Errorf("expected status %d for %s outside the base path, got %d", http.StatusNotFound, target, w.Code)
+		}
+	}
+
+	// the feed links to itself as the client reached it through the proxy
+	r = httptest.NewRequest(http.MethodGet, "/snip/feed.xml", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "notes.example.com")
+	w = send(r)
+	if !strings.Contains(w.Body.String(), "https://notes.example.com/snip/feed.xml") {
+		t.Errorf("expected the forwarded address in the feed, got %s", w.Body.String())
+	}
+
+	// preflight requests are answered without a token for allowed origins only
+	r = httptest.NewRequest(http.MethodOptions, "/snip/snips", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.Header.Set("Access-Control-Request-Method", "GET")
+	w = send(r)
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("expected an allowed preflight, got %d with %v", w.Code, w.Header())
+	}
+	if !strings.Contains(w.Header().Get("Access-Control-Allow-Headers"), "Authorization") {
+		t.Errorf("expected the Authorization header to be allowed, got %s", w.Header().Get("Access-Control-Allow-Headers"))
+	}
+	r.Header.Set("Origin", "https://other.example.com")
+	w = send(r)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected no cross origin access for another origin, got %v", w.Header())
+	}
+}
+
+func TestProxyForwardedFor(t *testing.T) {
+	tests := []struct {
+		trust    bool
+		expected []int
+	}{
+		// every client shares the address of the proxy unless forwarded addresses are trusted
+		{false, []int{http.StatusOK, http.StatusTooManyRequests}},
+		{true, []int{http.StatusOK, http.StatusOK}},
+	}
+	for _, tt := range tests {
+		ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		handler := Proxy{TrustForwarded: tt.trust}.Wrap(Limit(ok, Limits{RequestsPerMinute: 1}))
+		for idx, client := range []string{"203.0.113.1", "203.0.113.2"} {
+			r := httptest.NewRequest(http.MethodGet, "/snips", nil)
+			r.RemoteAddr = "127.0.0.1:40000"
+			// only the address appended by the proxy is used, since the client may send its own header
+			r.Header.Set("X-Forwarded-For", "198.51.100.9, "+client)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.expected[idx] {
+				t.Errorf("expected status %d for %s trusting forwarded addresses %v, got %d", tt.expected[idx], client, tt.trust, w.Code)
+			}
+		}
+	}
+}
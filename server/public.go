@@ -0,0 +1,205 @@
+package server
+
+import (
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"github.com/ryanfrishkorn/snip/export"
+	"html/template"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// publicTemplates lay out the index and snip pages of public mode
+var publicTemplates = template.Must(template.New("public").Funcs(template.FuncMap{"image": snip.IsImageAttachment}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+pre { background: #f4f4f4; padding: 0.5em; overflow-x: auto; }
+a { color: #2a5db0; }
+.meta { color: #777; font-size: small; }
+.thumbnail { display: block; margin: 0.5em 0; }
+</style>
+</head>
+<body>
+{{end}}
+{{define "index"}}{{template "head" "snips"}}<h1>snips</h1>
+<ul>
+{{range .}}<li><a href="{{.UUID}}">{{.Name}}</a> <span class="meta">{{.Timestamp.Format "2006-01-02"}}</span></li>
+{{end}}</ul>
+</body>
+</html>
+{{end}}
+{{define "snip"}}{{template "head" .Snip.Name}}<p><a href="./">all snips</a></p>
+<h1>{{.Snip.Name}}</h1>
+<p class="meta">{{.Snip.Timestamp.Format "2006-01-02 15:04"}}{{range .Snip.Tags}} #{{.}}{{end}}</p>
+{{.Body}}
+{{if .Snip.Attachments}}<h2>Attachments</h2>
+<ul>
+{{range .Snip.Attachments}}<li>{{if image .Name}}<a class="thumbnail" href="{{$.Snip.UUID}}/{{.UUID}}"><img src="{{$.Snip.UUID}}/{{.UUID}}/thumbnail" alt="{{.Name}}" loading="lazy"></a>{{end}}<a href="{{$.Snip.UUID}}/{{.UUID}}">{{.Name}}</a> ({{.Size}} bytes)</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+{{end}}
+`))
+
+// PublicHandler serves the snips with any of Tags to anyone, without a token, as rendered html and json
+type PublicHandler struct {
+	Tags []string
+}
+
+// ServeHTTP implements http.Handler for paths of the form /public/[index.json], /public/<uuid>[.json], and /public/<uuid>/<attachment uuid>[/thumbnail]
+func (p PublicHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/public/")
+
+	database.Mu.Lock()
+	defer database.Mu.Unlock()
+
+	if path == "" || path == "index.json" {
+		snips, err := p.list()
+		if err != nil {
+			log.Debug().Err(err).Msg("error listing public snips")
+			writeError(w, http.StatusInternalServerError, "snips could not be listed")
+			return
+		}
+		if path == "index.json" {
+			writeJSON(w, http.StatusOK, snips)
+			return
+		}
+		p.render(w, "index", snips)
+		return
+	}
+
+	idStr, attachment, _ := strings.Cut(path, "/")
+	idStr, asJSON := strings.CutSuffix(idStr, ".json")
+	// snips that are not public are indistinguishable from those that do not exist
+	s, ok := p.get(idStr)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case attachment != "":
+		p.attachment(w, r, s, attachment)
+	case asJSON:
+		writeJSON(w, http.StatusOK, s)
+	default:
+		body, err := export.SnipMarkdown(s.UUID, s.Data)
+		if err != nil {
+			log.Debug().Err(err).Str("uuid", s.UUID.String()).Msg("error rendering public snip")
+			writeError(w, http.StatusInternalServerError, "snip could not be rendered")
+			return
+		}
+		p.render(w, "snip", struct {
+			Snip snip.Snip
+			Body template.HTML
+		}{s, body})
+	}
+}
+
+// list returns the metadata of the public snips, newest first
+func (p PublicHandler) list() ([]snip.Snip, error) {
+	seen := make(map[uuid.UUID]bool)
+	snips := []snip.Snip{}
+	for _, tag := range p.Tags {
+		tagged, _, err := snip.ListPage(snip.ListOptions{Tag: tag, Sort: "newest"})
+		if err != nil {
+			return snips, err
+		}
+		for _, s := range tagged {
+			if !seen[s.UUID] {
+				seen[s.UUID] = true
+				// the users of the server are not shown to anyone without a token
+				s.Owner = ""
+				snips = append(snips, s)
+			}
+		}
+	}
+	sort.SliceStable(snips, func(i, j int) bool {
+		return snips[i].Timestamp.After(snips[j].Timestamp)
+	})
+	return snips, nil
+}
+
+// get returns the snip with the full uuid idStr when it is public, with the metadata of its attachments
+func (p PublicHandler) get(idStr string) (snip.Snip, bool) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return snip.Snip{}, false
+	}
+	s, err := snip.GetSnipMeta(id.String())
+	if err != nil {
+		return s, false
+	}
+	if expired, err := snip.IsExpired(id); err != nil || expired {
+		return s, false
+	}
+	if err = s.LoadData(); err != nil {
+		log.Debug().Err(err).Str("uuid", id.String()).Msg("error reading public snip")
+		return s, false
+	}
+	s.Owner = ""
+	for _, tag := range p.Tags {
+		for _, t := range s.Tags {
+			if strings.EqualFold(t, tag) {
+				return s, true
+			}
+		}
+	}
+	return s, false
+}
+
+// attachment writes an attachment of a public snip as a download, or a thumbnail of it
+func (p PublicHandler) attachment(w http.ResponseWriter, r *http.Request, s snip.Snip, path string) {
+	id, sub, _ := strings.Cut(path, "/")
+	for _, meta := range s.Attachments {
+		if meta.UUID.String() != id {
+			continue
+		}
+		if sub == "thumbnail" {
+			w.Header().Set("Cache-Control", "public, max-age=86400")
+			writeThumbnail(w, r, meta.UUID, publicThumbnailSizes)
+			return
+		}
+		if sub != "" {
+			break
+		}
+		a, err := snip.GetAttachmentFromUUID(id)
+		if err != nil {
+			break
+		}
+		contentType := mime.TypeByExtension(filepath.Ext(a.Name))
+		if contentType == "" {
+			contentType = http.DetectContentType(a.Data)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(a.Data)))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+		w.Write(a.Data)
+		return
+	}
+	writeError(w, http.StatusNotFound, "not found")
+}
+
+// render writes the named public template as an html page
+func (p PublicHandler) render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := publicTemplates.ExecuteTemplate(w, name, data); err != nil {
+		log.Debug().Err(err).Str("template", name).Msg("error rendering public page")
+	}
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/ryanfrishkorn/snip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublic(t *testing.T) {
+	published := snip.New()
+	published.Name = "Published Notes"
+	published.Data = "# Heading\n\nsome *published* text"
+	published.Owner = "alice"
+	if err := snip.InsertSnip(published, snip.WithTags("published")); err != nil {
+		t.Fatalf("error inserting snip: %v", err)
+	}
+	defer snip.Remove(published.UUID)
+
+	expired := snip.New()
+	expired.Name = "Expired Notes"
+	if err := snip.InsertSnip(expired, snip.WithTags("published")); err != nil {
+		t.Fatalf("error inserting snip: %v", err)
+	}
+	defer snip.Remove(expired.UUID)
+	if err := snip.SetExpiry(expired.UUID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("error setting expiry: %v", err)
+	}
+
+	handler := PublicHandler{Tags: []string{"Published"}}
+	get := func(target string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	var snips []snip.Snip
+	w := get("/public/index.json")
+	if strings.Contains(w.Body.String(), "alice") {
+		t.Errorf("expected the owner left out, got %s", w.Body.String())
+	}
+	if err := json.NewDecoder(w.Body).Decode(&snips); err != nil {
+		t.Fatalf("error decoding index: %v", err)
+	}
+	if len(snips) != 1 || snips[0].UUID != published.UUID {
+		t.Errorf("expected the index to list only the published snip, got %v", snips)
+	}
+	if w = get("/public/"); !strings.Contains(w.Body.String(), "Published Notes") || strings.Contains(w.Body.String(), testSnip.Name) {
+		t.Errorf("expected the html index to list only the published snip, got %s", w.Body.String())
+	}
+
+	w = get("/public/" + published.UUID.String())
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<em>published</em>") {
+		t.Errorf("expected the rendered snip, got %d %s", w.Code, w.Body.String())
+	}
+	var got snip.Snip
+	w = get("/public/" + published.UUID.String() + ".json")
+	if strings.Contains(w.Body.String(), "Owner") {
+		t.Errorf("expected the owner left out, got %s", w.Body.String())
+	}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil || got.Data != published.Data {
+		t.Errorf("expected the snip as json, got %d %s", w.Code, w.Body.String())
+	}
+
+	// snips without the tag, expired snips, and short ids are not found
+	for _, target := range []string{
+		"/public/" + testSnip.UUID.String(),
+		"/public/" + testSnip.UUID.String() + ".json",
+		"/public/" + expired.UUID.String(),
+		"/public/" + published.UUID.String()[:8],
+	} {
+		if w = get(target); w.Code != http.StatusNotFound {
+			t.Errorf("expected status %d for %s, got %d", http.StatusNotFound, target, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/public/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
@@ -0,0 +1,537 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"github.com/ryanfrishkorn/snip/export"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventKeepAlive is how often an idle event stream is sent a comment, so that proxies and browsers do not close it
+var EventKeepAlive = 30 * time.Second
+
+// errNotFound hides snips of other owners as though they did not exist
+var errNotFound = errors.New("not found")
+
+// Server serves snip operations as a JSON api
+type Server struct {
+	mux     *http.ServeMux
+	store   snip.Store
+	metrics *metrics
+}
+
+// New returns a Server for the local database with all routes registered
+func New() *Server {
+	srv := &Server{
+		mux:     http.NewServeMux(),
+		store:   snip.LocalStore{},
+		metrics: newMetrics(),
+	}
+	srv.mux.HandleFunc("/attachments/", srv.handleAttachment)
+	srv.mux.HandleFunc("/snips", srv.handleSnips)
+	srv.mux.HandleFunc("/snips/", srv.handleSnip)
+	srv.mux.HandleFunc("/events", srv.handleEvents)
+	srv.mux.HandleFunc("/feed.xml", srv.handleFeed)
+	srv.mux.HandleFunc("/search", srv.handleSearch)
+	srv.mux.HandleFunc("/metrics", srv.handleMetrics)
+	return srv
+}
+
+// ServeHTTP implements http.Handler
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Str("method", r.Method).Str("path", r.URL.Path).Msg("request")
+	_, route := srv.mux.Handler(r)
+	recorder := &statusRecorder{ResponseWriter: w}
+	start := time.Now()
+	srv.mux.ServeHTTP(recorder, r)
+	srv.metrics.observe(route, r.Method, recorder.status(), time.Since(start))
+}
+
+// Identity is the user a token acts for
+type Identity struct {
+	// Owner is the user whose snips the token sees and who owns the snips it adds
+	Owner string
+	// Admin sees and changes every snip regardless of its owner
+	Admin bool
+}
+
+type identityKey struct{}
+
+// RequireToken wraps a handler so that requests must present token as a bearer credential, with an admin identity.
+// The token is also accepted as a basic auth password for /feed.xml, since feed readers rarely support bearer tokens.
+func RequireToken(next http.Handler, token string) http.Handler {
+	return RequireTokens(next, map[string]Identity{token: {Admin: true}})
+}
+
+// RequireTokens wraps a handler so that requests must present one of tokens as RequireToken does, acting for the identity of the token presented
+func RequireTokens(next http.Handler, tokens map[string]Identity) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		supplied := []byte(requestToken(r))
+		var id Identity
+		found := false
+		// every token is compared so that the time taken does not reveal which one nearly matched
+		for token, identity := range tokens {
+			if subtle.ConstantTimeCompare(supplied, []byte(token)) == 1 {
+				id, found = identity, true
+			}
+		}
+		if !found {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="snip"`)
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+	})
+}
+
+// requestIdentity returns the identity a request acts for, which is an admin when no tokens are required
+func requestIdentity(r *http.Request) Identity {
+	if id, ok := r.Context().Value(identityKey{}).(Identity); ok {
+		return id
+	}
+	return Identity{Admin: true}
+}
+
+// canSee reports whether the identity may see and change snips belonging to owner
+func (id Identity) canSee(owner string) bool {
+	return id.Admin || id.Owner == owner
+}
+
+// requireAdmin writes 403 Forbidden and returns false unless the request acts for an admin, for routes covering the snips of every owner
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !requestIdentity(r).Admin {
+		writeError(w, http.StatusForbidden, "an admin token is required")
+		return false
+	}
+	return true
+}
+
+// requestToken returns the token presented by a request as a bearer credential, or as a basic auth password for the feed only
+func requestToken(r *http.Request) string {
+	if _, password, ok := r.BasicAuth(); ok && r.URL.Path == "/feed.xml" {
+		return password
+	}
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// handleEvents streams change events to the client as server-sent events
+func (srv *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	// resume after the last event the client received when reconnecting
+	var events <-chan snip.Event
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		id, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		events = snip.SubscribeSince(r.Context(), id)
+	} else {
+		var err error
+		events, err = srv.store.Subscribe(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx buffers responses unless told otherwise, which would hold back events
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// browsers reconnect after the retry delay in milliseconds, resuming with Last-Event-ID
+	fmt.Fprintf(w, "retry: %d\n\n", (3 * time.Second).Milliseconds())
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(EventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				log.Debug().Err(err).Msg("error encoding event")
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// handleAttachment returns a single attachment including its data by full or partial uuid, or a thumbnail of it
+func (srv *Server) handleAttachment(w http.ResponseWriter, r *http.Request) {
+	id, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/attachments/"), "/")
+	switch sub {
+	case "":
+	case "thumbnail":
+		srv.handleThumbnail(w, r, id)
+		return
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	a, err := srv.store.GetAttachment(id)
+	if err == nil && !requestIdentity(r).Admin {
+		var s snip.Snip
+		if s, err = srv.store.Get(a.SnipUUID.String()); err == nil && !requestIdentity(r).canSee(s.Owner) {
+			err = errNotFound
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, a)
+}
+
+// handleFeed returns an Atom feed of the most recent snips
+func (srv *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	limit, err := intParam(r, "limit", 20)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	database.Mu.Lock()
+	err = export.Feed(&buf, "snips", externalURL(r, r.URL.Path), limit)
+	database.Mu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// listFields maps the fields that may be selected when listing snips to their json keys
+var listFields = map[string]string{
+	"uuid":      "UUID",
+	"name":      "Name",
+	"timestamp": "Timestamp",
+	"tags":      "Tags",
+	"data":      "Data",
+}
+
+// handleSnips lists a page of snips without their data, or inserts a new snip.
+// Listings may be sorted, filtered by tag and creation time, and limited to some fields, and the next page is linked in the Link header.
+func (srv *Server) handleSnips(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		opts, fields, err := listParams(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if id := requestIdentity(r); !id.Admin {
+			opts.Owner = id.Owner
+		}
+		snips, next, err := srv.store.ListPage(opts)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if next != "" {
+			query := r.URL.Query()
+			query.Set("cursor", next)
+			w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, externalPath(r, r.URL.Path), query.Encode()))
+		}
+		if fields == nil {
+			if snips == nil {
+				snips = []snip.Snip{}
+			}
+			writeJSON(w, http.StatusOK, snips)
+			return
+		}
+		writeJSON(w, http.StatusOK, selectFields(snips, fields))
+
+	case http.MethodPost:
+		var s snip.Snip
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			writeError(w, bodyStatus(err), err.Error())
+			return
+		}
+		if s.UUID == uuid.Nil {
+			writeError(w, http.StatusBadRequest, "snip uuid is required")
+			return
+		}
+		// admins may add snips for any owner
+		if id := requestIdentity(r); !id.Admin || s.Owner == "" {
+			s.Owner = id.Owner
+		}
+		if err := srv.store.Insert(s); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, s)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleSnip returns or removes a single snip by full or partial uuid, or adds an attachment to it
+func (srv *Server) handleSnip(w http.ResponseWriter, r *http.Request) {
+	id, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/snips/"), "/")
+
+	s, err := srv.store.Get(id)
+	if err == nil && !requestIdentity(r).canSee(s.Owner) {
+		err = errNotFound
+	}
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	switch {
+	case sub == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s)
+
+	case sub == "" && r.Method == http.MethodDelete:
+		if err := srv.store.Remove(s.UUID); err != nil {
+			writeError(w, errorStatus(err), err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case sub == "attachments" && r.Method == http.MethodPost:
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			writeError(w, http.StatusBadRequest, "attachment name is required")
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, bodyStatus(err), err.Error())
+			return
+		}
+		a, err := srv.store.Attach(s.UUID, name, data)
+		if err != nil {
+			writeError(w, errorStatus(err), err.Error())
+			return
+		}
+		// the client already has the data
+		a.Data = nil
+		writeJSON(w, http.StatusCreated, a)
+
+	case sub == "" || sub == "attachments":
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleSearch returns scored index search results with context
+func (srv *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	terms := r.URL.Query()["q"]
+	if len(terms) == 0 {
+		writeError(w, http.StatusBadRequest, "at least one search term q is required")
+		return
+	}
+	limit, err := intParam(r, "limit", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	adjacent, err := intParam(r, "context", 6)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id := requestIdentity(r)
+	if !id.Admin {
+		// the limit is applied once the matches of other owners are left out
+		matches, err := srv.store.Search(terms, 0, adjacent)
+		if err == nil {
+			matches, err = ownedMatches(id.Owner, matches, limit)
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, matches)
+		return
+	}
+	matches, err := srv.store.Search(terms, limit, adjacent)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if matches == nil {
+		matches = []snip.SearchMatch{}
+	}
+	writeJSON(w, http.StatusOK, matches)
+}
+
+// ownedMatches returns up to limit of matches belonging to owner in their order, zero limit returns all of them
+func ownedMatches(owner string, matches []snip.SearchMatch, limit int) ([]snip.SearchMatch, error) {
+	ids := make([]uuid.UUID, len(matches))
+	for idx, m := range matches {
+		ids[idx] = m.UUID
+	}
+	database.Mu.Lock()
+	owned, err := snip.OwnedBy(owner, ids)
+	database.Mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	selected := []snip.SearchMatch{}
+	for _, m := range matches {
+		if owned[m.UUID] && (limit == 0 || len(selected) < limit) {
+			selected = append(selected, m)
+		}
+	}
+	return selected, nil
+}
+
+// listParams reads the options of a snip listing and the json keys of the fields selected, which are nil when all are wanted
+func listParams(r *http.Request) (snip.ListOptions, []string, error) {
+	query := r.URL.Query()
+	opts := snip.ListOptions{
+		Sort:   query.Get("sort"),
+		Tag:    query.Get("tag"),
+		Cursor: query.Get("cursor"),
+	}
+	var err error
+	if opts.Limit, err = intParam(r, "limit", 0); err != nil {
+		return opts, nil, err
+	}
+	if opts.Since, err = timeParam(r, "since"); err != nil {
+		return opts, nil, err
+	}
+	if opts.Until, err = timeParam(r, "until"); err != nil {
+		return opts, nil, err
+	}
+
+	var fields []string
+	if value := query.Get("fields"); value != "" {
+		for _, field := range strings.Split(value, ",") {
+			key, ok := listFields[strings.TrimSpace(field)]
+			if !ok {
+				return opts, nil, fmt.Errorf("unknown field %q (uuid|name|timestamp|tags|data)", field)
+			}
+			if key == "Data" {
+				opts.Data = true
+			}
+			fields = append(fields, key)
+		}
+	}
+	return opts, fields, opts.Validate()
+}
+
+// selectFields returns the snips as json objects with only the given keys
+func selectFields(snips []snip.Snip, fields []string) []map[string]any {
+	selected := make([]map[string]any, 0, len(snips))
+	for _, s := range snips {
+		all := map[string]any{"UUID": s.UUID, "Name": s.Name, "Timestamp": s.Timestamp, "Tags": s.Tags, "Data": s.Data}
+		object := make(map[string]any)
+		for _, key := range fields {
+			object[key] = all[key]
+		}
+		selected = append(selected, object)
+	}
+	return selected
+}
+
+// timeParam parses a query parameter as an RFC 3339 time or a date in UTC, returning the zero time if absent
+func timeParam(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("%s must be an RFC 3339 time or a date", name)
+	}
+	return t, nil
+}
+
+// intParam parses an integer query parameter, returning fallback if absent
+func intParam(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// errorStatus returns the http status for an error of a store operation
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, snip.ErrLocked):
+		return http.StatusConflict
+	case errors.Is(err, snip.ErrAttachmentTooLarge), errors.Is(err, snip.ErrQuotaExceeded):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError writes a json error message with the given status
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeJSON writes v as the json response body with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debug().Err(err).Msg("error encoding response")
+	}
+}
@@ -0,0 +1,266 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSnip snip.Snip
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "snip-server-test")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating temporary directory: %v", err)
+		os.Exit(1)
+	}
+
+	database.Conn, err = sqlite3.Open(filepath.Join(dir, "test.sqlite3"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening sqlite test database: %v", err)
+		os.Exit(1)
+	}
+	err = snip.CreateNewDatabase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating test database schema: %v", err)
+		os.Exit(1)
+	}
+
+	testSnip = snip.New()
+	testSnip.Name = "Server Test"
+	testSnip.Data = "the quick brown fox jumps over the lazy dog"
+	if err = snip.InsertSnip(testSnip); err != nil {
+		fmt.Fprintf(os.Stderr, "error inserting test snip: %v", err)
+		os.Exit(1)
+	}
+	if err = testSnip.Index(); err != nil {
+		fmt.Fprintf(os.Stderr, "error indexing test snip: %v", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	database.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// request performs a request against a new Server and decodes the json response into v
+func request(t *testing.T, method string, target string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	New().ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	if v != nil {
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatalf("error decoding response: %v", err)
+		}
+	}
+	return w
+}
+
+func TestListSnips(t *testing.T) {
+	var snips []snip.Snip
+	w := request(t, http.MethodGet, "/snips", &snips)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if len(snips) != 1 {
+		t.Fatalf("expected 1 snip, got %d", len(snips))
+	}
+	if snips[0].UUID != testSnip.UUID || snips[0].Name != testSnip.Name {
+		t.Errorf("expected %s %s, got %s %s", testSnip.UUID, testSnip.Name, snips[0].UUID, snips[0].Name)
+	}
+	if snips[0].Data != "" {
+		t.Errorf("expected listing to omit data, got %s", snips[0].Data)
+	}
+}
+
+func TestListSnipsPages(t *testing.T) {
+	for _, name := range []string{"Page One", "Page Two", "Page Three"} {
+		s := snip.New()
+		s.Name = name
+		s.Data = "page data"
+		if err := snip.InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer snip.Remove(s.UUID)
+	}
+
+	var names []string
+	target := "/snips?limit=2&fields=name,data"
+	for target != "" {
+		var page []map[string]any
+		w := request(t, http.MethodGet, target, &page)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		for _, object := range page {
+			if len(object) != 2 || object["Data"] == nil {
+				t.Errorf("expected only name and data, got %v", object)
+			}
+			names = append(names, fmt.Sprint(object["Name"]))
+		}
+		target = ""
+		if link := w.Header().Get("Link"); link != "" {
+			target = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+		}
+	}
+	expected := []string{testSnip.Name, "Page One", "Page Two", "Page Three"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	for _, target := range []string{"/snips?sort=size", "/snips?fields=secret", "/snips?cursor=bogus", "/snips?since=yesterday"} {
+		if w := request(t, http.MethodGet, target, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d for %s, got %d", http.StatusBadRequest, target, w.Code)
+		}
+	}
+}
+
+func TestGetSnip(t *testing.T) {
+	var s snip.Snip
+	w := request(t, http.MethodGet, "/snips/"+snip.ShortenUUID(testSnip.UUID)[0], &s)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if s.Data != testSnip.Data {
+		t.Errorf("expected data %s, got %s", testSnip.Data, s.Data)
+	}
+
+	w = request(t, http.MethodGet, "/snips/ffffffff", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	var matches []snip.SearchMatch
+	w := request(t, http.MethodGet, "/search?q=jumping&q=fox&context=1", &matches)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+	if matches[0].UUID != testSnip.UUID || matches[0].Words != 9 {
+		t.Errorf("expected match %s with 9 words, got %s with %d", testSnip.UUID, matches[0].UUID, matches[0].Words)
+	}
+	if len(matches[0].Context) != 2 || matches[0].Context[0].Term != "jumps" {
+		t.Errorf("expected context for jumps and fox, got %v", matches[0].Context)
+	}
+
+	w = request(t, http.MethodGet, "/search", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	handler := RequireToken(New(), "secret")
+
+	// feed readers authenticate with the token as a basic auth password
+	r := httptest.NewRequest(http.MethodGet, "/feed.xml", nil)
+	r.SetBasicAuth("reader", "secret")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/atom+xml") {
+		t.Errorf("expected atom content type, got %s", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(w.Body.String(), testSnip.UUID.String()) {
+		t.Errorf("expected feed to include test snip, got %s", w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/feed.xml", nil)
+	r.SetBasicAuth("reader", "wrong")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	// the rest of the api takes only bearer tokens
+	r = httptest.NewRequest(http.MethodGet, "/snips", nil)
+	r.SetBasicAuth("reader", "secret")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d for basic auth beyond the feed, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	pollInterval, keepAlive := snip.EventPollInterval, EventKeepAlive
+	snip.EventPollInterval, EventKeepAlive = 10*time.Millisecond, 20*time.Millisecond
+	defer func() { snip.EventPollInterval, EventKeepAlive = pollInterval, keepAlive }()
+
+	ts := httptest.NewServer(New())
+	defer ts.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("error requesting events: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected content type text/event-stream, got %q", ct)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	// wait for a line beginning with prefix, failing when the stream ends or stalls
+	expect := func(prefix string) string {
+		t.Helper()
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("stream ended waiting for %q", prefix)
+				}
+				if strings.HasPrefix(line, prefix) {
+					return line
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("timed out waiting for %q", prefix)
+			}
+		}
+	}
+	expect("retry: ")
+	// idle streams are kept alive with comments
+	expect(": keepalive")
+
+	s := snip.New()
+	s.Data = "event stream test"
+	database.Mu.Lock()
+	err = snip.InsertSnip(s)
+	database.Mu.Unlock()
+	if err != nil {
+		t.Fatalf("error inserting snip: %v", err)
+	}
+	defer snip.Remove(s.UUID)
+	expect("event: create")
+	if data := expect("data: "); !strings.Contains(data, s.UUID.String()) {
+		t.Errorf("expected the event to name snip %s, got %s", s.UUID, data)
+	}
+}
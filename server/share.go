@@ -0,0 +1,145 @@
+package server
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"github.com/ryanfrishkorn/snip/export"
+	"html/template"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sharePage renders a shared snip for a browser
+var sharePage = template.Must(template.New("share").Funcs(template.FuncMap{"image": snip.IsImageAttachment}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Snip.Name}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; }
+pre { background: #f4f4f4; padding: 0.5em; overflow-x: auto; }
+footer { color: #777; font-size: small; }
+.thumbnail { display: block; margin: 0.5em 0; }
+</style>
+</head>
+<body>
+<h1>{{.Snip.Name}}</h1>
+{{.Body}}
+{{if .Snip.Attachments}}<h2>Attachments</h2>
+<ul>
+{{range .Snip.Attachments}}<li>{{if image .Name}}<a class="thumbnail" href="{{$.Token}}/{{.UUID}}"><img src="{{$.Token}}/{{.UUID}}/thumbnail" alt="{{.Name}}" loading="lazy"></a>{{end}}<a href="{{$.Token}}/{{.UUID}}">{{.Name}}</a> ({{.Size}} bytes)</li>
+{{end}}</ul>
+{{end}}<footer>shared until {{.Share.Expires.Format "2006-01-02 15:04 MST"}}</footer>
+</body>
+</html>
+`))
+
+// ShareHandler serves shared snips and their attachments to anyone holding an unexpired share token
+type ShareHandler struct{}
+
+// sharedContent is what a share request reads from the database, so that the response is written after the database is released
+type sharedContent struct {
+	Snip  snip.Snip
+	Share snip.Share
+	Token string
+	// Body is the data of the snip rendered as html
+	Body template.HTML
+
+	attachment snip.Attachment
+}
+
+// ServeHTTP implements http.Handler for paths of the form /share/<token>[/<attachment uuid>[/thumbnail]]
+func (ShareHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token, path, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/share/"), "/")
+	attachment, sub, _ := strings.Cut(path, "/")
+
+	database.Mu.Lock()
+	c, found := readShare(token, attachment, sub)
+	var err error
+	if found && attachment == "" {
+		c.Body, err = export.SnipMarkdown(c.Snip.UUID, c.Snip.Data)
+	}
+	if found && sub == "thumbnail" {
+		// thumbnails are made when first requested, so they are written while the database is held
+		w.Header().Set("Cache-Control", "no-store")
+		writeThumbnail(w, r, c.attachment.UUID, publicThumbnailSizes)
+	}
+	database.Mu.Unlock()
+
+	switch {
+	case !found:
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		log.Debug().Err(err).Str("uuid", c.Snip.UUID.String()).Msg("error rendering shared snip")
+		http.Error(w, "snip could not be rendered", http.StatusInternalServerError)
+		return
+	case sub == "thumbnail":
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	if attachment == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err = sharePage.Execute(w, c); err != nil {
+			log.Debug().Err(err).Msg("error rendering share page")
+		}
+		return
+	}
+
+	a := c.attachment
+	contentType := mime.TypeByExtension(filepath.Ext(a.Name))
+	if contentType == "" {
+		contentType = http.DetectContentType(a.Data)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(a.Data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Write(a.Data)
+}
+
+// readShare returns the snip shared by token, with the data of its attachment when one is requested, or false when there is none.
+// The caller holds database.Mu.
+func readShare(token string, attachment string, sub string) (sharedContent, bool) {
+	// expired and unknown tokens are indistinguishable to the visitor
+	sh, err := snip.GetShare(token)
+	if err != nil {
+		log.Debug().Err(err).Msg("share request refused")
+		return sharedContent{}, false
+	}
+	s, err := snip.GetFromUUID(sh.UUID.String())
+	if err != nil {
+		return sharedContent{}, false
+	}
+	c := sharedContent{Snip: s, Share: sh, Token: token}
+	if attachment == "" {
+		return c, true
+	}
+
+	// only attachments of the shared snip are reachable
+	for _, meta := range s.Attachments {
+		if meta.UUID.String() != attachment {
+			continue
+		}
+		switch sub {
+		case "thumbnail":
+			c.attachment = meta
+			return c, true
+		case "":
+			c.attachment, err = snip.GetAttachmentFromUUID(attachment)
+			return c, err == nil
+		}
+		return c, false
+	}
+	return c, false
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"github.com/ryanfrishkorn/snip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareHandler(t *testing.T) {
+	sh, err := snip.CreateShare(testSnip.UUID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snip.RevokeShare(sh.Token)
+
+	w := httptest.NewRecorder()
+	ShareHandler{}.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/share/"+sh.Token, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), testSnip.Data) {
+		t.Errorf("expected page to contain snip data, got %s", w.Body.String())
+	}
+
+	// the snip is rendered as the public pages render it
+	md := snip.New()
+	md.Name = "shared markdown"
+	md.Data = "# Shared heading\n\nsome *emphasis*\n"
+	if err = snip.InsertSnip(md); err != nil {
+		t.Fatal(err)
+	}
+	defer snip.Remove(md.UUID)
+	mdShare, err := snip.CreateShare(md.UUID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snip.RevokeShare(mdShare.Token)
+	w = httptest.NewRecorder()
+	ShareHandler{}.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/share/"+mdShare.Token, nil))
+	if !strings.Contains(w.Body.String(), "<h1>Shared heading</h1>") || !strings.Contains(w.Body.String(), "<em>emphasis</em>") {
+		t.Errorf("expected the snip rendered from markdown, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	ShareHandler{}.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/share/"+sh.Token+"x", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for unknown token, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/database"
+	"net/http"
+	"strconv"
+)
+
+// publicThumbnailSizes are the only sizes made for the share and public pages, so that requests without a token
+// cannot fill the database with a thumbnail of every size
+var publicThumbnailSizes = []int{128, snip.ThumbnailSize, 512}
+
+// handleThumbnail returns a thumbnail of an image attachment by full uuid, no larger than the size query parameter in pixels
+func (srv *Server) handleThumbnail(w http.ResponseWriter, r *http.Request, idStr string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "thumbnails are found by full attachment uuid")
+		return
+	}
+
+	database.Mu.Lock()
+	defer database.Mu.Unlock()
+	a, err := snip.GetAttachmentMetadata(id)
+	if err == nil && !requestIdentity(r).Admin {
+		var owned map[uuid.UUID]bool
+		if owned, err = snip.OwnedBy(requestIdentity(r).Owner, []uuid.UUID{a.SnipUUID}); err == nil && !owned[a.SnipUUID] {
+			err = errNotFound
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	// the response depends on the token presented, so shared caches must not keep it
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	writeThumbnail(w, r, id, nil)
+}
+
+// writeThumbnail writes a thumbnail of attachment id no larger than the size query parameter, or snip.ThumbnailSize when absent.
+// The size must be one of sizes, or any up to snip.MaxThumbnailSize when sizes is nil.
+// Thumbnails never change, so a client holding the current one is answered with 304 Not Modified. The caller holds database.Mu.
+func writeThumbnail(w http.ResponseWriter, r *http.Request, id uuid.UUID, sizes []int) {
+	size, err := intParam(r, "size", snip.ThumbnailSize)
+	if err != nil || size < 1 || size > snip.MaxThumbnailSize {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("size must be from 1 to %d", snip.MaxThumbnailSize))
+		return
+	}
+	if sizes != nil {
+		allowed := false
+		for _, s := range sizes {
+			allowed = allowed || s == size
+		}
+		if !allowed {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("size must be one of %v", sizes))
+			return
+		}
+	}
+	etag := fmt.Sprintf(`"%s-%d"`, id, size)
+	if r.Header.Get("If-None-Match") == etag {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	t, err := snip.GetThumbnail(id, size)
+	switch {
+	case errors.Is(err, snip.ErrNotImage):
+		writeError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	case errors.Is(err, database.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	case err != nil:
+		log.Debug().Err(err).Str("uuid", id.String()).Msg("error making thumbnail")
+		writeError(w, http.StatusInternalServerError, "thumbnail could not be made")
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", t.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(t.Data)))
+	w.Write(t.Data)
+}
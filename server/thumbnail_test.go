@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bytes"
+	"github.com/ryanfrishkorn/snip"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	s := snip.New()
+	s.Name = "Screenshots"
+	if err := snip.InsertSnip(s, snip.WithTags("published")); err != nil {
+		t.Fatalf("error inserting snip: %v", err)
+	}
+	defer snip.Remove(s.UUID)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1200, 800))); err != nil {
+		t.Fatal(err)
+	}
+	img, err := s.AddAttachment("error.png", buf.Bytes())
+	if err != nil {
+		t.Fatalf("error adding attachment: %v", err)
+	}
+	text, err := s.AddAttachment("error.txt", []byte("segmentation fault"))
+	if err != nil {
+		t.Fatalf("error adding attachment: %v", err)
+	}
+
+	w := request(t, http.MethodGet, "/attachments/"+img.UUID.String()+"/thumbnail?size=120", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("expected a jpeg thumbnail, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	config, _, err := image.DecodeConfig(w.Body)
+	if err != nil || config.Width != 120 || config.Height != 80 {
+		t.Errorf("expected a thumbnail of 120x80, got %dx%d %v", config.Width, config.Height, err)
+	}
+
+	// clients holding the thumbnail are not sent it again
+	r := httptest.NewRequest(http.MethodGet, "/attachments/"+img.UUID.String()+"/thumbnail?size=120", nil)
+	r.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	New().ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+
+	for target, status := range map[string]int{
+		"/attachments/" + text.UUID.String() + "/thumbnail":          http.StatusUnsupportedMediaType,
+		"/attachments/" + img.UUID.String() + "/thumbnail?size=5000": http.StatusBadRequest,
+		"/attachments/" + img.UUID.String()[:8] + "/thumbnail":       http.StatusNotFound,
+		"/attachments/" + testSnip.UUID.String() + "/thumbnail":      http.StatusNotFound,
+		"/attachments/" + img.UUID.String() + "/preview":             http.StatusNotFound,
+	} {
+		if w = request(t, http.MethodGet, target, nil); w.Code != status {
+			t.Errorf("expected status %d for %s, got %d", status, target, w.Code)
+		}
+	}
+
+	// the public page shows thumbnails of image attachments only
+	handler := PublicHandler{Tags: []string{"published"}}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/"+s.UUID.String(), nil))
+	page := w.Body.String()
+	if !strings.Contains(page, `<img src="`+s.UUID.String()+`/`+img.UUID.String()+`/thumbnail"`) || strings.Contains(page, text.UUID.String()+"/thumbnail") {
+		t.Errorf("expected a thumbnail of the image only, got %s", page)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/"+s.UUID.String()+"/"+img.UUID.String()+"/thumbnail", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "public, max-age=86400" {
+		t.Errorf("expected a cacheable thumbnail, got %d %s", w.Code, w.Header().Get("Cache-Control"))
+	}
+	// pages without a token only make thumbnails of a few sizes
+	for size, status := range map[string]int{"512": http.StatusOK, "120": http.StatusBadRequest} {
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/"+s.UUID.String()+"/"+img.UUID.String()+"/thumbnail?size="+size, nil))
+		if w.Code != status {
+			t.Errorf("expected status %d for size %s, got %d", status, size, w.Code)
+		}
+	}
+}
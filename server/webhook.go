@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/config"
+	"github.com/ryanfrishkorn/snip/database"
+	"net/http"
+	"time"
+)
+
+// webhookAttempts is the number of deliveries attempted before a notification is dropped
+const webhookAttempts = 3
+
+// WebhookPayload is the json body posted to webhooks
+type WebhookPayload struct {
+	Event snip.Event
+	// Snip contains metadata of the snip, and is absent when it no longer exists
+	Snip *snip.Snip `json:",omitempty"`
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body using secret, as sent in the X-Snip-Signature header
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DispatchWebhooks posts a signed payload to each webhook for every change until ctx is done
+func DispatchWebhooks(ctx context.Context, webhooks []config.Webhook) error {
+	if len(webhooks) == 0 {
+		return nil
+	}
+	events, err := snip.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	// each webhook receives deliveries in order from its own queue
+	client := &http.Client{Timeout: 10 * time.Second}
+	queues := make([]chan WebhookPayload, len(webhooks))
+	for idx, webhook := range webhooks {
+		queues[idx] = make(chan WebhookPayload, 256)
+		go func(webhook config.Webhook, queue chan WebhookPayload) {
+			for payload := range queue {
+				deliverWebhook(ctx, client, webhook, payload)
+			}
+		}(webhook, queues[idx])
+	}
+
+	go func() {
+		defer func() {
+			for _, queue := range queues {
+				close(queue)
+			}
+		}()
+		for e := range events {
+			payload := WebhookPayload{Event: e}
+			database.Mu.Lock()
+			s, err := snip.GetFromUUID(e.UUID.String())
+			database.Mu.Unlock()
+			if err == nil {
+				s.Data = ""
+				for idx := range s.Attachments {
+					s.Attachments[idx].Data = nil
+				}
+				payload.Snip = &s
+			}
+
+			for idx, webhook := range webhooks {
+				if !webhookWants(webhook, e.Type) {
+					continue
+				}
+				select {
+				case queues[idx] <- payload:
+				default:
+					log.Debug().Str("url", webhook.URL).Msg("webhook queue full, dropping event")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// webhookWants determines if a webhook subscribes to an event type
+func webhookWants(webhook config.Webhook, eventType snip.EventType) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+	for _, e := range webhook.Events {
+		if e == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverWebhook posts the payload, retrying with backoff on failure
+func deliverWebhook(ctx context.Context, client *http.Client, webhook config.Webhook, payload WebhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Debug().Err(err).Msg("error encoding webhook payload")
+		return
+	}
+
+	backoff := time.Second
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err = postWebhook(ctx, client, webhook, payload.Event, body)
+		if err == nil {
+			return
+		}
+		log.Debug().Err(err).Str("url", webhook.URL).Int("attempt", attempt).Msg("webhook delivery failed")
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// postWebhook sends one signed delivery
+func postWebhook(ctx context.Context, client *http.Client, webhook config.Webhook, e snip.Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Snip-Event", string(e.Type))
+	req.Header.Set("X-Snip-Delivery", fmt.Sprintf("%d", e.ID))
+	if webhook.Secret != "" {
+		req.Header.Set("X-Snip-Signature", Sign(webhook.Secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ryanfrishkorn/snip"
+	"github.com/ryanfrishkorn/snip/config"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDispatchWebhooks(t *testing.T) {
+	interval := snip.EventPollInterval
+	snip.EventPollInterval = 10 * time.Millisecond
+	defer func() { snip.EventPollInterval = interval }()
+
+	type delivery struct {
+		signature string
+		payload   WebhookPayload
+		body      []byte
+	}
+	deliveries := make(chan delivery, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("error decoding payload: %v", err)
+		}
+		deliveries <- delivery{signature: r.Header.Get("X-Snip-Signature"), payload: payload, body: body}
+	}))
+	defer receiver.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webhooks := []config.Webhook{{URL: receiver.URL, Secret: "shared secret", Events: []string{"create"}}}
+	if err := DispatchWebhooks(ctx, webhooks); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	s := snip.New()
+	s.Name = "Webhook Test"
+	s.Data = "webhook data"
+	database.Mu.Lock()
+	err := snip.InsertSnip(s)
+	database.Mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		database.Mu.Lock()
+		snip.Remove(s.UUID)
+		database.Mu.Unlock()
+	}()
+
+	select {
+	case d := <-deliveries:
+		if d.signature != Sign("shared secret", d.body) {
+			t.Errorf("expected valid signature, got %s", d.signature)
+		}
+		if d.payload.Event.Type != snip.EventCreate || d.payload.Snip == nil || d.payload.Snip.Name != s.Name {
+			t.Errorf("expected create payload for %s, got %+v", s.Name, d.payload)
+		}
+		if d.payload.Snip.Data != "" {
+			t.Errorf("expected payload to omit data")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for webhook delivery")
+	}
+}
@@ -0,0 +1,148 @@
+package snip
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"time"
+)
+
+// Share grants read access to a snip and its attachments to anyone holding Token until Expires
+type Share struct {
+	Token   string
+	UUID    uuid.UUID
+	Expires time.Time
+}
+
+// Expired determines if the share may no longer be used
+func (sh Share) Expired() bool {
+	return !time.Now().Before(sh.Expires)
+}
+
+// CreateShare registers a new random share token for a snip that is valid for ttl
+func CreateShare(id uuid.UUID, ttl time.Duration) (Share, error) {
+	if ttl <= 0 {
+		return Share{}, fmt.Errorf("share duration must be positive")
+	}
+	secret := make([]byte, 18)
+	if _, err := rand.Read(secret); err != nil {
+		return Share{}, err
+	}
+	sh := Share{
+		Token:   base64.RawURLEncoding.EncodeToString(secret),
+		UUID:    id,
+		Expires: time.Now().Add(ttl),
+	}
+
+	stmt, err := database.Conn.Prepare(`INSERT INTO snip_share (token, uuid, expires) VALUES (?, ?, ?)`)
+	if err != nil {
+		return sh, err
+	}
+	defer stmt.Close()
+
+	err = stmt.Exec(sh.Token, sh.UUID.String(), sh.Expires.Format(time.RFC3339Nano))
+	return sh, err
+}
+
+// GetShare returns the unexpired share with the given token
+func GetShare(token string) (Share, error) {
+	sh := Share{Token: token}
+
+	stmt, err := database.Conn.Prepare(`SELECT uuid, expires FROM snip_share WHERE token = ?`, token)
+	if err != nil {
+		return sh, err
+	}
+	defer stmt.Close()
+
+	hasRow, err := stmt.Step()
+	if err != nil {
+		return sh, err
+	}
+	if !hasRow {
+		return sh, fmt.Errorf("share not found")
+	}
+	var idStr, expires string
+	err = stmt.Scan(&idStr, &expires)
+	if err != nil {
+		return sh, err
+	}
+	sh.UUID, err = uuid.Parse(idStr)
+	if err != nil {
+		return sh, err
+	}
+	sh.Expires, err = time.Parse(time.RFC3339Nano, expires)
+	if err != nil {
+		return sh, err
+	}
+	if sh.Expired() {
+		return sh, fmt.Errorf("share not found")
+	}
+	return sh, nil
+}
+
+// ListShares returns all unexpired shares, soonest to expire first
+func ListShares() ([]Share, error) {
+	var shares []Share
+
+	stmt, err := database.Conn.Prepare(`SELECT token, uuid, expires FROM snip_share ORDER BY expires`)
+	if err != nil {
+		return shares, err
+	}
+	defer stmt.Close()
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return shares, err
+		}
+		if !hasRow {
+			break
+		}
+		var sh Share
+		var idStr, expires string
+		err = stmt.Scan(&sh.Token, &idStr, &expires)
+		if err != nil {
+			return shares, err
+		}
+		sh.UUID, err = uuid.Parse(idStr)
+		if err != nil {
+			return shares, err
+		}
+		sh.Expires, err = time.Parse(time.RFC3339Nano, expires)
+		if err != nil {
+			return shares, err
+		}
+		if sh.Expired() {
+			continue
+		}
+		shares = append(shares, sh)
+	}
+	return shares, nil
+}
+
+// RevokeShare deletes a share so that its token may no longer be used
+func RevokeShare(token string) error {
+	if _, err := GetShare(token); err != nil {
+		return err
+	}
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_share WHERE token = ?`, token)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
+
+// RemoveShares deletes all shares of a snip
+func RemoveShares(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_share WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
@@ -0,0 +1,50 @@
+package snip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShare(t *testing.T) {
+	s := New()
+	s.Data = "share test"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	sh, err := CreateShare(s.UUID, time.Hour)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	got, err := GetShare(sh.Token)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if got.UUID != s.UUID {
+		t.Errorf("expected uuid %s, got %s", s.UUID, got.UUID)
+	}
+
+	expired, err := CreateShare(s.UUID, time.Nanosecond)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err = GetShare(expired.Token); err == nil {
+		t.Errorf("expected error getting expired share")
+	}
+	shares, err := ListShares()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(shares) != 1 || shares[0].Token != sh.Token {
+		t.Errorf("expected only the unexpired share listed, got %v", shares)
+	}
+
+	if err = RevokeShare(sh.Token); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if _, err = GetShare(sh.Token); err == nil {
+		t.Errorf("expected error getting revoked share")
+	}
+}
@@ -0,0 +1,219 @@
+package snip
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/bvinc/go-sqlite-lite/sqlite3"
+	"github.com/ryanfrishkorn/snip/database"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Snapshot is a named point-in-time copy of the database, which the database can be rolled back to
+type Snapshot struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	// Snips is the number of snips the database held
+	Snips int `json:"snips"`
+	// Size is the size of the copy in bytes
+	Size int64 `json:"-"`
+}
+
+// ValidateSnapshotName returns an error unless name is made of letters, digits, and the punctuation - _ . and does not begin with .
+func ValidateSnapshotName(name string) error {
+	if name == "" {
+		return fmt.Errorf("snapshot name must not be empty")
+	}
+	if strings.HasPrefix(name, ".") {
+		return fmt.Errorf("snapshot name %q must not begin with .", name)
+	}
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune("-_.", c) {
+			return fmt.Errorf("snapshot name %q may contain only letters, digits, and - _ .", name)
+		}
+	}
+	return nil
+}
+
+// snapshotPaths returns the locations of the copy of snapshot name within dir and of the metadata describing it
+func snapshotPaths(dir string, name string) (data string, meta string) {
+	return filepath.Join(dir, name+".sqlite3"), filepath.Join(dir, name+".json")
+}
+
+// snapshotBlobDir returns the directory holding the blobs that the attachments of snapshot name within dir are kept in
+func snapshotBlobDir(dir string, name string) string {
+	return filepath.Join(dir, name+".blobs")
+}
+
+// CreateSnapshot copies the open database into dir as snapshot name using the sqlite backup api, failing if the snapshot exists.
+// The blobs its attachments are kept in are copied along with it, since they are deleted with the last attachment using them.
+func CreateSnapshot(dir string, name string, now time.Time) (Snapshot, error) {
+	s := Snapshot{Name: name, Created: now}
+	if err := ValidateSnapshotName(name); err != nil {
+		return s, err
+	}
+	dataPath, metaPath := snapshotPaths(dir, name)
+	if _, err := os.Stat(metaPath); err == nil {
+		return s, fmt.Errorf("snapshot %s already exists", name)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return s, err
+	}
+	// a copy left without metadata by an interrupted snapshot is replaced
+	if err := os.Remove(dataPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return s, err
+	}
+	blobDir := snapshotBlobDir(dir, name)
+	if err := os.RemoveAll(blobDir); err != nil {
+		return s, err
+	}
+	if err := database.QueryRow(`SELECT COUNT(*) FROM snip`, nil, &s.Snips); err != nil {
+		return s, err
+	}
+
+	dst, err := sqlite3.Open(dataPath)
+	if err != nil {
+		return s, err
+	}
+	err = copyDatabase(database.Conn, dst)
+	if err == nil {
+		err = copyReferencedBlobs(dst, blobDir)
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dataPath)
+		os.RemoveAll(blobDir)
+		return s, err
+	}
+
+	info, err := os.Stat(dataPath)
+	if err != nil {
+		return s, err
+	}
+	s.Size = info.Size()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return s, err
+	}
+	return s, os.WriteFile(metaPath, data, 0600)
+}
+
+// copyDatabase replaces the contents of the main database of dst with those of src in a single step, so that readers of either never see
+// a partial copy
+func copyDatabase(src *sqlite3.Conn, dst *sqlite3.Conn) error {
+	b, err := src.Backup("main", dst, "main")
+	if err != nil {
+		return err
+	}
+	err = b.Step(-1)
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	if closeErr := b.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// ListSnapshots returns the snapshots in dir, oldest first
+func ListSnapshots(dir string) ([]Snapshot, error) {
+	var snapshots []Snapshot
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return snapshots, nil
+	}
+	if err != nil {
+		return snapshots, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		s, err := GetSnapshot(dir, strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			return snapshots, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.Before(snapshots[j].Created)
+	})
+	return snapshots, nil
+}
+
+// GetSnapshot returns snapshot name in dir, or an error wrapping database.ErrNoRows if it does not exist
+func GetSnapshot(dir string, name string) (Snapshot, error) {
+	var s Snapshot
+	if err := ValidateSnapshotName(name); err != nil {
+		return s, err
+	}
+	dataPath, metaPath := snapshotPaths(dir, name)
+	data, err := os.ReadFile(metaPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, fmt.Errorf("snapshot %s does not exist: %w", name, database.ErrNoRows)
+	}
+	if err != nil {
+		return s, err
+	}
+	if err = json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", metaPath, err)
+	}
+	info, err := os.Stat(dataPath)
+	if err != nil {
+		return s, err
+	}
+	s.Size = info.Size()
+	return s, nil
+}
+
+// RollbackSnapshot replaces the contents of the open database with snapshot name in dir, which is kept so that it may be rolled back to again.
+// The blobs of the snapshot missing from BlobDir are put back first, so that its attachments have their data.
+func RollbackSnapshot(dir string, name string) (Snapshot, error) {
+	s, err := GetSnapshot(dir, name)
+	if err != nil {
+		return s, err
+	}
+	dataPath, _ := snapshotPaths(dir, name)
+	src, err := sqlite3.Open(dataPath, sqlite3.OPEN_READONLY)
+	if err != nil {
+		return s, err
+	}
+	defer src.Close()
+	sums, err := blobDigests(src)
+	if err != nil {
+		return s, err
+	}
+	if len(sums) > 0 {
+		if BlobDir == "" {
+			return s, fmt.Errorf("no blob directory is set to restore the data of the attachments of snapshot %s", name)
+		}
+		if err = copyBlobs(sums, snapshotBlobDir(dir, name), BlobDir); err != nil {
+			return s, err
+		}
+	}
+	return s, copyDatabase(src, database.Conn)
+}
+
+// RemoveSnapshot deletes snapshot name from dir, returning an error wrapping database.ErrNoRows if it does not exist
+func RemoveSnapshot(dir string, name string) error {
+	if _, err := GetSnapshot(dir, name); err != nil {
+		return err
+	}
+	dataPath, metaPath := snapshotPaths(dir, name)
+	if err := os.Remove(metaPath); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(snapshotBlobDir(dir, name)); err != nil {
+		return err
+	}
+	return os.Remove(dataPath)
+}
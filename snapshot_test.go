@@ -0,0 +1,106 @@
+package snip
+
+import (
+	"bytes"
+	"errors"
+	"github.com/ryanfrishkorn/snip/database"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	before, err := CreateSnapshot(dir, "before-import", time.Now())
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if _, err = CreateSnapshot(dir, "before-import", time.Now()); err == nil {
+		t.Errorf("expected error creating a snapshot that exists")
+	}
+
+	s := New()
+	s.Data = "added after the snapshot"
+	if err = InsertSnip(s, WithName("after")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = RollbackSnapshot(dir, "before-import"); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if _, err = GetFromUUID(s.UUID.String()); !errors.Is(err, database.ErrNoRows) {
+		Remove(s.UUID)
+		t.Errorf("expected the snip added after the snapshot gone, got %v", err)
+	}
+	var count int
+	if err = database.QueryRow(`SELECT COUNT(*) FROM snip`, nil, &count); err != nil || count != before.Snips {
+		t.Errorf("expected %d snips, got %d: %v", before.Snips, count, err)
+	}
+
+	snapshots, err := ListSnapshots(dir)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Name != "before-import" || snapshots[0].Size == 0 {
+		t.Errorf("expected the snapshot listed, got %+v", snapshots)
+	}
+	if err = RemoveSnapshot(dir, "before-import"); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if err = RemoveSnapshot(dir, "before-import"); !errors.Is(err, database.ErrNoRows) {
+		t.Errorf("expected ErrNoRows, got %v", err)
+	}
+	if err = ValidateSnapshotName("../escape"); err == nil {
+		t.Errorf("expected error for a name with a path")
+	}
+}
+
+func TestSnapshotBlobs(t *testing.T) {
+	BlobDir, StoreBlobs = t.TempDir(), true
+	defer func() {
+		BlobDir, StoreBlobs = "", false
+	}()
+	s := New()
+	s.Data = "a snip with a picture"
+	if err := InsertSnip(s, WithName("blob snapshot")); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	data := []byte("the pixels of a picture")
+	a, err := s.AddAttachment("pic.bin", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if _, err = CreateSnapshot(dir, "with-blob", time.Now()); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	// removing the last attachment using the blob deletes it
+	if err = RemoveAttachment(a.UUID); err != nil {
+		t.Fatal(err)
+	}
+	path, err := blobPath(Checksum(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected the blob deleted, got %v", err)
+	}
+
+	// rolling back brings back the attachment along with its data
+	if _, err = RollbackSnapshot(dir, "with-blob"); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	got, err := GetAttachmentFromUUID(a.UUID.String())
+	if err != nil || !bytes.Equal(got.Data, data) {
+		t.Errorf("expected the data of the attachment restored, got %q %v", got.Data, err)
+	}
+
+	if err = RemoveSnapshot(dir, "with-blob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(snapshotBlobDir(dir, "with-blob")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the blobs of the snapshot removed with it, got %v", err)
+	}
+}
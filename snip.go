@@ -1,6 +1,9 @@
 package snip
 
 import (
+	"container/heap"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/bvinc/go-sqlite-lite/sqlite3"
 	"github.com/google/uuid"
@@ -8,8 +11,10 @@ import (
 	"github.com/rivo/uniseg"
 	"github.com/rs/zerolog/log"
 	"github.com/ryanfrishkorn/snip/database"
+	"io"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -32,6 +37,46 @@ type SearchScore struct {
 	UUID         uuid.UUID
 	Score        float64
 	SearchCounts []SearchCount
+	// Fields are the terms found in the name and tags, which raise the score by the weights of SearchBoost
+	Fields FieldMatches
+}
+
+// TermCount is the number of times a search term occurs in the data of a search result, and whether it is in its name or tags
+type TermCount struct {
+	Term  string
+	Count int
+	Name  bool
+	Tags  bool
+}
+
+// TermCounts returns the occurrences of each of the search terms in the result, in the order the terms were given.
+// The counts of every stem a wildcard term matched are added together.
+func (s SearchScore) TermCounts(terms []string) []TermCount {
+	var counts []TermCount
+	for _, term := range queryTerms(terms) {
+		c := TermCount{Term: term}
+		for _, sc := range s.SearchCounts {
+			if sc.Term == term {
+				c.Count += sc.Count
+			}
+		}
+		for _, t := range s.Fields.Name {
+			c.Name = c.Name || t == term
+		}
+		for _, t := range s.Fields.Tags {
+			c.Tags = c.Tags || t == term
+		}
+		counts = append(counts, c)
+	}
+	return counts
+}
+
+// SearchMatch is a scored search result along with the context surrounding its matching terms
+type SearchMatch struct {
+	SearchScore
+	Name    string
+	Words   int
+	Context []TermContext
 }
 
 type TermContext struct {
@@ -40,36 +85,73 @@ type TermContext struct {
 	Term        string
 	After       []string
 	AfterEnd    int
+	// Offset and Length locate the term in the data of the snip in bytes
+	Offset int
+	Length int
+	// Line and Column are where the term begins, counted from 1 with columns in bytes
+	Line   int
+	Column int
 }
 
 // Snip represents a snippet of data with additional metadata
 type Snip struct {
 	Attachments []Attachment
 	Data        string
+	Meta        map[string]string
 	Timestamp   time.Time
 	Name        string
-	UUID        uuid.UUID
+	// Owner is the user the snip belongs to on a server shared by several users, empty when it has none
+	Owner string `json:",omitempty"`
+	Tags  []string
+	UUID  uuid.UUID
 }
 
 // Attach adds files associated with a snip
 func (s *Snip) Attach(name string, data []byte) error {
+	_, err := s.AddAttachment(name, data)
+	return err
+}
+
+// AddAttachment attaches data to the snip and returns the stored attachment
+func (s *Snip) AddAttachment(name string, data []byte) (Attachment, error) {
+	if err := checkLocked(s.UUID); err != nil {
+		return Attachment{}, err
+	}
+	if err := CheckAttachmentSize(int64(len(data))); err != nil {
+		return Attachment{}, err
+	}
+
 	// build and insert attachment
 	a := NewAttachment()
 	a.Data = data
 	a.Name = name
 	a.SnipUUID = s.UUID
+	a.Size = len(data)
 
 	stmt, err := database.Conn.Prepare(`INSERT INTO snip_attachment (uuid, snip_uuid, timestamp, name, data, size) VALUES (?, ?, ?, ?, ?, ?)`)
 	if err != nil {
-		return err
+		return a, err
 	}
 	defer stmt.Close()
 
-	err = stmt.Exec(a.UUID.String(), a.SnipUUID.String(), a.Timestamp.Format(time.RFC3339Nano), a.Name, a.Data, len(a.Data))
+	stored := a.Data
+	if StoreBlobs {
+		stored = []byte{}
+	}
+	err = stmt.Exec(a.UUID.String(), a.SnipUUID.String(), a.Timestamp.Format(time.RFC3339Nano), a.Name, stored, len(a.Data))
 	if err != nil {
-		return err
+		return a, err
 	}
-	return nil
+	if StoreBlobs {
+		if err = storeBlob(a.UUID, a.Data); err != nil {
+			return a, err
+		}
+	}
+	err = SetAttachmentChecksum(a.UUID, Checksum(a.Data))
+	if err != nil {
+		return a, err
+	}
+	return a, recordEvent(EventAttach, s.UUID, a.UUID)
 }
 
 // CountWords returns an integer estimating the number of words in data
@@ -79,11 +161,7 @@ func (s *Snip) CountWords() int {
 
 // GatherContext returns the surrounding words matching the given term
 func (s *Snip) GatherContext(term string, adjacent int) ([]TermContext, error) {
-	var (
-		ctxAll []TermContext
-		words  []string
-		stems  []string
-	)
+	var ctxAll []TermContext
 	termStemmed, err := snowball.Stem(term, "english", true)
 	if err != nil {
 		return ctxAll, err
@@ -92,6 +170,16 @@ func (s *Snip) GatherContext(term string, adjacent int) ([]TermContext, error) {
 	if err != nil {
 		return ctxAll, err
 	}
+	return s.contextAt(positions, adjacent)
+}
+
+// contextAt returns the surrounding words at each of the comma separated word positions
+func (s *Snip) contextAt(positions string, adjacent int) ([]TermContext, error) {
+	var (
+		ctxAll []TermContext
+		words  []string
+		stems  []string
+	)
 	positionsSplit := strings.Split(positions, ",")
 	if len(positionsSplit) == 0 {
 		return ctxAll, fmt.Errorf("splitting positions producted zero elements")
@@ -113,7 +201,8 @@ func (s *Snip) GatherContext(term string, adjacent int) ([]TermContext, error) {
 	log.Debug().Any("positions", positionsSplitInt).Msg("positions")
 
 	// build split words and corresponding stems
-	words = SplitWords(s.Data)
+	spans := tokenSpans(IndexTokenizer, s.Data)
+	words = spanTokens(spans)
 	for _, word := range words {
 		// apparently we don't need to use DownCase here since the stemmer does so
 		stem, err := snowball.Stem(word, "english", true)
@@ -125,6 +214,10 @@ func (s *Snip) GatherContext(term string, adjacent int) ([]TermContext, error) {
 
 	// iterate through all positions
 	for _, position := range positionsSplitInt {
+		// positions written by another tokenizer may lie beyond the words, until the index is rebuilt
+		if position >= len(words) {
+			continue
+		}
 		var ctx TermContext
 		// establish either the amount of terms requested (adjacent) or the maximum we can satisfy
 		// attempt to find words before term
@@ -147,6 +240,11 @@ func (s *Snip) GatherContext(term string, adjacent int) ([]TermContext, error) {
 
 		// assign term from data source, not supplied search term
 		ctx.Term = words[position]
+		ctx.Offset = spans[position].offset
+		ctx.Length = len(ctx.Term)
+		lineStart := strings.LastIndexByte(s.Data[:ctx.Offset], '\n') + 1
+		ctx.Line = strings.Count(s.Data[:lineStart], "\n") + 1
+		ctx.Column = ctx.Offset - lineStart + 1
 
 		// attempt to find words after term
 		lastElement := position + adjacent
@@ -168,11 +266,7 @@ func (s *Snip) GatherContext(term string, adjacent int) ([]TermContext, error) {
 
 // GenerateName returns a clean string derived from processing the data field
 func (s *Snip) GenerateName(wordCount int) string {
-	data := FlattenString(s.Data)
-	// FIXME by allowing additional sensible characters such as `:`
-	pattern := regexp.MustCompile(`\w+`)
-	name := pattern.FindAllString(data, wordCount)
-	return strings.Join(name, " ")
+	return firstWords(s.Data, wordCount)
 }
 
 // StripPunctuation strips all commas, periods, etc. from a slice of strings
@@ -209,62 +303,71 @@ func DownCase(words []string) []string {
 	return output
 }
 
-// Index stems all data and writes it to a search table
+// IndexTerms is the analysis of snip data written to the search index
+type IndexTerms struct {
+	// Counts is the number of occurrences of each stemmed term
+	Counts map[string]int
+	// Positions are the word positions of each stemmed term
+	Positions map[string][]int
+}
+
+// Index stems all data, along with the text read from its attachments, and writes it to a search table
 func (s *Snip) Index() error {
+	// attachment text follows the data, beyond the words that search results show in context
+	text, err := attachmentText(s.UUID)
+	if err != nil {
+		return err
+	}
+	if text != "" {
+		text = s.Data + "\n\n" + text
+	} else {
+		text = s.Data
+	}
+	terms, err := AnalyzeTerms(text)
+	if err != nil {
+		return err
+	}
+	if err = s.WriteIndex(terms); err != nil {
+		return err
+	}
+	return s.IndexTrigrams()
+}
+
+// AnalyzeTerms stems the words of data split by IndexTokenizer and records where each term occurs.
+// It does not use the database, so data may be analyzed concurrently before it is written with WriteIndex.
+func AnalyzeTerms(data string) (IndexTerms, error) {
+	terms := IndexTerms{
+		Counts:    make(map[string]int),
+		Positions: make(map[string][]int),
+	}
 	// TODO: remove stop words from dict
-	dataCleaned := SplitWords(s.Data)
+	dataCleaned := IndexTokenizer.Tokens(data)
 	dataCleaned = DownCase(dataCleaned)
-	var dataStemmed []string
-	for _, word := range dataCleaned {
+	for idx, word := range dataCleaned {
 		stem, err := snowball.Stem(word, "english", true)
 		if err != nil {
-			return err
+			return terms, err
 		}
-		dataStemmed = append(dataStemmed, stem)
-	}
-	// confirm equal length of split words and stemmed words
-	if len(dataCleaned) != len(dataStemmed) {
-		return fmt.Errorf("expected len(dataCleaned) %d to equal len(dataStemmed) %d", len(dataCleaned), len(dataStemmed))
+		terms.Counts[stem]++
+		terms.Positions[stem] = append(terms.Positions[stem], idx)
 	}
+	return terms, nil
+}
 
-	// build terms and counts
-	terms := make(map[string]int, 0)
-	termsPositions := make(map[string][]int, 0)
-	for _, term := range dataStemmed {
-		// determine if term has already been processed
-		_, ok := terms[term]
-		if ok {
-			// skip
-			continue
-		}
-
-		// count occurrences
-		var count int
-		var positions []int
-		for idx, t := range dataStemmed {
-			if term == t {
-				count++
-				positions = append(positions, idx)
-			}
-		}
-		terms[term] = count
-		// log.Debug().Str("term", term).Int("count", count).Msg("indexing stem")
-		termsPositions[term] = positions
-		// log.Debug().Str("term", term).Any("positions", positions).Msg("indexing positions")
-	}
-	for term, count := range terms {
+// WriteIndex writes terms analyzed from the data of the snip to the search table
+func (s *Snip) WriteIndex(terms IndexTerms) error {
+	for term, count := range terms.Counts {
 		err := s.SetIndexTermCount(term, count)
 		if err != nil {
 			return err
 		}
 	}
-	for term, positions := range termsPositions {
+	for term, positions := range terms.Positions {
 		err := s.SetPositions(term, positions)
 		if err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
@@ -281,15 +384,11 @@ func (s *Snip) Rename(newName string) error {
 // GetPositions gets the position indicators for a given term
 func (s *Snip) GetPositions(term string) (string, error) {
 	var positions string
-	stmt, err := database.Conn.Prepare(`SELECT positions FROM snip_index WHERE term = ? AND uuid = ?`)
-	if err != nil {
-		return positions, err
-	}
-	err = stmt.Exec(term, s.UUID.String())
+	stmt, err := database.Prepare(`SELECT positions FROM snip_index WHERE term = ? AND uuid = ?`, term, s.UUID.String())
 	if err != nil {
 		return positions, err
 	}
-	defer stmt.Close()
+	defer database.Release(stmt)
 
 	hasRow, err := stmt.Step()
 	if err != nil {
@@ -306,6 +405,45 @@ func (s *Snip) GetPositions(term string) (string, error) {
 	return positions, nil
 }
 
+// termPositionsQuery selects the positions of terms in snips, taking json arrays of uuids and of stemmed terms as its parameters
+const termPositionsQuery = `SELECT uuid, term, positions FROM snip_index WHERE uuid IN (SELECT value FROM json_each(?)) AND term IN (SELECT value FROM json_each(?))`
+
+// getPositions returns the position indicators of each of the stemmed terms in each snip in ids, by uuid and then term
+func getPositions(ids []uuid.UUID, terms []string) (map[uuid.UUID]map[string]string, error) {
+	positions := make(map[uuid.UUID]map[string]string)
+
+	termList := make([]string, len(terms))
+	for idx, term := range terms {
+		termList[idx] = strconv.Quote(term)
+	}
+	stmt, err := database.Prepare(termPositionsQuery, uuidList(ids), "["+strings.Join(termList, ",")+"]")
+	if err != nil {
+		return positions, err
+	}
+	defer database.Release(stmt)
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return positions, err
+		}
+		if !hasRow {
+			break
+		}
+		var id uuid.UUID
+		var term, termPositions string
+		err = database.Scan(stmt, &id, &term, &termPositions)
+		if err != nil {
+			return positions, err
+		}
+		if positions[id] == nil {
+			positions[id] = make(map[string]string)
+		}
+		positions[id][term] = termPositions
+	}
+	return positions, nil
+}
+
 // SetPositions writes the word positions of a given term
 func (s *Snip) SetPositions(term string, positions []int) error {
 	// join positions into a string
@@ -314,17 +452,7 @@ func (s *Snip) SetPositions(term string, positions []int) error {
 		positionsStr = append(positionsStr, strconv.Itoa(p))
 	}
 	positionsJoined := strings.Join(positionsStr, ",")
-	stmt, err := database.Conn.Prepare(`UPDATE snip_index SET positions = ? WHERE term = ? AND uuid = ?`)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-
-	err = stmt.Exec(positionsJoined, term, s.UUID.String())
-	if err != nil {
-		return err
-	}
-	return nil
+	return database.Exec(`UPDATE snip_index SET positions = ? WHERE term = ? AND uuid = ?`, positionsJoined, term, s.UUID.String())
 }
 
 // SetIndexTermCount inserts or updates the count of a term indexed
@@ -334,33 +462,23 @@ func (s *Snip) SetIndexTermCount(term string, count int) error {
 		return err
 	}
 
-	var stmt *sqlite3.Stmt
 	if countCurrent != 0 {
 		// remove current count and replace with new count
-		stmt, err = database.Conn.Prepare(`UPDATE snip_index SET count = ? WHERE term = ? AND uuid = ?`)
-		if err != nil {
-			return err
-		}
-		err = stmt.Exec(count, term, s.UUID.String())
-		if err != nil {
-			return err
-		}
-	} else {
-		stmt, err = database.Conn.Prepare(`INSERT INTO snip_index (term, uuid, count) VALUES (?, ?, ?)`)
-		if err != nil {
-			return err
-		}
-		err = stmt.Exec(term, s.UUID.String(), count)
-		if err != nil {
-			return err
-		}
+		return database.Exec(`UPDATE snip_index SET count = ? WHERE term = ? AND uuid = ?`, count, term, s.UUID.String())
 	}
-	stmt.Close()
-	return nil
+	err = database.Exec(`INSERT INTO snip_index (term, uuid, count) VALUES (?, ?, ?)`, term, s.UUID.String(), count)
+	if err != nil {
+		return err
+	}
+	return addTerm(term)
 }
 
 // Update writes all fields, overwriting existing snip data
 func (s *Snip) Update() error {
+	if err := checkLocked(s.UUID); err != nil {
+		return err
+	}
+
 	// verify that current record is present and unique
 	stmt, err := database.Conn.Prepare(`SELECT count() FROM snip where uuid = ?`, s.UUID.String())
 	if err != nil {
@@ -398,6 +516,12 @@ func (s *Snip) Update() error {
 		return fmt.Errorf("should have returned 1 snip record, found %d", count)
 	}
 
+	// keep the data being replaced
+	err = saveVersion(s.UUID, []byte(s.Data))
+	if err != nil {
+		return err
+	}
+
 	// FIXME handle attachments
 	// update the record
 	stmt2, err := database.Conn.Prepare(`UPDATE snip SET (data, timestamp, name) = (?, ?, ?) WHERE uuid = ?`)
@@ -406,17 +530,44 @@ func (s *Snip) Update() error {
 	}
 	defer stmt2.Close()
 
-	err = stmt2.Exec(s.Data, s.Timestamp.Format(time.RFC3339Nano), s.Name, s.UUID.String())
+	err = stmt2.Exec([]byte(s.Data), s.Timestamp.Format(time.RFC3339Nano), s.Name, s.UUID.String())
 	if err != nil {
 		return err
 	}
-	return nil
+	err = SetChecksum(s.UUID, Checksum([]byte(s.Data)))
+	if err != nil {
+		return err
+	}
+	err = SetLanguage(s.UUID, DetectLanguage(s.Data))
+	if err != nil {
+		return err
+	}
+	err = RecordAccess(s.UUID)
+	if err != nil {
+		return err
+	}
+	return recordEvent(EventUpdate, s.UUID, uuid.Nil)
+}
+
+// tableExists reports whether the database has a table named name
+func tableExists(name string) (bool, error) {
+	var exists bool
+	err := database.QueryRow(`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`, []interface{}{name}, &exists)
+	return exists, err
 }
 
 // CreateNewDatabase creates a new sqlite3 database
 func CreateNewDatabase() error {
 	// build schema
-	err := database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip(uuid TEXT, timestamp TEXT, name TEXT, data TEXT)`)
+	err := database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip(uuid TEXT, timestamp TEXT, name TEXT, data BLOB)`)
+	if err != nil {
+		return err
+	}
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_access(uuid TEXT PRIMARY KEY, accessed TEXT)`)
+	if err != nil {
+		return err
+	}
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_alias(alias TEXT PRIMARY KEY, uuid TEXT)`)
 	if err != nil {
 		return err
 	}
@@ -428,147 +579,476 @@ func CreateNewDatabase() error {
 	if err != nil {
 		return err
 	}
-
-	return nil
-}
-
-// CumulativeTermsCount returns a total of all occurrences of all known terms in a document's search index
-func CumulativeTermsCount(id uuid.UUID) (int, error) {
-	var count int
-
-	stmt, err := database.Conn.Prepare(`SELECT sum(count) from snip_index where uuid = ?`)
+	// the term list is read by prefix and by reversed suffix to expand wildcard terms
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_term(term TEXT PRIMARY KEY, reversed TEXT)`)
 	if err != nil {
-		return count, err
+		return err
 	}
-	err = stmt.Exec(id.String())
+	err = database.Conn.Exec(`CREATE INDEX IF NOT EXISTS snip_term_reversed ON snip_term(reversed)`)
 	if err != nil {
-		return count, err
+		return err
 	}
-	defer stmt.Close()
-
-	hasRow, err := stmt.Step()
+	// searches look up terms, and changes to a snip replace its entries
+	err = database.Conn.Exec(`CREATE INDEX IF NOT EXISTS snip_index_term ON snip_index(term)`)
 	if err != nil {
-		return count, err
+		return err
 	}
-	if !hasRow {
-		return count, fmt.Errorf("cumulative count returned zero rows on a sum() operation")
+	err = database.Conn.Exec(`CREATE INDEX IF NOT EXISTS snip_index_uuid ON snip_index(uuid)`)
+	if err != nil {
+		return err
 	}
-
-	err = stmt.Scan(&count)
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_meta(uuid TEXT, key TEXT, value TEXT)`)
 	if err != nil {
-		return count, err
+		return err
 	}
-
-	return count, nil
-}
-
-// Remove removes a snip from the database
-func Remove(id uuid.UUID) error {
-	// remove associated attachments
-	attachments, err := GetAttachments(id)
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_owner(uuid TEXT PRIMARY KEY, owner TEXT)`)
 	if err != nil {
 		return err
 	}
-	for _, a := range attachments {
-		err = RemoveAttachment(a.UUID)
-		if err != nil {
-			return err
-		}
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_path(path TEXT, uuid TEXT)`)
+	if err != nil {
+		return err
 	}
-	// remove
-	stmt, err := database.Conn.Prepare(`DELETE from snip WHERE uuid = ?`, id.String())
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_event(id INTEGER PRIMARY KEY AUTOINCREMENT, type TEXT, uuid TEXT, attachment TEXT, timestamp TEXT)`)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
-	err = stmt.Exec()
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_due(uuid TEXT PRIMARY KEY, due TEXT, notified INTEGER)`)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-// DropIndex drops the search index from the database
-func DropIndex() error {
-	stmt, err := database.Conn.Prepare(`DELETE FROM snip_index`)
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_expire(uuid TEXT PRIMARY KEY, expires TEXT)`)
 	if err != nil {
 		return err
 	}
-	err = stmt.Exec()
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_version(uuid TEXT, version INTEGER, timestamp TEXT, name TEXT, data BLOB, PRIMARY KEY (uuid, version))`)
 	if err != nil {
 		return err
 	}
-	return nil
-}
-
-// FlattenString returns a string with all newline, tabs, and spaces squeezed
-func FlattenString(input string) string {
-	// remove newlines and tabs
-	dataSummary := strings.ReplaceAll(input, "\n", " ")
-	dataSummary = strings.ReplaceAll(dataSummary, "\t", " ")
-	// squeeze whitespace
-	pattern := regexp.MustCompile(` +`)
-	dataSummary = pattern.ReplaceAllString(dataSummary, " ")
-
-	return dataSummary
-}
-
-// GetAllSnipIDs returns a slice of all known snip uuids
-func GetAllSnipIDs() ([]uuid.UUID, error) {
-	var snipIDs []uuid.UUID
-
-	stmt, err := database.Conn.Prepare(`SELECT uuid from snip`)
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_review(uuid TEXT PRIMARY KEY, due TEXT, interval INTEGER, ease REAL, repetitions INTEGER)`)
 	if err != nil {
-		return snipIDs, err
+		return err
 	}
-	defer stmt.Close()
-
-	err = stmt.Exec()
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_lock(uuid TEXT PRIMARY KEY, timestamp TEXT)`)
 	if err != nil {
-		return snipIDs, err
+		return err
 	}
-
-	for {
-		hasRow, err := stmt.Step()
-		if err != nil {
-			return snipIDs, err
-		}
-		if !hasRow {
-			break
-		}
-		var idStr string
-		err = stmt.Scan(&idStr)
-		if err != nil {
-			return snipIDs, err
-		}
-		id, err := uuid.Parse(idStr)
-		if err != nil {
-			return snipIDs, err
-		}
-		snipIDs = append(snipIDs, id)
+	// intervals of time worked on snips, whose stop is empty while running
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_track(id INTEGER PRIMARY KEY AUTOINCREMENT, uuid TEXT, start TEXT, stop TEXT)`)
+	if err != nil {
+		return err
 	}
-	return snipIDs, nil
-}
-
-// GetAttachments returns a slice of Attachment associated with the supplied snip uuid
-func GetAttachments(searchUUID uuid.UUID) ([]Attachment, error) {
-	var attachments []Attachment
-
-	ids, err := GetAttachmentsUUID(searchUUID)
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_note(id INTEGER PRIMARY KEY AUTOINCREMENT, uuid TEXT, timestamp TEXT, text TEXT)`)
 	if err != nil {
-		return attachments, err
+		return err
 	}
-
-	for _, id := range ids {
-		a, err := GetAttachmentFromUUID(id.String())
-		if err != nil {
-			return attachments, err
-		}
-		attachments = append(attachments, a)
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_share(token TEXT, uuid TEXT, expires TEXT)`)
+	if err != nil {
+		return err
 	}
-	return attachments, nil
-}
-
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_star(uuid TEXT PRIMARY KEY, stars INTEGER)`)
+	if err != nil {
+		return err
+	}
+	// progress of snips kept as tasks, todo, doing, or done
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_status(uuid TEXT PRIMARY KEY, status TEXT)`)
+	if err != nil {
+		return err
+	}
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_tag(uuid TEXT, tag TEXT)`)
+	if err != nil {
+		return err
+	}
+	// digests are backfilled only by the migration creating their table, after which verify reports any that are missing
+	checksummed, err := tableExists("snip_checksum")
+	if err != nil {
+		return err
+	}
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_checksum(uuid TEXT PRIMARY KEY, sha256 TEXT)`)
+	if err != nil {
+		return err
+	}
+	err = database.Conn.Exec(`CREATE INDEX IF NOT EXISTS snip_checksum_sha256 ON snip_checksum(sha256)`)
+	if err != nil {
+		return err
+	}
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_attachment_checksum(uuid TEXT PRIMARY KEY, sha256 TEXT)`)
+	if err != nil {
+		return err
+	}
+	// attachments whose data is kept in a file of the blob directory rather than in snip_attachment
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_attachment_blob(uuid TEXT PRIMARY KEY, sha256 TEXT)`)
+	if err != nil {
+		return err
+	}
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_attachment_text(uuid TEXT PRIMARY KEY, source TEXT, text TEXT)`)
+	if err != nil {
+		return err
+	}
+	// the programming language detected in the data of each snip, empty for prose
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_lang(uuid TEXT PRIMARY KEY, lang TEXT)`)
+	if err != nil {
+		return err
+	}
+	err = database.Conn.Exec(`CREATE TABLE IF NOT EXISTS snip_thumbnail(uuid TEXT, size INTEGER, type TEXT, data BLOB, PRIMARY KEY (uuid, size))`)
+	if err != nil {
+		return err
+	}
+	if !checksummed {
+		err = backfillChecksums()
+		if err != nil {
+			return err
+		}
+	}
+	err = backfillTerms()
+	if err != nil {
+		return err
+	}
+	err = backfillLanguages()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// cumulativeTermsQuery totals the occurrences of every indexed term of a snip, taking its uuid as the parameter
+const cumulativeTermsQuery = `SELECT sum(count) from snip_index where uuid = ?`
+
+// CumulativeTermsCount returns a total of all occurrences of all known terms in a document's search index
+func CumulativeTermsCount(id uuid.UUID) (int, error) {
+	var count int
+	err := database.QueryRow(cumulativeTermsQuery, []interface{}{id.String()}, &count)
+	if errors.Is(err, database.ErrNoRows) {
+		return count, fmt.Errorf("cumulative count returned zero rows on a sum() operation")
+	}
+	return count, err
+}
+
+// cumulativeTermsCountsQuery totals the occurrences of every indexed term of each snip, taking a json array of uuids as the parameter
+const cumulativeTermsCountsQuery = `SELECT uuid, sum(count) FROM snip_index WHERE uuid IN (SELECT value FROM json_each(?)) GROUP BY uuid`
+
+// CumulativeTermsCounts returns the total of all occurrences of all known terms in the search index of each snip in ids
+func CumulativeTermsCounts(ids []uuid.UUID) (map[uuid.UUID]int, error) {
+	counts := make(map[uuid.UUID]int)
+
+	stmt, err := database.Prepare(cumulativeTermsCountsQuery, uuidList(ids))
+	if err != nil {
+		return counts, err
+	}
+	defer database.Release(stmt)
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return counts, err
+		}
+		if !hasRow {
+			break
+		}
+		var id uuid.UUID
+		var count int
+		err = database.Scan(stmt, &id, &count)
+		if err != nil {
+			return counts, err
+		}
+		counts[id] = count
+	}
+	return counts, nil
+}
+
+// uuidList returns ids as a json array, which queries expand into rows with json_each
+func uuidList(ids []uuid.UUID) string {
+	list := make([]string, len(ids))
+	for idx, id := range ids {
+		list[idx] = strconv.Quote(id.String())
+	}
+	return "[" + strings.Join(list, ",") + "]"
+}
+
+// Remove removes a snip from the database
+func Remove(id uuid.UUID) error {
+	if err := checkLocked(id); err != nil {
+		return err
+	}
+
+	// remove associated attachments
+	attachments, err := GetAttachments(id)
+	if err != nil {
+		return err
+	}
+	// the index of the snip is removed along with it, so it is not rebuilt as attachments with text are removed
+	for _, a := range attachments {
+		_, _, err = removeAttachment(a.UUID)
+		if err != nil {
+			return err
+		}
+	}
+	err = RemoveMeta(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveAliases(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveAccess(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveNotes(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveOwner(id)
+	if err != nil {
+		return err
+	}
+	err = RemovePaths(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveIndex(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveShares(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveStars(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveTags(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveChecksum(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveLanguage(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveIntervals(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveStatus(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveDue(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveExpiry(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveReview(id)
+	if err != nil {
+		return err
+	}
+	err = RemoveVersions(id)
+	if err != nil {
+		return err
+	}
+	// remove
+	stmt, err := database.Conn.Prepare(`DELETE from snip WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+	return recordEvent(EventDelete, id, uuid.Nil)
+}
+
+// DropIndex drops the search index from the database
+func DropIndex() error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_index`)
+	if err != nil {
+		return err
+	}
+	err = stmt.Exec()
+	if err != nil {
+		return err
+	}
+	err = database.Exec(`DELETE FROM snip_term`)
+	if err != nil {
+		return err
+	}
+	return removeTrigrams(uuid.Nil)
+}
+
+// RemoveIndex removes all search index entries of a snip
+func RemoveIndex(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_index WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if err = stmt.Exec(); err != nil {
+		return err
+	}
+	return removeTrigrams(id)
+}
+
+// FlattenString returns a string with all newline, tabs, and spaces squeezed
+func FlattenString(input string) string {
+	// remove newlines and tabs
+	dataSummary := strings.ReplaceAll(input, "\n", " ")
+	dataSummary = strings.ReplaceAll(dataSummary, "\t", " ")
+	// squeeze whitespace
+	pattern := regexp.MustCompile(` +`)
+	dataSummary = pattern.ReplaceAllString(dataSummary, " ")
+
+	return dataSummary
+}
+
+// GetRandomSnipID returns the uuid of a randomly selected snip having all of tags, or uuid.Nil if there is none
+func GetRandomSnipID(tags ...string) (uuid.UUID, error) {
+	query := `SELECT uuid FROM snip WHERE ` + unexpired
+	args := []interface{}{sortableNow()}
+	for _, tag := range tags {
+		query += ` AND uuid IN (SELECT uuid FROM snip_tag WHERE tag = ?)`
+		args = append(args, tag)
+	}
+	stmt, err := database.Conn.Prepare(query+` ORDER BY random() LIMIT 1`, args...)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer stmt.Close()
+
+	hasRow, err := stmt.Step()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !hasRow {
+		return uuid.Nil, nil
+	}
+	var idStr string
+	err = stmt.Scan(&idStr)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(idStr)
+}
+
+// GetAllSnipIDs returns a slice of all known snip uuids, including those of expired snips
+func GetAllSnipIDs() ([]uuid.UUID, error) {
+	return querySnipIDs(`SELECT uuid from snip`)
+}
+
+// GetUnexpiredSnipIDs returns a slice of the uuids of all snips that have not expired
+func GetUnexpiredSnipIDs() ([]uuid.UUID, error) {
+	return querySnipIDs(`SELECT uuid from snip WHERE `+unexpired, sortableNow())
+}
+
+// querySnipIDs returns the uuids selected by query
+func querySnipIDs(query string, args ...interface{}) ([]uuid.UUID, error) {
+	var snipIDs []uuid.UUID
+
+	stmt, err := database.Conn.Prepare(query, args...)
+	if err != nil {
+		return snipIDs, err
+	}
+	defer stmt.Close()
+
+	err = stmt.Exec()
+	if err != nil {
+		return snipIDs, err
+	}
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return snipIDs, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr string
+		err = stmt.Scan(&idStr)
+		if err != nil {
+			return snipIDs, err
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return snipIDs, err
+		}
+		snipIDs = append(snipIDs, id)
+	}
+	return snipIDs, nil
+}
+
+// GetUUIDByData returns the uuid of a snip with data identical to the supplied data, or uuid.Nil if none exists
+func GetUUIDByData(data string) (uuid.UUID, error) {
+	// data written before it was stored as a blob is text, which never compares equal to a blob
+	stmt, err := database.Conn.Prepare(`SELECT uuid FROM snip WHERE CAST(data AS BLOB) = ? LIMIT 1`, []byte(data))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer stmt.Close()
+
+	hasRow, err := stmt.Step()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !hasRow {
+		return uuid.Nil, nil
+	}
+	var idStr string
+	err = stmt.Scan(&idStr)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(idStr)
+}
+
+// GetAttachments returns a slice of Attachment associated with the supplied snip uuid
+func GetAttachments(searchUUID uuid.UUID) ([]Attachment, error) {
+	var attachments []Attachment
+
+	ids, err := GetAttachmentsUUID(searchUUID)
+	if err != nil {
+		return attachments, err
+	}
+
+	for _, id := range ids {
+		a, err := GetAttachmentFromUUID(id.String())
+		if err != nil {
+			return attachments, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
+// getAttachmentsMetadata returns the attachments of a snip without their data
+func getAttachmentsMetadata(snipUUID uuid.UUID) ([]Attachment, error) {
+	var attachments []Attachment
+
+	ids, err := GetAttachmentsUUID(snipUUID)
+	if err != nil {
+		return attachments, err
+	}
+	for _, id := range ids {
+		a, err := GetAttachmentMetadata(id)
+		if err != nil {
+			return attachments, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
 // GetAttachmentsAll returns a slice of uuids for all attachments in the system
 func GetAttachmentsAll() ([]uuid.UUID, error) {
 	var attachmentIDs []uuid.UUID
@@ -610,18 +1090,12 @@ func GetAttachmentsAll() ([]uuid.UUID, error) {
 func GetAttachmentsUUID(snipUUID uuid.UUID) ([]uuid.UUID, error) {
 	var results []uuid.UUID
 
-	stmt, err := database.Conn.Prepare(`SELECT uuid FROM snip_attachment WHERE snip_uuid = ?`)
-	if err != nil {
-		return results, err
-	}
-	defer stmt.Close()
-
-	err = stmt.Exec(snipUUID.String())
+	stmt, err := database.Prepare(`SELECT uuid FROM snip_attachment WHERE snip_uuid = ?`, snipUUID.String())
 	if err != nil {
 		return results, err
 	}
+	defer database.Release(stmt)
 
-	resultCount := 0
 	for {
 		hasRow, err := stmt.Step()
 		if err != nil {
@@ -630,14 +1104,8 @@ func GetAttachmentsUUID(snipUUID uuid.UUID) ([]uuid.UUID, error) {
 		if !hasRow {
 			break
 		}
-		resultCount++
-
-		var idStr string
-		err = stmt.Scan(&idStr)
-		if err != nil {
-			return results, err
-		}
-		id, err := uuid.Parse(idStr)
+		var id uuid.UUID
+		err = database.Scan(stmt, &id)
 		if err != nil {
 			return results, err
 		}
@@ -648,100 +1116,140 @@ func GetAttachmentsUUID(snipUUID uuid.UUID) ([]uuid.UUID, error) {
 
 // GetFromUUID retrieves a single Snip by its unique identifier
 func GetFromUUID(searchUUID string) (Snip, error) {
+	return getSnip(searchUUID, true)
+}
+
+// GetSnipMeta retrieves a single Snip by its unique identifier without its data or the data of its attachments.
+// The data may be read afterward with LoadData or DataReader.
+func GetSnipMeta(searchUUID string) (Snip, error) {
+	return getSnip(searchUUID, false)
+}
+
+// getSnip retrieves a single Snip by full or partial uuid, reading its data and the data of its attachments when withData is set
+func getSnip(searchUUID string, withData bool) (Snip, error) {
 	s := Snip{}
+	searchUUID, err := resolveAlias(searchUUID)
+	if err != nil {
+		return s, err
+	}
 
 	// determine exact or partial matching
-	var exactMatch bool
 	var maxLength = 36
-	var err error
 	length := len(searchUUID)
-
-	switch {
-	case length > maxLength || length == 0:
+	if length > maxLength || length == 0 {
 		return s, fmt.Errorf("supplied uuid string must be 1 to %d characters", maxLength)
-	case length == maxLength:
-		exactMatch = true
-	default:
-		exactMatch = false
+	}
+	condition := `uuid = ?`
+	if length < maxLength {
+		condition = `uuid LIKE ?`
+		searchUUID = "%" + searchUUID + "%"
 	}
 
-	var stmt *sqlite3.Stmt
-	if exactMatch {
-		stmt, err = database.Conn.Prepare(`SELECT uuid, data, timestamp, name FROM snip WHERE uuid = ?`, searchUUID)
+	// enforce only one result to avoid ambiguous behavior
+	var data []byte
+	if withData {
+		err = database.QueryRow(`SELECT uuid, timestamp, name, data FROM snip WHERE `+condition, []interface{}{searchUUID}, &s.UUID, &s.Timestamp, &s.Name, &data)
+		s.Data = string(data)
 	} else {
-		searchUUIDFuzzy := "%" + searchUUID + "%"
-		stmt, err = database.Conn.Prepare(`SELECT uuid, data, timestamp, name FROM snip WHERE uuid LIKE ?`, searchUUIDFuzzy)
+		err = database.QueryRow(`SELECT uuid, timestamp, name FROM snip WHERE `+condition, []interface{}{searchUUID}, &s.UUID, &s.Timestamp, &s.Name)
 	}
 	if err != nil {
 		return s, err
 	}
-	defer stmt.Close()
 
+	// gather attachments
+	if withData {
+		s.Attachments, err = GetAttachments(s.UUID)
+	} else {
+		s.Attachments, err = getAttachmentsMetadata(s.UUID)
+	}
+	if err != nil {
+		return s, err
+	}
+
+	s.Meta, err = GetMeta(s.UUID)
+	if err != nil {
+		return s, err
+	}
+
+	s.Tags, err = GetTags(s.UUID)
+	if err != nil {
+		return s, err
+	}
+
+	s.Owner, err = GetOwner(s.UUID)
 	if err != nil {
 		return s, err
 	}
 
-	resultCount := 0
+	return s, nil
+}
+
+// LoadData reads the data of a snip obtained without it, such as from GetSnipMeta or ListMetadata
+func (s *Snip) LoadData() error {
+	var data []byte
+	err := database.QueryRow(`SELECT data FROM snip WHERE uuid = ?`, []interface{}{s.UUID.String()}, &data)
+	if err != nil {
+		return err
+	}
+	s.Data = string(data)
+	return nil
+}
+
+// DataReader returns a reader of the stored data of a snip, which is read from the database incrementally rather than all at once.
+// The reader must be closed before the snip is changed.
+func (s *Snip) DataReader() (io.ReadCloser, error) {
+	var rowID int64
+	err := database.QueryRow(`SELECT rowid FROM snip WHERE uuid = ?`, []interface{}{s.UUID.String()}, &rowID)
+	if err != nil {
+		return nil, err
+	}
+	return database.Conn.BlobIO("main", "snip", "data", rowID, false)
+}
+
+// snipsByIDQuery selects snips without their attachments, metadata, or tags, taking a json array of uuids as the parameter
+const snipsByIDQuery = `SELECT uuid, data, timestamp, name FROM snip WHERE uuid IN (SELECT value FROM json_each(?))`
+
+// GetSnips returns the snips in ids by uuid, reading only their name, timestamp, and data
+func GetSnips(ids []uuid.UUID) (map[uuid.UUID]Snip, error) {
+	snips := make(map[uuid.UUID]Snip)
+
+	stmt, err := database.Prepare(snipsByIDQuery, uuidList(ids))
+	if err != nil {
+		return snips, err
+	}
+	defer database.Release(stmt)
+
 	for {
 		hasRow, err := stmt.Step()
 		if err != nil {
-			return s, err
-		}
-		if !hasRow {
-			break
-		}
-		resultCount++
-		// enforce only one result to avoid ambiguous behavior
-		if resultCount > 1 {
-			return s, fmt.Errorf("database search returned multiple results")
+			return snips, err
 		}
-
-		var data string
-		var id string
-		var timestamp string
-		var name string
-		err = stmt.Scan(&id, &data, &timestamp, &name)
-		if err != nil {
-			return s, err
-		}
-		s.Data = data
-		s.UUID, err = uuid.Parse(id)
-		if err != nil {
-			return s, fmt.Errorf("error parsing uuid string into struct")
+		if !hasRow {
+			break
 		}
-		s.Name = name
-		s.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp)
+		var s Snip
+		var data []byte
+		err = database.Scan(stmt, &s.UUID, &data, &s.Timestamp, &s.Name)
 		if err != nil {
-			return s, err
+			return snips, err
 		}
+		s.Data = string(data)
+		snips[s.UUID] = s
 	}
-	if resultCount == 0 {
-		return s, fmt.Errorf("database search returned zero results")
-	}
-
-	// gather attachments
-	s.Attachments, err = GetAttachments(s.UUID)
-	if err != nil {
-		return s, err
-	}
-
-	return s, nil
+	return snips, nil
 }
 
 // GetIndexTermCount returns the index count for a term matching id
 func GetIndexTermCount(term string, id uuid.UUID) (int, error) {
 	var matches = 0
 	// return zero if nothing matches (which should not be present in database)
-	stmt, err := database.Conn.Prepare(`SELECT count from snip_index WHERE term = ? AND uuid = ?`)
+	stmt, err := database.Prepare(`SELECT count from snip_index WHERE term = ? AND uuid = ?`, term, id.String())
 	if err != nil {
 		return matches, err
 	}
-	defer stmt.Close()
+	defer database.Release(stmt)
 
-	err = stmt.Exec(term, id.String())
-	if err != nil {
-		return matches, err
-	}
 	hasRow, err := stmt.Step()
 	if err != nil {
 		return matches, err
@@ -756,20 +1264,73 @@ func GetIndexTermCount(term string, id uuid.UUID) (int, error) {
 	return matches, nil
 }
 
-// InsertSnip adds a new Snip to the database
-func InsertSnip(s Snip) error {
-	stmt, err := database.Conn.Prepare(`INSERT INTO snip VALUES (?, ?, ?, ?)`)
+// InsertOption sets a field of a snip as it is inserted
+type InsertOption func(*Snip)
+
+// WithName inserts the snip under the given name
+func WithName(name string) InsertOption {
+	return func(s *Snip) {
+		s.Name = name
+	}
+}
+
+// WithTimestamp inserts the snip with the given creation time
+func WithTimestamp(timestamp time.Time) InsertOption {
+	return func(s *Snip) {
+		s.Timestamp = timestamp
+	}
+}
+
+// WithTags applies tags to the snip in addition to those it already has
+func WithTags(tags ...string) InsertOption {
+	return func(s *Snip) {
+		s.Tags = append(s.Tags, tags...)
+	}
+}
+
+// InsertSnip adds a new Snip and its tags to the database
+func InsertSnip(s Snip, opts ...InsertOption) error {
+	for _, opt := range opts {
+		opt(&s)
+	}
+	// validate tags before anything is written
+	for _, tag := range s.Tags {
+		if _, err := NormalizeTag(tag); err != nil {
+			return err
+		}
+	}
+
+	// reference
+	err := database.Exec(`INSERT INTO snip VALUES (?, ?, ?, ?)`, s.UUID.String(), s.Timestamp.Format(time.RFC3339Nano), s.Name, []byte(s.Data))
+	if err != nil {
+		return err
+	}
+	err = SetChecksum(s.UUID, Checksum([]byte(s.Data)))
+	if err != nil {
+		return err
+	}
+	err = SetLanguage(s.UUID, DetectLanguage(s.Data))
+	if err != nil {
+		return err
+	}
+	err = SetOwner(s.UUID, s.Owner)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
 
-	// reference
-	err = stmt.Exec(s.UUID.String(), s.Timestamp.Format(time.RFC3339Nano), s.Name, s.Data)
+	tags := s.Tags
+	s.Tags = nil
+	for _, tag := range tags {
+		err = s.AddTag(tag)
+		if err != nil {
+			return err
+		}
+	}
+	err = RecordAccess(s.UUID)
 	if err != nil {
 		return err
 	}
-	return nil
+	return recordEvent(EventCreate, s.UUID, uuid.Nil)
 }
 
 // IsWord determines if a string is a valid word using unicode functions
@@ -789,12 +1350,12 @@ func List(limit int) ([]Snip, error) {
 	var err error
 
 	if limit != 0 {
-		stmt, err = database.Conn.Prepare(`SELECT uuid, timestamp, name, data from snip LIMIT ?`, limit)
+		stmt, err = database.Conn.Prepare(`SELECT uuid, timestamp, name, data from snip WHERE `+unexpired+` LIMIT ?`, sortableNow(), limit)
 		if err != nil {
 			return results, err
 		}
 	} else {
-		stmt, err = database.Conn.Prepare(`SELECT uuid, timestamp, name, data from snip`)
+		stmt, err = database.Conn.Prepare(`SELECT uuid, timestamp, name, data from snip WHERE `+unexpired, sortableNow())
 		if err != nil {
 			return results, err
 		}
@@ -813,7 +1374,7 @@ func List(limit int) ([]Snip, error) {
 		var idStr string
 		var timestampStr string
 		var name string
-		var data string
+		var data []byte
 
 		err = stmt.Scan(&idStr, &timestampStr, &name, &data)
 		if err != nil {
@@ -834,13 +1395,78 @@ func List(limit int) ([]Snip, error) {
 			UUID:      id,
 			Timestamp: timestamp,
 			Name:      name,
-			Data:      data,
+			Data:      string(data),
+		}
+		results = append(results, s)
+	}
+	return results, nil
+}
+
+// ListMetadata returns the uuid, timestamp, and name of unexpired snips, limit of zero returns all
+func ListMetadata(limit int) ([]Snip, error) {
+	var results []Snip
+
+	// a negative limit is no limit to sqlite
+	if limit == 0 {
+		limit = -1
+	}
+	stmt, err := database.Prepare(`SELECT uuid, timestamp, name FROM snip WHERE `+unexpired+` LIMIT ?`, sortableNow(), limit)
+	if err != nil {
+		return results, err
+	}
+	defer database.Release(stmt)
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return results, err
+		}
+		if !hasRow {
+			break
+		}
+		var s Snip
+		err = database.Scan(stmt, &s.UUID, &s.Timestamp, &s.Name)
+		if err != nil {
+			return results, err
 		}
 		results = append(results, s)
 	}
 	return results, nil
 }
 
+// ListSizes returns the size in bytes of the data of every snip, including the size of its attachments
+func ListSizes() (map[uuid.UUID]int64, error) {
+	sizes := make(map[uuid.UUID]int64)
+
+	stmt, err := database.Conn.Prepare(`SELECT snip.uuid, length(CAST(snip.data AS BLOB)) + coalesce(sum(snip_attachment.size), 0) FROM snip LEFT JOIN snip_attachment ON snip_attachment.snip_uuid = snip.uuid GROUP BY snip.uuid`)
+	if err != nil {
+		return sizes, err
+	}
+	defer stmt.Close()
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return sizes, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr string
+		var size int64
+		err = stmt.Scan(&idStr, &size)
+		if err != nil {
+			return sizes, err
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return sizes, err
+		}
+		sizes[id] = size
+	}
+	return sizes, nil
+}
+
 // New returns a new snippet and generates a new UUID for it
 func New() Snip {
 	return Snip{
@@ -853,24 +1479,266 @@ func New() Snip {
 
 // ScoreCounts returns a floating point score for search result validity
 func ScoreCounts(id uuid.UUID, terms []string, counts []SearchCount) (float64, error) {
-	var matchTermsRatio float64
-	var matchProminence float64
-	// calculate the ratio of matching terms to search terms
-	matchTermsRatio = float64(len(counts)) / float64(len(terms))
-
-	// calculate the ratio representing the prominence of the search term is within the document itself
 	// add all the counts for all terms in the index matching this uuid
 	indexedTerms, err := CumulativeTermsCount(id)
 	if err != nil {
 		return 0, err
 	}
+	return scoreCounts(terms, counts, indexedTerms), nil
+}
+
+// scoreCounts returns the score of a search result given the total occurrences of all terms indexed for the snip
+func scoreCounts(terms []string, counts []SearchCount, indexedTerms int) float64 {
+	var matchTermsRatio float64
+	var matchProminence float64
+	// calculate the ratio of matching terms to search terms, where a wildcard term may match several stems
+	matched := make(map[string]bool)
+	for _, c := range counts {
+		matched[c.Term] = true
+	}
+	matchTermsRatio = float64(len(matched)) / float64(len(terms))
+
+	// calculate the ratio representing the prominence of the search term is within the document itself
 	if indexedTerms != 0 {
 		matchProminence = float64(len(terms)) / float64(indexedTerms)
 	}
 	log.Debug().Float64("matchTermsRatio", matchTermsRatio).Msg("scoring")
 	log.Debug().Float64("matchProminence", matchProminence).Msg("scoring")
 
-	return (matchTermsRatio + matchProminence) / 2.0, nil
+	return (matchTermsRatio + matchProminence) / 2.0
+}
+
+// Search returns index search results matching all terms ordered by highest score
+func Search(terms []string, limit int) ([]SearchScore, error) {
+	return searchWithin(context.Background(), terms, nil, limit)
+}
+
+// SearchAny returns index search results matching any of the terms ordered by highest score, where matching more of them ranks higher.
+// It finds the snips related to a question, whose words are rarely all found in one snip.
+func SearchAny(terms []string, limit int) ([]SearchScore, error) {
+	terms = queryTerms(terms)
+	searchResults, err := SearchIndexTerm(terms, false)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := matchFields(terms)
+	if err != nil {
+		return nil, err
+	}
+	// snips matched by their fields alone have no index search results
+	for id := range fields {
+		if _, ok := searchResults[id]; !ok {
+			searchResults[id] = nil
+		}
+	}
+	scores, err := rankResults(terms, searchResults, fields, 0)
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[uuid.UUID]int, len(scores))
+	for _, score := range scores {
+		found := make(map[string]bool)
+		for _, c := range score.SearchCounts {
+			found[c.Term] = true
+		}
+		for _, term := range terms {
+			if found[term] || score.Fields.has(term) {
+				matched[score.UUID]++
+			}
+		}
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return matched[scores[i].UUID] > matched[scores[j].UUID]
+	})
+	if limit > 0 && len(scores) > limit {
+		scores = scores[:limit]
+	}
+	return scores, nil
+}
+
+// searchWithin returns index search results matching all terms ordered by highest score, only among the snips of within unless it is nil.
+// The error of ctx is returned when it is done between stages.
+func searchWithin(ctx context.Context, terms []string, within map[uuid.UUID]bool, limit int) ([]SearchScore, error) {
+	var scores []SearchScore
+
+	terms = queryTerms(terms)
+	searchResults, err := SearchIndexTerm(terms, false)
+	if err != nil {
+		return scores, err
+	}
+	if err = ctx.Err(); err != nil {
+		return scores, err
+	}
+	fields, err := matchFields(terms)
+	if err != nil {
+		return scores, err
+	}
+	if err = ctx.Err(); err != nil {
+		return scores, err
+	}
+	pruned := pruneResults(terms, searchResults, fields)
+	if within != nil {
+		for id := range pruned {
+			if !within[id] {
+				delete(pruned, id)
+			}
+		}
+	}
+	return rankResults(terms, pruned, fields, limit)
+}
+
+// scoreHeap is a heap of search results whose root is the lowest ranked of them
+type scoreHeap []SearchScore
+
+func (h scoreHeap) Len() int            { return len(h) }
+func (h scoreHeap) Less(i, j int) bool  { return ranksAbove(h[j], h[i]) }
+func (h scoreHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *scoreHeap) Push(x interface{}) { *h = append(*h, x.(SearchScore)) }
+func (h *scoreHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// ranksAbove reports whether search result a is listed before b, by higher score and then by uuid so that ties are listed in the same order every time
+func ranksAbove(a SearchScore, b SearchScore) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.UUID.String() < b.UUID.String()
+}
+
+// rankResults scores index search results, raised by the terms in fields, and returns up to limit of them ordered by highest score.
+// When limit is positive only the highest ranked results are kept as they are scored, rather than sorting every result.
+func rankResults(terms []string, searchResults map[uuid.UUID][]SearchCount, fields map[uuid.UUID]FieldMatches, limit int) ([]SearchScore, error) {
+	var scores []SearchScore
+
+	ids := make([]uuid.UUID, 0, len(searchResults))
+	for id := range searchResults {
+		ids = append(ids, id)
+	}
+	indexedTerms, err := CumulativeTermsCounts(ids)
+	if err != nil {
+		return scores, fmt.Errorf("scoring: %w", err)
+	}
+	var top scoreHeap
+	for id, result := range searchResults {
+		score := SearchScore{
+			UUID:         id,
+			Score:        scoreCounts(terms, result, indexedTerms[id]) + boostScore(terms, fields[id]),
+			SearchCounts: result,
+			Fields:       fields[id],
+		}
+		switch {
+		case limit <= 0:
+			scores = append(scores, score)
+		case len(top) < limit:
+			heap.Push(&top, score)
+		case ranksAbove(score, top[0]):
+			// the lowest ranked result kept is replaced
+			top[0] = score
+			heap.Fix(&top, 0)
+		}
+	}
+	if limit > 0 {
+		scores = top
+	}
+
+	// sorted output by highest score
+	sort.Slice(scores, func(i int, j int) bool {
+		return ranksAbove(scores[i], scores[j])
+	})
+	return scores, nil
+}
+
+// SearchWithContext returns index search results including the words adjacent to each matching term
+func SearchWithContext(terms []string, limit int, adjacent int) ([]SearchMatch, error) {
+	return SearchContext(context.Background(), terms, limit, adjacent)
+}
+
+// SearchContext returns the results of SearchWithContext, or the error of ctx when it is done first, such as when its deadline passes
+func SearchContext(ctx context.Context, terms []string, limit int, adjacent int) ([]SearchMatch, error) {
+	return searchMatches(ctx, terms, nil, limit, adjacent)
+}
+
+// SearchWithin returns the results of SearchContext among the snips of ids, so that the results of an earlier search can be narrowed by further terms
+func SearchWithin(ctx context.Context, terms []string, ids []uuid.UUID, limit int, adjacent int) ([]SearchMatch, error) {
+	within := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		within[id] = true
+	}
+	return searchMatches(ctx, terms, within, limit, adjacent)
+}
+
+// searchMatches returns index search results among the snips of within, or all snips when it is nil, including the words adjacent to each matching term.
+// Queries running when ctx is done are interrupted, and its error is returned.
+func searchMatches(ctx context.Context, terms []string, within map[uuid.UUID]bool, limit int, adjacent int) ([]SearchMatch, error) {
+	var matches []SearchMatch
+	stop := database.InterruptOn(ctx)
+	defer stop()
+
+	terms = queryTerms(terms)
+	scores, err := searchWithin(ctx, terms, within, limit)
+	if err == nil {
+		matches, err = gatherMatches(terms, scores, adjacent)
+	}
+	// an interrupted query fails with an error of its own
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return matches, err
+}
+
+// gatherMatches collects the name, word count, and context of each scored search result
+func gatherMatches(terms []string, scores []SearchScore, adjacent int) ([]SearchMatch, error) {
+	var matches []SearchMatch
+
+	// the snips and the positions of every term are read for all results at once
+	ids := make([]uuid.UUID, 0, len(scores))
+	for _, score := range scores {
+		ids = append(ids, score.UUID)
+	}
+	snips, err := GetSnips(ids)
+	if err != nil {
+		return matches, err
+	}
+	// the stems matched are those of the results, since a wildcard term matches several
+	var stems []string
+	seen := make(map[string]bool)
+	for _, score := range scores {
+		for _, c := range score.SearchCounts {
+			if !seen[c.Stem] {
+				seen[c.Stem] = true
+				stems = append(stems, c.Stem)
+			}
+		}
+	}
+	positions, err := getPositions(ids, stems)
+	if err != nil {
+		return matches, err
+	}
+
+	for _, score := range scores {
+		s, ok := snips[score.UUID]
+		if !ok {
+			return matches, fmt.Errorf("snip %s of search results could not be found", score.UUID)
+		}
+		match := SearchMatch{
+			SearchScore: score,
+			Name:        s.Name,
+			Words:       s.CountWords(),
+		}
+		for _, c := range score.SearchCounts {
+			ctxAll, err := s.contextAt(positions[s.UUID][c.Stem], adjacent)
+			if err != nil {
+				return matches, fmt.Errorf("gathering context for term %s: %w", c.Term, err)
+			}
+			match.Context = append(match.Context, ctxAll...)
+		}
+		matches = append(matches, match)
+	}
+	return matches, nil
 }
 
 // SearchDataTerm returns a slice of Snips whose data matches supplied terms
@@ -882,7 +1750,7 @@ func SearchDataTerm(term string) ([]Snip, error) {
 
 	// modify term for fuzziness
 	termFuzzy := "%" + term + "%"
-	stmt, err := database.Conn.Prepare(`SELECT uuid from snip where data LIKE ?`, termFuzzy)
+	stmt, err := database.Conn.Prepare(`SELECT uuid from snip where data LIKE ? AND `+unexpired, termFuzzy, sortableNow())
 	if err != nil {
 		return searchResult, err
 	}
@@ -914,87 +1782,117 @@ func SearchDataTerm(term string) ([]Snip, error) {
 	return searchResult, nil
 }
 
+// indexTermQuery selects the index entries of a stemmed term, taking the stem and sortableNow as its parameters
+const indexTermQuery = `SELECT uuid, count FROM snip_index WHERE term = ? AND ` + unexpired
+
 // SearchIndexTerm searches the index and returns results matching the given term
 func SearchIndexTerm(terms []string, requireAll bool) (map[uuid.UUID][]SearchCount, error) {
 	var searchResults = make(map[uuid.UUID][]SearchCount, 0)
+	terms = queryTerms(terms)
 
 	if len(terms) <= 0 {
 		return searchResults, fmt.Errorf("refusing to search for empty string")
 	}
 
 	for _, term := range terms {
+		if isWildcard(term) {
+			expanded, err := expandWildcard(term)
+			if err != nil {
+				return searchResults, err
+			}
+			for _, stem := range expanded {
+				if err = lookupIndexTerm(term, stem, searchResults); err != nil {
+					return searchResults, err
+				}
+			}
+			continue
+		}
 		// stem the term
 		termStemmed, err := snowball.Stem(term, "english", true)
+		if err != nil {
+			return searchResults, err
+		}
 		log.Debug().Str("termStemmed", termStemmed).Msg("term stemmed")
 
-		stmt, err := database.Conn.Prepare(`SELECT uuid, count FROM snip_index WHERE term = ?`, termStemmed)
+		err = lookupIndexTerm(term, termStemmed, searchResults)
 		if err != nil {
 			return searchResults, err
 		}
-		// defer stmt.Close()
+	}
 
-		for {
-			hasRow, err := stmt.Step()
-			if err != nil {
-				stmt.Close()
-				return searchResults, err
-			}
-			if !hasRow {
-				break
-			}
+	if requireAll {
+		return pruneResults(terms, searchResults, nil), nil
+	}
 
-			var (
-				idStr string
-				count int
-			)
-			err = stmt.Scan(&idStr, &count)
-			if err != nil {
-				stmt.Close()
-				return searchResults, err
-			}
-			id, err := uuid.Parse(idStr)
-			if err != nil {
-				stmt.Close()
-				return searchResults, err
-			}
-			result := SearchCount{
-				Term:  term,
-				Stem:  termStemmed,
-				Count: count,
-			}
-			searchResults[id] = append(searchResults[id], result)
-		}
+	return searchResults, nil
+}
+
+// lookupIndexTerm adds the unexpired snips indexed under stem to searchResults
+func lookupIndexTerm(term string, stem string, searchResults map[uuid.UUID][]SearchCount) error {
+	stmt, err := database.Prepare(indexTermQuery, stem, sortableNow())
+	if err != nil {
+		return err
 	}
+	defer database.Release(stmt)
 
-	if requireAll {
-		// prune results that do not contain all supplied terms
-		searchResultsPruned := make(map[uuid.UUID][]SearchCount, 0)
-		for id, result := range searchResults {
-			// check each id
-			var termsCollected []string
-			for _, item := range result {
-				// check if term is in collected
-				if !func() bool {
-					for _, t := range termsCollected {
-						if t == item.Term {
-							return true
-						}
-					}
-					return false
-				}() {
-					termsCollected = append(termsCollected, item.Term)
-				}
-			}
-			// keep this id
-			if len(termsCollected) == len(terms) {
-				searchResultsPruned[id] = result
-			}
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			break
 		}
 
-		return searchResultsPruned, nil
+		var (
+			id    uuid.UUID
+			count int
+		)
+		err = database.Scan(stmt, &id, &count)
+		if err != nil {
+			return err
+		}
+		result := SearchCount{
+			Term:  term,
+			Stem:  stem,
+			Count: count,
+		}
+		searchResults[id] = append(searchResults[id], result)
 	}
+	return nil
+}
 
-	return searchResults, nil
+// pruneResults removes index search results that do not contain all supplied terms, in their data or in the fields matched.
+// Snips whose fields contain all the terms are kept along with the index search results, even if their data has none of them.
+func pruneResults(terms []string, searchResults map[uuid.UUID][]SearchCount, fields map[uuid.UUID]FieldMatches) map[uuid.UUID][]SearchCount {
+	searchResultsPruned := make(map[uuid.UUID][]SearchCount, 0)
+	// snips matched by their fields alone have no index search results
+	candidates := make(map[uuid.UUID][]SearchCount, len(searchResults))
+	for id, result := range searchResults {
+		candidates[id] = result
+	}
+	for id := range fields {
+		if _, ok := candidates[id]; !ok {
+			candidates[id] = nil
+		}
+	}
+	for id, result := range candidates {
+		// collect the terms found for each id
+		termsCollected := make(map[string]bool)
+		for _, item := range result {
+			termsCollected[item.Term] = true
+		}
+		for _, term := range terms {
+			if fields[id].has(term) {
+				termsCollected[term] = true
+			}
+		}
+		// keep this id
+		if len(termsCollected) == len(terms) {
+			searchResultsPruned[id] = result
+		}
+	}
+	return searchResultsPruned
 }
 
 // SearchUUID returns a slice of Snips with uuids matching partial search term
@@ -1005,7 +1903,7 @@ func SearchUUID(term string) ([]Snip, error) {
 	}
 
 	termFuzzy := "%" + term + "%"
-	stmt, err := database.Conn.Prepare(`SELECT uuid from snip where uuid LIKE ?`, termFuzzy)
+	stmt, err := database.Conn.Prepare(`SELECT uuid from snip where uuid LIKE ? AND `+unexpired, termFuzzy, sortableNow())
 	if err != nil {
 		return searchResult, err
 	}
@@ -1035,6 +1933,36 @@ func SearchUUID(term string) ([]Snip, error) {
 	return searchResult, nil
 }
 
+// matchUUIDQuery selects the uuid, timestamp, and name of snips whose uuid contains the parameter, oldest first
+const matchUUIDQuery = `SELECT uuid, timestamp, name FROM snip WHERE uuid LIKE ? ORDER BY timestamp, uuid`
+
+// MatchUUID returns the snips a partial uuid matches as GetFromUUID matches them, without their data, so that one of several may be chosen
+func MatchUUID(partial string) ([]Snip, error) {
+	var results []Snip
+	if partial == "" {
+		return results, fmt.Errorf("refusing to search for empty string")
+	}
+	stmt, err := database.Prepare(matchUUIDQuery, "%"+partial+"%")
+	if err != nil {
+		return results, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return results, err
+		}
+		if !hasRow {
+			return results, nil
+		}
+		var s Snip
+		if err = database.Scan(stmt, &s.UUID, &s.Timestamp, &s.Name); err != nil {
+			return results, err
+		}
+		results = append(results, s)
+	}
+}
+
 func ShortenUUID(id uuid.UUID) []string {
 	idSplit := strings.Split(id.String(), "-")
 	if len(idSplit) != 5 {
@@ -2,7 +2,9 @@ package snip
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"github.com/bvinc/go-sqlite-lite/sqlite3"
 	"github.com/google/uuid"
@@ -120,9 +122,16 @@ func TestMain(m *testing.M) {
 		os.Exit(1)
 	}
 
+	// ensure tables absent from the CSV data are present
+	err = CreateNewDatabase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating test database schema: %v", err)
+		os.Exit(1)
+	}
+
 	// close database after all tests have run
 	defer func() {
-		database.Conn.Close()
+		database.Close()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error closing test database %s: %v", DatabasePath, err)
 			os.Exit(1)
@@ -197,6 +206,86 @@ func TestGetFromUUID(t *testing.T) {
 	}
 }
 
+func TestGetSnipMeta(t *testing.T) {
+	s := New()
+	s.Data = "lazily loaded data"
+	if err := InsertSnip(s, WithName("lazy"), WithTags("lazy")); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	if err := s.Attach("lazy.txt", []byte("attached data")); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := GetSnipMeta(s.UUID.String()[:8])
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if m.UUID != s.UUID || m.Name != "lazy" || m.Data != "" || len(m.Tags) != 1 {
+		t.Errorf("expected metadata without data, got %+v", m)
+	}
+	if len(m.Attachments) != 1 || m.Attachments[0].Size != 13 || m.Attachments[0].Data != nil {
+		t.Errorf("expected attachment metadata without data, got %+v", m.Attachments)
+	}
+
+	if err = m.LoadData(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if m.Data != s.Data {
+		t.Errorf("expected %q, got %q", s.Data, m.Data)
+	}
+
+	r, err := m.DataReader()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	first := make([]byte, 6)
+	if _, err = io.ReadFull(r, first); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if string(first) != "lazily" || string(rest) != " loaded data" {
+		t.Errorf("expected the data read in parts, got %q and %q", first, rest)
+	}
+}
+
+func TestMatchUUID(t *testing.T) {
+	// every random uuid holds its version 4, so the partial id matches every snip
+	if _, err := GetFromUUID("4"); !errors.Is(err, database.ErrMultipleRows) {
+		t.Fatalf("expected ErrMultipleRows, got %v", err)
+	}
+	matches, err := MatchUUID("4")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(matches) < 2 {
+		t.Fatalf("expected several matches, got %d", len(matches))
+	}
+	for idx := 1; idx < len(matches); idx++ {
+		if matches[idx].Timestamp.Before(matches[idx-1].Timestamp) {
+			t.Errorf("expected matches oldest first, got %s before %s", matches[idx-1].Timestamp, matches[idx].Timestamp)
+		}
+	}
+
+	s := New()
+	s.Data = "matched by a partial id"
+	if err = InsertSnip(s, WithName("partial")); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	matches, err = MatchUUID(s.UUID.String()[:13])
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(matches) != 1 || matches[0].UUID != s.UUID || matches[0].Name != "partial" || matches[0].Data != "" {
+		t.Errorf("expected only the metadata of %s, got %+v", s.UUID, matches)
+	}
+}
+
 func TestFlattenString(t *testing.T) {
 	original := "This is  a\n\nstring that\thas\t\tlots of  whitespace."
 	expected := "This is a string that has lots of whitespace."
@@ -281,6 +370,72 @@ func TestSnipIndex(t *testing.T) {
 	}
 }
 
+func TestBatchedLookups(t *testing.T) {
+	var ids []uuid.UUID
+	for _, data := range []string{"batched lookup of snips", "batched lookup lookup"} {
+		s := New()
+		s.Data = data
+		if err := InsertSnip(s, WithName(data)); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		if err := s.Index(); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s.UUID)
+	}
+
+	listed, err := ListMetadata(0)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	found := 0
+	for _, s := range listed {
+		if s.Data != "" {
+			t.Errorf("expected metadata without data, got %q", s.Data)
+		}
+		if s.UUID == ids[0] || s.UUID == ids[1] {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("expected both snips to be listed, found %d", found)
+	}
+	if listed, err = ListMetadata(1); err != nil || len(listed) != 1 {
+		t.Errorf("expected 1 snip with a limit, got %d %v", len(listed), err)
+	}
+
+	snips, err := GetSnips(append(ids, uuid.New()))
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(snips) != 2 || snips[ids[1]].Data != "batched lookup lookup" || snips[ids[0]].Name != "batched lookup of snips" {
+		t.Errorf("expected both snips by uuid, got %v", snips)
+	}
+
+	counts, err := CumulativeTermsCounts(ids)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	for _, id := range ids {
+		count, err := CumulativeTermsCount(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts[id] != count {
+			t.Errorf("expected %d terms for %s, got %d", count, id, counts[id])
+		}
+	}
+
+	positions, err := getPositions(ids, []string{"lookup", "absent"})
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if positions[ids[0]]["lookup"] != "1" || positions[ids[1]]["lookup"] != "1,2" || len(positions[ids[1]]) != 1 {
+		t.Errorf("expected the positions of lookup in both snips, got %v", positions)
+	}
+}
+
 func TestSplitWords(t *testing.T) {
 	text := `This is simple test data. Let's keep it simple, for the time being.
 This is the second line.`
@@ -356,3 +511,341 @@ func TestUnicodeSplit(t *testing.T) {
 		}
 	}
 }
+
+func TestGetUUIDByData(t *testing.T) {
+	id, err := GetUUIDByData(DataTest)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if id != UUIDTest {
+		t.Errorf("expected uuid %s, got %s", UUIDTest, id)
+	}
+
+	id, err = GetUUIDByData("data that is not present in any snip")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if id != uuid.Nil {
+		t.Errorf("expected nil uuid, got %s", id)
+	}
+}
+
+func TestBinaryData(t *testing.T) {
+	// NUL bytes and invalid UTF-8 must survive unchanged
+	data := "binary\x00data\xff\xfe\x80 end\x00"
+	s := New()
+	s.Data = data
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	result, err := GetFromUUID(s.UUID.String())
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if result.Data != data {
+		t.Errorf("expected data %q, got %q", data, result.Data)
+	}
+
+	id, err := GetUUIDByData(data)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if id != s.UUID {
+		t.Errorf("expected uuid %s, got %s", s.UUID, id)
+	}
+
+	result.Data = data + "\x00"
+	if err = result.Update(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	result, err = GetFromUUID(s.UUID.String())
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if result.Data != data+"\x00" {
+		t.Errorf("expected updated data %q, got %q", data+"\x00", result.Data)
+	}
+
+	attachment := []byte{0x00, 0xff, 0x00, 0xc3, 0x28}
+	a, err := result.AddAttachment("binary", attachment)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	a, err = GetAttachmentFromUUID(a.UUID.String())
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if string(a.Data) != string(attachment) {
+		t.Errorf("expected attachment data %q, got %q", attachment, a.Data)
+	}
+}
+
+func TestInsertSnipOptions(t *testing.T) {
+	s := New()
+	s.Data = DataTest
+	s.Tags = []string{"existing"}
+	timestamp := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	err := InsertSnip(s, WithName("options"), WithTimestamp(timestamp), WithTags("Added", "existing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	c, err := GetFromUUID(s.UUID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "options" {
+		t.Errorf("expected name options, got %s", c.Name)
+	}
+	if !c.Timestamp.Equal(timestamp) {
+		t.Errorf("expected timestamp %s, got %s", timestamp, c.Timestamp)
+	}
+	if strings.Join(c.Tags, " ") != "added existing" {
+		t.Errorf("expected tags [added existing], got %v", c.Tags)
+	}
+
+	// an invalid tag prevents the insert entirely
+	bad := New()
+	if err = InsertSnip(bad, WithTags("not valid")); err == nil {
+		Remove(bad.UUID)
+		t.Fatal("expected error for invalid tag")
+	}
+	if _, err = GetFromUUID(bad.UUID.String()); err == nil {
+		t.Error("expected snip with invalid tag not to be inserted")
+	}
+}
+
+func TestGenerateNameStrategy(t *testing.T) {
+	data := []byte("#todo first\n\n```\n# not a heading\n```\n## The *Real* Heading ##\nsecond line")
+	tests := []struct {
+		strategy NameStrategy
+		expected string
+	}{
+		{NameStrategy{Kind: NameWords, Words: 2}, "todo first"},
+		{NameStrategy{Kind: NameLine}, "#todo first"},
+		{NameStrategy{Kind: NameHeading}, "The *Real* Heading"},
+		{NameStrategy{Kind: NameHeading, Words: 1}, "The *Real* Heading"},
+		{NameStrategy{Kind: NameTemplate, Words: 1, Template: "{{.Words}}: {{.Heading}}"}, "todo: The *Real* Heading"},
+		{NameStrategy{Kind: NameTemplate, Words: 2, Template: "{{.Missing}}"}, "todo first"},
+	}
+	for _, test := range tests {
+		name := GenerateName(data, test.strategy)
+		if name != test.expected {
+			t.Errorf("expected %q with %+v, got %q", test.expected, test.strategy, name)
+		}
+	}
+
+	// headings are required by the heading strategy, otherwise words are used
+	if name := GenerateName([]byte("no heading here at all today"), NameStrategy{Kind: NameHeading, Words: 3}); name != "no heading here" {
+		t.Errorf("expected fallback to words, got %q", name)
+	}
+	if name := GenerateName([]byte(strings.Repeat("a", 100)), NameStrategy{Kind: NameLine}); len([]rune(name)) != nameMaxLength {
+		t.Errorf("expected line truncated to %d characters, got %d", nameMaxLength, len([]rune(name)))
+	}
+	if err := (NameStrategy{Kind: "bogus"}).Validate(); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestGetRandomSnipID(t *testing.T) {
+	s := New()
+	s.Data = DataTest
+	if err := InsertSnip(s, WithTags("random-review", "other")); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	id, err := GetRandomSnipID("random-review", "other")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if id != s.UUID {
+		t.Errorf("expected the only tagged snip %s, got %s", s.UUID, id)
+	}
+	if id, err = GetRandomSnipID("random-review", "missing"); err != nil || id != uuid.Nil {
+		t.Errorf("expected no snip with both tags, got %s %v", id, err)
+	}
+	if id, err = GetRandomSnipID(); err != nil || id == uuid.Nil {
+		t.Errorf("expected any snip, got %s %v", id, err)
+	}
+}
+
+func TestSearchWithin(t *testing.T) {
+	var ids []uuid.UUID
+	for _, data := range []string{"narrowed gerbil feeding", "narrowed gerbil bedding", "narrowed hamster feeding"} {
+		s := New()
+		s.Data = data
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		if err := s.Index(); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s.UUID)
+	}
+
+	// the first two snips are the results of an earlier search for gerbil
+	matches, err := SearchWithin(context.Background(), []string{"feeding"}, ids[:2], 0, 2)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(matches) != 1 || matches[0].UUID != ids[0] {
+		t.Errorf("expected only the first snip, got %+v", matches)
+	}
+
+	matches, err = SearchWithin(context.Background(), []string{"feeding"}, nil, 0, 2)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no matches within no snips, got %+v", matches)
+	}
+}
+
+func TestSearchAny(t *testing.T) {
+	var ids []uuid.UUID
+	for _, data := range []string{"restart the quokka service", "quokka service logs", "unrelated wombat"} {
+		s := New()
+		s.Data = data
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		if err := s.Index(); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s.UUID)
+	}
+
+	// no snip holds every word of the question, and the one holding the most ranks first
+	scores, err := SearchAny([]string{"how", "to", "restart", "quokka", "service"}, 0)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	found := make(map[uuid.UUID]int)
+	for idx, score := range scores {
+		found[score.UUID] = idx + 1
+	}
+	if found[ids[0]] == 0 || found[ids[1]] == 0 || found[ids[0]] > found[ids[1]] {
+		t.Errorf("expected the first snip ranked above the second, got %+v", scores)
+	}
+	if found[ids[2]] != 0 {
+		t.Errorf("expected the unrelated snip left out, got %+v", scores)
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	for _, data := range []string{
+		"ranked ocelot",
+		"ranked ocelot with a few more words",
+		"ranked ocelot with many more words to lower its prominence",
+		"ranked ocelot",
+		"ranked ocelot among a great many other words that lower its prominence further",
+	} {
+		s := New()
+		s.Data = data
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		if err := s.Index(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := Search([]string{"ocelot"}, 0)
+	if err != nil || len(all) != 5 {
+		t.Fatalf("expected 5 results and nil err, got %d and %v", len(all), err)
+	}
+	// the results kept by the limit are the highest ranked, in the same order
+	for limit := 1; limit <= 5; limit++ {
+		top, err := Search([]string{"ocelot"}, limit)
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if len(top) != limit {
+			t.Fatalf("expected %d results, got %d", limit, len(top))
+		}
+		for idx := range top {
+			if top[idx].UUID != all[idx].UUID {
+				t.Errorf("expected %s at %d with limit %d, got %s", all[idx].UUID, idx, limit, top[idx].UUID)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = SearchContext(ctx, []string{"ocelot"}, 0, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the error of the canceled context, got %v", err)
+	}
+	// searches after the canceled one are not interrupted
+	if matches, err := SearchContext(context.Background(), []string{"ocelot"}, 2, 2); err != nil || len(matches) != 2 {
+		t.Errorf("expected 2 matches and nil err, got %d and %v", len(matches), err)
+	}
+}
+
+func TestTermCounts(t *testing.T) {
+	score := SearchScore{
+		SearchCounts: []SearchCount{
+			{Term: "deploy*", Stem: "deploy", Count: 2},
+			{Term: "deploy*", Stem: "deployment", Count: 1},
+			{Term: "nightly", Stem: "night", Count: 3},
+		},
+		Fields: FieldMatches{Name: []string{"backup"}, Tags: []string{"nightly"}},
+	}
+	counts := score.TermCounts([]string{"nightly", "backup", "deploy*"})
+	expected := []TermCount{
+		{Term: "nightly", Count: 3, Tags: true},
+		{Term: "backup", Name: true},
+		{Term: "deploy*", Count: 3},
+	}
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, counts)
+	}
+	for idx := range expected {
+		if counts[idx] != expected[idx] {
+			t.Errorf("expected %+v, got %+v", expected[idx], counts[idx])
+		}
+	}
+}
+
+func TestContextLocation(t *testing.T) {
+	s := New()
+	s.Data = "first line\nthe located ibex\n  ibex again"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	if err := s.Index(); err != nil {
+		t.Fatal(err)
+	}
+
+	contexts, err := s.GatherContext("ibex", 1)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	expected := []struct {
+		offset int
+		line   int
+		column int
+	}{
+		{23, 2, 13},
+		{30, 3, 3},
+	}
+	if len(contexts) != len(expected) {
+		t.Fatalf("expected %d contexts, got %+v", len(expected), contexts)
+	}
+	for idx, e := range expected {
+		c := contexts[idx]
+		if c.Offset != e.offset || c.Length != 4 || c.Line != e.line || c.Column != e.column {
+			t.Errorf("expected offset %d line %d column %d, got %+v", e.offset, e.line, e.column, c)
+		}
+		if s.Data[c.Offset:c.Offset+c.Length] != "ibex" {
+			t.Errorf("expected the offset of ibex, got %q", s.Data[c.Offset:c.Offset+c.Length])
+		}
+	}
+}
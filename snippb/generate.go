@@ -0,0 +1,4 @@
+// Package snippb contains the protocol buffer definitions of the snip gRPC api
+package snippb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative snip.proto
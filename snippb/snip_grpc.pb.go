@@ -0,0 +1,422 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: snip.proto
+
+package snippb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SnipService_ListSnips_FullMethodName     = "/snip.v1.SnipService/ListSnips"
+	SnipService_GetSnip_FullMethodName       = "/snip.v1.SnipService/GetSnip"
+	SnipService_Search_FullMethodName        = "/snip.v1.SnipService/Search"
+	SnipService_WatchEvents_FullMethodName   = "/snip.v1.SnipService/WatchEvents"
+	SnipService_GetAttachment_FullMethodName = "/snip.v1.SnipService/GetAttachment"
+	SnipService_AddAttachment_FullMethodName = "/snip.v1.SnipService/AddAttachment"
+)
+
+// SnipServiceClient is the client API for SnipService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SnipServiceClient interface {
+	// ListSnips returns the metadata of snips without their data.
+	ListSnips(ctx context.Context, in *ListSnipsRequest, opts ...grpc.CallOption) (*ListSnipsResponse, error)
+	// GetSnip returns a single snip by full or partial uuid.
+	GetSnip(ctx context.Context, in *GetSnipRequest, opts ...grpc.CallOption) (*Snip, error)
+	// Search streams scored index search results, best match first.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (SnipService_SearchClient, error)
+	// WatchEvents streams change events until the client cancels.
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (SnipService_WatchEventsClient, error)
+	// GetAttachment streams an attachment, metadata in the first chunk followed by its data.
+	GetAttachment(ctx context.Context, in *GetAttachmentRequest, opts ...grpc.CallOption) (SnipService_GetAttachmentClient, error)
+	// AddAttachment stores an attachment sent as a stream, metadata in the first chunk.
+	AddAttachment(ctx context.Context, opts ...grpc.CallOption) (SnipService_AddAttachmentClient, error)
+}
+
+type snipServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSnipServiceClient(cc grpc.ClientConnInterface) SnipServiceClient {
+	return &snipServiceClient{cc}
+}
+
+func (c *snipServiceClient) ListSnips(ctx context.Context, in *ListSnipsRequest, opts ...grpc.CallOption) (*ListSnipsResponse, error) {
+	out := new(ListSnipsResponse)
+	err := c.cc.Invoke(ctx, SnipService_ListSnips_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *snipServiceClient) GetSnip(ctx context.Context, in *GetSnipRequest, opts ...grpc.CallOption) (*Snip, error) {
+	out := new(Snip)
+	err := c.cc.Invoke(ctx, SnipService_GetSnip_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *snipServiceClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (SnipService_SearchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SnipService_ServiceDesc.Streams[0], SnipService_Search_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &snipServiceSearchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SnipService_SearchClient interface {
+	Recv() (*SearchMatch, error)
+	grpc.ClientStream
+}
+
+type snipServiceSearchClient struct {
+	grpc.ClientStream
+}
+
+func (x *snipServiceSearchClient) Recv() (*SearchMatch, error) {
+	m := new(SearchMatch)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *snipServiceClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (SnipService_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SnipService_ServiceDesc.Streams[1], SnipService_WatchEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &snipServiceWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SnipService_WatchEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type snipServiceWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *snipServiceWatchEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *snipServiceClient) GetAttachment(ctx context.Context, in *GetAttachmentRequest, opts ...grpc.CallOption) (SnipService_GetAttachmentClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SnipService_ServiceDesc.Streams[2], SnipService_GetAttachment_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &snipServiceGetAttachmentClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SnipService_GetAttachmentClient interface {
+	Recv() (*AttachmentChunk, error)
+	grpc.ClientStream
+}
+
+type snipServiceGetAttachmentClient struct {
+	grpc.ClientStream
+}
+
+func (x *snipServiceGetAttachmentClient) Recv() (*AttachmentChunk, error) {
+	m := new(AttachmentChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *snipServiceClient) AddAttachment(ctx context.Context, opts ...grpc.CallOption) (SnipService_AddAttachmentClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SnipService_ServiceDesc.Streams[3], SnipService_AddAttachment_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &snipServiceAddAttachmentClient{stream}
+	return x, nil
+}
+
+type SnipService_AddAttachmentClient interface {
+	Send(*AttachmentChunk) error
+	CloseAndRecv() (*Attachment, error)
+	grpc.ClientStream
+}
+
+type snipServiceAddAttachmentClient struct {
+	grpc.ClientStream
+}
+
+func (x *snipServiceAddAttachmentClient) Send(m *AttachmentChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *snipServiceAddAttachmentClient) CloseAndRecv() (*Attachment, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Attachment)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SnipServiceServer is the server API for SnipService service.
+// All implementations must embed UnimplementedSnipServiceServer
+// for forward compatibility
+type SnipServiceServer interface {
+	// ListSnips returns the metadata of snips without their data.
+	ListSnips(context.Context, *ListSnipsRequest) (*ListSnipsResponse, error)
+	// GetSnip returns a single snip by full or partial uuid.
+	GetSnip(context.Context, *GetSnipRequest) (*Snip, error)
+	// Search streams scored index search results, best match first.
+	Search(*SearchRequest, SnipService_SearchServer) error
+	// WatchEvents streams change events until the client cancels.
+	WatchEvents(*WatchEventsRequest, SnipService_WatchEventsServer) error
+	// GetAttachment streams an attachment, metadata in the first chunk followed by its data.
+	GetAttachment(*GetAttachmentRequest, SnipService_GetAttachmentServer) error
+	// AddAttachment stores an attachment sent as a stream, metadata in the first chunk.
+	AddAttachment(SnipService_AddAttachmentServer) error
+	mustEmbedUnimplementedSnipServiceServer()
+}
+
+// UnimplementedSnipServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSnipServiceServer struct {
+}
+
+func (UnimplementedSnipServiceServer) ListSnips(context.Context, *ListSnipsRequest) (*ListSnipsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSnips not implemented")
+}
+func (UnimplementedSnipServiceServer) GetSnip(context.Context, *GetSnipRequest) (*Snip, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSnip not implemented")
+}
+func (UnimplementedSnipServiceServer) Search(*SearchRequest, SnipService_SearchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedSnipServiceServer) WatchEvents(*WatchEventsRequest, SnipService_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedSnipServiceServer) GetAttachment(*GetAttachmentRequest, SnipService_GetAttachmentServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetAttachment not implemented")
+}
+func (UnimplementedSnipServiceServer) AddAttachment(SnipService_AddAttachmentServer) error {
+	return status.Errorf(codes.Unimplemented, "method AddAttachment not implemented")
+}
+func (UnimplementedSnipServiceServer) mustEmbedUnimplementedSnipServiceServer() {}
+
+// UnsafeSnipServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SnipServiceServer will
+// result in compilation errors.
+type UnsafeSnipServiceServer interface {
+	mustEmbedUnimplementedSnipServiceServer()
+}
+
+func RegisterSnipServiceServer(s grpc.ServiceRegistrar, srv SnipServiceServer) {
+	s.RegisterService(&SnipService_ServiceDesc, srv)
+}
+
+func _SnipService_ListSnips_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSnipsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnipServiceServer).ListSnips(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SnipService_ListSnips_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnipServiceServer).ListSnips(ctx, req.(*ListSnipsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SnipService_GetSnip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSnipRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnipServiceServer).GetSnip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SnipService_GetSnip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnipServiceServer).GetSnip(ctx, req.(*GetSnipRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SnipService_Search_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SnipServiceServer).Search(m, &snipServiceSearchServer{stream})
+}
+
+type SnipService_SearchServer interface {
+	Send(*SearchMatch) error
+	grpc.ServerStream
+}
+
+type snipServiceSearchServer struct {
+	grpc.ServerStream
+}
+
+func (x *snipServiceSearchServer) Send(m *SearchMatch) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SnipService_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SnipServiceServer).WatchEvents(m, &snipServiceWatchEventsServer{stream})
+}
+
+type SnipService_WatchEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type snipServiceWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *snipServiceWatchEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SnipService_GetAttachment_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetAttachmentRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SnipServiceServer).GetAttachment(m, &snipServiceGetAttachmentServer{stream})
+}
+
+type SnipService_GetAttachmentServer interface {
+	Send(*AttachmentChunk) error
+	grpc.ServerStream
+}
+
+type snipServiceGetAttachmentServer struct {
+	grpc.ServerStream
+}
+
+func (x *snipServiceGetAttachmentServer) Send(m *AttachmentChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SnipService_AddAttachment_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SnipServiceServer).AddAttachment(&snipServiceAddAttachmentServer{stream})
+}
+
+type SnipService_AddAttachmentServer interface {
+	SendAndClose(*Attachment) error
+	Recv() (*AttachmentChunk, error)
+	grpc.ServerStream
+}
+
+type snipServiceAddAttachmentServer struct {
+	grpc.ServerStream
+}
+
+func (x *snipServiceAddAttachmentServer) SendAndClose(m *Attachment) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *snipServiceAddAttachmentServer) Recv() (*AttachmentChunk, error) {
+	m := new(AttachmentChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SnipService_ServiceDesc is the grpc.ServiceDesc for SnipService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SnipService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "snip.v1.SnipService",
+	HandlerType: (*SnipServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListSnips",
+			Handler:    _SnipService_ListSnips_Handler,
+		},
+		{
+			MethodName: "GetSnip",
+			Handler:    _SnipService_GetSnip_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Search",
+			Handler:       _SnipService_Search_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _SnipService_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetAttachment",
+			Handler:       _SnipService_GetAttachment_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "AddAttachment",
+			Handler:       _SnipService_AddAttachment_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "snip.proto",
+}
@@ -0,0 +1,75 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ryanfrishkorn/snip/database"
+	"strings"
+)
+
+// ErrWrite is returned by Query for a statement that would change the database when writing is not allowed
+var ErrWrite = errors.New("statement changes the database")
+
+// QueryResult holds the columns and rows returned by a statement given to Query
+type QueryResult struct {
+	Columns []string
+	// Rows hold int64, float64, string, []byte, or nil values
+	Rows [][]interface{}
+	// Changes is the number of rows changed by a statement that writes
+	Changes int
+}
+
+// Query runs a single sql statement against the database, refusing statements that change it unless write is set
+func Query(sql string, write bool) (QueryResult, error) {
+	var result QueryResult
+
+	if !write {
+		// refuse changes made by any means, such as functions with side effects, not only those apparent from the statement
+		err := database.Conn.Exec(`PRAGMA query_only = ON`)
+		if err != nil {
+			return result, err
+		}
+		defer database.Conn.Exec(`PRAGMA query_only = OFF`)
+	}
+
+	stmt, err := database.Conn.Prepare(sql)
+	if err != nil {
+		return result, err
+	}
+	if stmt == nil {
+		return result, fmt.Errorf("no statement given")
+	}
+	defer stmt.Close()
+
+	if strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(stmt.Tail), ";")) != "" {
+		return result, fmt.Errorf("only a single statement may be given")
+	}
+	if !write && !stmt.ReadOnly() {
+		return result, ErrWrite
+	}
+
+	result.Columns = stmt.ColumnNames()
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return result, err
+		}
+		if !hasRow {
+			break
+		}
+		row := make([]interface{}, len(result.Columns))
+		dst := make([]interface{}, len(row))
+		for idx := range row {
+			dst[idx] = &row[idx]
+		}
+		err = stmt.Scan(dst...)
+		if err != nil {
+			return result, err
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if !stmt.ReadOnly() {
+		result.Changes = database.Conn.Changes()
+	}
+	return result, nil
+}
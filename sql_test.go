@@ -0,0 +1,52 @@
+package snip
+
+import (
+	"errors"
+	"github.com/ryanfrishkorn/snip/database"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	s := New()
+	s.Data = "queried directly"
+	if err := InsertSnip(s, WithName("query test")); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	result, err := Query(`SELECT name, length(data), NULL FROM snip WHERE uuid = '`+s.UUID.String()+`';`, false)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(result.Columns) != 3 || result.Columns[0] != "name" {
+		t.Errorf("expected 3 columns starting with name, got %v", result.Columns)
+	}
+	if len(result.Rows) != 1 || result.Rows[0][0] != "query test" || result.Rows[0][1] != int64(16) || result.Rows[0][2] != nil {
+		t.Errorf("expected the name, length, and null, got %v", result.Rows)
+	}
+
+	if _, err = Query(`DELETE FROM snip WHERE uuid = '`+s.UUID.String()+`'`, false); !errors.Is(err, ErrWrite) {
+		t.Errorf("expected ErrWrite, got %v", err)
+	}
+	if _, err = GetFromUUID(s.UUID.String()); err != nil {
+		t.Errorf("expected the snip to remain after a refused write, got %v", err)
+	}
+	if _, err = Query(`SELECT 1; SELECT 2`, false); err == nil {
+		t.Error("expected error for more than one statement")
+	}
+
+	result, err = Query(`UPDATE snip SET name = 'changed' WHERE uuid = '`+s.UUID.String()+`'`, true)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if result.Changes != 1 {
+		t.Errorf("expected 1 change, got %d", result.Changes)
+	}
+	// the connection is writable once a read-only query is done
+	if _, err = Query(`SELECT 1`, false); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if err = database.Conn.Exec(`UPDATE snip SET name = 'changed again' WHERE uuid = ?`, s.UUID.String()); err != nil {
+		t.Errorf("expected the connection to allow writes after a read-only query, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package snip
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+)
+
+// MaxStars is the highest rating a snip may be given
+const MaxStars = 5
+
+// SetStars rates the quality or usefulness of a snip from 1 to MaxStars
+func SetStars(id uuid.UUID, stars int) error {
+	if stars < 1 || stars > MaxStars {
+		return fmt.Errorf("rating must be from 1 to %d stars", MaxStars)
+	}
+	stmt, err := database.Conn.Prepare(`INSERT OR REPLACE INTO snip_star (uuid, stars) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec(id.String(), stars)
+}
+
+// GetStars returns the rating of a snip, or zero if it has not been rated
+func GetStars(id uuid.UUID) (int, error) {
+	stmt, err := database.Conn.Prepare(`SELECT stars FROM snip_star WHERE uuid = ?`, id.String())
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	hasRow, err := stmt.Step()
+	if err != nil || !hasRow {
+		return 0, err
+	}
+	var stars int
+	err = stmt.Scan(&stars)
+	return stars, err
+}
+
+// ListStars returns the rating of every rated snip
+func ListStars() (map[uuid.UUID]int, error) {
+	ratings := make(map[uuid.UUID]int)
+
+	stmt, err := database.Conn.Prepare(`SELECT uuid, stars FROM snip_star`)
+	if err != nil {
+		return ratings, err
+	}
+	defer stmt.Close()
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return ratings, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr string
+		var stars int
+		err = stmt.Scan(&idStr, &stars)
+		if err != nil {
+			return ratings, err
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return ratings, err
+		}
+		ratings[id] = stars
+	}
+	return ratings, nil
+}
+
+// RemoveStars clears the rating of a snip
+func RemoveStars(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_star WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
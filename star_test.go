@@ -0,0 +1,48 @@
+package snip
+
+import (
+	"testing"
+)
+
+func TestStars(t *testing.T) {
+	s := New()
+	s.Data = "a reference worth rating"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	stars, err := GetStars(s.UUID)
+	if err != nil || stars != 0 {
+		t.Errorf("expected an unrated snip, got %d %v", stars, err)
+	}
+	for _, invalid := range []int{0, MaxStars + 1} {
+		if err = SetStars(s.UUID, invalid); err == nil {
+			t.Errorf("expected error rating %d stars", invalid)
+		}
+	}
+
+	if err = SetStars(s.UUID, 3); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if err = SetStars(s.UUID, 4); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if stars, _ = GetStars(s.UUID); stars != 4 {
+		t.Errorf("expected the rating to be replaced with 4, got %d", stars)
+	}
+	ratings, err := ListStars()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if ratings[s.UUID] != 4 {
+		t.Errorf("expected 4 stars in the listing, got %v", ratings)
+	}
+
+	if err = RemoveStars(s.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if stars, _ = GetStars(s.UUID); stars != 0 {
+		t.Errorf("expected no rating after removal, got %d", stars)
+	}
+}
@@ -0,0 +1,21 @@
+package snip
+
+import (
+	"github.com/ryanfrishkorn/snip/database"
+)
+
+// Stats describes the size of the database
+type Stats struct {
+	Snips       int
+	Attachments int
+	Tags        int
+	// Bytes is the size of the database file, including space freed but not yet reclaimed by vacuum
+	Bytes int64
+}
+
+// GetStats counts the snips, attachments, and distinct tags in the database and measures its size
+func GetStats() (Stats, error) {
+	var stats Stats
+	err := database.QueryRow(`SELECT (SELECT count(*) FROM snip), (SELECT count(*) FROM snip_attachment), (SELECT count(DISTINCT tag) FROM snip_tag), page_count * page_size FROM pragma_page_count(), pragma_page_size()`, nil, &stats.Snips, &stats.Attachments, &stats.Tags, &stats.Bytes)
+	return stats, err
+}
@@ -0,0 +1,33 @@
+package snip
+
+import (
+	"testing"
+)
+
+func TestGetStats(t *testing.T) {
+	before, err := GetStats()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+
+	s := New()
+	s.Data = "counted by the stats"
+	if err = InsertSnip(s, WithTags("stats-test-tag")); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	if err = s.Attach("stats.txt", []byte("attached")); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := GetStats()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if after.Snips != before.Snips+1 || after.Attachments != before.Attachments+1 || after.Tags != before.Tags+1 {
+		t.Errorf("expected one more snip, attachment, and tag than %+v, got %+v", before, after)
+	}
+	if after.Bytes <= 0 {
+		t.Errorf("expected a positive database size, got %d", after.Bytes)
+	}
+}
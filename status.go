@@ -0,0 +1,125 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"strings"
+)
+
+// Status is the progress of a snip kept as a task, empty for a snip that is not one
+type Status string
+
+// Statuses a task moves through
+const (
+	StatusNone  Status = ""
+	StatusTodo  Status = "todo"
+	StatusDoing Status = "doing"
+	StatusDone  Status = "done"
+)
+
+// Statuses are the statuses of tasks in the order they move through them, as the columns of a board
+var Statuses = []Status{StatusTodo, StatusDoing, StatusDone}
+
+// String returns the status, or none for a snip that is not a task
+func (s Status) String() string {
+	if s == StatusNone {
+		return "none"
+	}
+	return string(s)
+}
+
+// ParseStatus reads a status of todo, doing, done, or none
+func ParseStatus(value string) (Status, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "none" {
+		return StatusNone, nil
+	}
+	for _, s := range Statuses {
+		if value == string(s) {
+			return s, nil
+		}
+	}
+	return StatusNone, fmt.Errorf("status %q is not supported (todo|doing|done|none)", value)
+}
+
+// SetStatus records the status of snip id, clearing it for StatusNone
+func SetStatus(id uuid.UUID, status Status) error {
+	if status == StatusNone {
+		return RemoveStatus(id)
+	}
+	return database.Exec(`INSERT OR REPLACE INTO snip_status (uuid, status) VALUES (?, ?)`, id.String(), string(status))
+}
+
+// GetStatus returns the status of snip id, or StatusNone when it has none
+func GetStatus(id uuid.UUID) (Status, error) {
+	var status string
+	err := database.QueryRow(`SELECT status FROM snip_status WHERE uuid = ?`, []interface{}{id.String()}, &status)
+	if errors.Is(err, database.ErrNoRows) {
+		return StatusNone, nil
+	}
+	return Status(status), err
+}
+
+// RemoveStatus clears the status of snip id
+func RemoveStatus(id uuid.UUID) error {
+	return database.Exec(`DELETE FROM snip_status WHERE uuid = ?`, id.String())
+}
+
+// ListStatuses returns the status of every snip that has one
+func ListStatuses() (map[uuid.UUID]Status, error) {
+	statuses := make(map[uuid.UUID]Status)
+	stmt, err := database.Prepare(`SELECT uuid, status FROM snip_status`)
+	if err != nil {
+		return statuses, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return statuses, err
+		}
+		if !hasRow {
+			break
+		}
+		var id uuid.UUID
+		var status string
+		if err = database.Scan(stmt, &id, &status); err != nil {
+			return statuses, err
+		}
+		statuses[id] = Status(status)
+	}
+	return statuses, nil
+}
+
+// StatusSnips returns the uuids of the snips with status, or of those without one for StatusNone
+func StatusSnips(status Status) ([]uuid.UUID, error) {
+	query := `SELECT uuid FROM snip_status WHERE status = ?`
+	args := []interface{}{string(status)}
+	if status == StatusNone {
+		query = `SELECT uuid FROM snip WHERE uuid NOT IN (SELECT uuid FROM snip_status)`
+		args = nil
+	}
+	var ids []uuid.UUID
+	stmt, err := database.Prepare(query, args...)
+	if err != nil {
+		return ids, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return ids, err
+		}
+		if !hasRow {
+			break
+		}
+		var id uuid.UUID
+		if err = database.Scan(stmt, &id); err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
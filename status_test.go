@@ -0,0 +1,84 @@
+package snip
+
+import (
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Status
+		fails    bool
+	}{
+		{"todo", StatusTodo, false},
+		{" Doing", StatusDoing, false},
+		{"DONE", StatusDone, false},
+		{"none", StatusNone, false},
+		{"blocked", StatusNone, true},
+		{"", StatusNone, true},
+	}
+	for _, test := range tests {
+		status, err := ParseStatus(test.value)
+		if (err != nil) != test.fails || status != test.expected {
+			t.Errorf("expected %q to parse as %q (fails: %v), got %q %v", test.value, test.expected, test.fails, status, err)
+		}
+	}
+	if StatusNone.String() != "none" || StatusDoing.String() != "doing" {
+		t.Errorf("expected none and doing, got %s and %s", StatusNone, StatusDoing)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	task, other := New(), New()
+	for _, s := range []Snip{task, other} {
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+	}
+
+	if status, err := GetStatus(task.UUID); err != nil || status != StatusNone {
+		t.Errorf("expected no status, got %q %v", status, err)
+	}
+	if err := SetStatus(task.UUID, StatusTodo); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetStatus(task.UUID, StatusDoing); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := GetStatus(task.UUID); err != nil || status != StatusDoing {
+		t.Errorf("expected doing, got %q %v", status, err)
+	}
+	statuses, err := ListStatuses()
+	if err != nil || statuses[task.UUID] != StatusDoing {
+		t.Errorf("expected the task listed as doing, got %v %v", statuses, err)
+	}
+	if _, ok := statuses[other.UUID]; ok {
+		t.Errorf("expected the other snip to have no status")
+	}
+
+	contains := func(status Status, s Snip) bool {
+		t.Helper()
+		ids, err := StatusSnips(status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range ids {
+			if id == s.UUID {
+				return true
+			}
+		}
+		return false
+	}
+	if !contains(StatusDoing, task) || contains(StatusDoing, other) || !contains(StatusNone, other) || contains(StatusNone, task) {
+		t.Errorf("expected the task among those doing and the other among those without a status")
+	}
+
+	// none clears the status
+	if err = SetStatus(task.UUID, StatusNone); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := GetStatus(task.UUID); err != nil || status != StatusNone {
+		t.Errorf("expected the status cleared, got %q %v", status, err)
+	}
+}
@@ -0,0 +1,115 @@
+package snip
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+)
+
+// Store is the set of snip operations offered by both the local database and a remote server
+type Store interface {
+	// List returns the metadata of snips without their data, limit of zero returns all
+	List(limit int) ([]Snip, error)
+	// ListPage returns a page of snips selected and ordered by opts, and the cursor of the next page, which is empty on the last page
+	ListPage(opts ListOptions) ([]Snip, string, error)
+	// Get returns a single snip by full or partial uuid
+	Get(id string) (Snip, error)
+	// Search returns scored index search results with adjacent words of context
+	Search(terms []string, limit int, adjacent int) ([]SearchMatch, error)
+	// Insert stores and indexes a new snip along with its metadata and tags
+	Insert(s Snip) error
+	// Remove deletes a snip and everything associated with it
+	Remove(id uuid.UUID) error
+	// Attach adds data to a snip and returns the stored attachment
+	Attach(id uuid.UUID, name string, data []byte) (Attachment, error)
+	// GetAttachment returns a single attachment including its data by full or partial uuid
+	GetAttachment(id string) (Attachment, error)
+	// Subscribe returns a channel receiving the events that occur after subscribing, closed once ctx is done
+	Subscribe(ctx context.Context) (<-chan Event, error)
+}
+
+// LocalStore implements Store against the open database, serializing access with database.Mu
+type LocalStore struct{}
+
+// List returns the metadata of snips without their data
+func (LocalStore) List(limit int) ([]Snip, error) {
+	database.Mu.Lock()
+	defer database.Mu.Unlock()
+
+	return ListMetadata(limit)
+}
+
+// ListPage returns a page of snips selected and ordered by opts, and the cursor of the next page
+func (LocalStore) ListPage(opts ListOptions) ([]Snip, string, error) {
+	database.Mu.Lock()
+	defer database.Mu.Unlock()
+
+	return ListPage(opts)
+}
+
+// Get returns a single snip by full or partial uuid
+func (LocalStore) Get(id string) (Snip, error) {
+	database.Mu.Lock()
+	defer database.Mu.Unlock()
+
+	return GetFromUUID(id)
+}
+
+// Search returns scored index search results with context
+func (LocalStore) Search(terms []string, limit int, adjacent int) ([]SearchMatch, error) {
+	database.Mu.Lock()
+	defer database.Mu.Unlock()
+
+	return SearchWithContext(terms, limit, adjacent)
+}
+
+// Insert stores and indexes a new snip along with its metadata and tags
+func (LocalStore) Insert(s Snip) error {
+	database.Mu.Lock()
+	defer database.Mu.Unlock()
+
+	err := InsertSnip(s)
+	if err != nil {
+		return err
+	}
+	for key, value := range s.Meta {
+		err = s.SetMeta(key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return s.Index()
+}
+
+// Remove deletes a snip and everything associated with it
+func (LocalStore) Remove(id uuid.UUID) error {
+	database.Mu.Lock()
+	defer database.Mu.Unlock()
+
+	return Remove(id)
+}
+
+// Attach adds data to a snip and returns the stored attachment
+func (LocalStore) Attach(id uuid.UUID, name string, data []byte) (Attachment, error) {
+	database.Mu.Lock()
+	defer database.Mu.Unlock()
+
+	s, err := GetFromUUID(id.String())
+	if err != nil {
+		return Attachment{}, err
+	}
+	return s.AddAttachment(name, data)
+}
+
+// GetAttachment returns a single attachment including its data by full or partial uuid
+func (LocalStore) GetAttachment(id string) (Attachment, error) {
+	database.Mu.Lock()
+	defer database.Mu.Unlock()
+
+	return GetAttachmentFromUUID(id)
+}
+
+// Subscribe returns a channel receiving the events that occur after subscribing, closed once ctx is done
+func (LocalStore) Subscribe(ctx context.Context) (<-chan Event, error) {
+	return Subscribe(ctx)
+}
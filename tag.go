@@ -0,0 +1,210 @@
+package snip
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tagPattern limits tags to characters that are safe in file names and urls
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}_.-]+$`)
+
+// NormalizeTag returns the lower case form of a tag, or an error if it contains disallowed characters
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !tagPattern.MatchString(tag) || tag == "." || tag == ".." {
+		return tag, fmt.Errorf("tag %q may only contain letters, numbers, and _ . -", tag)
+	}
+	return tag, nil
+}
+
+// GetTags returns the sorted tags of a snip
+func GetTags(id uuid.UUID) ([]string, error) {
+	var tags []string
+
+	stmt, err := database.Conn.Prepare(`SELECT tag FROM snip_tag WHERE uuid = ? ORDER BY tag`, id.String())
+	if err != nil {
+		return tags, err
+	}
+	defer stmt.Close()
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return tags, err
+		}
+		if !hasRow {
+			break
+		}
+		var tag string
+		err = stmt.Scan(&tag)
+		if err != nil {
+			return tags, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// GetTagUUIDs returns the uuids of all snips with a tag
+func GetTagUUIDs(tag string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+
+	stmt, err := database.Conn.Prepare(`SELECT uuid FROM snip_tag WHERE tag = ?`, tag)
+	if err != nil {
+		return ids, err
+	}
+	defer stmt.Close()
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return ids, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr string
+		err = stmt.Scan(&idStr)
+		if err != nil {
+			return ids, err
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ListTags returns every tag in use along with the number of snips it is applied to
+func ListTags() (map[string]int, error) {
+	tags := make(map[string]int)
+
+	stmt, err := database.Conn.Prepare(`SELECT tag, COUNT(*) FROM snip_tag GROUP BY tag`)
+	if err != nil {
+		return tags, err
+	}
+	defer stmt.Close()
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return tags, err
+		}
+		if !hasRow {
+			break
+		}
+		var tag string
+		var count int
+		err = stmt.Scan(&tag, &count)
+		if err != nil {
+			return tags, err
+		}
+		tags[tag] = count
+	}
+	return tags, nil
+}
+
+// ListSnipTags returns the sorted tags of every tagged snip
+func ListSnipTags() (map[uuid.UUID][]string, error) {
+	tags := make(map[uuid.UUID][]string)
+
+	stmt, err := database.Conn.Prepare(`SELECT snip_tag.uuid, snip_tag.tag FROM snip_tag JOIN snip ON snip.uuid = snip_tag.uuid ORDER BY snip_tag.tag`)
+	if err != nil {
+		return tags, err
+	}
+	defer stmt.Close()
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return tags, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr, tag string
+		err = stmt.Scan(&idStr, &tag)
+		if err != nil {
+			return tags, err
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return tags, err
+		}
+		tags[id] = append(tags[id], tag)
+	}
+	return tags, nil
+}
+
+// RemoveTags deletes all tags of a snip
+func RemoveTags(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_tag WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
+
+// AddTag applies a tag to the snip if it is not already present
+func (s *Snip) AddTag(tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	if err = checkLocked(s.UUID); err != nil {
+		return err
+	}
+	for _, existing := range s.Tags {
+		if existing == tag {
+			return nil
+		}
+	}
+
+	stmt, err := database.Conn.Prepare(`INSERT INTO snip_tag (uuid, tag) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM snip_tag WHERE uuid = ? AND tag = ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.Exec(s.UUID.String(), tag, s.UUID.String(), tag)
+	if err != nil {
+		return err
+	}
+	s.Tags = append(s.Tags, tag)
+	sort.Strings(s.Tags)
+	return nil
+}
+
+// RemoveTag removes a tag from the snip
+func (s *Snip) RemoveTag(tag string) error {
+	if err := checkLocked(s.UUID); err != nil {
+		return err
+	}
+	tag = strings.ToLower(strings.TrimSpace(tag))
+
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_tag WHERE uuid = ? AND tag = ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	err = stmt.Exec(s.UUID.String(), tag)
+	if err != nil {
+		return err
+	}
+	for idx, existing := range s.Tags {
+		if existing == tag {
+			s.Tags = append(s.Tags[:idx], s.Tags[idx+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package snip
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	valid := map[string]string{"Go": "go", " how-to ": "how-to", "v1.2_x": "v1.2_x", "café": "café"}
+	for input, expected := range valid {
+		tag, err := NormalizeTag(input)
+		if err != nil {
+			t.Errorf("expected nil err for %q, got %v", input, err)
+		}
+		if tag != expected {
+			t.Errorf("expected %q, got %q", expected, tag)
+		}
+	}
+	for _, input := range []string{"", "two words", "a/b", "..", "<b>"} {
+		if _, err := NormalizeTag(input); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestTags(t *testing.T) {
+	s := New()
+	s.Data = "tag test"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	for _, tag := range []string{"zeta", "Alpha", "zeta"} {
+		if err := s.AddTag(tag); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+	}
+	tags, err := GetTags(s.UUID)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"alpha", "zeta"}) {
+		t.Errorf("expected [alpha zeta], got %v", tags)
+	}
+
+	ids, err := GetTagUUIDs("alpha")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(ids) != 1 || ids[0] != s.UUID {
+		t.Errorf("expected only %s tagged alpha, got %v", s.UUID, ids)
+	}
+
+	if err = s.RemoveTag("alpha"); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	counts, err := ListTags()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if counts["alpha"] != 0 || counts["zeta"] != 1 {
+		t.Errorf("expected only zeta in use, got %v", counts)
+	}
+	all, err := ListSnipTags()
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if !reflect.DeepEqual(all[s.UUID], []string{"zeta"}) {
+		t.Errorf("expected [zeta] listed for %s, got %v", s.UUID, all[s.UUID])
+	}
+}
@@ -0,0 +1,165 @@
+package snip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+)
+
+// ThumbnailSize is the largest width or height in pixels of a thumbnail when no other size is asked for
+const ThumbnailSize = 256
+
+// MaxThumbnailSize is the largest width or height in pixels a thumbnail may be made with
+const MaxThumbnailSize = 1024
+
+// maxThumbnailPixels bounds the images decoded to make thumbnails, so that a small file claiming huge dimensions cannot exhaust memory
+const maxThumbnailPixels = 64 << 20
+
+// ErrNotImage is returned when a thumbnail is asked of an attachment that is not an image
+var ErrNotImage = errors.New("attachment is not an image")
+
+// thumbnailExtensions are the file extensions of the attachments thumbnails are made of
+var thumbnailExtensions = map[string]bool{
+	".gif":  true,
+	".jpeg": true,
+	".jpg":  true,
+	".png":  true,
+}
+
+// Thumbnail is a reduced copy of an image attachment
+type Thumbnail struct {
+	UUID        uuid.UUID
+	Size        int
+	ContentType string
+	Data        []byte
+}
+
+// IsImageAttachment reports whether thumbnails can be made of an attachment with the given name
+func IsImageAttachment(name string) bool {
+	return thumbnailExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// GetThumbnail returns a thumbnail of attachment id no larger than size pixels in either dimension.
+// Thumbnails are made the first time they are asked for and kept in the database, since attachments never change.
+func GetThumbnail(id uuid.UUID, size int) (Thumbnail, error) {
+	t := Thumbnail{UUID: id, Size: size}
+	if size < 1 || size > MaxThumbnailSize {
+		return t, fmt.Errorf("thumbnail size must be from 1 to %d", MaxThumbnailSize)
+	}
+	err := database.QueryRow(`SELECT type, data FROM snip_thumbnail WHERE uuid = ? AND size = ?`, []interface{}{id.String(), size}, &t.ContentType, &t.Data)
+	if err == nil {
+		return t, nil
+	}
+	if !errors.Is(err, database.ErrNoRows) {
+		return t, err
+	}
+
+	var name string
+	var data []byte
+	err = database.QueryRow(`SELECT name, data FROM snip_attachment WHERE uuid = ?`, []interface{}{id.String()}, &name, &data)
+	if err != nil {
+		return t, err
+	}
+	if !IsImageAttachment(name) {
+		return t, ErrNotImage
+	}
+	if data, err = AttachmentData(id, data); err != nil {
+		return t, err
+	}
+	t.ContentType, t.Data, err = MakeThumbnail(data, size)
+	if err != nil {
+		return t, fmt.Errorf("%s: %w", name, err)
+	}
+	err = database.Exec(`INSERT OR REPLACE INTO snip_thumbnail (uuid, size, type, data) VALUES (?, ?, ?, ?)`, id.String(), size, t.ContentType, t.Data)
+	return t, err
+}
+
+// RemoveThumbnails deletes the thumbnails kept of attachment id
+func RemoveThumbnails(id uuid.UUID) error {
+	return database.Exec(`DELETE FROM snip_thumbnail WHERE uuid = ?`, id.String())
+}
+
+// MakeThumbnail scales an encoded gif, jpeg, or png image down to fit within size pixels in either dimension, keeping its proportions,
+// and returns the content type and data of the result. Images with transparency are encoded as png and others as jpeg.
+func MakeThumbnail(data []byte, size int) (string, []byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+	if config.Width*config.Height > maxThumbnailPixels {
+		return "", nil, fmt.Errorf("image of %dx%d pixels is too large for a thumbnail", config.Width, config.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrNotImage, err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, height*size/width
+		} else {
+			width, height = width*size/height, size
+		}
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	thumb := scaleDown(rgba, width, height)
+
+	var buf bytes.Buffer
+	if thumb.Opaque() {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		return "image/jpeg", buf.Bytes(), err
+	}
+	err = png.Encode(&buf, thumb)
+	return "image/png", buf.Bytes(), err
+}
+
+// scaleDown returns src reduced to width by height pixels, each the average of the source pixels it covers
+func scaleDown(src *image.RGBA, width int, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, (y+1)*srcHeight/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, (x+1)*srcWidth/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+			count := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[offset+c] = uint8(sum[c] / count)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,114 @@
+package snip
+
+import (
+	"bytes"
+	"errors"
+	"github.com/ryanfrishkorn/snip/database"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// testImage returns a png of the given size, transparent when alpha is set
+func testImage(t *testing.T, width int, height int, alpha bool) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{R: uint8(x), G: uint8(y), B: 200, A: 255}
+			if alpha && x < width/2 {
+				c.A = 0
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMakeThumbnail(t *testing.T) {
+	tests := []struct {
+		width, height int
+		alpha         bool
+		contentType   string
+		expectedW     int
+		expectedH     int
+	}{
+		{600, 300, false, "image/jpeg", 256, 128},
+		{300, 600, true, "image/png", 128, 256},
+		{40, 20, false, "image/jpeg", 40, 20},
+		{1000, 2, false, "image/jpeg", 256, 1},
+	}
+	for _, test := range tests {
+		contentType, data, err := MakeThumbnail(testImage(t, test.width, test.height, test.alpha), ThumbnailSize)
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		if contentType != test.contentType {
+			t.Errorf("expected %s for %dx%d, got %s", test.contentType, test.width, test.height, contentType)
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Width != test.expectedW || config.Height != test.expectedH {
+			t.Errorf("expected %dx%d, got %dx%d", test.expectedW, test.expectedH, config.Width, config.Height)
+		}
+	}
+
+	if _, _, err := MakeThumbnail([]byte("not an image"), ThumbnailSize); !errors.Is(err, ErrNotImage) {
+		t.Errorf("expected ErrNotImage, got %v", err)
+	}
+}
+
+func TestGetThumbnail(t *testing.T) {
+	s := New()
+	s.Name = "thumbnail test"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	img, err := s.AddAttachment("screenshot.png", testImage(t, 800, 600, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := s.AddAttachment("notes.txt", []byte("plain text"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := GetThumbnail(img.UUID, 100)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if first.ContentType != "image/jpeg" || len(first.Data) == 0 {
+		t.Errorf("expected a jpeg thumbnail, got %s of %d bytes", first.ContentType, len(first.Data))
+	}
+	var count int
+	if err = database.QueryRow(`SELECT COUNT(*) FROM snip_thumbnail WHERE uuid = ?`, []interface{}{img.UUID.String()}, &count); err != nil || count != 1 {
+		t.Errorf("expected the thumbnail kept, got %d %v", count, err)
+	}
+	second, err := GetThumbnail(img.UUID, 100)
+	if err != nil || !bytes.Equal(first.Data, second.Data) {
+		t.Errorf("expected the kept thumbnail, got %v", err)
+	}
+
+	if _, err = GetThumbnail(text.UUID, 100); !errors.Is(err, ErrNotImage) {
+		t.Errorf("expected ErrNotImage, got %v", err)
+	}
+	if _, err = GetThumbnail(img.UUID, MaxThumbnailSize+1); err == nil {
+		t.Errorf("expected an error for a size over %d", MaxThumbnailSize)
+	}
+
+	// thumbnails are removed with their attachment
+	if err = RemoveAttachment(img.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if err = database.QueryRow(`SELECT COUNT(*) FROM snip_thumbnail WHERE uuid = ?`, []interface{}{img.UUID.String()}, &count); err != nil || count != 0 {
+		t.Errorf("expected the thumbnail removed, got %d %v", count, err)
+	}
+}
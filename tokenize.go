@@ -0,0 +1,198 @@
+package snip
+
+import (
+	"fmt"
+	"github.com/rivo/uniseg"
+	"strings"
+	"unicode"
+)
+
+// Tokenizer splits text into the words that are stemmed and written to the search index, and that search terms are matched against
+type Tokenizer interface {
+	Tokens(text string) []string
+}
+
+// IndexTokenizer is the Tokenizer used to index and search snips. The index must be rebuilt with RebuildIndex after it is changed,
+// since snips indexed by one tokenizer are not found by the terms of another.
+var IndexTokenizer Tokenizer = WordTokenizer{}
+
+// NewTokenizer returns the tokenizer named words (the default when empty) or ngram, where n is the length of the n-grams, 2 when zero
+func NewTokenizer(name string, n int) (Tokenizer, error) {
+	switch name {
+	case "", "words":
+		return WordTokenizer{}, nil
+	case "ngram":
+		if n == 0 {
+			n = 2
+		}
+		if n < 1 {
+			return nil, fmt.Errorf("n-gram length must be positive, got %d", n)
+		}
+		return NgramTokenizer{N: n}, nil
+	}
+	return nil, fmt.Errorf("unknown tokenizer %q (words|ngram)", name)
+}
+
+// WordTokenizer splits text at the word boundaries of unicode text segmentation, which separate words with spaces and punctuation.
+// Words joined by punctuation within them, such as config.yaml, err_conn, and don't, are kept whole.
+type WordTokenizer struct{}
+
+// Tokens implements Tokenizer
+func (t WordTokenizer) Tokens(text string) []string {
+	return spanTokens(t.spans(text))
+}
+
+// spans implements spanner
+func (WordTokenizer) spans(text string) []tokenSpan {
+	var spans []tokenSpan
+	var segment string
+	var offset int
+	state := -1
+	for rest := text; len(rest) > 0; offset += len(segment) {
+		segment, rest, state = uniseg.FirstWordInString(rest, state)
+		if isToken(segment) {
+			spans = append(spans, tokenSpan{segment, offset})
+		}
+	}
+	return spans
+}
+
+// isToken reports whether a segment of text is a word, holding letters or digits rather than only spaces, punctuation, or symbols
+func isToken(segment string) bool {
+	return strings.IndexFunc(segment, func(c rune) bool {
+		return unicode.IsLetter(c) || unicode.IsDigit(c)
+	}) >= 0
+}
+
+// NgramTokenizer splits text as WordTokenizer does, except that runs of Chinese, Japanese, and Korean characters become overlapping n-grams of N characters.
+// These languages do not separate words with spaces, so each character would otherwise be a word of its own, or whole phrases a single word.
+// Runs shorter than N are a single token, so search terms shorter than N match only text of the same length.
+type NgramTokenizer struct {
+	N int
+}
+
+// Tokens implements Tokenizer
+func (t NgramTokenizer) Tokens(text string) []string {
+	return spanTokens(t.spans(text))
+}
+
+// spans implements spanner
+func (t NgramTokenizer) spans(text string) []tokenSpan {
+	var spans []tokenSpan
+	var run []rune
+	var runOffsets []int
+	// each run of cjk text ends at the first segment that is not, including spaces and punctuation
+	flush := func() {
+		for idx, gram := range ngrams(run, t.N) {
+			spans = append(spans, tokenSpan{gram, runOffsets[idx]})
+		}
+		run = run[:0]
+		runOffsets = runOffsets[:0]
+	}
+	var segment string
+	var offset int
+	state := -1
+	for rest := text; len(rest) > 0; offset += len(segment) {
+		segment, rest, state = uniseg.FirstWordInString(rest, state)
+		if isCJK(segment) {
+			for idx, c := range segment {
+				run = append(run, c)
+				runOffsets = append(runOffsets, offset+idx)
+			}
+			continue
+		}
+		flush()
+		if isToken(segment) {
+			spans = append(spans, tokenSpan{segment, offset})
+		}
+	}
+	flush()
+	return spans
+}
+
+// tokenSpan is a token and the byte offset at which it begins in the text it was split from
+type tokenSpan struct {
+	token  string
+	offset int
+}
+
+// spanner is implemented by tokenizers that report where each of their tokens begins
+type spanner interface {
+	spans(text string) []tokenSpan
+}
+
+// spanTokens returns the tokens of spans
+func spanTokens(spans []tokenSpan) []string {
+	tokens := make([]string, 0, len(spans))
+	for _, span := range spans {
+		tokens = append(tokens, span.token)
+	}
+	return tokens
+}
+
+// tokenSpans returns the tokens of text split by t along with their offsets.
+// The tokens of other tokenizers are found in the text in turn, and are given the offset of the previous token when they are not found as they are.
+func tokenSpans(t Tokenizer, text string) []tokenSpan {
+	if s, ok := t.(spanner); ok {
+		return s.spans(text)
+	}
+	var spans []tokenSpan
+	var next int
+	for _, token := range t.Tokens(text) {
+		offset := next
+		if idx := strings.Index(text[next:], token); idx >= 0 {
+			offset = next + idx
+			next = offset + len(token)
+		}
+		spans = append(spans, tokenSpan{token, offset})
+	}
+	return spans
+}
+
+// isCJK reports whether text consists of Chinese, Japanese, or Korean characters alone
+func isCJK(text string) bool {
+	for _, c := range text {
+		if !unicode.In(c, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return false
+		}
+	}
+	return text != ""
+}
+
+// ngrams returns the overlapping sequences of n characters in run, or run itself when it is not longer than n
+func ngrams(run []rune, n int) []string {
+	if len(run) == 0 {
+		return nil
+	}
+	if len(run) <= n {
+		return []string{string(run)}
+	}
+	grams := make([]string, 0, len(run)-n+1)
+	for idx := 0; idx+n <= len(run); idx++ {
+		grams = append(grams, string(run[idx:idx+n]))
+	}
+	return grams
+}
+
+// queryTerms splits search terms into the tokens of IndexTokenizer, so that a term holding several words or a run of cjk text requires each of its tokens.
+// Wildcard terms, and terms without any token, are kept as they are.
+func queryTerms(terms []string) []string {
+	var results []string
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		tokens := IndexTokenizer.Tokens(term)
+		if isWildcard(term) {
+			tokens = []string{strings.ToLower(term)}
+		}
+		if len(tokens) == 0 {
+			tokens = []string{term}
+		}
+		for _, token := range tokens {
+			if !seen[token] {
+				seen[token] = true
+				results = append(results, token)
+			}
+		}
+	}
+	return results
+}
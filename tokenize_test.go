@@ -0,0 +1,104 @@
+package snip
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNgramTokenizer(t *testing.T) {
+	tests := []struct {
+		n        int
+		text     string
+		expected []string
+	}{
+		{2, "東京都に住む", []string{"東京", "京都", "都に", "に住", "住む"}},
+		{2, "Tokyo 東京, カタカナ語", []string{"Tokyo", "東京", "カタ", "タカ", "カナ", "ナ語"}},
+		{2, "서울에 살다", []string{"서울", "울에", "살다"}},
+		{3, "東京 は", []string{"東京", "は"}},
+		{2, "plain words only", []string{"plain", "words", "only"}},
+	}
+	for _, tt := range tests {
+		if got := (NgramTokenizer{N: tt.n}).Tokens(tt.text); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("expected %q for %q with n %d, got %q", tt.expected, tt.text, tt.n, got)
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		n    int
+		ok   bool
+	}{{"", 0, true}, {"words", 0, true}, {"ngram", 0, true}, {"ngram", 3, true}, {"ngram", -1, false}, {"stems", 0, false}} {
+		if _, err := NewTokenizer(tt.name, tt.n); (err == nil) != tt.ok {
+			t.Errorf("expected tokenizer %q with n %d to be valid %t, got %v", tt.name, tt.n, tt.ok, err)
+		}
+	}
+}
+
+func TestSearchNgram(t *testing.T) {
+	IndexTokenizer = NgramTokenizer{N: 2}
+	defer func() { IndexTokenizer = WordTokenizer{} }()
+
+	s := New()
+	s.Data = "東京都に住んでいます。Tokyo is home."
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	if err := s.Index(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		terms []string
+		found bool
+	}{
+		{[]string{"東京"}, true},
+		{[]string{"東京都"}, true},
+		{[]string{"住んで", "tokyo"}, true},
+		{[]string{"大阪"}, false},
+		{[]string{"東京", "大阪"}, false},
+	}
+	for _, tt := range tests {
+		matches, err := SearchWithContext(tt.terms, 0, 2)
+		if err != nil {
+			t.Fatalf("expected nil err searching %q, got %v", tt.terms, err)
+		}
+		var found bool
+		for _, m := range matches {
+			if m.UUID == s.UUID {
+				found = true
+				if len(m.Context) == 0 {
+					t.Errorf("expected context for %q", tt.terms)
+				}
+			}
+		}
+		if found != tt.found {
+			t.Errorf("expected %q found %t, got %t", tt.terms, tt.found, found)
+		}
+	}
+}
+
+// fieldsTokenizer splits text at spaces, lacking the offsets of the built in tokenizers
+type fieldsTokenizer struct{}
+
+func (fieldsTokenizer) Tokens(text string) []string {
+	return strings.Fields(text)
+}
+
+func TestTokenSpans(t *testing.T) {
+	tests := []struct {
+		tokenizer Tokenizer
+		text      string
+		expected  []tokenSpan
+	}{
+		{WordTokenizer{}, "see config.yaml, now", []tokenSpan{{"see", 0}, {"config.yaml", 4}, {"now", 17}}},
+		{NgramTokenizer{N: 2}, "at 東京都", []tokenSpan{{"at", 0}, {"東京", 3}, {"京都", 6}}},
+		{fieldsTokenizer{}, "aa a  b", []tokenSpan{{"aa", 0}, {"a", 3}, {"b", 6}}},
+	}
+	for _, tt := range tests {
+		if spans := tokenSpans(tt.tokenizer, tt.text); !reflect.DeepEqual(spans, tt.expected) {
+			t.Errorf("expected %v for %q, got %v", tt.expected, tt.text, spans)
+		}
+	}
+}
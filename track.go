@@ -0,0 +1,175 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"sort"
+	"time"
+)
+
+// ErrNotTracking is returned when stopping time tracking that is not running
+var ErrNotTracking = errors.New("time is not being tracked")
+
+// Interval is a span of time worked on a snip, such as a task or a client project
+type Interval struct {
+	ID    int64
+	UUID  uuid.UUID
+	Start time.Time
+	// Stop is zero while the interval is running
+	Stop time.Time
+}
+
+// Running reports whether time is still being tracked in the interval
+func (i Interval) Running() bool {
+	return i.Stop.IsZero()
+}
+
+// Duration returns the length of the interval, up until now while it is running
+func (i Interval) Duration(now time.Time) time.Duration {
+	if i.Running() {
+		return now.Sub(i.Start)
+	}
+	return i.Stop.Sub(i.Start)
+}
+
+// StartTracking starts an interval of time worked on snip id at now. Work is tracked on one snip at a time,
+// so any running interval is stopped first and returned.
+func StartTracking(id uuid.UUID, now time.Time) ([]Interval, error) {
+	var stopped []Interval
+	err := database.Conn.WithTx(func() error {
+		var err error
+		stopped, err = queryIntervals(`SELECT id, uuid, start, stop FROM snip_track WHERE stop = '' ORDER BY id`)
+		if err != nil {
+			return err
+		}
+		for idx := range stopped {
+			if err = stopInterval(&stopped[idx], now); err != nil {
+				return err
+			}
+		}
+		return database.Exec(`INSERT INTO snip_track (uuid, start, stop) VALUES (?, ?, '')`, id.String(), now.UTC().Format(sortableLayout))
+	})
+	return stopped, err
+}
+
+// StopTracking stops the running interval of snip id at now, or the one running on any snip when id is uuid.Nil,
+// returning an error wrapping ErrNotTracking when there is none
+func StopTracking(id uuid.UUID, now time.Time) (Interval, error) {
+	query := `SELECT id, uuid, start, stop FROM snip_track WHERE stop = '' ORDER BY id`
+	var args []interface{}
+	if id != uuid.Nil {
+		query = `SELECT id, uuid, start, stop FROM snip_track WHERE stop = '' AND uuid = ? ORDER BY id`
+		args = append(args, id.String())
+	}
+	running, err := queryIntervals(query, args...)
+	if err != nil {
+		return Interval{}, err
+	}
+	if len(running) == 0 {
+		if id == uuid.Nil {
+			return Interval{}, ErrNotTracking
+		}
+		return Interval{}, fmt.Errorf("%w on %s", ErrNotTracking, id)
+	}
+	i := running[0]
+	return i, stopInterval(&i, now)
+}
+
+// stopInterval records the end of interval i at now, which is never before its start
+func stopInterval(i *Interval, now time.Time) error {
+	i.Stop = now
+	if now.Before(i.Start) {
+		i.Stop = i.Start
+	}
+	return database.Exec(`UPDATE snip_track SET stop = ? WHERE id = ?`, i.Stop.UTC().Format(sortableLayout), i.ID)
+}
+
+// RunningInterval returns the interval being tracked, if any
+func RunningInterval() (Interval, bool, error) {
+	running, err := queryIntervals(`SELECT id, uuid, start, stop FROM snip_track WHERE stop = '' ORDER BY id LIMIT 1`)
+	if err != nil || len(running) == 0 {
+		return Interval{}, false, err
+	}
+	return running[0], true, nil
+}
+
+// ListIntervals returns the intervals that overlap the time from from until until, oldest first
+func ListIntervals(from time.Time, until time.Time) ([]Interval, error) {
+	return queryIntervals(`SELECT id, uuid, start, stop FROM snip_track WHERE start < ? AND (stop = '' OR stop > ?) ORDER BY start, id`,
+		until.UTC().Format(sortableLayout), from.UTC().Format(sortableLayout))
+}
+
+// RemoveIntervals deletes the time tracked on a snip
+func RemoveIntervals(id uuid.UUID) error {
+	return database.Exec(`DELETE FROM snip_track WHERE uuid = ?`, id.String())
+}
+
+// TrackedTime returns the time tracked on each snip from from until until, counting only the part of each interval within it
+// and running intervals up until now, ordered by the most time tracked
+func TrackedTime(from time.Time, until time.Time, now time.Time) (map[uuid.UUID]time.Duration, []uuid.UUID, error) {
+	intervals, err := ListIntervals(from, until)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracked := make(map[uuid.UUID]time.Duration)
+	var ids []uuid.UUID
+	for _, i := range intervals {
+		start, stop := i.Start, i.Stop
+		if i.Running() {
+			stop = now
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if stop.After(until) {
+			stop = until
+		}
+		if !stop.After(start) {
+			continue
+		}
+		if _, ok := tracked[i.UUID]; !ok {
+			ids = append(ids, i.UUID)
+		}
+		tracked[i.UUID] += stop.Sub(start)
+	}
+	sort.SliceStable(ids, func(a, b int) bool {
+		return tracked[ids[a]] > tracked[ids[b]]
+	})
+	return tracked, ids, nil
+}
+
+// queryIntervals returns the intervals of the rows of id, uuid, start, and stop returned by query
+func queryIntervals(query string, args ...interface{}) ([]Interval, error) {
+	var intervals []Interval
+	stmt, err := database.Prepare(query, args...)
+	if err != nil {
+		return intervals, err
+	}
+	defer database.Release(stmt)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return intervals, err
+		}
+		if !hasRow {
+			break
+		}
+		var i Interval
+		var start, stop string
+		if err = database.Scan(stmt, &i.ID, &i.UUID, &start, &stop); err != nil {
+			return intervals, err
+		}
+		if i.Start, err = time.Parse(sortableLayout, start); err != nil {
+			return intervals, err
+		}
+		if stop != "" {
+			if i.Stop, err = time.Parse(sortableLayout, stop); err != nil {
+				return intervals, err
+			}
+		}
+		intervals = append(intervals, i)
+	}
+	return intervals, nil
+}
@@ -0,0 +1,64 @@
+package snip
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestTracking(t *testing.T) {
+	first, second := New(), New()
+	defer RemoveIntervals(first.UUID)
+	defer RemoveIntervals(second.UUID)
+	start := time.Date(2031, 3, 3, 9, 0, 0, 0, time.UTC)
+
+	if _, err := StartTracking(first.UUID, start); err != nil {
+		t.Fatal(err)
+	}
+	running, ok, err := RunningInterval()
+	if err != nil || !ok || running.UUID != first.UUID || !running.Start.Equal(start) {
+		t.Fatalf("expected the first snip to be tracked, got %+v %v %v", running, ok, err)
+	}
+	// starting on another snip stops the first
+	stopped, err := StartTracking(second.UUID, start.Add(90*time.Minute))
+	if err != nil || len(stopped) != 1 || stopped[0].UUID != first.UUID || stopped[0].Duration(time.Time{}) != 90*time.Minute {
+		t.Fatalf("expected the first snip to be stopped after 90m, got %+v %v", stopped, err)
+	}
+	if _, err = StopTracking(first.UUID, start.Add(2*time.Hour)); !errors.Is(err, ErrNotTracking) {
+		t.Errorf("expected ErrNotTracking stopping a snip not tracked, got %v", err)
+	}
+	i, err := StopTracking(uuid.Nil, start.Add(2*time.Hour))
+	if err != nil || i.UUID != second.UUID || i.Duration(time.Time{}) != 30*time.Minute {
+		t.Fatalf("expected the second snip to be stopped after 30m, got %+v %v", i, err)
+	}
+	if _, ok, _ = RunningInterval(); ok {
+		t.Errorf("expected nothing to be tracked")
+	}
+
+	// an interval running past midnight counts toward each day, and a running one up until now
+	if _, err = StartTracking(first.UUID, start.Add(14*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = StopTracking(first.UUID, start.Add(16*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = StartTracking(second.UUID, start.Add(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2031, 3, 3, 0, 0, 0, 0, time.UTC)
+	tracked, ids, err := TrackedTime(day, day.AddDate(0, 0, 1), start.Add(25*time.Hour))
+	if err != nil || len(ids) != 2 || ids[0] != first.UUID {
+		t.Fatalf("expected both snips ordered by time tracked, got %v %v", ids, err)
+	}
+	if tracked[first.UUID] != 2*time.Hour+30*time.Minute || tracked[second.UUID] != 30*time.Minute {
+		t.Errorf("expected 2h30m and 30m on the day, got %v", tracked)
+	}
+	tracked, _, err = TrackedTime(day.AddDate(0, 0, 1), day.AddDate(0, 0, 2), start.Add(25*time.Hour))
+	if err != nil || tracked[first.UUID] != time.Hour || tracked[second.UUID] != time.Hour {
+		t.Errorf("expected 1h of each on the next day, got %v %v", tracked, err)
+	}
+	if _, err = StopTracking(second.UUID, start.Add(25*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+}
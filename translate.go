@@ -0,0 +1,65 @@
+package snip
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"regexp"
+	"strings"
+)
+
+// Metadata keys linking a translation to the snip it was translated from
+const (
+	// MetaTranslatedFrom is the snip:// link of the snip a translation was made from
+	MetaTranslatedFrom = "translated_from"
+	// MetaLanguage is the language code of the text of a snip, such as de or pt-BR
+	MetaLanguage = "language"
+)
+
+// languageCode is a language code in the form of BCP 47, such as de, fil, or pt-BR
+var languageCode = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// NormalizeLanguageCode returns a language code such as DE or pt-br in its usual form de or pt-BR, or an error when it is not one
+func NormalizeLanguageCode(code string) (string, error) {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "_", "-"))
+	if !languageCode.MatchString(code) {
+		return "", fmt.Errorf("%q is not a language code such as de or pt-BR", code)
+	}
+	parts := strings.Split(code, "-")
+	for idx, part := range parts[1:] {
+		// regions are written in capitals and scripts such as Latn with one
+		switch len(part) {
+		case 2:
+			parts[idx+1] = strings.ToUpper(part)
+		case 4:
+			parts[idx+1] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// NewTranslation returns a snip holding data, the text of s translated into lang, which shares the tags of s and links to it by its metadata
+func NewTranslation(s Snip, lang string, data string) Snip {
+	t := New()
+	t.Name = fmt.Sprintf("%s (%s)", s.Name, lang)
+	t.Data = data
+	t.Tags = append([]string(nil), s.Tags...)
+	t.Meta = map[string]string{
+		MetaTranslatedFrom: SnipLinkScheme + s.UUID.String(),
+		MetaLanguage:       lang,
+	}
+	return t
+}
+
+// FindTranslation returns the uuid of the translation of snip id into lang, or uuid.Nil when it has none
+func FindTranslation(id uuid.UUID, lang string) (uuid.UUID, error) {
+	var found uuid.UUID
+	err := database.QueryRow(`SELECT f.uuid FROM snip_meta f JOIN snip_meta l ON l.uuid = f.uuid `+
+		`WHERE f.key = ? AND f.value = ? AND l.key = ? AND l.value = ? ORDER BY f.rowid LIMIT 1`,
+		[]interface{}{MetaTranslatedFrom, SnipLinkScheme + id.String(), MetaLanguage, lang}, &found)
+	if errors.Is(err, database.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	return found, err
+}
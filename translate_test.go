@@ -0,0 +1,54 @@
+package snip
+
+import (
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestNormalizeLanguageCode(t *testing.T) {
+	for code, expected := range map[string]string{
+		"de":         "de",
+		"DE":         "de",
+		"pt_br":      "pt-BR",
+		"zh-hant-tw": "zh-Hant-TW",
+	} {
+		if got, err := NormalizeLanguageCode(code); err != nil || got != expected {
+			t.Errorf("%s: expected %s, got %s %v", code, expected, got, err)
+		}
+	}
+	for _, code := range []string{"", "german", "d", "de--at"} {
+		if _, err := NormalizeLanguageCode(code); err == nil {
+			t.Errorf("%s: expected an error", code)
+		}
+	}
+}
+
+func TestTranslation(t *testing.T) {
+	s := New()
+	s.Name = "greeting"
+	s.Data = "good morning"
+	s.Tags = []string{"phrases"}
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	if found, err := FindTranslation(s.UUID, "de"); err != nil || found != uuid.Nil {
+		t.Errorf("expected no translation, got %s %v", found, err)
+	}
+	translation := NewTranslation(s, "de", "guten Morgen")
+	if translation.Name != "greeting (de)" || len(translation.Tags) != 1 || translation.Meta[MetaTranslatedFrom] != "snip://"+s.UUID.String() {
+		t.Errorf("expected a translation linked to the snip, got %+v", translation)
+	}
+	if err := (LocalStore{}).Insert(translation); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(translation.UUID)
+
+	if found, err := FindTranslation(s.UUID, "de"); err != nil || found != translation.UUID {
+		t.Errorf("expected the translation %s, got %s %v", translation.UUID, found, err)
+	}
+	if found, err := FindTranslation(s.UUID, "fr"); err != nil || found != uuid.Nil {
+		t.Errorf("expected no translation into fr, got %s %v", found, err)
+	}
+}
@@ -0,0 +1,170 @@
+package snip
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"strings"
+)
+
+// EnableTrigrams creates the trigram index and fills it from the data of every snip, reporting each snip indexed to progress.
+// The index records every sequence of three characters in the data of each snip, so that snips containing a substring such as err_conn are found
+// without reading the data of every snip. It is optional since it is several times the size of the term index.
+// Once enabled, it is kept up to date as snips are indexed and removed.
+func EnableTrigrams(progress Progress) error {
+	err := database.Exec(`CREATE TABLE IF NOT EXISTS snip_trigram(trigram TEXT, uuid TEXT)`)
+	if err != nil {
+		return err
+	}
+	err = database.Exec(`CREATE INDEX IF NOT EXISTS snip_trigram_trigram ON snip_trigram(trigram)`)
+	if err != nil {
+		return err
+	}
+	err = database.Exec(`CREATE INDEX IF NOT EXISTS snip_trigram_uuid ON snip_trigram(uuid)`)
+	if err != nil {
+		return err
+	}
+
+	ids, err := GetAllSnipIDs()
+	if err != nil {
+		return err
+	}
+	return database.Conn.WithTx(func() error {
+		if err := database.Exec(`DELETE FROM snip_trigram`); err != nil {
+			return err
+		}
+		for idx, id := range ids {
+			s, err := GetFromUUID(id.String())
+			if err != nil {
+				return err
+			}
+			if err = writeTrigrams("main", s.UUID, s.Data); err != nil {
+				return err
+			}
+			reportStep(progress, "indexing trigrams", idx+1, len(ids))
+		}
+		return nil
+	})
+}
+
+// DisableTrigrams removes the trigram index, after which substring searches read the data of every snip
+func DisableTrigrams() error {
+	return database.Exec(`DROP TABLE IF EXISTS snip_trigram`)
+}
+
+// TrigramsEnabled reports whether the trigram index has been enabled
+func TrigramsEnabled() (bool, error) {
+	return trigramsEnabled("main")
+}
+
+// trigramsEnabled reports whether the database attached as schema has a trigram index
+func trigramsEnabled(schema string) (bool, error) {
+	var count int
+	err := database.QueryRow(`SELECT count(*) FROM `+schema+`.sqlite_master WHERE type = 'table' AND name = 'snip_trigram'`, nil, &count)
+	return count > 0, err
+}
+
+// trigrams returns the distinct sequences of three characters in the lower case of text, in the order they first appear
+func trigrams(text string) []string {
+	runes := []rune(strings.ToLower(text))
+	seen := make(map[string]bool)
+	var results []string
+	for idx := 0; idx+3 <= len(runes); idx++ {
+		trigram := string(runes[idx : idx+3])
+		if !seen[trigram] {
+			seen[trigram] = true
+			results = append(results, trigram)
+		}
+	}
+	return results
+}
+
+// writeTrigrams replaces the trigrams of snip id in the database attached as schema with those of data
+func writeTrigrams(schema string, id uuid.UUID, data string) error {
+	err := database.Exec(`DELETE FROM `+schema+`.snip_trigram WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	list, err := json.Marshal(trigrams(data))
+	if err != nil {
+		return err
+	}
+	return database.Exec(`INSERT INTO `+schema+`.snip_trigram (trigram, uuid) SELECT value, ? FROM json_each(?)`, id.String(), string(list))
+}
+
+// IndexTrigrams updates the trigrams of the snip when the trigram index is enabled, as Index does after writing its terms
+func (s *Snip) IndexTrigrams() error {
+	enabled, err := TrigramsEnabled()
+	if err != nil || !enabled {
+		return err
+	}
+	return writeTrigrams("main", s.UUID, s.Data)
+}
+
+// removeTrigrams removes the trigrams of snip id, or those of every snip when id is nil, when the trigram index is enabled
+func removeTrigrams(id uuid.UUID) error {
+	enabled, err := TrigramsEnabled()
+	if err != nil || !enabled {
+		return err
+	}
+	if id == uuid.Nil {
+		return database.Exec(`DELETE FROM snip_trigram`)
+	}
+	return database.Exec(`DELETE FROM snip_trigram WHERE uuid = ?`, id.String())
+}
+
+// SearchSubstring returns the unexpired snips whose data contains substr, ignoring case, with up to limit results when limit is positive.
+// The trigram index narrows the snips read when it is enabled and substr has at least three characters, and otherwise the data of every snip is read.
+func SearchSubstring(substr string, limit int) ([]Snip, error) {
+	var results []Snip
+	if substr == "" {
+		return results, fmt.Errorf("refusing to search for empty string")
+	}
+	query := `SELECT uuid, timestamp, name, data FROM snip WHERE ` + unexpired
+	args := []interface{}{sortableNow()}
+
+	enabled, err := TrigramsEnabled()
+	if err != nil {
+		return results, err
+	}
+	needed := trigrams(substr)
+	if enabled && len(needed) > 0 {
+		list, err := json.Marshal(needed)
+		if err != nil {
+			return results, err
+		}
+		// candidates have every trigram of substr, though not necessarily in sequence, so their data is still checked
+		query += ` AND uuid IN (SELECT uuid FROM snip_trigram WHERE trigram IN (SELECT value FROM json_each(?)) GROUP BY uuid HAVING count(*) = ?)`
+		args = append(args, string(list), len(needed))
+	}
+	stmt, err := database.Prepare(query+` ORDER BY rowid`, args...)
+	if err != nil {
+		return results, err
+	}
+	defer database.Release(stmt)
+
+	lower := strings.ToLower(substr)
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return results, err
+		}
+		if !hasRow {
+			return results, nil
+		}
+		var s Snip
+		var data []byte
+		if err = database.Scan(stmt, &s.UUID, &s.Timestamp, &s.Name, &data); err != nil {
+			return results, err
+		}
+		s.Data = string(data)
+		if !strings.Contains(strings.ToLower(s.Data), lower) {
+			continue
+		}
+		results = append(results, s)
+		if limit > 0 && len(results) == limit {
+			return results, nil
+		}
+	}
+}
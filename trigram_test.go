@@ -0,0 +1,100 @@
+package snip
+
+import (
+	"bytes"
+	"github.com/ryanfrishkorn/snip/database"
+	"reflect"
+	"testing"
+)
+
+func TestTrigrams(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"ab", nil},
+		{"Err_Conn", []string{"err", "rr_", "r_c", "_co", "con", "onn"}},
+		{"aaaa", []string{"aaa"}},
+		{"日本語です", []string{"日本語", "本語で", "語です"}},
+	}
+	for _, tt := range tests {
+		if got := trigrams(tt.text); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("expected trigrams %q of %q, got %q", tt.expected, tt.text, got)
+		}
+	}
+}
+
+func TestSearchSubstring(t *testing.T) {
+	s := New()
+	s.Data = "dial failed: ERR_CONN_REFUSED on port 5432"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+	// the trigrams of the substring in another order do not match
+	other := New()
+	other.Data = "conn err refused"
+	if err := InsertSnip(other); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(other.UUID)
+
+	search := func(substr string) []Snip {
+		t.Helper()
+		results, err := SearchSubstring(substr, 0)
+		if err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+		return results
+	}
+	for _, enabled := range []bool{false, true} {
+		if enabled {
+			if err := EnableTrigrams(nil); err != nil {
+				t.Fatalf("expected nil err, got %v", err)
+			}
+			defer DisableTrigrams()
+		}
+		for _, substr := range []string{"err_conn", "5432", "N_R", "d:"} {
+			if results := search(substr); len(results) != 1 || results[0].UUID != s.UUID {
+				t.Errorf("expected %q to find only %s with trigrams enabled %t, got %v", substr, s.UUID, enabled, results)
+			}
+		}
+		if results := search("conn_err"); len(results) != 0 {
+			t.Errorf("expected no results with trigrams enabled %t, got %v", enabled, results)
+		}
+	}
+
+	// the index follows changes made once it is enabled
+	s.Data = "now about ECONNRESET"
+	if err := s.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveIndex(s.UUID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Index(); err != nil {
+		t.Fatal(err)
+	}
+	if results := search("err_conn"); len(results) != 0 {
+		t.Errorf("expected the old data not to be found, got %v", results)
+	}
+	if results := search("connreset"); len(results) != 1 {
+		t.Errorf("expected the new data to be found, got %v", results)
+	}
+	var rows int
+	if err := database.QueryRow(`SELECT count(*) FROM snip_trigram WHERE uuid = ?`, []interface{}{s.UUID.String()}, &rows); err != nil || rows == 0 {
+		t.Errorf("expected trigrams of the changed snip, got %d rows and err %v", rows, err)
+	}
+
+	// importing an index file keeps the trigrams
+	var buf bytes.Buffer
+	if err := ExportIndex(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportIndex(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if results := search("connreset"); len(results) != 1 {
+		t.Errorf("expected the data to be found after importing the index, got %v", results)
+	}
+}
@@ -0,0 +1,98 @@
+package snip
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ryanfrishkorn/snip/database"
+	"time"
+)
+
+// Version is earlier data of a snip, kept when the data is replaced
+type Version struct {
+	UUID uuid.UUID
+	// Number counts the versions of a snip from 1, the oldest
+	Number int
+	// Timestamp is when the data was replaced
+	Timestamp time.Time
+	Name      string
+	Data      string
+}
+
+// saveVersion keeps the stored data of a snip as its next version if it differs from data
+func saveVersion(id uuid.UUID, data []byte) error {
+	stmt, err := database.Conn.Prepare(`INSERT INTO snip_version (uuid, version, timestamp, name, data)
+		SELECT uuid, (SELECT coalesce(max(version), 0) + 1 FROM snip_version WHERE uuid = ?), ?, name, CAST(data AS BLOB)
+		FROM snip WHERE uuid = ? AND CAST(data AS BLOB) != ?`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec(id.String(), time.Now().Format(time.RFC3339Nano), id.String(), data)
+}
+
+// GetVersions returns the earlier versions of a snip, oldest first
+func GetVersions(id uuid.UUID) ([]Version, error) {
+	return queryVersions(`SELECT uuid, version, timestamp, name, data FROM snip_version WHERE uuid = ? ORDER BY version`, id.String())
+}
+
+// GetVersion returns version number n of a snip
+func GetVersion(id uuid.UUID, n int) (Version, error) {
+	versions, err := queryVersions(`SELECT uuid, version, timestamp, name, data FROM snip_version WHERE uuid = ? AND version = ?`, id.String(), n)
+	if err != nil {
+		return Version{}, err
+	}
+	if len(versions) == 0 {
+		return Version{}, fmt.Errorf("snip %s has no version %d", id, n)
+	}
+	return versions[0], nil
+}
+
+// RemoveVersions deletes the earlier versions of a snip
+func RemoveVersions(id uuid.UUID) error {
+	stmt, err := database.Conn.Prepare(`DELETE FROM snip_version WHERE uuid = ?`, id.String())
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.Exec()
+}
+
+// queryVersions returns the version in each row returned by query
+func queryVersions(query string, args ...interface{}) ([]Version, error) {
+	stmt, err := database.Conn.Prepare(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var versions []Version
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return versions, err
+		}
+		if !hasRow {
+			break
+		}
+		var idStr, timestamp string
+		var data []byte
+		v := Version{}
+		err = stmt.Scan(&idStr, &v.Number, &timestamp, &v.Name, &data)
+		if err != nil {
+			return versions, err
+		}
+		v.Data = string(data)
+		v.UUID, err = uuid.Parse(idStr)
+		if err != nil {
+			return versions, err
+		}
+		v.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return versions, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
@@ -0,0 +1,50 @@
+package snip
+
+import (
+	"testing"
+)
+
+func TestVersions(t *testing.T) {
+	s := New()
+	s.Data = "first draft\n"
+	if err := InsertSnip(s); err != nil {
+		t.Fatal(err)
+	}
+	defer Remove(s.UUID)
+
+	// renaming keeps the data, so no version is kept
+	if err := s.Rename("renamed"); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	for _, data := range []string{"second draft\n", "final\n"} {
+		s.Data = data
+		if err := s.Update(); err != nil {
+			t.Fatalf("expected nil err, got %v", err)
+		}
+	}
+
+	versions, err := GetVersions(s.UUID)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %v", versions)
+	}
+	if versions[0].Number != 1 || versions[0].Data != "first draft\n" || versions[1].Number != 2 || versions[1].Data != "second draft\n" {
+		t.Errorf("expected the drafts oldest first, got %v", versions)
+	}
+	v, err := GetVersion(s.UUID, 2)
+	if err != nil || v.Data != "second draft\n" || v.Name != "renamed" {
+		t.Errorf("expected the second draft, got %v %v", v, err)
+	}
+	if _, err = GetVersion(s.UUID, 3); err == nil {
+		t.Error("expected error for a missing version")
+	}
+
+	if err = Remove(s.UUID); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if versions, _ = GetVersions(s.UUID); len(versions) != 0 {
+		t.Errorf("expected versions removed with the snip, got %v", versions)
+	}
+}
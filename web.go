@@ -0,0 +1,367 @@
+package snip
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Article represents the readable content extracted from a web page
+type Article struct {
+	Title string
+	Text  string
+	HTML  []byte
+	URL   string
+}
+
+// skipped elements never contain readable article text
+var articleSkipAtoms = map[atom.Atom]bool{
+	atom.Aside:    true,
+	atom.Button:   true,
+	atom.Footer:   true,
+	atom.Form:     true,
+	atom.Head:     true,
+	atom.Header:   true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Noscript: true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+}
+
+// block elements are separated by blank lines when rendered as text
+var articleBlockAtoms = map[atom.Atom]bool{
+	atom.Blockquote: true,
+	atom.Dd:         true,
+	atom.Div:        true,
+	atom.Dt:         true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Li:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Tr:         true,
+}
+
+// FetchArticle retrieves the page at url and extracts the main article text
+func FetchArticle(url string) (Article, error) {
+	a := Article{URL: url}
+
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return a, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return a, fmt.Errorf("fetching %s returned status %s", url, resp.Status)
+	}
+
+	a.HTML, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return a, err
+	}
+
+	a.Title, a.Text, err = ExtractArticle(a.HTML)
+	if err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// ExtractArticle returns the title and readable text of the main content in an html document
+func ExtractArticle(data []byte) (string, string, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", "", err
+	}
+
+	title := articleTitle(doc)
+
+	// prefer explicit article markup, falling back to the best scoring container
+	var best *html.Node
+	bestScore := 0
+	walkNodes(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		if articleSkipAtoms[n.DataAtom] {
+			return false
+		}
+		var score int
+		switch n.DataAtom {
+		case atom.Article, atom.Main:
+			// weight explicit markup heavily so that it wins over generic containers
+			score = len(nodeText(n)) * 2
+		default:
+			// score containers by the paragraphs they directly contain
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.DataAtom == atom.P || c.DataAtom == atom.Pre) {
+					text := nodeText(c)
+					score += len(text) + strings.Count(text, ",")*10
+				}
+			}
+		}
+		if score > bestScore {
+			best = n
+			bestScore = score
+		}
+		return true
+	})
+	if best == nil {
+		best = findNode(doc, atom.Body)
+	}
+	if best == nil {
+		return title, "", fmt.Errorf("no readable content found")
+	}
+
+	var b strings.Builder
+	renderText(&b, best)
+	text := strings.TrimSpace(squeezeBlankLines(b.String()))
+
+	// use the first heading if the document has no title
+	if title == "" {
+		if h := findNode(best, atom.H1); h != nil {
+			title = strings.TrimSpace(FlattenString(nodeText(h)))
+		}
+	}
+	return title, text, nil
+}
+
+// articleTitle returns the og:title or title element of a document
+func articleTitle(doc *html.Node) string {
+	var title string
+	walkNodes(doc, func(n *html.Node) bool {
+		if title != "" {
+			return false
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Meta {
+			var property, content string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "property", "name":
+					property = attr.Val
+				case "content":
+					content = attr.Val
+				}
+			}
+			if property == "og:title" {
+				title = content
+			}
+		}
+		return true
+	})
+	if title == "" {
+		if n := findNode(doc, atom.Title); n != nil {
+			title = nodeText(n)
+		}
+	}
+	return strings.TrimSpace(FlattenString(title))
+}
+
+// findNode returns the first element of the given type in depth first order
+func findNode(root *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walkNodes(root, func(n *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if n.Type == html.ElementNode && n.DataAtom == a {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// nodeText returns all text contained in a node excluding skipped elements
+func nodeText(root *html.Node) string {
+	var b strings.Builder
+	walkNodes(root, func(n *html.Node) bool {
+		if n.Type == html.ElementNode && articleSkipAtoms[n.DataAtom] {
+			return false
+		}
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		return true
+	})
+	return b.String()
+}
+
+// renderText writes readable text from a node, separating blocks with blank lines
+func renderText(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if insidePre(n) {
+			b.WriteString(n.Data)
+			return
+		}
+		text := FlattenString(n.Data)
+		// avoid leading whitespace at the start of a line
+		if b.Len() == 0 || strings.HasSuffix(b.String(), "\n") || strings.HasSuffix(b.String(), "\n- ") {
+			text = strings.TrimLeft(text, " ")
+		}
+		b.WriteString(text)
+		return
+	case html.ElementNode:
+		if articleSkipAtoms[n.DataAtom] {
+			return
+		}
+		if n.DataAtom == atom.Br {
+			b.WriteString("\n")
+			return
+		}
+	}
+
+	block := n.Type == html.ElementNode && articleBlockAtoms[n.DataAtom]
+	if block {
+		b.WriteString("\n\n")
+		if n.DataAtom == atom.Li {
+			b.WriteString("- ")
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderText(b, c)
+	}
+	if block {
+		b.WriteString("\n\n")
+	}
+}
+
+// insidePre determines if a node is contained by a preformatted element
+func insidePre(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.DataAtom == atom.Pre {
+			return true
+		}
+	}
+	return false
+}
+
+// squeezeBlankLines trims trailing space from each line and collapses runs of blank lines into one
+func squeezeBlankLines(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			if !blank {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// walkNodes visits nodes depth first, descending into children only when fn returns true
+func walkNodes(n *html.Node, fn func(*html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkNodes(c, fn)
+	}
+}
+
+// URLStatus represents the result of checking a url
+type URLStatus struct {
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+// Dead determines if the url could not be retrieved successfully
+func (u URLStatus) Dead() bool {
+	return u.Err != nil || u.StatusCode < 200 || u.StatusCode > 399
+}
+
+// CheckURLs verifies urls concurrently, returning results in the order supplied
+func CheckURLs(urls []string, workers int) []URLStatus {
+	results := make([]URLStatus, len(urls))
+	if workers < 1 {
+		workers = 1
+	}
+
+	client := http.Client{Timeout: 15 * time.Second}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = checkURL(&client, urls[idx])
+			}
+		}()
+	}
+	for idx := range urls {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// checkURL requests the headers of a url, falling back to GET for servers that refuse HEAD
+func checkURL(client *http.Client, url string) URLStatus {
+	result := URLStatus{URL: url}
+
+	resp, err := client.Head(url)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = client.Get(url)
+	}
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	return result
+}
+
+// ExtractURLs returns all unique http and https urls found in data
+func ExtractURLs(data string) []string {
+	var urls []string
+	pattern := regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+	seen := make(map[string]bool)
+	for _, url := range pattern.FindAllString(data, -1) {
+		// trailing punctuation belongs to the surrounding sentence
+		url = strings.TrimRight(url, ".,;:!?*")
+		// only trim closing brackets when they are unbalanced, as in markdown links
+		for _, pair := range [][2]string{{"(", ")"}, {"[", "]"}} {
+			for strings.HasSuffix(url, pair[1]) && strings.Count(url, pair[1]) > strings.Count(url, pair[0]) {
+				url = strings.TrimSuffix(url, pair[1])
+			}
+		}
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+		urls = append(urls, url)
+	}
+	return urls
+}
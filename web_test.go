@@ -0,0 +1,109 @@
+package snip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var articleHTML = `<html>
+<head><title>Fallback Title</title><meta property="og:title" content="Gardening Notes"></head>
+<body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<div class="sidebar"><p>Subscribe to the newsletter</p></div>
+<article>
+<h1>Gardening Notes</h1>
+<p>Tomatoes need plenty of sun, water, and patience.</p>
+<p>Prune the <em>suckers</em> early in the season.</p>
+<ul><li>basil</li><li>oregano</li></ul>
+<pre>  indented
+    code</pre>
+</article>
+<footer>Copyright</footer>
+<script>var tracking = true;</script>
+</body>
+</html>`
+
+func TestExtractArticle(t *testing.T) {
+	title, text, err := ExtractArticle([]byte(articleHTML))
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if title != "Gardening Notes" {
+		t.Errorf(`expected title "Gardening Notes", got "%s"`, title)
+	}
+
+	expected := "Gardening Notes\n\nTomatoes need plenty of sun, water, and patience.\n\nPrune the suckers early in the season.\n\n- basil\n\n- oregano\n\n  indented\n    code"
+	if text != expected {
+		t.Errorf("expected text:\n%q\ngot:\n%q", expected, text)
+	}
+	for _, unwanted := range []string{"Home", "newsletter", "Copyright", "tracking"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("expected text to exclude %s", unwanted)
+		}
+	}
+}
+
+func TestFetchArticle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(articleHTML))
+	}))
+	defer server.Close()
+
+	a, err := FetchArticle(server.URL)
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if a.URL != server.URL {
+		t.Errorf("expected url %s, got %s", server.URL, a.URL)
+	}
+	if string(a.HTML) != articleHTML {
+		t.Errorf("expected raw html to be retained")
+	}
+	if a.Title != "Gardening Notes" {
+		t.Errorf(`expected title "Gardening Notes", got "%s"`, a.Title)
+	}
+}
+
+func TestExtractURLs(t *testing.T) {
+	data := `See https://example.com/docs. Also [the wiki](https://en.wikipedia.org/wiki/Wren_(bird)) and
+(http://example.org/path?q=1), plus https://example.com/docs again.`
+	expected := []string{
+		"https://example.com/docs",
+		"https://en.wikipedia.org/wiki/Wren_(bird)",
+		"http://example.org/path?q=1",
+	}
+	urls := ExtractURLs(data)
+	if len(urls) != len(expected) {
+		t.Fatalf("expected %d urls, got %d: %v", len(expected), len(urls), urls)
+	}
+	for idx, url := range expected {
+		if urls[idx] != url {
+			t.Errorf("expected %s, got %s", url, urls[idx])
+		}
+	}
+}
+
+func TestCheckURLs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	results := CheckURLs([]string{server.URL + "/ok", server.URL + "/missing", "http://127.0.0.1:0/"}, 2)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Dead() {
+		t.Errorf("expected %s to be alive, got status %d", results[0].URL, results[0].StatusCode)
+	}
+	if !results[1].Dead() || results[1].StatusCode != http.StatusNotFound {
+		t.Errorf("expected %s to be dead with status 404, got %d", results[1].URL, results[1].StatusCode)
+	}
+	if !results[2].Dead() || results[2].Err == nil {
+		t.Errorf("expected %s to be dead with an error", results[2].URL)
+	}
+}
@@ -0,0 +1,138 @@
+package snip
+
+import (
+	"fmt"
+	"github.com/ryanfrishkorn/snip/database"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxWildcardTerms is the most index terms a wildcard term may expand to, so that patterns such as a* do not search most of the index
+const maxWildcardTerms = 1000
+
+// isWildcard reports whether a search term is a pattern where * matches any characters, such as deploy* or *.yaml
+func isWildcard(term string) bool {
+	return strings.Contains(term, "*")
+}
+
+// reverse returns text with its characters in reverse order
+func reverse(text string) string {
+	runes := []rune(text)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// addTerm records a term of the index in the term list, which is read by prefix and by suffix to expand wildcard terms
+func addTerm(term string) error {
+	return database.Exec(`INSERT OR IGNORE INTO snip_term (term, reversed) VALUES (?, ?)`, term, reverse(term))
+}
+
+// backfillTerms fills the term list from the index when the list is empty, as it is in databases indexed before it was kept
+func backfillTerms() error {
+	var listed bool
+	err := database.QueryRow(`SELECT EXISTS (SELECT 1 FROM snip_term)`, nil, &listed)
+	if err != nil || listed {
+		return err
+	}
+	stmt, err := database.Conn.Prepare(`SELECT DISTINCT term FROM snip_index`)
+	if err != nil {
+		return err
+	}
+	var terms []string
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			stmt.Close()
+			return err
+		}
+		if !hasRow {
+			break
+		}
+		var term string
+		if err = stmt.Scan(&term); err != nil {
+			stmt.Close()
+			return err
+		}
+		terms = append(terms, term)
+	}
+	stmt.Close()
+
+	return database.Conn.WithTx(func() error {
+		for _, term := range terms {
+			if err := addTerm(term); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// wildcardMatch reports whether term matches pattern, where each * matches any characters
+func wildcardMatch(pattern string, term string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(term, parts[0]) {
+		return false
+	}
+	term = term[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(term, part)
+		if idx < 0 {
+			return false
+		}
+		term = term[idx+len(part):]
+	}
+	return strings.HasSuffix(term, last)
+}
+
+// expandWildcard returns the index terms matching pattern, ignoring case. Terms are found by the characters before the first * when there are any,
+// otherwise by those after the last *, and otherwise by reading every term.
+func expandWildcard(pattern string) ([]string, error) {
+	var terms []string
+	pattern = strings.ToLower(pattern)
+	if strings.Trim(pattern, "*") == "" {
+		return terms, fmt.Errorf("wildcard term %q must contain characters besides *", pattern)
+	}
+	prefix := pattern[:strings.Index(pattern, "*")]
+	suffix := pattern[strings.LastIndex(pattern, "*")+1:]
+
+	query := `SELECT term FROM snip_term`
+	var args []interface{}
+	switch {
+	case prefix != "":
+		// every term beginning with prefix sorts from prefix up to prefix followed by the greatest character
+		query += ` WHERE term >= ? AND term < ?`
+		args = append(args, prefix, prefix+string(utf8.MaxRune))
+	case suffix != "":
+		query += ` WHERE reversed >= ? AND reversed < ?`
+		args = append(args, reverse(suffix), reverse(suffix)+string(utf8.MaxRune))
+	}
+	stmt, err := database.Prepare(query, args...)
+	if err != nil {
+		return terms, err
+	}
+	defer database.Release(stmt)
+
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return terms, err
+		}
+		if !hasRow {
+			return terms, nil
+		}
+		var term string
+		if err = database.Scan(stmt, &term); err != nil {
+			return terms, err
+		}
+		if !wildcardMatch(pattern, term) {
+			continue
+		}
+		if len(terms) == maxWildcardTerms {
+			return terms, fmt.Errorf("wildcard term %q matches more than %d terms", pattern, maxWildcardTerms)
+		}
+		terms = append(terms, term)
+	}
+}
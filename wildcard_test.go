@@ -0,0 +1,98 @@
+package snip
+
+import (
+	"github.com/ryanfrishkorn/snip/database"
+	"testing"
+)
+
+func TestWildcardMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		term    string
+		match   bool
+	}{
+		{"deploy*", "deploy", true},
+		{"deploy*", "deployment", true},
+		{"deploy*", "redeploy", false},
+		{"*.yaml", "config.yaml", true},
+		{"*.yaml", "config.yml", false},
+		{"*ploy*", "redeployed", true},
+		{"k*s", "kubernetes", true},
+		{"k*s", "kubectl", false},
+		{"a*a", "a", false},
+	}
+	for _, tt := range tests {
+		if got := wildcardMatch(tt.pattern, tt.term); got != tt.match {
+			t.Errorf("expected %q matching %q to be %t, got %t", tt.pattern, tt.term, tt.match, got)
+		}
+	}
+}
+
+func TestSearchWildcard(t *testing.T) {
+	deploy := New()
+	deploy.Data = "the deployment of config.yaml failed while deploying"
+	other := New()
+	other.Data = "a redeploy of values.yml"
+	for _, s := range []Snip{deploy, other} {
+		if err := InsertSnip(s); err != nil {
+			t.Fatal(err)
+		}
+		defer Remove(s.UUID)
+		if err := s.Index(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		terms []string
+		found []Snip
+	}{
+		{[]string{"deploy*"}, []Snip{deploy}},
+		{[]string{"*.yaml"}, []Snip{deploy}},
+		{[]string{"*.YML"}, []Snip{other}},
+		{[]string{"*deploy*"}, []Snip{deploy, other}},
+		{[]string{"*deploy*", "config*"}, []Snip{deploy}},
+		{[]string{"config.yaml"}, []Snip{deploy}},
+	}
+	for _, tt := range tests {
+		matches, err := SearchWithContext(tt.terms, 0, 2)
+		if err != nil {
+			t.Fatalf("expected nil err searching %q, got %v", tt.terms, err)
+		}
+		if len(matches) != len(tt.found) {
+			t.Errorf("expected %d matches for %q, got %v", len(tt.found), tt.terms, matches)
+			continue
+		}
+		for _, m := range matches {
+			if m.UUID != deploy.UUID && m.UUID != other.UUID {
+				t.Errorf("unexpected match %s for %q", m.UUID, tt.terms)
+			}
+			if len(m.Context) == 0 || m.Score > 1 {
+				t.Errorf("expected context and a score of at most 1 for %q, got %+v", tt.terms, m)
+			}
+		}
+	}
+
+	if _, err := Search([]string{"**"}, 0); err == nil {
+		t.Errorf("expected an error for a wildcard without characters")
+	}
+
+	// databases indexed before the term list was kept have it filled from the index
+	if err := database.Exec(`DELETE FROM snip_term`); err != nil {
+		t.Fatal(err)
+	}
+	if err := backfillTerms(); err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if terms, err := expandWildcard("deploy*"); err != nil || len(terms) == 0 {
+		t.Errorf("expected terms after filling the term list, got %v and err %v", terms, err)
+	}
+
+	// terms are listed again when the index is rebuilt
+	if err := RebuildIndex(nil); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := SearchIndexTerm([]string{"*.yaml"}, true); err != nil || len(results) != 1 {
+		t.Errorf("expected one result after rebuilding the index, got %v and err %v", results, err)
+	}
+}